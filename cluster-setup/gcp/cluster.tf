@@ -3,7 +3,9 @@
 #
 # Node pools carry the same contract the Rulebricks chart targets everywhere:
 # a core pool for always-on services and a burst pool labeled and tainted
-# rulebricks.com/pool=burst that the KEDA-scaled worker fleet lands on.
+# rulebricks.com/pool=burst that the KEDA-scaled worker fleet lands on. An
+# optional stateful pool (rulebricks.com/pool=stateful) isolates Kafka and
+# Postgres from both.
 
 # Least-privilege node service account (GKE default SA is over-broad).
 resource "google_service_account" "nodes" {
@@ -160,6 +162,7 @@ resource "google_container_node_pool" "burst" {
 
   node_config {
     machine_type    = var.burst_machine_type
+    spot            = var.burst_spot
     disk_type       = var.node_disk_type
     disk_size_gb    = var.node_disk_size_gb
     service_account = google_service_account.nodes.email
@@ -182,3 +185,52 @@ resource "google_container_node_pool" "burst" {
     }
   }
 }
+
+# --- Stateful pool: Kafka and Postgres (optional) ------------------------------
+# The taint keeps everything else off it; with infrastructure.statefulPool set,
+# the chart gives the broker and the database a matching toleration and a hard
+# nodeSelector. Fixed size: the volumes are zonal and must find their node.
+resource "google_container_node_pool" "stateful" {
+  count = var.enable_stateful_pool ? 1 : 0
+
+  name     = "stateful"
+  location = var.region
+  cluster  = google_container_cluster.main.name
+
+  initial_node_count = 0
+
+  autoscaling {
+    total_min_node_count = var.stateful_node_count
+    total_max_node_count = var.stateful_node_count
+    location_policy      = "ANY"
+  }
+
+  management {
+    auto_repair  = true
+    auto_upgrade = true
+  }
+
+  node_config {
+    machine_type    = var.stateful_machine_type
+    disk_type       = var.node_disk_type
+    disk_size_gb    = var.node_disk_size_gb
+    service_account = google_service_account.nodes.email
+    oauth_scopes    = ["https://www.googleapis.com/auth/cloud-platform"]
+    tags            = ["gke-${var.cluster_name}"]
+
+    workload_metadata_config {
+      mode = "GKE_METADATA"
+    }
+
+    labels = {
+      environment           = "rulebricks"
+      "rulebricks.com/pool" = "stateful"
+    }
+
+    taint {
+      key    = "rulebricks.com/pool"
+      value  = "stateful"
+      effect = "NO_SCHEDULE"
+    }
+  }
+}
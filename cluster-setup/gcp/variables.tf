@@ -155,6 +155,38 @@ variable "burst_max_count" {
   default     = 1
 }
 
+variable "burst_spot" {
+  description = <<-EOT
+    Run the burst pool on Spot VMs. Only workers land there; see "Spot burst
+    nodes" in cluster-setup/README.md for the tradeoffs.
+  EOT
+  type        = bool
+  default     = false
+}
+
+variable "enable_stateful_pool" {
+  description = <<-EOT
+    Dedicated stateful node pool labeled and tainted rulebricks.com/pool=stateful
+    for the in-cluster Kafka broker and Postgres, away from bursty workers.
+    Set infrastructure.statefulPool: true in the deployment config so the
+    chart pins both there.
+  EOT
+  type        = bool
+  default     = false
+}
+
+variable "stateful_machine_type" {
+  description = "Stateful pool machine type. Default 4 vCPU / 16 GiB fits the broker and database requests with headroom."
+  type        = string
+  default     = "n4-standard-4"
+}
+
+variable "stateful_node_count" {
+  description = "Stateful pool size (total). Fixed: the pool never scales to zero under its volumes."
+  type        = number
+  default     = 1
+}
+
 variable "cluster_deletion_protection" {
   description = "Blocks terraform destroy of the GKE cluster. Set false before tearing down."
   type        = bool
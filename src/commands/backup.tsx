@@ -1,4 +1,6 @@
 import React, { useEffect, useState } from "react";
+import path from "path";
+import { promises as fs } from "fs";
 import { Box, Text, useApp } from "ink";
 import {
   BorderBox,
@@ -15,28 +17,61 @@ import { CommandDeniedError } from "../lib/commandApproval.js";
 import {
   checkClusterAccessible,
   createJobFromCronJob,
+  execInPodToFile,
   isKubectlInstalled,
   waitForJobComplete,
 } from "../lib/kubernetes.js";
+import { formatBackupSize, k8sName } from "../lib/backupStorage.js";
 import { DeploymentConfig, getNamespace, getReleaseName } from "../types/index.js";
 
 interface BackupCommandProps {
   name: string;
+  // Dump the bundled database to this local file instead of running the
+  // backup job into object storage.
+  output?: string;
 }
 
-type Step = "loading" | "preflight" | "running" | "complete" | "error";
+type Step =
+  | "loading"
+  | "preflight"
+  | "running"
+  | "complete"
+  | "managed"
+  | "error";
 type Status = "pending" | "running" | "success" | "error" | "skipped";
 
-function k8sName(value: string): string {
-  return value.toLowerCase().replace(/[^a-z0-9-]/g, "-").slice(0, 63).replace(/-+$/, "");
+/**
+ * Streams a custom-format pg_dump of the bundled database to a local file.
+ * Connects as supabase_admin with the db container's POSTGRES_PASSWORD, the
+ * same credentials `restore` uses; the file restores with pg_restore.
+ */
+async function dumpDatabase(
+  config: DeploymentConfig,
+  filePath: string,
+): Promise<number> {
+  await execInPodToFile(
+    getNamespace(config.name),
+    `svc/${getReleaseName(config.name)}-supabase-db`,
+    undefined,
+    [
+      "sh",
+      "-c",
+      'PGPASSWORD="$POSTGRES_PASSWORD" pg_dump -h localhost -U supabase_admin -d postgres -Fc',
+    ],
+    filePath,
+  );
+  return (await fs.stat(filePath)).size;
 }
 
-function BackupCommandInner({ name }: BackupCommandProps) {
+function BackupCommandInner({ name, output }: BackupCommandProps) {
   const { exit } = useApp();
   const { colors } = useTheme();
   const [step, setStep] = useState<Step>("loading");
   const [error, setError] = useState<string | null>(null);
   const [logs, setLogs] = useState("");
+  const [dump, setDump] = useState<{ path: string; size: number } | null>(
+    null,
+  );
   const [status, setStatus] = useState<Record<string, Status>>({
     preflight: "pending",
     job: "pending",
@@ -49,6 +84,11 @@ function BackupCommandInner({ name }: BackupCommandProps) {
   async function runBackup() {
     try {
       const config = await loadDeploymentConfig(name);
+      if (config.database.type === "supabase-cloud") {
+        setStep("managed");
+        setTimeout(() => exit(), 5000);
+        return;
+      }
       validateConfig(config);
 
       setStep("preflight");
@@ -56,6 +96,18 @@ function BackupCommandInner({ name }: BackupCommandProps) {
       await runPreflight(config);
       setStatus((current) => ({ ...current, preflight: "success" }));
 
+      if (output) {
+        setStep("running");
+        setStatus((current) => ({ ...current, job: "running" }));
+        const filePath = path.resolve(output);
+        const size = await dumpDatabase(config, filePath);
+        setDump({ path: filePath, size });
+        setStatus((current) => ({ ...current, job: "success" }));
+        setStep("complete");
+        setTimeout(() => exit(), 5000);
+        return;
+      }
+
       const namespace = getNamespace(config.name);
       const releaseName = getReleaseName(config.name);
       const cronJobName = `${releaseName}-db-backup`;
@@ -82,9 +134,13 @@ function BackupCommandInner({ name }: BackupCommandProps) {
   }
 
   function validateConfig(config: DeploymentConfig) {
-    if (config.database.type !== "self-hosted") {
-      throw new Error("Backups are only available for self-hosted Supabase.");
+    if (config.externalServices?.postgres?.mode === "external") {
+      throw new Error(
+        "This deployment uses an external PostgreSQL database; back it up with your provider's snapshots or pg_dump against it directly.",
+      );
     }
+    // A local dump only needs the running database.
+    if (output) return;
     if (!config.storage) {
       throw new Error("Shared object storage is required for database backups.");
     }
@@ -141,6 +197,44 @@ function BackupCommandInner({ name }: BackupCommandProps) {
     );
   }
 
+  if (step === "managed") {
+    return (
+      <BorderBox title="Backups Managed by Supabase">
+        <Box flexDirection="column" marginY={1}>
+          <Text>
+            This deployment uses Supabase Cloud, which backs up the database
+            itself.
+          </Text>
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.muted}>
+              • Daily backups and point-in-time recovery: Supabase dashboard →
+              Database → Backups
+            </Text>
+            <Text color={colors.muted}>
+              • A copy of your own: pg_dump against the project's connection
+              string (Project Settings → Database)
+            </Text>
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "complete" && dump) {
+    return (
+      <BorderBox title="Backup Complete">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.success} bold>
+            ✓ Database dumped to {dump.path} ({formatBackupSize(dump.size)})
+          </Text>
+          <Text color={colors.muted}>
+            Custom-format pg_dump; restore it with pg_restore.
+          </Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
   if (step === "complete") {
     return (
       <BorderBox title="Backup Complete">
@@ -163,10 +257,19 @@ function BackupCommandInner({ name }: BackupCommandProps) {
     <BorderBox title={`Backing Up ${name}`}>
       <Box flexDirection="column" marginY={1}>
         <StatusLine status={status.preflight} label="Preflight checks" />
-        <StatusLine status={status.job} label="Database backup job" />
+        <StatusLine
+          status={status.job}
+          label={output ? "Database dump" : "Database backup job"}
+        />
         <Box marginTop={1}>
           <Spinner
-            label={step === "running" ? "Running backup job..." : "Preparing backup..."}
+            label={
+              step === "running"
+                ? output
+                  ? `Dumping the database to ${output}...`
+                  : "Running backup job..."
+                : "Preparing backup..."
+            }
           />
         </Box>
       </Box>
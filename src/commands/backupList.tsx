@@ -0,0 +1,165 @@
+import React, { useEffect, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  ThemeProvider,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import { loadDeploymentConfig } from "../lib/config.js";
+import { updateKubeconfig } from "../lib/cloudCli.js";
+import { CommandDeniedError } from "../lib/commandApproval.js";
+import {
+  checkClusterAccessible,
+  isKubectlInstalled,
+} from "../lib/kubernetes.js";
+import {
+  dbBackupsTarget,
+  formatBackupSize,
+  listStoredBackups,
+  resolveBackupImages,
+  StoredBackup,
+} from "../lib/backupStorage.js";
+import { DeploymentConfig } from "../types/index.js";
+
+interface BackupListCommandProps {
+  name: string;
+}
+
+function BackupListCommandInner({ name }: BackupListCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [backups, setBackups] = useState<StoredBackup[] | null>(null);
+  const [location, setLocation] = useState("");
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    run();
+  }, []);
+
+  async function run() {
+    try {
+      const cfg = await loadDeploymentConfig(name);
+      if (cfg.database.type !== "self-hosted") {
+        throw new Error(
+          "Backups are only kept for self-hosted Supabase; Supabase Cloud manages its own.",
+        );
+      }
+      if (!cfg.storage) {
+        throw new Error("Shared object storage is required for database backups.");
+      }
+      setLocation(`${cfg.storage.provider}: ${dbBackupsTarget(cfg)}`);
+      await runPreflight(cfg);
+      const images = await resolveBackupImages(cfg);
+      setBackups(await listStoredBackups(cfg, images));
+      setTimeout(() => exit(), 5000);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Listing backups failed");
+    }
+  }
+
+  async function runPreflight(cfg: DeploymentConfig) {
+    if (!(await isKubectlInstalled())) {
+      throw new Error("kubectl is not installed. Please install kubectl first.");
+    }
+
+    let clusterError = await checkClusterAccessible();
+    if (
+      clusterError &&
+      cfg.infrastructure.provider &&
+      cfg.infrastructure.region &&
+      cfg.infrastructure.clusterName
+    ) {
+      try {
+        await updateKubeconfig(
+          cfg.infrastructure.provider,
+          cfg.infrastructure.clusterName,
+          cfg.infrastructure.region,
+          {
+            gcpProjectId: cfg.infrastructure.gcpProjectId,
+            azureResourceGroup: cfg.infrastructure.azureResourceGroup,
+          },
+        );
+      } catch (err) {
+        if (!(err instanceof CommandDeniedError)) {
+          throw err;
+        }
+      }
+      clusterError = await checkClusterAccessible();
+    }
+
+    if (clusterError) {
+      throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+    }
+  }
+
+  if (error) {
+    return (
+      <BorderBox title="Backup List Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>✗ Error</Text>
+          <Text color={colors.error}>{error}</Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (!backups) {
+    return (
+      <BorderBox title={`Backups: ${name}`}>
+        <Box marginY={1}>
+          <Spinner label="Listing backups in object storage..." />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={`Backups: ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        <Text color={colors.muted}>{location}</Text>
+        {backups.length === 0 ? (
+          <Box marginTop={1}>
+            <Text>No database backups found.</Text>
+          </Box>
+        ) : (
+          <Box marginTop={1} flexDirection="column">
+            <Text bold>
+              {"ID".padEnd(28)}
+              {"TAKEN".padEnd(22)}
+              SIZE
+            </Text>
+            {backups.map((backup) => (
+              <Text key={backup.id}>
+                {backup.id.padEnd(28)}
+                {(backup.modifiedAt
+                  ? new Date(backup.modifiedAt).toLocaleString()
+                  : "-"
+                ).padEnd(22)}
+                {formatBackupSize(backup.sizeBytes)}
+              </Text>
+            ))}
+            <Box marginTop={1}>
+              <Text color={colors.muted}>
+                Restore one with: rulebricks backup restore {name} {"<id>"}
+              </Text>
+            </Box>
+          </Box>
+        )}
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function BackupListCommand(props: BackupListCommandProps) {
+  return (
+    <ThemeProvider theme="status">
+      <Logo />
+      <CommandApprovalProvider>
+        <BackupListCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
@@ -1,4 +1,10 @@
-import React, { useCallback, useEffect, useState } from "react";
+import React, {
+  useCallback,
+  useEffect,
+  useMemo,
+  useRef,
+  useState,
+} from "react";
 import { Box, Text, useApp } from "ink";
 import { platform } from "os";
 import {
@@ -11,27 +17,51 @@ import {
   CommandApprovalProvider,
 } from "../components/common/index.js";
 import { DNSWaitScreen } from "../components/DNSWaitScreen.js";
+import { checkExternalPostgres } from "../lib/externalPostgres.js";
+import {
+  findDNSMismatches,
+  formatDNSMismatches,
+  getDeploymentDNSRecords,
+  getLoadBalancerAddress,
+} from "../lib/dns.js";
 import {
   loadDeploymentConfig,
   loadDeploymentState,
   loadHelmValues,
+  pinConfigChartVersion,
   saveDeploymentState,
+  updateDeploymentState,
   updateDeploymentStatus,
 } from "../lib/config.js";
 import {
+  classifyReleaseOwnership,
+  fetchLatestChartVersion,
+  getInstalledChartVersion,
+  getReleaseLabels,
+  helmOverrideArgs,
+  HelmValueOverrides,
   installOrUpgradeChart,
   upgradeChart,
-  isHelmInstalled,
 } from "../lib/helm.js";
 import { assertValidHelmValues } from "../lib/validateValues.js";
+import { assertRequiredTools, requiredTools } from "../lib/toolCheck.js";
+import {
+  assertValidDeploymentConfig,
+  ConfigValidationError,
+} from "../lib/configValidation.js";
 import {
-  isKubectlInstalled,
   checkClusterAccessible,
+  currentContextMatchesCluster,
+  databaseClaimResize,
+  getCurrentContext,
+  getPersistentVolumeClaims,
+  growDatabaseClaim,
   waitForCertificatesReady,
 } from "../lib/kubernetes.js";
 import {
   updateKubeconfig,
   checkAuroraLogicalReplication,
+  checkNodeAutoscaling,
 } from "../lib/cloudCli.js";
 import {
   ensureWorkloadIdentityFederation,
@@ -42,21 +72,82 @@ import {
   generateHelmValuesPreservingEdits,
   updateHelmValuesForTLS,
 } from "../lib/helmValues.js";
-import { resolveImageCatalog } from "../lib/imageCatalog.js";
+import { ImageCatalog, resolveImageCatalog } from "../lib/imageCatalog.js";
 import { ensureNamespace, applyDeploymentSecrets } from "../lib/secrets.js";
 import { setupExternalSecrets } from "../lib/eso.js";
+import { ensureOriginCertificate } from "../lib/cloudflareOrigin.js";
+import { ensureDns01Issuer } from "../lib/dns01Issuer.js";
 import {
   runInstallSequence,
   secretModeForConfig,
   SecretMode,
 } from "../lib/deploySequence.js";
 import { CommandDeniedError } from "../lib/commandApproval.js";
+import {
+  createProgressReporter,
+  diffStepStates,
+  resolveProgressMode,
+  ProgressEvent,
+  ProgressMode,
+} from "../lib/progress.js";
+import type { DeploySummary } from "../lib/deployResult.js";
+import { retryStep } from "../lib/stepRetry.js";
+import { finishEvent, formatDurationMs } from "../lib/deploymentHistory.js";
+import {
+  DEFAULT_STEP_ESTIMATES,
+  DEPLOY_STEP_ORDER,
+  DeployStepKey,
+  formatEstimate,
+  isOverrun,
+  recordableDurations,
+  remainingEstimateMs,
+  StepEstimates,
+  stepEstimates,
+  summarizeEstimates,
+} from "../lib/deployEstimate.js";
+import { runStepGraph } from "../lib/stepGraph.js";
+import {
+  ChartVersionChoice,
+  describeChartVersion,
+  resolveChartVersion,
+} from "../lib/chartPin.js";
+import {
+  ComponentTimeouts,
+  componentTimeoutSeconds,
+  configComponentTimeouts,
+  secondsWithinDeadline,
+  TimeoutComponent,
+  toHelmDuration,
+} from "../lib/componentTimeouts.js";
+import {
+  appliedConfigFor,
+  DeployPhase,
+  DeployPlan,
+  planComponentDeploy,
+  planIncrementalDeploy,
+} from "../lib/incrementalDeploy.js";
+import {
+  EMPTY_ROLLOUT_VIEW,
+  RolloutView,
+  watchRollout as startRolloutWatch,
+} from "../lib/rolloutWatch.js";
+import { RolloutPanel } from "../components/RolloutPanel.js";
+import {
+  SkippableComponent,
+  writeHeldValues,
+} from "../lib/skipComponents.js";
+import {
+  NotificationTarget,
+  notificationTargets,
+  sendNotifications,
+} from "../lib/notifications.js";
 import {
   DeploymentConfig,
   DeploymentState,
   isSupportedDnsProvider,
   getNamespace,
   getReleaseName,
+  usesCloudflareOriginTls,
 } from "../types/index.js";
 
 interface DeployCommandProps {
@@ -65,6 +156,9 @@ interface DeployCommandProps {
   version?: string;
   regenerateValues?: boolean;
   assumeDnsConfigured?: boolean;
+  // Without a terminal to answer the DNS screen, the records are checked once
+  // before TLS and a mismatch fails the deploy; this enables TLS regardless.
+  skipDnsCheck?: boolean;
   // When true, secrets are written inline into values.yaml (dev/direct-chart).
   // Default (false): the config's secrets backend decides - "eso" (cloud
   // secrets manager synced by the External Secrets Operator, the default) or
@@ -74,12 +168,61 @@ interface DeployCommandProps {
   // ESO backends only: overwrite provider entries with the config's values
   // (default is create-if-absent so client-rotated values are preserved).
   syncSecrets?: boolean;
+  // plain/json also write one line per step transition to stdout.
+  progress?: ProgressMode;
+  // Per-component wait deadlines (--component-timeout); these override the
+  // config's timeouts block, and anything unset in both defaults.
+  componentTimeouts?: ComponentTimeouts;
+  // When the whole deploy must be done (epoch ms, --timeout). Every wait,
+  // helm's included, is capped at what is left of it.
+  deadlineAt?: number;
+  // Only run the phases whose config sections changed since the last
+  // successful deploy (falls back to a full deploy when unsure).
+  sinceState?: boolean;
+  // Only run these phases (--components); the rest must already be applied.
+  components?: DeployPhase[];
+  // Ad-hoc values layered over values.yaml for this install (--set/--set-file).
+  valueOverrides?: HelmValueOverrides;
+  // Leave these components as the live release has them for this deploy
+  // (--skip-monitoring, --skip-logging).
+  skipComponents?: SkippableComponent[];
+  // Post the deploy's summary here instead of the config's notifications
+  // targets (--notify).
+  notify?: NotificationTarget;
+  // Receives every progress event regardless of --progress (--health-port).
+  onProgressEvent?: (event: ProgressEvent) => void;
+  // Show live pod phases and Warning events while workloads are installing.
+  watchRollout?: boolean;
+  // Take over an existing same-named release this CLI didn't install.
+  adopt?: boolean;
+  // Deploy wherever kubectl points, even if it isn't the config's cluster.
+  useCurrentContext?: boolean;
+  // Where plain/json progress lines go (default stdout).
+  writeProgress?: (line: string) => void;
+  // Re-run a failed retry-safe step (identity trust, Helm install, TLS
+  // upgrade) up to this many times with backoff (--retry-failed-step).
+  retryFailedStep?: number;
+  // Write the chart version this deploy installed into config.yaml
+  // (chartVersion) so later deploys stay on it (--pin-version).
+  pinVersion?: boolean;
+  // Called once when the deploy ends (--output json); the app then exits
+  // without waiting on the final screen.
+  onFinish?: (summary: DeploySummary) => void;
 }
 
 function getConfigProductVersion(config: DeploymentConfig): string {
   return config.version;
 }
 
+/** database.storageSize when it sizes the bundled Postgres volume. */
+function bundledDatabaseStorageSize(
+  config: DeploymentConfig,
+): string | undefined {
+  if (config.database.type !== "self-hosted") return undefined;
+  if (config.externalServices?.postgres?.mode === "external") return undefined;
+  return config.database.storageSize;
+}
+
 type DeployStep =
   | "loading"
   | "preflight"
@@ -102,14 +245,46 @@ interface StepStatus {
   helmUpgradeTls: "pending" | "running" | "success" | "error" | "skipped";
 }
 
+// Human labels for plain progress output; JSON events use the keys as-is.
+export const PROGRESS_LABELS: Record<
+  keyof StepStatus | "deploy",
+  string
+> = {
+  preflight: "Preflight checks",
+  federation: "Workload identity setup",
+  kubeconfig: "Kubernetes configuration",
+  helmInstall: "Helm chart installation",
+  certCheck: "TLS certificate verification",
+  dnsConfig: "DNS configuration",
+  helmUpgradeTls: "TLS configuration",
+  deploy: "Deployment",
+};
+
 function DeployCommandInner({
   name,
   skipDns,
   version,
   regenerateValues = true,
   assumeDnsConfigured = false,
+  skipDnsCheck = false,
   inlineSecrets = false,
   syncSecrets = false,
+  progress = "auto",
+  componentTimeouts,
+  deadlineAt,
+  sinceState = false,
+  components,
+  valueOverrides,
+  skipComponents = [],
+  notify,
+  onProgressEvent,
+  watchRollout = false,
+  adopt = false,
+  useCurrentContext = false,
+  writeProgress,
+  retryFailedStep = 0,
+  pinVersion = false,
+  onFinish,
 }: DeployCommandProps) {
   const { exit } = useApp();
   const { colors } = useTheme();
@@ -118,8 +293,44 @@ function DeployCommandInner({
   const [error, setError] = useState<string | null>(null);
   const [useExternalDns, setUseExternalDns] = useState(false);
   const [tlsWarning, setTlsWarning] = useState<string | null>(null);
+  const [dnsWarning, setDnsWarning] = useState<string | null>(null);
   const [federationWarning, setFederationWarning] = useState<string | null>(null);
   const [autoscalerWarning, setAutoscalerWarning] = useState<string | null>(null);
+  const [nodeScalingWarning, setNodeScalingWarning] = useState<string | null>(
+    null,
+  );
+  const [configWarnings, setConfigWarnings] = useState<string[]>([]);
+  const [retryNotes, setRetryNotes] = useState<string[]>([]);
+  const [chartChoice, setChartChoice] = useState<ChartVersionChoice | null>(
+    null,
+  );
+  // The chart version being installed: --chart-version, the config pin, or
+  // the version recorded by the last deploy; filled in after install when
+  // none applied and helm took the latest chart.
+  const chartVersion = useRef<string | undefined>(undefined);
+  // The history entry this run records when it ends.
+  const run = useRef<{
+    startedAt: Date;
+    fromVersion?: string;
+    toVersion?: string;
+  }>({ startedAt: new Date() });
+  // Step estimates and measured timings, for the elapsed/remaining line and
+  // the durations the next deploy estimates from.
+  const [estimates, setEstimates] = useState<StepEstimates>(
+    DEFAULT_STEP_ESTIMATES,
+  );
+  const recordedDurations = useRef<Record<string, number>>({});
+  const stepStartedAt = useRef<Partial<Record<string, number>>>({});
+  const stepDurations = useRef<Partial<Record<string, number>>>({});
+  const [now, setNow] = useState(Date.now());
+  // For the notification sent when the run ends: the config as loaded (the
+  // state copy is stale inside runDeployment) and the step last started.
+  const loadedConfig = useRef<DeploymentConfig | null>(null);
+  const lastStartedStep = useRef<string | undefined>(undefined);
+  const [notifyWarnings, setNotifyWarnings] = useState<string[]>([]);
+  const [deployPlan, setDeployPlan] = useState<DeployPlan | null>(null);
+  const overrideArgs = helmOverrideArgs(valueOverrides);
+  const [rollout, setRollout] = useState<RolloutView>(EMPTY_ROLLOUT_VIEW);
   const [status, setStatus] = useState<StepStatus>({
     preflight: "pending",
     federation: "pending",
@@ -130,10 +341,127 @@ function DeployCommandInner({
     helmUpgradeTls: "pending",
   });
 
+  const reporter = useMemo(
+    () =>
+      createProgressReporter(
+        resolveProgressMode(progress, Boolean(process.stdout.isTTY)),
+        {
+          labels: PROGRESS_LABELS,
+          onEvent: onProgressEvent,
+          write: writeProgress,
+        },
+      ),
+    [progress, onProgressEvent, writeProgress],
+  );
+  const previousStatus = useRef(status);
+
   useEffect(() => {
     runDeployment();
   }, []);
 
+  useEffect(() => {
+    for (const [key, event] of diffStepStates(previousStatus.current, status)) {
+      const startedAt = stepStartedAt.current[key];
+      if (event === "started") {
+        stepStartedAt.current[key] = Date.now();
+        lastStartedStep.current = key;
+      } else if (event === "completed" && startedAt !== undefined) {
+        stepDurations.current[key] = Date.now() - startedAt;
+      }
+      reporter.emit(key, event, event === "failed" ? error ?? undefined : undefined);
+    }
+    previousStatus.current = status;
+  }, [status]);
+
+  useEffect(() => {
+    if (step === "complete") {
+      reporter.emit("deploy", "completed", `https://${config?.domain}`);
+    } else if (step === "error") {
+      reporter.emit("deploy", "failed", error ?? undefined);
+    }
+    if (onFinish && (step === "complete" || step === "error")) {
+      onFinish({
+        url: config ? `https://${config.domain}` : undefined,
+        error: error ?? undefined,
+        warnings: [
+          ...configWarnings,
+          federationWarning,
+          autoscalerWarning,
+          nodeScalingWarning,
+          dnsWarning,
+          tlsWarning,
+          ...retryNotes,
+        ].filter((warning): warning is string => Boolean(warning)),
+      });
+      exit();
+    }
+  }, [step]);
+
+  useEffect(() => {
+    if (step === "complete" || step === "error") return;
+    const timer = setInterval(() => setNow(Date.now()), 1000);
+    return () => clearInterval(timer);
+  }, [step]);
+
+  const installingWorkloads =
+    step === "helm-install" || step === "helm-upgrade-tls";
+
+  useEffect(() => {
+    if (!watchRollout || !installingWorkloads || !config) return;
+    setRollout(EMPTY_ROLLOUT_VIEW);
+    return startRolloutWatch(getNamespace(config.name), setRollout);
+  }, [watchRollout, installingWorkloads, config]);
+
+  // --component-timeout wins over the config file's timeouts block. Under
+  // --timeout, helm is handed the remaining budget as its own --timeout so
+  // it fails the release cleanly rather than being killed mid-upgrade.
+  const deadline = (cfg: DeploymentConfig, component: TimeoutComponent) =>
+    secondsWithinDeadline(
+      componentTimeoutSeconds(
+        { ...configComponentTimeouts(cfg.timeouts), ...componentTimeouts },
+        component,
+      ),
+      deadlineAt,
+    );
+
+  // --retry-failed-step: re-runs a whole retry-safe step after a backoff.
+  // Denied commands and config errors fail the same way every time.
+  const withRetries = <T,>(key: keyof StepStatus, fn: () => Promise<T>) =>
+    retryStep(fn, {
+      retries: retryFailedStep,
+      shouldRetry: (err) =>
+        !(err instanceof CommandDeniedError) &&
+        !(err instanceof ConfigValidationError),
+      onRetry: async (attempt, err, delayMs) => {
+        const message = err instanceof Error ? err.message : String(err);
+        const note = `${PROGRESS_LABELS[key]} failed (${message.split("\n")[0]}); retry ${attempt}/${retryFailedStep} in ${delayMs / 1000}s`;
+        setRetryNotes((notes) => [...notes, note]);
+        reporter.emit(key, "started", note);
+        // Keep the recorded state current between attempts.
+        await updateDeploymentStatus(name, "deploying").catch(() => {});
+      },
+    });
+
+  // --skip-monitoring/--skip-logging: Helm gets a copy of values.yaml with
+  // those components held at the live release's values.
+  async function withHeldValues(
+    cfg: DeploymentConfig,
+    install: (valuesPath?: string) => Promise<void>,
+  ): Promise<void> {
+    if (skipComponents.length === 0) return install();
+    const held = await writeHeldValues(
+      name,
+      getReleaseName(cfg.name),
+      getNamespace(cfg.name),
+      skipComponents,
+    );
+    try {
+      await install(held.path);
+    } finally {
+      await held.release();
+    }
+  }
+
   const markRunning = (key: keyof StepStatus) => {
     setStatus((s) => ({ ...s, [key]: "running" }));
   };
@@ -144,7 +472,10 @@ function DeployCommandInner({
 
   const handleDnsComplete = useCallback(async () => {
     if (!config) return;
+    await enableTls(config);
+  }, [config, name, exit]);
 
+  async function enableTls(cfg: DeploymentConfig): Promise<void> {
     try {
       setStep("helm-upgrade-tls");
       setStatus((s) => ({
@@ -155,22 +486,69 @@ function DeployCommandInner({
 
       await updateHelmValuesForTLS(name, true);
 
-      const namespace = getNamespace(config.name);
-      const releaseName = getReleaseName(config.name);
+      const namespace = getNamespace(cfg.name);
+      const releaseName = getReleaseName(cfg.name);
 
-      await upgradeChart(name, { releaseName, namespace, version, wait: true });
+      await withRetries("helmUpgradeTls", () =>
+        withHeldValues(cfg, (valuesPath) =>
+          upgradeChart(name, {
+            releaseName,
+            namespace,
+            version: chartVersion.current,
+            wait: true,
+            timeout: toHelmDuration(deadline(cfg, "chart")),
+            overrides: valueOverrides,
+            valuesPath,
+          }),
+        ),
+      );
 
       setStatus((s) => ({ ...s, helmUpgradeTls: "success", certCheck: "running" }));
       setStep("cert-check");
-      await verifyCertificates(namespace);
+      await verifyCertificates(cfg, namespace);
 
-      await markRunningState(config, namespace);
+      await markRunningState(cfg, namespace);
       setStep("complete");
       setTimeout(() => exit(), 5000);
     } catch (err) {
       await failDeployment(err, "TLS upgrade failed");
     }
-  }, [config, name, version, exit]);
+  }
+
+  // Let's Encrypt fails (and can rate-limit the domain) when the names don't
+  // point at the load balancer yet. With no terminal to wait on the DNS
+  // screen, check once: a mismatch stops the deploy before TLS is enabled,
+  // unless --skip-dns-check.
+  async function checkDnsBeforeTls(cfg: DeploymentConfig): Promise<void> {
+    // Origin certificates need no challenge, and proxied records resolve to
+    // Cloudflare rather than the load balancer.
+    if (usesCloudflareOriginTls(cfg)) return;
+    const namespace = getNamespace(cfg.name);
+    const loadBalancer = await getLoadBalancerAddress(namespace);
+    const problems = loadBalancer.address
+      ? formatDNSMismatches(
+          await findDNSMismatches(
+            getDeploymentDNSRecords(
+              cfg,
+              loadBalancer.address,
+              loadBalancer.type!,
+            ),
+          ),
+        )
+      : "  • the load balancer has no address yet";
+    if (!problems) return;
+
+    const message = `DNS does not point at the load balancer${
+      loadBalancer.address ? ` (${loadBalancer.address})` : ""
+    }:\n${problems}`;
+    if (!skipDnsCheck) {
+      setStatus((s) => ({ ...s, dnsConfig: "error" }));
+      throw new Error(
+        `${message}\nUpdate the records and deploy again, or pass --skip-dns-check to enable TLS anyway.`,
+      );
+    }
+    setDnsWarning(`${message}\nTLS was enabled anyway (--skip-dns-check).`);
+  }
 
   const handleDnsSkip = useCallback(async () => {
     if (!config) return;
@@ -187,26 +565,53 @@ function DeployCommandInner({
     await updateDeploymentStatus(name, "waiting-dns", {
       application: {
         version: productVersion,
-        chartVersion: version || "latest",
+        chartVersion: chartVersion.current ?? "latest",
         namespace,
         url: `https://${config.domain}`,
       },
     });
+    await recordRun();
 
     setStep("complete");
     setTimeout(() => exit(), 5000);
-  }, [config, name, version, exit]);
+  }, [config, name, exit]);
 
   async function runDeployment() {
     try {
       const cfg = await loadDeploymentConfig(name);
       setConfig(cfg);
+      loadedConfig.current = cfg;
 
       const externalDnsEnabled =
         cfg.dns.autoManage && isSupportedDnsProvider(cfg.dns.provider);
       setUseExternalDns(externalDnsEnabled);
 
       const existingState = await loadDeploymentState(name);
+      recordedDurations.current = existingState?.stepDurations ?? {};
+      const estimate = stepEstimates(existingState?.stepDurations);
+      setEstimates(estimate);
+      reporter.emit(
+        "deploy",
+        "started",
+        summarizeEstimates(estimate, PROGRESS_LABELS),
+      );
+      const chart = resolveChartVersion({
+        flag: version,
+        config: cfg,
+        state: existingState,
+      });
+      chartVersion.current = chart.version;
+      // --since-state compares chart versions, so "latest" has to become the
+      // concrete version it stands for, which is then what gets installed.
+      if (sinceState && !components && !chartVersion.current) {
+        chartVersion.current = await fetchLatestChartVersion();
+      }
+      setChartChoice(chart);
+      run.current = {
+        ...run.current,
+        fromVersion: existingState?.application?.version,
+        toVersion: getConfigProductVersion(cfg),
+      };
       const state: DeploymentState = existingState || {
         name,
         version: version || "latest",
@@ -215,112 +620,249 @@ function DeployCommandInner({
         status: "deploying",
       };
 
-      await saveDeploymentState(name, { ...state, status: "deploying" });
+      // Under the state lock, so a concurrent writer's changes survive.
+      const marked = await updateDeploymentState(name, (current) => ({
+        ...current,
+        status: "deploying",
+      }));
+      if (!marked) await saveDeploymentState(name, state);
 
       setStep("preflight");
       markRunning("preflight");
       await runPreflightChecks(cfg);
+      await assertReleaseOwnership(cfg, existingState);
       markSuccess("preflight");
 
-      // Ensure the per-namespace workload-identity trust exists. cluster-setup
-      // creates the deployment-independent identity; this wires it to this
-      // deployment's ServiceAccounts so one cluster can host many deployments.
-      setStep("federation");
-      markRunning("federation");
-      try {
-        const federation = await ensureWorkloadIdentityFederation(cfg);
-        setStatus((s) => ({
-          ...s,
-          federation: federation.skipped ? "skipped" : "success",
-        }));
-      } catch (federationError) {
-        if (!(federationError instanceof CommandDeniedError)) {
-          throw federationError;
-        }
-        setFederationWarning(
-          "Workload identity setup was skipped because a cloud CLI command was denied. Continuing assumes you created the trust manually.",
-        );
+      // The config's secrets backend decides the mode (ESO by default);
+      // --inline-secrets remains the explicit dev/direct-chart escape hatch.
+      const secretMode: SecretMode = inlineSecrets
+        ? "inline"
+        : secretModeForConfig(cfg);
+
+      // --since-state: plan against the baseline recorded by the last
+      // successful deploy (read before this run marked state "deploying").
+      // --components names the phases outright.
+      const plan = components
+        ? planComponentDeploy(components, existingState)
+        : sinceState
+          ? planIncrementalDeploy(cfg, existingState, {
+              chartVersion: chartVersion.current ?? "latest",
+              secretMode,
+            })
+          : null;
+      setDeployPlan(plan);
+      const runs = (phase: DeployPhase) =>
+        !plan || plan.full || plan.phases.has(phase);
+
+      if (plan && !plan.full && plan.phases.size === 0) {
         setStatus((s) => ({
           ...s,
           federation: "skipped",
+          helmInstall: "skipped",
+          dnsConfig: "skipped",
+          helmUpgradeTls: "skipped",
+          certCheck: "skipped",
         }));
+        await updateDeploymentStatus(name, "running");
+        await recordRun();
+        setStep("complete");
+        setTimeout(() => exit(), 5000);
+        return;
       }
 
-      setStep("helm-install");
-      markRunning("helmInstall");
-
       const namespace = getNamespace(cfg.name);
       const releaseName = getReleaseName(cfg.name);
-
-      // Resolve the infrastructure image tags from the chart's own
-      // images/manifest.yaml for the exact chart version being installed
-      // (--chart-version, or whatever the registry currently serves). Resolved
-      // once so both TLS generation phases use the same catalog.
-      const imageCatalog = await resolveImageCatalog(version);
-
-      // The config's secrets backend decides the mode (ESO by default);
-      // --inline-secrets remains the explicit dev/direct-chart escape hatch.
-      const secretMode: SecretMode = inlineSecrets
-        ? "inline"
-        : secretModeForConfig(cfg);
-
-      // Never ship a known-crashlooping autoscaler: when neither the
-      // conventional cluster-setup role nor an existing association backs the
-      // fixed "cluster-autoscaler" SA, disable it in the generated values and
-      // say so instead of stalling helm --wait for the full timeout.
+      let imageCatalog: ImageCatalog | undefined;
       let clusterAutoscalerIdentityMissing = false;
-      try {
-        const autoscalerIdentity = await verifyClusterAutoscalerIdentity(cfg);
-        if (!autoscalerIdentity.ok) {
-          clusterAutoscalerIdentityMissing = true;
-          setAutoscalerWarning(
-            `Node autoscaling is disabled for this deploy: no IAM credentials found for the cluster-autoscaler. ` +
-              `Provision the ${cfg.infrastructure.clusterName}-cluster-autoscaler role (cluster-setup stack) or create a ` +
-              `Pod Identity association for the "cluster-autoscaler" service account in ${namespace}, then redeploy.`,
-          );
-        }
-      } catch (autoscalerError) {
-        if (!(autoscalerError instanceof CommandDeniedError)) {
-          throw autoscalerError;
-        }
-        // Denied cloud lookups: keep the autoscaler enabled and assume
-        // manually-managed credentials, matching the federation fallback.
-      }
 
-      await runInstallSequence(
+      // Pre-install steps talk to different systems (cloud identity APIs, the
+      // chart registry), so they run as a dependency graph rather than in line.
+      // The autoscaler check reads the associations federation creates.
+      setStep("federation");
+      await runStepGraph([
         {
-          regenerateValues,
-          tlsEnabled: externalDnsEnabled,
-          secretMode,
+          // Ensure the per-namespace workload-identity trust exists.
+          // cluster-setup creates the deployment-independent identity; this
+          // wires it to this deployment's ServiceAccounts so one cluster can
+          // host many deployments.
+          id: "federation",
+          run: async () => {
+            if (!runs("federation")) {
+              setStatus((s) => ({ ...s, federation: "skipped" }));
+              return;
+            }
+            markRunning("federation");
+            try {
+              const federation = await withRetries("federation", () =>
+                ensureWorkloadIdentityFederation(cfg),
+              );
+              setStatus((s) => ({
+                ...s,
+                federation: federation.skipped ? "skipped" : "success",
+              }));
+            } catch (federationError) {
+              if (!(federationError instanceof CommandDeniedError)) {
+                throw federationError;
+              }
+              setFederationWarning(
+                "Workload identity setup was skipped because a cloud CLI command was denied. Continuing assumes you created the trust manually.",
+              );
+              setStatus((s) => ({
+                ...s,
+                federation: "skipped",
+              }));
+            }
+          },
         },
         {
-          // Merge-preserving generation: config-driven values are refreshed
-          // while manual values.yaml edits and configure-only changes survive.
-          generateValues: (tlsEnabled, mode) =>
-            generateHelmValuesPreservingEdits(cfg, {
-              tlsEnabled,
-              secretMode: mode,
-              images: imageCatalog,
-              clusterAutoscalerIdentityMissing,
-            }),
-          validateValues: ensureGeneratedValuesValid,
-          ensureNamespace: () => ensureNamespace(namespace),
-          applySecrets: async () => {
-            await applyDeploymentSecrets(cfg, namespace);
+          // Resolve the infrastructure image tags from the chart's own
+          // images/manifest.yaml for the exact chart version being installed
+          // (the pinned version, or whatever the registry currently serves).
+          // Resolved once so both TLS generation phases use the same catalog.
+          id: "images",
+          run: async () => {
+            imageCatalog = await resolveImageCatalog(chartVersion.current);
           },
-          setupExternalSecrets: async () => {
-            await setupExternalSecrets(cfg, { overwriteSecrets: syncSecrets });
+        },
+        {
+          // Never ship a known-crashlooping autoscaler: when neither the
+          // conventional cluster-setup role nor an existing association backs
+          // the fixed "cluster-autoscaler" SA, disable it in the generated
+          // values and say so instead of stalling helm --wait for the full
+          // timeout.
+          id: "autoscaler",
+          dependsOn: ["federation"],
+          run: async () => {
+            try {
+              const autoscalerIdentity =
+                await verifyClusterAutoscalerIdentity(cfg);
+              if (!autoscalerIdentity.ok) {
+                clusterAutoscalerIdentityMissing = true;
+                setAutoscalerWarning(
+                  `Node autoscaling is disabled for this deploy: no IAM credentials found for the cluster-autoscaler. ` +
+                    `Provision the ${cfg.infrastructure.clusterName}-cluster-autoscaler role (cluster-setup stack) or create a ` +
+                    `Pod Identity association for the "cluster-autoscaler" service account in ${namespace}, then redeploy.`,
+                );
+              }
+            } catch (autoscalerError) {
+              if (!(autoscalerError instanceof CommandDeniedError)) {
+                throw autoscalerError;
+              }
+              // Denied cloud lookups: keep the autoscaler enabled and assume
+              // manually-managed credentials, matching the federation
+              // fallback.
+            }
           },
-          installChart: () =>
-            installOrUpgradeChart(name, {
-              releaseName,
-              namespace,
-              version,
-              wait: true,
-            }),
         },
+        {
+          // Node pools that can't scale leave burst pods Pending under load
+          // with nothing in the deploy itself failing; say so up front.
+          // EKS: the Auto Scaling groups need the cluster-autoscaler
+          // discovery tags. GKE/AKS: autoscaling must be on for a pool.
+          id: "node-autoscaling",
+          run: async () => {
+            try {
+              const scaling = await checkNodeAutoscaling(cfg);
+              if (scaling.status === "inactive") {
+                setNodeScalingWarning(
+                  `Node autoscaling is not active: ${scaling.message}. Pods beyond the current nodes' capacity will stay Pending.`,
+                );
+              }
+            } catch (scalingError) {
+              if (!(scalingError instanceof CommandDeniedError)) {
+                throw scalingError;
+              }
+            }
+          },
+        },
+      ]);
+
+      setStep("helm-install");
+      markRunning("helmInstall");
+
+      // A running deployment whose domain/DNS/ingress settings are unchanged
+      // already has DNS and certificates: install straight to TLS and skip the
+      // DNS handshake and certificate wait.
+      const reuseTls = !runs("dns");
+      // A Cloudflare Origin certificate doesn't depend on DNS, so Traefik
+      // can serve it from the first install.
+      const originTls = usesCloudflareOriginTls(cfg);
+
+      await withRetries("helmInstall", () =>
+        runInstallSequence(
+          {
+            // values.yaml is only rewritten when it is going to be applied.
+            regenerateValues: regenerateValues && runs("chart"),
+            tlsEnabled: externalDnsEnabled || reuseTls || originTls,
+            secretMode,
+          },
+          {
+            // Merge-preserving generation: config-driven values are refreshed
+            // while manual values.yaml edits and configure-only changes survive.
+            generateValues: (tlsEnabled, mode) =>
+              generateHelmValuesPreservingEdits(cfg, {
+                tlsEnabled,
+                secretMode: mode,
+                images: imageCatalog,
+                clusterAutoscalerIdentityMissing,
+              }),
+            validateValues: ensureGeneratedValuesValid,
+            ensureNamespace: () => ensureNamespace(namespace),
+            applySecrets: async () => {
+              if (!runs("secrets")) return;
+              await applyDeploymentSecrets(cfg, namespace);
+            },
+            setupExternalSecrets: async () => {
+              if (!runs("secrets")) return;
+              await setupExternalSecrets(cfg, {
+                overwriteSecrets: syncSecrets,
+                syncTimeoutSeconds: deadline(cfg, "secrets"),
+              });
+            },
+            installChart: async () => {
+              if (!runs("chart")) return;
+              if (originTls) {
+                await ensureNamespace(namespace);
+                const { warning } = await ensureOriginCertificate(cfg);
+                if (warning) setConfigWarnings((w) => [...w, warning]);
+              }
+              const storageSize = bundledDatabaseStorageSize(cfg);
+              if (storageSize) {
+                await growDatabaseClaim(namespace, releaseName, storageSize);
+              }
+              await withHeldValues(cfg, (valuesPath) =>
+                installOrUpgradeChart(name, {
+                  releaseName,
+                  namespace,
+                  version: chartVersion.current,
+                  wait: true,
+                  timeout: toHelmDuration(deadline(cfg, "chart")),
+                  overrides: valueOverrides,
+                  valuesPath,
+                }),
+              );
+              // Needs the cert-manager CRDs the chart just installed.
+              await ensureDns01Issuer(cfg);
+            },
+          },
+        ),
       );
 
+      if (!runs("chart")) {
+        setStatus((s) => ({
+          ...s,
+          helmInstall: "skipped",
+          dnsConfig: "skipped",
+          helmUpgradeTls: "skipped",
+          certCheck: "skipped",
+        }));
+        await markRunningState(cfg, namespace);
+        setStep("complete");
+        setTimeout(() => exit(), 5000);
+        return;
+      }
+      await recordChartVersion(releaseName, namespace);
+
       if (externalDnsEnabled) {
         setStatus((s) => ({
           ...s,
@@ -331,7 +873,7 @@ function DeployCommandInner({
         }));
 
         setStep("cert-check");
-        await verifyCertificates(namespace);
+        await verifyCertificates(cfg, namespace);
         await markRunningState(cfg, namespace);
         setStep("complete");
         setTimeout(() => exit(), 5000);
@@ -340,6 +882,19 @@ function DeployCommandInner({
 
       markSuccess("helmInstall");
 
+      if (reuseTls) {
+        setStatus((s) => ({
+          ...s,
+          dnsConfig: "skipped",
+          helmUpgradeTls: "skipped",
+          certCheck: "skipped",
+        }));
+        await markRunningState(cfg, namespace);
+        setStep("complete");
+        setTimeout(() => exit(), 5000);
+        return;
+      }
+
       if (assumeDnsConfigured) {
         setStatus((s) => ({
           ...s,
@@ -348,7 +903,7 @@ function DeployCommandInner({
           certCheck: "running",
         }));
         setStep("cert-check");
-        await verifyCertificates(namespace);
+        await verifyCertificates(cfg, namespace);
         await markRunningState(cfg, namespace);
         setStep("complete");
         setTimeout(() => exit(), 5000);
@@ -366,16 +921,24 @@ function DeployCommandInner({
         await updateDeploymentStatus(name, "waiting-dns", {
           application: {
             version: productVersion,
-            chartVersion: version || "latest",
+            chartVersion: chartVersion.current ?? "latest",
             namespace,
             url: `https://${cfg.domain}`,
           },
         });
+        await recordRun();
         setStep("complete");
         setTimeout(() => exit(), 5000);
         return;
       }
 
+      if (!process.stdin.isTTY) {
+        markRunning("dnsConfig");
+        await checkDnsBeforeTls(cfg);
+        await enableTls(cfg);
+        return;
+      }
+
       await updateDeploymentStatus(name, "waiting-dns");
       setStep("dns-wait");
       markRunning("dnsConfig");
@@ -393,19 +956,33 @@ function DeployCommandInner({
     }
   }
 
-  async function runPreflightChecks(cfg: DeploymentConfig): Promise<void> {
-    const [helm, kubectl] = await Promise.all([
-      isHelmInstalled(),
-      isKubectlInstalled(),
-    ]);
-
-    if (!helm) {
-      throw new Error("Helm is not installed. Please install Helm first.");
+  // `helm upgrade --install` would silently take over (or, when stranded,
+  // uninstall) a same-named release installed by something else.
+  async function assertReleaseOwnership(
+    cfg: DeploymentConfig,
+    existingState: DeploymentState | null,
+  ): Promise<void> {
+    const namespace = getNamespace(cfg.name);
+    const releaseName = getReleaseName(cfg.name);
+    const ownership = classifyReleaseOwnership(
+      await getReleaseLabels(releaseName, namespace),
+      Boolean(existingState?.application || existingState?.appliedConfig),
+    );
+    if (ownership === "foreign" && !adopt) {
+      throw new Error(
+        `A Helm release named ${releaseName} already exists in ${namespace} but was not installed by the Rulebricks CLI. ` +
+          `Remove it, or re-run with --adopt to take it over.`,
+      );
     }
+  }
 
-    if (!kubectl) {
-      throw new Error("kubectl is not installed. Please install kubectl first.");
-    }
+  async function runPreflightChecks(cfg: DeploymentConfig): Promise<void> {
+    // Report every cross-field config problem at once (with field paths)
+    // instead of failing on the first one deep inside values generation.
+    const warnings = assertValidDeploymentConfig(cfg);
+    setConfigWarnings(warnings.map((w) => `${w.path}: ${w.message}`));
+
+    await assertRequiredTools(requiredTools(cfg));
 
     let clusterError = await checkClusterAccessible();
     if (
@@ -460,6 +1037,45 @@ function DeployCommandInner({
       throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
     }
 
+    // On a machine with several clusters the current context can be any of
+    // them; the config's cluster decides, and refreshing its kubeconfig
+    // switches to it.
+    const clusterName = cfg.infrastructure.clusterName;
+    if (
+      clusterName &&
+      !useCurrentContext &&
+      !(await currentContextMatchesCluster(clusterName))
+    ) {
+      if (cfg.infrastructure.provider && cfg.infrastructure.region) {
+        setStep("kubeconfig");
+        markRunning("kubeconfig");
+        try {
+          await updateKubeconfig(
+            cfg.infrastructure.provider,
+            clusterName,
+            cfg.infrastructure.region,
+            {
+              gcpProjectId: cfg.infrastructure.gcpProjectId,
+              azureResourceGroup: cfg.infrastructure.azureResourceGroup,
+            },
+          );
+        } catch (err) {
+          if (!(err instanceof CommandDeniedError)) throw err;
+        }
+      }
+      if (!(await currentContextMatchesCluster(clusterName))) {
+        throw new Error(
+          `kubectl points at ${(await getCurrentContext()) ?? "no context"}, not ${clusterName} (infrastructure.clusterName). ` +
+            `Switch contexts, or pass --use-current-context to deploy there anyway.`,
+        );
+      }
+      clusterError = await checkClusterAccessible();
+      if (clusterError) {
+        throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+      }
+      markSuccess("kubeconfig");
+    }
+
     setStatus((s) => ({
       ...s,
       kubeconfig: s.kubeconfig === "success" ? "success" : "skipped",
@@ -496,6 +1112,38 @@ function DeployCommandInner({
       }
     }
 
+    if (pg?.mode === "external") {
+      const pgCheck = await checkExternalPostgres(cfg);
+      if (pgCheck.status === "failed") {
+        throw new Error(`External Postgres check failed: ${pgCheck.message}`);
+      }
+      if (pgCheck.status === "unreachable") {
+        setConfigWarnings((w) => [
+          ...w,
+          `externalServices.postgres: not reachable from this machine, so the credentials were not checked (${pgCheck.reason})`,
+        ]);
+      }
+    }
+
+    // Kubernetes never shrinks a volume, and helm can't change the Postgres
+    // StatefulSet's volumeClaimTemplate, so a smaller database.storageSize
+    // would fail the upgrade. (A larger one is grown before the install.)
+    const storageSize = bundledDatabaseStorageSize(cfg);
+    if (storageSize) {
+      const resize = databaseClaimResize(
+        await getPersistentVolumeClaims(getNamespace(cfg.name)).catch(
+          () => [],
+        ),
+        getReleaseName(cfg.name),
+        storageSize,
+      );
+      if (resize?.direction === "shrink") {
+        throw new Error(
+          `database.storageSize: ${storageSize} is smaller than ${resize.claim.name} (${resize.claim.requestedGi ?? resize.claim.sizeGi}Gi). Volumes can't shrink; set it to at least the current size.`,
+        );
+      }
+    }
+
     // AWS MSK IAM without Pod Identity credentials wedges the topic-provision
     // pre-install hook until the helm timeout ("no EC2 IMDS role found"), so
     // fail in seconds here instead. Deploy covers the common case itself by
@@ -533,18 +1181,46 @@ function DeployCommandInner({
     }
   }
 
-  async function verifyCertificates(namespace: string): Promise<void> {
+  async function verifyCertificates(
+    cfg: DeploymentConfig,
+    namespace: string,
+  ): Promise<void> {
+    // The Origin certificate was written before the chart was installed;
+    // there are no cert-manager Certificates to wait on.
+    if (usesCloudflareOriginTls(cfg)) {
+      markSuccess("certCheck");
+      return;
+    }
     try {
-      await waitForCertificatesReady(namespace);
+      await waitForCertificatesReady(namespace, {
+        timeoutMs: deadline(cfg, "certificates") * 1000,
+      });
       markSuccess("certCheck");
-    } catch {
+    } catch (err) {
       setStatus((s) => ({ ...s, certCheck: "error" }));
       setTlsWarning(
-        "TLS certificates are still being issued. HTTPS may not be available yet.",
+        "TLS certificates are still being issued. HTTPS may not be available yet." +
+          (err instanceof Error ? `\n${err.message}` : ""),
       );
     }
   }
 
+  // A deploy without a pin installs whatever chart the registry serves;
+  // record the concrete version so state (and the next deploy) stays on it.
+  // --pin-version also writes it into config.yaml.
+  async function recordChartVersion(
+    releaseName: string,
+    namespace: string,
+  ): Promise<void> {
+    if (!chartVersion.current) {
+      chartVersion.current =
+        (await getInstalledChartVersion(releaseName, namespace)) ?? undefined;
+    }
+    if (pinVersion && chartVersion.current) {
+      await pinConfigChartVersion(name, chartVersion.current);
+    }
+  }
+
   async function markRunningState(
     cfg: DeploymentConfig,
     namespace: string,
@@ -553,11 +1229,57 @@ function DeployCommandInner({
     await updateDeploymentStatus(name, "running", {
       application: {
         version: productVersion,
-        chartVersion: version || "latest",
+        chartVersion: chartVersion.current ?? "latest",
         namespace,
         url: `https://${cfg.domain}`,
       },
+      stepDurations: {
+        ...recordedDurations.current,
+        ...recordableDurations(stepDurations.current),
+      },
+      // Baseline for the next `deploy --since-state`. A --components deploy
+      // left phases out, so the previous baseline stays. --set values are not
+      // in the config, so no baseline describes the release: the next
+      // incremental deploy runs in full, which also reverts them. The same
+      // goes for components held back by --skip-monitoring/--skip-logging.
+      ...(overrideArgs.length > 0 || skipComponents.length > 0
+        ? { appliedConfig: undefined }
+        : components
+          ? {}
+          : {
+              appliedConfig: appliedConfigFor(cfg, {
+                chartVersion: chartVersion.current ?? "latest",
+                secretMode: inlineSecrets
+                  ? "inline"
+                  : secretModeForConfig(cfg),
+              }),
+            }),
+    });
+    await recordRun();
+  }
+
+  async function recordRun(err?: unknown): Promise<void> {
+    const event = finishEvent({ action: "deploy", ...run.current, error: err });
+    await recordDeploymentEvent(name, event);
+
+    const cfg = loadedConfig.current;
+    const targets = notificationTargets(cfg?.notifications, notify);
+    if (targets.length === 0) return;
+    const failedStep = lastStartedStep.current;
+    const warnings = await sendNotifications(targets, {
+      deployment: name,
+      outcome: event.outcome,
+      durationMs: event.durationMs,
+      version: event.toVersion,
+      chartVersion: chartVersion.current,
+      url: cfg ? `https://${cfg.domain}` : undefined,
+      failedStep:
+        event.outcome === "failed" && failedStep
+          ? PROGRESS_LABELS[failedStep as keyof typeof PROGRESS_LABELS]
+          : undefined,
+      error: event.error,
     });
+    setNotifyWarnings(warnings);
   }
 
   async function failDeployment(err: unknown, fallback: string): Promise<void> {
@@ -572,7 +1294,9 @@ function DeployCommandInner({
       helmUpgradeTls:
         step === "helm-upgrade-tls" ? "error" : s.helmUpgradeTls,
     }));
-    await updateDeploymentStatus(name, "failed");
+    // Bookkeeping must not replace the deploy's own error.
+    await updateDeploymentStatus(name, "failed").catch(() => {});
+    await recordRun(err ?? fallback).catch(() => {});
   }
 
   if (step === "error") {
@@ -594,6 +1318,11 @@ function DeployCommandInner({
               </Text>
             ))}
           </Box>
+          {notifyWarnings.map((warning, i) => (
+            <Text key={i} color={colors.warning}>
+              ⚠ {warning}
+            </Text>
+          ))}
         </Box>
       </BorderBox>
     );
@@ -644,6 +1373,11 @@ function DeployCommandInner({
                 DNS records will be created automatically by external-dns
               </Text>
             )}
+            {deployPlan && !deployPlan.full && deployPlan.phases.size === 0 && (
+              <Text color={colors.muted}>
+                No config changes since the last deploy; nothing was applied.
+              </Text>
+            )}
             {tlsSkipped && (
               <Box marginTop={1}>
                 <Text color={colors.warning}>
@@ -652,9 +1386,28 @@ function DeployCommandInner({
                 </Text>
               </Box>
             )}
+            {dnsWarning && (
+              <Box marginTop={1} flexDirection="column">
+                {dnsWarning.split("\n").map((line, i) => (
+                  <Text
+                    key={i}
+                    color={i === 0 ? colors.warning : colors.muted}
+                  >
+                    {i === 0 ? `⚠ ${line}` : line}
+                  </Text>
+                ))}
+              </Box>
+            )}
             {tlsWarning && (
-              <Box marginTop={1}>
-                <Text color={colors.warning}>⚠ {tlsWarning}</Text>
+              <Box marginTop={1} flexDirection="column">
+                {tlsWarning.split("\n").map((line, i) => (
+                  <Text
+                    key={i}
+                    color={i === 0 ? colors.warning : colors.muted}
+                  >
+                    {i === 0 ? `⚠ ${line}` : line}
+                  </Text>
+                ))}
               </Box>
             )}
             {federationWarning && (
@@ -667,6 +1420,16 @@ function DeployCommandInner({
                 <Text color={colors.warning}>⚠ {autoscalerWarning}</Text>
               </Box>
             )}
+            {nodeScalingWarning && (
+              <Box marginTop={1}>
+                <Text color={colors.warning}>⚠ {nodeScalingWarning}</Text>
+              </Box>
+            )}
+            {notifyWarnings.map((warning, i) => (
+              <Box key={i} marginTop={1}>
+                <Text color={colors.warning}>⚠ {warning}</Text>
+              </Box>
+            ))}
           </Box>
 
           <Box marginTop={1} flexDirection="column">
@@ -713,15 +1476,86 @@ function DeployCommandInner({
           ? "Azure federated identity credentials"
           : "Workload identity setup";
 
+  // Pending steps show their estimate and finished ones what they took; a
+  // step running far past its estimate is called out.
+  const runningElapsed: Partial<Record<string, number>> = {};
+  for (const key of DEPLOY_STEP_ORDER) {
+    const startedAt = stepStartedAt.current[key];
+    if (status[key] === "running" && startedAt !== undefined) {
+      runningElapsed[key] = now - startedAt;
+    }
+  }
+  const remainingMs = remainingEstimateMs(estimates, status, runningElapsed);
+  const overrun = DEPLOY_STEP_ORDER.find((key) =>
+    isOverrun(key, estimates[key], runningElapsed[key] ?? 0),
+  );
+  const stepTiming = (key: DeployStepKey): string | undefined => {
+    const took = stepDurations.current[key];
+    if (status[key] === "success" && took !== undefined) {
+      return formatDurationMs(took);
+    }
+    if (
+      (status[key] === "pending" || status[key] === "running") &&
+      key !== "dnsConfig"
+    ) {
+      return formatEstimate(estimates[key]);
+    }
+    return undefined;
+  };
+
   return (
     <BorderBox title={`Deploying ${name}`}>
       <Box flexDirection="column" marginY={1}>
-        <StatusLine status={status.preflight} label="Preflight checks" />
+        <StatusLine
+          status={status.preflight}
+          label="Preflight checks"
+          detail={stepTiming("preflight")}
+        />
+        {configWarnings.map((warning, i) => (
+          <Box key={i} marginLeft={2}>
+            <Text color={colors.warning}>{warning}</Text>
+          </Box>
+        ))}
+        {overrideArgs.length > 0 && (
+          <Box marginLeft={2}>
+            <Text color={colors.muted}>
+              Helm overrides (not saved): {overrideArgs.join(" ")}
+            </Text>
+          </Box>
+        )}
+        {skipComponents.length > 0 && (
+          <Box marginLeft={2}>
+            <Text color={colors.muted}>
+              Skipping {skipComponents.join(" and ")}: kept as currently
+              installed
+            </Text>
+          </Box>
+        )}
+        {deployPlan && (
+          <Box marginLeft={2}>
+            <Text color={colors.muted}>
+              {deployPlan.full
+                ? `Full deploy: ${deployPlan.reason}`
+                : components
+                  ? `Partial deploy: ${deployPlan.reason}`
+                  : `Incremental deploy: ${
+                      deployPlan.changed.length > 0
+                        ? `changed ${deployPlan.changed.join(", ")}`
+                        : "chart version changed"
+                    }`}
+            </Text>
+          </Box>
+        )}
         <StatusLine
           status={status.kubeconfig}
           label="Kubernetes configuration"
+          detail={stepTiming("kubeconfig")}
+        />
+        <StatusLine
+          status={status.federation}
+          label={federationLabel}
+          detail={stepTiming("federation")}
         />
-        <StatusLine status={status.federation} label={federationLabel} />
         {federationWarning && (
           <Box marginLeft={2}>
             <Text color={colors.warning}>{federationWarning}</Text>
@@ -732,20 +1566,65 @@ function DeployCommandInner({
             <Text color={colors.warning}>{autoscalerWarning}</Text>
           </Box>
         )}
-        <StatusLine status={status.helmInstall} label={helmInstallLabel} />
+        {nodeScalingWarning && (
+          <Box marginLeft={2}>
+            <Text color={colors.warning}>{nodeScalingWarning}</Text>
+          </Box>
+        )}
+        <StatusLine
+          status={status.helmInstall}
+          label={helmInstallLabel}
+          detail={stepTiming("helmInstall")}
+        />
+        {chartChoice && (
+          <Box marginLeft={2}>
+            <Text color={colors.muted}>{describeChartVersion(chartChoice)}</Text>
+          </Box>
+        )}
         {!useExternalDns && (
           <>
             <StatusLine status={status.dnsConfig} label="DNS configuration" />
             <StatusLine
               status={status.helmUpgradeTls}
               label="TLS configuration"
+              detail={stepTiming("helmUpgradeTls")}
             />
           </>
         )}
         <StatusLine
           status={status.certCheck}
           label="TLS certificate verification"
+          detail={stepTiming("certCheck")}
         />
+        {retryNotes.map((note, i) => (
+          <Box key={i} marginLeft={2}>
+            <Text color={colors.warning}>↻ {note}</Text>
+          </Box>
+        ))}
+
+        {watchRollout && installingWorkloads && (
+          <RolloutPanel view={rollout} />
+        )}
+
+        <Box marginTop={1} flexDirection="column">
+          <Text color={colors.muted}>
+            Elapsed {formatDurationMs(now - run.current.startedAt.getTime())}
+            {" · "}
+            {remainingMs > 0
+              ? `${formatEstimate(remainingMs)} remaining`
+              : "past the estimate"}
+            {" (estimated "}
+            {formatEstimate(remainingEstimateMs(estimates, {}))} total)
+          </Text>
+          {overrun && (
+            <Text color={colors.warning}>
+              ⚠ {PROGRESS_LABELS[overrun]} has run{" "}
+              {formatDurationMs(runningElapsed[overrun] ?? 0)} against{" "}
+              {formatEstimate(estimates[overrun])} estimated; check `rulebricks
+              status {name}` for stuck pods
+            </Text>
+          )}
+        </Box>
 
         <Box marginTop={1}>
           <Spinner label={getStepLabel(step, useExternalDns)} />
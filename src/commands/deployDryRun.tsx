@@ -0,0 +1,267 @@
+import React, { useEffect, useState } from "react";
+import path from "path";
+import { Box, Text, useApp } from "ink";
+import yaml from "yaml";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  ThemeProvider,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import {
+  getDeploymentDir,
+  loadDeploymentConfig,
+  loadDeploymentState,
+  loadHelmValues,
+  writePrivateFile,
+} from "../lib/config.js";
+import { updateKubeconfig } from "../lib/cloudCli.js";
+import { CommandDeniedError } from "../lib/commandApproval.js";
+import { resolveChartVersion } from "../lib/chartPin.js";
+import { assertValidDeploymentConfig } from "../lib/configValidation.js";
+import {
+  countManifestKinds,
+  extractRenderedManifests,
+  planDryRunActions,
+  type DryRunAction,
+  type ManifestKindCount,
+} from "../lib/deployDryRun.js";
+import { secretModeForConfig } from "../lib/deploySequence.js";
+import {
+  dryRunInstallOrUpgrade,
+  getInstalledChartVersion,
+  HelmValueOverrides,
+} from "../lib/helm.js";
+import { deriveTlsEnabled, previewHelmValues } from "../lib/helmValues.js";
+import { resolveImageCatalog } from "../lib/imageCatalog.js";
+import { checkClusterAccessible, namespaceExists } from "../lib/kubernetes.js";
+import { removeTempPath, writeTempFile } from "../lib/tempFiles.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+  isSupportedDnsProvider,
+} from "../types/index.js";
+
+interface DeployDryRunCommandProps {
+  name: string;
+  version?: string;
+  inlineSecrets?: boolean;
+  valueOverrides?: HelmValueOverrides;
+}
+
+interface DryRunResult {
+  actions: DryRunAction[];
+  kinds: ManifestKindCount[];
+  manifestsPath: string;
+  warnings: string[];
+}
+
+function DeployDryRunCommandInner({
+  name,
+  version,
+  inlineSecrets = false,
+  valueOverrides,
+}: DeployDryRunCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [result, setResult] = useState<DryRunResult | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    runDryRun();
+  }, []);
+
+  async function runDryRun() {
+    try {
+      const cfg = await loadDeploymentConfig(name);
+      const warnings = assertValidDeploymentConfig(cfg).map(
+        (w) => `${w.path}: ${w.message}`,
+      );
+      await ensureClusterAccess(cfg);
+      // Same pinning as a real deploy: flag, config, then last deployed.
+      const { version: chartVersion } = resolveChartVersion({
+        flag: version,
+        config: cfg,
+        state: await loadDeploymentState(name),
+      });
+
+      const namespace = getNamespace(cfg.name);
+      const releaseName = getReleaseName(cfg.name);
+      const [hasNamespace, installedChartVersion] = await Promise.all([
+        namespaceExists(namespace),
+        getInstalledChartVersion(releaseName, namespace),
+      ]);
+
+      const secretMode = inlineSecrets ? "inline" : secretModeForConfig(cfg);
+      const externalDns =
+        cfg.dns.autoManage && isSupportedDnsProvider(cfg.dns.provider);
+      // Installed releases keep their TLS state; a first install starts
+      // without TLS unless external-dns handles the records.
+      const tlsEnabled =
+        externalDns ||
+        (installedChartVersion !== null &&
+          deriveTlsEnabled(await loadHelmValues(name)));
+
+      const values = await previewHelmValues(cfg, {
+        tlsEnabled,
+        secretMode,
+        images: await resolveImageCatalog(chartVersion),
+      });
+      const valuesFile = await writeTempFile(
+        "rulebricks-dry-run-",
+        "values.yaml",
+        yaml.stringify(values),
+      );
+      let output: string;
+      try {
+        output = await dryRunInstallOrUpgrade(valuesFile, {
+          releaseName,
+          namespace,
+          version: chartVersion,
+          overrides: valueOverrides,
+        });
+      } finally {
+        await removeTempPath(path.dirname(valuesFile));
+      }
+
+      // Rendered manifests can carry inline secrets; keep them private and
+      // next to the deployment's other generated files.
+      const manifests = extractRenderedManifests(output);
+      const manifestsPath = path.join(
+        getDeploymentDir(name),
+        "dry-run-manifests.yaml",
+      );
+      await writePrivateFile(manifestsPath, `${manifests}\n`);
+
+      setResult({
+        actions: planDryRunActions({
+          config: cfg,
+          namespace,
+          releaseName,
+          namespaceExists: hasNamespace,
+          installedChartVersion,
+          chartVersion,
+          secretMode,
+          externalDns,
+        }),
+        kinds: countManifestKinds(manifests),
+        manifestsPath,
+        warnings,
+      });
+      setTimeout(() => exit(), 5000);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Dry run failed");
+    }
+  }
+
+  async function ensureClusterAccess(cfg: DeploymentConfig) {
+    let clusterError = await checkClusterAccessible();
+    if (
+      clusterError &&
+      cfg.infrastructure.provider &&
+      cfg.infrastructure.region &&
+      cfg.infrastructure.clusterName
+    ) {
+      try {
+        await updateKubeconfig(
+          cfg.infrastructure.provider,
+          cfg.infrastructure.clusterName,
+          cfg.infrastructure.region,
+          {
+            gcpProjectId: cfg.infrastructure.gcpProjectId,
+            azureResourceGroup: cfg.infrastructure.azureResourceGroup,
+          },
+        );
+      } catch (err) {
+        if (!(err instanceof CommandDeniedError)) {
+          throw err;
+        }
+      }
+      clusterError = await checkClusterAccessible();
+    }
+    if (clusterError) {
+      throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+    }
+  }
+
+  if (error) {
+    return (
+      <BorderBox title="Dry Run Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>
+            ✗ Error
+          </Text>
+          {error.split("\n").map((line, i) => (
+            <Text
+              key={i}
+              color={line.startsWith("  •") ? colors.muted : colors.error}
+            >
+              {line}
+            </Text>
+          ))}
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (!result) {
+    return (
+      <BorderBox title={`Dry run: ${name}`}>
+        <Box marginY={1}>
+          <Spinner label="Rendering the deployment without applying it..." />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={`Dry run: ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        <Text color={colors.accent}>A deploy would (no changes made):</Text>
+        {result.actions.map((a, i) => (
+          <Text key={a.step}>
+            {"  "}
+            {i + 1}. <Text bold>{a.step}</Text>: {a.action}
+          </Text>
+        ))}
+
+        <Box marginTop={1} flexDirection="column">
+          <Text color={colors.accent}>Rendered resources:</Text>
+          {result.kinds.map((k) => (
+            <Text key={k.kind} color={colors.muted}>
+              {"  "}
+              {k.count} × {k.kind}
+            </Text>
+          ))}
+          <Text color={colors.muted}>
+            Manifests written to {result.manifestsPath}
+          </Text>
+        </Box>
+
+        {result.warnings.length > 0 && (
+          <Box marginTop={1} flexDirection="column">
+            {result.warnings.map((warning, i) => (
+              <Text key={i} color={colors.warning}>
+                ⚠ {warning}
+              </Text>
+            ))}
+          </Box>
+        )}
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function DeployDryRunCommand(props: DeployDryRunCommandProps) {
+  return (
+    <ThemeProvider theme="deploy">
+      <Logo />
+      <CommandApprovalProvider>
+        <DeployDryRunCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
@@ -0,0 +1,284 @@
+import React, { useEffect, useState } from "react";
+import path from "path";
+import { Box, Text, useApp } from "ink";
+import yaml from "yaml";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  ThemeProvider,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import { loadDeploymentConfig, loadDeploymentState } from "../lib/config.js";
+import { updateKubeconfig } from "../lib/cloudCli.js";
+import { CommandDeniedError } from "../lib/commandApproval.js";
+import { resolveChartVersion } from "../lib/chartPin.js";
+import { extractRenderedManifests } from "../lib/deployDryRun.js";
+import { secretModeForConfig } from "../lib/deploySequence.js";
+import {
+  buildDriftReport,
+  formatDriftValue,
+  parseLiveWorkloads,
+  parseRenderedWorkloads,
+  type DriftReport,
+} from "../lib/driftReport.js";
+import {
+  dryRunInstallOrUpgrade,
+  getInstalledChartVersion,
+  getReleaseUserValues,
+} from "../lib/helm.js";
+import { deriveTlsEnabled, previewHelmValues } from "../lib/helmValues.js";
+import { resolveImageCatalog } from "../lib/imageCatalog.js";
+import { checkClusterAccessible, getWorkloadsJson } from "../lib/kubernetes.js";
+import { removeTempPath, writeTempFile } from "../lib/tempFiles.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+  isSupportedDnsProvider,
+} from "../types/index.js";
+
+interface DeployObserveCommandProps {
+  name: string;
+  version?: string;
+  /** Called once with the report, or with null when observing failed. */
+  onReport?: (report: DriftReport | null) => void;
+}
+
+function DeployObserveCommandInner({
+  name,
+  version,
+  onReport,
+}: DeployObserveCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [report, setReport] = useState<DriftReport | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    observe();
+  }, []);
+
+  async function observe() {
+    try {
+      const cfg = await loadDeploymentConfig(name);
+      await ensureClusterAccess(cfg);
+      // Only a pinned version can be compared; "latest" is whatever ships.
+      const { version: chartVersion } = resolveChartVersion({
+        flag: version,
+        config: cfg,
+        state: await loadDeploymentState(name),
+      });
+
+      const namespace = getNamespace(cfg.name);
+      const releaseName = getReleaseName(cfg.name);
+      const [installedChartVersion, liveValues] = await Promise.all([
+        getInstalledChartVersion(releaseName, namespace),
+        getReleaseUserValues(releaseName, namespace),
+      ]);
+
+      let result: DriftReport;
+      if (installedChartVersion === null) {
+        result = buildDriftReport({
+          release: releaseName,
+          namespace,
+          installedChartVersion,
+          desiredChartVersion: chartVersion,
+          liveValues: null,
+          desiredValues: {},
+          liveWorkloads: [],
+          desiredWorkloads: [],
+        });
+      } else {
+        // TLS state comes from the live release, as a deploy would keep it.
+        const tlsEnabled =
+          (cfg.dns.autoManage && isSupportedDnsProvider(cfg.dns.provider)) ||
+          deriveTlsEnabled(liveValues);
+        const values = await previewHelmValues(cfg, {
+          tlsEnabled,
+          secretMode: secretModeForConfig(cfg),
+          images: await resolveImageCatalog(chartVersion),
+        });
+        const valuesFile = await writeTempFile(
+          "rulebricks-observe-",
+          "values.yaml",
+          yaml.stringify(values),
+        );
+        let output: string;
+        try {
+          output = await dryRunInstallOrUpgrade(valuesFile, {
+            releaseName,
+            namespace,
+            version: chartVersion,
+          });
+        } finally {
+          await removeTempPath(path.dirname(valuesFile));
+        }
+
+        result = buildDriftReport({
+          release: releaseName,
+          namespace,
+          installedChartVersion,
+          desiredChartVersion: chartVersion,
+          liveValues,
+          desiredValues: values,
+          liveWorkloads: parseLiveWorkloads(await getWorkloadsJson(namespace)),
+          desiredWorkloads: parseRenderedWorkloads(
+            extractRenderedManifests(output),
+          ),
+        });
+      }
+
+      onReport?.(result);
+      setReport(result);
+      setTimeout(() => exit(), 500);
+    } catch (err) {
+      onReport?.(null);
+      setError(err instanceof Error ? err.message : "Observe failed");
+      setTimeout(() => exit(), 500);
+    }
+  }
+
+  async function ensureClusterAccess(cfg: DeploymentConfig) {
+    let clusterError = await checkClusterAccessible();
+    if (
+      clusterError &&
+      cfg.infrastructure.provider &&
+      cfg.infrastructure.region &&
+      cfg.infrastructure.clusterName
+    ) {
+      try {
+        await updateKubeconfig(
+          cfg.infrastructure.provider,
+          cfg.infrastructure.clusterName,
+          cfg.infrastructure.region,
+          {
+            gcpProjectId: cfg.infrastructure.gcpProjectId,
+            azureResourceGroup: cfg.infrastructure.azureResourceGroup,
+          },
+        );
+      } catch (err) {
+        if (!(err instanceof CommandDeniedError)) {
+          throw err;
+        }
+      }
+      clusterError = await checkClusterAccessible();
+    }
+    if (clusterError) {
+      throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+    }
+  }
+
+  if (error) {
+    return (
+      <BorderBox title="Observe Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>
+            ✗ Error
+          </Text>
+          {error.split("\n").map((line, i) => (
+            <Text key={i} color={colors.error}>
+              {line}
+            </Text>
+          ))}
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (!report) {
+    return (
+      <BorderBox title={`Observe: ${name}`}>
+        <Box marginY={1}>
+          <Spinner
+            label="Comparing the live release with the current config..."
+          />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (!report.installed) {
+    return (
+      <BorderBox title={`Observe: ${name}`}>
+        <Box marginY={1}>
+          <Text color={colors.warning}>
+            ⚠ {report.release} is not installed in {report.namespace}; a
+            deploy would install it.
+          </Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={`Observe: ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        {!report.drifted && (
+          <Text color={colors.success}>
+            ✓ No drift: the live release matches the current config.
+          </Text>
+        )}
+
+        {report.chart && (
+          <Text>
+            <Text color={colors.accent}>Chart: </Text>
+            {report.chart.live ?? "(unknown)"} → {report.chart.desired}
+          </Text>
+        )}
+
+        {report.values.length > 0 && (
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.accent}>
+              Values ({report.values.length}):
+            </Text>
+            {report.values.map((v) => (
+              <Text key={v.path}>
+                {"  "}
+                <Text bold>{v.path}</Text>
+                <Text color={colors.muted}>
+                  : {formatDriftValue(v.live)} → {formatDriftValue(v.desired)}
+                </Text>
+              </Text>
+            ))}
+          </Box>
+        )}
+
+        {report.workloads.length > 0 && (
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.accent}>
+              Workloads ({report.workloads.length}):
+            </Text>
+            {report.workloads.map((w) => (
+              <Text key={`${w.kind}/${w.name}/${w.change}`}>
+                {"  "}
+                <Text bold>
+                  {w.kind}/{w.name}
+                </Text>
+                <Text color={colors.muted}>
+                  {w.change === "missing"
+                    ? ": not running; a deploy would create it"
+                    : w.change === "extra"
+                      ? ": running but no longer rendered"
+                      : ` ${w.change}: ${w.live} → ${w.desired}`}
+                </Text>
+              </Text>
+            ))}
+          </Box>
+        )}
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function DeployObserveCommand(props: DeployObserveCommandProps) {
+  return (
+    <ThemeProvider theme="deploy">
+      <Logo />
+      <CommandApprovalProvider>
+        <DeployObserveCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
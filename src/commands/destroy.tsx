@@ -1,5 +1,6 @@
 import React, { useCallback, useState } from "react";
 import { Box, Text, useApp } from "ink";
+import TextInput from "ink-text-input";
 import {
   BorderBox,
   CommandApprovalProvider,
@@ -15,26 +16,35 @@ import {
   loadDeploymentState,
   deleteDeployment,
   deploymentExists,
+  recordDeploymentEvent,
   updateDeploymentStatus,
 } from "../lib/config.js";
+import { finishEvent } from "../lib/deploymentHistory.js";
 import { uninstallChart, getInstalledVersion } from "../lib/helm.js";
 import {
   cleanupKubeSystemLeftovers,
   cleanupNamespaceAPIServices,
+  currentContextMatchesCluster,
+  deleteClusterResources,
   deleteNamespace,
   deletePVCs,
   deleteRulebricksCRDs,
   forceReleaseStuckNamespaceFinalizers,
+  getCurrentContext,
+  getPersistentVolumeClaims,
   isClusterAccessible,
   isLastRulebricksDeployment,
   namespaceExists,
+  protectPVCsFromUninstall,
   removeBlockingFinalizers,
   waitForNamespaceDeletion,
 } from "../lib/kubernetes.js";
 import { CommandDeniedError } from "../lib/commandApproval.js";
 import { removeWorkloadIdentityFederation } from "../lib/workloadIdentity.js";
 import { removeEsoResources } from "../lib/eso.js";
+import { deleteDns01Certificate } from "../lib/dns01Issuer.js";
 import { secretModeForConfig } from "../lib/deploySequence.js";
+import { assertRequiredTools, requiredTools } from "../lib/toolCheck.js";
 import {
   DeploymentConfig,
   DeploymentState,
@@ -47,6 +57,8 @@ interface DestroyCommandProps {
   config?: boolean;
   force?: boolean;
   purge?: boolean;
+  // Keep the namespace and its PVCs (database, Kafka) for a reinstall.
+  keepData?: boolean;
 }
 
 type DestroyStep = "loading" | "confirm" | "destroying" | "complete" | "error";
@@ -66,6 +78,9 @@ interface DeploymentScope {
   hasHelmRelease: boolean;
   hasNamespace: boolean;
   clusterAccessible: boolean;
+  currentContext: string | null;
+  // kubectl points at a different cluster than the config records.
+  clusterMismatch: boolean;
 }
 
 function DestroyCommandInner({
@@ -73,6 +88,7 @@ function DestroyCommandInner({
   config,
   force,
   purge,
+  keepData,
 }: DestroyCommandProps) {
   const { exit } = useApp();
   const { colors } = useTheme();
@@ -82,9 +98,12 @@ function DestroyCommandInner({
     useState<DeploymentConfig | null>(null);
   const [scope, setScope] = useState<DeploymentScope | null>(null);
   const [error, setError] = useState<string | null>(null);
+  const [clusterConfirmation, setClusterConfirmation] = useState("");
+  const [confirmError, setConfirmError] = useState<string | null>(null);
   const [remainingSecretEntries, setRemainingSecretEntries] = useState<
     string[]
   >([]);
+  const [keptVolumes, setKeptVolumes] = useState<string[]>([]);
   const [status, setStatus] = useState<StepStatus>({
     helm: "pending",
     pvc: "pending",
@@ -115,13 +134,28 @@ function DestroyCommandInner({
         }
         setDeploymentConfig(cfg);
 
+        // Without kubectl/Helm the cluster just looks unreachable, and destroy
+        // would remove the local files while the release keeps running.
+        await assertRequiredTools(requiredTools(cfg));
+
         const st = await loadDeploymentState(name);
         setState(st);
 
-        const deploymentScope = await determineScope(name, st);
+        // The config names the cluster; state covers a missing config.
+        const deploymentScope = await determineScope(
+          name,
+          st,
+          cfg?.infrastructure.clusterName ?? st?.infrastructure?.clusterName,
+        );
         setScope(deploymentScope);
 
-        if (force) {
+        if (force && deploymentScope.clusterMismatch) {
+          setError(
+            `kubectl points at ${deploymentScope.currentContext}, not ${cfg?.infrastructure.clusterName}. ` +
+              `Switch contexts, or run without --force to confirm the cluster by name.`,
+          );
+          setStep("error");
+        } else if (force) {
           setStep("destroying");
           runDestroy(st, deploymentScope, cfg);
         } else {
@@ -138,7 +172,9 @@ function DestroyCommandInner({
 
   useGatedInput((input, key) => {
     if (step === "confirm") {
-      if (key.return) {
+      // A cluster mismatch is confirmed by typing its name (handled by the
+      // text input) rather than a bare Enter.
+      if (key.return && !scope?.clusterMismatch) {
         setStep("destroying");
         runDestroy(state, scope!, deploymentConfig);
       } else if (key.escape) {
@@ -155,6 +191,11 @@ function DestroyCommandInner({
       deploymentScope: DeploymentScope,
       cfg: DeploymentConfig | null,
     ) => {
+      const run = {
+        action: "destroy" as const,
+        startedAt: new Date(),
+        fromVersion: st?.application?.version,
+      };
       try {
         const namespace = st?.application?.namespace || getNamespace(name);
         const releaseName = getReleaseName(name);
@@ -173,6 +214,21 @@ function DestroyCommandInner({
             }
           }
 
+          // Before the uninstall, or Helm deletes the claims it created. A
+          // failure stops the destroy rather than risk the data.
+          if (keepData && deploymentScope.hasNamespace) {
+            setStatus((s) => ({ ...s, pvc: "running" }));
+            try {
+              await protectPVCsFromUninstall(namespace);
+              const claims = await getPersistentVolumeClaims(namespace);
+              setKeptVolumes(claims.map((claim) => claim.name));
+              setStatus((s) => ({ ...s, pvc: "success" }));
+            } catch (err) {
+              setStatus((s) => ({ ...s, pvc: "error" }));
+              throw err;
+            }
+          }
+
           if (deploymentScope.hasHelmRelease && deploymentScope.hasNamespace) {
             setStatus((s) => ({ ...s, helm: "running" }));
             try {
@@ -185,7 +241,15 @@ function DestroyCommandInner({
             setStatus((s) => ({ ...s, helm: "skipped" }));
           }
 
-          if (deploymentScope.hasNamespace) {
+          if (keepData) {
+            // The claims are namespaced; deleting the namespace would take
+            // them (and dynamically provisioned volumes) with it.
+            setStatus((s) => ({
+              ...s,
+              pvc: s.pvc === "pending" ? "skipped" : s.pvc,
+              namespace: "skipped",
+            }));
+          } else if (deploymentScope.hasNamespace) {
             setStatus((s) => ({ ...s, pvc: "running" }));
             try {
               await deletePVCs(namespace);
@@ -234,10 +298,17 @@ function DestroyCommandInner({
 
           // Leftovers `helm uninstall` does NOT remove. The prometheus-operator's
           // kube-system kubelet Service is per-release and operator-created, so
-          // always clean it (safe; scoped to this release only).
+          // always clean it (safe; scoped to this release only). Cluster-scoped
+          // objects added with `rulebricks apply --allow-cluster-scoped` go too,
+          // and with them the DNS-01 issuer, so a kept namespace loses the
+          // wildcard Certificate that would otherwise keep renewing against it.
           setStatus((s) => ({ ...s, kubeSystem: "running" }));
           try {
             await cleanupKubeSystemLeftovers(releaseName);
+            if (keepData) {
+              await deleteDns01Certificate(name).catch(() => {});
+            }
+            await deleteClusterResources(st?.appliedClusterResources ?? []);
             setStatus((s) => ({ ...s, kubeSystem: "success" }));
           } catch {
             setStatus((s) => ({ ...s, kubeSystem: "error" }));
@@ -311,15 +382,21 @@ function DestroyCommandInner({
         if (!config && deploymentScope.clusterAccessible) {
           await updateDeploymentStatus(name, "destroyed");
         }
+        // After --config the files are gone and this records nothing.
+        await recordDeploymentEvent(name, finishEvent(run));
 
         setStep("complete");
         setTimeout(() => exit(), 3000);
       } catch (err) {
+        await recordDeploymentEvent(
+          name,
+          finishEvent({ ...run, error: err ?? "Destruction failed" }),
+        ).catch(() => {});
         setError(err instanceof Error ? err.message : "Destruction failed");
         setStep("error");
       }
     },
-    [name, config, purge, exit],
+    [name, config, purge, keepData, exit],
   );
 
   if (step === "loading") {
@@ -353,7 +430,8 @@ function DestroyCommandInner({
   if (step === "complete") {
     const cleanedItems: string[] = [];
     if (status.helm === "success") cleanedItems.push("Helm release");
-    if (status.pvc === "success") cleanedItems.push("Persistent volume claims");
+    if (status.pvc === "success" && !keepData)
+      cleanedItems.push("Persistent volume claims");
     if (status.namespace === "success")
       cleanedItems.push("Kubernetes namespace");
     if (status.kubeSystem === "success")
@@ -364,6 +442,8 @@ function DestroyCommandInner({
     if (status.cleanup === "success")
       cleanedItems.push("Local configuration files");
 
+    const keptNamespace = state?.application?.namespace || getNamespace(name);
+
     const noClusterCleanup =
       status.helm === "skipped" &&
       status.pvc === "skipped" &&
@@ -406,6 +486,21 @@ function DestroyCommandInner({
             </Box>
           )}
 
+          {keepData && status.pvc === "success" && (
+            <Box marginTop={1} flexDirection="column">
+              <Text color={colors.muted}>
+                Kept namespace {keptNamespace} and its volumes; `rulebricks
+                deploy {name}` reinstalls onto them:
+              </Text>
+              {keptVolumes.map((volume) => (
+                <Text key={volume} color={colors.muted}>
+                  {" "}
+                  • {volume}
+                </Text>
+              ))}
+            </Box>
+          )}
+
           {remainingSecretEntries.length > 0 && (
             <Box marginTop={1} flexDirection="column">
               <Text color={colors.muted}>
@@ -438,7 +533,11 @@ function DestroyCommandInner({
               />
               <StatusLine
                 status={status.pvc}
-                label="Deleting persistent volumes"
+                label={
+                  keepData
+                    ? "Protecting persistent volumes"
+                    : "Deleting persistent volumes"
+                }
               />
               <StatusLine
                 status={status.namespace}
@@ -481,6 +580,16 @@ function DestroyCommandInner({
     );
   }
 
+  const clusterName = deploymentConfig?.infrastructure.clusterName;
+  const handleClusterConfirm = () => {
+    if (clusterConfirmation.trim() !== clusterName) {
+      setConfirmError(`Type "${clusterName}" to confirm the cluster.`);
+      return;
+    }
+    setStep("destroying");
+    runDestroy(state, scope!, deploymentConfig);
+  };
+
   const hasClusterResources = scope?.hasHelmRelease || scope?.hasNamespace;
   const onlyLocalFiles = !hasClusterResources;
   const willDeleteConfig = config && scope?.hasLocalFiles;
@@ -527,15 +636,25 @@ function DestroyCommandInner({
             </Text>
             <Box marginY={1} flexDirection="column">
               <Text color={colors.muted}>This will permanently delete:</Text>
-              {(scope?.hasHelmRelease || scope?.hasNamespace) && (
-                <>
-                  <Text color={colors.muted}> • Rulebricks application</Text>
-                  <Text color={colors.muted}> • All databases and stored data</Text>
-                  <Text color={colors.muted}> • All persistent volumes</Text>
-                  <Text color={colors.muted}> • Monitoring stack</Text>
-                  <Text color={colors.muted}> • Kubernetes namespace</Text>
-                </>
-              )}
+              {(scope?.hasHelmRelease || scope?.hasNamespace) &&
+                (keepData ? (
+                  <>
+                    <Text color={colors.muted}> • Rulebricks application</Text>
+                    <Text color={colors.muted}> • Monitoring stack</Text>
+                    <Text color={colors.muted}>
+                      Persistent volumes (databases, Kafka) and the namespace
+                      are kept for a reinstall.
+                    </Text>
+                  </>
+                ) : (
+                  <>
+                    <Text color={colors.muted}> • Rulebricks application</Text>
+                    <Text color={colors.muted}> • All databases and stored data</Text>
+                    <Text color={colors.muted}> • All persistent volumes</Text>
+                    <Text color={colors.muted}> • Monitoring stack</Text>
+                    <Text color={colors.muted}> • Kubernetes namespace</Text>
+                  </>
+                ))}
               {willDeleteConfig && (
                 <Text color={colors.muted}> • Local configuration files</Text>
               )}
@@ -559,9 +678,35 @@ function DestroyCommandInner({
           </>
         )}
 
-        <Box marginTop={1}>
-          <Text color={colors.warning}>Press Enter to confirm, Esc to cancel</Text>
-        </Box>
+        {scope?.clusterMismatch ? (
+          <Box flexDirection="column">
+            <Text color={colors.error} bold>
+              kubectl is pointed at {scope.currentContext}, but this deployment
+              was created on {clusterName}.
+            </Text>
+            <Text color={colors.muted}>
+              Type the cluster name to destroy in the current context anyway,
+              or Esc to cancel and switch contexts:
+            </Text>
+            <Box marginTop={1}>
+              <TextInput
+                value={clusterConfirmation}
+                onChange={setClusterConfirmation}
+                onSubmit={handleClusterConfirm}
+                placeholder={clusterName}
+              />
+            </Box>
+            {confirmError && (
+              <Text color={colors.error}>{confirmError}</Text>
+            )}
+          </Box>
+        ) : (
+          <Box marginTop={1}>
+            <Text color={colors.warning}>
+              Press Enter to confirm, Esc to cancel
+            </Text>
+          </Box>
+        )}
       </Box>
     </BorderBox>
   );
@@ -583,6 +728,7 @@ export function DestroyCommand(props: DestroyCommandProps) {
 async function determineScope(
   name: string,
   state: DeploymentState | null,
+  clusterName?: string,
 ): Promise<DeploymentScope> {
   const hasLocalFiles = true;
   const namespace = state?.application?.namespace || getNamespace(name);
@@ -597,8 +743,18 @@ async function determineScope(
 
   let hasHelmRelease = false;
   let hasNamespace = false;
+  let currentContext: string | null = null;
+  let clusterMismatch = false;
 
   if (clusterAccessible) {
+    // Deleting the namespace on whichever cluster kubectl happens to point
+    // at is how the wrong deployment gets destroyed; compare against the
+    // cluster the config was created for.
+    currentContext = await getCurrentContext();
+    if (clusterName && currentContext) {
+      clusterMismatch = !(await currentContextMatchesCluster(clusterName));
+    }
+
     try {
       const installedVersion = await getInstalledVersion(releaseName, namespace);
       hasHelmRelease = installedVersion !== null;
@@ -618,5 +774,7 @@ async function determineScope(
     hasHelmRelease,
     hasNamespace,
     clusterAccessible,
+    currentContext,
+    clusterMismatch,
   };
 }
@@ -0,0 +1,235 @@
+import React, { useEffect, useRef, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  StatusLine,
+  ThemeProvider,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import { loadDeploymentConfig } from "../lib/config.js";
+import { updateKubeconfig } from "../lib/cloudCli.js";
+import { CommandDeniedError } from "../lib/commandApproval.js";
+import {
+  checkClusterAccessible,
+  isKubectlInstalled,
+} from "../lib/kubernetes.js";
+import {
+  reencryptRealtimeTenants,
+  restartRealtime,
+  verifyRealtime,
+} from "../lib/realtimeRepair.js";
+import { DeploymentConfig } from "../types/index.js";
+
+interface FixRealtimeCommandProps {
+  name: string;
+}
+
+type Step =
+  | "loading"
+  | "preflight"
+  | "tenants"
+  | "restart"
+  | "verify"
+  | "complete"
+  | "error";
+type Status = "pending" | "running" | "success" | "error" | "skipped";
+
+const STATUS_KEYS: Partial<Record<Step, string>> = {
+  preflight: "preflight",
+  tenants: "tenants",
+  restart: "restart",
+  verify: "verify",
+};
+
+function FixRealtimeCommandInner({ name }: FixRealtimeCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [step, setStep] = useState<Step>("loading");
+  const [error, setError] = useState<string | null>(null);
+  const [tenants, setTenants] = useState(0);
+  const [problems, setProblems] = useState<string[]>([]);
+  const [status, setStatus] = useState<Record<string, Status>>({
+    preflight: "pending",
+    tenants: "pending",
+    restart: "pending",
+    verify: "pending",
+  });
+
+  useEffect(() => {
+    runRepair();
+  }, []);
+
+  // The async run outlives the render it started in; track its step here.
+  const currentStep = useRef<Step>("loading");
+  const begin = (next: Step) => {
+    currentStep.current = next;
+    setStep(next);
+    setStatus((current) => ({ ...current, [STATUS_KEYS[next]!]: "running" }));
+  };
+  const succeed = (key: string) =>
+    setStatus((current) => ({ ...current, [key]: "success" }));
+
+  async function runRepair() {
+    try {
+      const config = await loadDeploymentConfig(name);
+      if (config.database.type !== "self-hosted") {
+        throw new Error(
+          "fix-realtime only applies to self-hosted Supabase; Supabase Cloud manages Realtime itself.",
+        );
+      }
+
+      begin("preflight");
+      await runPreflight(config);
+      succeed("preflight");
+
+      begin("tenants");
+      setTenants(await reencryptRealtimeTenants(config));
+      succeed("tenants");
+
+      begin("restart");
+      await restartRealtime(config);
+      succeed("restart");
+
+      begin("verify");
+      // Give the new pod a moment to load its tenant and log any failure.
+      await new Promise((resolve) => setTimeout(resolve, 15000));
+      const found = await verifyRealtime(config);
+      setProblems(found);
+      setStatus((current) => ({
+        ...current,
+        verify: found.length > 0 ? "error" : "success",
+      }));
+
+      setStep("complete");
+      setTimeout(() => exit(), 5000);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Realtime repair failed");
+      const failed = STATUS_KEYS[currentStep.current];
+      if (failed) {
+        setStatus((current) => ({ ...current, [failed]: "error" }));
+      }
+      setStep("error");
+    }
+  }
+
+  async function runPreflight(config: DeploymentConfig) {
+    if (!(await isKubectlInstalled())) {
+      throw new Error("kubectl is not installed. Please install kubectl first.");
+    }
+
+    let clusterError = await checkClusterAccessible();
+    if (
+      clusterError &&
+      config.infrastructure.provider &&
+      config.infrastructure.region &&
+      config.infrastructure.clusterName
+    ) {
+      try {
+        await updateKubeconfig(
+          config.infrastructure.provider,
+          config.infrastructure.clusterName,
+          config.infrastructure.region,
+          {
+            gcpProjectId: config.infrastructure.gcpProjectId,
+            azureResourceGroup: config.infrastructure.azureResourceGroup,
+          },
+        );
+      } catch (err) {
+        if (!(err instanceof CommandDeniedError)) {
+          throw err;
+        }
+      }
+      clusterError = await checkClusterAccessible();
+    }
+
+    if (clusterError) {
+      throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+    }
+  }
+
+  const steps = (
+    <>
+      <StatusLine status={status.preflight} label="Preflight checks" />
+      <StatusLine
+        status={status.tenants}
+        label="Re-encrypt tenant JWT secret"
+      />
+      <StatusLine status={status.restart} label="Restart Realtime" />
+      <StatusLine status={status.verify} label="Verify Realtime" />
+    </>
+  );
+
+  if (step === "error") {
+    return (
+      <BorderBox title="Realtime Repair Failed">
+        <Box flexDirection="column" marginY={1}>
+          {steps}
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.error} bold>✗ Error</Text>
+            <Text color={colors.error}>{error}</Text>
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "complete") {
+    return (
+      <BorderBox title="Realtime Repair Complete">
+        <Box flexDirection="column" marginY={1}>
+          {steps}
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.success} bold>
+              ✓ Updated {tenants} Realtime tenant{tenants === 1 ? "" : "s"}
+            </Text>
+            {problems.length > 0 && (
+              <>
+                <Text color={colors.warning}>
+                  ⚠ Realtime still logs secret errors:
+                </Text>
+                {problems.slice(-5).map((line, index) => (
+                  <Text key={index} color={colors.muted}>
+                    {line}
+                  </Text>
+                ))}
+              </>
+            )}
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={`Repairing Realtime for ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        {steps}
+        <Box marginTop={1}>
+          <Spinner
+            label={
+              step === "restart"
+                ? "Waiting for Realtime to restart..."
+                : step === "verify"
+                  ? "Checking Realtime logs..."
+                  : "Repairing Realtime..."
+            }
+          />
+        </Box>
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function FixRealtimeCommand(props: FixRealtimeCommandProps) {
+  return (
+    <ThemeProvider theme="status">
+      <Logo />
+      <CommandApprovalProvider>
+        <FixRealtimeCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
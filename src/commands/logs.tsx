@@ -8,13 +8,21 @@ import {
   useTheme,
   Logo,
 } from "../components/common/index.js";
-import { loadDeploymentState } from "../lib/config.js";
+import { loadDeploymentState, loadHelmValues } from "../lib/config.js";
+import { noLogPodsMessage } from "../lib/components.js";
 import {
+  ALL_LOG_COMPONENTS,
   getComponentPods,
+  logComponentForPod,
   streamLogs,
   streamMultiPodLogs,
   VALID_LOG_COMPONENTS,
 } from "../lib/kubernetes.js";
+import {
+  formatLogEnvelope,
+  LogOutputFormat,
+  toLogEnvelope,
+} from "../lib/logFormat.js";
 import { getNamespace, getReleaseName } from "../types/index.js";
 
 interface LogsCommandProps {
@@ -23,6 +31,8 @@ interface LogsCommandProps {
   follow?: boolean;
   tail?: number;
   split?: boolean;
+  // "json" writes one envelope per line to stdout (the UI goes to stderr).
+  outputFormat?: LogOutputFormat;
 }
 
 const COMPONENTS = [
@@ -33,8 +43,19 @@ const COMPONENTS = [
   { label: "Supabase", value: "supabase" },
   { label: "Traefik", value: "traefik" },
   { label: "Redis", value: "redis" },
+  { label: "Grafana", value: "grafana" },
+  { label: "Prometheus", value: "prometheus" },
+  { label: "Vector", value: "vector" },
+  { label: "All components", value: ALL_LOG_COMPONENTS },
 ];
 
+function isLogComponent(component: string | undefined): component is string {
+  return (
+    component === ALL_LOG_COMPONENTS ||
+    (!!component && VALID_LOG_COMPONENTS.includes(component))
+  );
+}
+
 /**
  * Shortens a pod name for display.
  * E.g., "rulebricks-app-7f8b9c6d5-x2k4m" -> "app-x2k4m"
@@ -246,16 +267,13 @@ function LogsCommandInner({
   follow,
   tail,
   split,
+  outputFormat = "text",
 }: LogsCommandProps) {
   const { exit } = useApp();
   const { colors } = useTheme();
   const [step, setStep] = useState<
     "select" | "loading" | "streaming" | "streaming-split" | "error"
-  >(
-    component && VALID_LOG_COMPONENTS.includes(component)
-      ? "loading"
-      : "select",
-  );
+  >(isLogComponent(component) ? "loading" : "select");
   const [selectedComponent, setSelectedComponent] = useState(component);
   const [pods, setPods] = useState<string[]>([]);
   const [namespace, setNamespace] = useState<string>("");
@@ -285,7 +303,7 @@ function LogsCommandInner({
       setNamespace(ns);
       const releaseName = getReleaseName(name);
 
-      if (!VALID_LOG_COMPONENTS.includes(selectedComponent)) {
+      if (!isLogComponent(selectedComponent)) {
         setError(`Unknown component: ${selectedComponent}`);
         setStep("error");
         return;
@@ -298,7 +316,9 @@ function LogsCommandInner({
       );
 
       if (podNames.length === 0) {
-        setError(`No pods found for component: ${selectedComponent}`);
+        setError(
+          noLogPodsMessage(selectedComponent, ns, await loadHelmValues(name)),
+        );
         setStep("error");
         return;
       }
@@ -306,6 +326,31 @@ function LogsCommandInner({
       setPods(podNames);
       const isFollowing = follow ?? true;
 
+      // JSON envelopes: every container of every pod, one object per line.
+      if (outputFormat === "json") {
+        setStep("streaming");
+        cleanupRef.current = streamMultiPodLogs(podNames, ns, {
+          follow: isFollowing,
+          tail,
+          timestamps: true,
+          allContainers: true,
+          onLine: (podName, line) => {
+            const envelope = toLogEnvelope(
+              selectedComponent === ALL_LOG_COMPONENTS
+                ? (logComponentForPod(podName) ?? selectedComponent)
+                : selectedComponent,
+              podName,
+              line,
+            );
+            process.stdout.write(`${formatLogEnvelope(envelope)}\n`);
+          },
+          // Without --follow, exit once every pod's tail has been written.
+          // Following, a stream ending (a pod restarting) is not the end.
+          onDone: isFollowing ? undefined : () => exit(),
+        });
+        return;
+      }
+
       // Use split view if requested and multiple pods exist
       if (split && podNames.length > 1) {
         setStep("streaming-split");
@@ -321,17 +366,9 @@ function LogsCommandInner({
           follow: isFollowing,
           tail,
           timestamps: true,
+          // If not following, exit once every pod's logs are printed
+          onDone: isFollowing ? undefined : () => exit(),
         });
-
-        // If not following, wait a bit then exit
-        if (!isFollowing) {
-          setTimeout(() => {
-            if (cleanupRef.current) {
-              cleanupRef.current();
-            }
-            exit();
-          }, 2000);
-        }
         return;
       }
 
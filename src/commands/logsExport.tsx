@@ -0,0 +1,161 @@
+import React, { useEffect, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  StatusLine,
+  ThemeProvider,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import { loadDeploymentConfig } from "../lib/config.js";
+import { checkClusterAccessible } from "../lib/kubernetes.js";
+import {
+  bundleName,
+  exportLogs,
+  LogExportOptions,
+  LogExportResult,
+} from "../lib/logExport.js";
+import { getNamespace } from "../types/index.js";
+
+interface LogsExportCommandProps {
+  name: string;
+  /** Archive path (default: the bundle's name in the current directory). */
+  output?: string;
+  options: LogExportOptions;
+}
+
+type Step = "preflight" | "collecting" | "complete" | "error";
+type Status = "pending" | "running" | "success" | "error" | "skipped";
+
+function LogsExportCommandInner({
+  name,
+  output,
+  options,
+}: LogsExportCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [step, setStep] = useState<Step>("preflight");
+  const [error, setError] = useState<string | null>(null);
+  const [progress, setProgress] = useState<{ done: number; total: number }>();
+  const [result, setResult] = useState<LogExportResult | null>(null);
+  const [status, setStatus] = useState<Record<string, Status>>({
+    preflight: "running",
+    collect: "pending",
+  });
+
+  useEffect(() => {
+    runExport();
+  }, []);
+
+  async function runExport() {
+    try {
+      const config = await loadDeploymentConfig(name);
+      const clusterError = await checkClusterAccessible();
+      if (clusterError) {
+        throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+      }
+      setStatus({ preflight: "success", collect: "running" });
+
+      setStep("collecting");
+      const exported = await exportLogs(
+        config.name,
+        getNamespace(config.name),
+        output ?? `${bundleName(config.name, new Date())}.tar.gz`,
+        options,
+        (done, total) => setProgress({ done, total }),
+      );
+      setResult(exported);
+      setStatus((current) => ({ ...current, collect: "success" }));
+      setStep("complete");
+      setTimeout(() => exit(), 5000);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Log export failed");
+      setStatus((current) => ({
+        preflight:
+          current.preflight === "running" ? "error" : current.preflight,
+        collect: current.collect === "running" ? "error" : current.collect,
+      }));
+      setStep("error");
+    }
+  }
+
+  if (step === "error") {
+    return (
+      <BorderBox title="Log Export Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>✗ Error</Text>
+          <Text color={colors.error}>{error}</Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "complete" && result) {
+    return (
+      <BorderBox title="Log Export Complete">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.success} bold>
+            ✓ Logs from {result.pods} pod{result.pods === 1 ? "" : "s"}
+            written to {result.path}
+          </Text>
+          {result.failures.length > 0 && (
+            <Box marginTop={1} flexDirection="column">
+              <Text color={colors.warning}>
+                ⚠ {result.failures.length} item
+                {result.failures.length === 1 ? "" : "s"} could not be
+                collected (listed in errors.txt):
+              </Text>
+              {result.failures.slice(0, 5).map((failure, index) => (
+                <Text key={index} color={colors.muted}>
+                  {"  "}
+                  {failure}
+                </Text>
+              ))}
+            </Box>
+          )}
+          <Text color={colors.muted}>
+            Logs can contain sensitive data; review the archive before sharing
+            it.
+          </Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={`Exporting logs for ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        <StatusLine status={status.preflight} label="Cluster access" />
+        <StatusLine
+          status={status.collect}
+          label="Pod logs, descriptions and events"
+          detail={
+            progress ? `${progress.done}/${progress.total} pods` : undefined
+          }
+        />
+        <Box marginTop={1}>
+          <Spinner
+            label={
+              step === "preflight"
+                ? "Checking cluster access..."
+                : "Collecting logs..."
+            }
+          />
+        </Box>
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function LogsExportCommand(props: LogsExportCommandProps) {
+  return (
+    <ThemeProvider theme="logs">
+      <Logo />
+      <CommandApprovalProvider>
+        <LogsExportCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
@@ -0,0 +1,274 @@
+import React, { useEffect, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  StatusLine,
+  ThemeProvider,
+  useGatedInput,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import {
+  checkClusterAccessible,
+  cleanupNamespaceAPIServices,
+  clearNamespaceFinalizers,
+  forceReleaseStuckNamespaceFinalizers,
+  listStuckNamespaces,
+  removeBlockingFinalizers,
+  waitForNamespaceDeletion,
+  type StuckNamespace,
+} from "../lib/kubernetes.js";
+import { getNamespace } from "../types/index.js";
+
+interface NamespacesCleanupCommandProps {
+  /** Only this deployment's namespace; every Rulebricks namespace if unset. */
+  name?: string;
+  /** Clean up without asking first. */
+  force?: boolean;
+  /** Clear the namespace's own finalizers if releasing its contents fails. */
+  finalize?: boolean;
+}
+
+type Step = "loading" | "confirm" | "cleaning" | "complete" | "error";
+type Status = "pending" | "running" | "success" | "error" | "skipped";
+
+// How long a namespace gets to finish once its blockers are released.
+const RELEASE_WAIT_MS = 60000;
+const FINALIZE_WAIT_MS = 30000;
+
+/**
+ * Releases what a failed destroy left holding the namespace: APIServices
+ * backed by it (which break discovery) and the finalizers on custom
+ * resources whose operators are gone. With `finalize`, a namespace that
+ * still won't finish has its own finalizers cleared. Resolves true once the
+ * namespace is gone.
+ */
+async function cleanUpNamespace(
+  namespace: string,
+  finalize: boolean,
+): Promise<boolean> {
+  await cleanupNamespaceAPIServices(namespace);
+  await removeBlockingFinalizers(namespace);
+  await forceReleaseStuckNamespaceFinalizers(namespace);
+  if (await waitForNamespaceDeletion(namespace, RELEASE_WAIT_MS)) return true;
+  if (!finalize) return false;
+  await clearNamespaceFinalizers(namespace);
+  return waitForNamespaceDeletion(namespace, FINALIZE_WAIT_MS);
+}
+
+function NamespacesCleanupCommandInner({
+  name,
+  force,
+  finalize,
+}: NamespacesCleanupCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [step, setStep] = useState<Step>("loading");
+  const [error, setError] = useState<string | null>(null);
+  const [namespaces, setNamespaces] = useState<StuckNamespace[]>([]);
+  const [status, setStatus] = useState<Status[]>([]);
+  const [failures, setFailures] = useState<string[]>([]);
+
+  useEffect(() => {
+    loadNamespaces();
+  }, []);
+
+  useGatedInput((input, key) => {
+    if (step === "confirm") {
+      if (key.return) {
+        runCleanup(namespaces);
+      } else if (key.escape) {
+        exit();
+      }
+    } else if (step === "error" && (key.escape || key.return)) {
+      exit();
+    }
+  });
+
+  async function loadNamespaces() {
+    try {
+      const clusterError = await checkClusterAccessible();
+      if (clusterError) {
+        throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+      }
+      const target = name ? getNamespace(name) : undefined;
+      const stuck = (await listStuckNamespaces()).filter(
+        (ns) => !target || ns.name === target,
+      );
+      setNamespaces(stuck);
+      setStatus(stuck.map(() => "pending"));
+
+      if (stuck.length === 0) {
+        setStep("complete");
+        setTimeout(() => exit(), 5000);
+      } else if (force) {
+        runCleanup(stuck);
+      } else {
+        setStep("confirm");
+      }
+    } catch (err) {
+      setError(
+        err instanceof Error ? err.message : "Failed to list namespaces",
+      );
+      setStep("error");
+    }
+  }
+
+  async function runCleanup(stuck: StuckNamespace[]) {
+    setStep("cleaning");
+    const failed: string[] = [];
+    for (let index = 0; index < stuck.length; index++) {
+      const mark = (value: Status) =>
+        setStatus((current) =>
+          current.map((s, i) => (i === index ? value : s)),
+        );
+      mark("running");
+      try {
+        if (await cleanUpNamespace(stuck[index].name, Boolean(finalize))) {
+          mark("success");
+        } else {
+          mark("error");
+          failed.push(
+            `${stuck[index].name} is still terminating${
+              finalize ? "" : "; --finalize clears its own finalizers"
+            }`,
+          );
+        }
+      } catch (err) {
+        mark("error");
+        failed.push(
+          `${stuck[index].name}: ${
+            err instanceof Error ? err.message : "failed"
+          }`,
+        );
+      }
+    }
+    setFailures(failed);
+    setStep("complete");
+    setTimeout(() => exit(), 5000);
+  }
+
+  const namespaceLines = namespaces.map((ns, index) => {
+    const since = ns.deletionTimestamp
+      ? ` (deleting since ${ns.deletionTimestamp})`
+      : "";
+    return (
+      <Box key={ns.name} flexDirection="column">
+        <StatusLine status={status[index]} label={`${ns.name}${since}`} />
+        {ns.finalizers.length > 0 && (
+          <Text color={colors.muted}>
+            {"    "}finalizers: {ns.finalizers.join(", ")}
+          </Text>
+        )}
+        {ns.blockers.map((blocker) => (
+          <Text key={blocker} color={colors.muted}>
+            {"    "}
+            {blocker}
+          </Text>
+        ))}
+      </Box>
+    );
+  });
+
+  if (step === "loading") {
+    return (
+      <BorderBox title="Checking namespaces">
+        <Box marginY={1}>
+          <Spinner label="Looking for stuck namespaces..." />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "error") {
+    return (
+      <BorderBox title="Cleanup Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>✗ Error</Text>
+          <Text color={colors.error}>{error}</Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "confirm") {
+    return (
+      <BorderBox title="Stuck namespaces">
+        <Box flexDirection="column" marginY={1}>
+          {namespaceLines}
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.muted}>
+              Cleanup deletes APIServices backed by these namespaces and strips
+              finalizers from the resources left inside them.
+            </Text>
+            {finalize && (
+              <Text color={colors.warning}>
+                With --finalize, a namespace that still won't finish has its
+                own finalizers cleared; anything left in it is orphaned.
+              </Text>
+            )}
+            <Text color={colors.muted}>
+              Press Enter to clean up, or Esc to cancel
+            </Text>
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "complete") {
+    return (
+      <BorderBox title="Cleanup Complete">
+        <Box flexDirection="column" marginY={1}>
+          {namespaces.length === 0 ? (
+            <Text color={colors.success} bold>
+              ✓ No stuck namespaces
+            </Text>
+          ) : (
+            <>
+              {namespaceLines}
+              <Box marginTop={1} flexDirection="column">
+                {failures.length === 0 ? (
+                  <Text color={colors.success} bold>
+                    ✓ Removed {namespaces.length} namespace
+                    {namespaces.length === 1 ? "" : "s"}
+                  </Text>
+                ) : (
+                  failures.map((line, index) => (
+                    <Text key={index} color={colors.error}>
+                      ✗ {line}
+                    </Text>
+                  ))
+                )}
+              </Box>
+            </>
+          )}
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title="Cleaning up namespaces">
+      <Box flexDirection="column" marginY={1}>
+        {namespaceLines}
+        <Box marginTop={1}>
+          <Spinner label="Releasing finalizers..." />
+        </Box>
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function NamespacesCleanupCommand(props: NamespacesCleanupCommandProps) {
+  return (
+    <ThemeProvider theme="destroy">
+      <Logo />
+      <CommandApprovalProvider>
+        <NamespacesCleanupCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
@@ -0,0 +1,234 @@
+import React, { useEffect, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  StatusLine,
+  ThemeProvider,
+  useGatedInput,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import { loadDeploymentHealth } from "../lib/deploymentHealth.js";
+import { getCertificateStatus } from "../lib/kubernetes.js";
+import {
+  applyRepair,
+  describeRepair,
+  planRepairs,
+  type PlannedRepair,
+} from "../lib/repairs.js";
+import { DeploymentConfig } from "../types/index.js";
+
+interface RepairCommandProps {
+  name: string;
+  /** Apply the plan without asking first. */
+  yes?: boolean;
+}
+
+type Step = "loading" | "confirm" | "repairing" | "complete" | "error";
+type Status = "pending" | "running" | "success" | "error" | "skipped";
+
+function RepairCommandInner({ name, yes }: RepairCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [step, setStep] = useState<Step>("loading");
+  const [error, setError] = useState<string | null>(null);
+  const [config, setConfig] = useState<DeploymentConfig | null>(null);
+  const [repairs, setRepairs] = useState<PlannedRepair[]>([]);
+  const [status, setStatus] = useState<Status[]>([]);
+  const [failures, setFailures] = useState<string[]>([]);
+
+  useEffect(() => {
+    loadPlan();
+  }, []);
+
+  useGatedInput((input, key) => {
+    if (step === "confirm") {
+      if (key.return) {
+        runRepairs(config!, repairs);
+      } else if (key.escape) {
+        exit();
+      }
+    } else if (step === "error" && (key.escape || key.return)) {
+      exit();
+    }
+  });
+
+  async function loadPlan() {
+    try {
+      const health = await loadDeploymentHealth(name, {
+        refreshKubeconfig: true,
+      });
+      if (!health.config) {
+        throw new Error(`Invalid configuration:\n${health.configError}`);
+      }
+      if (health.clusterError) {
+        throw new Error(
+          `Cannot access Kubernetes cluster:\n${health.clusterError}`,
+        );
+      }
+      if (!health.helmVersion) {
+        throw new Error(
+          `${name} is not installed; run \`rulebricks deploy ${name}\` instead.`,
+        );
+      }
+
+      const certificates = await getCertificateStatus(health.namespace);
+      const plan = planRepairs({
+        config: health.config,
+        pods: health.pods,
+        certificates,
+      });
+      setConfig(health.config);
+      setRepairs(plan);
+      setStatus(plan.map(() => "pending"));
+
+      if (plan.length === 0) {
+        setStep("complete");
+        setTimeout(() => exit(), 5000);
+      } else if (yes) {
+        runRepairs(health.config, plan);
+      } else {
+        setStep("confirm");
+      }
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to check status");
+      setStep("error");
+      process.exitCode = 1;
+    }
+  }
+
+  async function runRepairs(cfg: DeploymentConfig, plan: PlannedRepair[]) {
+    setStep("repairing");
+    const failed: string[] = [];
+    // One at a time: a Realtime reseed waits for its rollout, and pod
+    // restarts shouldn't pile onto a cluster that is already struggling.
+    for (let index = 0; index < plan.length; index++) {
+      const mark = (value: Status) =>
+        setStatus((current) =>
+          current.map((s, i) => (i === index ? value : s)),
+        );
+      mark("running");
+      try {
+        await applyRepair(cfg, plan[index]);
+        mark("success");
+      } catch (err) {
+        mark("error");
+        failed.push(
+          `${describeRepair(plan[index])}: ${
+            err instanceof Error ? err.message : "failed"
+          }`,
+        );
+      }
+    }
+    setFailures(failed);
+    // Scripts and CI judge the repair by the exit status.
+    if (failed.length > 0) process.exitCode = 1;
+    setStep("complete");
+    setTimeout(() => exit(), 5000);
+  }
+
+  const planLines = repairs.map((repair, index) => (
+    <Box key={`${repair.kind}-${repair.target}`} flexDirection="column">
+      <StatusLine status={status[index]} label={describeRepair(repair)} />
+      <Text color={colors.muted}>
+        {"    "}
+        {repair.reason}
+      </Text>
+    </Box>
+  ));
+
+  if (step === "loading") {
+    return (
+      <BorderBox title={`Checking ${name}`}>
+        <Box marginY={1}>
+          <Spinner label="Looking for problems to repair..." />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "error") {
+    return (
+      <BorderBox title="Repair Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>✗ Error</Text>
+          <Text color={colors.error}>{error}</Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "confirm") {
+    return (
+      <BorderBox title={`Planned repairs for ${name}`}>
+        <Box flexDirection="column" marginY={1}>
+          {planLines}
+          <Box marginTop={1}>
+            <Text color={colors.muted}>
+              Press Enter to apply these repairs, or Esc to cancel
+            </Text>
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "complete") {
+    return (
+      <BorderBox title="Repair Complete">
+        <Box flexDirection="column" marginY={1}>
+          {repairs.length === 0 ? (
+            <Text color={colors.success} bold>
+              ✓ Nothing to repair
+            </Text>
+          ) : (
+            <>
+              {planLines}
+              <Box marginTop={1} flexDirection="column">
+                {failures.length === 0 ? (
+                  <Text color={colors.success} bold>
+                    ✓ Applied {repairs.length} repair
+                    {repairs.length === 1 ? "" : "s"}
+                  </Text>
+                ) : (
+                  failures.map((line, index) => (
+                    <Text key={index} color={colors.error}>
+                      ✗ {line}
+                    </Text>
+                  ))
+                )}
+                <Text color={colors.muted}>
+                  Run `rulebricks status {name}` to confirm the result
+                </Text>
+              </Box>
+            </>
+          )}
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={`Repairing ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        {planLines}
+        <Box marginTop={1}>
+          <Spinner label="Applying repairs..." />
+        </Box>
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function RepairCommand(props: RepairCommandProps) {
+  return (
+    <ThemeProvider theme="status">
+      <Logo />
+      <CommandApprovalProvider>
+        <RepairCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
@@ -23,14 +23,24 @@ import {
   waitForDeploymentReady,
 } from "../lib/kubernetes.js";
 import {
-  getInstalledChartVersion,
-  getReleaseComputedValues,
-} from "../lib/helm.js";
-import { resolveImageCatalog } from "../lib/imageCatalog.js";
+  backupJobLabels,
+  BackupImages,
+  dbBackupsTarget,
+  formatBackupSize,
+  k8sName,
+  listStoredBackups,
+  rcloneEnv,
+  resolveBackupImages,
+  StoredBackup,
+} from "../lib/backupStorage.js";
 import { DeploymentConfig, getNamespace, getReleaseName } from "../types/index.js";
 
 interface RestoreCommandProps {
   name: string;
+  // Restore this backup instead of picking one from the list.
+  backupId?: string;
+  // Skip the typed confirmation (automation).
+  force?: boolean;
 }
 
 type Step =
@@ -44,134 +54,11 @@ type Step =
   | "error";
 type Status = "pending" | "running" | "success" | "error" | "skipped";
 
-interface BackupInfo {
-  id: string;
-  label: string;
-}
-
 interface DeploymentReplica {
   name: string;
   replicas: number;
 }
 
-interface RestoreImages {
-  dbImage: string;
-  rcloneImage: string;
-}
-
-// Walks an image dict ({ registry, repository, tag }) out of computed Helm
-// values and builds a full reference. Returns null when the path is absent or
-// malformed so the caller can fall back to the chart image manifest.
-function imageRefFromValues(
-  values: Record<string, unknown> | null,
-  keys: string[],
-): string | null {
-  let node: unknown = values;
-  for (const key of keys) {
-    if (!node || typeof node !== "object") return null;
-    node = (node as Record<string, unknown>)[key];
-  }
-  const image = node as Record<string, unknown> | undefined;
-  if (
-    !image ||
-    typeof image.repository !== "string" ||
-    typeof image.tag !== "string"
-  ) {
-    return null;
-  }
-  const registry = typeof image.registry === "string" && image.registry
-    ? image.registry
-    : "docker.io";
-  return `${registry}/${image.repository}:${image.tag}`;
-}
-
-// The restore jobs must run exactly the images the deployment runs. Primary
-// source: the release's computed values (chart defaults + overrides) via
-// `helm get values --all`. Fallback: the chart image manifest for the
-// installed chart version (see src/lib/imageCatalog.ts).
-async function resolveRestoreImages(
-  cfg: DeploymentConfig,
-): Promise<RestoreImages> {
-  const namespace = getNamespace(cfg.name);
-  const releaseName = getReleaseName(cfg.name);
-
-  const computed = await getReleaseComputedValues(releaseName, namespace);
-  let dbImage = imageRefFromValues(computed, ["supabase", "db", "image"]);
-  let rcloneImage = imageRefFromValues(computed, ["global", "images", "rclone"]);
-
-  if (!dbImage || !rcloneImage) {
-    const chartVersion = await getInstalledChartVersion(releaseName, namespace);
-    const catalog = await resolveImageCatalog(chartVersion ?? undefined);
-    dbImage = dbImage ?? catalog.image("supabase-postgres", cfg.imageRegistry).ref;
-    rcloneImage = rcloneImage ?? catalog.image("rclone", cfg.imageRegistry).ref;
-  }
-
-  return { dbImage, rcloneImage };
-}
-
-function k8sName(value: string): string {
-  return value.toLowerCase().replace(/[^a-z0-9-]/g, "-").slice(0, 63).replace(/-+$/, "");
-}
-
-// The single bucket/container plus the db-backups prefix, e.g. "my-bucket/db-backups"
-// (S3/GCS) or "my-container/db-backups" (azure-blob).
-function dbBackupsTarget(config: DeploymentConfig): string {
-  const storage = config.storage;
-  if (!storage) throw new Error("Shared object storage is required.");
-  const prefix = (storage.paths?.dbBackups || "db-backups").replace(
-    /^\/+|\/+$/g,
-    "",
-  );
-  if (storage.provider === "azure-blob") {
-    return `${storage.azureBlobContainer || "rulebricks"}/${prefix}`;
-  }
-  return `${storage.bucket}/${prefix}`;
-}
-
-// rclone on-the-fly remote "dest" config via env vars (no config file). Auth is
-// the pod's workload identity (env_auth) for every provider, or an Azure Blob
-// connection string Secret in the fallback path.
-function rcloneEnv(config: DeploymentConfig): Array<Record<string, unknown>> {
-  const storage = config.storage;
-  if (!storage) throw new Error("Shared object storage is required.");
-  const env: Array<Record<string, unknown>> = [];
-
-  switch (storage.provider) {
-    case "azure-blob":
-      env.push({ name: "RCLONE_CONFIG_DEST_TYPE", value: "azureblob" });
-      env.push({ name: "RCLONE_CONFIG_DEST_ACCOUNT", value: storage.bucket });
-      if (storage.cloudAuthMode === "secret") {
-        if (!storage.azureBlobConnectionStringSecretRef) {
-          throw new Error("Azure Blob connection string secret ref is required.");
-        }
-        env.push({
-          name: "RCLONE_CONFIG_DEST_CONNECTION_STRING",
-          valueFrom: {
-            secretKeyRef: {
-              name: storage.azureBlobConnectionStringSecretRef.name,
-              key: storage.azureBlobConnectionStringSecretRef.key,
-            },
-          },
-        });
-      } else {
-        env.push({ name: "RCLONE_CONFIG_DEST_ENV_AUTH", value: "true" });
-      }
-      break;
-    case "gcs":
-      env.push({ name: "RCLONE_CONFIG_DEST_TYPE", value: "google cloud storage" });
-      env.push({ name: "RCLONE_CONFIG_DEST_ENV_AUTH", value: "true" });
-      env.push({ name: "RCLONE_CONFIG_DEST_BUCKET_POLICY_ONLY", value: "true" });
-      break;
-    default:
-      env.push({ name: "RCLONE_CONFIG_DEST_TYPE", value: "s3" });
-      env.push({ name: "RCLONE_CONFIG_DEST_PROVIDER", value: "AWS" });
-      env.push({ name: "RCLONE_CONFIG_DEST_ENV_AUTH", value: "true" });
-      env.push({ name: "RCLONE_CONFIG_DEST_REGION", value: storage.region });
-      break;
-  }
-  return env;
-}
-
 function pgEnv(
   config: DeploymentConfig,
   releaseName: string,
@@ -195,39 +82,14 @@ function pgEnv(
   ];
 }
 
-function jobLabels(config: DeploymentConfig): Record<string, string> {
-  const labels: Record<string, string> = {
-    "app.kubernetes.io/component": "db-restore",
-  };
-  // Azure Workload Identity requires this pod label so the projected token is
-  // injected for the rclone download. S3 (IRSA) and GCS (GKE WI) work via the SA.
-  if (
-    config.storage?.provider === "azure-blob" &&
-    config.storage.cloudAuthMode !== "secret"
-  ) {
-    labels["azure.workload.identity/use"] = "true";
-  }
-  return labels;
-}
-
-function parseBackups(output: string): BackupInfo[] {
-  return output
-    .split("\n")
-    .map((line) => line.trim().replace(/\/+$/, ""))
-    .filter((line) => line.length > 0 && !line.includes("/"))
-    .sort()
-    .reverse()
-    .map((id) => ({ id, label: id }));
-}
-
-function RestoreCommandInner({ name }: RestoreCommandProps) {
+function RestoreCommandInner({ name, backupId, force = false }: RestoreCommandProps) {
   const { exit } = useApp();
   const { colors } = useTheme();
   const [step, setStep] = useState<Step>("loading");
   const [config, setConfig] = useState<DeploymentConfig | null>(null);
-  const [restoreImages, setRestoreImages] = useState<RestoreImages | null>(null);
-  const [backups, setBackups] = useState<BackupInfo[]>([]);
-  const [selectedBackup, setSelectedBackup] = useState<BackupInfo | null>(null);
+  const [restoreImages, setRestoreImages] = useState<BackupImages | null>(null);
+  const [backups, setBackups] = useState<StoredBackup[]>([]);
+  const [selectedBackup, setSelectedBackup] = useState<StoredBackup | null>(null);
   const [confirmation, setConfirmation] = useState("");
   const [error, setError] = useState<string | null>(null);
   const [logs, setLogs] = useState("");
@@ -253,16 +115,35 @@ function RestoreCommandInner({ name }: RestoreCommandProps) {
       setStatus((current) => ({ ...current, preflight: "running" }));
       await runPreflight(cfg);
       // Needs cluster access (helm get values), so resolve after preflight.
-      const images = await resolveRestoreImages(cfg);
+      const images = await resolveBackupImages(cfg);
       setRestoreImages(images);
       setStatus((current) => ({ ...current, preflight: "success" }));
 
       setStep("listing");
       setStatus((current) => ({ ...current, list: "running" }));
-      const available = await listBackups(cfg, images);
+      const available = await listStoredBackups(cfg, images);
+      if (available.length === 0) {
+        throw new Error("No database backups found in object storage.");
+      }
       setBackups(available);
       setStatus((current) => ({ ...current, list: "success" }));
-      setStep("select");
+
+      if (!backupId) {
+        setStep("select");
+        return;
+      }
+      const backup = available.find((b) => b.id === backupId);
+      if (!backup) {
+        throw new Error(
+          `Backup "${backupId}" not found. Run \`rulebricks backup list ${cfg.name}\` to see the available backups.`,
+        );
+      }
+      setSelectedBackup(backup);
+      if (force) {
+        await restoreBackup(cfg, backup, images);
+      } else {
+        setStep("confirm");
+      }
     } catch (err) {
       setError(err instanceof Error ? err.message : "Restore preparation failed");
       setStep("error");
@@ -316,35 +197,6 @@ function RestoreCommandInner({ name }: RestoreCommandProps) {
     }
   }
 
-  async function listBackups(
-    cfg: DeploymentConfig,
-    images: RestoreImages,
-  ): Promise<BackupInfo[]> {
-    const namespace = getNamespace(cfg.name);
-    const releaseName = getReleaseName(cfg.name);
-    const target = dbBackupsTarget(cfg);
-    const result = await runEphemeralJob({
-      name: k8sName(`${releaseName}-backup-list-${Date.now()}`),
-      namespace,
-      serviceAccountName: `${releaseName}-backup`,
-      image: images.rcloneImage,
-      command: [
-        "/bin/sh",
-        "-c",
-        `rclone lsf "dest:${target}/" --dirs-only`,
-      ],
-      env: rcloneEnv(cfg),
-      labels: jobLabels(cfg),
-      timeoutSeconds: 300,
-    });
-
-    const parsed = parseBackups(result.logs);
-    if (parsed.length === 0) {
-      throw new Error("No database backups found in object storage.");
-    }
-    return parsed;
-  }
-
   async function handleRestore() {
     if (!config || !selectedBackup || !restoreImages) return;
     if (confirmation !== config.name) {
@@ -353,28 +205,36 @@ function RestoreCommandInner({ name }: RestoreCommandProps) {
     }
 
     setError(null);
+    await restoreBackup(config, selectedBackup, restoreImages);
+  }
+
+  async function restoreBackup(
+    cfg: DeploymentConfig,
+    backup: StoredBackup,
+    images: BackupImages,
+  ) {
     setStep("restoring");
     let originalReplicas: DeploymentReplica[] = [];
 
     try {
       setStatus((current) => ({ ...current, scaleDown: "running" }));
-      originalReplicas = await scaleDownForRestore(config);
+      originalReplicas = await scaleDownForRestore(cfg);
       setStatus((current) => ({ ...current, scaleDown: "success" }));
 
       setStatus((current) => ({ ...current, restore: "running" }));
-      const result = await runRestoreJob(config, selectedBackup.id, restoreImages);
+      const result = await runRestoreJob(cfg, backup.id, images);
       setLogs(result.logs);
       setStatus((current) => ({ ...current, restore: "success" }));
 
       setStatus((current) => ({ ...current, scaleUp: "running" }));
-      await scaleBackUp(config, originalReplicas);
+      await scaleBackUp(cfg, originalReplicas);
       setStatus((current) => ({ ...current, scaleUp: "success" }));
 
       setStep("complete");
       setTimeout(() => exit(), 8000);
     } catch (err) {
       if (originalReplicas.length > 0) {
-        await scaleBackUp(config, originalReplicas).catch(() => {});
+        await scaleBackUp(cfg, originalReplicas).catch(() => {});
       }
       setError(err instanceof Error ? err.message : "Restore failed");
       setStep("error");
@@ -413,7 +273,7 @@ function RestoreCommandInner({ name }: RestoreCommandProps) {
   async function runRestoreJob(
     cfg: DeploymentConfig,
     backupId: string,
-    images: RestoreImages,
+    images: BackupImages,
   ) {
     const namespace = getNamespace(cfg.name);
     const releaseName = getReleaseName(cfg.name);
@@ -455,7 +315,7 @@ function RestoreCommandInner({ name }: RestoreCommandProps) {
         ].join("\n"),
       ],
       env: pgEnv(cfg, releaseName),
-      labels: jobLabels(cfg),
+      labels: backupJobLabels(cfg, "db-restore"),
       volumeMounts: [{ name: "work", mountPath: "/work" }],
       volumes: [{ name: "work", emptyDir: {} }],
       timeoutSeconds: 3600,
@@ -481,7 +341,8 @@ function RestoreCommandInner({ name }: RestoreCommandProps) {
           <Box marginTop={1}>
             <SelectInput
               items={backups.map((backup) => ({
-                label: backup.label,
+                key: backup.id,
+                label: `${backup.id}  (${formatBackupSize(backup.sizeBytes)})`,
                 value: backup,
               }))}
               onSelect={(item) => {
@@ -0,0 +1,352 @@
+import React, { useEffect, useRef, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  StatusLine,
+  ThemeProvider,
+  useGatedInput,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import {
+  loadDeploymentConfig,
+  loadDeploymentState,
+  recordDeploymentEvent,
+  saveDeploymentConfig,
+} from "../lib/config.js";
+import { secretModeForConfig } from "../lib/deploySequence.js";
+import { finishEvent } from "../lib/deploymentHistory.js";
+import { parseLiveWorkloads } from "../lib/driftReport.js";
+import {
+  forceExternalSecretsSync,
+  setupExternalSecrets,
+} from "../lib/eso.js";
+import {
+  checkClusterAccessible,
+  getWorkloadsJson,
+  isKubectlInstalled,
+} from "../lib/kubernetes.js";
+import { reencryptRealtimeTenants } from "../lib/realtimeRepair.js";
+import {
+  restartWorkloads,
+  rotateConfigSecrets,
+  rotateDatabasePassword,
+  rotationProblem,
+  RotationTarget,
+  ROTATION_TARGET_LABELS,
+  workloadsToRestart,
+} from "../lib/secretRotation.js";
+import { applyDeploymentSecrets } from "../lib/secrets.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+} from "../types/index.js";
+
+interface SecretsRotateCommandProps {
+  name: string;
+  targets: RotationTarget[];
+  /** Skip the confirmation prompt. */
+  force?: boolean;
+}
+
+type Step =
+  | "loading"
+  | "confirm"
+  | "preflight"
+  | "realtime"
+  | "database"
+  | "config"
+  | "secrets"
+  | "restart"
+  | "complete"
+  | "error";
+type Status = "pending" | "running" | "success" | "error" | "skipped";
+
+const STATUS_KEYS: Partial<Record<Step, string>> = {
+  preflight: "preflight",
+  realtime: "realtime",
+  database: "database",
+  config: "config",
+  secrets: "secrets",
+  restart: "restart",
+};
+
+function SecretsRotateCommandInner({
+  name,
+  targets,
+  force,
+}: SecretsRotateCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [step, setStep] = useState<Step>("loading");
+  const [error, setError] = useState<string | null>(null);
+  const [config, setConfig] = useState<DeploymentConfig | null>(null);
+  const [restarted, setRestarted] = useState<string[]>([]);
+  const [status, setStatus] = useState<Record<string, Status>>({
+    preflight: "pending",
+    realtime: targets.includes("jwt") ? "pending" : "skipped",
+    database: targets.includes("dbPassword") ? "pending" : "skipped",
+    config: "pending",
+    secrets: "pending",
+    restart: "pending",
+  });
+
+  // The async run outlives the render it started in; track its step here.
+  const currentStep = useRef<Step>("loading");
+  const begin = (next: Step) => {
+    currentStep.current = next;
+    setStep(next);
+    setStatus((current) => ({ ...current, [STATUS_KEYS[next]!]: "running" }));
+  };
+  const succeed = (key: string) =>
+    setStatus((current) => ({ ...current, [key]: "success" }));
+
+  const fail = (err: unknown) => {
+    setError(err instanceof Error ? err.message : "Secret rotation failed");
+    const failed = STATUS_KEYS[currentStep.current];
+    if (failed) {
+      setStatus((current) => ({ ...current, [failed]: "error" }));
+    }
+    setStep("error");
+  };
+
+  useEffect(() => {
+    (async () => {
+      try {
+        const loaded = await loadDeploymentConfig(name);
+        const problem = rotationProblem(
+          loaded,
+          await loadDeploymentState(name),
+          targets,
+        );
+        if (problem) throw new Error(problem);
+        setConfig(loaded);
+        if (force) {
+          runRotation(loaded);
+        } else {
+          setStep("confirm");
+        }
+      } catch (err) {
+        fail(err);
+      }
+    })();
+  }, []);
+
+  useGatedInput((input, key) => {
+    if (step === "confirm" && config) {
+      if (key.return) {
+        runRotation(config);
+      } else if (key.escape) {
+        exit();
+      }
+    } else if (step === "error" && (key.escape || key.return)) {
+      exit();
+    }
+  });
+
+  async function runRotation(current: DeploymentConfig) {
+    const run = { action: "rotate-secrets" as const, startedAt: new Date() };
+    const namespace = getNamespace(current.name);
+    try {
+      begin("preflight");
+      if (!(await isKubectlInstalled())) {
+        throw new Error(
+          "kubectl is not installed. Please install kubectl first.",
+        );
+      }
+      const clusterError = await checkClusterAccessible();
+      if (clusterError) {
+        throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+      }
+      succeed("preflight");
+
+      const rotated = rotateConfigSecrets(current, targets);
+
+      // Saved before anything changes, so a new credential never exists
+      // only in this process: if a later step fails, config.yaml still
+      // holds what the database or Realtime may already be using.
+      begin("config");
+      await saveDeploymentConfig(rotated);
+      succeed("config");
+
+      // Both run against the database with the credentials it has now, so
+      // they come before the secrets switch to the new ones.
+      if (targets.includes("jwt")) {
+        begin("realtime");
+        await reencryptRealtimeTenants(rotated);
+        succeed("realtime");
+      }
+      if (targets.includes("dbPassword")) {
+        begin("database");
+        await rotateDatabasePassword(
+          rotated,
+          rotated.database.supabaseDbPassword!,
+        );
+        succeed("database");
+      }
+
+      begin("secrets");
+      if (secretModeForConfig(rotated) === "k8s") {
+        await applyDeploymentSecrets(rotated, namespace);
+      } else {
+        await setupExternalSecrets(rotated, { overwriteSecrets: true });
+        await forceExternalSecretsSync(rotated);
+      }
+      succeed("secrets");
+
+      begin("restart");
+      setRestarted(
+        await restartWorkloads(
+          namespace,
+          workloadsToRestart(
+            parseLiveWorkloads(await getWorkloadsJson(namespace)),
+            getReleaseName(current.name),
+            targets,
+          ),
+        ),
+      );
+      succeed("restart");
+
+      await recordDeploymentEvent(name, finishEvent(run));
+      setStep("complete");
+      setTimeout(() => exit(), 5000);
+    } catch (err) {
+      await recordDeploymentEvent(name, finishEvent({ ...run, error: err }));
+      fail(err);
+    }
+  }
+
+  const steps = (
+    <>
+      <StatusLine status={status.preflight} label="Preflight checks" />
+      <StatusLine status={status.config} label="Save config.yaml" />
+      <StatusLine
+        status={status.realtime}
+        label="Re-encrypt Realtime tenant JWT secret"
+      />
+      <StatusLine
+        status={status.database}
+        label="Change Postgres role passwords"
+      />
+      <StatusLine status={status.secrets} label="Update secrets" />
+      <StatusLine status={status.restart} label="Restart affected workloads" />
+    </>
+  );
+
+  if (step === "error") {
+    return (
+      <BorderBox title="Secret Rotation Failed">
+        <Box flexDirection="column" marginY={1}>
+          {steps}
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.error} bold>✗ Error</Text>
+            <Text color={colors.error}>{error}</Text>
+            {(status.realtime === "error" || status.database === "error") && (
+              <Text color={colors.muted}>
+                config.yaml already has the new values but the{" "}
+                {status.database === "error"
+                  ? "database still accepts only the old password"
+                  : "Realtime tenant still uses the old JWT secret"}
+                ; run `rulebricks secrets rotate {name}` again with the same
+                flags before any deploy, which would push secrets the
+                database doesn't accept yet.
+              </Text>
+            )}
+            {(status.secrets === "error" || status.restart === "error") && (
+              <Text color={colors.muted}>
+                config.yaml and the database already have the new values;
+                run `rulebricks deploy {name}` to finish applying them.
+              </Text>
+            )}
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "confirm") {
+    return (
+      <BorderBox title={`Rotate secrets for ${name}`}>
+        <Box flexDirection="column" marginY={1}>
+          <Text>These credentials will be replaced:</Text>
+          {targets.map((target) => (
+            <Text key={target} color={colors.accent}>
+              {"  "}• {ROTATION_TARGET_LABELS[target]}
+            </Text>
+          ))}
+          {targets.includes("jwt") && (
+            <Box marginTop={1}>
+              <Text color={colors.warning}>
+                ⚠ Every active session is invalidated: users must sign in
+                again, and clients holding the old anon or service key stop
+                working.
+              </Text>
+            </Box>
+          )}
+          <Text color={colors.warning}>
+            ⚠ The affected workloads restart
+            {targets.includes("dbPassword") ? ", including the database" : ""}.
+          </Text>
+          <Box marginTop={1}>
+            <Text color={colors.warning}>
+              Press Enter to confirm, Esc to cancel
+            </Text>
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "complete") {
+    return (
+      <BorderBox title="Secret Rotation Complete">
+        <Box flexDirection="column" marginY={1}>
+          {steps}
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.success} bold>
+              ✓ Rotated{" "}
+              {targets
+                .map((target) => ROTATION_TARGET_LABELS[target].toLowerCase())
+                .join(", ")}
+            </Text>
+            <Text color={colors.muted}>
+              Restarted {restarted.length} workload
+              {restarted.length === 1 ? "" : "s"}.
+            </Text>
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={`Rotating secrets for ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        {steps}
+        <Box marginTop={1}>
+          <Spinner
+            label={
+              step === "restart"
+                ? "Waiting for workloads to restart..."
+                : "Rotating secrets..."
+            }
+          />
+        </Box>
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function SecretsRotateCommand(props: SecretsRotateCommandProps) {
+  return (
+    <ThemeProvider theme="destroy">
+      <Logo />
+      <CommandApprovalProvider>
+        <SecretsRotateCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
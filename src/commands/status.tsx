@@ -12,6 +12,7 @@ import {
   getServiceStatus,
   getIngressStatus,
   getCertificateStatus,
+  getClusterResourceUsage,
   PodStatus,
   ServiceStatus,
   IngressStatus,
@@ -22,14 +23,28 @@ import {
   DeploymentState,
 } from "../types/index.js";
 import { CommandTheme } from "../lib/theme.js";
+import {
+  formatCpu,
+  formatMemory,
+  percentOf,
+  type NodeResourceUsage,
+  type ResourceAmount,
+  type UnschedulablePod,
+} from "../lib/resourceUsage.js";
 import {
   arePodsHealthy,
   DeploymentHealth,
   loadDeploymentHealth,
+  overallStatus,
 } from "../lib/deploymentHealth.js";
 
 interface StatusCommandProps {
   name: string;
+  /** Re-check every intervalMs until healthy (or Ctrl+C). */
+  watch?: boolean;
+  intervalMs?: number;
+  /** Add per-node CPU/memory and unschedulable pods. */
+  resources?: boolean;
 }
 
 interface ClusterStatus {
@@ -38,6 +53,13 @@ interface ClusterStatus {
   ingresses: IngressStatus[];
   certificates: CertificateStatus[];
   version: string | null;
+  resources?: ClusterResources;
+}
+
+interface ClusterResources {
+  nodes: NodeResourceUsage[];
+  unschedulable: UnschedulablePod[];
+  error?: string;
 }
 
 interface LoadedData {
@@ -45,42 +67,47 @@ interface LoadedData {
   state: DeploymentState | null;
   health: DeploymentHealth;
   clusterStatus: ClusterStatus;
+  checkedAt: Date;
+}
+
+interface WatchInfo {
+  intervalMs: number;
+  /** Set while the cluster API is unreachable and older data is shown. */
+  staleSince: Date | null;
+  staleError: string | null;
+  settled: boolean;
+}
+
+// Watching stops once every pod is up, the URL answers and certs are issued.
+function isSettled(data: LoadedData): boolean {
+  return (
+    data.health.kind === "online" &&
+    data.clusterStatus.certificates.every((c) => c.ready)
+  );
 }
 
 function StatusCommandInner({
   name,
   data,
-}: StatusCommandProps & { data: LoadedData }) {
+  watch,
+}: {
+  name: string;
+  data: LoadedData;
+  watch?: WatchInfo;
+}) {
   const { exit } = useApp();
   const { colors } = useTheme();
 
   const { config, state, health, clusterStatus } = data;
 
   useEffect(() => {
-    // Auto-exit after displaying
+    // Auto-exit after displaying; watch mode exits when it settles instead.
+    if (watch) return;
     const timer = setTimeout(() => exit(), 10000);
     return () => clearTimeout(timer);
-  }, [exit]);
-
-  // Determine overall status based on deployment state and pod health
-  const getOverallStatus = () => {
-    if (health.kind === "online") return "healthy";
-    if (health.kind === "installed-unreachable") return "unreachable";
-    if (health.kind === "installed-degraded") return "degraded";
-    if (health.kind === "cluster-unreachable") return "cluster-unreachable";
-    if (health.kind === "destroyed") return "destroyed";
-    if (health.kind === "not-installed") {
-      if (state?.status === "failed") return "failed";
-      if (state?.status === "pending") return "pending";
-      if (state?.status === "deploying") return "deploying";
-      if (state?.status === "waiting-dns") return "waiting-dns";
-      return state ? "not-installed" : "not-deployed";
-    }
-
-    return "unknown";
-  };
+  }, [exit, watch]);
 
-  const overallStatus = getOverallStatus();
+  const overall = overallStatus(health);
 
   const statusDisplay: Record<
     string,
@@ -108,7 +135,7 @@ function StatusCommandInner({
     unknown: { icon: "?", label: "Unknown", color: colors.muted },
   };
 
-  const status = statusDisplay[overallStatus] || statusDisplay["unknown"];
+  const status = statusDisplay[overall] || statusDisplay["unknown"];
 
   return (
     <BorderBox title={`Status: ${name}`}>
@@ -144,7 +171,7 @@ function StatusCommandInner({
         </Section>
 
         {/* Not Deployed message */}
-        {overallStatus === "not-deployed" && (
+        {overall === "not-deployed" && (
           <Box marginY={1} flexDirection="column">
             <Text color={colors.muted}>
               This configuration has not been deployed yet.
@@ -254,40 +281,169 @@ function StatusCommandInner({
                 ))
               )}
             </Section>
+
+            {clusterStatus.resources && (
+              <ResourcesSection resources={clusterStatus.resources} />
+            )}
           </>
         )}
 
+        {watch?.staleSince && (
+          <Box marginTop={1} flexDirection="column">
+            <Text color={colors.warning}>
+              ⚠ Cluster API unreachable; showing status from{" "}
+              {watch.staleSince.toLocaleTimeString()} and retrying.
+            </Text>
+            {watch.staleError && (
+              <Text color={colors.muted}>{watch.staleError}</Text>
+            )}
+          </Box>
+        )}
+
         <Box marginTop={1}>
-          <Text color={colors.muted}>Press Ctrl+C to exit</Text>
+          {!watch ? (
+            <Text color={colors.muted}>Press Ctrl+C to exit</Text>
+          ) : watch.settled ? (
+            <Text color={colors.success}>
+              ✓ All components healthy at {data.checkedAt.toLocaleTimeString()}
+            </Text>
+          ) : (
+            <Text color={colors.muted}>
+              Updated {data.checkedAt.toLocaleTimeString()} · refreshing every{" "}
+              {watch.intervalMs / 1000}s · Press Ctrl+C to exit
+            </Text>
+          )}
         </Box>
       </Box>
     </BorderBox>
   );
 }
 
+function formatAmount(
+  amount: ResourceAmount,
+  format: (value: number) => string,
+): string {
+  const requested = `${format(amount.requested)} requested (${percentOf(amount.requested, amount.allocatable)}%)`;
+  const used =
+    amount.used === null
+      ? ""
+      : `, ${format(amount.used)} used (${percentOf(amount.used, amount.allocatable)}%)`;
+  return `${requested}${used} of ${format(amount.allocatable)}`;
+}
+
+function ResourcesSection({ resources }: { resources: ClusterResources }) {
+  const { colors } = useTheme();
+  // Past this share of allocatable requested, new pods start failing to fit.
+  const tight = (amount: ResourceAmount) =>
+    percentOf(amount.requested, amount.allocatable) >= 90;
+
+  return (
+    <Section title="Node Resources">
+      {resources.error ? (
+        <Text color={colors.warning}>{resources.error}</Text>
+      ) : (
+        resources.nodes.map((node) => (
+          <Box key={node.name} flexDirection="column">
+            <Text>{node.name}</Text>
+            <Text color={tight(node.cpu) ? colors.warning : colors.muted}>
+              {"  "}CPU: {formatAmount(node.cpu, formatCpu)}
+            </Text>
+            <Text color={tight(node.memory) ? colors.warning : colors.muted}>
+              {"  "}Memory: {formatAmount(node.memory, formatMemory)}
+            </Text>
+          </Box>
+        ))
+      )}
+      {!resources.error &&
+        resources.nodes.length > 0 &&
+        resources.nodes[0].cpu.used === null && (
+          <Text color={colors.muted}>
+            Live usage unavailable (metrics-server not responding)
+          </Text>
+        )}
+      {resources.unschedulable.map((pod) => (
+        <Box key={pod.name} flexDirection="column">
+          <Text color={colors.error}>✗ {pod.name} cannot be scheduled</Text>
+          <Text color={colors.muted}>
+            {"  "}
+            {pod.message}
+          </Text>
+        </Box>
+      ))}
+    </Section>
+  );
+}
+
 /**
- * Loader component that fetches data and determines the appropriate theme
+ * Loader component that fetches data and determines the appropriate theme.
+ * With --watch it re-runs the checks on a timer, keeping the last good
+ * cluster data on screen while the API is briefly unreachable.
  */
-function StatusLoader({ name }: StatusCommandProps) {
+function StatusLoader({
+  name,
+  watch = false,
+  intervalMs = 5000,
+  resources = false,
+}: StatusCommandProps) {
+  const { exit } = useApp();
   const [loading, setLoading] = useState(true);
   const [data, setData] = useState<LoadedData | null>(null);
   const [error, setError] = useState<string | null>(null);
   const [theme, setTheme] = useState<CommandTheme>("status");
+  const [stale, setStale] = useState<{ since: Date; error: string } | null>(
+    null,
+  );
+  const [settled, setSettled] = useState(false);
 
   useEffect(() => {
-    loadStatus();
+    let cancelled = false;
+    let timer: ReturnType<typeof setTimeout> | undefined;
+    let lastGood: LoadedData | null = null;
+
+    const tick = async (first: boolean) => {
+      const next = await loadStatus(first);
+      if (cancelled) return;
+      if (!watch) return;
+
+      const clusterError = next?.health.clusterError;
+      if (clusterError && lastGood) {
+        // Keep the last good view up instead of blanking it on a blip.
+        const since = lastGood.checkedAt;
+        setStale((prev) => ({
+          since: prev?.since ?? since,
+          error: clusterError,
+        }));
+      } else if (next) {
+        if (!clusterError) lastGood = next;
+        setStale(null);
+        setData(next);
+        if (isSettled(next)) {
+          setSettled(true);
+          timer = setTimeout(() => exit(), 1000);
+          return;
+        }
+      }
+      // Chain timeouts so a slow check never overlaps the next one.
+      timer = setTimeout(() => tick(false), intervalMs);
+    };
+    tick(true);
+
+    return () => {
+      cancelled = true;
+      if (timer) clearTimeout(timer);
+    };
   }, []);
 
-  async function loadStatus() {
+  async function loadStatus(first: boolean): Promise<LoadedData | null> {
     try {
       const health = await loadDeploymentHealth(name, {
-        refreshKubeconfig: true,
+        refreshKubeconfig: first,
       });
 
       if (!health.config) {
         setError(health.configError || "Invalid deployment config");
         setLoading(false);
-        return;
+        return null;
       }
 
       const selectedTheme: CommandTheme =
@@ -306,7 +462,18 @@ function StatusLoader({ name }: StatusCommandProps) {
             getCertificateStatus(health.namespace),
           ]);
 
-      setData({
+      let clusterResources: ClusterResources | undefined;
+      if (resources && !health.clusterError) {
+        clusterResources = await getClusterResourceUsage(
+          health.namespace,
+        ).catch((err) => ({
+          nodes: [],
+          unschedulable: [],
+          error: err instanceof Error ? err.message : String(err),
+        }));
+      }
+
+      const loaded: LoadedData = {
         config: health.config,
         state: health.state,
         health,
@@ -316,12 +483,21 @@ function StatusLoader({ name }: StatusCommandProps) {
           ingresses,
           certificates,
           version: health.helmVersion,
+          resources: clusterResources,
         },
-      });
+        checkedAt: new Date(),
+      };
+      if (!watch) setData(loaded);
+      setError(null);
       setLoading(false);
+      return loaded;
     } catch (err) {
-      setError(err instanceof Error ? err.message : "Failed to load status");
-      setLoading(false);
+      // A watch keeps polling through failures; a one-shot status reports them.
+      if (!watch || first) {
+        setError(err instanceof Error ? err.message : "Failed to load status");
+        setLoading(false);
+      }
+      return null;
     }
   }
 
@@ -356,7 +532,20 @@ function StatusLoader({ name }: StatusCommandProps) {
   return (
     <ThemeProvider theme={theme}>
       <Logo />
-      <StatusCommandInner name={name} data={data} />
+      <StatusCommandInner
+        name={name}
+        data={data}
+        watch={
+          watch
+            ? {
+                intervalMs,
+                staleSince: stale?.since ?? null,
+                staleError: stale?.error ?? null,
+                settled,
+              }
+            : undefined
+        }
+      />
     </ThemeProvider>
   );
 }
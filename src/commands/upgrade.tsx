@@ -1,4 +1,10 @@
-import React, { useState, useEffect, useCallback } from "react";
+import React, {
+  useState,
+  useEffect,
+  useCallback,
+  useMemo,
+  useRef,
+} from "react";
 import { Box, Text, useApp, useInput } from "ink";
 import SelectInput from "ink-select-input";
 import {
@@ -11,13 +17,23 @@ import {
 import {
   loadDeploymentConfig,
   loadDeploymentState,
+  recordDeploymentEvent,
   updateDeploymentStatus,
   getHelmValuesPath,
+  writePrivateFile,
 } from "../lib/config.js";
+import {
+  createProgressReporter,
+  ProgressEvent,
+  stepTransitionEvents,
+} from "../lib/progress.js";
+import { finishEvent } from "../lib/deploymentHistory.js";
 import {
   upgradeChart,
   dryRunUpgrade,
+  getDeployedRevision,
   getInstalledChartVersion,
+  rollbackRelease,
 } from "../lib/helm.js";
 import {
   formatDate,
@@ -45,6 +61,10 @@ interface UpgradeCommandProps {
   name: string;
   targetVersion?: string;
   dryRun?: boolean;
+  /** Leave a failed upgrade in place instead of rolling it back. */
+  noRollback?: boolean;
+  // Receives progress events (--health-port).
+  onProgressEvent?: (event: ProgressEvent) => void;
 }
 
 function hasSameVersionHpsPatch(
@@ -89,6 +109,8 @@ function UpgradeCommandInner({
   name,
   targetVersion,
   dryRun,
+  noRollback,
+  onProgressEvent,
 }: UpgradeCommandProps) {
   const { exit } = useApp();
   const { colors } = useTheme();
@@ -100,6 +122,8 @@ function UpgradeCommandInner({
   );
   const [error, setError] = useState<string | null>(null);
   const [dryRunOutput, setDryRunOutput] = useState<string | null>(null);
+  // Revision a failed upgrade was rolled back to.
+  const [rolledBackTo, setRolledBackTo] = useState<number | null>(null);
   // Store actual deployed HPS version separately (may differ from expected)
   const [deployedHpsVersion, setDeployedHpsVersion] = useState<string | null>(
     null,
@@ -120,6 +144,23 @@ function UpgradeCommandInner({
     return (await getInstalledChartVersion(releaseName, namespace)) || undefined;
   }
 
+  const reporter = useMemo(
+    () => createProgressReporter("tty", { onEvent: onProgressEvent }),
+    [onProgressEvent],
+  );
+  const previousStep = useRef<UpgradeStep | null>(null);
+
+  useEffect(() => {
+    for (const [key, event] of stepTransitionEvents(
+      "upgrade",
+      previousStep.current,
+      step,
+    )) {
+      reporter.emit(key, event);
+    }
+    previousStep.current = step;
+  }, [step]);
+
   useEffect(() => {
     loadVersions();
   }, []);
@@ -215,7 +256,7 @@ function UpgradeCommandInner({
       (values.global as Record<string, unknown>).version = version.version;
 
       // Save updated values
-      await fs.writeFile(valuesPath, YAML.stringify(values), "utf8");
+      await writePrivateFile(valuesPath, YAML.stringify(values));
     } catch (err) {
       throw new Error(`Failed to update Helm values: ${err}`);
     }
@@ -225,18 +266,34 @@ function UpgradeCommandInner({
     if (!selectedVersion || !config) return;
 
     setStep("upgrading");
+    const releaseName = getReleaseName(name);
+    let namespace = getNamespace(name);
+    let valuesSnapshot: string | null = null;
+    let previousRevision: number | null = null;
+    // Only a release helm has touched needs rolling back.
+    let chartUpgradeStarted = false;
+    const startedAt = new Date();
+    let fromVersion: string | undefined;
     try {
-      // Update Helm values with the unified product version
-      await updateHelmValuesWithVersion(selectedVersion);
-
       const state = await loadDeploymentState(name);
+      fromVersion = state?.application?.version;
       // Use namespace from state if available (backwards compat), otherwise compute from deployment name
-      const namespace = state?.application?.namespace || getNamespace(name);
-      const releaseName = getReleaseName(name);
+      namespace = state?.application?.namespace || namespace;
+
+      // Captured before anything changes, so a failure anywhere below can put
+      // back exactly the release and values.yaml that were running.
+      valuesSnapshot = await fs
+        .readFile(getHelmValuesPath(name), "utf8")
+        .catch(() => null);
+      previousRevision = await getDeployedRevision(releaseName, namespace);
+
+      // Update Helm values with the unified product version
+      await updateHelmValuesWithVersion(selectedVersion);
 
       // Perform the upgrade
       const chartVersion = await resolvePinnedChartVersion(namespace, releaseName);
 
+      chartUpgradeStarted = true;
       await upgradeChart(name, {
         releaseName,
         namespace,
@@ -271,11 +328,52 @@ function UpgradeCommandInner({
           url: `https://${config.domain}`,
         },
       });
+      await recordDeploymentEvent(
+        name,
+        finishEvent({
+          action: "upgrade",
+          startedAt,
+          fromVersion,
+          toVersion: selectedVersion.version,
+        }),
+      );
 
       setStep("complete");
       setTimeout(() => exit(), 5000);
     } catch (err) {
-      setError(err instanceof Error ? err.message : "Upgrade failed");
+      let message = err instanceof Error ? err.message : "Upgrade failed";
+      if (chartUpgradeStarted && noRollback) {
+        message += "\n\nLeft in place for debugging (--no-rollback).";
+      } else {
+        if (chartUpgradeStarted && previousRevision !== null) {
+          try {
+            await rollbackRelease(releaseName, namespace, previousRevision);
+            setRolledBackTo(previousRevision);
+          } catch (rollbackErr) {
+            message += `\n\n${rollbackErr instanceof Error ? rollbackErr.message : "Rollback failed"}`;
+          }
+        }
+        // values.yaml goes back even when the rollback fails, so the next
+        // upgrade starts from the settings that last deployed cleanly.
+        if (valuesSnapshot !== null) {
+          await writePrivateFile(getHelmValuesPath(name), valuesSnapshot).catch(
+            (writeErr) => {
+              message += `\n\nCould not restore values.yaml: ${writeErr instanceof Error ? writeErr.message : String(writeErr)}`;
+            },
+          );
+        }
+      }
+      await recordDeploymentEvent(
+        name,
+        finishEvent({
+          action: "upgrade",
+          startedAt,
+          fromVersion,
+          toVersion: selectedVersion.version,
+          error: err ?? message,
+        }),
+      ).catch(() => {});
+      setError(message);
       setStep("error");
     }
   }
@@ -322,6 +420,21 @@ function UpgradeCommandInner({
       <BorderBox title="Upgrade Failed">
         <Box flexDirection="column" marginY={1}>
           <Text color={colors.error}>✗ {error}</Text>
+          {rolledBackTo !== null && (
+            <Box marginTop={1} flexDirection="column">
+              <Text color={colors.warning}>
+                The release was rolled back to revision {rolledBackTo}
+                {versionInfo?.current
+                  ? ` (${formatVersionDisplay(versionInfo.current.version)})`
+                  : ""}
+                .
+              </Text>
+              <Text color={colors.muted}>
+                Helm does not undo database migrations; restore a backup if
+                the new version already migrated the database.
+              </Text>
+            </Box>
+          )}
         </Box>
       </BorderBox>
     );
@@ -1,4 +1,10 @@
-import React, { useState, useEffect, useCallback } from "react";
+import React, {
+  useState,
+  useEffect,
+  useCallback,
+  useMemo,
+  useRef,
+} from "react";
 import { Box, Text, useApp, useInput } from "ink";
 import SelectInput from "ink-select-input";
 import fs from "fs/promises";
@@ -12,15 +18,26 @@ import {
 import {
   loadDeploymentConfig,
   loadDeploymentState,
+  recordDeploymentEvent,
   updateDeploymentStatus,
   getHelmValuesPath,
   loadHelmValues,
+  pinConfigChartVersion,
+  writePrivateFile,
 } from "../lib/config.js";
+import {
+  createProgressReporter,
+  ProgressEvent,
+  stepTransitionEvents,
+} from "../lib/progress.js";
+import { finishEvent } from "../lib/deploymentHistory.js";
 import {
   fetchAvailableChartVersions,
   getInstalledChartVersion,
   upgradeChart,
   dryRunUpgrade,
+  releasesBetween,
+  requiredUpgradeStops,
 } from "../lib/helm.js";
 import {
   deriveTlsEnabled,
@@ -40,10 +57,19 @@ import {
 
 const CHART_RELEASES_URL = "https://github.com/rulebricks/helm/releases";
 
+// Release notes lines shown per release on the confirm screen.
+const NOTES_PREVIEW_LINES = 6;
+
 interface ChartUpgradeCommandProps {
   name: string;
   /** Skip the selector and target this chart version directly. */
   targetVersion?: string;
+  /** Proceed even when the upgrade skips a required intermediate release. */
+  force?: boolean;
+  /** Leave a failed upgrade in place instead of rolling it back. */
+  noRollback?: boolean;
+  // Receives progress events (--health-port).
+  onProgressEvent?: (event: ProgressEvent) => void;
 }
 
 type ChartUpgradeStep =
@@ -58,6 +84,9 @@ type ChartUpgradeStep =
 function ChartUpgradeCommandInner({
   name,
   targetVersion,
+  force,
+  noRollback,
+  onProgressEvent,
 }: ChartUpgradeCommandProps) {
   const { exit } = useApp();
   const { colors } = useTheme();
@@ -71,10 +100,31 @@ function ChartUpgradeCommandInner({
   // Raw values.yaml content captured before regeneration; written back on any
   // non-success path so the local file always describes the deployed chart.
   const [valuesSnapshot, setValuesSnapshot] = useState<string | null>(null);
+  // Intermediate releases a --force upgrade skips, and the releases whose
+  // notes apply between the installed and target chart.
+  const [skippedStops, setSkippedStops] = useState<ChartVersion[]>([]);
+  const [releaseNotes, setReleaseNotes] = useState<ChartVersion[]>([]);
 
   const namespace = getNamespace(name);
   const releaseName = getReleaseName(name);
 
+  const reporter = useMemo(
+    () => createProgressReporter("tty", { onEvent: onProgressEvent }),
+    [onProgressEvent],
+  );
+  const previousStep = useRef<ChartUpgradeStep | null>(null);
+
+  useEffect(() => {
+    for (const [key, event] of stepTransitionEvents(
+      "upgrade",
+      previousStep.current,
+      step,
+    )) {
+      reporter.emit(key, event);
+    }
+    previousStep.current = step;
+  }, [step]);
+
   useEffect(() => {
     load();
   }, []);
@@ -106,7 +156,7 @@ function ChartUpgradeCommandInner({
           digest: "",
         };
         setSelected(target);
-        await prepare(cfg, target);
+        await prepare(cfg, target, installed, versions);
         return;
       }
 
@@ -129,9 +179,7 @@ function ChartUpgradeCommandInner({
 
   async function restoreValuesSnapshot(snapshot: string | null) {
     if (snapshot === null) return;
-    await fs
-      .writeFile(getHelmValuesPath(name), snapshot, "utf8")
-      .catch(() => {});
+    await writePrivateFile(getHelmValuesPath(name), snapshot).catch(() => {});
   }
 
   /**
@@ -139,7 +187,31 @@ function ChartUpgradeCommandInner({
    * a helm dry run. Any failure restores the values snapshot; nothing has
    * touched the cluster yet.
    */
-  async function prepare(cfg: DeploymentConfig, target: ChartVersion) {
+  async function prepare(
+    cfg: DeploymentConfig,
+    target: ChartVersion,
+    installed: string | null,
+    versions: ChartVersion[],
+  ) {
+    if (installed) {
+      const stops = requiredUpgradeStops(installed, target.version, versions);
+      if (stops.length > 0 && !force) {
+        const stopList = stops.map((v) => v.version).join(", ");
+        setError(
+          `Upgrading chart ${installed} to ${target.version} skips ${stopList}, which must be installed first. ` +
+            `Run "rulebricks upgrade ${name} --chart --version ${stops[0].version}" and continue from there, or pass --force to upgrade directly.`,
+        );
+        setStep("error");
+        return;
+      }
+      setSkippedStops(stops);
+      setReleaseNotes(
+        releasesBetween(installed, target.version, versions).filter(
+          (release) => release.notes,
+        ),
+      );
+    }
+
     setStep("preparing");
 
     let snapshot: string | null = null;
@@ -187,6 +259,12 @@ function ChartUpgradeCommandInner({
   async function performUpgrade() {
     if (!selected || !config) return;
     setStep("upgrading");
+    const run = {
+      action: "upgrade-chart" as const,
+      startedAt: new Date(),
+      fromVersion: installedVersion ?? undefined,
+      toVersion: selected.version,
+    };
 
     try {
       // Values were regenerated in ref-based secret mode, so the referenced
@@ -205,7 +283,7 @@ function ChartUpgradeCommandInner({
         namespace,
         version: selected.version,
         wait: true,
-        atomic: true,
+        atomic: !noRollback,
       });
 
       const state = await loadDeploymentState(name);
@@ -217,15 +295,32 @@ function ChartUpgradeCommandInner({
           url: state?.application?.url || `https://${config.domain}`,
         },
       });
+      // Deploys install the config's chartVersion when one is pinned; move
+      // the pin with the upgrade so the next deploy doesn't roll it back.
+      if (config.chartVersion) {
+        await pinConfigChartVersion(name, selected.version);
+      }
+      await recordDeploymentEvent(name, finishEvent(run));
 
       setStep("complete");
       setTimeout(() => exit(), 5000);
     } catch (err) {
+      const message =
+        err instanceof Error ? err.message : "Chart upgrade failed";
+      await recordDeploymentEvent(
+        name,
+        finishEvent({ ...run, error: err ?? message }),
+      ).catch(() => {});
+      if (noRollback) {
+        setError(`${message}\n\nLeft in place for debugging (--no-rollback).`);
+        setStep("error");
+        return;
+      }
       // --atomic already rolled the release back; restore values.yaml so the
       // local files match the still-running previous chart.
       await restoreValuesSnapshot(valuesSnapshot);
       setRolledBack(true);
-      setError(err instanceof Error ? err.message : "Chart upgrade failed");
+      setError(message);
       setStep("error");
     }
   }
@@ -235,10 +330,10 @@ function ChartUpgradeCommandInner({
       const version = available.find((v) => v.version === item.value);
       if (version && config) {
         setSelected(version);
-        prepare(config, version);
+        prepare(config, version, installedVersion, available);
       }
     },
-    [available, config],
+    [available, config, installedVersion],
   );
 
   useInput((_input, key) => {
@@ -347,14 +442,53 @@ function ChartUpgradeCommandInner({
             </Text>
           </Box>
 
+          {skippedStops.length > 0 && (
+            <Box marginTop={1}>
+              <Text color={colors.warning}>
+                ⚠ --force: skipping{" "}
+                {skippedStops.map((v) => v.version).join(", ")}. Migrations
+                that expect those releases to have run may fail.
+              </Text>
+            </Box>
+          )}
+
+          {releaseNotes.length > 0 && (
+            <Box marginTop={1} flexDirection="column">
+              <Text bold>Release notes:</Text>
+              {releaseNotes.map((release) => {
+                const lines = (release.notes ?? "")
+                  .split("\n")
+                  .filter((line) => line.trim());
+                return (
+                  <Box key={release.version} flexDirection="column">
+                    <Text color={colors.accent}>{release.version}</Text>
+                    {lines.slice(0, NOTES_PREVIEW_LINES).map((line, i) => (
+                      <Text key={i} color={colors.muted}>
+                        {"  "}
+                        {line}
+                      </Text>
+                    ))}
+                    {lines.length > NOTES_PREVIEW_LINES && (
+                      <Text color={colors.muted}>
+                        {"  "}… {CHART_RELEASES_URL}
+                      </Text>
+                    )}
+                  </Box>
+                );
+              })}
+            </Box>
+          )}
+
           <Box marginTop={1} flexDirection="column">
             <Text color={colors.warning}>
               ⚠ Infrastructure components (ingress, certificates, monitoring)
               may restart during the upgrade.
             </Text>
             <Text color={colors.muted}>
-              This can cause a brief period of downtime. If the upgrade fails,
-              Helm automatically rolls back to the current version.
+              This can cause a brief period of downtime.{" "}
+              {noRollback
+                ? "With --no-rollback, a failed upgrade is left in place."
+                : "If the upgrade fails, Helm automatically rolls back to the current version."}
             </Text>
           </Box>
 
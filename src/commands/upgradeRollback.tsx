@@ -0,0 +1,334 @@
+import React, { useEffect, useState } from "react";
+import { Box, Text, useApp, useInput } from "ink";
+import YAML from "yaml";
+import {
+  BorderBox,
+  Spinner,
+  ThemeProvider,
+  useTheme,
+  Logo,
+} from "../components/common/index.js";
+import {
+  getHelmValuesPath,
+  loadDeploymentConfig,
+  loadDeploymentState,
+  recordDeploymentEvent,
+  revertConfigVersions,
+  updateDeploymentStatus,
+  writePrivateFile,
+} from "../lib/config.js";
+import {
+  fetchAvailableChartVersions,
+  getReleaseHistory,
+  getReleaseUserValues,
+  previousServedRevision,
+  releasesBetween,
+  rollbackRelease,
+  type ReleaseRevision,
+} from "../lib/helm.js";
+import { formatVersionDisplay } from "../lib/dockerHub.js";
+import { ensureClusterContext } from "../lib/deploymentHealth.js";
+import { finishEvent } from "../lib/deploymentHistory.js";
+import {
+  ChartVersion,
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+} from "../types/index.js";
+
+interface UpgradeRollbackCommandProps {
+  name: string;
+  /** Also point config.yaml's versions at the rolled-back release. */
+  revertConfig?: boolean;
+  /** Roll back in kubectl's current context even if it's another cluster. */
+  useCurrentContext?: boolean;
+}
+
+type RollbackStep =
+  | "loading"
+  | "confirm"
+  | "rolling-back"
+  | "complete"
+  | "error";
+
+interface RevisionInfo extends ReleaseRevision {
+  /** global.version the revision was installed with. */
+  appVersion: string | null;
+}
+
+async function withAppVersion(
+  releaseName: string,
+  namespace: string,
+  revision: ReleaseRevision,
+): Promise<RevisionInfo> {
+  const values = await getReleaseUserValues(
+    releaseName,
+    namespace,
+    revision.revision,
+  );
+  const global = values?.global as { version?: unknown } | undefined;
+  return {
+    ...revision,
+    appVersion: typeof global?.version === "string" ? global.version : null,
+  };
+}
+
+function describeRevision(revision: RevisionInfo): string {
+  const parts = [
+    revision.appVersion ? formatVersionDisplay(revision.appVersion) : null,
+    revision.chartVersion ? `chart ${revision.chartVersion}` : null,
+  ].filter(Boolean);
+  const detail = parts.length > 0 ? ` (${parts.join(", ")})` : "";
+  return `revision ${revision.revision}${detail}`;
+}
+
+function UpgradeRollbackCommandInner({
+  name,
+  revertConfig,
+  useCurrentContext,
+}: UpgradeRollbackCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [step, setStep] = useState<RollbackStep>("loading");
+  const [error, setError] = useState<string | null>(null);
+  const [config, setConfig] = useState<DeploymentConfig | null>(null);
+  const [current, setCurrent] = useState<RevisionInfo | null>(null);
+  const [target, setTarget] = useState<RevisionInfo | null>(null);
+  // Chart releases whose changes the rollback undoes, oldest first.
+  const [undone, setUndone] = useState<ChartVersion[]>([]);
+
+  const releaseName = getReleaseName(name);
+
+  useEffect(() => {
+    load();
+  }, []);
+
+  async function load() {
+    try {
+      const cfg = await loadDeploymentConfig(name);
+      setConfig(cfg);
+      if (!useCurrentContext) {
+        const contextError = await ensureClusterContext(cfg);
+        if (contextError) {
+          throw new Error(
+            `${contextError} Switch contexts, or pass --use-current-context to roll back there anyway.`,
+          );
+        }
+      }
+      const state = await loadDeploymentState(name);
+      const namespace = state?.application?.namespace || getNamespace(name);
+
+      const pair = previousServedRevision(
+        await getReleaseHistory(releaseName, namespace),
+      );
+      if (!pair) {
+        throw new Error(
+          `${releaseName} has no earlier revision to roll back to.`,
+        );
+      }
+      setCurrent(await withAppVersion(releaseName, namespace, pair.current));
+      setTarget(await withAppVersion(releaseName, namespace, pair.target));
+
+      const from = pair.target.chartVersion;
+      const to = pair.current.chartVersion;
+      if (from && to && from !== to) {
+        // Release list is informational; offline, the rollback still runs.
+        const available = await fetchAvailableChartVersions().catch(() => []);
+        setUndone(releasesBetween(from, to, available));
+      }
+      setStep("confirm");
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to load history");
+      setStep("error");
+    }
+  }
+
+  async function performRollback() {
+    if (!target || !config) return;
+    setStep("rolling-back");
+    const run = {
+      action: "rollback" as const,
+      startedAt: new Date(),
+      fromVersion: current?.appVersion ?? undefined,
+      toVersion: target.appVersion ?? undefined,
+    };
+    try {
+      const state = await loadDeploymentState(name);
+      const namespace = state?.application?.namespace || getNamespace(name);
+      // values.yaml feeds `upgrade`, so it has to match what now runs: the
+      // values the target revision was installed with, not just its version.
+      const targetValues = await getReleaseUserValues(
+        releaseName,
+        namespace,
+        target.revision,
+      );
+      if (!targetValues) {
+        throw new Error(
+          `Could not read the values of revision ${target.revision}; nothing was rolled back.`,
+        );
+      }
+      await rollbackRelease(releaseName, namespace, target.revision);
+      await writePrivateFile(
+        getHelmValuesPath(name),
+        YAML.stringify(targetValues),
+      );
+
+      await updateDeploymentStatus(name, "running", {
+        application: {
+          version: target.appVersion ?? state?.application?.version ?? "",
+          chartVersion:
+            target.chartVersion ?? state?.application?.chartVersion,
+          namespace,
+          url: state?.application?.url || `https://${config.domain}`,
+        },
+      });
+
+      if (revertConfig) {
+        await revertConfigVersions(name, {
+          version: target.appVersion ?? undefined,
+          chartVersion: target.chartVersion ?? undefined,
+        });
+      }
+      await recordDeploymentEvent(name, finishEvent(run));
+
+      setStep("complete");
+      setTimeout(() => exit(), 5000);
+    } catch (err) {
+      await recordDeploymentEvent(
+        name,
+        finishEvent({ ...run, error: err ?? "Rollback failed" }),
+      ).catch(() => {});
+      setError(err instanceof Error ? err.message : "Rollback failed");
+      setStep("error");
+    }
+  }
+
+  useInput((input, key) => {
+    if (step === "confirm") {
+      if (key.return) {
+        performRollback();
+      } else if (key.escape) {
+        exit();
+      }
+    }
+  });
+
+  if (step === "loading") {
+    return (
+      <BorderBox title="Rollback">
+        <Box marginY={1}>
+          <Spinner label="Reading release history..." />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "error") {
+    return (
+      <BorderBox title="Rollback Failed">
+        <Box marginY={1}>
+          <Text color={colors.error}>✗ {error}</Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "rolling-back") {
+    return (
+      <BorderBox title="Rollback">
+        <Box marginY={1}>
+          <Spinner label={`Rolling back to ${describeRevision(target!)}...`} />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "complete") {
+    return (
+      <BorderBox title="Rollback Complete">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.success} bold>
+            ✓ Rolled back to {describeRevision(target!)}
+          </Text>
+          {revertConfig && (
+            <Text color={colors.muted}>
+              config.yaml now names these versions.
+            </Text>
+          )}
+          <Box marginTop={1}>
+            <Text>Run `rulebricks status {name}` to verify the deployment</Text>
+          </Box>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  // The next deploy installs whatever config.yaml names.
+  const configAhead = Boolean(
+    !revertConfig &&
+      target?.appVersion &&
+      config?.version &&
+      config.version !== target.appVersion,
+  );
+
+  return (
+    <BorderBox title="Confirm Rollback">
+      <Box flexDirection="column" marginY={1}>
+        <Text>
+          Current:{" "}
+          <Text color={colors.accent}>{describeRevision(current!)}</Text>
+        </Text>
+        <Text>
+          Roll back to:{" "}
+          <Text color={colors.success}>{describeRevision(target!)}</Text>
+        </Text>
+
+        <Box marginTop={1} flexDirection="column">
+          <Text color={colors.warning}>
+            ⚠ Database migrations are not reversed. The older version runs
+            against the schema the newer one left; restore a backup if it
+            cannot.
+          </Text>
+          {undone.length > 0 && (
+            <>
+              <Text color={colors.muted}>
+                Chart releases being undone (their migrations stay applied):
+              </Text>
+              {undone.map((release) => (
+                <Text key={release.version} color={colors.muted}>
+                  {" "}
+                  • {release.version}
+                </Text>
+              ))}
+            </>
+          )}
+        </Box>
+
+        {configAhead && (
+          <Box marginTop={1}>
+            <Text color={colors.muted}>
+              config.yaml still names {formatVersionDisplay(config!.version)},
+              which the next deploy reinstalls. Pass --revert-config to update
+              it too.
+            </Text>
+          </Box>
+        )}
+
+        <Box marginTop={1}>
+          <Text color={colors.success} bold>
+            Press Enter to roll back, Esc to cancel
+          </Text>
+        </Box>
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function UpgradeRollbackCommand(props: UpgradeRollbackCommandProps) {
+  return (
+    <ThemeProvider theme="upgrade">
+      <Logo />
+      <UpgradeRollbackCommandInner {...props} />
+    </ThemeProvider>
+  );
+}
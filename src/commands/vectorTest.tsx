@@ -0,0 +1,156 @@
+import React, { useEffect, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  ThemeProvider,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import { loadDeploymentConfig, loadHelmValues } from "../lib/config.js";
+import { updateKubeconfig } from "../lib/cloudCli.js";
+import { CommandDeniedError } from "../lib/commandApproval.js";
+import {
+  checkClusterAccessible,
+  isKubectlInstalled,
+} from "../lib/kubernetes.js";
+import {
+  runVectorTest,
+  VECTOR_TEST_PREFIX,
+  VectorTestResult,
+} from "../lib/vectorTest.js";
+import { DeploymentConfig } from "../types/index.js";
+
+interface VectorTestCommandProps {
+  name: string;
+}
+
+function VectorTestCommandInner({ name }: VectorTestCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [result, setResult] = useState<VectorTestResult | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    run();
+  }, []);
+
+  async function run() {
+    try {
+      const cfg = await loadDeploymentConfig(name);
+      await runPreflight(cfg);
+      const outcome = await runVectorTest(cfg, await loadHelmValues(name));
+      setResult(outcome);
+      if (outcome.error || outcome.sinks.some((sink) => !sink.ok)) {
+        process.exitCode = 1;
+      }
+      setTimeout(() => exit(), 5000);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Vector test failed");
+      process.exitCode = 1;
+    }
+  }
+
+  async function runPreflight(cfg: DeploymentConfig) {
+    if (!(await isKubectlInstalled())) {
+      throw new Error("kubectl is not installed. Please install kubectl first.");
+    }
+
+    let clusterError = await checkClusterAccessible();
+    if (
+      clusterError &&
+      cfg.infrastructure.provider &&
+      cfg.infrastructure.region &&
+      cfg.infrastructure.clusterName
+    ) {
+      try {
+        await updateKubeconfig(
+          cfg.infrastructure.provider,
+          cfg.infrastructure.clusterName,
+          cfg.infrastructure.region,
+          {
+            gcpProjectId: cfg.infrastructure.gcpProjectId,
+            azureResourceGroup: cfg.infrastructure.azureResourceGroup,
+          },
+        );
+      } catch (err) {
+        if (!(err instanceof CommandDeniedError)) {
+          throw err;
+        }
+      }
+      clusterError = await checkClusterAccessible();
+    }
+
+    if (clusterError) {
+      throw new Error(`Cannot access Kubernetes cluster:\n${clusterError}`);
+    }
+  }
+
+  if (error) {
+    return (
+      <BorderBox title="Vector Test Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>✗ Error</Text>
+          <Text color={colors.error}>{error}</Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (!result) {
+    return (
+      <BorderBox title={`Vector test: ${name}`}>
+        <Box marginY={1}>
+          <Spinner label="Sending a test event through every Vector sink..." />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  const width = Math.max(...result.sinks.map((sink) => sink.id.length));
+  return (
+    <BorderBox title={`Vector test: ${name}`}>
+      <Box flexDirection="column" marginY={1}>
+        {result.sinks.map((sink) => (
+          <Box key={sink.id} flexDirection="column">
+            <Text>
+              <Text color={sink.ok ? colors.success : colors.error}>
+                {sink.ok ? "✓" : "✗"}
+              </Text>{" "}
+              {sink.id.padEnd(width)}{" "}
+              <Text color={colors.muted}>{sink.type}</Text>
+            </Text>
+            {sink.error && (
+              <Box marginLeft={2}>
+                <Text color={colors.error}>{sink.error}</Text>
+              </Box>
+            )}
+          </Box>
+        ))}
+        {result.error && (
+          <Box marginTop={1}>
+            <Text color={colors.error}>{result.error}</Text>
+          </Box>
+        )}
+        <Box marginTop={1}>
+          <Text color={colors.muted}>
+            Object storage test events are written under {VECTOR_TEST_PREFIX}
+            {result.testId}/ and can be deleted.
+          </Text>
+        </Box>
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function VectorTestCommand(props: VectorTestCommandProps) {
+  return (
+    <ThemeProvider theme="status">
+      <Logo />
+      <CommandApprovalProvider>
+        <VectorTestCommandInner {...props} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
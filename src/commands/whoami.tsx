@@ -0,0 +1,215 @@
+import React, { useEffect, useState } from "react";
+import { Box, Text, useApp } from "ink";
+import {
+  BorderBox,
+  Logo,
+  Spinner,
+  ThemeProvider,
+  useTheme,
+  CommandApprovalProvider,
+} from "../components/common/index.js";
+import { listDeployments, loadDeploymentConfig } from "../lib/config.js";
+import {
+  CloudIdentity,
+  findIdentityMismatches,
+  getCloudIdentity,
+} from "../lib/cloudCli.js";
+import { getCurrentContext } from "../lib/kubernetes.js";
+import { CloudProvider, DeploymentConfig } from "../types/index.js";
+
+interface WhoamiCommandProps {
+  name?: string;
+}
+
+type Step = "loading" | "complete" | "error";
+
+const PROVIDER_LABELS: Record<CloudProvider, string> = {
+  aws: "AWS",
+  gcp: "Google Cloud",
+  azure: "Azure",
+};
+
+/**
+ * Providers to report on: the named deployment's provider, otherwise every
+ * provider used by a local deployment, otherwise all of them.
+ */
+async function resolveTargets(
+  name?: string,
+): Promise<{ providers: CloudProvider[]; config: DeploymentConfig | null }> {
+  if (name) {
+    const config = await loadDeploymentConfig(name);
+    const provider = config.infrastructure.provider;
+    return {
+      providers: provider ? [provider] : ["aws", "gcp", "azure"],
+      config,
+    };
+  }
+
+  const providers = new Set<CloudProvider>();
+  for (const deployment of await listDeployments()) {
+    try {
+      const config = await loadDeploymentConfig(deployment);
+      if (config.infrastructure.provider) {
+        providers.add(config.infrastructure.provider);
+      }
+    } catch {
+      // Unreadable configs are reported by `list`; skip them here.
+    }
+  }
+  return {
+    providers: providers.size > 0 ? [...providers] : ["aws", "gcp", "azure"],
+    config: null,
+  };
+}
+
+function IdentityDetails({ identity }: { identity: CloudIdentity }) {
+  const { colors } = useTheme();
+  const rows: Array<[string, string | undefined]> =
+    identity.provider === "aws"
+      ? [
+          ["Account", identity.account],
+          ["ARN", identity.principal],
+        ]
+      : identity.provider === "gcp"
+        ? [
+            ["Account", identity.account],
+            ["Project", identity.project],
+          ]
+        : [
+            ["Subscription", identity.subscriptionName],
+            ["Subscription ID", identity.account],
+            ["Tenant", identity.tenantId],
+            ["User", identity.principal],
+          ];
+
+  return (
+    <Box flexDirection="column" marginLeft={2}>
+      {rows
+        .filter(([, value]) => value)
+        .map(([label, value]) => (
+          <Text key={label}>
+            <Text color={colors.muted}>{label}: </Text>
+            {value}
+          </Text>
+        ))}
+    </Box>
+  );
+}
+
+function WhoamiCommandInner({ name }: WhoamiCommandProps) {
+  const { exit } = useApp();
+  const { colors } = useTheme();
+  const [step, setStep] = useState<Step>("loading");
+  const [loadingLabel, setLoadingLabel] = useState("Resolving providers...");
+  const [identities, setIdentities] = useState<CloudIdentity[]>([]);
+  const [mismatches, setMismatches] = useState<string[]>([]);
+  const [kubeContext, setKubeContext] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    (async () => {
+      try {
+        const { providers, config } = await resolveTargets(name);
+
+        const results: CloudIdentity[] = [];
+        for (const provider of providers) {
+          setLoadingLabel(`Checking ${PROVIDER_LABELS[provider]} identity...`);
+          results.push(await getCloudIdentity(provider));
+        }
+        setIdentities(results);
+        setKubeContext(await getCurrentContext());
+
+        if (config) {
+          setMismatches(
+            results.flatMap((identity) =>
+              findIdentityMismatches(config, identity),
+            ),
+          );
+        }
+
+        setStep("complete");
+        setTimeout(() => exit(), 250);
+      } catch (err) {
+        setError(
+          err instanceof Error ? err.message : "Failed to resolve identity",
+        );
+        setStep("error");
+        setTimeout(() => exit(), 1000);
+      }
+    })();
+  }, [exit]);
+
+  if (step === "loading") {
+    return (
+      <BorderBox title="Cloud Identity">
+        <Box marginY={1}>
+          <Spinner label={loadingLabel} />
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  if (step === "error") {
+    return (
+      <BorderBox title="Whoami Failed">
+        <Box flexDirection="column" marginY={1}>
+          <Text color={colors.error} bold>
+            ✗ Error
+          </Text>
+          <Text color={colors.error}>{error}</Text>
+        </Box>
+      </BorderBox>
+    );
+  }
+
+  return (
+    <BorderBox title={name ? `Cloud Identity: ${name}` : "Cloud Identity"}>
+      <Box flexDirection="column" marginY={1}>
+        {identities.map((identity) => (
+          <Box key={identity.provider} flexDirection="column" marginBottom={1}>
+            <Box>
+              <Text color={identity.authenticated ? "green" : "red"}>
+                {identity.authenticated ? "●" : "✗"}
+              </Text>
+              <Text bold> {PROVIDER_LABELS[identity.provider]}</Text>
+              {!identity.authenticated && (
+                <Text color={colors.muted}> {identity.error}</Text>
+              )}
+            </Box>
+            {identity.authenticated && <IdentityDetails identity={identity} />}
+          </Box>
+        ))}
+
+        <Text>
+          <Text color={colors.muted}>kubectl context: </Text>
+          {kubeContext ?? "(none)"}
+        </Text>
+
+        {mismatches.length > 0 && (
+          <Box flexDirection="column" marginTop={1}>
+            <Text color={colors.warning} bold>
+              ⚠ Identity does not match {name}'s configuration
+            </Text>
+            {mismatches.map((mismatch) => (
+              <Text key={mismatch} color={colors.warning}>
+                {"  • "}
+                {mismatch}
+              </Text>
+            ))}
+          </Box>
+        )}
+      </Box>
+    </BorderBox>
+  );
+}
+
+export function WhoamiCommand({ name }: WhoamiCommandProps) {
+  return (
+    <ThemeProvider theme="status">
+      <Logo />
+      <CommandApprovalProvider>
+        <WhoamiCommandInner name={name} />
+      </CommandApprovalProvider>
+    </ThemeProvider>
+  );
+}
@@ -0,0 +1,67 @@
+import React from "react";
+import { Box, Text } from "ink";
+import { useTheme } from "./common/index.js";
+import { RolloutView, rolloutPodHealth } from "../lib/rolloutWatch.js";
+
+/** Pods listed before the rest are summarized in a count. */
+const MAX_PODS = 12;
+
+interface RolloutPanelProps {
+  view: RolloutView;
+}
+
+/**
+ * Live pod phases and Warning events for `deploy --watch-rollout`. Failing
+ * pods sort first so a stuck image pull stays visible on a large install.
+ */
+export function RolloutPanel({ view }: RolloutPanelProps) {
+  const { colors } = useTheme();
+
+  const rank = { failing: 0, progressing: 1, ready: 2 };
+  const pods = [...view.pods].sort(
+    (a, b) => rank[rolloutPodHealth(a)] - rank[rolloutPodHealth(b)],
+  );
+  const shown = pods.slice(0, MAX_PODS);
+  const readyCount = pods.filter(
+    (pod) => rolloutPodHealth(pod) === "ready",
+  ).length;
+
+  return (
+    <Box flexDirection="column" marginTop={1} marginLeft={2}>
+      <Text color={colors.muted}>
+        {pods.length === 0
+          ? "Waiting for pods..."
+          : `Pods ready: ${readyCount}/${pods.length}`}
+      </Text>
+      {shown.map((pod) => {
+        const health = rolloutPodHealth(pod);
+        const color =
+          health === "failing"
+            ? colors.error
+            : health === "ready"
+              ? colors.success
+              : colors.warning;
+        return (
+          <Box key={pod.name}>
+            <Text color={color}>{pod.status.padEnd(20)}</Text>
+            <Text color={colors.muted}>
+              {pod.ready.padEnd(6)}
+              {pod.name}
+              {pod.restarts > 0 ? ` (${pod.restarts} restarts)` : ""}
+            </Text>
+          </Box>
+        );
+      })}
+      {pods.length > shown.length && (
+        <Text color={colors.muted}>
+          ...and {pods.length - shown.length} more
+        </Text>
+      )}
+      {view.warnings.map((warning, i) => (
+        <Text key={i} color={colors.warning}>
+          ⚠ {warning.object}: {warning.reason} - {warning.message}
+        </Text>
+      ))}
+    </Box>
+  );
+}
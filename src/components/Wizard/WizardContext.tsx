@@ -8,6 +8,7 @@ import {
   DnsProvider,
   KafkaPreset,
   KafkaSaslMechanism,
+  LoggingPlatformSink,
   LoggingSink,
   CloudLoggingAuthMode,
   ObjectStorageProvider,
@@ -71,6 +72,8 @@ export interface WizardState {
   // Cluster capabilities (informational; sizing follows chart defaults)
   nodeArchitecture: NodeArchitecture | null;
   arm64TolerationRequired: boolean;
+  // Chosen on mixed clusters; null runs Rulebricks on any node.
+  workloadArchitecture: "amd64" | "arm64" | null;
   storageClass: string;
   storageProvisioner: string;
   schedulableNodeCount: number;
@@ -125,6 +128,9 @@ export interface WizardState {
   prometheusMonitoringDestination: MonitoringDestination | null;
   prometheusRemoteWriteUrl: string;
   prometheusRemoteWriteDestination: RemoteWriteDestination | null;
+  // Not asked in the wizard; carried from config.yaml so configure keeps them.
+  prometheusRetention: string;
+  prometheusStorageSize: string;
   prometheusRemoteWriteAuthType: RemoteWriteAuthType | null;
   prometheusRemoteWriteAwsRegion: string;
   prometheusRemoteWriteAwsRoleArn: string;
@@ -143,6 +149,9 @@ export interface WizardState {
   loggingSink: LoggingSink;
   loggingPlatformCredential: string;
   loggingPlatformDetail: string;
+  // Further platforms (features.logging.sinks). Config-file only; carried
+  // through so configure does not drop them.
+  loggingExtraSinks: LoggingPlatformSink[];
 
   // Features - Distributed Tracing (in-cluster OTel collector -> pluggable
   // backend: Elastic APM, a generic OTLP/HTTP endpoint, or Azure Monitor).
@@ -462,6 +471,10 @@ type WizardAction =
   | { type: "SET_EMAIL_SUBJECTS"; subjects: Partial<EmailSubjects> }
   | { type: "SET_EMAIL_TEMPLATES"; templates: Partial<EmailTemplates> }
   | { type: "SET_LICENSE_KEY"; key: string }
+  | {
+      type: "SET_WORKLOAD_ARCHITECTURE";
+      architecture: "amd64" | "arm64" | undefined;
+    }
   | { type: "SET_VERSION"; version: string }
   | { type: "SET_CHART_VERSION"; version: string };
 
@@ -515,6 +528,7 @@ function getInitialState(profile?: ProfileConfig | null): WizardState {
     // Cluster capabilities (populated by the cluster scan)
     nodeArchitecture: null,
     arm64TolerationRequired: false,
+    workloadArchitecture: null,
     storageClass: "",
     storageProvisioner: "",
     schedulableNodeCount: 0,
@@ -574,6 +588,8 @@ function getInitialState(profile?: ProfileConfig | null): WizardState {
     prometheusMonitoringDestination: null,
     prometheusRemoteWriteUrl: "",
     prometheusRemoteWriteDestination: null,
+    prometheusRetention: "",
+    prometheusStorageSize: "",
     prometheusRemoteWriteAuthType: null,
     prometheusRemoteWriteAwsRegion: "",
     prometheusRemoteWriteAwsRoleArn: "",
@@ -589,6 +605,7 @@ function getInitialState(profile?: ProfileConfig | null): WizardState {
     loggingSink: "console", // Default to console only
     loggingPlatformCredential: "",
     loggingPlatformDetail: "",
+    loggingExtraSinks: [],
 
     // Features - Distributed Tracing
     tracingEnabled: false,
@@ -1208,6 +1225,7 @@ export function configToWizardState(
     nodeArchitecture: config.infrastructure.nodeArchitecture ?? null,
     arm64TolerationRequired:
       config.infrastructure.arm64TolerationRequired ?? false,
+    workloadArchitecture: config.infrastructure.workloadArchitecture ?? null,
     storageClass: config.infrastructure.storageClass ?? "",
     storageProvisioner: config.infrastructure.storageProvisioner ?? "",
     schedulableNodeCount: config.infrastructure.schedulableNodeCount ?? 0,
@@ -1268,6 +1286,9 @@ export function configToWizardState(
     prometheusRemoteWriteUrl:
       remoteWrite?.url ?? config.features.monitoring.remoteWriteUrl ?? "",
     prometheusRemoteWriteDestination: remoteWrite?.destination ?? null,
+    prometheusRetention: config.features.monitoring.metrics?.retention ?? "",
+    prometheusStorageSize:
+      config.features.monitoring.metrics?.storageSize ?? "",
     prometheusRemoteWriteAuthType: remoteWrite?.authType ?? null,
     prometheusRemoteWriteAwsRegion: remoteWrite?.awsRegion ?? "",
     prometheusRemoteWriteAwsRoleArn: remoteWrite?.awsRoleArn ?? "",
@@ -1290,6 +1311,7 @@ export function configToWizardState(
     loggingSink: config.features.logging.sink,
     loggingPlatformCredential: config.features.logging.bucket ?? "",
     loggingPlatformDetail: config.features.logging.region ?? "",
+    loggingExtraSinks: config.features.logging.sinks ?? [],
     // Distributed tracing (Elastic APM / generic OTLP / Azure Monitor)
     tracingEnabled: config.features.tracing?.enabled ?? false,
     tracingDestination: config.features.tracing?.destination ?? "elastic",
@@ -1643,6 +1665,8 @@ function wizardReducer(state: WizardState, action: WizardAction): WizardState {
       };
     case "SET_LICENSE_KEY":
       return { ...state, licenseKey: action.key };
+    case "SET_WORKLOAD_ARCHITECTURE":
+      return { ...state, workloadArchitecture: action.architecture ?? null };
     case "SET_VERSION":
       return {
         ...state,
@@ -1740,6 +1764,7 @@ export function WizardProvider({
           options.nodeArchitecture || state.nodeArchitecture || undefined,
         arm64TolerationRequired:
           options.arm64TolerationRequired ?? state.arm64TolerationRequired,
+        workloadArchitecture: state.workloadArchitecture ?? undefined,
         storageClass: options.storageClass || state.storageClass || undefined,
         storageProvisioner:
           options.storageProvisioner || state.storageProvisioner || undefined,
@@ -1896,6 +1921,13 @@ export function WizardProvider({
             ? state.prometheusRemoteWriteUrl || undefined
             : undefined,
           remoteWrite,
+          metrics:
+            state.prometheusRetention || state.prometheusStorageSize
+              ? {
+                  retention: state.prometheusRetention || undefined,
+                  storageSize: state.prometheusStorageSize || undefined,
+                }
+              : undefined,
         },
         observability: {
           clickstack: {
@@ -1995,6 +2027,10 @@ export function WizardProvider({
           sink: state.loggingSink,
           bucket: state.loggingPlatformCredential || undefined,
           region: state.loggingPlatformDetail || undefined,
+          sinks:
+            state.loggingExtraSinks.length > 0
+              ? state.loggingExtraSinks
+              : undefined,
           // Application/container log shipping to Elasticsearch (Vector agent).
           appLogs: !state.clickStackEnabled && state.appLogsEnabled
             ? {
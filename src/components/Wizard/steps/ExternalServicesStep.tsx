@@ -26,6 +26,7 @@ import {
 } from "../../../lib/cloudCli.js";
 import { CloudProvider, KafkaPreset } from "../../../types/index.js";
 import { externalServicesFieldOrder } from "../../../lib/wizardFlow.js";
+import { kafkaTopicPrefixError } from "../../../lib/configValidation.js";
 
 interface ExternalServicesStepProps {
   onComplete: () => void;
@@ -668,6 +669,13 @@ export function ExternalServicesStep({
           onChange={setTopicPrefix}
           placeholder="com.rulebricks."
           onSubmit={() => {
+            const prefixError =
+              topicPrefix.trim() && kafkaTopicPrefixError(topicPrefix.trim());
+            if (prefixError) {
+              setError(`Topic prefix ${prefixError}.`);
+              return;
+            }
+            setError(null);
             save({ kafkaTopicPrefix: topicPrefix.trim() });
             flow.next();
           }}
@@ -55,6 +55,7 @@ const LOGGING_PLATFORM_SINKS = [
   { label: "Grafana Loki", value: "loki" },
   { label: "New Relic", value: "newrelic" },
   { label: "Axiom", value: "axiom" },
+  { label: "OpenTelemetry collector (OTLP)", value: "otlp" },
 ];
 
 const DATADOG_SITES = [
@@ -193,6 +194,8 @@ export function FeatureConfigStep({
   const [newrelicAccountId, setNewrelicAccountId] = useState("");
   const [axiomApiToken, setAxiomApiToken] = useState("");
   const [axiomDataset, setAxiomDataset] = useState("rulebricks");
+  const [otlpEndpoint, setOtlpEndpoint] = useState("");
+  const [otlpHeaders, setOtlpHeaders] = useState("");
 
   // Distributed tracing
   const [tracingDestination, setTracingDestination] =
@@ -1214,6 +1217,50 @@ export function FeatureConfigStep({
         />
       ),
     },
+    {
+      id: "logging-otlp-endpoint",
+      render: (flow) => (
+        <TextField
+          label="OTLP/HTTP endpoint"
+          hint="The collector's OTLP/HTTP receiver (usually port 4318); /v1/logs is added when the URL has no path. gRPC is not supported."
+          value={otlpEndpoint}
+          onChange={setOtlpEndpoint}
+          placeholder="https://otel-collector.example.com:4318"
+          onSubmit={() => {
+            if (!otlpEndpoint || !isValidUrl(otlpEndpoint)) {
+              setError("A valid OTLP endpoint URL is required");
+              return;
+            }
+            setError(null);
+            flow.next();
+          }}
+        />
+      ),
+    },
+    {
+      id: "logging-otlp-headers",
+      render: (flow) => (
+        <TextField
+          label="OTLP headers (optional)"
+          hint="key=value pairs separated by commas, as in OTEL_EXPORTER_OTLP_HEADERS."
+          value={otlpHeaders}
+          onChange={setOtlpHeaders}
+          placeholder="Authorization=Bearer <token>"
+          mask
+          onSubmit={() => {
+            setError(null);
+            dispatch({
+              type: "SET_LOGGING_CONFIG",
+              config: {
+                loggingPlatformCredential: otlpEndpoint,
+                loggingPlatformDetail: otlpHeaders,
+              },
+            });
+            flow.next();
+          }}
+        />
+      ),
+    },
 
     // ----- Distributed tracing -----
     {
@@ -27,10 +27,17 @@ interface VersionStepProps {
   entryDirection?: "forward" | "back";
 }
 
+const ARCHITECTURE_ITEMS = [
+  { label: "Any node (no preference)", value: "any" },
+  { label: "x86 nodes only (amd64)", value: "amd64" },
+  { label: "ARM nodes only (arm64, e.g. AWS Graviton)", value: "arm64" },
+];
+
 function VersionPicker({ flow }: { flow: FlowController }) {
   const { state, dispatch } = useWizard();
   const { colors } = useTheme();
   const [loading, setLoading] = useState(true);
+  const [choosingArchitecture, setChoosingArchitecture] = useState(false);
   const [versions, setVersions] = useState<AppVersion[]>([]);
   const [loadError, setLoadError] = useState<string | null>(null);
   const [authError, setAuthError] = useState<string | null>(null);
@@ -41,13 +48,8 @@ function VersionPicker({ flow }: { flow: FlowController }) {
         // Scan the cluster for its capabilities (node architecture, storage
         // class, ARM tolerations) unless an earlier scan already populated
         // them. The architecture selects the matching image versions.
-        let architecture: NodeArchitecture | undefined;
-        if (
-          state.nodeArchitecture === "amd64" ||
-          state.nodeArchitecture === "arm64"
-        ) {
-          architecture = state.nodeArchitecture;
-        } else {
+        let nodeArchitecture = state.nodeArchitecture;
+        if (nodeArchitecture !== "amd64" && nodeArchitecture !== "arm64") {
           const capabilities = await inferClusterCapabilities();
           if (capabilities) {
             dispatch({
@@ -64,45 +66,80 @@ function VersionPicker({ flow }: { flow: FlowController }) {
               totalPersistentStorageGi:
                 capabilities.totalPersistentStorageGi ?? 0,
             });
-            if (
-              capabilities.nodeArchitecture === "amd64" ||
-              capabilities.nodeArchitecture === "arm64"
-            ) {
-              architecture = capabilities.nodeArchitecture;
-            }
+            nodeArchitecture = capabilities.nodeArchitecture;
           }
         }
 
-        const appVersions = await fetchAppVersions(
-          state.licenseKey,
-          architecture,
-        );
-        setVersions(appVersions);
-        if (appVersions.length === 0 && architecture) {
-          setLoadError(
-            `No compatible Rulebricks version found for ${architecture} nodes.`,
-          );
-        } else if (appVersions.length === 0) {
-          setLoadError("No Rulebricks versions found.");
-        }
-      } catch (err) {
-        const message =
-          err instanceof Error ? err.message : "Failed to fetch versions";
-        if (
-          message.includes("authentication") ||
-          message.includes("Invalid license")
-        ) {
-          setAuthError(
-            "Invalid license key - press Esc to go back and re-enter it.",
-          );
+        if (nodeArchitecture === "amd64" || nodeArchitecture === "arm64") {
+          await loadVersions(nodeArchitecture);
+        } else if (nodeArchitecture === "mixed") {
+          // Both x86 and ARM nodes: ask which one Rulebricks should run on.
+          setChoosingArchitecture(true);
+          setLoading(false);
         } else {
-          setLoadError(`${message}. Will use latest version.`);
+          await loadVersions(undefined);
         }
+      } catch (err) {
+        showLoadError(err);
+        setLoading(false);
       }
-      setLoading(false);
     })();
   }, []);
 
+  async function loadVersions(architecture: NodeArchitecture | undefined) {
+    try {
+      const appVersions = await fetchAppVersions(
+        state.licenseKey,
+        architecture,
+      );
+      setVersions(appVersions);
+      if (appVersions.length === 0 && architecture) {
+        setLoadError(
+          `No compatible Rulebricks version found for ${architecture} nodes.`,
+        );
+      } else if (appVersions.length === 0) {
+        setLoadError("No Rulebricks versions found.");
+      }
+    } catch (err) {
+      showLoadError(err);
+    }
+    setLoading(false);
+  }
+
+  function showLoadError(err: unknown) {
+    const message =
+      err instanceof Error ? err.message : "Failed to fetch versions";
+    if (
+      message.includes("authentication") ||
+      message.includes("Invalid license")
+    ) {
+      setAuthError(
+        "Invalid license key - press Esc to go back and re-enter it.",
+      );
+    } else {
+      setLoadError(`${message}. Will use latest version.`);
+    }
+  }
+
+  if (choosingArchitecture) {
+    return (
+      <WizardSelect
+        label="Which nodes should Rulebricks run on?"
+        hint="This cluster has both x86 and ARM nodes"
+        items={ARCHITECTURE_ITEMS}
+        initialValue={state.workloadArchitecture ?? "any"}
+        onSelect={(value) => {
+          const architecture =
+            value === "amd64" || value === "arm64" ? value : undefined;
+          dispatch({ type: "SET_WORKLOAD_ARCHITECTURE", architecture });
+          setChoosingArchitecture(false);
+          setLoading(true);
+          loadVersions(architecture);
+        }}
+      />
+    );
+  }
+
   if (loading) {
     return (
       <Box flexDirection="column" marginY={1}>
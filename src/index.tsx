@@ -1,25 +1,178 @@
 #!/usr/bin/env node
 import { createRequire } from "node:module";
+import { promises as fs } from "node:fs";
+import path from "node:path";
 import { Command } from "commander";
 import { render } from "ink";
 import React from "react";
 import chalk from "chalk";
+import yaml from "yaml";
 
 import { InitWizard } from "./commands/init.js";
-import { DeployCommand } from "./commands/deploy.js";
+import { DeployCommand, PROGRESS_LABELS } from "./commands/deploy.js";
+import { DeployDryRunCommand } from "./commands/deployDryRun.js";
+import { DeployObserveCommand } from "./commands/deployObserve.js";
 import { ConfigureCommand } from "./commands/configure.js";
 import { UpgradeCommand } from "./commands/upgrade.js";
 import { ChartUpgradeCommand } from "./commands/upgradeChart.js";
+import { UpgradeRollbackCommand } from "./commands/upgradeRollback.js";
 import { DestroyCommand } from "./commands/destroy.js";
+import { NamespacesCleanupCommand } from "./commands/namespacesCleanup.js";
 import { StatusCommand } from "./commands/status.js";
 import { ListCommand } from "./commands/list.js";
 import { LogsCommand } from "./commands/logs.js";
+import { LogsExportCommand } from "./commands/logsExport.js";
 import { CloneCommand } from "./commands/clone.js";
 import { OpenCommand } from "./commands/open.js";
 import { BenchmarkCommand } from "./commands/benchmark.js";
 import { BackupCommand } from "./commands/backup.js";
+import { BackupListCommand } from "./commands/backupList.js";
 import { RestoreCommand } from "./commands/restore.js";
-import { listDeployments, deploymentExists } from "./lib/config.js";
+import { WhoamiCommand } from "./commands/whoami.js";
+import { FixRealtimeCommand } from "./commands/fixRealtime.js";
+import { SecretsRotateCommand } from "./commands/secretsRotate.js";
+import { RepairCommand } from "./commands/repair.js";
+import { VectorTestCommand } from "./commands/vectorTest.js";
+import {
+  listDeployments,
+  deploymentExists,
+  encryptDeploymentConfig,
+  decryptDeploymentConfig,
+  loadDeploymentState,
+  updateDeploymentState,
+  loadHelmValues,
+  getDeploymentDir,
+  installDeploymentConfig,
+  loadDeploymentConfig,
+  recordDeploymentEvent,
+  saveHelmValues,
+  saveImportedDeploymentConfig,
+  validateConfigFile,
+} from "./lib/config.js";
+import {
+  importConfigFromValues,
+  importedConfigHeader,
+  parseKubeClusterName,
+} from "./lib/configImport.js";
+import {
+  maskSensitiveValues,
+  resolveAgeRecipient,
+} from "./lib/configEncryption.js";
+import { readLayeredConfig } from "./lib/configLayers.js";
+import { ConfigIssue, formatConfigIssues } from "./lib/configValidation.js";
+import {
+  applyManifests,
+  parseManifests,
+  prepareManifests,
+} from "./lib/manifestApply.js";
+import {
+  ALL_LOG_COMPONENTS,
+  checkClusterAccessible,
+  getClusterScopedKinds,
+  getCurrentContextCluster,
+  getPersistentVolumeClaims,
+  getScaledObjectFor,
+  getSecretsJson,
+  inferClusterCapabilities,
+  namespaceExists,
+  patchScaledObjectBounds,
+  PersistentVolumeClaimInfo,
+  VALID_LOG_COMPONENTS,
+} from "./lib/kubernetes.js";
+import {
+  getInstalledChartVersion,
+  getReleaseUserValues,
+  HelmValueOverrides,
+  listRulebricksReleases,
+  parseValueOverride,
+} from "./lib/helm.js";
+import {
+  assessCloudMigration,
+  formatMigrationRunbook,
+} from "./lib/cloudMigration.js";
+import { listComponents } from "./lib/components.js";
+import {
+  GrafanaCredentials,
+  grafanaPortForwardCommand,
+  maskSecret,
+  parseGrafanaAdminSecret,
+} from "./lib/grafana.js";
+import {
+  effectiveAuthEnvironment,
+  formatAuthEnvironment,
+} from "./lib/authSettings.js";
+import { buildHelmValues } from "./lib/helmValues.js";
+import { PsqlInvocation, psqlInvocation, runPsql } from "./lib/dbShell.js";
+import { collectStatusReport } from "./lib/statusReport.js";
+import { SmtpTestResult, testSmtp } from "./lib/smtpTest.js";
+import { secretModeForConfig } from "./lib/deploySequence.js";
+import {
+  CLOUD_PROVIDER_NAMES,
+  CloudProvider,
+  getNamespace,
+  getReleaseName,
+} from "./types/index.js";
+import {
+  isProgressMode,
+  ProgressEvent,
+  resolveProgressMode,
+  PROGRESS_MODES,
+} from "./lib/progress.js";
+import {
+  ComponentTimeouts,
+  parseComponentTimeouts,
+  parseDuration,
+  TIMEOUT_COMPONENTS,
+} from "./lib/componentTimeouts.js";
+import { isLogOutputFormat, LOG_OUTPUT_FORMATS } from "./lib/logFormat.js";
+import { parseRetryCount } from "./lib/stepRetry.js";
+import {
+  DEPLOY_PHASES,
+  DeployPhase,
+  parseDeployComponents,
+} from "./lib/incrementalDeploy.js";
+import type { DriftReport } from "./lib/driftReport.js";
+import {
+  abortEvents,
+  buildDeployResult,
+  DeployResult,
+  isOutputFormat,
+  OUTPUT_FORMATS,
+} from "./lib/deployResult.js";
+import {
+  finishEvent,
+  formatTable,
+  historyRows,
+} from "./lib/deploymentHistory.js";
+import { RotationTarget } from "./lib/secretRotation.js";
+import {
+  collectDoctorReport,
+  type DoctorReport,
+  type DoctorStatus,
+} from "./lib/doctor.js";
+import {
+  ensureClusterContext,
+  ensureConfiguredCluster,
+} from "./lib/deploymentHealth.js";
+import {
+  applyWorkerScaleToValues,
+  resolveWorkerScale,
+  solutionPartitions,
+} from "./lib/workerScaling.js";
+import { CONFIG_TOKEN_ENV, fetchRemoteConfig } from "./lib/remoteConfig.js";
+import {
+  DEFAULT_HEALTH_HOST,
+  HealthServer,
+  parseHealthPort,
+  startHealthServer,
+} from "./lib/healthServer.js";
+import type { SkippableComponent } from "./lib/skipComponents.js";
+import {
+  NotificationTarget,
+  notificationTargets,
+  parseNotifyUrl,
+  sendNotifications,
+} from "./lib/notifications.js";
 import { DeploymentPicker } from "./components/common/DeploymentPicker.js";
 
 const require = createRequire(import.meta.url);
@@ -27,8 +180,18 @@ const packageJson = require("../package.json") as { version: string };
 
 const VERSION = packageJson.version;
 
+// How long past `deploy --timeout` the deploy gets to fail on its own (helm
+// stops at its capped --timeout and the failure is recorded) before the
+// backstop kills it.
+const DEPLOY_TIMEOUT_GRACE_MS = 60_000;
+
 const program = new Command();
 
+// Accumulates a repeatable option's values (commander calls it per use).
+function collectOption(value: string, previous: string[]): string[] {
+  return [...previous, value];
+}
+
 program
   .name("rulebricks")
   .description("CLI for deploying and managing private Rulebricks instances")
@@ -48,20 +211,126 @@ program
     "-n, --name <name>",
     "Deployment name (alternative to positional argument)",
   )
+  .option(
+    "--from-existing",
+    "Rebuild config.yaml from a Rulebricks release running in the current cluster",
+  )
   .action(async (name, options) => {
     const deploymentName = name || options.name;
+    if (options.fromExisting) {
+      await importExistingDeployment(deploymentName);
+      return;
+    }
     const { waitUntilExit } = render(
       <InitWizard initialName={deploymentName} />,
     );
     await waitUntilExit();
   });
 
+/**
+ * `init --from-existing`: finds the deployment's release in the current
+ * cluster and writes a best-effort config.yaml from its Helm values, listing
+ * what it could not recover as TODO comments.
+ */
+async function importExistingDeployment(name: string | undefined) {
+  const clusterError = await checkClusterAccessible();
+  if (clusterError) {
+    console.error(
+      chalk.red(`Cannot access Kubernetes cluster:\n${clusterError}`),
+    );
+    process.exit(1);
+  }
+
+  let releases: Awaited<ReturnType<typeof listRulebricksReleases>>;
+  try {
+    releases = await listRulebricksReleases();
+  } catch (error) {
+    console.error(
+      chalk.red(
+        `Could not list Helm releases: ${error instanceof Error ? error.message : String(error)}`,
+      ),
+    );
+    process.exit(1);
+  }
+  const release = name
+    ? releases.find((r) => r.name === getReleaseName(name))
+    : releases.length === 1
+      ? releases[0]
+      : undefined;
+  if (!release) {
+    const found = releases
+      .map((r) => r.name.replace(/^rulebricks-/, ""))
+      .join(", ");
+    console.error(
+      chalk.red(
+        name
+          ? `No Rulebricks release for "${name}" in this cluster.`
+          : releases.length === 0
+            ? "No Rulebricks releases found in this cluster."
+            : `Several Rulebricks deployments found (${found}); name one.`,
+      ),
+    );
+    process.exit(1);
+  }
+  const deploymentName = release.name.replace(/^rulebricks-/, "");
+  if (await deploymentExists(deploymentName)) {
+    console.error(
+      chalk.red(
+        `Deployment "${deploymentName}" already has a local config; remove ${getDeploymentDir(deploymentName)} to re-import it.`,
+      ),
+    );
+    process.exit(1);
+  }
+
+  const [values, chartVersion, capabilities, kubeCluster] = await Promise.all([
+    getReleaseUserValues(release.name, release.namespace),
+    getInstalledChartVersion(release.name, release.namespace),
+    inferClusterCapabilities(),
+    getCurrentContextCluster(),
+  ]);
+  if (!values) {
+    console.error(
+      chalk.red(`Could not read the Helm values of ${release.name}.`),
+    );
+    process.exit(1);
+  }
+
+  const imported = importConfigFromValues({
+    name: deploymentName,
+    values,
+    chartVersion,
+    location: kubeCluster ? parseKubeClusterName(kubeCluster) : undefined,
+    capabilities,
+  });
+  const configPath = await saveImportedDeploymentConfig(
+    deploymentName,
+    imported.config,
+    importedConfigHeader(release.name, release.namespace, imported.todos),
+  );
+
+  console.log(chalk.green(`✓ Wrote ${configPath}`));
+  if (imported.todos.length > 0) {
+    console.log(chalk.yellow("\nFill in before deploying:"));
+    for (const todo of imported.todos) {
+      console.log(chalk.yellow(`  - ${todo}`));
+    }
+  }
+  console.log(
+    chalk.gray(
+      `\nCheck it with "rulebricks config validate ${deploymentName}", then "rulebricks deploy ${deploymentName} --observe-only" to compare it with the live release.`,
+    ),
+  );
+}
+
 // Deploy command
 program
   .command("deploy")
   .description("Deploy Rulebricks to your cluster")
   .argument("[name]", "Deployment name")
-  .option("--chart-version <version>", "Specific chart version to deploy")
+  .option(
+    "--chart-version <version>",
+    "Chart version to deploy (default: the config's chartVersion, else the last deployed version; \"latest\" to bump)",
+  )
   .option("--version <version>", "Deprecated alias for --chart-version")
   .option(
     "--inline-secrets",
@@ -71,7 +340,250 @@ program
     "--sync-secrets",
     "Overwrite the secrets manager entries with this config's values (default: create missing entries only, preserving rotated values)",
   )
+  .option(
+    "--progress <mode>",
+    `Progress output: ${PROGRESS_MODES.join(", ")} (plain/json write step events to stdout and the UI to stderr)`,
+    "auto",
+  )
+  .option(
+    "--since-state",
+    "Only run the steps affected by config changes since the last successful deploy (falls back to a full deploy when unsure)",
+  )
+  .option(
+    "--components <list>",
+    `Only run these deploy steps on top of a previous successful deploy (${DEPLOY_PHASES.join(", ")})`,
+  )
+  .option(
+    "--watch-rollout",
+    "Show live pod status and warning events while workloads install",
+  )
+  .option(
+    "--dry-run",
+    "Show what the deploy would do and render the chart's manifests without changing anything",
+  )
+  .option(
+    "--observe-only",
+    "Compare the live release (chart, values, replicas, images) with what the config would deploy; exits 2 on drift",
+  )
+  .option(
+    "--output <format>",
+    "Final result: text, or json to print one JSON object on stdout when the deploy ends (the UI and progress go to stderr)",
+    "text",
+  )
+  .option(
+    "--adopt",
+    "Take over an existing release of the same name that the CLI did not install",
+  )
+  .option(
+    "--skip-dns-check",
+    "Without a terminal, enable TLS even when DNS does not point at the load balancer yet",
+  )
+  .option(
+    "--health-port <port>",
+    "Serve /healthz, /metrics and the current step over HTTP while deploying",
+  )
+  .option(
+    "--health-host <host>",
+    "Address the --health-port server listens on",
+    DEFAULT_HEALTH_HOST,
+  )
+  .option(
+    "--retry-failed-step <count>",
+    "Re-run a failed retry-safe step (identity, Helm install, TLS upgrade) up to this many times with backoff",
+  )
+  .option(
+    "--pin-version",
+    "Write the chart version this deploy installs into the config (chartVersion)",
+  )
+  .option(
+    "--component-timeout <spec>",
+    `Per-component wait deadlines, e.g. chart=30m,certificates=10m (components: ${TIMEOUT_COMPONENTS.join(", ")})`,
+  )
+  .option(
+    "--set <component.path=value>",
+    "Override a Helm value for this deploy only, e.g. rulebricks.hps.workers.keda.lagThreshold=100 (repeatable)",
+    collectOption,
+    [],
+  )
+  .option(
+    "--set-file <component.path=file>",
+    "Override a Helm value with a file's contents for this deploy only (repeatable)",
+    collectOption,
+    [],
+  )
+  .option(
+    "--skip-monitoring",
+    "Leave monitoring (Prometheus) as currently installed for this deploy, whatever the config says",
+  )
+  .option(
+    "--skip-logging",
+    "Leave logging (Vector) as currently installed for this deploy, whatever the config says",
+  )
+  .option(
+    "--use-current-context",
+    "Deploy to kubectl's current context even when it isn't the config's infrastructure.clusterName",
+  )
+  .option(
+    "--config-url <url>",
+    `Fetch config.yaml from an https://, s3:// or gs:// URL first (bearer token from ${CONFIG_TOKEN_ENV})`,
+  )
+  .option(
+    "--timeout <duration>",
+    "Fail the whole deploy if it has not finished within this long, e.g. 45m (default: no limit)",
+  )
+  .option(
+    "--notify <url>",
+    "Post a summary to this webhook (or Slack incoming webhook) when the deploy ends, instead of the config's notifications",
+  )
   .action(async (name, options) => {
+    let deployTimeoutSeconds: number | undefined;
+    if (options.timeout !== undefined) {
+      try {
+        deployTimeoutSeconds = parseDuration(options.timeout);
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
+    let retryFailedStep = 0;
+    if (options.retryFailedStep !== undefined) {
+      try {
+        retryFailedStep = parseRetryCount(options.retryFailedStep);
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
+    let componentTimeouts: ComponentTimeouts | undefined;
+    if (options.componentTimeout) {
+      try {
+        componentTimeouts = parseComponentTimeouts(options.componentTimeout);
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
+    let components: DeployPhase[] | undefined;
+    if (options.components !== undefined) {
+      if (options.sinceState) {
+        console.error(
+          chalk.red("--components and --since-state cannot be combined."),
+        );
+        process.exit(1);
+      }
+      try {
+        components = parseDeployComponents(options.components);
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
+    let valueOverrides: HelmValueOverrides | undefined;
+    if (options.set.length > 0 || options.setFile.length > 0) {
+      if (options.observeOnly) {
+        console.error(
+          chalk.red(
+            "--set and --set-file cannot be combined with --observe-only.",
+          ),
+        );
+        process.exit(1);
+      }
+      try {
+        for (const spec of options.set as string[]) {
+          parseValueOverride(spec, "--set");
+        }
+        const setFile: string[] = [];
+        for (const spec of options.setFile as string[]) {
+          const { path: valuePath, value } = parseValueOverride(
+            spec,
+            "--set-file",
+          );
+          const file = path.resolve(value);
+          await fs.access(file).catch(() => {
+            throw new Error(`--set-file ${valuePath}: ${value} does not exist`);
+          });
+          setFile.push(`${valuePath}=${file}`);
+        }
+        valueOverrides = { set: options.set, setFile };
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
+    const skipComponents: SkippableComponent[] = [
+      ...(options.skipMonitoring ? ["monitoring" as const] : []),
+      ...(options.skipLogging ? ["logging" as const] : []),
+    ];
+    if (skipComponents.length > 0 && (options.dryRun || options.observeOnly)) {
+      console.error(
+        chalk.red(
+          "--skip-monitoring and --skip-logging cannot be combined with --dry-run or --observe-only.",
+        ),
+      );
+      process.exit(1);
+    }
+
+    let notify: NotificationTarget | undefined;
+    if (options.notify !== undefined) {
+      try {
+        notify = parseNotifyUrl(options.notify);
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
+    if (!isProgressMode(options.progress)) {
+      console.error(
+        chalk.red(
+          `Invalid --progress "${options.progress}". Use one of: ${PROGRESS_MODES.join(", ")}.`,
+        ),
+      );
+      process.exit(1);
+    }
+
+    if (!isOutputFormat(options.output)) {
+      console.error(
+        chalk.red(
+          `Invalid --output "${options.output}". Use one of: ${OUTPUT_FORMATS.join(", ")}.`,
+        ),
+      );
+      process.exit(1);
+    }
+    const jsonOutput = options.output === "json";
+
+    if (options.configUrl) {
+      try {
+        const content = await fetchRemoteConfig(options.configUrl);
+        name = await installDeploymentConfig(content, name);
+        console.error(
+          chalk.gray(`Using the config for ${name} from ${options.configUrl}`),
+        );
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
     const deploymentName = name || (await selectDeployment("deploy"));
     if (!deploymentName) {
       console.error(
@@ -80,15 +592,177 @@ program
       process.exit(1);
     }
 
-    const { waitUntilExit } = render(
+    if (options.observeOnly) {
+      // --output json: the report alone on stdout, the UI on stderr.
+      const observed: { report?: DriftReport | null } = {};
+      const { waitUntilExit } = render(
+        <DeployObserveCommand
+          name={deploymentName}
+          version={options.chartVersion || options.version}
+          onReport={(report) => {
+            observed.report = report;
+          }}
+        />,
+        jsonOutput ? { stdout: process.stderr } : undefined,
+      );
+      await waitUntilExit();
+      if (!observed.report) {
+        process.exitCode = 1;
+        return;
+      }
+      if (jsonOutput) console.log(JSON.stringify(observed.report, null, 2));
+      if (observed.report.drifted) process.exitCode = 2;
+      return;
+    }
+
+    if (options.dryRun) {
+      const { waitUntilExit } = render(
+        <DeployDryRunCommand
+          name={deploymentName}
+          version={options.chartVersion || options.version}
+          inlineSecrets={options.inlineSecrets}
+          valueOverrides={valueOverrides}
+        />,
+      );
+      await waitUntilExit();
+      return;
+    }
+
+    const health = await startHealthServerOrExit(options, "deploy");
+
+    const eventsOnStdout =
+      resolveProgressMode(options.progress, Boolean(process.stdout.isTTY)) !==
+      "tty";
+    // --output json keeps stdout for the result alone.
+    const events: ProgressEvent[] = [];
+    const outcome: { result?: DeployResult } = {};
+    const startedAt = new Date();
+    const deadlineAt = deployTimeoutSeconds
+      ? startedAt.getTime() + deployTimeoutSeconds * 1000
+      : undefined;
+    const { waitUntilExit, unmount } = render(
       <DeployCommand
         name={deploymentName}
         version={options.chartVersion || options.version}
         inlineSecrets={options.inlineSecrets}
         syncSecrets={options.syncSecrets}
+        progress={options.progress}
+        componentTimeouts={componentTimeouts}
+        deadlineAt={deadlineAt}
+        retryFailedStep={retryFailedStep}
+        pinVersion={options.pinVersion}
+        sinceState={options.sinceState}
+        components={components}
+        valueOverrides={valueOverrides}
+        skipComponents={skipComponents}
+        notify={notify}
+        watchRollout={options.watchRollout}
+        adopt={options.adopt}
+        useCurrentContext={options.useCurrentContext}
+        skipDnsCheck={options.skipDnsCheck}
+        onProgressEvent={(event) => {
+          events.push(event);
+          health?.record(event);
+        }}
+        writeProgress={
+          jsonOutput
+            ? (line) => process.stderr.write(`${line}\n`)
+            : undefined
+        }
+        onFinish={
+          jsonOutput
+            ? (summary) => {
+                outcome.result = buildDeployResult(
+                  deploymentName,
+                  events,
+                  summary,
+                );
+              }
+            : undefined
+        }
       />,
+      eventsOnStdout || jsonOutput ? { stdout: process.stderr } : undefined,
     );
+
+    // --timeout: the deploy caps helm and its other waits at the deadline and
+    // fails through its own error path. This backstop is for a step that
+    // ignores it (a hung cloud CLI call): after a grace period it exits,
+    // killing whatever process is still running.
+    const deadline = deployTimeoutSeconds
+      ? setTimeout(async () => {
+          // Only take over a deploy that hasn't recorded an outcome; one
+          // that has is finishing and will exit on its own.
+          let claimed = false;
+          const state = await updateDeploymentState(deploymentName, (st) => {
+            if (st.status !== "deploying" && st.status !== "waiting-dns") {
+              return st;
+            }
+            claimed = true;
+            return {
+              ...st,
+              status: "failed",
+              updatedAt: new Date().toISOString(),
+            };
+          });
+          if (state && !claimed) return;
+          const aborted = abortEvents(events, new Date(), "timed out");
+          const running = aborted
+            .map((event) => event.step)
+            .filter((step) => step !== "deploy")
+            .map(
+              (step) =>
+                PROGRESS_LABELS[step as keyof typeof PROGRESS_LABELS] ?? step,
+            );
+          const message = `Deploy timed out after ${options.timeout}${
+            running.length > 0 ? ` during: ${running.join(", ")}` : ""
+          }. Check "rulebricks status ${deploymentName}", then deploy again.`;
+          aborted.forEach((event) => health?.record(event));
+          const event = finishEvent({
+            action: "deploy",
+            startedAt,
+            error: message,
+          });
+          await recordDeploymentEvent(deploymentName, event);
+          const config = await loadDeploymentConfig(deploymentName).catch(
+            () => null,
+          );
+          const notifyWarnings = await sendNotifications(
+            notificationTargets(config?.notifications, notify),
+            {
+              deployment: deploymentName,
+              outcome: "failed",
+              durationMs: event.durationMs,
+              version: config?.version,
+              failedStep: running.join(", ") || undefined,
+              error: message,
+            },
+          );
+          // Exit in the same tick as unmount, before the awaiting code below
+          // resumes and treats the deploy as finished.
+          unmount();
+          if (jsonOutput) {
+            const result = buildDeployResult(
+              deploymentName,
+              [...events, ...aborted],
+              { error: message, warnings: [] },
+            );
+            console.log(JSON.stringify(result, null, 2));
+          } else {
+            console.error(chalk.red(message));
+          }
+          notifyWarnings.forEach((warning) =>
+            console.error(chalk.yellow(warning)),
+          );
+          process.exit(1);
+        }, deployTimeoutSeconds * 1000 + DEPLOY_TIMEOUT_GRACE_MS)
+      : undefined;
     await waitUntilExit();
+    if (deadline) clearTimeout(deadline);
+    await health?.close();
+    if (outcome.result) {
+      console.log(JSON.stringify(outcome.result, null, 2));
+      if (!outcome.result.success) process.exitCode = 1;
+    }
   });
 
 // Configure command
@@ -114,7 +788,7 @@ program
   });
 
 // Upgrade command
-program
+const upgradeCommand = program
   .command("upgrade")
   .description("Upgrade Rulebricks to a new version")
   .argument("[name]", "Deployment name")
@@ -124,7 +798,29 @@ program
     "Upgrade the infrastructure chart version instead of the app version",
   )
   .option("--dry-run", "Preview changes without applying")
+  .option(
+    "--force",
+    "With --chart, upgrade even when the target skips a required intermediate release",
+  )
+  .option(
+    "--no-rollback",
+    "Leave a failed upgrade in place for debugging instead of rolling it back",
+  )
+  .option(
+    "--health-port <port>",
+    "Serve /healthz, /metrics and the current step over HTTP while upgrading",
+  )
+  .option(
+    "--health-host <host>",
+    "Address the --health-port server listens on",
+    DEFAULT_HEALTH_HOST,
+  )
   .action(async (name, options) => {
+    if (options.force && !options.chart) {
+      console.error(chalk.red("--force only applies to --chart upgrades."));
+      process.exit(1);
+    }
+
     const deploymentName = name || (await selectDeployment("upgrade"));
     if (!deploymentName) {
       console.error(
@@ -133,14 +829,20 @@ program
       process.exit(1);
     }
 
+    const health = await startHealthServerOrExit(options, "upgrade");
+
     if (options.chart) {
       const { waitUntilExit } = render(
         <ChartUpgradeCommand
           name={deploymentName}
           targetVersion={options.version}
+          force={options.force}
+          noRollback={options.rollback === false}
+          onProgressEvent={health?.record}
         />,
       );
       await waitUntilExit();
+      await health?.close();
       return;
     }
 
@@ -149,6 +851,42 @@ program
         name={deploymentName}
         targetVersion={options.version}
         dryRun={options.dryRun}
+        noRollback={options.rollback === false}
+        onProgressEvent={health?.record}
+      />,
+    );
+    await waitUntilExit();
+    await health?.close();
+  });
+
+upgradeCommand
+  .command("rollback")
+  .description(
+    "Roll the release back to the revision that served before the last upgrade",
+  )
+  .argument("[name]", "Deployment name")
+  .option(
+    "--revert-config",
+    "Also set config.yaml's version (and chartVersion, when pinned) to the rolled-back release",
+  )
+  .option(
+    "--use-current-context",
+    "Roll back in kubectl's current context even when it isn't the config's infrastructure.clusterName",
+  )
+  .action(async (name, options) => {
+    const deploymentName = name || (await selectDeployment("roll back"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
+      <UpgradeRollbackCommand
+        name={deploymentName}
+        revertConfig={options.revertConfig}
+        useCurrentContext={options.useCurrentContext}
       />,
     );
     await waitUntilExit();
@@ -165,7 +903,21 @@ program
     "--purge",
     "Force removal of cluster-shared CRDs (cert-manager/keda/strimzi/prometheus); by default they're removed only when this is the last Rulebricks deployment on the cluster",
   )
+  .option(
+    "--keep-data",
+    "Keep the namespace and its persistent volumes (database, Kafka) so a later deploy reinstalls onto them",
+  )
   .action(async (name, options) => {
+    // The kept database only opens with the credentials in config.yaml.
+    if (options.keepData && options.config) {
+      console.error(
+        chalk.red(
+          "--keep-data needs the local config (it holds the database credentials); drop --config.",
+        ),
+      );
+      process.exit(1);
+    }
+
     // For destroy, require explicit deployment name
     if (!name) {
       const deployments = await listDeployments();
@@ -190,6 +942,34 @@ program
         config={options.config}
         force={options.force}
         purge={options.purge}
+        keepData={options.keepData}
+      />,
+    );
+    await waitUntilExit();
+  });
+
+// Namespace commands
+const namespacesCommand = program
+  .command("namespaces")
+  .description("Manage Rulebricks namespaces on the current cluster");
+
+namespacesCommand
+  .command("cleanup")
+  .description(
+    "Release Rulebricks namespaces stuck Terminating after a failed destroy",
+  )
+  .argument("[name]", "Only this deployment's namespace")
+  .option("-f, --force", "Skip the confirmation prompt")
+  .option(
+    "--finalize",
+    "If a namespace still won't finish, clear its own finalizers (orphans anything left inside)",
+  )
+  .action(async (name, options) => {
+    const { waitUntilExit } = render(
+      <NamespacesCleanupCommand
+        name={name}
+        force={options.force}
+        finalize={options.finalize}
       />,
     );
     await waitUntilExit();
@@ -200,7 +980,53 @@ program
   .command("status")
   .description("Show deployment status")
   .argument("[name]", "Deployment name")
-  .action(async (name) => {
+  .option(
+    "--repair",
+    "Fix what status finds: restart crash-looping pods, reissue failed certificates, reseed Realtime",
+  )
+  .option("-y, --yes", "With --repair, apply without confirmation")
+  .option(
+    "-w, --watch",
+    "Keep refreshing until every component is healthy (Ctrl+C to stop)",
+  )
+  .option("--interval <duration>", "Refresh interval for --watch", "5s")
+  .option(
+    "--resources",
+    "Include per-node CPU/memory (allocatable, requested, used) and pods that do not fit",
+  )
+  .option(
+    "--output <format>",
+    `Output format: ${OUTPUT_FORMATS.join(", ")} (json prints one status document to stdout)`,
+    "text",
+  )
+  .action(async (name, options) => {
+    if (!isOutputFormat(options.output)) {
+      console.error(
+        chalk.red(
+          `Invalid --output "${options.output}". Use one of: ${OUTPUT_FORMATS.join(", ")}.`,
+        ),
+      );
+      process.exit(1);
+    }
+    if (options.output === "json" && (options.watch || options.repair)) {
+      console.error(
+        chalk.red("--output json cannot be combined with --watch or --repair."),
+      );
+      process.exit(1);
+    }
+
+    let intervalSeconds = 5;
+    if (options.watch) {
+      try {
+        intervalSeconds = parseDuration(options.interval);
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+    }
+
     const deploymentName = name || (await selectDeployment("show status for"));
     if (!deploymentName) {
       console.error(
@@ -209,39 +1035,147 @@ program
       process.exit(1);
     }
 
-    const { waitUntilExit } = render(<StatusCommand name={deploymentName} />);
+    if (options.output === "json") {
+      try {
+        const report = await collectStatusReport(deploymentName, {
+          resources: options.resources,
+        });
+        console.log(JSON.stringify(report, null, 2));
+        if (report.configError) process.exitCode = 1;
+      } catch (err) {
+        console.error(
+          chalk.red(err instanceof Error ? err.message : String(err)),
+        );
+        process.exit(1);
+      }
+      return;
+    }
+
+    const { waitUntilExit } = render(
+      options.repair ? (
+        <RepairCommand name={deploymentName} yes={options.yes} />
+      ) : (
+        <StatusCommand
+          name={deploymentName}
+          watch={options.watch}
+          intervalMs={intervalSeconds * 1000}
+          resources={options.resources}
+        />
+      ),
+    );
     await waitUntilExit();
   });
 
 // Logs command
-program
+const logsCommand = program
   .command("logs")
   .description("View component logs")
   .argument("[name]", "Deployment name")
   .argument(
     "[component]",
-    "Component: app, hps, workers, kafka, supabase, traefik",
+    `Component: ${[...VALID_LOG_COMPONENTS, ALL_LOG_COMPONENTS].join(", ")} (all interleaves every component)`,
   )
   .option("-f, --follow", "Follow log output (default: true)")
   .option("--no-follow", "Show logs once without following")
   .option("-t, --tail <lines>", "Number of lines to show", "100")
   .option("-s, --split", "Show logs in split-pane view (side-by-side columns)")
+  .option(
+    "--output <format>",
+    `Log output: ${LOG_OUTPUT_FORMATS.join(", ")} (json writes one {component, pod, container, timestamp, message} object per line to stdout)`,
+    "text",
+  )
   .action(async (name, component, options) => {
-    const deploymentName = name || (await selectDeployment("view logs for"));
-    if (!deploymentName) {
+    const outputFormat: string = options.output;
+    if (!isLogOutputFormat(outputFormat)) {
       console.error(
-        chalk.red('No deployments found. Run "rulebricks init" first.'),
+        chalk.red(
+          `Invalid --output "${outputFormat}". Use one of: ${LOG_OUTPUT_FORMATS.join(", ")}.`,
+        ),
       );
       process.exit(1);
     }
-
-    const { waitUntilExit } = render(
+    if (
+      component &&
+      component !== ALL_LOG_COMPONENTS &&
+      !VALID_LOG_COMPONENTS.includes(component)
+    ) {
+      console.error(
+        chalk.red(
+          `Unknown component "${component}". Use one of: ${[...VALID_LOG_COMPONENTS, ALL_LOG_COMPONENTS].join(", ")}.`,
+        ),
+      );
+      process.exit(1);
+    }
+
+    const deploymentName = name || (await selectDeployment("view logs for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
       <LogsCommand
         name={deploymentName}
         component={component}
         follow={options.follow}
         tail={parseInt(options.tail, 10)}
         split={options.split}
+        outputFormat={outputFormat}
+      />,
+      outputFormat === "json" ? { stdout: process.stderr } : undefined,
+    );
+    await waitUntilExit();
+  })
+  // `logs export --output <file>` must not be read as the parent's --output.
+  .enablePositionalOptions();
+
+logsCommand
+  .command("export")
+  .description(
+    "Write every pod's logs, descriptions and recent events to a .tar.gz for support",
+  )
+  .argument("[name]", "Deployment name")
+  .option("--since <duration>", "Only logs from this long ago, e.g. 30m", "1h")
+  .option("-t, --tail <lines>", "At most this many lines per container")
+  .option(
+    "-o, --output <file>",
+    "Archive to write (default: rulebricks-<name>-logs-<time>.tar.gz)",
+  )
+  .action(async (name, options) => {
+    let sinceSeconds: number;
+    let tail: number | undefined;
+    try {
+      sinceSeconds = parseDuration(options.since);
+      if (options.tail !== undefined) {
+        tail = Number(options.tail);
+        if (!Number.isInteger(tail) || tail < 1) {
+          throw new Error(
+            `Invalid --tail "${options.tail}": expected a positive number of lines.`,
+          );
+        }
+      }
+    } catch (err) {
+      console.error(
+        chalk.red(err instanceof Error ? err.message : String(err)),
+      );
+      process.exit(1);
+    }
+
+    const deploymentName = name || (await selectDeployment("export logs for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
+      <LogsExportCommand
+        name={deploymentName}
+        output={options.output}
+        options={{ sinceSeconds, tail }}
       />,
     );
     await waitUntilExit();
@@ -256,6 +1190,219 @@ program
     await waitUntilExit();
   });
 
+// History command
+program
+  .command("history")
+  .description(
+    "Show the deploys, upgrades, rollbacks and destroys recorded for a deployment",
+  )
+  .argument("[name]", "Deployment name")
+  .option("--output <format>", "Output format: text, json", "text")
+  .action(async (name, options) => {
+    if (!isOutputFormat(options.output)) {
+      console.error(
+        chalk.red(
+          `Invalid --output "${options.output}". Use one of: ${OUTPUT_FORMATS.join(", ")}.`,
+        ),
+      );
+      process.exit(1);
+    }
+    const deploymentName = name || (await selectDeployment("show history for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const state = await loadDeploymentState(deploymentName);
+    const events = state?.history ?? [];
+    if (options.output === "json") {
+      console.log(JSON.stringify(events, null, 2));
+      return;
+    }
+    if (events.length === 0) {
+      console.log(chalk.gray(`No runs recorded for ${deploymentName} yet.`));
+      return;
+    }
+
+    const [header, ...rows] = formatTable(historyRows(events));
+    console.log(chalk.bold(header));
+    rows.forEach((row, i) =>
+      console.log(events[i].outcome === "failed" ? chalk.red(row) : row),
+    );
+  });
+
+// Doctor command
+const DOCTOR_LABELS: Record<DoctorStatus, string> = {
+  pass: chalk.green("PASS"),
+  warn: chalk.yellow("WARN"),
+  fail: chalk.red("FAIL"),
+};
+
+program
+  .command("doctor")
+  .description(
+    "Check tools, cluster access, pods, volumes, certificates, Kafka topics and events, and report pass/warn/fail",
+  )
+  .argument("[name]", "Deployment name")
+  .option(
+    "--vector",
+    "Also send a test event through every Vector sink (runs a one-shot job in the cluster)",
+  )
+  .option(
+    "--output <format>",
+    `Output format: ${OUTPUT_FORMATS.join(", ")} (json prints the report for a support ticket)`,
+    "text",
+  )
+  .action(async (name, options) => {
+    if (!isOutputFormat(options.output)) {
+      console.error(
+        chalk.red(
+          `Invalid --output "${options.output}". Use one of: ${OUTPUT_FORMATS.join(", ")}.`,
+        ),
+      );
+      process.exit(1);
+    }
+    const deploymentName = name || (await selectDeployment("diagnose"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    let report: DoctorReport;
+    try {
+      report = await collectDoctorReport(deploymentName, {
+        vector: options.vector,
+      });
+    } catch (err) {
+      console.error(
+        chalk.red(err instanceof Error ? err.message : String(err)),
+      );
+      process.exit(1);
+    }
+    if (report.status === "fail") process.exitCode = 1;
+    if (options.output === "json") {
+      console.log(JSON.stringify(report, null, 2));
+      return;
+    }
+
+    let category: string | null = null;
+    for (const check of report.checks) {
+      if (check.category !== category) {
+        if (category) console.log();
+        category = check.category;
+        console.log(chalk.bold(category));
+      }
+      const detail = check.detail ? chalk.gray(` ${check.detail}`) : "";
+      console.log(`  ${DOCTOR_LABELS[check.status]} ${check.name}${detail}`);
+    }
+    const counts = (["pass", "warn", "fail"] as const).map(
+      (status) =>
+        `${report.checks.filter((c) => c.status === status).length} ${status}`,
+    );
+    console.log(`\n${deploymentName}: ${counts.join(", ")}`);
+  });
+
+// Scale commands
+const scaleCommand = program
+  .command("scale")
+  .description("Adjust a deployment's autoscaling bounds");
+
+scaleCommand
+  .command("workers")
+  .description(
+    "Set the HPS worker fleet's KEDA min/max replicas, now and for later deploys",
+  )
+  .argument("[name]", "Deployment name")
+  .option("--min <count>", "Fewest workers KEDA keeps running")
+  .option("--max <count>", "Most workers KEDA scales out to")
+  .option("--replicas <count>", "Pin the fleet at exactly this many workers")
+  .action(async (name, options) => {
+    const deploymentName =
+      name || (await selectDeployment("scale workers for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    try {
+      const cfg = await loadDeploymentConfig(deploymentName);
+      const clusterError = await ensureConfiguredCluster(cfg, true);
+      if (clusterError) throw new Error(clusterError);
+
+      const namespace = getNamespace(deploymentName);
+      const workers = `${getReleaseName(deploymentName)}-hps-worker`;
+      const scaledObject = await getScaledObjectFor(namespace, workers);
+      if (!scaledObject) {
+        throw new Error(
+          `No KEDA ScaledObject scales ${workers} in ${namespace}. Deploy first with "rulebricks deploy ${deploymentName}".`,
+        );
+      }
+
+      const values = await loadHelmValues(deploymentName);
+      const scale = resolveWorkerScale(
+        options,
+        {
+          minReplicas: scaledObject.minReplicaCount,
+          maxReplicas: scaledObject.maxReplicaCount,
+        },
+        solutionPartitions(values),
+      );
+      await patchScaledObjectBounds(
+        namespace,
+        scaledObject.name,
+        scale.minReplicas,
+        scale.maxReplicas,
+      );
+      if (values) {
+        await saveHelmValues(
+          deploymentName,
+          applyWorkerScaleToValues(values, scale),
+        );
+      }
+
+      console.log(
+        chalk.green(
+          `✓ Workers for ${deploymentName} now scale between ${scale.minReplicas} and ${scale.maxReplicas} replicas.`,
+        ),
+      );
+      if (!values) {
+        console.log(
+          chalk.yellow(
+            "No values.yaml to record this in; the next deploy resets the bounds to the chart defaults.",
+          ),
+        );
+      }
+    } catch (err) {
+      console.error(
+        chalk.red(err instanceof Error ? err.message : String(err)),
+      );
+      process.exit(1);
+    }
+  });
+
+// Whoami command
+program
+  .command("whoami")
+  .description(
+    "Show the cloud identity (account, project, subscription) deploy and destroy will act as",
+  )
+  .argument("[name]", "Deployment name (checks its provider and flags mismatches)")
+  .action(async (name) => {
+    if (name && !(await deploymentExists(name))) {
+      console.error(chalk.red(`Deployment "${name}" not found.`));
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(<WhoamiCommand name={name} />);
+    await waitUntilExit();
+  });
+
 // Clone command
 program
   .command("clone")
@@ -303,23 +1450,63 @@ program
     await waitUntilExit();
   });
 
-// Benchmark command
-program
-  .command("benchmark")
-  .description("Run load tests against a Rulebricks deployment")
-  .argument("[name]", "Deployment name (optional)")
-  .action(async (name) => {
-    const { waitUntilExit } = render(<BenchmarkCommand name={name} />);
-    await waitUntilExit();
+// Config commands
+const configCommand = program
+  .command("config")
+  .description("Manage a deployment's config.yaml");
+
+configCommand
+  .command("encrypt")
+  .description(
+    "Encrypt the license key, passwords, and API keys in config.yaml with age",
+  )
+  .argument("[name]", "Deployment name")
+  .option(
+    "--recipient <recipient>",
+    "age public key to encrypt for (default: $RULEBRICKS_AGE_RECIPIENT)",
+  )
+  .action(async (name, options) => {
+    const deploymentName = name || (await selectDeployment("encrypt"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+    const recipient = resolveAgeRecipient(options.recipient);
+    if (!recipient) {
+      console.error(
+        chalk.red(
+          "No age recipient. Pass --recipient or set RULEBRICKS_AGE_RECIPIENT.",
+        ),
+      );
+      process.exit(1);
+    }
+
+    try {
+      const fields = await encryptDeploymentConfig(deploymentName, recipient);
+      if (fields.length === 0) {
+        console.log("No plaintext sensitive values to encrypt.");
+        return;
+      }
+      console.log(`Encrypted ${fields.length} value(s) in config.yaml:`);
+      for (const field of fields) {
+        console.log(`  ${chalk.yellow("•")} ${field}`);
+      }
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
   });
 
-// Backup command
-program
-  .command("backup")
-  .description("Run an on-demand database backup")
+configCommand
+  .command("decrypt")
+  .description("Decrypt encrypted values in config.yaml back to plaintext")
   .argument("[name]", "Deployment name")
   .action(async (name) => {
-    const deploymentName = name || (await selectDeployment("back up"));
+    const deploymentName = name || (await selectDeployment("decrypt"));
     if (!deploymentName) {
       console.error(
         chalk.red('No deployments found. Run "rulebricks init" first.'),
@@ -327,17 +1514,89 @@ program
       process.exit(1);
     }
 
-    const { waitUntilExit } = render(<BackupCommand name={deploymentName} />);
-    await waitUntilExit();
+    try {
+      const fields = await decryptDeploymentConfig(deploymentName);
+      if (fields.length === 0) {
+        console.log("No encrypted values in config.yaml.");
+        return;
+      }
+      console.log(`Decrypted ${fields.length} value(s) in config.yaml:`);
+      for (const field of fields) {
+        console.log(`  ${chalk.yellow("•")} ${field}`);
+      }
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
   });
 
-// Restore command
-program
-  .command("restore")
-  .description("Restore the database from a backup")
+configCommand
+  .command("validate")
+  .description(
+    "Check config.yaml for schema and cross-field problems without deploying",
+  )
+  .argument("[target]", "Deployment name or path to a config.yaml")
+  .option(
+    "--strict",
+    "Also report keys the config schema does not recognize",
+  )
+  .action(async (target, options) => {
+    let configPath: string;
+    let deploymentName: string | undefined;
+    if (target && /[\\/]|\.ya?ml$/.test(target)) {
+      configPath = target;
+    } else {
+      deploymentName = target || (await selectDeployment("validate"));
+      if (!deploymentName) {
+        console.error(
+          chalk.red('No deployments found. Run "rulebricks init" first.'),
+        );
+        process.exit(1);
+      }
+      configPath = path.join(getDeploymentDir(deploymentName), "config.yaml");
+    }
+
+    let issues: ConfigIssue[];
+    try {
+      issues = await validateConfigFile(configPath, {
+        strict: options.strict,
+        name: deploymentName,
+      });
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
+
+    if (issues.length > 0) {
+      console.log(formatConfigIssues(issues));
+    }
+    if (issues.some((issue) => issue.severity === "error")) {
+      process.exit(1);
+    }
+    console.log(chalk.green(`✓ ${configPath} is valid`));
+  });
+
+configCommand
+  .command("show")
+  .description("Print the config a deploy would use, with credentials masked")
   .argument("[name]", "Deployment name")
-  .action(async (name) => {
-    const deploymentName = name || (await selectDeployment("restore"));
+  .option(
+    "--resolve-secrets",
+    "Print credentials in plaintext instead of masking them",
+  )
+  .option("--output <format>", "Output format: yaml, json", "yaml")
+  .action(async (name, options) => {
+    if (options.output !== "yaml" && options.output !== "json") {
+      console.error(
+        chalk.red(`Invalid --output "${options.output}". Use yaml or json.`),
+      );
+      process.exit(1);
+    }
+    const deploymentName = name || (await selectDeployment("show"));
     if (!deploymentName) {
       console.error(
         chalk.red('No deployments found. Run "rulebricks init" first.'),
@@ -345,10 +1604,722 @@ program
       process.exit(1);
     }
 
-    const { waitUntilExit } = render(<RestoreCommand name={deploymentName} />);
-    await waitUntilExit();
+    try {
+      const { basePath } = await readLayeredConfig(
+        path.join(getDeploymentDir(deploymentName), "config.yaml"),
+      );
+      const config = await loadDeploymentConfig(deploymentName);
+      const shown = options.resolveSecrets
+        ? config
+        : await maskSensitiveValues(config);
+      if (basePath) {
+        console.error(chalk.gray(`# layered over ${basePath}`));
+      }
+      if (options.resolveSecrets) {
+        console.error(chalk.yellow("# credentials shown in plaintext"));
+      }
+      if (options.output === "json") {
+        console.log(JSON.stringify(shown, null, 2));
+      } else {
+        process.stdout.write(yaml.stringify(shown));
+      }
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
   });
 
+// Apply command
+program
+  .command("apply")
+  .description(
+    "Apply extra Kubernetes manifests into a deployment's namespace with its labels",
+  )
+  .argument("[name]", "Deployment name")
+  .requiredOption("-f, --filename <path>", "Manifest file to apply")
+  .option(
+    "--allow-cluster-scoped",
+    "Allow cluster-scoped objects (deleted again by destroy)",
+  )
+  .option(
+    "--use-current-context",
+    "Apply to kubectl's current context even when it isn't the config's infrastructure.clusterName",
+  )
+  .action(async (name, options) => {
+    const deploymentName = name || (await selectDeployment("apply to"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+    if (!(await deploymentExists(deploymentName))) {
+      console.error(chalk.red(`Deployment "${deploymentName}" not found.`));
+      process.exit(1);
+    }
+
+    try {
+      if (!options.useCurrentContext) {
+        const contextError = await ensureClusterContext(
+          await loadDeploymentConfig(deploymentName),
+        );
+        if (contextError) {
+          throw new Error(
+            `${contextError} Switch contexts, or pass --use-current-context to apply there anyway.`,
+          );
+        }
+      }
+
+      const namespace = getNamespace(deploymentName);
+      const prepared = prepareManifests(
+        parseManifests(await fs.readFile(options.filename, "utf-8")),
+        {
+          namespace,
+          releaseName: getReleaseName(deploymentName),
+          clusterScopedKinds: await getClusterScopedKinds(),
+          allowClusterScoped: options.allowClusterScoped,
+        },
+      );
+      if (prepared.errors.length > 0) {
+        console.error(chalk.red(`Nothing applied from ${options.filename}:`));
+        for (const issue of prepared.errors) {
+          console.error(`  ${chalk.red("•")} ${issue}`);
+        }
+        process.exit(1);
+      }
+
+      console.log(await applyManifests(prepared.manifests));
+
+      if (prepared.clusterScoped.length > 0) {
+        const state = await updateDeploymentState(deploymentName, (st) => ({
+          ...st,
+          appliedClusterResources: [
+            ...new Set([
+              ...(st.appliedClusterResources ?? []),
+              ...prepared.clusterScoped,
+            ]),
+          ],
+          updatedAt: new Date().toISOString(),
+        }));
+        if (!state) {
+          console.log(
+            chalk.yellow(
+              `No state recorded for "${deploymentName}"; destroy will not remove ${prepared.clusterScoped.join(", ")}.`,
+            ),
+          );
+        }
+      }
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
+  });
+
+// Components command
+const componentsCommand = program
+  .command("components")
+  .description("Describe what a Rulebricks deployment runs");
+
+componentsCommand
+  .command("list")
+  .description(
+    "List each component, its purpose, dependencies, and whether it is enabled",
+  )
+  .argument("[name]", "Deployment name (reads its generated values.yaml)")
+  .option("--output <format>", "Output format: text, json", "text")
+  .action(async (name, options) => {
+    if (options.output !== "text" && options.output !== "json") {
+      console.error(
+        chalk.red(`Invalid --output "${options.output}". Use text or json.`),
+      );
+      process.exit(1);
+    }
+    if (name && !(await deploymentExists(name))) {
+      console.error(chalk.red(`Deployment "${name}" not found.`));
+      process.exit(1);
+    }
+
+    const components = listComponents(
+      name ? getNamespace(name) : getNamespace("<name>"),
+      name ? await loadHelmValues(name) : null,
+    );
+
+    if (options.output === "json") {
+      console.log(JSON.stringify(components, null, 2));
+      return;
+    }
+
+    const width = Math.max(...components.map((c) => c.name.length));
+    console.log(
+      chalk.bold(`Components in namespace ${components[0]?.namespace}:`),
+    );
+    for (const component of components) {
+      const status =
+        component.status === "enabled"
+          ? chalk.green("enabled ")
+          : component.status === "disabled"
+            ? chalk.gray("disabled")
+            : chalk.yellow("unknown ");
+      console.log(
+        `  ${component.name.padEnd(width)}  ${status}  ${component.purpose}`,
+      );
+      if (component.dependsOn.length > 0) {
+        console.log(
+          chalk.gray(
+            `  ${" ".repeat(width)}            needs ${component.dependsOn.join(", ")}`,
+          ),
+        );
+      }
+    }
+  });
+
+// Monitoring commands
+const monitoringCommand = program
+  .command("monitoring")
+  .description("Access a deployment's in-cluster monitoring");
+
+monitoringCommand
+  .command("credentials")
+  .description("Print the in-cluster Grafana URL and admin login")
+  .argument("[name]", "Deployment name")
+  .option("--show-password", "Print the password instead of masking it")
+  .action(async (name, options) => {
+    const deploymentName =
+      name || (await selectDeployment("show Grafana credentials for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    // The secret is read even without a local config (e.g. it was lost).
+    const config = await loadDeploymentConfig(deploymentName).catch(
+      () => null,
+    );
+    if (config && config.features.monitoring.destination !== "local-grafana") {
+      console.error(
+        chalk.red(
+          `${deploymentName} does not run Grafana in the cluster (features.monitoring.destination is not "local-grafana").`,
+        ),
+      );
+      process.exit(1);
+    }
+
+    const namespace = getNamespace(deploymentName);
+    let credentials: GrafanaCredentials | null;
+    try {
+      credentials = parseGrafanaAdminSecret(
+        await getSecretsJson(namespace, "app.kubernetes.io/name=grafana"),
+      );
+    } catch (err) {
+      console.error(
+        chalk.red(err instanceof Error ? err.message : String(err)),
+      );
+      process.exit(1);
+    }
+    if (!credentials) {
+      console.error(
+        chalk.red(
+          `No Grafana admin secret found in ${namespace}. Is the deployment installed with local Grafana?`,
+        ),
+      );
+      process.exit(1);
+    }
+
+    console.log(chalk.bold(`Grafana for ${deploymentName}:`));
+    console.log(`  URL:      ${chalk.cyan("http://localhost:3000")}`);
+    console.log(
+      chalk.gray(
+        `            (first run ${grafanaPortForwardCommand(namespace, getReleaseName(deploymentName))})`,
+      ),
+    );
+    console.log(`  Username: ${credentials.username}`);
+    console.log(
+      `  Password: ${
+        options.showPassword
+          ? credentials.password
+          : `${maskSecret(credentials.password)} ${chalk.gray("(--show-password to reveal)")}`
+      }`,
+    );
+    console.log(
+      chalk.gray(`  From secret ${namespace}/${credentials.secretName}`),
+    );
+  });
+
+// Vector (decision-log pipeline) commands
+const vectorCommand = program
+  .command("vector")
+  .description("Check a deployment's decision-log pipeline");
+
+vectorCommand
+  .command("test")
+  .description(
+    "Send a synthetic event through every configured log sink and report which accept it",
+  )
+  .argument("[name]", "Deployment name")
+  .action(async (name) => {
+    const deploymentName = name || (await selectDeployment("test Vector for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
+      <VectorTestCommand name={deploymentName} />,
+    );
+    await waitUntilExit();
+  });
+
+// Email commands
+const emailCommand = program
+  .command("email")
+  .description("Check a deployment's outgoing email");
+
+emailCommand
+  .command("test")
+  .description(
+    "Connect and log in to the configured SMTP server, and optionally send a test message",
+  )
+  .argument("[name]", "Deployment name")
+  .option("--to <address>", "Also send a test message to this address")
+  .option("--output <format>", "Output format: text, json", "text")
+  .action(async (name, options) => {
+    if (!isOutputFormat(options.output)) {
+      console.error(
+        chalk.red(
+          `Invalid --output "${options.output}". Use one of: ${OUTPUT_FORMATS.join(", ")}.`,
+        ),
+      );
+      process.exit(1);
+    }
+    const deploymentName = name || (await selectDeployment("test email for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    let result: SmtpTestResult;
+    try {
+      const config = await loadDeploymentConfig(deploymentName);
+      result = await testSmtp(config.smtp, { to: options.to });
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
+
+    if (options.output === "json") {
+      console.log(JSON.stringify(result, null, 2));
+    } else {
+      console.log(
+        `${result.host}:${result.port} (${result.transport === "tls" ? "implicit TLS" : "STARTTLS"})`,
+      );
+      for (const step of result.steps) {
+        const mark = step.ok ? chalk.green("✓") : chalk.red("✗");
+        console.log(
+          `  ${mark} ${step.name}${step.detail ? chalk.gray(` ${step.detail}`) : ""}`,
+        );
+      }
+    }
+    if (!result.ok) process.exit(1);
+  });
+
+// Credential commands
+const secretsCommand = program
+  .command("secrets")
+  .description("Manage a deployment's credentials");
+
+secretsCommand
+  .command("rotate")
+  .description(
+    "Replace self-hosted Supabase credentials, update the secrets and restart what uses them",
+  )
+  .argument("[name]", "Deployment name")
+  .option("--jwt", "Rotate the JWT secret (and the anon and service keys)")
+  .option("--db-password", "Rotate the Postgres password")
+  .option("--dashboard", "Rotate the Studio dashboard password")
+  .option("-f, --force", "Skip the confirmation prompt")
+  .action(async (name, options) => {
+    const targets: RotationTarget[] = [
+      ...(options.jwt ? (["jwt"] as const) : []),
+      ...(options.dbPassword ? (["dbPassword"] as const) : []),
+      ...(options.dashboard ? (["dashboard"] as const) : []),
+    ];
+    if (targets.length === 0) {
+      console.error(
+        chalk.red(
+          "Choose what to rotate: --jwt, --db-password and/or --dashboard.",
+        ),
+      );
+      process.exit(1);
+    }
+
+    const deploymentName =
+      name || (await selectDeployment("rotate secrets for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
+      <SecretsRotateCommand
+        name={deploymentName}
+        targets={targets}
+        force={options.force}
+      />,
+    );
+    await waitUntilExit();
+  });
+
+// Supabase maintenance commands
+const supabaseCommand = program
+  .command("supabase")
+  .description("Maintain a deployment's self-hosted Supabase");
+
+supabaseCommand
+  .command("fix-realtime")
+  .description(
+    "Re-encrypt Realtime's tenant JWT secret after a secret change and restart Realtime",
+  )
+  .argument("[name]", "Deployment name")
+  .action(async (name) => {
+    const deploymentName = name || (await selectDeployment("repair"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
+      <FixRealtimeCommand name={deploymentName} />,
+    );
+    await waitUntilExit();
+  });
+
+supabaseCommand
+  .command("dump-config")
+  .description(
+    "Print the auth (GoTrue) environment the deployment's values produce, secrets redacted",
+  )
+  .argument("[name]", "Deployment name")
+  .option(
+    "--from-config",
+    "Generate values from config.yaml instead of reading the last generated values.yaml",
+  )
+  .option("--output <format>", "Output format: text, json", "text")
+  .action(async (name, options) => {
+    if (options.output !== "text" && options.output !== "json") {
+      console.error(
+        chalk.red(`Invalid --output "${options.output}". Use text or json.`),
+      );
+      process.exit(1);
+    }
+    const deploymentName = name || (await selectDeployment("inspect"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    let values: Record<string, unknown> | null = null;
+    let source = "values.yaml";
+    try {
+      const cfg = await loadDeploymentConfig(deploymentName);
+      if (cfg.database.type !== "self-hosted") {
+        console.error(
+          chalk.red(
+            "Auth runs in Supabase Cloud for this deployment; see the project's Auth settings there.",
+          ),
+        );
+        process.exit(1);
+      }
+      if (!options.fromConfig) {
+        values = await loadHelmValues(deploymentName);
+      }
+      if (!values) {
+        values = buildHelmValues(cfg, {
+          secretMode: secretModeForConfig(cfg),
+        });
+        source = "config.yaml";
+      }
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
+
+    const settings = effectiveAuthEnvironment(values);
+    if (options.output === "json") {
+      console.log(JSON.stringify(settings, null, 2));
+      return;
+    }
+    console.log(chalk.bold(`Auth environment for ${deploymentName}`));
+    console.log(chalk.gray(`(from ${source}; secrets redacted)`));
+    console.log(formatAuthEnvironment(settings));
+  });
+
+// Database commands
+const dbCommand = program
+  .command("db")
+  .description("Work with a deployment's database");
+
+dbCommand
+  .command("psql")
+  .description("Open a psql shell against the deployment's database")
+  .argument("[name]", "Deployment name")
+  .option(
+    "-c, --command <sql>",
+    "Run a single SQL statement non-interactively and exit",
+  )
+  .action(async (name, options) => {
+    const deploymentName = name || (await selectDeployment("connect to"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    let invocation: PsqlInvocation;
+    try {
+      const config = await loadDeploymentConfig(deploymentName);
+      invocation = psqlInvocation(config, {
+        sql: options.command,
+        tty: !options.command && Boolean(process.stdin.isTTY),
+      });
+      if (invocation.command === "kubectl") {
+        const clusterError = await checkClusterAccessible();
+        if (clusterError) {
+          throw new Error(
+            `Cannot access Kubernetes cluster:\n${clusterError}`,
+          );
+        }
+      }
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
+
+    // On stderr, so --command output stays clean for piping.
+    if (!options.command) {
+      console.error(chalk.gray(`Connecting to ${invocation.target}...`));
+    }
+    try {
+      process.exitCode = await runPsql(invocation);
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
+  });
+
+// Benchmark command
+program
+  .command("benchmark")
+  .description("Run load tests against a Rulebricks deployment")
+  .argument("[name]", "Deployment name (optional)")
+  .action(async (name) => {
+    const { waitUntilExit } = render(<BenchmarkCommand name={name} />);
+    await waitUntilExit();
+  });
+
+// Backup command
+const backupCommand = program
+  .command("backup")
+  .description("Run an on-demand database backup")
+  .argument("[name]", "Deployment name")
+  .option(
+    "-o, --output <file>",
+    "Write a pg_dump of the bundled database to a local file instead",
+  )
+  .action(async (name, options) => {
+    const deploymentName = name || (await selectDeployment("back up"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
+      <BackupCommand name={deploymentName} output={options.output} />,
+    );
+    await waitUntilExit();
+  });
+
+backupCommand
+  .command("list")
+  .description("List the database backups in object storage")
+  .argument("[name]", "Deployment name")
+  .action(async (name) => {
+    const deploymentName = name || (await selectDeployment("list backups for"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    const { waitUntilExit } = render(
+      <BackupListCommand name={deploymentName} />,
+    );
+    await waitUntilExit();
+  });
+
+async function runRestore(
+  name: string | undefined,
+  backupId: string | undefined,
+  options: { force?: boolean },
+) {
+  const deploymentName = name || (await selectDeployment("restore"));
+  if (!deploymentName) {
+    console.error(
+      chalk.red('No deployments found. Run "rulebricks init" first.'),
+    );
+    process.exit(1);
+  }
+  if (options.force && !backupId) {
+    console.error(chalk.red("--force needs a backup id to restore."));
+    process.exit(1);
+  }
+
+  const { waitUntilExit } = render(
+    <RestoreCommand
+      name={deploymentName}
+      backupId={backupId}
+      force={options.force}
+    />,
+  );
+  await waitUntilExit();
+}
+
+backupCommand
+  .command("restore")
+  .description("Restore the database from a backup (overwrites live data)")
+  .argument("[name]", "Deployment name")
+  .argument("[backup-id]", "Backup to restore (see `backup list`)")
+  .option("-f, --force", "Skip the confirmation prompt")
+  .action(runRestore);
+
+// Restore command
+program
+  .command("restore")
+  .description("Restore the database from a backup")
+  .argument("[name]", "Deployment name")
+  .argument("[backup-id]", "Backup to restore (see `backup list`)")
+  .option("-f, --force", "Skip the confirmation prompt")
+  .action(runRestore);
+
+// Migrate-cloud command
+program
+  .command("migrate-cloud")
+  .description(
+    "Assess moving a deployment to another cloud and print a migration runbook (changes nothing)",
+  )
+  .argument("[name]", "Deployment name")
+  .requiredOption("--to <provider>", "Target cloud provider (aws, gcp, azure)")
+  .option("--region <region>", "Target region")
+  .action(async (name, options) => {
+    if (!(options.to in CLOUD_PROVIDER_NAMES)) {
+      console.error(
+        chalk.red(
+          `Invalid --to "${options.to}". Use one of: ${Object.keys(CLOUD_PROVIDER_NAMES).join(", ")}`,
+        ),
+      );
+      process.exit(1);
+    }
+    const to = options.to as CloudProvider;
+
+    const deploymentName = name || (await selectDeployment("assess"));
+    if (!deploymentName) {
+      console.error(
+        chalk.red('No deployments found. Run "rulebricks init" first.'),
+      );
+      process.exit(1);
+    }
+
+    let config;
+    try {
+      config = await loadDeploymentConfig(deploymentName);
+    } catch (error) {
+      console.error(
+        chalk.red(error instanceof Error ? error.message : String(error)),
+      );
+      process.exit(1);
+    }
+
+    // Volume sizes come from the live cluster; the rest of the assessment
+    // works from the config alone.
+    const namespace = getNamespace(deploymentName);
+    let volumes: PersistentVolumeClaimInfo[] | null = null;
+    if (
+      !(await checkClusterAccessible()) &&
+      (await namespaceExists(namespace))
+    ) {
+      volumes = await getPersistentVolumeClaims(namespace).catch(() => null);
+    }
+
+    const assessment = assessCloudMigration({
+      config,
+      to,
+      region: options.region,
+      volumes,
+    });
+    console.log(
+      chalk.bold(
+        `Migrating ${deploymentName} to ${CLOUD_PROVIDER_NAMES[to]}${options.region ? ` (${options.region})` : ""}\n`,
+      ),
+    );
+    console.log(formatMigrationRunbook(assessment));
+    if (assessment.checks.some((check) => check.status === "blocker")) {
+      process.exit(1);
+    }
+  });
+
+/**
+ * Starts the --health-port server on --health-host when the option was
+ * given. An invalid or busy port is reported and exits before the operation
+ * starts.
+ */
+async function startHealthServerOrExit(
+  options: { healthPort?: string; healthHost?: string },
+  operation: string,
+): Promise<HealthServer | null> {
+  if (!options.healthPort) return null;
+  try {
+    return await startHealthServer(
+      parseHealthPort(options.healthPort),
+      operation,
+      options.healthHost,
+    );
+  } catch (err) {
+    console.error(
+      chalk.red(
+        `Could not start the health server: ${err instanceof Error ? err.message : String(err)}`,
+      ),
+    );
+    process.exit(1);
+  }
+}
+
 /**
  * Resolves a deployment name when none was given on the command line.
  * - 0 deployments: returns null (callers print the "run init first" error)
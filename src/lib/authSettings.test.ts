@@ -0,0 +1,61 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  effectiveAuthEnvironment,
+  formatAuthEnvironment,
+  REDACTED,
+} from "./authSettings.js";
+import { configFixture } from "./configFixtures.js";
+import { buildHelmValues } from "./helmValues.js";
+
+function env(settings: ReturnType<typeof effectiveAuthEnvironment>) {
+  return Object.fromEntries(settings.map((s) => [s.name, s.value]));
+}
+
+test("inline secrets are redacted, never printed", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const settings = effectiveAuthEnvironment(
+    buildHelmValues(config, { secretMode: "inline" }),
+  );
+  const vars = env(settings);
+
+  assert.equal(vars.GOTRUE_SITE_URL, `https://${config.domain}`);
+  assert.equal(vars.API_EXTERNAL_URL, `https://supabase.${config.domain}`);
+  assert.equal(vars.GOTRUE_JWT_SECRET, REDACTED);
+  assert.equal(vars.GOTRUE_SMTP_PASS, REDACTED);
+  assert.equal(vars.GOTRUE_SMTP_HOST, config.smtp.host);
+  assert.ok(vars.GOTRUE_MAILER_SUBJECTS_INVITE);
+
+  const text = formatAuthEnvironment(settings);
+  assert.ok(!text.includes(config.database.supabaseJwtSecret!));
+  assert.ok(!text.includes(config.smtp.pass));
+});
+
+test("secretRef mode names the Secret the value comes from", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const vars = env(
+    effectiveAuthEnvironment(buildHelmValues(config, { secretMode: "k8s" })),
+  );
+  assert.match(vars.GOTRUE_JWT_SECRET, /^<from Secret .*-supabase-jwt>$/);
+  assert.match(vars.GOTRUE_SMTP_PASS, /^<from Secret .*-supabase-smtp>$/);
+});
+
+test("auth.environment overrides replace derived values", () => {
+  const vars = env(
+    effectiveAuthEnvironment({
+      supabase: {
+        auth: {
+          siteUrl: "https://rules.acme.test",
+          environment: {
+            GOTRUE_SITE_URL: "https://app.acme.test",
+            GOTRUE_DISABLE_SIGNUP: true,
+            GOTRUE_EXTERNAL_GOOGLE_SECRET: "shh",
+          },
+        },
+      },
+    }),
+  );
+  assert.equal(vars.GOTRUE_SITE_URL, "https://app.acme.test");
+  assert.equal(vars.GOTRUE_DISABLE_SIGNUP, "true");
+  assert.equal(vars.GOTRUE_EXTERNAL_GOOGLE_SECRET, REDACTED);
+});
@@ -0,0 +1,109 @@
+/**
+ * `supabase dump-config`: the auth (GoTrue) environment a deployment's values
+ * produce. The supabase subchart turns global.smtp, global.supabase.emails and
+ * supabase.auth into GOTRUE_* variables; this walks the same keys so operators
+ * can check signup/JWT/mailer settings without exec'ing into the auth pod.
+ * Secret values are never printed - only whether they are set, and from where.
+ */
+
+export interface AuthSetting {
+  name: string;
+  value: string;
+  secret?: boolean;
+}
+
+export const REDACTED = "<redacted>";
+
+const MAILER_KEYS = [
+  ["invite", "INVITE"],
+  ["confirmation", "CONFIRMATION"],
+  ["recovery", "RECOVERY"],
+  ["emailChange", "EMAIL_CHANGE"],
+] as const;
+
+// Override variables whose names look like credentials are redacted too.
+const SECRET_NAME = /PASS|SECRET|KEY|TOKEN/i;
+
+type Values = Record<string, any>;
+
+function str(value: unknown): string | undefined {
+  if (value === undefined || value === null || value === "") return undefined;
+  return String(value);
+}
+
+/**
+ * A secret setting: redacted when inline, otherwise the Secret it is read
+ * from. Unset secrets are left out.
+ */
+function secretSetting(
+  name: string,
+  inline: unknown,
+  secretRef: unknown,
+): AuthSetting | null {
+  if (str(inline)) return { name, value: REDACTED, secret: true };
+  if (str(secretRef)) {
+    return { name, value: `<from Secret ${secretRef}>`, secret: true };
+  }
+  return null;
+}
+
+export function effectiveAuthEnvironment(values: Values): AuthSetting[] {
+  const global: Values = values.global ?? {};
+  const supabase: Values = values.supabase ?? {};
+  const auth: Values = supabase.auth ?? {};
+  const smtp: Values = global.smtp ?? {};
+  const emails: Values = global.supabase?.emails ?? {};
+  const secretRefs: Values = supabase.secret ?? {};
+  const settings: AuthSetting[] = [];
+
+  const add = (name: string, value: unknown) => {
+    const text = str(value);
+    if (text !== undefined) settings.push({ name, value: text });
+  };
+  const addSecret = (name: string, inline: unknown, secretRef: unknown) => {
+    const setting = secretSetting(name, inline, secretRef);
+    if (setting) settings.push(setting);
+  };
+
+  add("API_EXTERNAL_URL", auth.externalUrl);
+  add("GOTRUE_SITE_URL", auth.siteUrl);
+  addSecret(
+    "GOTRUE_JWT_SECRET",
+    global.supabase?.jwtSecret ?? secretRefs.jwt?.secret,
+    secretRefs.jwt?.secretRef,
+  );
+
+  add("GOTRUE_SMTP_HOST", smtp.host);
+  add("GOTRUE_SMTP_PORT", smtp.port);
+  addSecret("GOTRUE_SMTP_USER", smtp.user, secretRefs.smtp?.secretRef);
+  addSecret("GOTRUE_SMTP_PASS", smtp.pass, secretRefs.smtp?.secretRef);
+  add("GOTRUE_SMTP_ADMIN_EMAIL", smtp.from);
+  add("GOTRUE_SMTP_SENDER_NAME", smtp.fromName);
+
+  for (const [key, suffix] of MAILER_KEYS) {
+    add(`GOTRUE_MAILER_SUBJECTS_${suffix}`, emails.subjects?.[key]);
+  }
+  for (const [key, suffix] of MAILER_KEYS) {
+    add(`GOTRUE_MAILER_TEMPLATES_${suffix}`, emails.templates?.[key]);
+  }
+
+  // Explicit auth.environment overrides win over anything derived above.
+  for (const [name, value] of Object.entries(
+    (auth.environment ?? {}) as Record<string, unknown>,
+  )) {
+    const index = settings.findIndex((s) => s.name === name);
+    if (index !== -1) settings.splice(index, 1);
+    if (SECRET_NAME.test(name)) {
+      addSecret(name, value, undefined);
+    } else {
+      add(name, value);
+    }
+  }
+
+  return settings;
+}
+
+/** `NAME=value` lines, as printed by `supabase dump-config`. */
+export function formatAuthEnvironment(settings: AuthSetting[]): string {
+  return settings.map((s) => `${s.name}=${s.value}`).join("\n");
+}
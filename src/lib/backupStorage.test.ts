@@ -0,0 +1,52 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  backupJobLabels,
+  dbBackupsTarget,
+  formatBackupSize,
+  parseBackupListing,
+} from "./backupStorage.js";
+import { configFixture } from "./configFixtures.js";
+
+test("groups backup objects by directory, newest first", () => {
+  const output = `2026/10/14 02:00:01 NOTICE: using env auth
+[
+{"Path":"20261013-020000/db.dump","Size":1048576,"ModTime":"2026-10-13T02:01:10Z"},
+{"Path":"20261013-020000/globals.sql","Size":2048,"ModTime":"2026-10-13T02:00:05Z"},
+{"Path":"20261014-020000/db.dump","Size":2097152,"ModTime":"2026-10-14T02:01:30Z"},
+{"Path":"stray.txt","Size":10,"ModTime":"2026-10-01T00:00:00Z"}
+]`;
+  assert.deepEqual(parseBackupListing(output), [
+    {
+      id: "20261014-020000",
+      sizeBytes: 2097152,
+      modifiedAt: "2026-10-14T02:01:30Z",
+    },
+    {
+      id: "20261013-020000",
+      sizeBytes: 1050624,
+      modifiedAt: "2026-10-13T02:01:10Z",
+    },
+  ]);
+  assert.deepEqual(parseBackupListing("no backups here"), []);
+});
+
+test("backup target and labels follow the storage provider", () => {
+  const aws = configFixture("aws-backup-enabled");
+  assert.match(dbBackupsTarget(aws), /\/db-backups$/);
+  assert.deepEqual(backupJobLabels(aws, "db-backup-list"), {
+    "app.kubernetes.io/component": "db-backup-list",
+  });
+
+  const azure = configFixture("azure-workload-identity");
+  assert.equal(
+    backupJobLabels(azure, "db-restore")["azure.workload.identity/use"],
+    "true",
+  );
+});
+
+test("formats backup sizes", () => {
+  assert.equal(formatBackupSize(2048), "2.0 KiB");
+  assert.equal(formatBackupSize(5 * 1024 ** 2), "5.0 MiB");
+  assert.equal(formatBackupSize(3 * 1024 ** 3), "3.0 GiB");
+});
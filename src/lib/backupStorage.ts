@@ -0,0 +1,222 @@
+import {
+  getInstalledChartVersion,
+  getReleaseComputedValues,
+} from "./helm.js";
+import { resolveImageCatalog } from "./imageCatalog.js";
+import { runEphemeralJob } from "./kubernetes.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+} from "../types/index.js";
+
+/**
+ * Database backups in the deployment's shared object storage: where they
+ * live, how in-cluster rclone jobs reach them, and listing them. Used by
+ * `backup list` and `restore`; the chart's backup CronJob writes them.
+ */
+
+export interface BackupImages {
+  dbImage: string;
+  rcloneImage: string;
+}
+
+export interface StoredBackup {
+  /** Backup directory name under the db-backups prefix; the restore id. */
+  id: string;
+  sizeBytes: number;
+  /** Newest object's modification time (ISO 8601). */
+  modifiedAt?: string;
+}
+
+// Walks an image dict ({ registry, repository, tag }) out of computed Helm
+// values and builds a full reference. Returns null when the path is absent or
+// malformed so the caller can fall back to the chart image manifest.
+function imageRefFromValues(
+  values: Record<string, unknown> | null,
+  keys: string[],
+): string | null {
+  let node: unknown = values;
+  for (const key of keys) {
+    if (!node || typeof node !== "object") return null;
+    node = (node as Record<string, unknown>)[key];
+  }
+  const image = node as Record<string, unknown> | undefined;
+  if (
+    !image ||
+    typeof image.repository !== "string" ||
+    typeof image.tag !== "string"
+  ) {
+    return null;
+  }
+  const registry = typeof image.registry === "string" && image.registry
+    ? image.registry
+    : "docker.io";
+  return `${registry}/${image.repository}:${image.tag}`;
+}
+
+// Backup jobs must run exactly the images the deployment runs. Primary
+// source: the release's computed values (chart defaults + overrides) via
+// `helm get values --all`. Fallback: the chart image manifest for the
+// installed chart version (see src/lib/imageCatalog.ts).
+export async function resolveBackupImages(
+  cfg: DeploymentConfig,
+): Promise<BackupImages> {
+  const namespace = getNamespace(cfg.name);
+  const releaseName = getReleaseName(cfg.name);
+
+  const computed = await getReleaseComputedValues(releaseName, namespace);
+  let dbImage = imageRefFromValues(computed, ["supabase", "db", "image"]);
+  let rcloneImage = imageRefFromValues(computed, ["global", "images", "rclone"]);
+
+  if (!dbImage || !rcloneImage) {
+    const chartVersion = await getInstalledChartVersion(releaseName, namespace);
+    const catalog = await resolveImageCatalog(chartVersion ?? undefined);
+    dbImage = dbImage ?? catalog.image("supabase-postgres", cfg.imageRegistry).ref;
+    rcloneImage = rcloneImage ?? catalog.image("rclone", cfg.imageRegistry).ref;
+  }
+
+  return { dbImage, rcloneImage };
+}
+
+export function k8sName(value: string): string {
+  return value.toLowerCase().replace(/[^a-z0-9-]/g, "-").slice(0, 63).replace(/-+$/, "");
+}
+
+// The single bucket/container plus the db-backups prefix, e.g. "my-bucket/db-backups"
+// (S3/GCS) or "my-container/db-backups" (azure-blob).
+export function dbBackupsTarget(config: DeploymentConfig): string {
+  const storage = config.storage;
+  if (!storage) throw new Error("Shared object storage is required.");
+  const prefix = (storage.paths?.dbBackups || "db-backups").replace(
+    /^\/+|\/+$/g,
+    "",
+  );
+  if (storage.provider === "azure-blob") {
+    return `${storage.azureBlobContainer || "rulebricks"}/${prefix}`;
+  }
+  return `${storage.bucket}/${prefix}`;
+}
+
+// rclone on-the-fly remote "dest" config via env vars (no config file). Auth is
+// the pod's workload identity (env_auth) for every provider, or an Azure Blob
+// connection string Secret in the fallback path.
+export function rcloneEnv(config: DeploymentConfig): Array<Record<string, unknown>> {
+  const storage = config.storage;
+  if (!storage) throw new Error("Shared object storage is required.");
+  const env: Array<Record<string, unknown>> = [];
+
+  switch (storage.provider) {
+    case "azure-blob":
+      env.push({ name: "RCLONE_CONFIG_DEST_TYPE", value: "azureblob" });
+      env.push({ name: "RCLONE_CONFIG_DEST_ACCOUNT", value: storage.bucket });
+      if (storage.cloudAuthMode === "secret") {
+        if (!storage.azureBlobConnectionStringSecretRef) {
+          throw new Error("Azure Blob connection string secret ref is required.");
+        }
+        env.push({
+          name: "RCLONE_CONFIG_DEST_CONNECTION_STRING",
+          valueFrom: {
+            secretKeyRef: {
+              name: storage.azureBlobConnectionStringSecretRef.name,
+              key: storage.azureBlobConnectionStringSecretRef.key,
+            },
+          },
+        });
+      } else {
+        env.push({ name: "RCLONE_CONFIG_DEST_ENV_AUTH", value: "true" });
+      }
+      break;
+    case "gcs":
+      env.push({ name: "RCLONE_CONFIG_DEST_TYPE", value: "google cloud storage" });
+      env.push({ name: "RCLONE_CONFIG_DEST_ENV_AUTH", value: "true" });
+      env.push({ name: "RCLONE_CONFIG_DEST_BUCKET_POLICY_ONLY", value: "true" });
+      break;
+    default:
+      env.push({ name: "RCLONE_CONFIG_DEST_TYPE", value: "s3" });
+      env.push({ name: "RCLONE_CONFIG_DEST_PROVIDER", value: "AWS" });
+      env.push({ name: "RCLONE_CONFIG_DEST_ENV_AUTH", value: "true" });
+      env.push({ name: "RCLONE_CONFIG_DEST_REGION", value: storage.region });
+      break;
+  }
+  return env;
+}
+
+export function backupJobLabels(
+  config: DeploymentConfig,
+  component: string,
+): Record<string, string> {
+  const labels: Record<string, string> = {
+    "app.kubernetes.io/component": component,
+  };
+  // Azure Workload Identity requires this pod label so the projected token is
+  // injected for the rclone download. S3 (IRSA) and GCS (GKE WI) work via the SA.
+  if (
+    config.storage?.provider === "azure-blob" &&
+    config.storage.cloudAuthMode !== "secret"
+  ) {
+    labels["azure.workload.identity/use"] = "true";
+  }
+  return labels;
+}
+
+/**
+ * Groups `rclone lsjson -R --files-only` output by backup directory, newest
+ * first. Tolerates log lines around the JSON array.
+ */
+export function parseBackupListing(output: string): StoredBackup[] {
+  const start = output.indexOf("[");
+  const end = output.lastIndexOf("]");
+  if (start === -1 || end < start) return [];
+  const entries = JSON.parse(output.slice(start, end + 1)) as Array<{
+    Path?: string;
+    Size?: number;
+    ModTime?: string;
+  }>;
+
+  const backups = new Map<string, StoredBackup>();
+  for (const entry of entries) {
+    const [id, ...rest] = (entry.Path ?? "").split("/");
+    // Only files inside a backup directory count; stray top-level objects
+    // are not restorable.
+    if (!id || rest.length === 0) continue;
+    const backup = backups.get(id) ?? { id, sizeBytes: 0 };
+    backup.sizeBytes += entry.Size ?? 0;
+    if (entry.ModTime && (!backup.modifiedAt || entry.ModTime > backup.modifiedAt)) {
+      backup.modifiedAt = entry.ModTime;
+    }
+    backups.set(id, backup);
+  }
+  return [...backups.values()].sort((a, b) => b.id.localeCompare(a.id));
+}
+
+export function formatBackupSize(bytes: number): string {
+  if (bytes >= 1024 ** 3) return `${(bytes / 1024 ** 3).toFixed(1)} GiB`;
+  if (bytes >= 1024 ** 2) return `${(bytes / 1024 ** 2).toFixed(1)} MiB`;
+  return `${(bytes / 1024).toFixed(1)} KiB`;
+}
+
+/** Lists the backups in object storage with an in-cluster rclone job. */
+export async function listStoredBackups(
+  cfg: DeploymentConfig,
+  images: BackupImages,
+): Promise<StoredBackup[]> {
+  const namespace = getNamespace(cfg.name);
+  const releaseName = getReleaseName(cfg.name);
+  const target = dbBackupsTarget(cfg);
+  const result = await runEphemeralJob({
+    name: k8sName(`${releaseName}-backup-list-${Date.now()}`),
+    namespace,
+    serviceAccountName: `${releaseName}-backup`,
+    image: images.rcloneImage,
+    command: [
+      "/bin/sh",
+      "-c",
+      `rclone lsjson "dest:${target}/" -R --files-only`,
+    ],
+    env: rcloneEnv(cfg),
+    labels: backupJobLabels(cfg, "db-backup-list"),
+    timeoutSeconds: 300,
+  });
+  return parseBackupListing(result.logs);
+}
@@ -0,0 +1,71 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  describeChartVersion,
+  isConcreteChartVersion,
+  resolveChartVersion,
+} from "./chartPin.js";
+
+const deployed = (chartVersion: string) => ({
+  application: {
+    version: "1.0.0",
+    chartVersion,
+    namespace: "rulebricks-acme",
+    url: "https://rules.acme.test",
+  },
+});
+
+test("a first deploy with nothing pinned takes the latest chart", () => {
+  assert.deepEqual(resolveChartVersion({ config: {}, state: null }), {
+    source: "latest",
+  });
+});
+
+test("redeploys stay on the version recorded in state", () => {
+  assert.deepEqual(
+    resolveChartVersion({ config: {}, state: deployed("2.3.1") }),
+    { version: "2.3.1", source: "state" },
+  );
+});
+
+test("a recorded 'latest' from older CLIs does not pin", () => {
+  assert.deepEqual(
+    resolveChartVersion({ config: {}, state: deployed("latest") }),
+    { source: "latest" },
+  );
+});
+
+test("config pins win over state, and the flag wins over both", () => {
+  assert.deepEqual(
+    resolveChartVersion({
+      config: { chartVersion: "2.4.0" },
+      state: deployed("2.3.1"),
+    }),
+    { version: "2.4.0", source: "config" },
+  );
+  assert.deepEqual(
+    resolveChartVersion({
+      flag: "2.5.0",
+      config: { chartVersion: "2.4.0" },
+      state: deployed("2.3.1"),
+    }),
+    { version: "2.5.0", source: "flag" },
+  );
+});
+
+test("--chart-version latest is an explicit bump past the pin", () => {
+  const choice = resolveChartVersion({
+    flag: "latest",
+    config: {},
+    state: deployed("2.3.1"),
+  });
+  assert.deepEqual(choice, { version: undefined, source: "flag" });
+  assert.match(describeChartVersion(choice), /latest/);
+});
+
+test("isConcreteChartVersion rejects unset and the latest alias", () => {
+  assert.equal(isConcreteChartVersion("2.3.1"), true);
+  assert.equal(isConcreteChartVersion("latest"), false);
+  assert.equal(isConcreteChartVersion(""), false);
+  assert.equal(isConcreteChartVersion(undefined), false);
+});
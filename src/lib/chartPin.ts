@@ -0,0 +1,61 @@
+import type { DeploymentConfig, DeploymentState } from "../types/index.js";
+
+/**
+ * Which chart version a deploy installs. An explicit --chart-version wins,
+ * then the config's chartVersion, then the concrete version the last deploy
+ * recorded in state - so redeploying never silently moves to a newer chart.
+ * Only a first deploy (or `--chart-version latest`) takes whatever the
+ * registry currently serves; that version is recorded once installed.
+ */
+
+export type ChartVersionSource = "flag" | "config" | "state" | "latest";
+
+export interface ChartVersionChoice {
+  /** undefined means "latest": helm pulls the newest chart. */
+  version?: string;
+  source: ChartVersionSource;
+}
+
+/** True for a real chart version, false for unset or the "latest" alias. */
+export function isConcreteChartVersion(
+  version: string | undefined | null,
+): version is string {
+  return Boolean(version && version.trim() && version.trim() !== "latest");
+}
+
+export function resolveChartVersion(input: {
+  flag?: string;
+  config: Pick<DeploymentConfig, "chartVersion">;
+  state: Pick<DeploymentState, "application"> | null;
+}): ChartVersionChoice {
+  if (input.flag) {
+    return {
+      version: isConcreteChartVersion(input.flag) ? input.flag : undefined,
+      source: "flag",
+    };
+  }
+  if (isConcreteChartVersion(input.config.chartVersion)) {
+    return { version: input.config.chartVersion, source: "config" };
+  }
+  const recorded = input.state?.application?.chartVersion;
+  if (isConcreteChartVersion(recorded)) {
+    return { version: recorded, source: "state" };
+  }
+  return { source: "latest" };
+}
+
+/** One-line description for the deploy screen. */
+export function describeChartVersion(choice: ChartVersionChoice): string {
+  switch (choice.source) {
+    case "flag":
+      return choice.version
+        ? `Chart ${choice.version} (--chart-version)`
+        : "Latest chart (--chart-version latest)";
+    case "config":
+      return `Chart ${choice.version} (pinned in config)`;
+    case "state":
+      return `Chart ${choice.version} (last deployed; pass --chart-version to change)`;
+    case "latest":
+      return "Latest chart";
+  }
+}
@@ -1,6 +1,14 @@
 import test from "node:test";
 import assert from "node:assert/strict";
-import { extractSecretCredential } from "./cloudCli.js";
+import {
+  extractSecretCredential,
+  findIdentityMismatches,
+  parseAksNodePools,
+  parseEksAutoscalingGroups,
+  parseGkeNodePools,
+  summarizeNodeAutoscaling,
+} from "./cloudCli.js";
+import { configFixture } from "./configFixtures.js";
 
 test("unwraps RDS-managed {username, password} secrets", () => {
   assert.equal(
@@ -33,3 +41,106 @@ test("returns unrecognized JSON envelopes verbatim", () => {
   const raw = '{"connectionString":"redis://..."}';
   assert.equal(extractSecretCredential(raw), raw);
 });
+
+test("flags an AWS caller in a different account than the configured roles", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.deepEqual(
+    findIdentityMismatches(config, {
+      provider: "aws",
+      authenticated: true,
+      account: "123456789012",
+    }),
+    [],
+  );
+  const mismatches = findIdentityMismatches(config, {
+    provider: "aws",
+    authenticated: true,
+    account: "999999999999",
+  });
+  assert.equal(mismatches.length, 1);
+  assert.match(mismatches[0], /123456789012/);
+});
+
+test("flags an Azure subscription in a different tenant", () => {
+  const config = configFixture("azure-workload-identity");
+  const mismatches = findIdentityMismatches(config, {
+    provider: "azure",
+    authenticated: true,
+    tenantId: "33333333-3333-3333-3333-333333333333",
+  });
+  assert.equal(mismatches.length, 1);
+});
+
+test("unauthenticated identities report no mismatches", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.deepEqual(
+    findIdentityMismatches(config, { provider: "aws", authenticated: false }),
+    [],
+  );
+});
+
+test("EKS groups scale only with the enabled tag and room to grow", () => {
+  const pools = parseEksAutoscalingGroups(
+    JSON.stringify([
+      {
+        name: "eks-core",
+        min: 3,
+        max: 6,
+        tags: [
+          "k8s.io/cluster-autoscaler/enabled",
+          "k8s.io/cluster-autoscaler/rulebricks",
+        ],
+      },
+      {
+        name: "eks-pinned",
+        min: 2,
+        max: 2,
+        tags: ["k8s.io/cluster-autoscaler/enabled"],
+      },
+    ]),
+  );
+  assert.deepEqual(
+    pools.map((p) => [p.name, p.enabled]),
+    [
+      ["eks-core", true],
+      ["eks-pinned", false],
+    ],
+  );
+  const check = summarizeNodeAutoscaling("aws", pools);
+  assert.equal(check.status, "active");
+  assert.match(check.message, /fixed size: eks-pinned/);
+});
+
+test("EKS without tagged Auto Scaling groups cannot scale", () => {
+  const check = summarizeNodeAutoscaling(
+    "aws",
+    parseEksAutoscalingGroups("[]"),
+  );
+  assert.equal(check.status, "inactive");
+  assert.match(check.message, /discovery tags/);
+});
+
+test("GKE and AKS report pools with autoscaling turned off", () => {
+  const gke = parseGkeNodePools(
+    JSON.stringify([
+      {
+        name: "core",
+        autoscaling: {
+          enabled: true,
+          totalMinNodeCount: 3,
+          totalMaxNodeCount: 9,
+        },
+      },
+      { name: "burst" },
+    ]),
+  );
+  assert.deepEqual(gke[0], { name: "core", enabled: true, min: 3, max: 9 });
+  assert.equal(summarizeNodeAutoscaling("gcp", gke).status, "active");
+
+  const aks = parseAksNodePools(
+    JSON.stringify([{ name: "system", enabled: false, min: null, max: null }]),
+  );
+  const check = summarizeNodeAutoscaling("azure", aks);
+  assert.equal(check.status, "inactive");
+  assert.match(check.message, /off on every node pool: system/);
+});
@@ -8,8 +8,15 @@
 import { exec } from "child_process";
 import { promisify } from "util";
 import { execa } from "execa";
-import { CloudProvider, CLOUD_REGIONS } from "../types/index.js";
-import { approveCloudCommandOrThrow } from "./commandApproval.js";
+import {
+  CloudProvider,
+  CLOUD_REGIONS,
+  DeploymentConfig,
+} from "../types/index.js";
+import {
+  approveCloudCommandOrThrow,
+  CommandDeniedError,
+} from "./commandApproval.js";
 import { filterAzureWorkloadIdentities } from "./clusterSetupDefaults.js";
 
 const execAsync = promisify(exec);
@@ -428,6 +435,176 @@ export async function checkAuroraLogicalReplication(
   }
 }
 
+export interface NodePoolAutoscaling {
+  name: string;
+  enabled: boolean;
+  min?: number;
+  max?: number;
+}
+
+export interface NodeAutoscalingCheck {
+  status: "active" | "inactive" | "unknown";
+  pools: NodePoolAutoscaling[];
+  message: string;
+}
+
+/**
+ * EKS Auto Scaling groups found by the k8s.io/cluster-autoscaler/<cluster>
+ * discovery tag. The autoscaler only scales groups that also carry the
+ * .../enabled tag and have room between min and max.
+ */
+export function parseEksAutoscalingGroups(raw: string): NodePoolAutoscaling[] {
+  const groups = JSON.parse(raw || "[]") as Array<{
+    name: string;
+    min?: number;
+    max?: number;
+    tags?: string[];
+  }>;
+  return groups.map((g) => ({
+    name: g.name,
+    enabled:
+      (g.tags ?? []).includes("k8s.io/cluster-autoscaler/enabled") &&
+      (g.max ?? 0) > (g.min ?? 0),
+    min: g.min,
+    max: g.max,
+  }));
+}
+
+/** `gcloud container node-pools list --format=json(name,autoscaling)`. */
+export function parseGkeNodePools(raw: string): NodePoolAutoscaling[] {
+  const pools = JSON.parse(raw || "[]") as Array<{
+    name: string;
+    autoscaling?: {
+      enabled?: boolean;
+      minNodeCount?: number;
+      maxNodeCount?: number;
+      totalMinNodeCount?: number;
+      totalMaxNodeCount?: number;
+    };
+  }>;
+  return pools.map((p) => ({
+    name: p.name,
+    enabled: p.autoscaling?.enabled === true,
+    min: p.autoscaling?.totalMinNodeCount ?? p.autoscaling?.minNodeCount,
+    max: p.autoscaling?.totalMaxNodeCount ?? p.autoscaling?.maxNodeCount,
+  }));
+}
+
+/** `az aks nodepool list`, projected to name/enabled/min/max. */
+export function parseAksNodePools(raw: string): NodePoolAutoscaling[] {
+  const pools = JSON.parse(raw || "[]") as Array<{
+    name: string;
+    enabled?: boolean | null;
+    min?: number | null;
+    max?: number | null;
+  }>;
+  return pools.map((p) => ({
+    name: p.name,
+    enabled: p.enabled === true,
+    min: p.min ?? undefined,
+    max: p.max ?? undefined,
+  }));
+}
+
+export function summarizeNodeAutoscaling(
+  provider: CloudProvider,
+  pools: NodePoolAutoscaling[],
+): NodeAutoscalingCheck {
+  const scaling = pools.filter((p) => p.enabled);
+  const fixed = pools.filter((p) => !p.enabled).map((p) => p.name);
+  if (pools.length === 0) {
+    return provider === "aws"
+      ? {
+          status: "inactive",
+          pools,
+          message:
+            "No Auto Scaling groups carry this cluster's k8s.io/cluster-autoscaler discovery tags, so cluster-autoscaler cannot add nodes",
+        }
+      : { status: "unknown", pools, message: "No node pools found" };
+  }
+  if (scaling.length === 0) {
+    return {
+      status: "inactive",
+      pools,
+      message:
+        provider === "aws"
+          ? `No node group can scale (missing k8s.io/cluster-autoscaler/enabled or max equals min): ${fixed.join(", ")}`
+          : `Autoscaling is off on every node pool: ${fixed.join(", ")}`,
+    };
+  }
+  return {
+    status: "active",
+    pools,
+    message:
+      fixed.length > 0
+        ? `Autoscaling on ${scaling.map((p) => p.name).join(", ")}; fixed size: ${fixed.join(", ")}`
+        : `Autoscaling on ${scaling.map((p) => p.name).join(", ")}`,
+  };
+}
+
+/**
+ * Whether the cluster's nodes can actually scale. GKE and AKS scale node pools
+ * natively when autoscaling is enabled on them; EKS relies on the chart's
+ * cluster-autoscaler, which only touches Auto Scaling groups carrying the
+ * discovery tags. Fails open ("unknown") when the cloud CLI can't answer.
+ */
+export async function checkNodeAutoscaling(
+  config: DeploymentConfig,
+): Promise<NodeAutoscalingCheck> {
+  const { provider, clusterName, region, gcpProjectId, azureResourceGroup } =
+    config.infrastructure;
+  const unknown = (message: string): NodeAutoscalingCheck => ({
+    status: "unknown",
+    pools: [],
+    message,
+  });
+  if (!provider || !clusterName) {
+    return unknown("No cloud cluster configured");
+  }
+
+  let command: string;
+  let parse: (raw: string) => NodePoolAutoscaling[];
+  switch (provider) {
+    case "aws":
+      if (!region) return unknown("No region configured");
+      command =
+        `aws autoscaling describe-auto-scaling-groups --region ${region} ` +
+        `--filters Name=tag-key,Values=k8s.io/cluster-autoscaler/${clusterName} ` +
+        `--query "AutoScalingGroups[].{name:AutoScalingGroupName,min:MinSize,max:MaxSize,tags:Tags[].Key}" --output json`;
+      parse = parseEksAutoscalingGroups;
+      break;
+    case "gcp":
+      if (!region) return unknown("No region configured");
+      command =
+        `gcloud container node-pools list --cluster ${clusterName} --location ${region}` +
+        (gcpProjectId ? ` --project ${gcpProjectId}` : "") +
+        ` --format="json(name,autoscaling)"`;
+      parse = parseGkeNodePools;
+      break;
+    case "azure":
+      if (!azureResourceGroup) return unknown("No resource group configured");
+      command =
+        `az aks nodepool list --cluster-name ${clusterName} --resource-group ${azureResourceGroup} ` +
+        `--query "[].{name:name,enabled:enableAutoScaling,min:minCount,max:maxCount}" --output json`;
+      parse = parseAksNodePools;
+      break;
+  }
+
+  try {
+    const result = await execCommand(command, {
+      intent: "Check node autoscaling",
+      provider,
+    });
+    if (result.stderr && !result.stdout) {
+      return unknown(result.stderr.trim().split("\n")[0]);
+    }
+    return summarizeNodeAutoscaling(provider, parse(result.stdout));
+  } catch (error) {
+    if (error instanceof CommandDeniedError) throw error;
+    return unknown("Could not read node pool autoscaling");
+  }
+}
+
 async function describeEksCluster(
   name: string,
   region: string,
@@ -1282,6 +1459,145 @@ export async function checkAllCloudClis(): Promise<AllCloudCliStatus> {
   return { aws, gcp, azure, anyAvailable, anyInstalled };
 }
 
+/**
+ * The identity a provider CLI is currently acting as - the ambient
+ * credentials deploy/destroy will use.
+ */
+export interface CloudIdentity {
+  provider: CloudProvider;
+  authenticated: boolean;
+  /** AWS account ID, GCP account email, or Azure subscription ID. */
+  account?: string;
+  /** AWS caller ARN or Azure signed-in user/service principal. */
+  principal?: string;
+  /** GCP active project. */
+  project?: string;
+  /** Azure subscription display name. */
+  subscriptionName?: string;
+  /** Azure tenant ID. */
+  tenantId?: string;
+  error?: string;
+}
+
+/**
+ * Reports the active cloud identity for a provider. Never throws; CLI and
+ * authentication failures come back as `authenticated: false` with an error.
+ */
+export async function getCloudIdentity(
+  provider: CloudProvider,
+): Promise<CloudIdentity> {
+  const identity: CloudIdentity = { provider, authenticated: false };
+  try {
+    switch (provider) {
+      case "aws": {
+        const result = await execCommand(
+          "aws sts get-caller-identity --output json",
+        );
+        if (!result.stdout) {
+          identity.error = result.stderr.includes("ExpiredToken")
+            ? "Session expired - refresh your credentials"
+            : result.stderr.includes("Unable to locate credentials")
+              ? 'Not authenticated - run "aws configure" or set credentials'
+              : result.stderr.trim() || "AWS CLI not available";
+          return identity;
+        }
+        const parsed = JSON.parse(result.stdout);
+        identity.account = parsed.Account;
+        identity.principal = parsed.Arn;
+        identity.authenticated = Boolean(parsed.Account);
+        return identity;
+      }
+      case "gcp": {
+        const result = await execCommand('gcloud config list --format="json"');
+        if (!result.stdout) {
+          identity.error = result.stderr.trim() || "gcloud CLI not available";
+          return identity;
+        }
+        const parsed = JSON.parse(result.stdout);
+        identity.account = parsed.core?.account;
+        identity.project = parsed.core?.project;
+        if (!identity.account) {
+          identity.error = 'Not authenticated - run "gcloud auth login"';
+          return identity;
+        }
+        identity.authenticated = true;
+        return identity;
+      }
+      case "azure": {
+        const result = await execCommand("az account show --output json");
+        if (!result.stdout) {
+          identity.error = result.stderr.includes("Please run")
+            ? 'Not authenticated - run "az login"'
+            : result.stderr.trim() || "Azure CLI not available";
+          return identity;
+        }
+        const parsed = JSON.parse(result.stdout);
+        identity.account = parsed.id;
+        identity.subscriptionName = parsed.name;
+        identity.tenantId = parsed.tenantId;
+        identity.principal = parsed.user?.name;
+        identity.authenticated = Boolean(parsed.id);
+        return identity;
+      }
+    }
+  } catch (error) {
+    identity.error = error instanceof Error ? error.message : "Unknown error";
+  }
+  return identity;
+}
+
+/**
+ * Compares the active identity against what a deployment config expects and
+ * returns human-readable mismatches (wrong AWS account, GCP project, or Azure
+ * tenant). Only fields the config actually pins are compared.
+ */
+export function findIdentityMismatches(
+  config: DeploymentConfig,
+  identity: CloudIdentity,
+): string[] {
+  const mismatches: string[] = [];
+  if (!identity.authenticated) return mismatches;
+
+  if (identity.provider === "aws" && identity.account) {
+    const arns = [
+      config.storage?.awsIamRoleArn,
+      config.secrets?.aws?.roleArn,
+      config.externalServices?.kafka?.external?.identity?.awsRoleArn,
+    ].filter((arn): arn is string => Boolean(arn));
+    for (const arn of arns) {
+      const account = arn.split(":")[4];
+      if (account && account !== identity.account) {
+        mismatches.push(
+          `Config references AWS account ${account} (${arn}) but the CLI is using account ${identity.account}.`,
+        );
+        break;
+      }
+    }
+  }
+
+  if (
+    identity.provider === "gcp" &&
+    config.infrastructure.gcpProjectId &&
+    identity.project !== config.infrastructure.gcpProjectId
+  ) {
+    mismatches.push(
+      `Config targets GCP project ${config.infrastructure.gcpProjectId} but the active gcloud project is ${identity.project ?? "(unset)"}.`,
+    );
+  }
+
+  if (identity.provider === "azure" && identity.tenantId) {
+    const tenant =
+      config.secrets?.azure?.tenantId ?? config.storage?.azureBlobTenantId;
+    if (tenant && tenant !== identity.tenantId) {
+      mismatches.push(
+        `Config references Azure tenant ${tenant} but the active subscription is in tenant ${identity.tenantId}.`,
+      );
+    }
+  }
+
+  return mismatches;
+}
+
 /**
  * List regions for a specific provider
  */
@@ -0,0 +1,94 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  assessCloudMigration,
+  formatMigrationRunbook,
+} from "./cloudMigration.js";
+import { configFixture } from "./configFixtures.js";
+
+function statusOf(
+  checks: { area: string; status: string }[],
+  area: string,
+): string | undefined {
+  return checks.find((c) => c.area === area)?.status;
+}
+
+test("flags the source-cloud pieces of an AWS deployment moving to GCP", () => {
+  const assessment = assessCloudMigration({
+    config: configFixture("aws-self-hosted-minimal"),
+    to: "gcp",
+    region: "us-central1",
+    volumes: [
+      { name: "data-db-0", storageClass: "gp3", sizeGi: 20 },
+      { name: "data-kafka-0", storageClass: "gp3", sizeGi: 20 },
+    ],
+  });
+
+  assert.equal(statusOf(assessment.checks, "Provider"), "ok");
+  assert.equal(statusOf(assessment.checks, "Region"), "ok");
+  assert.equal(statusOf(assessment.checks, "Storage class"), "action");
+  assert.equal(statusOf(assessment.checks, "Object storage"), "action");
+  // Backups are off in this fixture, so restore has nothing to read.
+  assert.equal(statusOf(assessment.checks, "Database"), "action");
+  assert.equal(assessment.totalVolumeGi, 40);
+
+  const titles = assessment.steps.map((s) => s.title);
+  assert.equal(titles[0], "Back up");
+  assert.ok(titles.indexOf("Restore") > titles.indexOf("Deploy target"));
+  assert.ok(titles.indexOf("Cut over DNS") > titles.indexOf("Restore"));
+  assert.equal(titles[titles.length - 1], "Retire source");
+});
+
+test("blocks a same-provider target and a region from another cloud", () => {
+  const same = assessCloudMigration({
+    config: configFixture("aws-self-hosted-minimal"),
+    to: "aws",
+    volumes: null,
+  });
+  assert.equal(statusOf(same.checks, "Provider"), "blocker");
+  assert.equal(statusOf(same.checks, "Region"), "action");
+
+  const wrongRegion = assessCloudMigration({
+    config: configFixture("aws-self-hosted-minimal"),
+    to: "azure",
+    region: "us-east-1",
+    volumes: null,
+  });
+  assert.equal(statusOf(wrongRegion.checks, "Region"), "blocker");
+});
+
+test("Supabase Cloud and cloud-bound Kafka change the plan", () => {
+  const cloud = assessCloudMigration({
+    config: configFixture("aws-supabase-cloud"),
+    to: "gcp",
+    region: "us-central1",
+    volumes: [],
+  });
+  assert.equal(statusOf(cloud.checks, "Database"), "ok");
+  assert.ok(!cloud.steps.some((s) => s.title === "Restore"));
+
+  const msk = assessCloudMigration({
+    config: configFixture("aws-external-kafka-msk"),
+    to: "azure",
+    region: "eastus",
+    volumes: [],
+  });
+  assert.equal(statusOf(msk.checks, "Kafka"), "action");
+});
+
+test("runbook reports unknown data volume when the cluster is unreachable", () => {
+  const text = formatMigrationRunbook(
+    assessCloudMigration({
+      config: configFixture("aws-self-hosted-minimal"),
+      to: "gcp",
+      region: "us-central1",
+      volumes: null,
+    }),
+  );
+  assert.match(text, /unknown \(the cluster was not reachable\)/);
+  assert.match(text, /\$ rulebricks backup aws-self-hosted-minimal/);
+  assert.match(
+    text,
+    /rulebricks clone aws-self-hosted-minimal aws-self-hosted-minimal-gcp/,
+  );
+});
@@ -0,0 +1,371 @@
+import {
+  CLOUD_PROVIDER_NAMES,
+  CLOUD_REGIONS,
+  CloudProvider,
+  DeploymentConfig,
+  SecretsBackend,
+} from "../types/index.js";
+import type { PersistentVolumeClaimInfo } from "./kubernetes.js";
+
+/**
+ * `migrate-cloud --to <provider>`: an assessment, not a migration. Checks what
+ * in a deployment's config is tied to its current cloud, sizes the data that
+ * has to move, and lays out the runbook (backup → provision target → restore
+ * → cut over DNS). Nothing here changes a cluster or a config file.
+ */
+
+export type MigrationCheckStatus = "ok" | "action" | "blocker";
+
+export interface MigrationCheck {
+  area: string;
+  status: MigrationCheckStatus;
+  message: string;
+}
+
+export interface MigrationStep {
+  title: string;
+  detail: string;
+  command?: string;
+}
+
+export interface CloudMigrationAssessment {
+  from?: CloudProvider;
+  to: CloudProvider;
+  region?: string;
+  checks: MigrationCheck[];
+  /** PVCs in the deployment namespace; null when the cluster was unreachable. */
+  volumes: PersistentVolumeClaimInfo[] | null;
+  totalVolumeGi: number | null;
+  steps: MigrationStep[];
+}
+
+/** What the bundled cluster-setup template provisions on each cloud. */
+const TARGET_PLATFORM: Record<
+  CloudProvider,
+  {
+    template: string;
+    coreNodes: string;
+    arm64Nodes: string;
+    storageClass: string;
+    objectStorage: "s3" | "gcs" | "azure-blob";
+    secretsBackend: SecretsBackend;
+    kafkaPreset: string;
+  }
+> = {
+  aws: {
+    template: "cluster-setup/aws (CloudFormation)",
+    coreNodes: "3 x m7i.xlarge",
+    arm64Nodes: "m7g (Graviton)",
+    storageClass: "gp3",
+    objectStorage: "s3",
+    secretsBackend: "aws-secrets-manager",
+    kafkaPreset: "aws-msk-iam",
+  },
+  gcp: {
+    template: "cluster-setup/gcp (Terraform)",
+    coreNodes: "3 x n4-standard-4",
+    arm64Nodes: "c4a (Axion)",
+    storageClass: "pd-balanced",
+    objectStorage: "gcs",
+    secretsBackend: "gcp-secret-manager",
+    kafkaPreset: "gcp-managed",
+  },
+  azure: {
+    template: "cluster-setup/azure (Bicep)",
+    coreNodes: "3 x Standard_F4as_v6",
+    arm64Nodes: "Dpsv6 (Cobalt)",
+    storageClass: "managed-premium",
+    objectStorage: "azure-blob",
+    secretsBackend: "azure-key-vault",
+    kafkaPreset: "azure-event-hubs",
+  },
+};
+
+const CLOUD_DNS_PROVIDERS: Partial<Record<string, CloudProvider>> = {
+  route53: "aws",
+  google: "gcp",
+  azure: "azure",
+};
+
+function usesBundledPostgres(config: DeploymentConfig): boolean {
+  return (
+    config.database.type === "self-hosted" &&
+    config.externalServices?.postgres?.mode !== "external"
+  );
+}
+
+export function assessCloudMigration(input: {
+  config: DeploymentConfig;
+  to: CloudProvider;
+  region?: string;
+  volumes: PersistentVolumeClaimInfo[] | null;
+}): CloudMigrationAssessment {
+  const { config, to, region, volumes } = input;
+  const from = config.infrastructure.provider;
+  const target = TARGET_PLATFORM[to];
+  const toName = CLOUD_PROVIDER_NAMES[to];
+  const checks: MigrationCheck[] = [];
+
+  if (from === to) {
+    checks.push({
+      area: "Provider",
+      status: "blocker",
+      message: `${config.name} already runs on ${toName}; there is nothing to migrate`,
+    });
+  } else {
+    checks.push({
+      area: "Provider",
+      status: "ok",
+      message: `${from ? CLOUD_PROVIDER_NAMES[from] : "Unknown provider"} → ${toName}`,
+    });
+  }
+
+  if (!region) {
+    checks.push({
+      area: "Region",
+      status: "action",
+      message: `No target region given; pass --region (e.g. ${CLOUD_REGIONS[to][0]})`,
+    });
+  } else if (!CLOUD_REGIONS[to].includes(region)) {
+    checks.push({
+      area: "Region",
+      status: "blocker",
+      message: `${region} is not a ${toName} region`,
+    });
+  } else {
+    checks.push({
+      area: "Region",
+      status: "ok",
+      message: `${region} is available on ${toName}`,
+    });
+  }
+
+  const arch = config.infrastructure.nodeArchitecture;
+  checks.push({
+    area: "Compute",
+    status: "ok",
+    message:
+      arch === "arm64"
+        ? `Current nodes are arm64; use ${target.arm64Nodes} nodes on ${toName} (the ${target.template} default is x86)`
+        : `${target.template} provisions ${target.coreNodes} core nodes plus the burst pool`,
+  });
+
+  const storageClass = config.infrastructure.storageClass;
+  checks.push(
+    storageClass
+      ? {
+          area: "Storage class",
+          status: "action",
+          message: `infrastructure.storageClass "${storageClass}" is cluster-specific; clear it or set a ${toName} class (default ${target.storageClass})`,
+        }
+      : {
+          area: "Storage class",
+          status: "ok",
+          message: `Volumes use the ${toName} default (${target.storageClass})`,
+        },
+  );
+
+  const storage = config.storage;
+  if (storage && storage.provider !== target.objectStorage) {
+    checks.push({
+      area: "Object storage",
+      status: "action",
+      message: `Decision logs and backups live in ${storage.provider} bucket ${storage.bucket}; create a ${target.objectStorage} bucket and point storage.* at it`,
+    });
+  } else if (storage) {
+    checks.push({
+      area: "Object storage",
+      status: "ok",
+      message: `Already on ${storage.provider}`,
+    });
+  }
+
+  const secretsBackend = config.secrets?.backend;
+  if (
+    secretsBackend &&
+    secretsBackend !== target.secretsBackend &&
+    Object.values(TARGET_PLATFORM).some(
+      (p) => p.secretsBackend === secretsBackend,
+    )
+  ) {
+    checks.push({
+      area: "Secrets",
+      status: "action",
+      message: `secrets.backend ${secretsBackend} is tied to the source cloud; switch to ${target.secretsBackend} (deploy re-seeds the entries)`,
+    });
+  }
+
+  if (config.database.type === "supabase-cloud") {
+    checks.push({
+      area: "Database",
+      status: "ok",
+      message: "Supabase Cloud stays where it is; no database data moves",
+    });
+  } else if (usesBundledPostgres(config)) {
+    checks.push(
+      config.backup?.enabled
+        ? {
+            area: "Database",
+            status: "ok",
+            message: "Bundled Postgres moves via backup and restore",
+          }
+        : {
+            area: "Database",
+            status: "action",
+            message:
+              "Bundled Postgres moves via backup and restore; enable backup (backup.enabled) so the target can restore from object storage",
+          },
+    );
+  } else {
+    const pgProvider = config.externalServices?.postgres?.external?.provider;
+    checks.push({
+      area: "Database",
+      status: pgProvider && pgProvider !== to ? "action" : "ok",
+      message:
+        pgProvider && pgProvider !== to
+          ? `Managed Postgres runs on ${CLOUD_PROVIDER_NAMES[pgProvider]}; provision one on ${toName} and move the data with pg_dump/pg_restore, or keep it cross-cloud`
+          : "External Postgres is reachable independently of the cluster",
+    });
+  }
+
+  const kafkaPreset = config.externalServices?.kafka?.external?.preset;
+  if (
+    config.externalServices?.kafka?.mode === "external" &&
+    kafkaPreset &&
+    kafkaPreset !== "custom" &&
+    kafkaPreset !== target.kafkaPreset
+  ) {
+    checks.push({
+      area: "Kafka",
+      status: "action",
+      message: `Kafka preset ${kafkaPreset} does not run on ${toName}; use ${target.kafkaPreset} or the in-cluster broker (queued events are not migrated)`,
+    });
+  }
+
+  const dnsCloud = CLOUD_DNS_PROVIDERS[config.dns.provider];
+  if (config.dns.autoManage && dnsCloud && dnsCloud !== to) {
+    checks.push({
+      area: "DNS",
+      status: "action",
+      message: `external-dns manages ${config.dns.provider} records; grant the ${toName} cluster access to the zone or manage the records by hand`,
+    });
+  }
+
+  const totalVolumeGi = volumes
+    ? volumes.reduce((sum, v) => sum + v.sizeGi, 0)
+    : null;
+
+  return {
+    from,
+    to,
+    region,
+    checks,
+    volumes,
+    totalVolumeGi,
+    steps: planMigrationSteps(config, to, region),
+  };
+}
+
+function planMigrationSteps(
+  config: DeploymentConfig,
+  to: CloudProvider,
+  region?: string,
+): MigrationStep[] {
+  const target = TARGET_PLATFORM[to];
+  const toName = CLOUD_PROVIDER_NAMES[to];
+  const targetName = `${config.name}-${to}`;
+  const steps: MigrationStep[] = [];
+
+  if (usesBundledPostgres(config)) {
+    steps.push({
+      title: "Back up",
+      detail: "Take a fresh database backup into object storage",
+      command: `rulebricks backup ${config.name}`,
+    });
+  } else if (config.externalServices?.postgres?.mode === "external") {
+    steps.push({
+      title: "Back up",
+      detail: "Snapshot the managed Postgres (pg_dump or a provider snapshot)",
+    });
+  }
+  if (config.storage) {
+    steps.push({
+      title: "Copy object storage",
+      detail: `Copy ${config.storage.bucket} (decision logs, backups) to the ${toName} bucket, e.g. with rclone sync`,
+    });
+  }
+
+  steps.push({
+    title: "Provision target",
+    detail: `Create the ${toName} cluster${region ? ` in ${region}` : ""} with ${target.template}`,
+  });
+  steps.push({
+    title: "Configure target",
+    detail: `Clone the config, then switch provider, region, cluster, storage and secrets to ${toName}`,
+    command: `rulebricks clone ${config.name} ${targetName} && rulebricks configure ${targetName}`,
+  });
+  steps.push({
+    title: "Deploy target",
+    detail: "Deploy alongside the source; traffic still goes to the source",
+    command: `rulebricks deploy ${targetName}`,
+  });
+
+  if (usesBundledPostgres(config)) {
+    steps.push({
+      title: "Restore",
+      detail: "Restore the backup into the target's bundled Postgres",
+      command: `rulebricks restore ${targetName}`,
+    });
+  }
+
+  steps.push({
+    title: "Cut over DNS",
+    detail: `Lower the TTL on ${config.domain} ahead of time, then point it at the target's ingress`,
+  });
+  steps.push({
+    title: "Verify",
+    detail: "Confirm the target is healthy and serving before retiring the source",
+    command: `rulebricks status ${targetName}`,
+  });
+  steps.push({
+    title: "Retire source",
+    detail: `Destroy the ${config.name} deployment once traffic has drained`,
+    command: `rulebricks destroy ${config.name}`,
+  });
+
+  return steps;
+}
+
+/** Plain-text runbook, as printed by `migrate-cloud`. */
+export function formatMigrationRunbook(
+  assessment: CloudMigrationAssessment,
+): string {
+  const marks: Record<MigrationCheckStatus, string> = {
+    ok: "✓",
+    action: "!",
+    blocker: "✗",
+  };
+  const lines = ["Checks:"];
+  for (const check of assessment.checks) {
+    lines.push(`  ${marks[check.status]} ${check.area}: ${check.message}`);
+  }
+
+  lines.push("", "Data to move:");
+  if (assessment.volumes === null) {
+    lines.push("  unknown (the cluster was not reachable)");
+  } else if (assessment.volumes.length === 0) {
+    lines.push("  no persistent volumes");
+  } else {
+    for (const volume of assessment.volumes) {
+      lines.push(`  ${volume.name}: ${volume.sizeGi} Gi`);
+    }
+    lines.push(`  total: ${assessment.totalVolumeGi} Gi provisioned`);
+  }
+
+  lines.push("", "Runbook:");
+  assessment.steps.forEach((step, i) => {
+    lines.push(`  ${i + 1}. ${step.title}: ${step.detail}`);
+    if (step.command) lines.push(`     $ ${step.command}`);
+  });
+  return lines.join("\n");
+}
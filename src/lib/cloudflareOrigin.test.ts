@@ -0,0 +1,112 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  originCertificateHostnames,
+  originCertificateProblem,
+} from "./cloudflareOrigin.js";
+import { configFixture } from "./configFixtures.js";
+
+// Self-signed, SANs rb.example.com and *.rb.example.com, valid until
+// 2036-10-12.
+const CERTIFICATE = `-----BEGIN CERTIFICATE-----
+MIIBtDCCAVugAwIBAgIUfF6zIUWPbvB3/jHQ7+3fTJ6WCkowCgYIKoZIzj0EAwIw
+GTEXMBUGA1UEAwwOcmIuZXhhbXBsZS5jb20wHhcNMjYxMDE1MTQzMDA4WhcNMzYx
+MDEyMTQzMDA4WjAZMRcwFQYDVQQDDA5yYi5leGFtcGxlLmNvbTBZMBMGByqGSM49
+AgEGCCqGSM49AwEHA0IABKPU3hb78gQtaueF9No9Z061IokKVFyLX3NwAM/t1ubT
+vSxDWbXGFAjBApxt4IsWNt8bB/E4YvbrDFQF0QWj6wSjgYAwfjAdBgNVHQ4EFgQU
+NXobzacfCCTanLZj7cST30VHjMUwHwYDVR0jBBgwFoAUNXobzacfCCTanLZj7cST
+30VHjMUwDwYDVR0TAQH/BAUwAwEB/zArBgNVHREEJDAigg5yYi5leGFtcGxlLmNv
+bYIQKi5yYi5leGFtcGxlLmNvbTAKBggqhkjOPQQDAgNHADBEAiAR03sRxM2VWPfo
+NrN1oHVLh+ESItV1KD2nANJZOMMUlAIgSLgY2vK+HOLVT4KPRF9u7VI8xRyIY/3+
+GobPtB6S6sY=
+-----END CERTIFICATE-----`;
+
+// Same names, 30 days from 2026-10-15 to 2026-11-14.
+const SHORT_LIVED_CERTIFICATE = `-----BEGIN CERTIFICATE-----
+MIIBtTCCAVugAwIBAgIUTfcRHrvfYYJdRzU6RomrcuQrT5EwCgYIKoZIzj0EAwIw
+GTEXMBUGA1UEAwwOcmIuZXhhbXBsZS5jb20wHhcNMjYxMDE1MTUwMjE5WhcNMjYx
+MTE0MTUwMjE5WjAZMRcwFQYDVQQDDA5yYi5leGFtcGxlLmNvbTBZMBMGByqGSM49
+AgEGCCqGSM49AwEHA0IABLEBe0mkBGhrUA1fYwH/tEEe0nzvmwBdZtR0PKeo3WFk
+rk0S2JG1Zzzuqn6Nh2WtRmms8s97ckl5qfVNB1IMZHyjgYAwfjAdBgNVHQ4EFgQU
+qtyII1V5FVDqVoaAcXoV5UItm1kwHwYDVR0jBBgwFoAUqtyII1V5FVDqVoaAcXoV
+5UItm1kwDwYDVR0TAQH/BAUwAwEB/zArBgNVHREEJDAigg5yYi5leGFtcGxlLmNv
+bYIQKi5yYi5leGFtcGxlLmNvbTAKBggqhkjOPQQDAgNIADBFAiEA3ymwJRsnSy28
+yBTP15n0I+n7sAwaxmqJHc5YmKKkoXYCIC99ykIEm4pImeRkspQ9/hSoen16VCtc
+KXJK1vXRIIRf
+-----END CERTIFICATE-----`;
+
+test("the Origin certificate covers every served host and tls.domains", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.tls = { domains: ["*.rules.rb.example.com"] };
+  const hostnames = originCertificateHostnames(config);
+  assert.ok(hostnames.includes("rb.example.com"));
+  assert.ok(hostnames.includes("supabase.rb.example.com"));
+  assert.ok(hostnames.includes("*.rules.rb.example.com"));
+  assert.equal(new Set(hostnames).size, hostnames.length);
+});
+
+test("a stored certificate is reused until it misses a host or nears expiry", () => {
+  const now = new Date("2027-01-01T00:00:00Z");
+  assert.equal(
+    originCertificateProblem(
+      CERTIFICATE,
+      ["rb.example.com", "supabase.rb.example.com"],
+      now,
+    ),
+    null,
+  );
+  assert.match(
+    String(
+      originCertificateProblem(
+        CERTIFICATE,
+        ["rb.example.com", "a.b.rb.example.com"],
+        now,
+      ),
+    ),
+    /does not cover a\.b\.rb\.example\.com/,
+  );
+  assert.match(
+    String(
+      originCertificateProblem(
+        CERTIFICATE,
+        ["rb.example.com"],
+        new Date("2036-10-01T00:00:00Z"),
+      ),
+    ),
+    /expires 2036-10-12/,
+  );
+  assert.match(
+    String(originCertificateProblem("not a certificate", [], now)),
+    /could not be parsed/,
+  );
+});
+
+test("a short-lived certificate renews with a third of its lifetime left", () => {
+  const hosts = ["rb.example.com"];
+  assert.equal(
+    originCertificateProblem(
+      SHORT_LIVED_CERTIFICATE,
+      hosts,
+      new Date("2026-10-16T00:00:00Z"),
+    ),
+    null,
+  );
+  assert.equal(
+    originCertificateProblem(
+      SHORT_LIVED_CERTIFICATE,
+      hosts,
+      new Date("2026-11-01T00:00:00Z"),
+    ),
+    null,
+  );
+  assert.match(
+    String(
+      originCertificateProblem(
+        SHORT_LIVED_CERTIFICATE,
+        hosts,
+        new Date("2026-11-06T00:00:00Z"),
+      ),
+    ),
+    /expires 2026-11-14/,
+  );
+});
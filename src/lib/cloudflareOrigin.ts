@@ -0,0 +1,240 @@
+import { generateKeyPairSync, X509Certificate } from "crypto";
+import path from "path";
+import { execa } from "execa";
+import { getDeploymentDNSRecords } from "./dns.js";
+import { createTLSSecret, getTLSSecretCertificate } from "./kubernetes.js";
+import { removeTempPath, writeTempFile } from "./tempFiles.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  originCertificateSecretName,
+  wildcardTlsDomains,
+} from "../types/index.js";
+
+/**
+ * tls.provider "cloudflare-origin": for deployments behind Cloudflare's
+ * proxy, which only needs the origin to present a certificate it trusts.
+ * The CLI issues a Cloudflare Origin CA certificate for every host through
+ * the Cloudflare API and stores it as a TLS Secret that Traefik serves as
+ * its default certificate, in place of cert-manager and Let's Encrypt. The
+ * private key is generated locally and only ever written to the cluster.
+ */
+
+const ORIGIN_CA_API = "https://api.cloudflare.com/client/v4/certificates";
+
+const DEFAULT_VALIDITY_DAYS = 5475;
+
+/**
+ * Reissue once a third of the certificate's lifetime is left, and at most
+ * this many days ahead, so a 7- or 30-day certificate isn't replaced on
+ * every deploy.
+ */
+const RENEW_BEFORE_DAYS = 30;
+const RENEW_AT_LIFETIME_LEFT = 1 / 3;
+
+const DAY_MS = 24 * 60 * 60 * 1000;
+
+/** Secret annotation holding the Origin CA id, to revoke it when replaced. */
+export const ORIGIN_CERTIFICATE_ID_ANNOTATION =
+  "rulebricks.com/cloudflare-origin-certificate-id";
+
+/**
+ * Hostnames the certificate covers: every host the deployment serves plus
+ * tls.domains. The Origin CA issues wildcards without a DNS challenge.
+ */
+export function originCertificateHostnames(config: DeploymentConfig): string[] {
+  const hosts = getDeploymentDNSRecords(config, "", "ip").map(
+    (record) => record.hostname,
+  );
+  return [...new Set([...hosts, ...wildcardTlsDomains(config)])];
+}
+
+function coversHostname(sans: string[], hostname: string): boolean {
+  if (sans.includes(hostname)) return true;
+  const dot = hostname.indexOf(".");
+  return dot > 0 && sans.includes(`*${hostname.slice(dot)}`);
+}
+
+/**
+ * Why `pem` has to be reissued for `hostnames`, or null while it still
+ * covers all of them and is not about to expire.
+ */
+export function originCertificateProblem(
+  pem: string,
+  hostnames: string[],
+  now: Date = new Date(),
+): string | null {
+  let cert: X509Certificate;
+  try {
+    cert = new X509Certificate(pem);
+  } catch {
+    return "the stored certificate could not be parsed";
+  }
+  const sans = (cert.subjectAltName ?? "")
+    .split(",")
+    .map((entry) => entry.trim())
+    .filter((entry) => entry.startsWith("DNS:"))
+    .map((entry) => entry.slice("DNS:".length));
+  const missing = hostnames.filter((host) => !coversHostname(sans, host));
+  if (missing.length > 0) {
+    return `it does not cover ${missing.join(", ")}`;
+  }
+  const expires = new Date(cert.validTo);
+  const lifetimeMs = expires.getTime() - new Date(cert.validFrom).getTime();
+  const renewBeforeMs = Math.min(
+    RENEW_BEFORE_DAYS * DAY_MS,
+    lifetimeMs * RENEW_AT_LIFETIME_LEFT,
+  );
+  if (expires.getTime() - now.getTime() < renewBeforeMs) {
+    return `it expires ${expires.toISOString().slice(0, 10)}`;
+  }
+  return null;
+}
+
+/** A fresh RSA key and a CSR for it; the hostnames go in the API request. */
+async function generateKeyAndCsr(
+  commonName: string,
+): Promise<{ privateKey: string; csr: string }> {
+  const { privateKey } = generateKeyPairSync("rsa", {
+    modulusLength: 2048,
+    privateKeyEncoding: { type: "pkcs8", format: "pem" },
+    publicKeyEncoding: { type: "spki", format: "pem" },
+  });
+  const keyFile = await writeTempFile("rb-origin-", "tls.key", privateKey);
+  try {
+    const { stdout } = await execa("openssl", [
+      "req",
+      "-new",
+      "-key",
+      keyFile,
+      "-subj",
+      `/CN=${commonName}`,
+    ]);
+    return { privateKey, csr: stdout };
+  } catch (error) {
+    throw new Error(
+      `Could not create a certificate request with openssl (is it installed?): ${
+        error instanceof Error ? error.message : String(error)
+      }`,
+    );
+  } finally {
+    await removeTempPath(path.dirname(keyFile));
+  }
+}
+
+interface OriginCaResponse {
+  success: boolean;
+  errors?: Array<{ code: number; message: string }>;
+  result?: { id: string; certificate: string; expires_on: string };
+}
+
+/** Issues an Origin CA certificate through the Cloudflare API. */
+export async function requestOriginCertificate(
+  apiToken: string,
+  hostnames: string[],
+  csr: string,
+  validityDays: number = DEFAULT_VALIDITY_DAYS,
+): Promise<{ id: string; certificate: string; expiresOn: string }> {
+  const response = await fetch(ORIGIN_CA_API, {
+    method: "POST",
+    headers: {
+      Authorization: `Bearer ${apiToken}`,
+      "Content-Type": "application/json",
+    },
+    body: JSON.stringify({
+      hostnames,
+      requested_validity: validityDays,
+      request_type: "origin-rsa",
+      csr,
+    }),
+  });
+  const body = (await response.json().catch(() => ({
+    success: false,
+  }))) as OriginCaResponse;
+  if (!response.ok || !body.success || !body.result) {
+    const reasons = (body.errors ?? [])
+      .map((e) => `${e.message} (${e.code})`)
+      .join("; ");
+    throw new Error(
+      `Cloudflare Origin CA request failed (HTTP ${response.status})${
+        reasons ? `: ${reasons}` : ""
+      }. The API token needs Zone / SSL and Certificates / Edit.`,
+    );
+  }
+  return {
+    id: body.result.id,
+    certificate: body.result.certificate,
+    expiresOn: body.result.expires_on,
+  };
+}
+
+/** Revokes an Origin CA certificate through the Cloudflare API. */
+export async function revokeOriginCertificate(
+  apiToken: string,
+  id: string,
+): Promise<void> {
+  const response = await fetch(`${ORIGIN_CA_API}/${encodeURIComponent(id)}`, {
+    method: "DELETE",
+    headers: { Authorization: `Bearer ${apiToken}` },
+  });
+  const body = (await response.json().catch(() => ({
+    success: false,
+  }))) as OriginCaResponse;
+  if (!response.ok || !body.success) {
+    const reasons = (body.errors ?? [])
+      .map((e) => `${e.message} (${e.code})`)
+      .join("; ");
+    throw new Error(`HTTP ${response.status}${reasons ? `: ${reasons}` : ""}`);
+  }
+}
+
+/**
+ * Makes sure the deployment's Origin certificate Secret exists, covers every
+ * host and isn't close to expiry, issuing a new one otherwise. The replaced
+ * certificate is revoked; failing that is returned as a warning, since the
+ * new one is already in place.
+ */
+export async function ensureOriginCertificate(
+  config: DeploymentConfig,
+): Promise<{ issued: boolean; warning?: string }> {
+  const origin = config.tls?.cloudflareOrigin;
+  if (!origin) {
+    throw new Error(
+      'tls.cloudflareOrigin.apiToken is required when tls.provider is "cloudflare-origin"',
+    );
+  }
+  const namespace = getNamespace(config.name);
+  const secretName = originCertificateSecretName(config.name);
+  const hostnames = originCertificateHostnames(config);
+
+  const current = await getTLSSecretCertificate(namespace, secretName);
+  if (current && !originCertificateProblem(current.certificate, hostnames)) {
+    return { issued: false };
+  }
+
+  const { privateKey, csr } = await generateKeyAndCsr(config.domain);
+  const issued = await requestOriginCertificate(
+    origin.apiToken,
+    hostnames,
+    csr,
+    origin.validityDays,
+  );
+  await createTLSSecret(namespace, secretName, issued.certificate, privateKey, {
+    [ORIGIN_CERTIFICATE_ID_ANNOTATION]: issued.id,
+  });
+
+  const replacedId = current?.annotations[ORIGIN_CERTIFICATE_ID_ANNOTATION];
+  if (replacedId && replacedId !== issued.id) {
+    try {
+      await revokeOriginCertificate(origin.apiToken, replacedId);
+    } catch (error) {
+      return {
+        issued: true,
+        warning: `Could not revoke the replaced Origin certificate ${replacedId} (${
+          error instanceof Error ? error.message : String(error)
+        }); revoke it in the Cloudflare dashboard under SSL/TLS > Origin Server.`,
+      };
+    }
+  }
+  return { issued: true };
+}
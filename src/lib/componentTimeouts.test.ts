@@ -0,0 +1,70 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  componentTimeoutSeconds,
+  configComponentTimeouts,
+  parseComponentTimeouts,
+  parseDuration,
+  secondsWithinDeadline,
+  toHelmDuration,
+} from "./componentTimeouts.js";
+
+test("parseDuration accepts Go-style durations and bare seconds", () => {
+  assert.equal(parseDuration("90s"), 90);
+  assert.equal(parseDuration("20m"), 1200);
+  assert.equal(parseDuration("1h30m"), 5400);
+  assert.equal(parseDuration("45"), 45);
+  assert.throws(() => parseDuration("0m"));
+  assert.throws(() => parseDuration("ten minutes"));
+});
+
+test("unspecified components fall back to their defaults", () => {
+  const timeouts = parseComponentTimeouts("chart=30m, certificates=10m");
+  assert.equal(componentTimeoutSeconds(timeouts, "chart"), 1800);
+  assert.equal(componentTimeoutSeconds(timeouts, "certificates"), 600);
+  assert.equal(componentTimeoutSeconds(timeouts, "secrets"), 120);
+  assert.equal(
+    toHelmDuration(componentTimeoutSeconds(undefined, "chart")),
+    "900s",
+  );
+});
+
+test("unknown components and malformed entries are rejected", () => {
+  assert.throws(
+    () => parseComponentTimeouts("kafka=20m"),
+    /Unknown component "kafka"/,
+  );
+  assert.throws(
+    () => parseComponentTimeouts("chart"),
+    /<component>=<duration>/,
+  );
+  assert.throws(
+    () => parseComponentTimeouts("chart=soon"),
+    /Invalid duration/,
+  );
+});
+
+test("config timeouts fall back to default, and the flag overrides both", () => {
+  const fromConfig = configComponentTimeouts({ default: "20m", chart: "45m" });
+  assert.deepEqual(fromConfig, {
+    secrets: 1200,
+    chart: 2700,
+    certificates: 1200,
+  });
+  assert.deepEqual(configComponentTimeouts(undefined), {});
+
+  const merged = {
+    ...fromConfig,
+    ...parseComponentTimeouts("chart=1h"),
+  };
+  assert.equal(componentTimeoutSeconds(merged, "chart"), 3600);
+  assert.equal(componentTimeoutSeconds(merged, "secrets"), 1200);
+});
+
+test("waits are capped at what is left of the deploy deadline", () => {
+  const now = Date.parse("2026-10-15T12:00:00Z");
+  assert.equal(secondsWithinDeadline(900, undefined, now), 900);
+  assert.equal(secondsWithinDeadline(900, now + 300_500, now), 301);
+  assert.equal(secondsWithinDeadline(120, now + 600_000, now), 120);
+  assert.equal(secondsWithinDeadline(900, now - 5_000, now), 1);
+});
@@ -0,0 +1,120 @@
+// Per-component wait deadlines for `deploy --component-timeout`. A deploy
+// waits on three things: ExternalSecret sync before the install, the chart's
+// own `helm --wait` (every workload - Kafka, Supabase, the app - rolls out
+// inside that one release), and TLS certificate issuance afterwards. Each can
+// be given its own deadline; unspecified components keep their defaults.
+
+export type TimeoutComponent = "secrets" | "chart" | "certificates";
+export const TIMEOUT_COMPONENTS: TimeoutComponent[] = [
+  "secrets",
+  "chart",
+  "certificates",
+];
+
+/** Defaults in seconds, matching the previous hard-coded deadlines. */
+export const DEFAULT_COMPONENT_TIMEOUTS: Record<TimeoutComponent, number> = {
+  secrets: 120,
+  chart: 15 * 60,
+  certificates: 120,
+};
+
+export type ComponentTimeouts = Partial<Record<TimeoutComponent, number>>;
+
+const DURATION_UNITS: Record<string, number> = { h: 3600, m: 60, s: 1 };
+
+/**
+ * Parses a Go-style duration ("90s", "20m", "1h30m") or a bare number of
+ * seconds into whole seconds.
+ */
+export function parseDuration(value: string): number {
+  const trimmed = value.trim();
+  if (/^\d+$/.test(trimmed)) {
+    const seconds = Number(trimmed);
+    if (seconds > 0) return seconds;
+  } else if (/^(\d+[hms])+$/.test(trimmed)) {
+    let seconds = 0;
+    for (const [, amount, unit] of trimmed.matchAll(/(\d+)([hms])/g)) {
+      seconds += Number(amount) * DURATION_UNITS[unit];
+    }
+    if (seconds > 0) return seconds;
+  }
+  throw new Error(`Invalid duration "${value}". Use e.g. 90s, 20m or 1h30m.`);
+}
+
+function isTimeoutComponent(value: string): value is TimeoutComponent {
+  return (TIMEOUT_COMPONENTS as string[]).includes(value);
+}
+
+/**
+ * Parses "chart=30m,certificates=10m" into per-component seconds. Throws on
+ * unknown components or malformed entries so typos fail before deploying.
+ */
+export function parseComponentTimeouts(spec: string): ComponentTimeouts {
+  const timeouts: ComponentTimeouts = {};
+  for (const entry of spec.split(",")) {
+    if (!entry.trim()) continue;
+    const [rawName, rawDuration, ...rest] = entry.split("=");
+    const name = rawName.trim();
+    if (rawDuration === undefined || rest.length > 0) {
+      throw new Error(
+        `Invalid component timeout "${entry.trim()}". Use <component>=<duration>, e.g. chart=30m.`,
+      );
+    }
+    if (!isTimeoutComponent(name)) {
+      throw new Error(
+        `Unknown component "${name}". Timeouts can be set for: ${TIMEOUT_COMPONENTS.join(", ")} ` +
+          "(Kafka, Supabase and the app all roll out within the chart).",
+      );
+    }
+    timeouts[name] = parseDuration(rawDuration);
+  }
+  return timeouts;
+}
+
+/** The config file's `timeouts` block (durations as written). */
+export type ConfigTimeouts = Partial<
+  Record<TimeoutComponent | "default", string>
+>;
+
+/**
+ * Resolves the config file's `timeouts` block to per-component seconds: a
+ * component's own entry, else `default`, else nothing (the built-in default
+ * applies). Throws on a malformed duration.
+ */
+export function configComponentTimeouts(
+  timeouts: ConfigTimeouts | undefined,
+): ComponentTimeouts {
+  const resolved: ComponentTimeouts = {};
+  for (const component of TIMEOUT_COMPONENTS) {
+    const value = timeouts?.[component] ?? timeouts?.default;
+    if (value !== undefined) resolved[component] = parseDuration(value);
+  }
+  return resolved;
+}
+
+/** Deadline in seconds for a component, falling back to its default. */
+export function componentTimeoutSeconds(
+  timeouts: ComponentTimeouts | undefined,
+  component: TimeoutComponent,
+): number {
+  return timeouts?.[component] ?? DEFAULT_COMPONENT_TIMEOUTS[component];
+}
+
+/**
+ * Caps a component's wait at what is left before `deadlineAt` (epoch ms, from
+ * `deploy --timeout`), never below one second so the wait still runs and
+ * fails on its own terms.
+ */
+export function secondsWithinDeadline(
+  seconds: number,
+  deadlineAt: number | undefined,
+  now: number = Date.now(),
+): number {
+  if (deadlineAt === undefined) return seconds;
+  return Math.max(1, Math.min(seconds, Math.ceil((deadlineAt - now) / 1000)));
+}
+
+/** Formats seconds for `helm --timeout`. */
+export function toHelmDuration(seconds: number): string {
+  return `${seconds}s`;
+}
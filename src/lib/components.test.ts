@@ -0,0 +1,80 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  COMPONENTS,
+  componentStatus,
+  listComponents,
+  noLogPodsMessage,
+} from "./components.js";
+import { buildHelmValues } from "./helmValues.js";
+import { buildConfigMatrix } from "./configFixtures.js";
+import { VALID_LOG_COMPONENTS } from "./kubernetes.js";
+
+test("every component maps to a section buildHelmValues emits", () => {
+  const entry = buildConfigMatrix().find((c) => c.name === "aws-all-features");
+  assert.ok(entry);
+  const values = buildHelmValues(entry.config) as Record<string, unknown>;
+
+  for (const component of COMPONENTS) {
+    let section: unknown = values;
+    for (const key of component.valuesPath) {
+      section = (section as Record<string, unknown> | undefined)?.[key];
+    }
+    assert.equal(
+      typeof section,
+      "object",
+      `${component.name}: no values at ${component.valuesPath.join(".")}`,
+    );
+  }
+});
+
+test("dependencies and log components refer to known components", () => {
+  const names = new Set(COMPONENTS.map((c) => c.name));
+  for (const component of COMPONENTS) {
+    for (const dependency of component.dependsOn) {
+      assert.ok(names.has(dependency), `${component.name} -> ${dependency}`);
+    }
+  }
+  const logComponents = COMPONENTS.map((c) => c.logComponent).filter(Boolean);
+  assert.deepEqual(
+    [...logComponents].sort(),
+    [...VALID_LOG_COMPONENTS].sort(),
+  );
+});
+
+test("a disabled parent section disables the components under it", () => {
+  const workers = COMPONENTS.find((c) => c.name === "workers")!;
+  assert.equal(componentStatus(workers, null), "unknown");
+  assert.equal(
+    componentStatus(workers, { rulebricks: { hps: {} } }),
+    "enabled",
+  );
+  assert.equal(
+    componentStatus(workers, { rulebricks: { hps: { enabled: false } } }),
+    "disabled",
+  );
+
+  const listing = listComponents("rulebricks-prod", {
+    supabase: { enabled: false },
+  });
+  const supabase = listing.find((c) => c.name === "supabase");
+  assert.equal(supabase?.status, "disabled");
+  assert.equal(supabase?.namespace, "rulebricks-prod");
+});
+
+test("an empty logs result says why there is nothing to show", () => {
+  assert.equal(
+    noLogPodsMessage("grafana", "rulebricks-prod", {
+      "kube-prometheus-stack": { enabled: false },
+    }),
+    "No pods found for component 'grafana' in namespace rulebricks-prod: grafana is disabled in this deployment's values.",
+  );
+  assert.equal(
+    noLogPodsMessage("grafana", "rulebricks-prod", null),
+    "No pods found for component 'grafana' in namespace rulebricks-prod. Is monitoring enabled?",
+  );
+  assert.match(
+    noLogPodsMessage("app", "rulebricks-prod", {}),
+    /Is the deployment installed\?/,
+  );
+});
@@ -0,0 +1,194 @@
+/**
+ * What a Rulebricks deployment is made of, for `rulebricks components list`.
+ *
+ * Everything ships in the one `rulebricks` Helm chart and lands in the
+ * deployment namespace, so a component is a chart section: whether it is on
+ * is read from the same generated values.yaml the deploy installs, and a
+ * test pins every `valuesPath` to the keys buildHelmValues emits so this
+ * catalog can't drift from the chart wiring.
+ */
+
+export interface ComponentInfo {
+  name: string;
+  purpose: string;
+  /** Path of the component's section in the generated Helm values. */
+  valuesPath: string[];
+  /** Other components it needs running (by name). */
+  dependsOn: string[];
+  /** Component name accepted by `rulebricks logs`, when it has one. */
+  logComponent?: string;
+}
+
+export const COMPONENTS: ComponentInfo[] = [
+  {
+    name: "app",
+    purpose: "Web app and admin API (rule editor, dashboards, auth)",
+    valuesPath: ["rulebricks", "app"],
+    dependsOn: ["supabase", "redis", "kafka", "traefik"],
+    logComponent: "app",
+  },
+  {
+    name: "hps",
+    purpose: "High Performance Server: the rule-execution API",
+    valuesPath: ["rulebricks", "hps"],
+    dependsOn: ["redis", "kafka", "traefik"],
+    logComponent: "hps",
+  },
+  {
+    name: "workers",
+    purpose: "HPS workers that solve queued rule executions",
+    valuesPath: ["rulebricks", "hps", "workers"],
+    dependsOn: ["kafka", "keda"],
+    logComponent: "workers",
+  },
+  {
+    name: "kafka",
+    purpose: "Message bus between HPS, workers, and decision logging",
+    valuesPath: ["kafka"],
+    dependsOn: ["strimzi-kafka-operator"],
+    logComponent: "kafka",
+  },
+  {
+    name: "strimzi-kafka-operator",
+    purpose: "Operator that runs the in-cluster Kafka broker and topics",
+    valuesPath: ["strimzi-kafka-operator"],
+    dependsOn: [],
+  },
+  {
+    name: "supabase",
+    purpose: "Postgres database, auth, and REST/realtime APIs",
+    valuesPath: ["supabase"],
+    dependsOn: ["traefik"],
+    logComponent: "supabase",
+  },
+  {
+    name: "redis",
+    purpose: "Cache and rate-limit store shared by app and HPS",
+    valuesPath: ["rulebricks", "redis"],
+    dependsOn: [],
+    logComponent: "redis",
+  },
+  {
+    name: "vector",
+    purpose: "Ships decision logs from Kafka to object storage and sinks",
+    valuesPath: ["vector"],
+    dependsOn: ["kafka"],
+    logComponent: "vector",
+  },
+  {
+    name: "traefik",
+    purpose: "Ingress controller and TLS termination",
+    valuesPath: ["traefik"],
+    dependsOn: [],
+    logComponent: "traefik",
+  },
+  {
+    name: "cert-manager",
+    purpose: "Issues Let's Encrypt certificates for the ingresses",
+    valuesPath: ["cert-manager"],
+    dependsOn: ["traefik"],
+  },
+  {
+    name: "keda",
+    purpose: "Autoscales workers from Kafka consumer lag",
+    valuesPath: ["keda"],
+    dependsOn: ["kafka"],
+  },
+  {
+    name: "external-secrets",
+    purpose: "Syncs credentials from the cloud secrets manager",
+    valuesPath: ["external-secrets"],
+    dependsOn: [],
+  },
+  {
+    name: "clickhouse",
+    purpose: "Decision-log and telemetry store for ClickStack",
+    valuesPath: ["clickhouse"],
+    dependsOn: ["vector"],
+  },
+  {
+    name: "prometheus",
+    purpose: "Metrics collection (kube-prometheus-stack)",
+    valuesPath: ["kube-prometheus-stack"],
+    dependsOn: [],
+    logComponent: "prometheus",
+  },
+  {
+    name: "grafana",
+    purpose: "In-cluster dashboards for the collected metrics",
+    valuesPath: ["kube-prometheus-stack", "grafana"],
+    dependsOn: ["prometheus"],
+    logComponent: "grafana",
+  },
+];
+
+export type ComponentStatus = "enabled" | "disabled" | "unknown";
+
+export interface ComponentListing extends ComponentInfo {
+  namespace: string;
+  status: ComponentStatus;
+}
+
+function getPath(values: unknown, path: string[]): unknown {
+  let current = values;
+  for (const key of path) {
+    if (!current || typeof current !== "object") return undefined;
+    current = (current as Record<string, unknown>)[key];
+  }
+  return current;
+}
+
+/**
+ * Whether a component is on in a deployment's generated values. A component
+ * is off when its section, or any section above it, sets `enabled: false`;
+ * sections without the flag follow their parent (the chart's defaults are
+ * on). Without values.yaml the status is unknown.
+ */
+export function componentStatus(
+  component: ComponentInfo,
+  values: Record<string, unknown> | null,
+): ComponentStatus {
+  if (!values) return "unknown";
+  for (let depth = 1; depth <= component.valuesPath.length; depth++) {
+    const section = getPath(values, component.valuesPath.slice(0, depth));
+    if (
+      section &&
+      typeof section === "object" &&
+      (section as { enabled?: unknown }).enabled === false
+    ) {
+      return "disabled";
+    }
+  }
+  return "enabled";
+}
+
+export function listComponents(
+  namespace: string,
+  values: Record<string, unknown> | null,
+): ComponentListing[] {
+  return COMPONENTS.map((component) => ({
+    ...component,
+    namespace,
+    status: componentStatus(component, values),
+  }));
+}
+
+/**
+ * Why `rulebricks logs <component>` found nothing to show: the component is
+ * switched off, or (for the monitoring stack) probably never installed.
+ */
+export function noLogPodsMessage(
+  component: string,
+  namespace: string,
+  values: Record<string, unknown> | null,
+): string {
+  const message = `No pods found for component '${component}' in namespace ${namespace}`;
+  const info = COMPONENTS.find((c) => c.logComponent === component);
+  if (info && componentStatus(info, values) === "disabled") {
+    return `${message}: ${info.name} is disabled in this deployment's values.`;
+  }
+  if (info && [info.name, ...info.dependsOn].includes("prometheus")) {
+    return `${message}. Is monitoring enabled?`;
+  }
+  return `${message}. Is the deployment installed? Check "rulebricks status".`;
+}
@@ -0,0 +1,204 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { promises as fs } from "node:fs";
+import os from "node:os";
+import path from "node:path";
+import yaml from "yaml";
+import { configFixture } from "./configFixtures.js";
+import { formatEncryptedValue } from "./configEncryption.js";
+import type { ConfigIssue } from "./configValidation.js";
+
+// config.ts resolves ~/.rulebricks at import time, so point HOME at a scratch
+// directory before loading it (or anything that imports it).
+const home = await fs.mkdtemp(path.join(os.tmpdir(), "rb-config-test-"));
+process.env.HOME = home;
+const {
+  saveDeploymentConfig,
+  saveDeploymentState,
+  saveHelmValues,
+  saveProfile,
+  extractProfileFromConfig,
+  writePrivateFile,
+  pinConfigChartVersion,
+  getDeploymentDir,
+  installDeploymentConfig,
+  loadDeploymentConfig,
+  validateConfigFile,
+} = await import("./config.js");
+const { buildHelmValues } = await import("./helmValues.js");
+
+async function listFiles(dir: string): Promise<string[]> {
+  const entries = await fs.readdir(dir, { withFileTypes: true });
+  const files: string[] = [];
+  for (const entry of entries) {
+    const full = path.join(dir, entry.name);
+    if (entry.isDirectory()) {
+      files.push(full, ...(await listFiles(full)));
+    } else {
+      files.push(full);
+    }
+  }
+  return files;
+}
+
+test("deploy-time writes leave no group/world-readable files", async () => {
+  const config = configFixture("aws-all-features");
+
+  await saveDeploymentConfig(config);
+  await saveHelmValues(
+    config.name,
+    buildHelmValues(config, { secretMode: "inline" }),
+  );
+  await saveDeploymentState(config.name, {
+    name: config.name,
+    version: config.version,
+    createdAt: "2026-01-01T00:00:00.000Z",
+    updatedAt: "2026-01-01T00:00:00.000Z",
+    status: "deploying",
+  });
+  await saveProfile(extractProfileFromConfig(config));
+
+  const root = path.join(home, ".rulebricks");
+  const written = await listFiles(root);
+  assert.ok(written.some((f) => f.endsWith("config.yaml")));
+  for (const file of [root, ...written]) {
+    const mode = (await fs.stat(file)).mode & 0o777;
+    assert.equal(mode & 0o077, 0, `${file} has mode ${mode.toString(8)}`);
+  }
+});
+
+test("writePrivateFile tightens a pre-existing world-readable file", async () => {
+  const file = path.join(home, "legacy-values.yaml");
+  await fs.writeFile(file, "old", { mode: 0o644 });
+  await writePrivateFile(file, "new");
+  assert.equal((await fs.stat(file)).mode & 0o777, 0o600);
+  assert.equal(await fs.readFile(file, "utf-8"), "new");
+});
+
+test("writePrivateFile writes through a symlink instead of replacing it", async () => {
+  const real = path.join(home, "dotfiles-config.yaml");
+  const link = path.join(home, "linked-config.yaml");
+  await fs.writeFile(real, "old");
+  await fs.symlink(real, link);
+  await writePrivateFile(link, "new");
+  assert.ok((await fs.lstat(link)).isSymbolicLink());
+  assert.equal(await fs.readFile(real, "utf-8"), "new");
+});
+
+test("pinConfigChartVersion edits config.yaml in place", async () => {
+  const dir = getDeploymentDir("pinned");
+  await fs.mkdir(dir, { recursive: true });
+  const file = path.join(dir, "config.yaml");
+  await fs.writeFile(
+    file,
+    "# managed by ops\nname: pinned\nsecret: enc:v1:abc\n",
+  );
+  await pinConfigChartVersion("pinned", "2.3.1");
+  const content = await fs.readFile(file, "utf-8");
+  assert.match(content, /^# managed by ops/);
+  assert.match(content, /secret: enc:v1:abc/);
+  assert.match(content, /chartVersion: 2\.3\.1/);
+  assert.equal((await fs.stat(file)).mode & 0o777, 0o600);
+});
+
+test("a config extending a base saves only its differences", async () => {
+  const config = configFixture("aws-all-features");
+  const dir = getDeploymentDir("layered");
+  await fs.mkdir(dir, { recursive: true });
+  await fs.writeFile(
+    path.join(home, "base.yaml"),
+    yaml.stringify({ ...config, name: "base" }),
+  );
+  await fs.writeFile(
+    path.join(dir, "config.yaml"),
+    yaml.stringify({ extends: "../../../base.yaml", name: "layered" }),
+  );
+
+  const loaded = await loadDeploymentConfig("layered");
+  assert.equal(loaded.name, "layered");
+  assert.equal(loaded.domain, config.domain);
+
+  await saveDeploymentConfig({
+    ...loaded,
+    infrastructure: { ...loaded.infrastructure, region: "eu-west-1" },
+  });
+  const own = yaml.parse(
+    await fs.readFile(path.join(dir, "config.yaml"), "utf-8"),
+  );
+  assert.deepEqual(own, {
+    extends: "../../../base.yaml",
+    name: "layered",
+    infrastructure: { region: "eu-west-1" },
+  });
+  const reloaded = await loadDeploymentConfig("layered");
+  assert.equal(reloaded.infrastructure.region, "eu-west-1");
+  assert.equal(reloaded.domain, config.domain);
+});
+
+test("validate flags plaintext credentials only once encryption is in use", async () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  await saveDeploymentConfig(config);
+  const file = path.join(getDeploymentDir(config.name), "config.yaml");
+
+  const plaintext = (issues: ConfigIssue[]) =>
+    issues.filter((issue) => issue.message.startsWith("plaintext credential"));
+  assert.deepEqual(plaintext(await validateConfigFile(file)), []);
+
+  process.env.RULEBRICKS_AGE_RECIPIENT = "age1example";
+  try {
+    const strict = plaintext(await validateConfigFile(file, { strict: true }));
+    assert.ok(strict.some((issue) => issue.path === "licenseKey"));
+    assert.ok(strict.every((issue) => issue.severity === "warning"));
+  } finally {
+    delete process.env.RULEBRICKS_AGE_RECIPIENT;
+  }
+});
+
+test("saving refuses to write an encrypted config back in plaintext", async () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const named = { ...config, name: "encrypted" };
+  await saveDeploymentConfig(named);
+  const file = path.join(getDeploymentDir(named.name), "config.yaml");
+  const raw = yaml.parse(await fs.readFile(file, "utf8"));
+  raw.licenseKey = formatEncryptedValue("c2VjcmV0");
+  await fs.writeFile(file, yaml.stringify(raw));
+
+  await assert.rejects(saveDeploymentConfig(named), /RULEBRICKS_AGE_RECIPIENT/);
+  assert.equal(
+    yaml.parse(await fs.readFile(file, "utf8")).licenseKey,
+    raw.licenseKey,
+  );
+});
+
+test("a fetched config installs verbatim once it validates", async () => {
+  const config = configFixture("aws-external-postgres");
+  const content = `# from the artifact server\n${yaml.stringify(config)}`;
+
+  await assert.rejects(
+    installDeploymentConfig(content, "someone-else"),
+    /is for deployment/,
+  );
+  await assert.rejects(
+    installDeploymentConfig(yaml.stringify({ ...config, domain: 42 })),
+  );
+  await assert.rejects(fs.stat(getDeploymentDir(config.name)));
+
+  assert.equal(await installDeploymentConfig(content), config.name);
+  const file = path.join(getDeploymentDir(config.name), "config.yaml");
+  assert.equal(await fs.readFile(file, "utf-8"), content);
+  assert.equal((await fs.stat(file)).mode & 0o777, 0o600);
+});
+
+test("a fetched config is validated over the base it extends", async () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const basePath = path.join(getDeploymentDir(config.name), "..", "base.yaml");
+  const content = yaml.stringify({
+    name: config.name,
+    extends: "../base.yaml",
+  });
+
+  await assert.rejects(installDeploymentConfig(content), /could not be read/);
+  await fs.writeFile(basePath, yaml.stringify({ ...config, name: undefined }));
+  assert.equal(await installDeploymentConfig(content), config.name);
+  await fs.rm(basePath);
+});
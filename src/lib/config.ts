@@ -4,11 +4,34 @@ import os from "os";
 import yaml from "yaml";
 import {
   DeploymentConfig,
-  DeploymentConfigSchema,
+  DeploymentEvent,
   DeploymentState,
   ProfileConfig,
   ProfileConfigSchema,
 } from "../types/index.js";
+import {
+  ConfigIssue,
+  ConfigValidationError,
+  findUnknownConfigKeys,
+  parseDeploymentConfig,
+  sortConfigIssues,
+  validateDeploymentConfig,
+} from "./configValidation.js";
+import {
+  decryptSensitiveValues,
+  encryptSensitiveValues,
+  hasEncryptedValues,
+  listSensitiveFields,
+  resolveAgeRecipient,
+} from "./configEncryption.js";
+import { appendEvent } from "./deploymentHistory.js";
+import { waitForFileUnlocked, withFileLock } from "./fileLock.js";
+import {
+  configOverlay,
+  EXTENDS_KEY,
+  layerConfig,
+  readLayeredConfig,
+} from "./configLayers.js";
 
 const RULEBRICKS_DIR = path.join(os.homedir(), ".rulebricks");
 const DEPLOYMENTS_DIR = path.join(RULEBRICKS_DIR, "deployments");
@@ -18,7 +41,42 @@ const PROFILE_FILE = "profile.yaml";
  * Ensures the base directories exist
  */
 export async function ensureDirectories(): Promise<void> {
-  await fs.mkdir(DEPLOYMENTS_DIR, { recursive: true });
+  await fs.mkdir(DEPLOYMENTS_DIR, { recursive: true, mode: 0o700 });
+}
+
+/**
+ * Writes a file readable only by the current user. config.yaml, values.yaml
+ * (with --inline-secrets), and profile.yaml hold plaintext credentials (SMTP
+ * password, JWT secret, DB password, license and API keys), so every file the
+ * CLI writes under ~/.rulebricks goes through here.
+ *
+ * The content goes to a temporary file that is renamed over the target, so
+ * a crash mid-write leaves the previous file intact, never a truncated one.
+ * A symlinked target (a config.yaml kept in a dotfiles repo) is written
+ * through: the rename replaces the file the link points at, not the link.
+ */
+export async function writePrivateFile(
+  filePath: string,
+  content: string,
+): Promise<void> {
+  const target = await fs.realpath(filePath).catch((error) => {
+    if ((error as NodeJS.ErrnoException).code === "ENOENT") return filePath;
+    throw error;
+  });
+  const tempPath = `${target}.${process.pid}.tmp`;
+  try {
+    await fs.writeFile(tempPath, content, { encoding: "utf-8", mode: 0o600 });
+    // `mode` only applies on creation; tighten a leftover temporary file.
+    await fs.chmod(tempPath, 0o600);
+    await fs.rename(tempPath, target);
+  } catch (error) {
+    await fs.rm(tempPath, { force: true });
+    throw error;
+  }
+}
+
+async function ensurePrivateDir(dir: string): Promise<void> {
+  await fs.mkdir(dir, { recursive: true, mode: 0o700 });
 }
 
 /**
@@ -162,27 +220,152 @@ async function migrateConfig(
 }
 
 /**
- * Saves a deployment configuration
+ * Saves a deployment configuration. With RULEBRICKS_AGE_RECIPIENT set, the
+ * credentials are written encrypted. Without it, a config.yaml that holds
+ * encrypted values is not overwritten, since the config in memory was
+ * decrypted on load and would be written back in plaintext. A config that
+ * extends a base keeps doing so, and only what differs from the base is
+ * written.
  */
 export async function saveDeploymentConfig(
   config: DeploymentConfig,
 ): Promise<void> {
   const dir = getDeploymentDir(config.name);
-  await fs.mkdir(dir, { recursive: true });
+  await ensurePrivateDir(dir);
+  const configPath = path.join(dir, "config.yaml");
+
+  let own: unknown = config;
+  const existing = await readLayeredConfig(configPath).catch(() => null);
+  const recipient = resolveAgeRecipient();
+  if (!recipient && existing && (await hasEncryptedValues(existing.own))) {
+    throw new Error(
+      `${configPath} has encrypted values; saving without RULEBRICKS_AGE_RECIPIENT would write them back in plaintext. Set it to the age public key the config is encrypted for, or run \`rulebricks config decrypt ${config.name}\` first.`,
+    );
+  }
+  if (existing?.base) {
+    const base = (await hasEncryptedValues(existing.base))
+      ? await decryptSensitiveValues(existing.base)
+      : existing.base;
+    own = {
+      [EXTENDS_KEY]: (existing.own as Record<string, unknown>)[EXTENDS_KEY],
+      ...configOverlay(
+        base as Record<string, unknown>,
+        config as Record<string, unknown>,
+      ),
+    };
+  }
+
+  const content = recipient
+    ? await encryptSensitiveValues(own, recipient)
+    : own;
+  await writePrivateFile(configPath, yaml.stringify(content));
+}
 
+/**
+ * Writes a config.yaml that may not validate yet (`init --from-existing`),
+ * with `header` as its leading comment block.
+ */
+export async function saveImportedDeploymentConfig(
+  name: string,
+  config: Record<string, unknown>,
+  header: string,
+): Promise<string> {
+  const dir = getDeploymentDir(name);
+  await ensurePrivateDir(dir);
+
+  const doc = new yaml.Document(config);
+  doc.commentBefore = header;
   const configPath = path.join(dir, "config.yaml");
-  await fs.writeFile(configPath, yaml.stringify(config), "utf-8");
+  await writePrivateFile(configPath, doc.toString());
+  return configPath;
 }
 
 /**
- * Loads a deployment configuration
+ * Installs a config.yaml fetched by `deploy --config-url` as the deployment's
+ * config, byte for byte so encrypted values and comments survive. It has to
+ * validate first; a broken remote config never replaces a working one.
+ * Returns the deployment name the config declares.
+ */
+export async function installDeploymentConfig(
+  content: string,
+  expectedName?: string,
+): Promise<string> {
+  const own = yaml.parse(content);
+  const name =
+    own && typeof own === "object" && typeof own.name === "string"
+      ? own.name
+      : null;
+  if (!name) {
+    throw new Error("The fetched config has no deployment name.");
+  }
+  if (expectedName && name !== expectedName) {
+    throw new Error(
+      `The fetched config is for deployment "${name}", not "${expectedName}".`,
+    );
+  }
+  const dir = getDeploymentDir(name);
+  const configPath = path.join(dir, "config.yaml");
+  // Validate what the deploy will load: the config over the base it extends.
+  let { parsed: effective } = await layerConfig(configPath, own);
+  if (await hasEncryptedValues(effective)) {
+    effective = await decryptSensitiveValues(effective);
+  }
+  const { config, issues } = parseDeploymentConfig(effective);
+  if (!config) {
+    throw new ConfigValidationError(issues);
+  }
+
+  await ensurePrivateDir(dir);
+  await writePrivateFile(configPath, content);
+  return name;
+}
+
+/**
+ * Pins the chart version in a deployment's config.yaml (`deploy
+ * --pin-version`). Edits the file in place rather than re-saving the loaded
+ * config, so encrypted values stay encrypted and comments survive.
+ */
+export async function pinConfigChartVersion(
+  name: string,
+  chartVersion: string,
+): Promise<void> {
+  const configPath = path.join(getDeploymentDir(name), "config.yaml");
+  const doc = yaml.parseDocument(await fs.readFile(configPath, "utf-8"));
+  doc.set("chartVersion", chartVersion);
+  await writePrivateFile(configPath, doc.toString());
+}
+
+/**
+ * Points config.yaml back at the versions a rollback returned to (`upgrade
+ * rollback --revert-config`), editing in place like pinConfigChartVersion.
+ * The chart version is only rewritten when the config pins one.
+ */
+export async function revertConfigVersions(
+  name: string,
+  versions: { version?: string; chartVersion?: string },
+): Promise<void> {
+  const configPath = path.join(getDeploymentDir(name), "config.yaml");
+  const doc = yaml.parseDocument(await fs.readFile(configPath, "utf-8"));
+  if (versions.version) doc.set("version", versions.version);
+  if (versions.chartVersion && doc.has("chartVersion")) {
+    doc.set("chartVersion", versions.chartVersion);
+  }
+  await writePrivateFile(configPath, doc.toString());
+}
+
+/**
+ * Loads a deployment configuration. Schema problems are reported together as a
+ * ConfigValidationError listing every offending field path. Values encrypted
+ * with `rulebricks config encrypt` are decrypted in memory first.
  */
 export async function loadDeploymentConfig(
   name: string,
 ): Promise<DeploymentConfig> {
   const configPath = path.join(getDeploymentDir(name), "config.yaml");
-  const content = await fs.readFile(configPath, "utf-8");
-  const parsed = yaml.parse(content);
+  let { parsed } = await readLayeredConfig(configPath);
+  if (await hasEncryptedValues(parsed)) {
+    parsed = await decryptSensitiveValues(parsed);
+  }
   if (
     parsed &&
     typeof parsed === "object" &&
@@ -197,7 +380,95 @@ export async function loadDeploymentConfig(
     );
   }
   await migrateConfig(name, parsed);
-  return DeploymentConfigSchema.parse(parsed);
+  const { config, issues } = parseDeploymentConfig(parsed);
+  if (!config) {
+    throw new ConfigValidationError(issues);
+  }
+  return config;
+}
+
+/**
+ * Checks a config.yaml without deploying it: schema problems and the
+ * cross-field rules in one list, plus (strict) keys the schema ignores.
+ * Plaintext credentials are a warning in a config that uses encryption.
+ * `name` locates values.yaml/state.yaml for the version migration; it
+ * defaults to the config's directory name.
+ */
+export async function validateConfigFile(
+  configPath: string,
+  options: { strict?: boolean; name?: string } = {},
+): Promise<ConfigIssue[]> {
+  let { parsed } = await readLayeredConfig(configPath);
+  // Plaintext credentials are only flagged in a config that uses
+  // encryption: one with encrypted values, or with a recipient configured.
+  const encrypted = await hasEncryptedValues(parsed);
+  const plaintext =
+    encrypted || resolveAgeRecipient()
+      ? (await listSensitiveFields(parsed))
+          .filter((field) => !field.encrypted)
+          .map(
+            (field): ConfigIssue => ({
+              path: field.path,
+              message:
+                "plaintext credential; encrypt it with `rulebricks config encrypt`",
+              severity: "warning",
+            }),
+          )
+      : [];
+  if (encrypted) {
+    parsed = await decryptSensitiveValues(parsed);
+  }
+  await migrateConfig(
+    options.name || path.basename(path.dirname(path.resolve(configPath))),
+    parsed,
+  );
+
+  const { config, issues } = parseDeploymentConfig(parsed);
+  if (!config) {
+    return sortConfigIssues([...issues, ...plaintext]);
+  }
+  return sortConfigIssues([
+    ...(options.strict ? findUnknownConfigKeys(parsed, config) : []),
+    ...validateDeploymentConfig(config),
+    ...plaintext,
+  ]);
+}
+
+/**
+ * Encrypts the sensitive values in a deployment's config.yaml in place for
+ * the given age recipient. Returns the field paths encrypted by this call.
+ */
+export async function encryptDeploymentConfig(
+  name: string,
+  recipient: string,
+): Promise<string[]> {
+  const configPath = path.join(getDeploymentDir(name), "config.yaml");
+  const parsed = yaml.parse(await fs.readFile(configPath, "utf-8"));
+  const pending = (await listSensitiveFields(parsed)).filter(
+    (field) => !field.encrypted,
+  );
+  if (pending.length > 0) {
+    const encrypted = await encryptSensitiveValues(parsed, recipient);
+    await writePrivateFile(configPath, yaml.stringify(encrypted));
+  }
+  return pending.map((field) => field.path);
+}
+
+/**
+ * Decrypts a deployment's config.yaml back to plaintext in place. Returns
+ * the field paths decrypted by this call.
+ */
+export async function decryptDeploymentConfig(name: string): Promise<string[]> {
+  const configPath = path.join(getDeploymentDir(name), "config.yaml");
+  const parsed = yaml.parse(await fs.readFile(configPath, "utf-8"));
+  const encrypted = (await listSensitiveFields(parsed)).filter(
+    (field) => field.encrypted,
+  );
+  if (encrypted.length > 0) {
+    const decrypted = await decryptSensitiveValues(parsed);
+    await writePrivateFile(configPath, yaml.stringify(decrypted));
+  }
+  return encrypted.map((field) => field.path);
 }
 
 /**
@@ -217,6 +488,23 @@ export async function cloneDeploymentConfig(
   return clonedConfig;
 }
 
+// Held while state.yaml is written; see fileLock.ts.
+function stateLockPath(name: string): string {
+  return path.join(getDeploymentDir(name), "state.yaml.lock");
+}
+
+async function readDeploymentState(
+  name: string,
+): Promise<DeploymentState | null> {
+  const statePath = path.join(getDeploymentDir(name), "state.yaml");
+  try {
+    const content = await fs.readFile(statePath, "utf-8");
+    return yaml.parse(content) as DeploymentState;
+  } catch {
+    return null;
+  }
+}
+
 /**
  * Saves the deployment state
  */
@@ -225,10 +513,12 @@ export async function saveDeploymentState(
   state: DeploymentState,
 ): Promise<void> {
   const dir = getDeploymentDir(name);
-  await fs.mkdir(dir, { recursive: true });
+  await ensurePrivateDir(dir);
 
   const statePath = path.join(dir, "state.yaml");
-  await fs.writeFile(statePath, yaml.stringify(state), "utf-8");
+  await withFileLock(stateLockPath(name), () =>
+    writePrivateFile(statePath, yaml.stringify(state)),
+  );
 }
 
 /**
@@ -237,13 +527,35 @@ export async function saveDeploymentState(
 export async function loadDeploymentState(
   name: string,
 ): Promise<DeploymentState | null> {
-  const statePath = path.join(getDeploymentDir(name), "state.yaml");
+  await waitForFileUnlocked(stateLockPath(name));
+  return readDeploymentState(name);
+}
+
+/**
+ * Reads, changes and writes the deployment state under its lock, so a
+ * concurrent command's update can't be lost in between. Does nothing and
+ * returns null when the deployment has no state.
+ */
+export async function updateDeploymentState(
+  name: string,
+  update: (state: DeploymentState) => DeploymentState,
+): Promise<DeploymentState | null> {
+  const dir = getDeploymentDir(name);
   try {
-    const content = await fs.readFile(statePath, "utf-8");
-    return yaml.parse(content) as DeploymentState;
+    await fs.access(dir);
   } catch {
     return null;
   }
+  return withFileLock(stateLockPath(name), async () => {
+    const state = await readDeploymentState(name);
+    if (!state) return null;
+    const updated = update(state);
+    await writePrivateFile(
+      path.join(dir, "state.yaml"),
+      yaml.stringify(updated),
+    );
+    return updated;
+  });
 }
 
 /**
@@ -254,10 +566,10 @@ export async function saveHelmValues(
   values: Record<string, unknown>,
 ): Promise<string> {
   const dir = getDeploymentDir(name);
-  await fs.mkdir(dir, { recursive: true });
+  await ensurePrivateDir(dir);
 
   const valuesPath = path.join(dir, "values.yaml");
-  await fs.writeFile(valuesPath, yaml.stringify(values), "utf-8");
+  await writePrivateFile(valuesPath, yaml.stringify(values));
   return valuesPath;
 }
 
@@ -299,10 +611,9 @@ export async function updateDeploymentStatus(
   status: DeploymentState["status"],
   updates?: Partial<DeploymentState>,
 ): Promise<void> {
-  const state = await loadDeploymentState(name);
-  if (state) {
+  await updateDeploymentState(name, (state) => {
     // Deep merge nested objects like application, infrastructure, dnsRecords
-    const updatedState: DeploymentState = {
+    return {
       ...state,
       ...updates,
       // Deep merge application object to preserve existing fields
@@ -316,8 +627,21 @@ export async function updateDeploymentStatus(
       status,
       updatedAt: new Date().toISOString(),
     };
-    await saveDeploymentState(name, updatedState);
-  }
+  });
+}
+
+/**
+ * Appends a finished run to the deployment's history. Does nothing when the
+ * deployment has no state, e.g. after destroy removed its files.
+ */
+export async function recordDeploymentEvent(
+  name: string,
+  event: DeploymentEvent,
+): Promise<void> {
+  await updateDeploymentState(name, (state) => ({
+    ...state,
+    history: appendEvent(state.history, event),
+  }));
 }
 
 // ============================================================================
@@ -357,7 +681,7 @@ export async function loadProfile(): Promise<ProfileConfig | null> {
  * Saves the user profile to ~/.rulebricks/profile.yaml
  */
 export async function saveProfile(profile: ProfileConfig): Promise<void> {
-  await fs.mkdir(RULEBRICKS_DIR, { recursive: true });
+  await ensurePrivateDir(RULEBRICKS_DIR);
   const profilePath = path.join(RULEBRICKS_DIR, PROFILE_FILE);
 
   // Filter out undefined values to keep the file clean
@@ -365,7 +689,7 @@ export async function saveProfile(profile: ProfileConfig): Promise<void> {
     Object.entries(profile).filter(([_, v]) => v !== undefined),
   );
 
-  await fs.writeFile(profilePath, yaml.stringify(cleanProfile), "utf-8");
+  await writePrivateFile(profilePath, yaml.stringify(cleanProfile));
 }
 
 /**
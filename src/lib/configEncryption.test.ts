@@ -0,0 +1,125 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  formatEncryptedValue,
+  isEncryptedValue,
+  listSensitiveFields,
+  mapSensitiveValues,
+  maskSensitiveValues,
+  MASKED_VALUE,
+  parseEncryptedValue,
+} from "./configEncryption.js";
+import { configFixture } from "./configFixtures.js";
+
+const ARMORED =
+  "-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n-----END AGE ENCRYPTED FILE-----\n";
+
+test("encrypted values round-trip through the ENC marker", () => {
+  const marked = formatEncryptedValue(ARMORED);
+  assert.ok(marked.startsWith("ENC[age,"));
+  assert.ok(isEncryptedValue(marked));
+  assert.equal(parseEncryptedValue(marked), ARMORED);
+  assert.equal(isEncryptedValue("hunter2"), false);
+});
+
+test("only non-empty values under sensitive keys are transformed", async () => {
+  const config = {
+    name: "prod",
+    licenseKey: "lic-123",
+    smtp: { host: "smtp.example.com", user: "mailer", pass: "secret" },
+    database: { supabaseDbPassword: "", existingSecret: "db-creds" },
+    features: { ai: { enabled: true, openaiApiKey: "sk-test" } },
+  };
+
+  const result = (await mapSensitiveValues(config, async (value) =>
+    value.toUpperCase(),
+  )) as typeof config;
+
+  assert.equal(result.licenseKey, "LIC-123");
+  assert.equal(result.smtp.pass, "SECRET");
+  assert.equal(result.smtp.user, "mailer");
+  assert.equal(result.database.supabaseDbPassword, "");
+  assert.equal(result.database.existingSecret, "db-creds");
+  assert.equal(result.features.ai.openaiApiKey, "SK-TEST");
+  assert.equal(config.licenseKey, "lic-123");
+});
+
+test("sensitive fields are listed with their encryption status", async () => {
+  const fields = await listSensitiveFields({
+    licenseKey: formatEncryptedValue(ARMORED),
+    smtp: { pass: "secret" },
+  });
+  assert.deepEqual(fields, [
+    { path: "licenseKey", encrypted: true },
+    { path: "smtp.pass", encrypted: false },
+  ]);
+});
+
+test("masking hides credentials and leaves secret references", async () => {
+  const masked = (await maskSensitiveValues({
+    licenseKey: "lic-123",
+    smtp: { user: "mailer", pass: formatEncryptedValue(ARMORED) },
+    remoteWrite: { passwordSecretRef: { name: "metrics", key: "password" } },
+  })) as Record<string, any>;
+
+  assert.equal(masked.licenseKey, MASKED_VALUE);
+  assert.equal(masked.smtp.pass, MASKED_VALUE);
+  assert.equal(masked.smtp.user, "mailer");
+  assert.deepEqual(masked.remoteWrite.passwordSecretRef, {
+    name: "metrics",
+    key: "password",
+  });
+});
+
+test("credentials are found by schema path, not leaf name", async () => {
+  const fields = await listSensitiveFields({
+    storage: { bucket: "decision-logs" },
+    externalServices: {
+      postgres: {
+        external: { bootstrap: { masterPassword: "master", appRole: "app" } },
+      },
+    },
+    features: {
+      logging: {
+        sink: "datadog",
+        bucket: "dd-api-key",
+        region: "datadoghq.eu",
+        sinks: [
+          { sink: "otlp", bucket: "https://otel:4318", region: "x-key=abc" },
+          { sink: "loki", bucket: "https://loki:3100", region: "prod" },
+        ],
+      },
+      tracing: { otlp: { headers: { "x-api-key": "abc" } } },
+    },
+  });
+  assert.deepEqual(
+    fields.map((field) => field.path),
+    [
+      "externalServices.postgres.external.bootstrap.masterPassword",
+      "features.logging.bucket",
+      "features.logging.sinks[0].region",
+      "features.tracing.otlp.headers.x-api-key",
+    ],
+  );
+});
+
+test("config show masks every credential of an external Postgres deployment", async () => {
+  const config = configFixture("aws-external-postgres");
+  config.features.logging = {
+    sink: "axiom",
+    bucket: "xaat-axiom-token",
+    region: "rulebricks",
+    sinks: [{ sink: "otlp", bucket: "https://otel:4318", region: "k=v" }],
+  };
+
+  const masked = (await maskSensitiveValues(config)) as Record<string, any>;
+  const bootstrap = masked.externalServices.postgres.external.bootstrap;
+  assert.equal(bootstrap.masterPassword, MASKED_VALUE);
+  assert.equal(bootstrap.appRole, "postgres");
+  assert.equal(masked.licenseKey, MASKED_VALUE);
+  assert.equal(masked.features.logging.bucket, MASKED_VALUE);
+  assert.equal(masked.features.logging.region, "rulebricks");
+  assert.equal(masked.features.logging.sinks[0].region, MASKED_VALUE);
+  assert.equal(masked.features.logging.sinks[0].bucket, "https://otel:4318");
+  assert.doesNotMatch(JSON.stringify(masked), /master-pw-change-me/);
+});
@@ -0,0 +1,265 @@
+import os from "os";
+import path from "path";
+import { execa } from "execa";
+
+/**
+ * At-rest encryption for the credentials in config.yaml.
+ *
+ * `rulebricks config encrypt` replaces each credential (license key, SMTP
+ * and database passwords, API keys and tokens) with an age-encrypted value
+ * written as `ENC[age,<base64>]`, so the rest of the file stays readable and
+ * diffable. loadDeploymentConfig decrypts those values before validation
+ * whenever an age identity is available, so every command keeps working on
+ * the plaintext config in memory. Encryption and decryption shell out to the
+ * `age` CLI, the same way cloud and cluster operations shell out to theirs.
+ */
+
+/**
+ * Config fields that hold credentials, as dotted schema paths where `*`
+ * matches any record key. Matching the full path rather than the leaf name
+ * keeps a secret reference's `key` readable while catching credentials with
+ * generic names.
+ */
+const SENSITIVE_PATHS = [
+  "licenseKey",
+  "smtp.pass",
+  "database.supabaseJwtSecret",
+  "database.supabaseDbPassword",
+  "database.supabaseDashboardPass",
+  "database.supabaseServiceKey",
+  "database.supabaseAccessToken",
+  "externalServices.redis.external.password",
+  "externalServices.redis.external.httpApi.token",
+  "externalServices.kafka.external.sasl.password",
+  "externalServices.postgres.external.bootstrap.masterPassword",
+  "notifications.webhook.token",
+  "notifications.slack.webhookUrl",
+  "tls.cloudflareOrigin.apiToken",
+  "features.ai.openaiApiKey",
+  "features.sso.clientSecret",
+  "features.tracing.elastic.secretToken",
+  "features.tracing.elastic.apiKey",
+  "features.tracing.otlp.token",
+  "features.tracing.otlp.apiKey",
+  "features.tracing.otlp.headerValue",
+  "features.tracing.otlp.headers.*",
+  "features.tracing.azureMonitor.connectionString",
+  "features.logging.appLogs.elasticsearch.password",
+  "features.logging.appLogs.elasticsearch.apiKey",
+  "features.logging.appLogs.generic.authHeader",
+];
+
+function pathPattern(schemaPath: string): RegExp {
+  const source = schemaPath
+    .split(".")
+    .map((segment) => (segment === "*" ? "[^.]+" : segment))
+    .join("\\.");
+  return new RegExp(`^${source}$`);
+}
+
+const SENSITIVE_PATTERNS = SENSITIVE_PATHS.map(pathPattern);
+
+/**
+ * Logging platforms repurpose `bucket`/`region` (features.logging and each
+ * of its sinks) for their credential: the API key or token, Elasticsearch's
+ * JSON config with its auth, or OTLP's auth headers.
+ */
+const LOGGING_CREDENTIAL_FIELD: Record<string, "bucket" | "region"> = {
+  datadog: "bucket",
+  splunk: "bucket",
+  elasticsearch: "bucket",
+  newrelic: "bucket",
+  axiom: "bucket",
+  otlp: "region",
+};
+
+const LOGGING_SINK_PATH = /^features\.logging(\.sinks\[\d+\])?$/;
+
+/** Whether `key` of the object at `parentPath` holds a credential. */
+export function isSensitiveField(
+  parent: Record<string, unknown>,
+  parentPath: string,
+  key: string,
+): boolean {
+  const fieldPath = parentPath ? `${parentPath}.${key}` : key;
+  if (SENSITIVE_PATTERNS.some((pattern) => pattern.test(fieldPath))) {
+    return true;
+  }
+  return (
+    LOGGING_SINK_PATH.test(parentPath) &&
+    typeof parent.sink === "string" &&
+    LOGGING_CREDENTIAL_FIELD[parent.sink] === key
+  );
+}
+
+function getErrorMessage(error: unknown): string {
+  const execaError = error as { stderr?: string; message?: string };
+  return execaError.stderr?.trim() || execaError.message || String(error);
+}
+
+const ENCRYPTED_PATTERN = /^ENC\[age,([A-Za-z0-9+/=]+)\]$/;
+
+export function isEncryptedValue(value: unknown): value is string {
+  return typeof value === "string" && ENCRYPTED_PATTERN.test(value);
+}
+
+export function formatEncryptedValue(ciphertext: string): string {
+  return `ENC[age,${Buffer.from(ciphertext, "utf-8").toString("base64")}]`;
+}
+
+export function parseEncryptedValue(value: string): string {
+  const match = ENCRYPTED_PATTERN.exec(value);
+  if (!match) {
+    throw new Error("Value is not an ENC[age,...] encrypted value");
+  }
+  return Buffer.from(match[1], "base64").toString("utf-8");
+}
+
+type LeafTransform = (value: string, fieldPath: string) => Promise<string>;
+
+/**
+ * Returns a copy of a parsed config with every non-empty string in a
+ * sensitive field passed through `transform`. Other values are left as-is.
+ */
+export async function mapSensitiveValues(
+  value: unknown,
+  transform: LeafTransform,
+  fieldPath = "",
+): Promise<unknown> {
+  if (Array.isArray(value)) {
+    return Promise.all(
+      value.map((item, i) =>
+        mapSensitiveValues(item, transform, `${fieldPath}[${i}]`),
+      ),
+    );
+  }
+  if (!value || typeof value !== "object") return value;
+
+  const parent = value as Record<string, unknown>;
+  const result: Record<string, unknown> = {};
+  for (const [key, child] of Object.entries(parent)) {
+    const childPath = fieldPath ? `${fieldPath}.${key}` : key;
+    if (
+      typeof child === "string" &&
+      child &&
+      isSensitiveField(parent, fieldPath, key)
+    ) {
+      result[key] = await transform(child, childPath);
+    } else {
+      result[key] = await mapSensitiveValues(child, transform, childPath);
+    }
+  }
+  return result;
+}
+
+/** Dotted paths of the sensitive fields in a parsed config. */
+export async function listSensitiveFields(
+  value: unknown,
+): Promise<{ path: string; encrypted: boolean }[]> {
+  const fields: { path: string; encrypted: boolean }[] = [];
+  await mapSensitiveValues(value, async (leaf, fieldPath) => {
+    fields.push({ path: fieldPath, encrypted: isEncryptedValue(leaf) });
+    return leaf;
+  });
+  return fields;
+}
+
+/** What `config show` prints in place of a credential. */
+export const MASKED_VALUE = "********";
+
+/** A copy of a parsed config with every credential replaced by a mask. */
+export async function maskSensitiveValues(value: unknown): Promise<unknown> {
+  return mapSensitiveValues(value, async () => MASKED_VALUE);
+}
+
+/** Whether a parsed config holds any encrypted values. */
+export async function hasEncryptedValues(value: unknown): Promise<boolean> {
+  const fields = await listSensitiveFields(value);
+  return fields.some((field) => field.encrypted);
+}
+
+/**
+ * Recipient for `config encrypt`: the --recipient flag, else
+ * RULEBRICKS_AGE_RECIPIENT.
+ */
+export function resolveAgeRecipient(flag?: string): string | undefined {
+  return flag || process.env.RULEBRICKS_AGE_RECIPIENT || undefined;
+}
+
+/**
+ * Identity file used for decryption: RULEBRICKS_AGE_KEY_FILE, else
+ * SOPS_AGE_KEY_FILE, else the sops default location.
+ */
+export function resolveAgeIdentityFile(): string {
+  return (
+    process.env.RULEBRICKS_AGE_KEY_FILE ||
+    process.env.SOPS_AGE_KEY_FILE ||
+    path.join(os.homedir(), ".config", "sops", "age", "keys.txt")
+  );
+}
+
+async function runAge(
+  args: string[],
+  input: string,
+  stripFinalNewline = true,
+): Promise<string> {
+  try {
+    const { stdout } = await execa("age", args, { input, stripFinalNewline });
+    return stdout;
+  } catch (error) {
+    if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+      throw new Error(
+        "The age CLI is required for encrypted config values. Install it from https://age-encryption.org.",
+      );
+    }
+    throw new Error(getErrorMessage(error));
+  }
+}
+
+/**
+ * Encrypts every plaintext sensitive value in a parsed config. Values that
+ * are already encrypted are kept, so re-running after `configure` only
+ * encrypts what was added.
+ */
+export async function encryptSensitiveValues(
+  parsed: unknown,
+  recipient: string,
+): Promise<unknown> {
+  return mapSensitiveValues(parsed, async (value) => {
+    if (isEncryptedValue(value)) return value;
+    // Keep the armor's trailing newline so the decoded block round-trips.
+    const armored = await runAge(
+      ["--encrypt", "--armor", "-r", recipient],
+      value,
+      false,
+    );
+    return formatEncryptedValue(armored);
+  });
+}
+
+/**
+ * Decrypts every ENC[age,...] value in a parsed config with the identity
+ * from resolveAgeIdentityFile().
+ */
+export async function decryptSensitiveValues(
+  parsed: unknown,
+): Promise<unknown> {
+  const identityFile = resolveAgeIdentityFile();
+  return mapSensitiveValues(parsed, async (value, fieldPath) => {
+    if (!isEncryptedValue(value)) return value;
+    try {
+      // The plaintext is returned byte for byte, trailing newline included.
+      return await runAge(
+        ["--decrypt", "-i", identityFile],
+        parseEncryptedValue(value),
+        false,
+      );
+    } catch (error) {
+      throw new Error(
+        `Could not decrypt ${fieldPath} with ${identityFile}: ${getErrorMessage(
+          error,
+        )}. Set RULEBRICKS_AGE_KEY_FILE to the matching age identity.`,
+      );
+    }
+  });
+}
@@ -582,3 +582,12 @@ export function buildConfigMatrix(): { name: string; config: DeploymentConfig }[
 
   return cases.map((options) => ({ name: options.name, config: build(options) }));
 }
+
+/** A deep copy of one matrix config by name, for tests to modify freely. */
+export function configFixture(name: string): DeploymentConfig {
+  const entry = buildConfigMatrix().find((c) => c.name === name);
+  if (!entry) {
+    throw new Error(`missing matrix fixture ${name}`);
+  }
+  return JSON.parse(JSON.stringify(entry.config)) as DeploymentConfig;
+}
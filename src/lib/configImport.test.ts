@@ -0,0 +1,85 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  importConfigFromValues,
+  importedConfigHeader,
+  parseKubeClusterName,
+} from "./configImport.js";
+import { configFixture } from "./configFixtures.js";
+import { buildHelmValues } from "./helmValues.js";
+import { parseDeploymentConfig } from "./configValidation.js";
+
+test("a self-hosted config round-trips through its Helm values", () => {
+  const original = configFixture("aws-self-hosted-minimal");
+  const { config, todos } = importConfigFromValues({
+    name: original.name,
+    values: buildHelmValues(original),
+    chartVersion: "2.4.1",
+    location: parseKubeClusterName(
+      "arn:aws:eks:us-east-1:123456789012:cluster/rulebricks-cluster",
+    ),
+  });
+
+  const { config: parsed, issues } = parseDeploymentConfig(config);
+  assert.deepEqual(issues, []);
+  assert.ok(parsed);
+  assert.equal(parsed.domain, original.domain);
+  assert.equal(parsed.tlsEmail, original.tlsEmail);
+  assert.deepEqual(parsed.smtp, original.smtp);
+  assert.equal(parsed.database.type, "self-hosted");
+  assert.equal(
+    parsed.database.supabaseJwtSecret,
+    original.database.supabaseJwtSecret,
+  );
+  assert.equal(parsed.licenseKey, original.licenseKey);
+  assert.equal(parsed.version, original.version);
+  assert.equal(parsed.chartVersion, "2.4.1");
+  assert.equal(parsed.infrastructure.clusterName, "rulebricks-cluster");
+  assert.equal(parsed.storage?.bucket, original.storage?.bucket);
+  // Only the settings the values can't carry are left to the user.
+  assert.deepEqual(
+    todos.map((todo) => todo.split(":")[0]),
+    ["features.monitoring, features.logging, externalServices, secrets"],
+  );
+});
+
+test("settings missing from the values become TODOs", () => {
+  const { config, todos } = importConfigFromValues({
+    name: "prod",
+    values: { global: { domain: "rb.example.com", smtp: { port: 587 } } },
+  });
+  assert.equal(config.domain, "rb.example.com");
+  assert.equal((config.smtp as Record<string, unknown>).port, 587);
+  const paths = todos.map((todo) => todo.split(":")[0]);
+  for (const path of [
+    "infrastructure.provider, region, clusterName",
+    "adminEmail",
+    "smtp.pass",
+    "database.supabaseUrl",
+    "licenseKey",
+    "version",
+  ]) {
+    assert.ok(paths.includes(path), `expected a TODO for ${path}`);
+  }
+  assert.ok(!paths.includes("domain"));
+
+  const header = importedConfigHeader("rulebricks-prod", "rulebricks-prod", [
+    "licenseKey: not in the release values",
+  ]);
+  assert.match(header, /TODO before deploying:\n {3}- licenseKey/);
+});
+
+test("kubeconfig cluster names map back to the cloud cluster", () => {
+  assert.deepEqual(parseKubeClusterName("admin@prod.eu-west-1.eksctl.io"), {
+    provider: "aws",
+    region: "eu-west-1",
+    clusterName: "prod",
+  });
+  assert.deepEqual(parseKubeClusterName("gke_acme-prod_us-central1_rb"), {
+    provider: "gcp",
+    gcpProjectId: "acme-prod",
+    region: "us-central1",
+    clusterName: "rb",
+  });
+  assert.deepEqual(parseKubeClusterName("rb-aks"), {});
+});
@@ -0,0 +1,300 @@
+import type { ClusterCapabilities } from "./kubernetes.js";
+
+/**
+ * `init --from-existing`: rebuilds a deployment's config.yaml from what is
+ * running in the cluster, for when the local copy was lost. The source is the
+ * release's user-supplied values (`helm get values`), which the CLI generated
+ * from the original config, so most settings map straight back. Anything the
+ * values don't carry is left out and reported as a TODO for the user to fill
+ * in before the next deploy.
+ */
+
+export interface ImportedConfig {
+  /** A best-effort config.yaml body; may not validate until TODOs are done. */
+  config: Record<string, unknown>;
+  /** One line per setting that could not be recovered, keyed by its path. */
+  todos: string[];
+}
+
+export interface ClusterLocation {
+  provider?: "aws" | "gcp" | "azure";
+  region?: string;
+  clusterName?: string;
+  gcpProjectId?: string;
+}
+
+// external-dns provider names back to dns.provider (see helmValues).
+const DNS_PROVIDERS: Record<string, string> = {
+  aws: "route53",
+  cloudflare: "cloudflare",
+  google: "google",
+  azure: "azure",
+};
+
+function get(values: unknown, path: string): unknown {
+  let current = values;
+  for (const key of path.split(".")) {
+    if (typeof current !== "object" || current === null) return undefined;
+    current = (current as Record<string, unknown>)[key];
+  }
+  return current;
+}
+
+function str(values: unknown, path: string): string | undefined {
+  const value = get(values, path);
+  return typeof value === "string" && value !== "" ? value : undefined;
+}
+
+/** Drops undefined leaves so the YAML only lists what was recovered. */
+function compact(value: Record<string, unknown>): Record<string, unknown> {
+  return Object.fromEntries(
+    Object.entries(value).filter(([, v]) => v !== undefined),
+  );
+}
+
+/**
+ * The cloud cluster behind a kubeconfig cluster entry, from the names each
+ * provider's CLI writes (see kubeNameMatchesCluster). AKS entries are the bare
+ * cluster name and say nothing about the provider.
+ */
+export function parseKubeClusterName(kubeName: string): ClusterLocation {
+  const eks = kubeName.match(/^arn:aws:eks:([^:]+):\d+:cluster\/(.+)$/);
+  if (eks) return { provider: "aws", region: eks[1], clusterName: eks[2] };
+  const eksctl = kubeName.match(/@([^.]+)\.([^.]+)\.eksctl\.io$/);
+  if (eksctl) {
+    return { provider: "aws", region: eksctl[2], clusterName: eksctl[1] };
+  }
+  const gke = kubeName.match(/^gke_([^_]+)_([^_]+)_(.+)$/);
+  if (gke) {
+    return {
+      provider: "gcp",
+      gcpProjectId: gke[1],
+      region: gke[2],
+      clusterName: gke[3],
+    };
+  }
+  return {};
+}
+
+export function importConfigFromValues(input: {
+  name: string;
+  values: Record<string, unknown>;
+  chartVersion?: string | null;
+  location?: ClusterLocation;
+  capabilities?: ClusterCapabilities | null;
+}): ImportedConfig {
+  const { values } = input;
+  const todos: string[] = [];
+  const required = (path: string, value: unknown, hint: string) => {
+    if (value === undefined) todos.push(`${path}: ${hint}`);
+    return value;
+  };
+  const notInValues = "not in the release values";
+
+  const location = input.location ?? {};
+  if (!location.provider) {
+    todos.push(
+      "infrastructure.provider, region, clusterName: optional; set them so the CLI can refresh kubeconfig",
+    );
+  }
+  const capabilities = input.capabilities;
+  const infrastructure = compact({
+    mode: "existing",
+    ...location,
+    nodeArchitecture: capabilities?.nodeArchitecture,
+    arm64TolerationRequired: capabilities?.arm64TolerationRequired,
+    storageClass: capabilities?.storageClass,
+    storageProvisioner: capabilities?.storageProvisioner,
+  });
+
+  const externalDns = get(values, "global.externalDnsEnabled") === true;
+  const externalDnsProvider = str(values, "external-dns.provider.name");
+  const dnsProvider = externalDnsProvider
+    ? DNS_PROVIDERS[externalDnsProvider]
+    : undefined;
+  const dns = externalDns
+    ? { provider: dnsProvider ?? "route53", autoManage: true }
+    : { provider: "other", autoManage: false };
+  if (externalDns && !dnsProvider) {
+    todos.push(
+      "dns.provider: external-dns is enabled but its provider is unknown",
+    );
+  }
+
+  const smtpPort = get(values, "global.smtp.port");
+  const smtp = compact({
+    host: required("smtp.host", str(values, "global.smtp.host"), notInValues),
+    port: required(
+      "smtp.port",
+      typeof smtpPort === "number" ? smtpPort : undefined,
+      notInValues,
+    ),
+    user: required("smtp.user", str(values, "global.smtp.user"), notInValues),
+    pass: required(
+      "smtp.pass",
+      str(values, "global.smtp.pass"),
+      "not in the release values (kept in the secrets backend?)",
+    ),
+    from: required("smtp.from", str(values, "global.smtp.from"), notInValues),
+    fromName: required(
+      "smtp.fromName",
+      str(values, "global.smtp.fromName"),
+      notInValues,
+    ),
+  });
+
+  const selfHosted = get(values, "supabase.enabled") === true;
+  const database = selfHosted
+    ? compact({
+        type: "self-hosted",
+        supabaseJwtSecret: required(
+          "database.supabaseJwtSecret",
+          str(values, "supabase.secret.jwt.secret") ??
+            str(values, "global.supabase.jwtSecret"),
+          notInValues,
+        ),
+        supabaseDbPassword: required(
+          "database.supabaseDbPassword",
+          str(values, "supabase.secret.db.password"),
+          notInValues,
+        ),
+        supabaseDashboardUser: str(
+          values,
+          "supabase.secret.dashboard.username",
+        ),
+        supabaseDashboardPass: str(
+          values,
+          "supabase.secret.dashboard.password",
+        ),
+      })
+    : compact({
+        type: "supabase-cloud",
+        supabaseUrl: required(
+          "database.supabaseUrl",
+          str(values, "global.supabase.url"),
+          notInValues,
+        ),
+        supabaseAnonKey: required(
+          "database.supabaseAnonKey",
+          str(values, "global.supabase.anonKey"),
+          notInValues,
+        ),
+        supabaseServiceKey: required(
+          "database.supabaseServiceKey",
+          str(values, "global.supabase.serviceKey"),
+          notInValues,
+        ),
+        supabaseAccessToken: required(
+          "database.supabaseAccessToken",
+          str(values, "global.supabase.accessToken"),
+          notInValues,
+        ),
+        supabaseProjectRef: str(values, "global.supabase.projectRef"),
+      });
+
+  const provider = str(values, "global.storage.provider");
+  const storage = provider
+    ? compact({
+        provider,
+        cloudAuthMode:
+          provider === "azure-blob"
+            ? str(values, "global.storage.azure.authMode") ===
+              "connection-string"
+              ? "secret"
+              : "workload-identity"
+            : undefined,
+        bucket: required(
+          "storage.bucket",
+          str(values, "global.storage.bucket"),
+          notInValues,
+        ),
+        region: required(
+          "storage.region",
+          str(values, "global.storage.region"),
+          notInValues,
+        ),
+        awsIamRoleArn: str(values, "global.storage.s3.iamRoleArn"),
+        azureBlobClientId: str(values, "global.storage.azure.clientId"),
+        azureBlobTenantId: str(values, "global.storage.azure.tenantId"),
+        azureBlobContainer: str(values, "global.storage.azure.container"),
+        gcpServiceAccountEmail: str(
+          values,
+          "global.storage.gcp.serviceAccountEmail",
+        ),
+      })
+    : undefined;
+  if (!storage) todos.push(`storage: ${notInValues}`);
+
+  const aiEnabled = get(values, "global.ai.enabled") === true;
+  const ssoEnabled = get(values, "global.sso.enabled") === true;
+  todos.push(
+    "features.monitoring, features.logging, externalServices, secrets: not imported; review them with `rulebricks configure`",
+  );
+
+  const config = compact({
+    name: input.name,
+    infrastructure,
+    domain: required("domain", str(values, "global.domain"), notInValues),
+    adminEmail: required(
+      "adminEmail",
+      str(values, "global.email"),
+      notInValues,
+    ),
+    tlsEmail: required(
+      "tlsEmail",
+      str(values, "clusterIssuer.email"),
+      notInValues,
+    ),
+    dns,
+    smtp,
+    database,
+    storage,
+    features: {
+      ai: compact({
+        enabled: aiEnabled,
+        openaiApiKey: aiEnabled
+          ? str(values, "global.ai.openaiApiKey")
+          : undefined,
+      }),
+      sso: ssoEnabled
+        ? compact({
+            enabled: true,
+            provider: str(values, "global.sso.provider"),
+            url: str(values, "global.sso.url"),
+            clientId: str(values, "global.sso.clientId"),
+            clientSecret: str(values, "global.sso.clientSecret"),
+          })
+        : { enabled: false },
+      monitoring: {},
+      logging: { sink: "console" },
+    },
+    licenseKey: required(
+      "licenseKey",
+      str(values, "global.licenseKey"),
+      notInValues,
+    ),
+    version: required(
+      "version",
+      str(values, "global.version"),
+      "not pinned in the release; set the Rulebricks version it runs",
+    ),
+    imageRegistry: str(values, "global.imageRegistry"),
+    chartVersion: input.chartVersion ?? undefined,
+  });
+
+  return { config, todos };
+}
+
+/** The comment block written above an imported config.yaml. */
+export function importedConfigHeader(
+  release: string,
+  namespace: string,
+  todos: string[],
+): string {
+  return [
+    ` Imported from Helm release ${release} in ${namespace}.`,
+    ...(todos.length > 0
+      ? [" TODO before deploying:", ...todos.map((todo) => `   - ${todo}`)]
+      : []),
+  ].join("\n");
+}
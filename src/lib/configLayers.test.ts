@@ -0,0 +1,134 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { promises as fs } from "node:fs";
+import os from "node:os";
+import path from "node:path";
+import {
+  configOverlay,
+  mergeConfigLayers,
+  readLayeredConfig,
+  resolveBasePath,
+} from "./configLayers.js";
+
+async function scratchDir(): Promise<string> {
+  return fs.mkdtemp(path.join(os.tmpdir(), "rb-config-layers-"));
+}
+
+test("overlay objects merge into the base; scalars and lists replace", () => {
+  const merged = mergeConfigLayers(
+    {
+      cloud: { provider: "aws", region: "us-east-1" },
+      features: { ai: { enabled: false } },
+      domain: "base.example.com",
+      dns: { aliases: ["a.example.com", "b.example.com"] },
+    },
+    {
+      cloud: { region: "eu-west-1" },
+      domain: "prod.example.com",
+      dns: { aliases: ["c.example.com"] },
+    },
+  );
+
+  assert.deepEqual(merged, {
+    cloud: { provider: "aws", region: "eu-west-1" },
+    features: { ai: { enabled: false } },
+    domain: "prod.example.com",
+    dns: { aliases: ["c.example.com"] },
+  });
+});
+
+test("configOverlay keeps only what differs and layers back to the config", () => {
+  const base = {
+    cloud: { provider: "aws", region: "us-east-1" },
+    tier: "small",
+    dns: { aliases: ["a.example.com"] },
+  };
+  const config = {
+    name: "prod",
+    cloud: { provider: "aws", region: "eu-west-1" },
+    tier: "small",
+    dns: { aliases: ["a.example.com"] },
+  };
+
+  const overlay = configOverlay(base, config);
+  assert.deepEqual(overlay, { name: "prod", cloud: { region: "eu-west-1" } });
+  assert.deepEqual(mergeConfigLayers(base, overlay), config);
+});
+
+test("keys removed from the config are written as null and stay removed", () => {
+  const base = {
+    features: { ai: { enabled: true, model: "small" } },
+    smtp: { host: "smtp.example.com" },
+  };
+  const config = { name: "prod", features: { ai: { enabled: true } } };
+
+  const overlay = configOverlay(base, config);
+  assert.deepEqual(overlay, {
+    name: "prod",
+    features: { ai: { model: null } },
+    smtp: null,
+  });
+  assert.deepEqual(mergeConfigLayers(base, overlay), config);
+});
+
+test("base paths resolve against the config's directory", () => {
+  assert.equal(
+    resolveBasePath("/deployments/prod/config.yaml", "../base.yaml"),
+    "/deployments/base.yaml",
+  );
+  assert.equal(
+    resolveBasePath("/deployments/prod/config.yaml", "/etc/base.yaml"),
+    "/etc/base.yaml",
+  );
+  assert.equal(
+    resolveBasePath("/deployments/prod/config.yaml", "~/base.yaml"),
+    path.join(os.homedir(), "base.yaml"),
+  );
+});
+
+test("readLayeredConfig layers a config over the base it extends", async () => {
+  const dir = await scratchDir();
+  await fs.writeFile(
+    path.join(dir, "base.yaml"),
+    "tier: small\ncloud:\n  provider: aws\n  region: us-east-1\n",
+  );
+  const configPath = path.join(dir, "config.yaml");
+  await fs.writeFile(
+    configPath,
+    "extends: base.yaml\nname: staging\ncloud:\n  region: us-west-2\n",
+  );
+
+  const layered = await readLayeredConfig(configPath);
+  assert.equal(layered.basePath, path.join(dir, "base.yaml"));
+  assert.deepEqual(layered.parsed, {
+    tier: "small",
+    name: "staging",
+    cloud: { provider: "aws", region: "us-west-2" },
+  });
+  assert.equal((layered.own as { extends: string }).extends, "base.yaml");
+});
+
+test("a config without extends reads as written", async () => {
+  const dir = await scratchDir();
+  const configPath = path.join(dir, "config.yaml");
+  await fs.writeFile(configPath, "name: solo\n");
+
+  const layered = await readLayeredConfig(configPath);
+  assert.deepEqual(layered.parsed, { name: "solo" });
+  assert.equal(layered.basePath, undefined);
+});
+
+test("readLayeredConfig rejects missing and chained bases", async () => {
+  const dir = await scratchDir();
+  const configPath = path.join(dir, "config.yaml");
+
+  await fs.writeFile(configPath, "extends: missing.yaml\n");
+  await assert.rejects(readLayeredConfig(configPath), /could not be read/);
+
+  await fs.writeFile(path.join(dir, "base.yaml"), "extends: root.yaml\n");
+  await fs.writeFile(configPath, "extends: base.yaml\n");
+  await assert.rejects(readLayeredConfig(configPath), /can't extend/);
+
+  await fs.writeFile(configPath, "extends: 3\n");
+  await assert.rejects(readLayeredConfig(configPath), /must be a file path/);
+});
@@ -0,0 +1,133 @@
+import { promises as fs } from "fs";
+import os from "os";
+import path from "path";
+import yaml from "yaml";
+
+/**
+ * Shared base configs. A config.yaml with `extends: <file>` is layered over
+ * that file: objects merge key by key, and anything else the config sets
+ * (scalars, lists) replaces the base's value. Staging and production can
+ * then keep their common settings in one base file and only their
+ * differences in their own config.yaml. A null in the config removes the
+ * base's key instead. The path is relative to the config file; a base can't
+ * itself extend another file.
+ */
+
+export const EXTENDS_KEY = "extends";
+
+type ConfigObject = Record<string, unknown>;
+
+function isPlainObject(value: unknown): value is ConfigObject {
+  return typeof value === "object" && value !== null && !Array.isArray(value);
+}
+
+export function mergeConfigLayers(
+  base: ConfigObject,
+  overlay: ConfigObject,
+): ConfigObject {
+  const merged: ConfigObject = { ...base };
+  for (const [key, value] of Object.entries(overlay)) {
+    if (value === null) {
+      delete merged[key];
+      continue;
+    }
+    const baseValue = base[key];
+    merged[key] =
+      isPlainObject(value) && isPlainObject(baseValue)
+        ? mergeConfigLayers(baseValue, value)
+        : value;
+  }
+  return merged;
+}
+
+/**
+ * The smallest overlay that layers over `base` to give `config`: only the
+ * values that differ, and a null for each key the base sets but `config`
+ * lacks, so a setting removed from the config doesn't come back from the
+ * base.
+ */
+export function configOverlay(
+  base: ConfigObject,
+  config: ConfigObject,
+): ConfigObject {
+  const overlay: ConfigObject = {};
+  for (const [key, value] of Object.entries(config)) {
+    const baseValue = base[key];
+    if (isPlainObject(value) && isPlainObject(baseValue)) {
+      const nested = configOverlay(baseValue, value);
+      if (Object.keys(nested).length > 0) overlay[key] = nested;
+    } else if (JSON.stringify(value) !== JSON.stringify(baseValue)) {
+      overlay[key] = value;
+    }
+  }
+  for (const key of Object.keys(base)) {
+    if (config[key] === undefined) overlay[key] = null;
+  }
+  return overlay;
+}
+
+/** The base file a config names, resolved against the config's directory. */
+export function resolveBasePath(configPath: string, ref: string): string {
+  const expanded = ref.startsWith("~/")
+    ? path.join(os.homedir(), ref.slice(2))
+    : ref;
+  return path.resolve(path.dirname(configPath), expanded);
+}
+
+export interface LayeredConfig {
+  /** The effective config: the base with the file layered over it. */
+  parsed: unknown;
+  /** The file as written, before layering. */
+  own: unknown;
+  basePath?: string;
+  base?: ConfigObject;
+}
+
+/** Reads a config file and layers it over the base it extends, if any. */
+export async function readLayeredConfig(
+  configPath: string,
+): Promise<LayeredConfig> {
+  const own = yaml.parse(await fs.readFile(configPath, "utf-8"));
+  return layerConfig(configPath, own);
+}
+
+/**
+ * Layers already-parsed config content over the base it extends, as if it
+ * were the file at `configPath`; used to check a config before writing it.
+ */
+export async function layerConfig(
+  configPath: string,
+  own: unknown,
+): Promise<LayeredConfig> {
+  const ref = isPlainObject(own) ? own[EXTENDS_KEY] : undefined;
+  if (ref === undefined) return { parsed: own, own };
+  if (typeof ref !== "string" || !ref) {
+    throw new Error(`${configPath}: "${EXTENDS_KEY}" must be a file path.`);
+  }
+
+  const basePath = resolveBasePath(configPath, ref);
+  let base: unknown;
+  try {
+    base = yaml.parse(await fs.readFile(basePath, "utf-8"));
+  } catch (error) {
+    throw new Error(
+      `${configPath} extends ${basePath}, which could not be read: ${error instanceof Error ? error.message : String(error)}`,
+    );
+  }
+  if (!isPlainObject(base)) {
+    throw new Error(`${basePath} is not a YAML mapping.`);
+  }
+  if (EXTENDS_KEY in base) {
+    throw new Error(
+      `${basePath} is a base config and can't extend another file.`,
+    );
+  }
+  const overlay = { ...(own as ConfigObject) };
+  delete overlay[EXTENDS_KEY];
+  return {
+    parsed: mergeConfigLayers(base, overlay),
+    own,
+    basePath,
+    base,
+  };
+}
@@ -0,0 +1,325 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  ConfigValidationError,
+  assertValidDeploymentConfig,
+  findUnknownConfigKeys,
+  formatConfigIssues,
+  parseDeploymentConfig,
+  validateDeploymentConfig,
+} from "./configValidation.js";
+import { buildConfigMatrix, configFixture } from "./configFixtures.js";
+
+test("config matrix has no error-severity cross-field issues", () => {
+  for (const { name, config } of buildConfigMatrix()) {
+    const errors = validateDeploymentConfig(config).filter(
+      (i) => i.severity === "error",
+    );
+    assert.deepEqual(errors, [], `${name}: unexpected config errors`);
+  }
+});
+
+test("parseDeploymentConfig reports every schema issue with its path", () => {
+  const raw = configFixture("aws-self-hosted-minimal") as Record<string, any>;
+  raw.adminEmail = "not-an-email";
+  raw.smtp.port = 0;
+  delete raw.licenseKey;
+
+  const { config, issues } = parseDeploymentConfig(raw);
+  assert.equal(config, null);
+  const paths = issues.map((i) => i.path);
+  assert.ok(paths.includes("adminEmail"));
+  assert.ok(paths.includes("smtp.port"));
+  assert.ok(paths.includes("licenseKey"));
+});
+
+test("Prometheus retention and storage size must be valid", () => {
+  const raw = configFixture("aws-self-hosted-minimal") as Record<string, any>;
+  raw.features.monitoring.metrics = { retention: "1d12h", storageSize: "80Gi" };
+  assert.ok(parseDeploymentConfig(raw).config);
+
+  raw.features.monitoring.metrics = { retention: "30", storageSize: "80GB" };
+  const { config, issues } = parseDeploymentConfig(raw);
+  assert.equal(config, null);
+  assert.deepEqual(
+    issues.map((i) => i.path),
+    [
+      "features.monitoring.metrics.retention",
+      "features.monitoring.metrics.storageSize",
+    ],
+  );
+});
+
+test("cross-field rules accumulate instead of stopping at the first", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.database.supabaseJwtSecret = undefined;
+  cfg.features.ai = { enabled: true };
+  cfg.features.sso = { enabled: true };
+
+  const paths = validateDeploymentConfig(cfg).map((i) => i.path);
+  assert.ok(paths.includes("database.supabaseJwtSecret"));
+  assert.ok(paths.includes("features.ai.openaiApiKey"));
+  assert.ok(paths.includes("features.sso.provider"));
+  assert.ok(paths.includes("features.sso.clientSecret"));
+});
+
+test("errors sort ahead of warnings", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.features.logging.sink = "pending";
+  cfg.features.ai = { enabled: true };
+
+  const severities = validateDeploymentConfig(cfg).map((i) => i.severity);
+  assert.deepEqual(severities, ["error", "warning"]);
+});
+
+test("additional logging sinks need credentials and distinct names", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.features.logging.sinks = [
+    { sink: "datadog", name: "alerts", bucket: "dd-key" },
+    { sink: "splunk", name: "alerts" },
+    { sink: "axiom", name: "console", bucket: "token" },
+  ];
+  const paths = validateDeploymentConfig(cfg)
+    .filter((i) => i.severity === "error")
+    .map((i) => i.path);
+  assert.deepEqual(paths, [
+    "features.logging.sinks.1.bucket",
+    "features.logging.sinks.1.name",
+    "features.logging.sinks.2.name",
+  ]);
+});
+
+test("an additional sink can't take the primary sink's id", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.features.logging.sink = "datadog";
+  cfg.features.logging.bucket = "dd-key";
+  cfg.features.logging.sinks = [
+    { sink: "splunk", name: "datadog", bucket: "hec-token" },
+  ];
+  const errors = validateDeploymentConfig(cfg).filter(
+    (i) => i.severity === "error",
+  );
+  assert.deepEqual(
+    errors.map((i) => i.path),
+    ["features.logging.sinks.0.name"],
+  );
+});
+
+test("assertValidDeploymentConfig returns warnings and throws on errors", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.features.logging.sink = "pending";
+  const warnings = assertValidDeploymentConfig(cfg);
+  assert.equal(warnings.length, 1);
+  assert.equal(warnings[0].path, "features.logging.sink");
+
+  cfg.database.supabaseDbPassword = undefined;
+  assert.throws(
+    () => assertValidDeploymentConfig(cfg),
+    (err: unknown) =>
+      err instanceof ConfigValidationError &&
+      err.issues.length === 2 &&
+      err.message.includes("database.supabaseDbPassword"),
+  );
+});
+
+test("formatConfigIssues lists each issue on its own bullet line", () => {
+  const text = formatConfigIssues([
+    { path: "a.b", message: "warn me", severity: "warning" },
+    { path: "c", message: "broken", severity: "error" },
+  ]);
+  assert.equal(
+    text,
+    "Configuration has 1 problem:\n  • c: broken\n  • a.b: warn me (warning)",
+  );
+});
+
+test("an ingress class other than traefik is a warning", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.ingress = { className: "nginx" };
+
+  const issues = validateDeploymentConfig(cfg);
+  assert.deepEqual(
+    issues.map((i) => [i.path, i.severity]),
+    [["ingress.className", "warning"]],
+  );
+});
+
+test("database resources must be valid quantities with room for Postgres", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.database.resources = {
+    requests: { cpu: "two", memory: "768Mi" },
+    limits: { memory: "512Mi" },
+  };
+
+  const issues = validateDeploymentConfig(cfg);
+  assert.deepEqual(
+    issues.map((i) => [i.path, i.severity]),
+    [
+      ["database.resources.requests.cpu", "error"],
+      ["database.resources.requests.memory", "error"],
+      ["database.resources.limits.memory", "warning"],
+    ],
+  );
+
+  cfg.database.resources = {
+    requests: { cpu: "500m", memory: "2Gi" },
+    limits: { cpu: 2, memory: "4Gi" },
+  };
+  assert.deepEqual(validateDeploymentConfig(cfg), []);
+});
+
+test("timeouts must be valid durations", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.timeouts = { default: "20m", chart: "soon" };
+
+  const issues = validateDeploymentConfig(cfg);
+  assert.deepEqual(
+    issues.map((i) => [i.path, i.severity]),
+    [["timeouts.chart", "error"]],
+  );
+
+  cfg.timeouts = { chart: "1h30m", certificates: "600" };
+  assert.deepEqual(validateDeploymentConfig(cfg), []);
+});
+
+test("a pinned architecture must exist on the cluster", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.infrastructure.nodeArchitecture = "amd64";
+  cfg.infrastructure.workloadArchitecture = "arm64";
+  assert.deepEqual(
+    validateDeploymentConfig(cfg).map((i) => [i.path, i.severity]),
+    [["infrastructure.workloadArchitecture", "error"]],
+  );
+
+  cfg.infrastructure.nodeArchitecture = "mixed";
+  assert.deepEqual(validateDeploymentConfig(cfg), []);
+});
+
+test("strict validation reports keys the schema drops", () => {
+  const raw: Record<string, any> = configFixture("aws-self-hosted-minimal");
+  raw.domian = "typo.example.com";
+  raw.database.supabaseJwtSecrt = "typo";
+
+  const { config } = parseDeploymentConfig(raw);
+  assert.ok(config);
+  assert.deepEqual(
+    findUnknownConfigKeys(raw, config).map((issue) => issue.path),
+    ["domian", "database.supabaseJwtSecrt"],
+  );
+});
+
+test("a clean config has no unknown keys", () => {
+  const raw = configFixture("aws-all-features");
+  const { config } = parseDeploymentConfig(raw);
+  assert.ok(config);
+  assert.deepEqual(findUnknownConfigKeys(raw, config), []);
+});
+
+test("wildcard TLS names need a DNS-01 solver the provider supports", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.tls = { domains: ["rules.example.com", "*.rules.example.com"] };
+  assert.deepEqual(
+    validateDeploymentConfig(config).map((i) => i.path),
+    ["tls.dns01"],
+  );
+
+  config.tls.dns01 = { secretRef: "dns-credentials" };
+  assert.deepEqual(validateDeploymentConfig(config), []);
+
+  config.dns.provider = "azure";
+  assert.deepEqual(
+    validateDeploymentConfig(config).map((i) => i.path),
+    [
+      "tls.dns01.subscriptionId",
+      "tls.dns01.resourceGroup",
+      "tls.dns01.clientId",
+      "tls.dns01.tenantId",
+    ],
+  );
+
+  config.dns.provider = "other";
+  assert.deepEqual(
+    validateDeploymentConfig(config).map((i) => i.path),
+    ["tls.domains"],
+  );
+});
+
+test("placement overrides must target an in-cluster service", () => {
+  const cfg = configFixture("aws-self-hosted-minimal");
+  cfg.scheduling = {
+    kafka: {
+      tolerations: [{ key: "dedicated", operator: "Exists", value: "kafka" }],
+    },
+    database: { nodeSelector: { pool: "db" } },
+  };
+  assert.deepEqual(
+    validateDeploymentConfig(cfg).map((i) => [i.path, i.severity]),
+    [["scheduling.kafka.tolerations.0.value", "error"]],
+  );
+
+  delete cfg.scheduling.kafka!.tolerations![0].value;
+  assert.deepEqual(validateDeploymentConfig(cfg), []);
+
+  const external = configFixture("aws-external-postgres");
+  external.scheduling = { database: { nodeSelector: { pool: "db" } } };
+  assert.deepEqual(
+    validateDeploymentConfig(external)
+      .filter((i) => i.path.startsWith("scheduling"))
+      .map((i) => [i.path, i.severity]),
+    [["scheduling.database", "warning"]],
+  );
+});
+
+test("Kafka topic prefixes must make valid topic names", () => {
+  const cfg = configFixture("aws-external-kafka-msk");
+  const prefixIssues = () =>
+    validateDeploymentConfig(cfg)
+      .filter((i) => i.path.endsWith("topicPrefix"))
+      .map((i) => [i.path, i.severity, i.message]);
+
+  assert.deepEqual(prefixIssues(), []);
+
+  cfg.externalServices!.kafka!.external!.topicPrefix = "acme/rulebricks.";
+  assert.deepEqual(prefixIssues(), [
+    [
+      "externalServices.kafka.external.topicPrefix",
+      "error",
+      "may only contain letters, digits, '.', '_' and '-'",
+    ],
+  ]);
+
+  cfg.externalServices!.kafka!.external!.topicPrefix = "a".repeat(233);
+  assert.match(String(prefixIssues()[0]?.[2]), /at most 232 characters/);
+
+  cfg.externalServices!.kafka!.external!.topicPrefix = "acme.";
+  cfg.externalServices!.kafka!.mode = "embedded";
+  assert.deepEqual(
+    prefixIssues().map(([path, severity]) => [path, severity]),
+    [["externalServices.kafka.external.topicPrefix", "warning"]],
+  );
+});
+
+test("Cloudflare Origin TLS needs a token and covers wildcards itself", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.tls = {
+    provider: "cloudflare-origin",
+    domains: ["*.rules.example.com"],
+  };
+  assert.deepEqual(
+    validateDeploymentConfig(config).map((i) => [i.path, i.severity]),
+    [
+      ["tls.cloudflareOrigin.apiToken", "error"],
+      ["tls.provider", "warning"],
+    ],
+  );
+
+  config.dns.provider = "cloudflare";
+  config.tls.cloudflareOrigin = { apiToken: "cf-token", validityDays: 60 };
+  assert.deepEqual(
+    validateDeploymentConfig(config).map((i) => i.path),
+    ["tls.cloudflareOrigin.validityDays"],
+  );
+
+  config.tls.cloudflareOrigin.validityDays = 365;
+  assert.deepEqual(validateDeploymentConfig(config), []);
+});
@@ -0,0 +1,526 @@
+import { ZodIssue } from "zod";
+import { parseDuration } from "./componentTimeouts.js";
+import { parseCpuMillicores, parseMemoryBytes } from "./quantities.js";
+import {
+  DeploymentConfig,
+  DeploymentConfigSchema,
+  isDns01Provider,
+  ORIGIN_CERT_VALIDITY_DAYS,
+  usesCloudflareOriginTls,
+  wildcardTlsDomains,
+} from "../types/index.js";
+
+/**
+ * Whole-config validation that reports every problem at once.
+ *
+ * The Zod schema catches shape errors, but the cross-field rules (what a
+ * self-hosted database needs, which credentials an enabled feature needs)
+ * used to surface one at a time as deploy-time throws from buildHelmValues.
+ * This collects both kinds into a single list keyed by config field path so
+ * a user can fix config.yaml in one pass.
+ */
+
+export type ConfigIssueSeverity = "error" | "warning";
+
+export interface ConfigIssue {
+  /** Dotted config field path, e.g. "database.supabaseJwtSecret". */
+  path: string;
+  message: string;
+  severity: ConfigIssueSeverity;
+}
+
+const SEVERITY_ORDER: Record<ConfigIssueSeverity, number> = {
+  error: 0,
+  warning: 1,
+};
+
+/**
+ * Thrown when a deployment config has one or more error-severity issues.
+ * The message lists every issue; callers that render richer output can read
+ * `issues` directly.
+ */
+export class ConfigValidationError extends Error {
+  readonly issues: ConfigIssue[];
+
+  constructor(issues: ConfigIssue[]) {
+    super(formatConfigIssues(issues));
+    this.name = "ConfigValidationError";
+    this.issues = issues;
+  }
+}
+
+/** Errors first, then warnings; order within a severity is preserved. */
+export function sortConfigIssues(issues: ConfigIssue[]): ConfigIssue[] {
+  return [...issues].sort(
+    (a, b) => SEVERITY_ORDER[a.severity] - SEVERITY_ORDER[b.severity],
+  );
+}
+
+function fromZodIssue(issue: ZodIssue): ConfigIssue {
+  return {
+    path: issue.path.length > 0 ? issue.path.join(".") : "config",
+    message: issue.message,
+    severity: "error",
+  };
+}
+
+/**
+ * Formats issues as a header line plus one bullet per issue, matching the
+ * "  •" detail-line convention the command error screens render muted.
+ */
+export function formatConfigIssues(issues: ConfigIssue[]): string {
+  const sorted = sortConfigIssues(issues);
+  const errors = sorted.filter((i) => i.severity === "error").length;
+  const header =
+    errors > 0
+      ? `Configuration has ${errors} ${errors === 1 ? "problem" : "problems"}:`
+      : "Configuration warnings:";
+  return [
+    header,
+    ...sorted.map(
+      (i) =>
+        `  • ${i.path}: ${i.message}${i.severity === "warning" ? " (warning)" : ""}`,
+    ),
+  ].join("\n");
+}
+
+/**
+ * Parses a raw config object against the schema, returning every schema issue
+ * instead of throwing on the first.
+ */
+export function parseDeploymentConfig(raw: unknown): {
+  config: DeploymentConfig | null;
+  issues: ConfigIssue[];
+} {
+  const result = DeploymentConfigSchema.safeParse(raw);
+  if (!result.success) {
+    return { config: null, issues: result.error.issues.map(fromZodIssue) };
+  }
+  return { config: result.data, issues: [] };
+}
+
+function isPlainObject(value: unknown): value is Record<string, unknown> {
+  return !!value && typeof value === "object" && !Array.isArray(value);
+}
+
+/**
+ * Keys a raw config sets that the schema dropped while parsing: typos and
+ * removed options that nothing reads. Compares the raw object against the
+ * parsed config rather than the schema definition, so records (annotations,
+ * labels) and defaults are handled exactly as parsing handles them.
+ */
+export function findUnknownConfigKeys(
+  raw: unknown,
+  parsed: unknown,
+  path: string[] = [],
+): ConfigIssue[] {
+  if (Array.isArray(raw) && Array.isArray(parsed)) {
+    return raw.flatMap((item, index) =>
+      findUnknownConfigKeys(item, parsed[index], [...path, String(index)]),
+    );
+  }
+  if (!isPlainObject(raw) || !isPlainObject(parsed)) return [];
+
+  const issues: ConfigIssue[] = [];
+  for (const [key, value] of Object.entries(raw)) {
+    const keyPath = [...path, key];
+    if (!(key in parsed)) {
+      issues.push({
+        path: keyPath.join("."),
+        message: "unknown key; it is ignored",
+        severity: "error",
+      });
+      continue;
+    }
+    issues.push(...findUnknownConfigKeys(value, parsed[key], keyPath));
+  }
+  return issues;
+}
+
+// Below this, the bundled Postgres gets OOMKilled under production query load
+// (shared_buffers plus per-connection work_mem for the API, auth and realtime
+// pools).
+const MIN_POSTGRES_MEMORY_LIMIT = "1Gi";
+
+/** Kafka's topic name limit; "solution-response" is the longest suffix. */
+const MAX_KAFKA_TOPIC_LENGTH = 249;
+const LONGEST_TOPIC_SUFFIX = "solution-response";
+
+/**
+ * Why `prefix` can't start Kafka topic names, or null. Brokers reject names
+ * outside [a-zA-Z0-9._-] or longer than 249 characters, which would only
+ * surface when the topic provisioning Job fails mid-deploy.
+ */
+export function kafkaTopicPrefixError(prefix: string): string | null {
+  if (!/^[a-zA-Z0-9._-]+$/.test(prefix)) {
+    return "may only contain letters, digits, '.', '_' and '-'";
+  }
+  const room = MAX_KAFKA_TOPIC_LENGTH - LONGEST_TOPIC_SUFFIX.length;
+  if (prefix.length > room) {
+    return `must be at most ${room} characters so "${LONGEST_TOPIC_SUFFIX}" still fits Kafka's ${MAX_KAFKA_TOPIC_LENGTH}-character limit`;
+  }
+  return null;
+}
+
+/**
+ * Cross-field rules the schema cannot express. Run on a schema-valid config;
+ * these are kept out of loadDeploymentConfig so `configure` can still open a
+ * config that is incomplete and fix it.
+ */
+export function validateDeploymentConfig(
+  config: DeploymentConfig,
+): ConfigIssue[] {
+  const issues: ConfigIssue[] = [];
+  const error = (path: string, message: string) =>
+    issues.push({ path, message, severity: "error" });
+  const warning = (path: string, message: string) =>
+    issues.push({ path, message, severity: "warning" });
+
+  const { nodeArchitecture, workloadArchitecture } = config.infrastructure;
+  if (
+    workloadArchitecture &&
+    (nodeArchitecture === "amd64" || nodeArchitecture === "arm64") &&
+    workloadArchitecture !== nodeArchitecture
+  ) {
+    error(
+      "infrastructure.workloadArchitecture",
+      `the cluster only has ${nodeArchitecture} nodes; ${workloadArchitecture} pods would never schedule`,
+    );
+  }
+
+  const scheduling = config.scheduling ?? {};
+  if (
+    scheduling.kafka &&
+    config.externalServices?.kafka?.mode === "external"
+  ) {
+    warning(
+      "scheduling.kafka",
+      "Kafka is external, so there is no in-cluster broker to place; ignored",
+    );
+  }
+  if (
+    scheduling.database &&
+    (config.database.type !== "self-hosted" ||
+      config.externalServices?.postgres?.mode === "external")
+  ) {
+    warning(
+      "scheduling.database",
+      "Postgres does not run in the cluster, so this is ignored",
+    );
+  }
+  for (const component of ["kafka", "database"] as const) {
+    (scheduling[component]?.tolerations ?? []).forEach((toleration, i) => {
+      if (toleration.operator === "Exists" && toleration.value) {
+        error(
+          `scheduling.${component}.tolerations.${i}.value`,
+          'must be empty when operator is "Exists"',
+        );
+      }
+    });
+  }
+
+  const db = config.database;
+  if (db.type === "self-hosted") {
+    if (!db.supabaseJwtSecret) {
+      error("database.supabaseJwtSecret", "required for self-hosted Supabase");
+    }
+    if (!db.supabaseDbPassword) {
+      error("database.supabaseDbPassword", "required for self-hosted Supabase");
+    }
+  } else {
+    if (!db.supabaseUrl) {
+      error("database.supabaseUrl", "required for managed Supabase");
+    }
+    if (!db.supabaseAnonKey) {
+      error("database.supabaseAnonKey", "required for managed Supabase");
+    }
+    if (!db.supabaseServiceKey) {
+      error("database.supabaseServiceKey", "required for managed Supabase");
+    }
+    if (!db.supabaseAccessToken) {
+      error("database.supabaseAccessToken", "required for managed Supabase");
+    }
+  }
+
+  if (db.resources) {
+    const external =
+      db.type !== "self-hosted" ||
+      config.externalServices?.postgres?.mode === "external";
+    if (external) {
+      warning(
+        "database.resources",
+        "only applies to the bundled in-cluster Postgres and is ignored here",
+      );
+    }
+
+    const cpu = { requests: 0, limits: 0 };
+    const memory = { requests: 0, limits: 0 };
+    for (const kind of ["requests", "limits"] as const) {
+      const quantities = db.resources[kind];
+      if (quantities?.cpu !== undefined) {
+        const parsed = parseCpuMillicores(quantities.cpu);
+        if (parsed === undefined) {
+          error(
+            `database.resources.${kind}.cpu`,
+            `"${quantities.cpu}" is not a CPU quantity (e.g. "500m" or "2")`,
+          );
+        } else {
+          cpu[kind] = parsed;
+        }
+      }
+      if (quantities?.memory !== undefined) {
+        const parsed = parseMemoryBytes(quantities.memory);
+        if (parsed === undefined) {
+          error(
+            `database.resources.${kind}.memory`,
+            `"${quantities.memory}" is not a memory quantity (e.g. "4Gi")`,
+          );
+        } else {
+          memory[kind] = parsed;
+        }
+      }
+    }
+
+    if (cpu.requests && cpu.limits && cpu.requests > cpu.limits) {
+      error("database.resources.requests.cpu", "must not exceed the CPU limit");
+    }
+    if (memory.requests && memory.limits && memory.requests > memory.limits) {
+      error(
+        "database.resources.requests.memory",
+        "must not exceed the memory limit",
+      );
+    }
+    if (
+      !external &&
+      memory.limits &&
+      memory.limits < parseMemoryBytes(MIN_POSTGRES_MEMORY_LIMIT)!
+    ) {
+      warning(
+        "database.resources.limits.memory",
+        `below ${MIN_POSTGRES_MEMORY_LIMIT}; Postgres is likely to be OOMKilled under production load`,
+      );
+    }
+  }
+
+  const { ai, sso, logging } = config.features;
+  if (ai.enabled && !ai.openaiApiKey) {
+    error("features.ai.openaiApiKey", "required when AI features are enabled");
+  }
+  if (sso.enabled) {
+    if (!sso.provider) {
+      error("features.sso.provider", "required when SSO is enabled");
+    }
+    if (!sso.clientId) {
+      error("features.sso.clientId", "required when SSO is enabled");
+    }
+    if (!sso.clientSecret) {
+      error("features.sso.clientSecret", "required when SSO is enabled");
+    }
+  }
+
+  if (logging.sink === "pending") {
+    warning(
+      "features.logging.sink",
+      "external logging is enabled but no destination is selected; only console logging will run",
+    );
+  } else if (logging.sink !== "console" && !logging.bucket) {
+    error(
+      "features.logging.bucket",
+      `credential/endpoint required for the ${logging.sink} logging sink`,
+    );
+  }
+  // The primary platform sink's Vector id is its platform name.
+  const primarySinkName =
+    logging.sink !== "console" && logging.sink !== "pending"
+      ? logging.sink
+      : undefined;
+  const sinkNames = new Set<string>();
+  (logging.sinks ?? []).forEach((entry, i) => {
+    if (!entry.bucket) {
+      error(
+        `features.logging.sinks.${i}.bucket`,
+        `credential/endpoint required for the ${entry.sink} logging sink`,
+      );
+    }
+    if (!entry.name) return;
+    if (entry.name === "console" || entry.name === "decision_logs") {
+      error(
+        `features.logging.sinks.${i}.name`,
+        `"${entry.name}" is reserved for a built-in Vector sink`,
+      );
+    } else if (entry.name === primarySinkName) {
+      error(
+        `features.logging.sinks.${i}.name`,
+        `"${entry.name}" is the id of the features.logging.sink sink`,
+      );
+    } else if (sinkNames.has(entry.name)) {
+      error(
+        `features.logging.sinks.${i}.name`,
+        `duplicate sink name "${entry.name}"`,
+      );
+    }
+    sinkNames.add(entry.name);
+  });
+
+  const ext = config.externalServices;
+  if (ext?.redis?.mode === "external" && !ext.redis.external?.host) {
+    error(
+      "externalServices.redis.external.host",
+      "required when Redis mode is external",
+    );
+  }
+  if (ext?.kafka?.mode === "external" && !ext.kafka.external?.brokers) {
+    error(
+      "externalServices.kafka.external.brokers",
+      "required when Kafka mode is external",
+    );
+  }
+  const topicPrefix = ext?.kafka?.external?.topicPrefix;
+  if (topicPrefix) {
+    const prefixError = kafkaTopicPrefixError(topicPrefix);
+    if (prefixError) {
+      error("externalServices.kafka.external.topicPrefix", prefixError);
+    } else if (ext?.kafka?.mode !== "external") {
+      warning(
+        "externalServices.kafka.external.topicPrefix",
+        "in-cluster Kafka topics are never prefixed; ignored",
+      );
+    }
+  }
+  if (ext?.postgres?.mode === "external") {
+    if (db.type !== "self-hosted") {
+      error(
+        "externalServices.postgres.mode",
+        "external Postgres is only supported with self-hosted Supabase",
+      );
+    }
+    if (!ext.postgres.external?.host) {
+      error(
+        "externalServices.postgres.external.host",
+        "required when Postgres mode is external",
+      );
+    }
+  }
+
+  const usesInClusterPostgres =
+    db.type === "self-hosted" && ext?.postgres?.mode !== "external";
+  if (config.backup?.enabled && !usesInClusterPostgres) {
+    warning(
+      "backup.enabled",
+      "backups only run for the in-cluster database and will be skipped",
+    );
+  }
+  if (config.backup?.enabled && !config.storage) {
+    error("storage", "object storage is required when backups are enabled");
+  }
+
+  const provider = config.infrastructure.provider;
+  const storage = config.storage;
+  if (provider && storage && storage.cloudAuthMode !== "secret") {
+    const expected = { aws: "s3", gcp: "gcs", azure: "azure-blob" }[provider];
+    if (storage.provider !== expected) {
+      warning(
+        "storage.provider",
+        `${storage.provider} storage on a ${provider} cluster cannot use workload identity; set storage.cloudAuthMode to "secret"`,
+      );
+    }
+  }
+
+  // One ingress class drives the app, supabase and ClickStack ingresses.
+  const className = config.ingress?.className;
+  if (className && className !== "traefik") {
+    warning(
+      "ingress.className",
+      `the bundled Traefik only serves the "traefik" class; an existing controller must serve "${className}" for the app and supabase ingresses`,
+    );
+  }
+
+  const wildcards = wildcardTlsDomains(config);
+  if (usesCloudflareOriginTls(config)) {
+    const origin = config.tls?.cloudflareOrigin;
+    if (!origin) {
+      error(
+        "tls.cloudflareOrigin.apiToken",
+        'required when tls.provider is "cloudflare-origin"',
+      );
+    } else if (
+      origin.validityDays !== undefined &&
+      !ORIGIN_CERT_VALIDITY_DAYS.includes(origin.validityDays)
+    ) {
+      error(
+        "tls.cloudflareOrigin.validityDays",
+        `must be one of ${ORIGIN_CERT_VALIDITY_DAYS.join(", ")}`,
+      );
+    }
+    if (config.dns.provider !== "cloudflare") {
+      warning(
+        "tls.provider",
+        `Origin CA certificates are only trusted by Cloudflare's proxy; dns.provider is "${config.dns.provider}", so the hosts must still be proxied through Cloudflare`,
+      );
+    }
+    if (config.tls?.dns01) {
+      warning(
+        "tls.dns01",
+        "unused with Cloudflare Origin certificates, which cover wildcards without a DNS-01 challenge",
+      );
+    }
+  } else if (wildcards.length > 0) {
+    const dns01 = config.tls?.dns01;
+    if (!isDns01Provider(config.dns.provider)) {
+      error(
+        "tls.domains",
+        `wildcard names need a DNS-01 solver, which dns.provider "${config.dns.provider}" does not support (use route53, google, azure or cloudflare)`,
+      );
+    } else if (!dns01) {
+      error(
+        "tls.dns01",
+        `required for wildcard names (${wildcards.join(", ")}); HTTP-01 cannot issue them`,
+      );
+    } else if (
+      config.dns.provider === "google" &&
+      !dns01.project &&
+      !config.infrastructure.gcpProjectId
+    ) {
+      error(
+        "tls.dns01.project",
+        "required for the google DNS-01 solver when infrastructure.gcpProjectId is unset",
+      );
+    } else if (config.dns.provider === "azure") {
+      for (const key of [
+        "subscriptionId",
+        "resourceGroup",
+        "clientId",
+        "tenantId",
+      ] as const) {
+        if (!dns01[key]) {
+          error(`tls.dns01.${key}`, "required for the azure DNS-01 solver");
+        }
+      }
+    }
+  }
+
+  for (const [key, value] of Object.entries(config.timeouts ?? {})) {
+    if (value === undefined) continue;
+    try {
+      parseDuration(value);
+    } catch (err) {
+      error(`timeouts.${key}`, (err as Error).message);
+    }
+  }
+
+  return sortConfigIssues(issues);
+}
+
+/**
+ * Throws a ConfigValidationError listing every error-severity issue (with any
+ * warnings appended). Returns the warnings so callers can surface them.
+ */
+export function assertValidDeploymentConfig(
+  config: DeploymentConfig,
+): ConfigIssue[] {
+  const issues = validateDeploymentConfig(config);
+  if (issues.some((i) => i.severity === "error")) {
+    throw new ConfigValidationError(issues);
+  }
+  return issues;
+}
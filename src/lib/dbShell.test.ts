@@ -0,0 +1,63 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import { psqlInvocation } from "./dbShell.js";
+import { configFixture } from "./configFixtures.js";
+
+test("bundled Postgres is reached through kubectl exec", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const release = `rulebricks-${config.name}`;
+
+  const shell = psqlInvocation(config, { tty: true });
+  assert.equal(shell.command, "kubectl");
+  assert.deepEqual(shell.args.slice(0, 7), [
+    "exec",
+    "-it",
+    "-n",
+    release,
+    `svc/${release}-supabase-db`,
+    "--",
+    "sh",
+  ]);
+  assert.equal(shell.env, undefined);
+
+  // The statement goes in on stdin, not spliced into the script.
+  const sql = "select count(*) from \"users\" where name = 'a b'";
+  const once = psqlInvocation(config, { sql, tty: false });
+  assert.equal(once.args[1], "-i");
+  assert.match(once.args.at(-1)!, /-v ON_ERROR_STOP=1 -f -$/);
+  assert.equal(once.input, sql);
+});
+
+test("external Postgres connects with the local psql over TLS", () => {
+  const config = configFixture("aws-external-postgres");
+  const shell = psqlInvocation(config, { sql: "select 1", tty: false });
+  assert.equal(shell.command, "psql");
+  assert.deepEqual(shell.args, [
+    "-X",
+    "-v",
+    "ON_ERROR_STOP=1",
+    "-c",
+    "select 1",
+  ]);
+  assert.equal(shell.env?.PGSSLMODE, "require");
+  assert.equal(shell.env?.PGPASSWORD, "master-pw-change-me");
+
+  config.externalServices!.postgres!.external!.sslMode = "verify-full";
+  assert.equal(
+    psqlInvocation(config, { tty: true }).env?.PGSSLMODE,
+    "verify-full",
+  );
+
+  config.externalServices!.postgres!.external!.bootstrap!.secretRef = "db";
+  assert.throws(
+    () => psqlInvocation(config, { tty: true }),
+    /pre-created secret/,
+  );
+});
+
+test("Supabase Cloud deployments are pointed at the project settings", () => {
+  assert.throws(
+    () => psqlInvocation(configFixture("aws-supabase-cloud"), { tty: true }),
+    /Supabase Cloud/,
+  );
+});
@@ -0,0 +1,114 @@
+import { execa } from "execa";
+import {
+  externalPostgresCredentials,
+  externalPostgresSslMode,
+} from "./externalPostgres.js";
+import { SUPABASE_DB_PSQL, supabaseDbService } from "./kubernetes.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+} from "../types/index.js";
+
+/**
+ * `rulebricks db psql`: a psql session against a deployment's database. The
+ * bundled Postgres is reached through `kubectl exec` into the db pod, as
+ * supabase_admin with the container's own POSTGRES_PASSWORD (the credentials
+ * `backup` and `fix-realtime` use), so nothing secret leaves the cluster. An
+ * external database is connected to directly with the local psql.
+ */
+
+export interface PsqlInvocation {
+  command: "kubectl" | "psql";
+  args: string[];
+  env?: Record<string, string>;
+  /** Fed to stdin instead of the terminal (the --sql statement). */
+  input?: string;
+  /** Where the session connects, for the banner. */
+  target: string;
+}
+
+export function psqlInvocation(
+  config: DeploymentConfig,
+  options: { sql?: string; tty: boolean },
+): PsqlInvocation {
+  if (config.database.type === "supabase-cloud") {
+    throw new Error(
+      "This deployment uses Supabase Cloud; connect with the connection string from the project's Database settings.",
+    );
+  }
+  const sqlArgs = options.sql
+    ? ["-v", "ON_ERROR_STOP=1", "-c", options.sql]
+    : [];
+
+  if (config.externalServices?.postgres?.mode === "external") {
+    const credentials = externalPostgresCredentials(config);
+    if (!credentials) {
+      throw new Error(
+        "The external database's password lives in a pre-created secret the CLI can't read; connect with psql directly.",
+      );
+    }
+    return {
+      command: "psql",
+      args: ["-X", ...sqlArgs],
+      env: {
+        PGHOST: credentials.host,
+        PGPORT: String(credentials.port),
+        PGDATABASE: credentials.database,
+        PGUSER: credentials.user,
+        PGPASSWORD: credentials.password,
+        // Same as the deploy preflight.
+        PGSSLMODE: externalPostgresSslMode(config),
+        PGCONNECT_TIMEOUT: "10",
+      },
+      target: `${credentials.user}@${credentials.host}:${credentials.port}/${credentials.database}`,
+    };
+  }
+
+  const service = supabaseDbService(getReleaseName(config.name));
+  // A statement goes in on stdin, so the shell never re-parses it.
+  const script = options.sql
+    ? `${SUPABASE_DB_PSQL} -v ON_ERROR_STOP=1 -f -`
+    : SUPABASE_DB_PSQL;
+  return {
+    command: "kubectl",
+    args: [
+      "exec",
+      options.tty ? "-it" : "-i",
+      "-n",
+      getNamespace(config.name),
+      service,
+      "--",
+      "sh",
+      "-c",
+      script,
+    ],
+    ...(options.sql ? { input: options.sql } : {}),
+    target: `supabase_admin@${service}/postgres`,
+  };
+}
+
+/**
+ * Runs the session with the terminal attached and resolves to psql's exit
+ * code.
+ */
+export async function runPsql(invocation: PsqlInvocation): Promise<number> {
+  try {
+    await execa(invocation.command, invocation.args, {
+      ...(invocation.input !== undefined
+        ? { input: invocation.input, stdout: "inherit", stderr: "inherit" }
+        : { stdio: "inherit" }),
+      env: invocation.env,
+    });
+    return 0;
+  } catch (error) {
+    const err = error as { code?: string; exitCode?: number };
+    if (err.code === "ENOENT") {
+      throw new Error(`${invocation.command} is not installed or not on PATH.`);
+    }
+    if (typeof err.exitCode === "number") {
+      return err.exitCode;
+    }
+    throw error;
+  }
+}
@@ -0,0 +1,100 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  countManifestKinds,
+  extractRenderedManifests,
+  planDryRunActions,
+} from "./deployDryRun.js";
+import { buildConfigMatrix } from "./configFixtures.js";
+
+const HELM_OUTPUT = `Release "rulebricks-prod" does not exist. Installing it now.
+NAME: rulebricks-prod
+STATUS: pending-install
+REVISION: 1
+HOOKS:
+---
+# Source: stack/templates/migrate-job.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+MANIFEST:
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: hps
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+
+NOTES:
+Rulebricks is installing.
+`;
+
+test("extracts hooks and manifests without the release header or notes", () => {
+  const manifests = extractRenderedManifests(HELM_OUTPUT);
+  assert.ok(manifests.startsWith("---"));
+  assert.ok(!manifests.includes("NAME: rulebricks-prod"));
+  assert.ok(!manifests.includes("MANIFEST:"));
+  assert.ok(!manifests.includes("Rulebricks is installing"));
+});
+
+test("counts rendered resources by kind", () => {
+  assert.deepEqual(
+    countManifestKinds(extractRenderedManifests(HELM_OUTPUT)),
+    [
+      { kind: "Service", count: 2 },
+      { kind: "Deployment", count: 1 },
+      { kind: "Job", count: 1 },
+    ],
+  );
+});
+
+test("plans a first install into a new namespace", () => {
+  const config = buildConfigMatrix()[0].config;
+  const actions = planDryRunActions({
+    config,
+    namespace: "rulebricks-prod",
+    releaseName: "rulebricks-prod",
+    namespaceExists: false,
+    installedChartVersion: null,
+    secretMode: "k8s",
+    externalDns: false,
+  });
+  const steps = actions.map((a) => a.step);
+  assert.ok(steps.includes("Namespace"));
+  assert.ok(steps.includes("Secrets"));
+  assert.equal(
+    actions.find((a) => a.step === "Helm")?.action,
+    "Install release rulebricks-prod (chart latest)",
+  );
+});
+
+test("plans an upgrade without namespace or secret steps in inline mode", () => {
+  const config = buildConfigMatrix()[0].config;
+  const actions = planDryRunActions({
+    config,
+    namespace: "rulebricks-prod",
+    releaseName: "rulebricks-prod",
+    namespaceExists: true,
+    installedChartVersion: "2.1.0",
+    chartVersion: "2.2.0",
+    secretMode: "inline",
+    externalDns: true,
+  });
+  const steps = actions.map((a) => a.step);
+  assert.ok(!steps.includes("Namespace"));
+  assert.ok(!steps.includes("Secrets"));
+  assert.equal(
+    actions.find((a) => a.step === "Helm")?.action,
+    "Upgrade release rulebricks-prod from chart 2.1.0 to 2.2.0",
+  );
+});
@@ -0,0 +1,107 @@
+import yaml from "yaml";
+import type { SecretMode } from "./deploySequence.js";
+import type { DeploymentConfig } from "../types/index.js";
+
+/**
+ * `deploy --dry-run`: what a deploy would do, worked out without changing
+ * the cluster, the cloud account, or the deployment's values.yaml. Values are
+ * generated in memory and rendered with `helm upgrade --install --dry-run`;
+ * the steps around the Helm install are described rather than run.
+ */
+
+export interface DryRunAction {
+  step: string;
+  action: string;
+}
+
+export interface ManifestKindCount {
+  kind: string;
+  count: number;
+}
+
+/**
+ * Pulls the rendered manifests (hooks included) out of helm's dry-run
+ * output, dropping the release header and NOTES.
+ */
+export function extractRenderedManifests(output: string): string {
+  const start = output.search(/^(HOOKS|MANIFEST):$/m);
+  if (start === -1) return "";
+  const notes = output.search(/^NOTES:$/m);
+  const body = output.slice(start, notes > start ? notes : undefined);
+  return body
+    .split("\n")
+    .filter((line) => !/^(HOOKS|MANIFEST):$/.test(line))
+    .join("\n")
+    .trim();
+}
+
+/** Resource counts by kind, most common first. */
+export function countManifestKinds(manifests: string): ManifestKindCount[] {
+  const counts = new Map<string, number>();
+  for (const doc of yaml.parseAllDocuments(manifests)) {
+    const kind = (doc.toJS() as { kind?: unknown } | null)?.kind;
+    if (typeof kind !== "string") continue;
+    counts.set(kind, (counts.get(kind) ?? 0) + 1);
+  }
+  return [...counts.entries()]
+    .map(([kind, count]) => ({ kind, count }))
+    .sort((a, b) => b.count - a.count || a.kind.localeCompare(b.kind));
+}
+
+/** The deploy steps, in order, as the dry run would have them run. */
+export function planDryRunActions(input: {
+  config: DeploymentConfig;
+  namespace: string;
+  releaseName: string;
+  namespaceExists: boolean;
+  installedChartVersion: string | null;
+  chartVersion?: string;
+  secretMode: SecretMode;
+  externalDns: boolean;
+}): DryRunAction[] {
+  const { config } = input;
+  const actions: DryRunAction[] = [];
+
+  if (config.infrastructure.provider && config.infrastructure.clusterName) {
+    actions.push({
+      step: "Workload identity",
+      action: `Ensure ${config.infrastructure.provider} workload identity trust for ${input.namespace}`,
+    });
+  }
+
+  if (!input.namespaceExists) {
+    actions.push({
+      step: "Namespace",
+      action: `Create namespace ${input.namespace}`,
+    });
+  }
+
+  if (input.secretMode === "k8s") {
+    actions.push({
+      step: "Secrets",
+      action: `Create or update the deployment Secrets in ${input.namespace}`,
+    });
+  } else if (input.secretMode === "eso") {
+    actions.push({
+      step: "Secrets",
+      action: `Seed missing ${config.secrets?.backend} entries and sync them with External Secrets`,
+    });
+  }
+
+  const target = input.chartVersion ?? "latest";
+  actions.push({
+    step: "Helm",
+    action: input.installedChartVersion
+      ? `Upgrade release ${input.releaseName} from chart ${input.installedChartVersion} to ${target}`
+      : `Install release ${input.releaseName} (chart ${target})`,
+  });
+
+  actions.push({
+    step: "DNS and TLS",
+    action: input.externalDns
+      ? `external-dns publishes ${config.domain}; wait for certificates`
+      : `Wait for DNS records for ${config.domain}, then enable TLS and wait for certificates`,
+  });
+
+  return actions;
+}
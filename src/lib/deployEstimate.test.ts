@@ -0,0 +1,95 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  DEFAULT_STEP_ESTIMATES,
+  formatEstimate,
+  isOverrun,
+  recordableDurations,
+  remainingEstimateMs,
+  stepEstimates,
+  summarizeEstimates,
+} from "./deployEstimate.js";
+
+const LABELS = {
+  preflight: "Preflight",
+  kubeconfig: "Kubeconfig",
+  federation: "Identity",
+  helmInstall: "Install",
+  dnsConfig: "DNS",
+  helmUpgradeTls: "TLS",
+  certCheck: "Certificates",
+};
+
+test("recorded durations replace the defaults, except for DNS", () => {
+  const estimates = stepEstimates({
+    helmInstall: 240000,
+    dnsConfig: 900000,
+    unknown: 5,
+  });
+  assert.equal(estimates.helmInstall, 240000);
+  assert.equal(estimates.dnsConfig, 0);
+  assert.equal(estimates.preflight, DEFAULT_STEP_ESTIMATES.preflight);
+  assert.deepEqual(stepEstimates(), DEFAULT_STEP_ESTIMATES);
+});
+
+test("only cluster-bound steps are recorded for the next deploy", () => {
+  assert.deepEqual(
+    recordableDurations({ preflight: 1200.4, dnsConfig: 60000, deploy: 9 }),
+    { preflight: 1200 },
+  );
+});
+
+test("remaining counts pending steps and what's left of running ones", () => {
+  const estimates = stepEstimates({
+    preflight: 10000,
+    kubeconfig: 10000,
+    federation: 10000,
+    helmInstall: 100000,
+    helmUpgradeTls: 20000,
+    certCheck: 30000,
+  });
+  assert.equal(remainingEstimateMs(estimates, {}), 180000);
+  assert.equal(
+    remainingEstimateMs(
+      estimates,
+      {
+        preflight: "success",
+        kubeconfig: "success",
+        federation: "skipped",
+        helmInstall: "running",
+      },
+      { helmInstall: 40000 },
+    ),
+    110000,
+  );
+  assert.equal(
+    remainingEstimateMs(
+      estimates,
+      {
+        helmInstall: "running",
+        helmUpgradeTls: "skipped",
+        certCheck: "success",
+      },
+      { helmInstall: 500000 },
+    ),
+    30000,
+  );
+});
+
+test("a step is overrun at twice its estimate and a minute over", () => {
+  assert.equal(isOverrun("helmInstall", 900000, 1700000), false);
+  assert.ok(isOverrun("helmInstall", 900000, 1900000));
+  assert.equal(isOverrun("preflight", 10000, 50000), false);
+  assert.ok(isOverrun("preflight", 10000, 80000));
+  assert.equal(isOverrun("dnsConfig", 0, 3600000), false);
+});
+
+test("estimates read as rough durations", () => {
+  assert.equal(formatEstimate(0), "~1s");
+  assert.equal(formatEstimate(45000), "~45s");
+  assert.equal(formatEstimate(90000), "~2m");
+  assert.equal(
+    summarizeEstimates(DEFAULT_STEP_ESTIMATES, LABELS),
+    "estimated ~24m: Preflight ~30s, Kubeconfig ~15s, Identity ~1m, Install ~15m, TLS ~5m, Certificates ~2m",
+  );
+});
@@ -0,0 +1,128 @@
+import type { StepState } from "./progress.js";
+
+/**
+ * Deploy time estimates. Each step starts from a default sized for a first
+ * deploy; once a deploy succeeds, its measured step durations (kept in
+ * state.yaml) replace the defaults, so redeploys estimate from this cluster.
+ * DNS configuration waits on the user, so it is never estimated.
+ */
+
+/** Deploy steps in the order the deploy screen lists them. */
+export const DEPLOY_STEP_ORDER = [
+  "preflight",
+  "kubeconfig",
+  "federation",
+  "helmInstall",
+  "dnsConfig",
+  "helmUpgradeTls",
+  "certCheck",
+] as const;
+
+export type DeployStepKey = (typeof DEPLOY_STEP_ORDER)[number];
+
+export type StepEstimates = Record<DeployStepKey, number>;
+
+const MINUTE_MS = 60000;
+
+export const DEFAULT_STEP_ESTIMATES: StepEstimates = {
+  preflight: 30000,
+  kubeconfig: 15000,
+  federation: MINUTE_MS,
+  helmInstall: 15 * MINUTE_MS,
+  dnsConfig: 0,
+  helmUpgradeTls: 5 * MINUTE_MS,
+  certCheck: 2 * MINUTE_MS,
+};
+
+/** Steps whose duration depends on the user rather than the cluster. */
+const INTERACTIVE_STEPS = new Set<DeployStepKey>(["dnsConfig"]);
+
+// A step is flagged once it has run this many times its estimate, and by at
+// least OVERRUN_MIN_MS, so short steps don't trip on a slow API call.
+const OVERRUN_FACTOR = 2;
+const OVERRUN_MIN_MS = MINUTE_MS;
+
+/** The defaults, with any durations recorded by the last successful deploy. */
+export function stepEstimates(
+  recorded?: Partial<Record<string, number>>,
+): StepEstimates {
+  const estimates = { ...DEFAULT_STEP_ESTIMATES };
+  for (const step of DEPLOY_STEP_ORDER) {
+    const ms = recorded?.[step];
+    if (!INTERACTIVE_STEPS.has(step) && typeof ms === "number" && ms >= 0) {
+      estimates[step] = ms;
+    }
+  }
+  return estimates;
+}
+
+/** The measured durations worth keeping for the next deploy's estimates. */
+export function recordableDurations(
+  measured: Partial<Record<string, number>>,
+): Partial<Record<DeployStepKey, number>> {
+  const durations: Partial<Record<DeployStepKey, number>> = {};
+  for (const step of DEPLOY_STEP_ORDER) {
+    const ms = measured[step];
+    if (!INTERACTIVE_STEPS.has(step) && typeof ms === "number") {
+      durations[step] = Math.round(ms);
+    }
+  }
+  return durations;
+}
+
+/**
+ * Estimated time left: every pending step in full, plus what remains of the
+ * running ones (never below zero). Finished and skipped steps count nothing.
+ */
+export function remainingEstimateMs(
+  estimates: StepEstimates,
+  statuses: Partial<Record<DeployStepKey, StepState>>,
+  runningElapsedMs: Partial<Record<string, number>> = {},
+): number {
+  let remaining = 0;
+  for (const step of DEPLOY_STEP_ORDER) {
+    const status = statuses[step] ?? "pending";
+    if (status === "pending") {
+      remaining += estimates[step];
+    } else if (status === "running") {
+      remaining += Math.max(
+        0,
+        estimates[step] - (runningElapsedMs[step] ?? 0),
+      );
+    }
+  }
+  return remaining;
+}
+
+/** Whether a step has run far longer than estimated. */
+export function isOverrun(
+  step: DeployStepKey,
+  estimateMs: number,
+  elapsedMs: number,
+): boolean {
+  if (INTERACTIVE_STEPS.has(step)) return false;
+  return (
+    elapsedMs > estimateMs * OVERRUN_FACTOR &&
+    elapsedMs - estimateMs >= OVERRUN_MIN_MS
+  );
+}
+
+/** A rough duration: "~45s" under a minute, otherwise whole minutes. */
+export function formatEstimate(ms: number): string {
+  if (ms < MINUTE_MS) return `~${Math.max(1, Math.round(ms / 1000))}s`;
+  return `~${Math.round(ms / MINUTE_MS)}m`;
+}
+
+/** One line listing each estimated step and the total, for plain output. */
+export function summarizeEstimates(
+  estimates: StepEstimates,
+  labels: Record<DeployStepKey, string>,
+): string {
+  const steps = DEPLOY_STEP_ORDER.filter(
+    (step) => !INTERACTIVE_STEPS.has(step),
+  );
+  const total = steps.reduce((sum, step) => sum + estimates[step], 0);
+  return `estimated ${formatEstimate(total)}: ${steps
+    .map((step) => `${labels[step]} ${formatEstimate(estimates[step])}`)
+    .join(", ")}`;
+}
@@ -0,0 +1,92 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { abortEvents, buildDeployResult } from "./deployResult.js";
+import type { ProgressEvent } from "./progress.js";
+
+function event(
+  step: string,
+  status: ProgressEvent["status"],
+  second: number,
+): ProgressEvent {
+  return {
+    step,
+    status,
+    timestamp: new Date(Date.UTC(2026, 0, 1, 0, 0, second)).toISOString(),
+  };
+}
+
+test("a successful deploy reports each step with its duration", () => {
+  const result = buildDeployResult(
+    "prod",
+    [
+      event("preflight", "started", 0),
+      event("preflight", "completed", 2),
+      event("federation", "skipped", 2),
+      event("helmInstall", "started", 3),
+      event("helmInstall", "completed", 40),
+      event("deploy", "completed", 41),
+    ],
+    { url: "https://rules.example.com", warnings: ["autoscaler disabled"] },
+  );
+
+  assert.equal(result.success, true);
+  assert.equal(result.url, "https://rules.example.com");
+  assert.equal(result.durationMs, 41_000);
+  assert.deepEqual(result.warnings, ["autoscaler disabled"]);
+  assert.deepEqual(
+    result.steps.map((s) => [s.step, s.status, s.durationMs]),
+    [
+      ["preflight", "completed", 2_000],
+      ["federation", "skipped", undefined],
+      ["helmInstall", "completed", 37_000],
+    ],
+  );
+  assert.equal(result.failedStep, undefined);
+  assert.equal(result.error, undefined);
+});
+
+test("a failed deploy names the failing step and error", () => {
+  const result = buildDeployResult(
+    "prod",
+    [
+      event("preflight", "started", 0),
+      event("preflight", "completed", 1),
+      event("helmInstall", "started", 1),
+      event("helmInstall", "failed", 9),
+      event("deploy", "failed", 9),
+    ],
+    { error: "Helm install/upgrade failed", warnings: [] },
+  );
+
+  assert.equal(result.success, false);
+  assert.equal(result.failedStep, "helmInstall");
+  assert.equal(result.error, "Helm install/upgrade failed");
+});
+
+test("an aborted deploy fails the steps still running, then the deploy", () => {
+  const events = [
+    event("preflight", "started", 0),
+    event("preflight", "completed", 2),
+    event("helmInstall", "started", 3),
+  ];
+  const aborted = abortEvents(
+    events,
+    new Date(Date.UTC(2026, 0, 1, 0, 0, 30)),
+    "Deploy timed out",
+  );
+  assert.deepEqual(
+    aborted.map((e) => [e.step, e.status]),
+    [
+      ["helmInstall", "failed"],
+      ["deploy", "failed"],
+    ],
+  );
+
+  const result = buildDeployResult("prod", [...events, ...aborted], {
+    error: "Deploy timed out",
+    warnings: [],
+  });
+  assert.equal(result.success, false);
+  assert.equal(result.failedStep, "helmInstall");
+  assert.equal(result.durationMs, 30000);
+});
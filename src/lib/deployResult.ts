@@ -0,0 +1,116 @@
+import type { ProgressEvent } from "./progress.js";
+
+/**
+ * The single JSON object `deploy --output json` prints when the deploy ends,
+ * assembled from the same progress events `--progress json` streams. CI can
+ * parse one document instead of following the stream.
+ */
+
+export type OutputFormat = "text" | "json";
+export const OUTPUT_FORMATS: OutputFormat[] = ["text", "json"];
+
+export function isOutputFormat(value: string): value is OutputFormat {
+  return (OUTPUT_FORMATS as string[]).includes(value);
+}
+
+export interface DeployStepResult {
+  step: string;
+  status: "completed" | "failed" | "skipped" | "running";
+  startedAt?: string;
+  finishedAt?: string;
+  durationMs?: number;
+}
+
+export interface DeployResult {
+  deployment: string;
+  success: boolean;
+  url?: string;
+  steps: DeployStepResult[];
+  failedStep?: string;
+  error?: string;
+  warnings: string[];
+  durationMs?: number;
+}
+
+/** What the deploy screen knows at the end that the events don't carry. */
+export interface DeploySummary {
+  url?: string;
+  error?: string;
+  warnings: string[];
+}
+
+/** The overall operation's events; everything else is a step. */
+const OPERATION_STEP = "deploy";
+
+export function buildDeployResult(
+  deployment: string,
+  events: ProgressEvent[],
+  summary: DeploySummary,
+): DeployResult {
+  const steps = new Map<string, DeployStepResult>();
+  let success = false;
+  let failedStep: string | undefined;
+
+  for (const event of events) {
+    if (event.step === OPERATION_STEP) {
+      success = event.status === "completed";
+      continue;
+    }
+    const current = steps.get(event.step) ?? {
+      step: event.step,
+      status: "running",
+    };
+    if (event.status === "started") {
+      current.status = "running";
+      current.startedAt = event.timestamp;
+    } else {
+      current.status = event.status;
+      current.finishedAt = event.timestamp;
+      if (current.startedAt) {
+        current.durationMs =
+          Date.parse(event.timestamp) - Date.parse(current.startedAt);
+      }
+      if (event.status === "failed") failedStep = event.step;
+    }
+    steps.set(event.step, current);
+  }
+
+  const first = events[0]?.timestamp;
+  const last = events[events.length - 1]?.timestamp;
+  return {
+    deployment,
+    success,
+    ...(summary.url ? { url: summary.url } : {}),
+    steps: [...steps.values()],
+    ...(failedStep ? { failedStep } : {}),
+    ...(!success && summary.error ? { error: summary.error } : {}),
+    warnings: summary.warnings,
+    ...(first && last
+      ? { durationMs: Date.parse(last) - Date.parse(first) }
+      : {}),
+  };
+}
+
+/**
+ * The events that close out a deploy cut short (`deploy --timeout`): each
+ * step still running fails, then the deploy itself.
+ */
+export function abortEvents(
+  events: ProgressEvent[],
+  at: Date,
+  detail: string,
+): ProgressEvent[] {
+  const running = new Set<string>();
+  for (const event of events) {
+    if (event.step === OPERATION_STEP) continue;
+    if (event.status === "started") running.add(event.step);
+    else running.delete(event.step);
+  }
+  const timestamp = at.toISOString();
+  return [...running, OPERATION_STEP].map((step): ProgressEvent => ({
+    step,
+    status: "failed",
+    detail,
+    timestamp,
+  }));
+}
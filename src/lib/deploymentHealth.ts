@@ -1,9 +1,13 @@
 import { ZodError } from "zod";
 import { updateKubeconfig } from "./cloudCli.js";
 import { loadDeploymentConfig, loadDeploymentState } from "./config.js";
+import { ConfigValidationError } from "./configValidation.js";
 import { getInstalledVersion } from "./helm.js";
+import { CommandDeniedError } from "./commandApproval.js";
 import {
   checkClusterAccessible,
+  currentContextMatchesCluster,
+  getCurrentContext,
   getPodStatus,
   type PodStatus,
 } from "./kubernetes.js";
@@ -43,6 +47,12 @@ interface LoadDeploymentHealthOptions {
 }
 
 export function formatConfigError(error: unknown): string {
+  if (error instanceof ConfigValidationError) {
+    return error.issues
+      .map((issue) => `${issue.path}: ${issue.message}`)
+      .join("\n");
+  }
+
   if (error instanceof ZodError) {
     return error.issues
       .map((issue) => {
@@ -91,6 +101,43 @@ export function classifyDeploymentHealth(input: {
   return "online";
 }
 
+/** The one-word status `rulebricks status` leads with. */
+export type OverallStatus =
+  | "healthy"
+  | "unreachable"
+  | "degraded"
+  | "cluster-unreachable"
+  | "destroyed"
+  | "failed"
+  | "pending"
+  | "deploying"
+  | "waiting-dns"
+  | "not-installed"
+  | "not-deployed"
+  | "config-error";
+
+export function overallStatus(health: DeploymentHealth): OverallStatus {
+  const { state } = health;
+  switch (health.kind) {
+    case "online":
+      return "healthy";
+    case "installed-unreachable":
+      return "unreachable";
+    case "installed-degraded":
+      return "degraded";
+    case "cluster-unreachable":
+    case "destroyed":
+    case "config-error":
+      return health.kind;
+    case "not-installed":
+      if (state?.status === "failed") return "failed";
+      if (state?.status === "pending") return "pending";
+      if (state?.status === "deploying") return "deploying";
+      if (state?.status === "waiting-dns") return "waiting-dns";
+      return state ? "not-installed" : "not-deployed";
+  }
+}
+
 export async function checkDeploymentHttpHealth(
   deploymentUrl: string,
 ): Promise<boolean> {
@@ -122,7 +169,11 @@ export async function checkDeploymentHttpHealth(
   }
 }
 
-async function ensureConfiguredCluster(
+/**
+ * Null when the cluster answers, refreshing the kubeconfig from the config's
+ * cluster first if allowed; otherwise why it can't be reached.
+ */
+export async function ensureConfiguredCluster(
   config: DeploymentConfig,
   refreshKubeconfig: boolean,
 ): Promise<string | null> {
@@ -155,6 +206,33 @@ async function ensureConfiguredCluster(
   return clusterError;
 }
 
+/**
+ * Null once kubectl's current context is the config's
+ * infrastructure.clusterName, switching to it by refreshing the kubeconfig
+ * when it isn't; otherwise why it points elsewhere. The same guard deploy
+ * and destroy apply, for commands that change the cluster.
+ */
+export async function ensureClusterContext(
+  config: DeploymentConfig,
+): Promise<string | null> {
+  const { clusterName, provider, region } = config.infrastructure;
+  if (!clusterName || (await currentContextMatchesCluster(clusterName))) {
+    return null;
+  }
+  if (provider && region) {
+    try {
+      await updateKubeconfig(provider, clusterName, region, {
+        gcpProjectId: config.infrastructure.gcpProjectId,
+        azureResourceGroup: config.infrastructure.azureResourceGroup,
+      });
+    } catch (error) {
+      if (!(error instanceof CommandDeniedError)) throw error;
+    }
+  }
+  if (await currentContextMatchesCluster(clusterName)) return null;
+  return `kubectl points at ${(await getCurrentContext()) ?? "no context"}, not ${clusterName} (infrastructure.clusterName).`;
+}
+
 export async function loadDeploymentHealth(
   name: string,
   options: LoadDeploymentHealthOptions = {},
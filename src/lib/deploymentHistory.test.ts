@@ -0,0 +1,98 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  appendEvent,
+  finishEvent,
+  formatDurationMs,
+  formatTable,
+  historyRows,
+  MAX_HISTORY_EVENTS,
+} from "./deploymentHistory.js";
+import type { DeploymentEvent } from "../types/index.js";
+
+const startedAt = new Date("2026-03-01T10:00:00.000Z");
+const finishedAt = new Date("2026-03-01T10:04:05.500Z");
+
+test("a finished run records its duration and outcome", () => {
+  assert.deepEqual(
+    finishEvent(
+      {
+        action: "upgrade",
+        startedAt,
+        fromVersion: "1.4.0",
+        toVersion: "1.5.0",
+      },
+      finishedAt,
+    ),
+    {
+      at: "2026-03-01T10:04:05.500Z",
+      action: "upgrade",
+      outcome: "succeeded",
+      durationMs: 245500,
+      fromVersion: "1.4.0",
+      toVersion: "1.5.0",
+    },
+  );
+});
+
+test("a failed run keeps the first line of its error", () => {
+  const event = finishEvent(
+    {
+      action: "deploy",
+      startedAt,
+      error: new Error("helm install timed out\nsee the logs"),
+    },
+    finishedAt,
+  );
+  assert.equal(event.outcome, "failed");
+  assert.equal(event.error, "helm install timed out");
+  assert.equal(event.fromVersion, undefined);
+});
+
+test("history keeps only the newest events", () => {
+  const event = (n: number): DeploymentEvent => ({
+    at: new Date(n * 1000).toISOString(),
+    action: "deploy",
+    outcome: "succeeded",
+    durationMs: n,
+  });
+  let history: DeploymentEvent[] | undefined;
+  for (let n = 0; n < MAX_HISTORY_EVENTS + 5; n++) {
+    history = appendEvent(history, event(n));
+  }
+  assert.equal(history?.length, MAX_HISTORY_EVENTS);
+  assert.equal(history?.[0].durationMs, 5);
+  assert.equal(history?.at(-1)?.durationMs, MAX_HISTORY_EVENTS + 4);
+});
+
+test("durations read as seconds, then minutes and seconds", () => {
+  assert.equal(formatDurationMs(800), "1s");
+  assert.equal(formatDurationMs(59_000), "59s");
+  assert.equal(formatDurationMs(245_500), "4m06s");
+});
+
+test("the history table aligns its columns", () => {
+  const lines = formatTable(
+    historyRows([
+      finishEvent(
+        { action: "deploy", startedAt, toVersion: "1.4.0" },
+        finishedAt,
+      ),
+      finishEvent(
+        {
+          action: "upgrade-chart",
+          startedAt,
+          fromVersion: "2.0.0",
+          toVersion: "2.1.0",
+          error: "timed out",
+        },
+        finishedAt,
+      ),
+    ]),
+  );
+  assert.deepEqual(lines, [
+    "WHEN                  ACTION         VERSION        DURATION  OUTCOME",
+    "2026-03-01 10:04:05Z  deploy         1.4.0          4m06s     succeeded",
+    "2026-03-01 10:04:05Z  upgrade-chart  2.0.0 → 2.1.0  4m06s     failed: timed out",
+  ]);
+});
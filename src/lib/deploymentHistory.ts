@@ -0,0 +1,87 @@
+import type { DeploymentEvent } from "../types/index.js";
+
+/**
+ * The run log kept in state.yaml: each deploy, upgrade, rollback, destroy and
+ * secret rotation appends one event, and `rulebricks history` prints them. It
+ * answers "what changed and when" without reading Helm revisions, which only
+ * cover the release and are pruned by helm's own history limit.
+ */
+
+/** Oldest events are dropped past this, so state.yaml stays small. */
+export const MAX_HISTORY_EVENTS = 100;
+
+export function finishEvent(
+  run: {
+    action: DeploymentEvent["action"];
+    startedAt: Date;
+    fromVersion?: string;
+    toVersion?: string;
+    error?: unknown;
+  },
+  finishedAt: Date = new Date(),
+): DeploymentEvent {
+  const event: DeploymentEvent = {
+    at: finishedAt.toISOString(),
+    action: run.action,
+    outcome: run.error === undefined ? "succeeded" : "failed",
+    durationMs: Math.max(0, finishedAt.getTime() - run.startedAt.getTime()),
+  };
+  if (run.fromVersion) event.fromVersion = run.fromVersion;
+  if (run.toVersion) event.toVersion = run.toVersion;
+  if (run.error !== undefined) {
+    const message =
+      run.error instanceof Error ? run.error.message : String(run.error);
+    event.error = message.split("\n")[0];
+  }
+  return event;
+}
+
+export function appendEvent(
+  history: DeploymentEvent[] | undefined,
+  event: DeploymentEvent,
+): DeploymentEvent[] {
+  return [...(history ?? []), event].slice(-MAX_HISTORY_EVENTS);
+}
+
+export function formatDurationMs(ms: number): string {
+  const seconds = Math.round(ms / 1000);
+  if (seconds < 60) return `${seconds}s`;
+  const minutes = Math.floor(seconds / 60);
+  return `${minutes}m${String(seconds % 60).padStart(2, "0")}s`;
+}
+
+function versionChange(event: DeploymentEvent): string {
+  if (event.fromVersion && event.toVersion) {
+    return event.fromVersion === event.toVersion
+      ? event.toVersion
+      : `${event.fromVersion} → ${event.toVersion}`;
+  }
+  return event.toVersion ?? event.fromVersion ?? "-";
+}
+
+/** The history as table rows, header first, newest run last. */
+export function historyRows(events: DeploymentEvent[]): string[][] {
+  return [
+    ["WHEN", "ACTION", "VERSION", "DURATION", "OUTCOME"],
+    ...events.map((event) => [
+      event.at.replace("T", " ").replace(/\.\d+Z$/, "Z"),
+      event.action,
+      versionChange(event),
+      formatDurationMs(event.durationMs),
+      event.error ? `${event.outcome}: ${event.error}` : event.outcome,
+    ]),
+  ];
+}
+
+export function formatTable(rows: string[][]): string[] {
+  const widths = rows[0].map((_, column) =>
+    Math.max(...rows.map((row) => row[column].length)),
+  );
+  return rows.map((row) =>
+    row
+      .map((cell, column) =>
+        column === row.length - 1 ? cell : cell.padEnd(widths[column]),
+      )
+      .join("  "),
+  );
+}
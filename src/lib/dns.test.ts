@@ -1,6 +1,11 @@
 import test from "node:test";
 import assert from "node:assert/strict";
-import { getRequiredDNSRecords } from "./dns.js";
+import {
+  formatDNSMismatches,
+  getDeploymentDNSRecords,
+  getRequiredDNSRecords,
+} from "./dns.js";
+import { configFixture } from "./configFixtures.js";
 
 test("manual DNS records include app, Supabase, and built-in observability", () => {
   const records = getRequiredDNSRecords(
@@ -74,3 +79,28 @@ test("manual DNS records use custom Valkey Admin hostname", () => {
   );
   assert.ok(records.every((record) => record.type === "CNAME"));
 });
+
+test("deployment DNS records add non-wildcard tls.domains once", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.tls = {
+    domains: ["*.rb.example.com", "api.example.com", "rb.example.com"],
+  };
+
+  const records = getDeploymentDNSRecords(config, "lb.example.net", "hostname");
+  const hostnames = records.map((record) => record.hostname);
+  assert.ok(hostnames.includes("api.example.com"));
+  assert.ok(!hostnames.includes("*.rb.example.com"));
+  assert.equal(hostnames.filter((h) => h === "rb.example.com").length, 1);
+  assert.ok(records.every((record) => record.type === "CNAME"));
+});
+
+test("DNS mismatches list what each name resolves to", () => {
+  assert.equal(
+    formatDNSMismatches([
+      { hostname: "rb.example.com", target: "4.3.2.1", records: ["1.2.3.4"] },
+      { hostname: "supabase.rb.example.com", target: "4.3.2.1", records: [] },
+    ]),
+    "  • rb.example.com → 1.2.3.4, expected 4.3.2.1\n" +
+      "  • supabase.rb.example.com → (does not resolve), expected 4.3.2.1",
+  );
+});
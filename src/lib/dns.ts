@@ -1,6 +1,10 @@
 import * as dns from "dns";
 import { execa } from "execa";
-import { DNSRecord, DEFAULT_NAMESPACE } from "../types/index.js";
+import {
+  DNSRecord,
+  DEFAULT_NAMESPACE,
+  DeploymentConfig,
+} from "../types/index.js";
 
 /**
  * DNS resolvers to try in order:
@@ -342,6 +346,83 @@ export function getRequiredDNSRecords(
   return records;
 }
 
+/**
+ * The records a deployment needs before TLS: the DNS wait screen's set, plus
+ * any extra non-wildcard tls.domains names (wildcards are validated by the
+ * DNS-01 solver, not by pointing them at the load balancer).
+ */
+export function getDeploymentDNSRecords(
+  config: DeploymentConfig,
+  loadBalancerAddress: string,
+  loadBalancerType: "ip" | "hostname",
+): DNSRecord[] {
+  const valkeyAdmin = config.features.cache?.valkeyAdmin;
+  const records = getRequiredDNSRecords(
+    config.domain,
+    loadBalancerAddress,
+    loadBalancerType,
+    config.database.type === "self-hosted",
+    config.features.observability?.clickstack?.enabled ?? true,
+    undefined,
+    valkeyAdmin?.enabled === true && valkeyAdmin.exposure === "ingress",
+    valkeyAdmin?.hostname,
+  );
+  for (const hostname of config.tls?.domains ?? []) {
+    if (hostname.startsWith("*.")) continue;
+    if (records.some((r) => r.hostname === hostname)) continue;
+    records.push({
+      hostname,
+      type: loadBalancerType === "ip" ? "A" : "CNAME",
+      target: loadBalancerAddress,
+      verified: false,
+      required: true,
+    });
+  }
+  return records;
+}
+
+/** A required record that does not resolve to the load balancer. */
+export interface DNSMismatch {
+  hostname: string;
+  target: string;
+  /** What the name resolves to now; empty when it doesn't resolve. */
+  records: string[];
+}
+
+/**
+ * Checks every record once (no polling) and returns the ones that don't
+ * point at their target yet.
+ */
+export async function findDNSMismatches(
+  records: DNSRecord[],
+): Promise<DNSMismatch[]> {
+  const results = await Promise.all(
+    records.map(async (record) => ({
+      record,
+      result: await checkDNSRecord(record.hostname, record.target),
+    })),
+  );
+  return results
+    .filter(({ result }) => !(result.resolved && result.matchesTarget))
+    .map(({ record, result }) => ({
+      hostname: record.hostname,
+      target: record.target,
+      records: result.records,
+    }));
+}
+
+/** One "  • host → found, expected target" line per mismatch. */
+export function formatDNSMismatches(mismatches: DNSMismatch[]): string {
+  return mismatches
+    .map(
+      (m) =>
+        `  • ${m.hostname} → ${
+          m.records.length > 0 ? m.records.join(", ") : "(does not resolve)"
+        }, expected ${m.target}`,
+    )
+    .join("\n");
+}
+
 /**
  * Polls DNS records until they resolve or timeout
  */
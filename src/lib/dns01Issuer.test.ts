@@ -0,0 +1,77 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  dns01Certificate,
+  dns01ClusterIssuer,
+  dns01IssuerName,
+} from "./dns01Issuer.js";
+import { configFixture } from "./configFixtures.js";
+
+test("wildcard tls.domains get their own DNS-01 ClusterIssuer", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.equal(dns01ClusterIssuer(config), undefined);
+
+  config.tls = {
+    domains: ["*.rules.example.com"],
+    dns01: { secretRef: "route53-credentials", hostedZoneId: "Z123" },
+  };
+  const issuer = dns01ClusterIssuer(config);
+  assert.ok(issuer);
+  assert.equal(issuer.kind, "ClusterIssuer");
+  assert.equal(issuer.metadata.name, dns01IssuerName(config.name));
+  assert.equal(issuer.metadata.name, `rulebricks-${config.name}-dns01`);
+  assert.deepEqual((issuer.spec as any).acme.solvers, [
+    {
+      selector: { dnsNames: ["*.rules.example.com"] },
+      dns01: {
+        route53: {
+          region: config.infrastructure.region,
+          hostedZoneID: "Z123",
+          accessKeyIDSecretRef: {
+            name: "route53-credentials",
+            key: "access-key-id",
+          },
+          secretAccessKeySecretRef: {
+            name: "route53-credentials",
+            key: "secret-access-key",
+          },
+        },
+      },
+    },
+  ]);
+});
+
+test("the wildcard names get a Certificate from the DNS-01 issuer", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.equal(dns01Certificate(config), undefined);
+
+  config.tls = {
+    domains: ["rules.example.com", "*.rules.example.com"],
+    dns01: { secretRef: "route53-credentials" },
+  };
+  const certificate = dns01Certificate(config);
+  assert.ok(certificate);
+  assert.equal(certificate.kind, "Certificate");
+  assert.equal(certificate.metadata.namespace, `rulebricks-${config.name}`);
+  assert.deepEqual(certificate.spec, {
+    secretName: `rulebricks-${config.name}-wildcard-tls`,
+    dnsNames: ["*.rules.example.com"],
+    issuerRef: {
+      group: "cert-manager.io",
+      kind: "ClusterIssuer",
+      name: dns01IssuerName(config.name),
+    },
+  });
+});
+
+test("Cloudflare Origin certificates need no DNS-01 issuer", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.tls = {
+    provider: "cloudflare-origin",
+    cloudflareOrigin: { apiToken: "token" },
+    domains: ["*.rules.example.com"],
+    dns01: { secretRef: "cloudflare-credentials" },
+  };
+  assert.equal(dns01ClusterIssuer(config), undefined);
+  assert.equal(dns01Certificate(config), undefined);
+});
@@ -0,0 +1,197 @@
+import { execa } from "execa";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+  usesCloudflareOriginTls,
+  wildcardTlsDomains,
+} from "../types/index.js";
+import { updateDeploymentState } from "./config.js";
+import {
+  APPLY_LABELS,
+  applyManifests,
+  groupKind,
+  type ManifestObject,
+} from "./manifestApply.js";
+
+/**
+ * The DNS-01 ClusterIssuer for wildcard tls.domains. The chart's Let's
+ * Encrypt ClusterIssuer only answers HTTP-01 challenges, which cannot prove
+ * wildcard names, and the chart has no way to add solvers to it. So the CLI
+ * applies a second ClusterIssuer next to it, with a DNS-01 solver on the
+ * dns.provider zone and credentials from tls.dns01.secretRef, plus one
+ * Certificate for the wildcard names that references it. The issuer is
+ * recorded like `apply --allow-cluster-scoped` objects, so `destroy` removes
+ * it; the Certificate lives in the deployment namespace and goes with it.
+ */
+
+const LETS_ENCRYPT_SERVER = "https://acme-v02.api.letsencrypt.org/directory";
+
+/** Name of the deployment's DNS-01 ClusterIssuer. */
+export function dns01IssuerName(deploymentName: string): string {
+  return `${getReleaseName(deploymentName)}-dns01`;
+}
+
+/** Name of the wildcard Certificate, and of the Secret it issues into. */
+export function dns01CertificateName(deploymentName: string): string {
+  return `${getReleaseName(deploymentName)}-wildcard-tls`;
+}
+
+/** The cert-manager DNS-01 solver for the config's dns.provider. */
+function dns01Solver(
+  config: DeploymentConfig,
+): Record<string, unknown> | undefined {
+  const dns01 = config.tls?.dns01;
+  if (!dns01) return undefined;
+
+  const secretKey = (key: string) => ({ name: dns01.secretRef, key });
+  switch (config.dns.provider) {
+    case "route53":
+      return {
+        route53: {
+          region: config.infrastructure.region || "us-east-1",
+          ...(dns01.hostedZoneId ? { hostedZoneID: dns01.hostedZoneId } : {}),
+          accessKeyIDSecretRef: secretKey("access-key-id"),
+          secretAccessKeySecretRef: secretKey("secret-access-key"),
+        },
+      };
+    case "google":
+      return {
+        cloudDNS: {
+          project: dns01.project ?? config.infrastructure.gcpProjectId,
+          serviceAccountSecretRef: secretKey("key.json"),
+        },
+      };
+    case "azure":
+      return {
+        azureDNS: {
+          subscriptionID: dns01.subscriptionId,
+          resourceGroupName: dns01.resourceGroup,
+          ...(dns01.hostedZoneName
+            ? { hostedZoneName: dns01.hostedZoneName }
+            : {}),
+          environment: "AzurePublicCloud",
+          clientID: dns01.clientId,
+          tenantID: dns01.tenantId,
+          clientSecretSecretRef: secretKey("client-secret"),
+        },
+      };
+    case "cloudflare":
+      return { cloudflare: { apiTokenSecretRef: secretKey("api-token") } };
+    default:
+      // configValidation rejects wildcards on providers without DNS-01.
+      return undefined;
+  }
+}
+
+/**
+ * The DNS-01 ClusterIssuer manifest when tls.domains lists wildcard names
+ * and Let's Encrypt issues the certificates; undefined otherwise.
+ */
+export function dns01ClusterIssuer(
+  config: DeploymentConfig,
+): ManifestObject | undefined {
+  const wildcards = wildcardTlsDomains(config);
+  if (wildcards.length === 0 || usesCloudflareOriginTls(config)) {
+    return undefined;
+  }
+  const solver = dns01Solver(config);
+  if (!solver) return undefined;
+
+  const name = dns01IssuerName(config.name);
+  return {
+    apiVersion: "cert-manager.io/v1",
+    kind: "ClusterIssuer",
+    metadata: {
+      name,
+      labels: {
+        [APPLY_LABELS.managedBy]: "rulebricks-cli",
+        [APPLY_LABELS.instance]: getReleaseName(config.name),
+      },
+    },
+    spec: {
+      acme: {
+        email: config.tlsEmail,
+        server: LETS_ENCRYPT_SERVER,
+        privateKeySecretRef: { name: `${name}-account-key` },
+        solvers: [{ selector: { dnsNames: wildcards }, dns01: solver }],
+      },
+    },
+  };
+}
+
+/**
+ * The Certificate for the wildcard tls.domains, issued by the DNS-01
+ * ClusterIssuer into a Secret of the same name in the deployment namespace.
+ * Undefined whenever there is no issuer.
+ */
+export function dns01Certificate(
+  config: DeploymentConfig,
+): ManifestObject | undefined {
+  const issuer = dns01ClusterIssuer(config);
+  if (!issuer) return undefined;
+
+  const name = dns01CertificateName(config.name);
+  return {
+    apiVersion: "cert-manager.io/v1",
+    kind: "Certificate",
+    metadata: {
+      name,
+      namespace: getNamespace(config.name),
+      labels: issuer.metadata.labels,
+    },
+    spec: {
+      secretName: name,
+      dnsNames: wildcardTlsDomains(config),
+      issuerRef: {
+        group: "cert-manager.io",
+        kind: issuer.kind,
+        name: issuer.metadata.name,
+      },
+    },
+  };
+}
+
+/**
+ * Applies the deployment's DNS-01 ClusterIssuer and wildcard Certificate
+ * when it needs them, and records the issuer for `destroy`. Run after the
+ * chart install, which brings the cert-manager CRDs and the namespace.
+ * Returns the issuer's name, or null when there is none.
+ */
+export async function ensureDns01Issuer(
+  config: DeploymentConfig,
+): Promise<string | null> {
+  const issuer = dns01ClusterIssuer(config);
+  const certificate = dns01Certificate(config);
+  if (!issuer || !certificate) return null;
+  await applyManifests([issuer, certificate]);
+  const kind = groupKind(issuer.apiVersion, issuer.kind);
+  const ref = `${kind}/${issuer.metadata.name}`;
+  await updateDeploymentState(config.name, (state) => ({
+    ...state,
+    appliedClusterResources: [
+      ...new Set([...(state.appliedClusterResources ?? []), ref]),
+    ],
+  }));
+  return issuer.metadata.name;
+}
+
+/**
+ * Deletes the wildcard Certificate, for `destroy --keep-data`, which keeps
+ * the namespace but removes the issuer it renews against. Its Secret stays.
+ */
+export async function deleteDns01Certificate(
+  deploymentName: string,
+): Promise<void> {
+  await execa(
+    "kubectl",
+    [
+      "delete",
+      `certificate.cert-manager.io/${dns01CertificateName(deploymentName)}`,
+      "-n",
+      getNamespace(deploymentName),
+      "--ignore-not-found",
+    ],
+    { timeout: 30000 },
+  );
+}
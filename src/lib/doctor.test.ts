@@ -0,0 +1,236 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  certificateChecks,
+  clusterChecks,
+  eventChecks,
+  expectedKafkaTopics,
+  kafkaTopicChecks,
+  pvcChecks,
+  toolChecks,
+  workloadChecks,
+  worstStatus,
+} from "./doctor.js";
+import type { DeploymentHealth } from "./deploymentHealth.js";
+
+function health(overrides: Partial<DeploymentHealth>): DeploymentHealth {
+  return {
+    name: "acme",
+    kind: "online",
+    config: null,
+    state: null,
+    namespace: "rulebricks-acme",
+    releaseName: "rulebricks-acme",
+    helmVersion: "1.4.0",
+    pods: [],
+    url: "https://rules.acme.com",
+    httpReachable: true,
+    clusterError: null,
+    configError: null,
+    ...overrides,
+  };
+}
+
+test("the report takes its worst check's status", () => {
+  const pass = { category: "tools", name: "kubectl", status: "pass" } as const;
+  assert.equal(worstStatus([]), "pass");
+  assert.equal(worstStatus([pass, { ...pass, status: "warn" }]), "warn");
+  assert.equal(
+    worstStatus([{ ...pass, status: "fail" }, { ...pass, status: "warn" }]),
+    "fail",
+  );
+});
+
+test("missing and outdated tools fail with where to get them", () => {
+  const checks = toolChecks([
+    { tool: "kubectl", installed: true, version: "1.29.1" },
+    { tool: "helm", installed: true, version: "3.10.0" },
+    { tool: "aws", installed: false },
+  ]);
+  assert.deepEqual(
+    checks.map((c) => [c.name, c.status]),
+    [
+      ["kubectl", "pass"],
+      ["Helm", "fail"],
+      ["AWS CLI", "fail"],
+    ],
+  );
+  assert.equal(checks[0].detail, "1.29.1");
+  assert.match(checks[1].detail ?? "", /older than the required 3\.13\.0/);
+});
+
+test("an unreachable cluster stops the cluster checks at kubeconfig", () => {
+  const checks = clusterChecks(
+    health({ clusterError: "Unable to connect to the server" }),
+  );
+  assert.deepEqual(
+    checks.map((c) => [c.name, c.status]),
+    [
+      ["config", "pass"],
+      ["kubeconfig", "fail"],
+    ],
+  );
+});
+
+test("a missing release fails and an unresponsive URL warns", () => {
+  const checks = clusterChecks(
+    health({ helmVersion: null, httpReachable: false }),
+  );
+  assert.deepEqual(
+    checks.map((c) => [c.name, c.status]),
+    [
+      ["config", "pass"],
+      ["kubeconfig", "pass"],
+      ["release", "fail"],
+      ["url", "warn"],
+    ],
+  );
+});
+
+test("workloads warn when partly ready and fail when nothing is", () => {
+  const checks = workloadChecks([
+    {
+      name: "rulebricks-acme-app-7d9f8b6c5d-x2k4p",
+      status: "Running",
+      ready: true,
+      restarts: 0,
+    },
+    {
+      name: "rulebricks-acme-hps-5c8d7f9b4d-k2m7q",
+      status: "Running",
+      ready: true,
+      restarts: 0,
+    },
+    {
+      name: "rulebricks-acme-hps-5c8d7f9b4d-q9w4z",
+      status: "Running",
+      ready: false,
+      restarts: 3,
+    },
+    {
+      name: "rulebricks-acme-kafka-0",
+      status: "Pending",
+      ready: false,
+      restarts: 0,
+    },
+  ]);
+  assert.deepEqual(
+    checks.map((c) => [c.name, c.status]),
+    [
+      ["rulebricks-acme-app", "pass"],
+      ["rulebricks-acme-hps", "warn"],
+      ["rulebricks-acme-kafka", "fail"],
+    ],
+  );
+  assert.equal(
+    checks[1].detail,
+    "1/2 ready; 3 restarts; not ready: rulebricks-acme-hps-5c8d7f9b4d-q9w4z Running",
+  );
+  assert.equal(workloadChecks([])[0].status, "fail");
+});
+
+test("only bound volumes pass", () => {
+  const checks = pvcChecks([
+    { name: "data-0", storageClass: "gp3", sizeGi: 20, phase: "Bound" },
+    { name: "data-1", storageClass: "gp3", sizeGi: 20, phase: "Pending" },
+    { name: "data-2", sizeGi: 20, phase: "Lost" },
+  ]);
+  assert.deepEqual(
+    checks.map((c) => [c.status, c.detail]),
+    [
+      ["pass", "Bound, 20Gi gp3"],
+      ["warn", "Pending, 20Gi gp3"],
+      ["fail", "Lost, 20Gi"],
+    ],
+  );
+});
+
+test("failed certificates fail and ones still issuing warn", () => {
+  const checks = certificateChecks([
+    {
+      name: "app-tls",
+      dnsNames: ["rules.acme.com"],
+      ready: true,
+      failed: false,
+    },
+    {
+      name: "supabase-tls",
+      dnsNames: [],
+      ready: false,
+      failed: true,
+      reason: "Failed",
+      message: "ACME challenge failed",
+    },
+    { name: "grafana-tls", dnsNames: [], ready: false, failed: false },
+  ]);
+  assert.deepEqual(
+    checks.map((c) => [c.status, c.detail]),
+    [
+      ["pass", "rules.acme.com"],
+      ["fail", "Failed: ACME challenge failed"],
+      ["warn", "not ready"],
+    ],
+  );
+});
+
+test("expected topics come from values.yaml for the in-cluster broker", () => {
+  const topics = [{ name: "logs" }, { name: "solution" }];
+  assert.deepEqual(
+    expectedKafkaTopics({ kafka: { enabled: true, topics } }),
+    ["logs", "solution"],
+  );
+  assert.deepEqual(
+    expectedKafkaTopics({ kafka: { enabled: false, topics } }),
+    [],
+  );
+  assert.deepEqual(expectedKafkaTopics(null), []);
+});
+
+test("missing topics fail and unreconciled ones warn", () => {
+  const checks = kafkaTopicChecks(
+    ["logs", "solution", "solution-response"],
+    [
+      { name: "logs", ready: true },
+      { name: "solution", ready: false, message: "Not authorized" },
+    ],
+  );
+  assert.deepEqual(
+    checks.map((c) => [c.name, c.status, c.detail]),
+    [
+      ["logs", "pass", undefined],
+      ["solution", "warn", "Not authorized"],
+      ["solution-response", "fail", "KafkaTopic not found"],
+    ],
+  );
+});
+
+test("warning events are grouped per object and reason", () => {
+  const backOff = {
+    object: "Pod/app-1",
+    reason: "BackOff",
+    message: "Back-off restarting failed container",
+    count: 2,
+  };
+  const checks = eventChecks([backOff, { ...backOff, count: 3 }]);
+  assert.deepEqual(checks, [
+    {
+      category: "events",
+      name: "Pod/app-1 BackOff",
+      status: "warn",
+      detail: "Back-off restarting failed container (x5)",
+    },
+  ]);
+  assert.equal(eventChecks([])[0].status, "pass");
+});
+
+test("events past the limit are summarized in one check", () => {
+  const events = Array.from({ length: 12 }, (_, i) => ({
+    object: `Pod/app-${i}`,
+    reason: "BackOff",
+    message: "Back-off",
+    count: 1,
+  }));
+  const checks = eventChecks(events);
+  assert.equal(checks.length, 11);
+  assert.equal(checks[10].detail, "2 more objects with Warning events");
+});
@@ -0,0 +1,374 @@
+import { loadHelmValues } from "./config.js";
+import {
+  arePodsHealthy,
+  loadDeploymentHealth,
+  type DeploymentHealth,
+} from "./deploymentHealth.js";
+import {
+  getPersistentVolumeClaims,
+  getCertificateStatus,
+  listKafkaTopics,
+  listWarningEvents,
+  type CertificateStatus,
+  type KafkaTopicStatus,
+  type PersistentVolumeClaimInfo,
+  type PodStatus,
+  type WarningEvent,
+} from "./kubernetes.js";
+import { groupWorkloads, podWorkload } from "./statusReport.js";
+import {
+  checkTool,
+  describeToolProblems,
+  requiredTools,
+  TOOL_SPECS,
+  type ToolStatus,
+} from "./toolCheck.js";
+import { runVectorTest, type VectorTestResult } from "./vectorTest.js";
+
+/**
+ * `rulebricks doctor`: everything worth attaching to a support ticket in one
+ * pass. Reuses the status checks (cluster, release, pods), the tool check,
+ * cert-manager and Strimzi resources, and optionally `vector test`, and
+ * reports each finding as pass, warn or fail under a category.
+ */
+
+export type DoctorStatus = "pass" | "warn" | "fail";
+
+export type DoctorCategory =
+  | "tools"
+  | "cluster"
+  | "workloads"
+  | "storage"
+  | "certificates"
+  | "kafka"
+  | "events"
+  | "vector";
+
+export interface DoctorCheck {
+  category: DoctorCategory;
+  name: string;
+  status: DoctorStatus;
+  detail?: string;
+}
+
+export interface DoctorReport {
+  deployment: string;
+  checkedAt: string;
+  /** The worst status among the checks. */
+  status: DoctorStatus;
+  checks: DoctorCheck[];
+}
+
+/** Distinct warnings reported; the rest are summarized in a count. */
+const MAX_EVENT_CHECKS = 10;
+
+const STATUS_RANK: Record<DoctorStatus, number> = { pass: 0, warn: 1, fail: 2 };
+
+export function worstStatus(checks: DoctorCheck[]): DoctorStatus {
+  return checks.reduce<DoctorStatus>(
+    (worst, check) =>
+      STATUS_RANK[check.status] > STATUS_RANK[worst] ? check.status : worst,
+    "pass",
+  );
+}
+
+export function toolChecks(statuses: ToolStatus[]): DoctorCheck[] {
+  return statuses.map((status): DoctorCheck => {
+    const [problem] = describeToolProblems([status]);
+    return {
+      category: "tools",
+      name: TOOL_SPECS[status.tool].label,
+      status: problem ? "fail" : "pass",
+      detail: problem ?? status.version,
+    };
+  });
+}
+
+/** Config, cluster access, Helm release and URL, from the status checks. */
+export function clusterChecks(health: DeploymentHealth): DoctorCheck[] {
+  if (health.configError) {
+    return [
+      {
+        category: "cluster",
+        name: "config",
+        status: "fail",
+        detail: health.configError,
+      },
+    ];
+  }
+  const checks: DoctorCheck[] = [
+    { category: "cluster", name: "config", status: "pass" },
+  ];
+  if (health.clusterError) {
+    checks.push({
+      category: "cluster",
+      name: "kubeconfig",
+      status: "fail",
+      detail: health.clusterError,
+    });
+    return checks;
+  }
+  checks.push({ category: "cluster", name: "kubeconfig", status: "pass" });
+  checks.push(
+    health.helmVersion
+      ? {
+          category: "cluster",
+          name: "release",
+          status: "pass",
+          detail: `${health.releaseName} (chart ${health.helmVersion})`,
+        }
+      : {
+          category: "cluster",
+          name: "release",
+          status: "fail",
+          detail: `Helm release ${health.releaseName} is not installed in ${health.namespace}`,
+        },
+  );
+  if (health.url) {
+    checks.push({
+      category: "cluster",
+      name: "url",
+      status: health.httpReachable ? "pass" : "warn",
+      detail: health.httpReachable
+        ? health.url
+        : `${health.url} did not respond`,
+    });
+  }
+  return checks;
+}
+
+/** One check per workload; some replicas ready warns, none ready fails. */
+export function workloadChecks(pods: PodStatus[]): DoctorCheck[] {
+  if (pods.length === 0) {
+    return [
+      {
+        category: "workloads",
+        name: "pods",
+        status: "fail",
+        detail: "No pods in the namespace",
+      },
+    ];
+  }
+  return groupWorkloads(pods).map((workload): DoctorCheck => {
+    const members = pods.filter(
+      (pod) => podWorkload(pod.name) === workload.name,
+    );
+    const restarts = members.reduce((sum, pod) => sum + pod.restarts, 0);
+    const notReady = members
+      .filter((pod) => !arePodsHealthy([pod]))
+      .map((pod) => `${pod.name} ${pod.status}`);
+    const detail = [
+      `${workload.ready}/${workload.total} ready`,
+      restarts > 0 ? `${restarts} restarts` : "",
+      notReady.length > 0 ? `not ready: ${notReady.join(", ")}` : "",
+    ]
+      .filter(Boolean)
+      .join("; ");
+    return {
+      category: "workloads",
+      name: workload.name,
+      status: workload.healthy ? "pass" : workload.ready > 0 ? "warn" : "fail",
+      detail,
+    };
+  });
+}
+
+export function pvcChecks(pvcs: PersistentVolumeClaimInfo[]): DoctorCheck[] {
+  return pvcs.map((pvc): DoctorCheck => {
+    const phase = pvc.phase ?? "Unknown";
+    return {
+      category: "storage",
+      name: pvc.name,
+      status: phase === "Bound" ? "pass" : phase === "Lost" ? "fail" : "warn",
+      detail: `${phase}, ${pvc.sizeGi}Gi${pvc.storageClass ? ` ${pvc.storageClass}` : ""}`,
+    };
+  });
+}
+
+/** Failed issuance fails; a certificate still being issued warns. */
+export function certificateChecks(certs: CertificateStatus[]): DoctorCheck[] {
+  return certs.map(
+    (cert): DoctorCheck => ({
+      category: "certificates",
+      name: cert.name,
+      status: cert.ready ? "pass" : cert.failed ? "fail" : "warn",
+      detail: cert.ready
+        ? cert.dnsNames.join(", ")
+        : [cert.reason, cert.message].filter(Boolean).join(": ") ||
+          "not ready",
+    }),
+  );
+}
+
+/**
+ * The topics the chart should have created (from values.yaml) against the
+ * KafkaTopics in the namespace: missing fails, not yet reconciled warns.
+ */
+export function kafkaTopicChecks(
+  expected: string[],
+  topics: KafkaTopicStatus[],
+): DoctorCheck[] {
+  return expected.map((name): DoctorCheck => {
+    const topic = topics.find((t) => t.name === name);
+    if (!topic) {
+      return {
+        category: "kafka",
+        name,
+        status: "fail",
+        detail: "KafkaTopic not found",
+      };
+    }
+    return {
+      category: "kafka",
+      name,
+      status: topic.ready ? "pass" : "warn",
+      ...(topic.ready ? {} : { detail: topic.message || "not ready" }),
+    };
+  });
+}
+
+/** Warning events grouped by object and reason, most recent first. */
+export function eventChecks(events: WarningEvent[]): DoctorCheck[] {
+  if (events.length === 0) {
+    return [
+      {
+        category: "events",
+        name: "warnings",
+        status: "pass",
+        detail: "No recent Warning events",
+      },
+    ];
+  }
+  const grouped = new Map<string, WarningEvent>();
+  for (const event of events) {
+    const key = `${event.object} ${event.reason}`;
+    const seen = grouped.get(key);
+    grouped.set(
+      key,
+      seen ? { ...seen, count: seen.count + event.count } : event,
+    );
+  }
+  const checks: DoctorCheck[] = [...grouped.entries()]
+    .slice(0, MAX_EVENT_CHECKS)
+    .map(([key, event]): DoctorCheck => ({
+      category: "events",
+      name: key,
+      status: "warn",
+      detail: `${event.message}${event.count > 1 ? ` (x${event.count})` : ""}`,
+    }));
+  if (grouped.size > MAX_EVENT_CHECKS) {
+    checks.push({
+      category: "events",
+      name: "more",
+      status: "warn",
+      detail: `${grouped.size - MAX_EVENT_CHECKS} more objects with Warning events`,
+    });
+  }
+  return checks;
+}
+
+export function vectorChecks(result: VectorTestResult): DoctorCheck[] {
+  const checks = result.sinks.map(
+    (sink): DoctorCheck => ({
+      category: "vector",
+      name: sink.id,
+      status: sink.ok ? "pass" : "fail",
+      detail: sink.ok ? sink.type : `${sink.type}: ${sink.error ?? "failed"}`,
+    }),
+  );
+  if (result.error) {
+    checks.push({
+      category: "vector",
+      name: "test job",
+      status: "fail",
+      detail: result.error,
+    });
+  }
+  return checks;
+}
+
+/** A check that could not run, e.g. the resource list failed. */
+function unavailable(
+  category: DoctorCategory,
+  name: string,
+  error: unknown,
+): DoctorCheck {
+  return {
+    category,
+    name,
+    status: "warn",
+    detail: error instanceof Error ? error.message : String(error),
+  };
+}
+
+/** Topic names the in-cluster broker should carry; empty for external Kafka. */
+export function expectedKafkaTopics(
+  values: Record<string, unknown> | null,
+): string[] {
+  const kafka = values?.kafka as
+    | { enabled?: boolean; topics?: Array<{ name?: string }> }
+    | undefined;
+  if (!kafka?.enabled) return [];
+  return (kafka.topics ?? [])
+    .map((topic) => topic.name)
+    .filter((name): name is string => Boolean(name));
+}
+
+/**
+ * Runs every check. `vector` also runs the Vector sink test, which starts a
+ * one-shot job in the cluster, so it is opt-in.
+ */
+export async function collectDoctorReport(
+  name: string,
+  options: { vector?: boolean } = {},
+): Promise<DoctorReport> {
+  const health = await loadDeploymentHealth(name, { refreshKubeconfig: true });
+  const checks: DoctorCheck[] = [
+    ...toolChecks(
+      await Promise.all(requiredTools(health.config).map(checkTool)),
+    ),
+    ...clusterChecks(health),
+  ];
+
+  if (health.config && !health.clusterError && health.helmVersion) {
+    const namespace = health.namespace;
+    const values = await loadHelmValues(name).catch(() => null);
+    const topics = expectedKafkaTopics(values);
+
+    checks.push(...workloadChecks(health.pods));
+    checks.push(
+      ...(await getPersistentVolumeClaims(namespace).then(pvcChecks, (err) => [
+        unavailable("storage", "pvcs", err),
+      ])),
+    );
+    checks.push(...certificateChecks(await getCertificateStatus(namespace)));
+    if (topics.length > 0) {
+      checks.push(
+        ...(await listKafkaTopics(namespace).then(
+          (found) => kafkaTopicChecks(topics, found),
+          (err) => [unavailable("kafka", "topics", err)],
+        )),
+      );
+    }
+    checks.push(
+      ...(await listWarningEvents(namespace).then(eventChecks, (err) => [
+        unavailable("events", "warnings", err),
+      ])),
+    );
+    if (options.vector) {
+      checks.push(
+        ...(await runVectorTest(health.config, values).then(
+          vectorChecks,
+          (err) => [unavailable("vector", "sinks", err)],
+        )),
+      );
+    }
+  }
+
+  return {
+    deployment: name,
+    checkedAt: new Date().toISOString(),
+    status: worstStatus(checks),
+    checks,
+  };
+}
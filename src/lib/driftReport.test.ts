@@ -0,0 +1,172 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  REDACTED_VALUE,
+  buildDriftReport,
+  diffValues,
+  diffWorkloads,
+  parseLiveWorkloads,
+  parseRenderedWorkloads,
+} from "./driftReport.js";
+
+const RENDERED = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - name: app
+          image: rulebricks/app:1.4.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hps
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: hps
+          image: rulebricks/hps:1.4.0
+---
+apiVersion: keda.sh/v1alpha1
+kind: ScaledObject
+metadata:
+  name: hps
+spec:
+  scaleTargetRef:
+    name: hps
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`;
+
+test("diffValues reports changed, added and removed leaves", () => {
+  const drift = diffValues(
+    { app: { replicas: 2, tag: "1.3.0", old: true }, same: "x" },
+    { app: { replicas: 3, tag: "1.3.0", extra: "y" }, same: "x" },
+  );
+  assert.deepEqual(drift, [
+    { path: "app.extra", live: undefined, desired: "y" },
+    { path: "app.old", live: true, desired: undefined },
+    { path: "app.replicas", live: 2, desired: 3 },
+  ]);
+});
+
+test("diffValues redacts credentials but still reports them", () => {
+  const drift = diffValues(
+    { global: { smtp: { pass: "old" }, licenseKey: "a" } },
+    { global: { smtp: { pass: "new" }, licenseKey: "a" } },
+  );
+  assert.deepEqual(drift, [
+    { path: "global.smtp.pass", live: REDACTED_VALUE, desired: REDACTED_VALUE },
+  ]);
+});
+
+test("diffValues treats null and absent as equal", () => {
+  assert.deepEqual(diffValues({ a: null }, {}), []);
+});
+
+test("parseRenderedWorkloads leaves scaler-owned replicas unset", () => {
+  const workloads = parseRenderedWorkloads(RENDERED);
+  assert.deepEqual(workloads, [
+    {
+      kind: "Deployment",
+      name: "app",
+      replicas: 2,
+      images: ["rulebricks/app:1.4.0"],
+    },
+    {
+      kind: "Deployment",
+      name: "hps",
+      replicas: undefined,
+      images: ["rulebricks/hps:1.4.0"],
+    },
+  ]);
+});
+
+test("parseLiveWorkloads reads a kubectl List", () => {
+  const raw = JSON.stringify({
+    kind: "List",
+    items: [
+      {
+        kind: "StatefulSet",
+        metadata: { name: "db" },
+        spec: {
+          replicas: 1,
+          template: { spec: { containers: [{ image: "postgres:15" }] } },
+        },
+      },
+    ],
+  });
+  assert.deepEqual(parseLiveWorkloads(raw), [
+    { kind: "StatefulSet", name: "db", replicas: 1, images: ["postgres:15"] },
+  ]);
+});
+
+test("diffWorkloads reports replica, image, missing and extra drift", () => {
+  const desired = parseRenderedWorkloads(RENDERED);
+  const live = [
+    {
+      kind: "Deployment",
+      name: "app",
+      replicas: 1,
+      images: ["rulebricks/app:1.3.0"],
+    },
+    {
+      kind: "Deployment",
+      name: "old-worker",
+      replicas: 1,
+      images: ["rulebricks/worker:1.3.0"],
+    },
+  ];
+  assert.deepEqual(
+    diffWorkloads(live, desired).map((d) => `${d.name}:${d.change}`),
+    ["app:replicas", "app:images", "hps:missing", "old-worker:extra"],
+  );
+});
+
+test("diffWorkloads ignores replicas a scaler owns", () => {
+  const desired = parseRenderedWorkloads(RENDERED);
+  const live = desired.map((w) => ({ ...w, replicas: 7 }));
+  assert.deepEqual(
+    diffWorkloads(live, desired).map((d) => `${d.name}:${d.change}`),
+    ["app:replicas"],
+  );
+});
+
+test("buildDriftReport flags an uninstalled release as drifted", () => {
+  const report = buildDriftReport({
+    release: "rulebricks-prod",
+    namespace: "rulebricks-prod",
+    installedChartVersion: null,
+    liveValues: null,
+    desiredValues: {},
+    liveWorkloads: [],
+    desiredWorkloads: [],
+  });
+  assert.equal(report.installed, false);
+  assert.equal(report.drifted, true);
+});
+
+test("buildDriftReport compares only a pinned chart version", () => {
+  const base = {
+    release: "rulebricks-prod",
+    namespace: "rulebricks-prod",
+    installedChartVersion: "1.3.0",
+    liveValues: {},
+    desiredValues: {},
+    liveWorkloads: [],
+    desiredWorkloads: [],
+  };
+  assert.equal(buildDriftReport(base).drifted, false);
+  const pinned = buildDriftReport({ ...base, desiredChartVersion: "1.4.0" });
+  assert.deepEqual(pinned.chart, { live: "1.3.0", desired: "1.4.0" });
+  assert.equal(pinned.drifted, true);
+});
@@ -0,0 +1,237 @@
+import yaml from "yaml";
+
+/**
+ * `deploy --observe-only`: what a deploy would change, measured against the
+ * live cluster rather than the last local values.yaml. Compares the installed
+ * chart version, the release's user-supplied values, and each Deployment /
+ * StatefulSet / DaemonSet's replicas and images with what the current config
+ * renders. Read-only; the command exits non-zero when anything drifted.
+ */
+
+export interface WorkloadSpec {
+  kind: string;
+  name: string;
+  /** Unset when the manifest leaves replicas to the controller or an HPA. */
+  replicas?: number;
+  images: string[];
+}
+
+export interface ValueDrift {
+  path: string;
+  live?: unknown;
+  desired?: unknown;
+}
+
+export interface WorkloadDrift {
+  kind: string;
+  name: string;
+  change: "missing" | "extra" | "replicas" | "images";
+  live?: string;
+  desired?: string;
+}
+
+export interface DriftReport {
+  release: string;
+  namespace: string;
+  installed: boolean;
+  chart: { live: string | null; desired: string } | null;
+  values: ValueDrift[];
+  workloads: WorkloadDrift[];
+  drifted: boolean;
+}
+
+const WORKLOAD_KINDS = new Set(["Deployment", "StatefulSet", "DaemonSet"]);
+
+// Leaf names whose values are credentials; drift is reported, values aren't.
+const SECRET_LEAF =
+  /(password|passwd|secret|token|apikey|api_key|licensekey|accesskey|privatekey|servicekey|anonkey|pass)$/i;
+
+export const REDACTED_VALUE = "<redacted>";
+
+function isPlainObject(value: unknown): value is Record<string, unknown> {
+  return typeof value === "object" && value !== null && !Array.isArray(value);
+}
+
+function flatten(
+  value: unknown,
+  prefix: string,
+  out: Map<string, unknown>,
+): Map<string, unknown> {
+  if (isPlainObject(value)) {
+    const entries = Object.entries(value);
+    if (entries.length === 0 && prefix) out.set(prefix, {});
+    for (const [key, child] of entries) {
+      flatten(child, prefix ? `${prefix}.${key}` : key, out);
+    }
+  } else if (prefix) {
+    out.set(prefix, value);
+  }
+  return out;
+}
+
+function redact(path: string, value: unknown): unknown {
+  if (value === undefined) return undefined;
+  return SECRET_LEAF.test(path.split(".").pop() ?? "") ? REDACTED_VALUE : value;
+}
+
+/**
+ * Leaf-by-leaf differences between the release's user-supplied values
+ * (`helm get values`) and the values the config generates now. Arrays compare
+ * as a whole. Credential values are redacted in the result.
+ */
+export function diffValues(
+  live: Record<string, unknown>,
+  desired: Record<string, unknown>,
+): ValueDrift[] {
+  const liveLeaves = flatten(live, "", new Map());
+  const desiredLeaves = flatten(desired, "", new Map());
+  const paths = [...new Set([...liveLeaves.keys(), ...desiredLeaves.keys()])];
+  const drift: ValueDrift[] = [];
+  for (const path of paths.sort()) {
+    const a = liveLeaves.get(path);
+    const b = desiredLeaves.get(path);
+    // null and absent render the same in a chart.
+    if ((a ?? null) === null && (b ?? null) === null) continue;
+    if (JSON.stringify(a) === JSON.stringify(b)) continue;
+    drift.push({ path, live: redact(path, a), desired: redact(path, b) });
+  }
+  return drift;
+}
+
+function toWorkloadSpec(resource: Record<string, any>): WorkloadSpec | null {
+  if (!WORKLOAD_KINDS.has(resource?.kind)) return null;
+  const podSpec = resource.spec?.template?.spec ?? {};
+  const containers = [
+    ...(podSpec.initContainers ?? []),
+    ...(podSpec.containers ?? []),
+  ] as Array<{ image?: string }>;
+  return {
+    kind: resource.kind,
+    name: resource.metadata?.name ?? "",
+    replicas:
+      typeof resource.spec?.replicas === "number"
+        ? resource.spec.replicas
+        : undefined,
+    images: containers
+      .map((c) => c.image)
+      .filter((image): image is string => Boolean(image))
+      .sort(),
+  };
+}
+
+/** Workloads in `kubectl get ... -o json` output (a List). */
+export function parseLiveWorkloads(raw: string): WorkloadSpec[] {
+  const list = JSON.parse(raw || "{}") as { items?: Record<string, any>[] };
+  return (list.items ?? [])
+    .map(toWorkloadSpec)
+    .filter((w): w is WorkloadSpec => w !== null);
+}
+
+/**
+ * Workloads in rendered manifests. Replicas of anything an HPA or KEDA
+ * ScaledObject targets are dropped: the live count is the scaler's call.
+ */
+export function parseRenderedWorkloads(manifests: string): WorkloadSpec[] {
+  const resources = yaml
+    .parseAllDocuments(manifests)
+    .map((doc) => doc.toJS() as Record<string, any> | null)
+    .filter((r): r is Record<string, any> => isPlainObject(r));
+  const scaled = new Set(
+    resources
+      .filter(
+        (r) =>
+          r.kind === "HorizontalPodAutoscaler" || r.kind === "ScaledObject",
+      )
+      .map((r) => r.spec?.scaleTargetRef?.name)
+      .filter(Boolean),
+  );
+  return resources
+    .map(toWorkloadSpec)
+    .filter((w): w is WorkloadSpec => w !== null)
+    .map((w) => (scaled.has(w.name) ? { ...w, replicas: undefined } : w));
+}
+
+export function diffWorkloads(
+  live: WorkloadSpec[],
+  desired: WorkloadSpec[],
+): WorkloadDrift[] {
+  const key = (w: WorkloadSpec) => `${w.kind}/${w.name}`;
+  const liveByKey = new Map(live.map((w) => [key(w), w]));
+  const desiredKeys = new Set(desired.map(key));
+  const drift: WorkloadDrift[] = [];
+
+  for (const want of desired) {
+    const have = liveByKey.get(key(want));
+    if (!have) {
+      drift.push({ kind: want.kind, name: want.name, change: "missing" });
+      continue;
+    }
+    if (want.replicas !== undefined && have.replicas !== want.replicas) {
+      drift.push({
+        kind: want.kind,
+        name: want.name,
+        change: "replicas",
+        live: String(have.replicas ?? "-"),
+        desired: String(want.replicas),
+      });
+    }
+    if (want.images.join(",") !== have.images.join(",")) {
+      drift.push({
+        kind: want.kind,
+        name: want.name,
+        change: "images",
+        live: have.images.join(", "),
+        desired: want.images.join(", "),
+      });
+    }
+  }
+  for (const have of live) {
+    if (!desiredKeys.has(key(have))) {
+      drift.push({ kind: have.kind, name: have.name, change: "extra" });
+    }
+  }
+  return drift;
+}
+
+export function buildDriftReport(input: {
+  release: string;
+  namespace: string;
+  installedChartVersion: string | null;
+  desiredChartVersion?: string;
+  liveValues: Record<string, unknown> | null;
+  desiredValues: Record<string, unknown>;
+  liveWorkloads: WorkloadSpec[];
+  desiredWorkloads: WorkloadSpec[];
+}): DriftReport {
+  const installed = input.installedChartVersion !== null;
+  // "latest" can't be compared; only a pinned version can drift.
+  const chart =
+    input.desiredChartVersion &&
+    input.installedChartVersion !== input.desiredChartVersion
+      ? {
+          live: input.installedChartVersion,
+          desired: input.desiredChartVersion,
+        }
+      : null;
+  const values = diffValues(input.liveValues ?? {}, input.desiredValues);
+  const workloads = diffWorkloads(input.liveWorkloads, input.desiredWorkloads);
+  return {
+    release: input.release,
+    namespace: input.namespace,
+    installed,
+    chart,
+    values,
+    workloads,
+    drifted:
+      !installed ||
+      chart !== null ||
+      values.length > 0 ||
+      workloads.length > 0,
+  };
+}
+
+/** A drifted value as shown in reports; unset leaves read "(unset)". */
+export function formatDriftValue(value: unknown): string {
+  if (value === undefined) return "(unset)";
+  return typeof value === "string" ? value : JSON.stringify(value);
+}
@@ -9,15 +9,9 @@ import {
 import { buildDeploymentSecrets } from "./secrets.js";
 import { deploymentSecretNames } from "./helmValues.js";
 import { secretModeForConfig } from "./deploySequence.js";
-import { buildConfigMatrix } from "./configFixtures.js";
+import { configFixture } from "./configFixtures.js";
 import { DeploymentConfig } from "../types/index.js";
 
-function fixture(name: string): DeploymentConfig {
-  const found = buildConfigMatrix().find((c) => c.name === name);
-  assert.ok(found, `fixture ${name} exists`);
-  return structuredClone(found!.config);
-}
-
 function withBackend(
   config: DeploymentConfig,
   secrets: DeploymentConfig["secrets"],
@@ -26,7 +20,7 @@ function withBackend(
 }
 
 test("secretModeForConfig: cluster/absent -> k8s, everything else -> eso", () => {
-  const base = fixture("aws-self-hosted-minimal");
+  const base = configFixture("aws-self-hosted-minimal");
   assert.equal(secretModeForConfig(base), "k8s");
   assert.equal(
     secretModeForConfig(withBackend(base, { backend: "cluster" })),
@@ -44,7 +38,7 @@ test("secretModeForConfig: cluster/absent -> k8s, everything else -> eso", () =>
 });
 
 test("eso entries mirror buildDeploymentSecrets exactly (same Secrets, same keys)", () => {
-  const config = withBackend(fixture("aws-all-features"), {
+  const config = withBackend(configFixture("aws-all-features"), {
     backend: "aws-secrets-manager",
     aws: { roleArn: "arn:aws:iam::1:role/x" },
   });
@@ -67,7 +61,7 @@ test("eso entries mirror buildDeploymentSecrets exactly (same Secrets, same keys
 });
 
 test("provider entry names: AWS uses / paths; Azure/GCP never contain /", () => {
-  const base = fixture("aws-self-hosted-minimal");
+  const base = configFixture("aws-self-hosted-minimal");
 
   const aws = esoSecretEntries(
     withBackend(base, { backend: "aws-secrets-manager" }),
@@ -90,7 +84,7 @@ test("provider entry names: AWS uses / paths; Azure/GCP never contain /", () =>
 });
 
 test("ExternalSecret targets are exactly the chart's secretRef names", () => {
-  const config = withBackend(fixture("aws-all-features"), {
+  const config = withBackend(configFixture("aws-all-features"), {
     backend: "aws-secrets-manager",
     aws: { roleArn: "arn:aws:iam::1:role/x" },
   });
@@ -124,7 +118,7 @@ test("ExternalSecret targets are exactly the chart's secretRef names", () => {
 });
 
 test("Azure manifests carry workload-identity SA + vault URL", () => {
-  const config = withBackend(fixture("azure-workload-identity"), {
+  const config = withBackend(configFixture("azure-workload-identity"), {
     backend: "azure-key-vault",
     azure: {
       vaultName: "acme-kv",
@@ -155,7 +149,7 @@ test("Azure manifests carry workload-identity SA + vault URL", () => {
 });
 
 test("byo-secret-store references the existing store and creates none", () => {
-  const config = withBackend(fixture("aws-self-hosted-minimal"), {
+  const config = withBackend(configFixture("aws-self-hosted-minimal"), {
     backend: "byo-secret-store",
     byo: { storeName: "corp-vault", storeKind: "ClusterSecretStore" },
   });
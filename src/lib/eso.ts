@@ -29,6 +29,7 @@
 
 import { execa } from "execa";
 import * as yaml from "yaml";
+import { withNetworkRetry } from "./networkRetry.js";
 import {
   DeploymentConfig,
   getNamespace,
@@ -230,30 +231,32 @@ export async function ensureEsoOperator(
     return { installed: false };
   }
   try {
-    await execa("helm", [
-      "upgrade",
-      "--install",
-      ESO_RELEASE_NAME,
-      "external-secrets",
-      "--repo",
-      ESO_HELM_REPO,
-      "--version",
-      ESO_CHART_VERSION,
-      "--namespace",
-      namespace,
-      "--create-namespace",
-      "--set",
-      "installCRDs=true",
-      "--set",
-      "scopedRBAC=true",
-      "--set",
-      "processClusterExternalSecret=false",
-      "--set",
-      "processClusterStore=false",
-      "--wait",
-      "--timeout",
-      "5m",
-    ]);
+    await withNetworkRetry(() =>
+      execa("helm", [
+        "upgrade",
+        "--install",
+        ESO_RELEASE_NAME,
+        "external-secrets",
+        "--repo",
+        ESO_HELM_REPO,
+        "--version",
+        ESO_CHART_VERSION,
+        "--namespace",
+        namespace,
+        "--create-namespace",
+        "--set",
+        "installCRDs=true",
+        "--set",
+        "scopedRBAC=true",
+        "--set",
+        "processClusterExternalSecret=false",
+        "--set",
+        "processClusterStore=false",
+        "--wait",
+        "--timeout",
+        "5m",
+      ]),
+    );
   } catch (error) {
     throw new Error(
       `Failed to install the External Secrets Operator (release ${ESO_RELEASE_NAME}): ${error instanceof Error ? error.message : String(error)}`,
@@ -417,6 +420,7 @@ export async function applyEsoManifests(
 interface ExternalSecretStatus {
   metadata?: { name?: string };
   status?: {
+    refreshTime?: string;
     conditions?: Array<{
       type?: string;
       status?: string;
@@ -429,16 +433,21 @@ interface ExternalSecretStatus {
 /**
  * Block until every deployment ExternalSecret reports Ready=True
  * (reason SecretSynced), so the Helm install never starts against missing
- * Secrets. Fails with the per-secret provider errors on timeout.
+ * Secrets. With `refreshedAfter`, a secret also has to have synced since
+ * then. Fails with the per-secret provider errors on timeout.
  */
 export async function waitForExternalSecrets(
   config: DeploymentConfig,
-  options: { timeoutSeconds?: number } = {},
+  options: { timeoutSeconds?: number; refreshedAfter?: Date } = {},
 ): Promise<void> {
   const namespace = getNamespace(config.name);
   const expected = esoSecretEntries(config).map((entry) => entry.k8sName);
   const timeoutSeconds = options.timeoutSeconds ?? 120;
   const deadline = Date.now() + timeoutSeconds * 1000;
+  // refreshTime has whole-second precision.
+  const refreshedAfter = options.refreshedAfter
+    ? Math.floor(options.refreshedAfter.getTime() / 1000) * 1000
+    : undefined;
 
   let pending = new Map<string, string>();
   while (Date.now() < deadline) {
@@ -467,6 +476,11 @@ export async function waitForExternalSecrets(
           name,
           ready?.message?.trim() || "no status yet (waiting for first sync)",
         );
+      } else if (
+        refreshedAfter !== undefined &&
+        !(Date.parse(item?.status?.refreshTime ?? "") >= refreshedAfter)
+      ) {
+        pending.set(name, "waiting for the requested refresh");
       }
     }
     if (pending.size === 0) return;
@@ -488,13 +502,42 @@ export async function waitForExternalSecrets(
   );
 }
 
+/**
+ * Makes ESO re-read the provider entries now instead of at its next hourly
+ * refresh (the force-sync annotation), and waits for every deployment
+ * ExternalSecret to have synced, so rotated values reach the Secrets
+ * before workloads restart.
+ */
+export async function forceExternalSecretsSync(
+  config: DeploymentConfig,
+  options: { timeoutSeconds?: number } = {},
+): Promise<void> {
+  const namespace = getNamespace(config.name);
+  const requestedAt = new Date();
+  for (const entry of esoSecretEntries(config)) {
+    await execa("kubectl", [
+      "annotate",
+      "externalsecret",
+      entry.k8sName,
+      "--namespace",
+      namespace,
+      `force-sync=${Math.floor(requestedAt.getTime() / 1000)}`,
+      "--overwrite",
+    ]);
+  }
+  await waitForExternalSecrets(config, {
+    timeoutSeconds: options.timeoutSeconds,
+    refreshedAfter: requestedAt,
+  });
+}
+
 /**
  * One-call ESO setup for the install sequence: seed, ensure operator, apply
  * manifests, and gate on the first sync.
  */
 export async function setupExternalSecrets(
   config: DeploymentConfig,
-  options: { overwriteSecrets: boolean },
+  options: { overwriteSecrets: boolean; syncTimeoutSeconds?: number },
 ): Promise<{ seeded: SeedSummary; operatorInstalled: boolean }> {
   const namespace = getNamespace(config.name);
   const seeded = await seedCloudSecrets(config, {
@@ -502,7 +545,9 @@ export async function setupExternalSecrets(
   });
   const { installed } = await ensureEsoOperator(namespace);
   await applyEsoManifests(config);
-  await waitForExternalSecrets(config);
+  await waitForExternalSecrets(config, {
+    timeoutSeconds: options.syncTimeoutSeconds,
+  });
   return { seeded, operatorInstalled: installed };
 }
 
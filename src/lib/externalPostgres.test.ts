@@ -0,0 +1,74 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  classifyPostgresProbeError,
+  externalPostgresCredentials,
+} from "./externalPostgres.js";
+import { configFixture } from "./configFixtures.js";
+
+const credentials = {
+  host: "db.example.com",
+  port: 5432,
+  database: "postgres",
+  user: "postgres",
+  password: "pw",
+};
+
+test("the probe uses the bootstrap master credentials", () => {
+  const config = configFixture("aws-external-postgres");
+  assert.deepEqual(externalPostgresCredentials(config), {
+    host: "db.cluster-xxxx.us-east-1.rds.amazonaws.com",
+    port: 5432,
+    database: "postgres",
+    user: "postgres",
+    password: "master-pw-change-me",
+  });
+
+  // Credentials in a pre-created secret can't be tested from here.
+  config.externalServices!.postgres!.external!.bootstrap!.secretRef = "db";
+  assert.equal(externalPostgresCredentials(config), null);
+
+  // Without the bootstrap the roles already exist; test the service role.
+  config.externalServices!.postgres!.external!.bootstrap = { enabled: false };
+  assert.equal(
+    externalPostgresCredentials(config)?.password,
+    config.database.supabaseDbPassword,
+  );
+
+  assert.equal(
+    externalPostgresCredentials(configFixture("aws-self-hosted-minimal")),
+    null,
+  );
+});
+
+test("probe failures separate bad credentials from an unreachable host", () => {
+  assert.deepEqual(
+    classifyPostgresProbeError(
+      'psql: error: connection to server at "db.example.com" (10.0.0.5), port 5432 failed: FATAL:  password authentication failed for user "postgres"',
+      credentials,
+    ),
+    {
+      status: "failed",
+      message:
+        'Cannot use postgres@db.example.com:5432/postgres: error: connection to server at "db.example.com" (10.0.0.5), port 5432 failed: FATAL:  password authentication failed for user "postgres"',
+    },
+  );
+
+  const denied = classifyPostgresProbeError(
+    "psql:<stdin>:1: ERROR:  permission denied for schema public",
+    credentials,
+  );
+  assert.equal(denied.status, "failed");
+  assert.match(
+    denied.status === "failed" ? denied.message : "",
+    /cannot create tables/,
+  );
+
+  assert.equal(
+    classifyPostgresProbeError(
+      'psql: error: connection to server at "db.example.com" (10.0.0.5), port 5432 failed: timeout expired',
+      credentials,
+    ).status,
+    "unreachable",
+  );
+});
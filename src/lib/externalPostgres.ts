@@ -0,0 +1,142 @@
+import { execa } from "execa";
+import type { DeploymentConfig } from "../types/index.js";
+
+/**
+ * Deploy preflight for external Postgres: connects with the credentials the
+ * chart's bootstrap hook will use, runs `SELECT 1`, and checks the user may
+ * create tables (inside a rolled-back transaction, so nothing is left
+ * behind). A wrong password or missing privilege otherwise only shows up as
+ * crashlooping Supabase pods. Runs the local `psql`; a database that is only
+ * reachable from inside the cluster can't be judged from here, so network
+ * failures (and a missing psql) never block the deploy.
+ */
+
+export type ExternalPostgresCheck =
+  | { status: "ok" }
+  | { status: "failed"; message: string }
+  | { status: "unreachable" | "skipped"; reason: string };
+
+export interface PostgresCredentials {
+  host: string;
+  port: number;
+  database: string;
+  user: string;
+  password: string;
+}
+
+// CREATE TABLE in a transaction that is always rolled back.
+const PROBE_SQL = [
+  "SELECT 1;",
+  "BEGIN;",
+  "CREATE TABLE rulebricks_preflight_probe (id integer);",
+  "ROLLBACK;",
+].join(" ");
+
+const NETWORK_ERRORS = [
+  /could not translate host name/i,
+  /timeout expired/i,
+  /Connection refused/i,
+  /Connection timed out/i,
+  /No route to host/i,
+  /Network is unreachable/i,
+  /Command timed out/i,
+];
+
+/**
+ * The credentials the deploy connects with: the bootstrap master user when
+ * the chart initializes the database, else the shared "postgres" service
+ * role. Null when they live in a pre-created secret the CLI can't read.
+ */
+export function externalPostgresCredentials(
+  config: DeploymentConfig,
+): PostgresCredentials | null {
+  const pg = config.externalServices?.postgres;
+  if (pg?.mode !== "external" || !pg.external?.host) return null;
+  const { host, port = 5432, database = "postgres", bootstrap } = pg.external;
+  if (bootstrap?.enabled !== false) {
+    if (bootstrap?.secretRef || !bootstrap?.masterPassword) return null;
+    return {
+      host,
+      port,
+      database,
+      user: bootstrap.masterUsername || "postgres",
+      password: bootstrap.masterPassword,
+    };
+  }
+  if (!config.database.supabaseDbPassword) return null;
+  return {
+    host,
+    port,
+    database,
+    user: "postgres",
+    password: config.database.supabaseDbPassword,
+  };
+}
+
+/** PGSSLMODE for the CLI's connections to the external database. */
+export function externalPostgresSslMode(config: DeploymentConfig): string {
+  return config.externalServices?.postgres?.external?.sslMode ?? "require";
+}
+
+/** Maps psql's failure output to a check result. */
+export function classifyPostgresProbeError(
+  output: string,
+  credentials: PostgresCredentials,
+): ExternalPostgresCheck {
+  const detail =
+    output
+      .split("\n")
+      .map((line) => line.replace(/^psql:\s*/, "").trim())
+      .find((line) => /error|fatal/i.test(line)) ?? output.trim();
+  const target = `${credentials.user}@${credentials.host}:${credentials.port}/${credentials.database}`;
+  if (NETWORK_ERRORS.some((pattern) => pattern.test(output))) {
+    return { status: "unreachable", reason: `${target}: ${detail}` };
+  }
+  if (/permission denied/i.test(output)) {
+    return {
+      status: "failed",
+      message: `${credentials.user} cannot create tables in ${credentials.database} (${detail}); the bootstrap and migrations need CREATE on the public schema.`,
+    };
+  }
+  return { status: "failed", message: `Cannot use ${target}: ${detail}` };
+}
+
+export async function checkExternalPostgres(
+  config: DeploymentConfig,
+): Promise<ExternalPostgresCheck> {
+  const credentials = externalPostgresCredentials(config);
+  if (!credentials) {
+    return {
+      status: "skipped",
+      reason: "no inline credentials to test with",
+    };
+  }
+  try {
+    await execa(
+      "psql",
+      ["-X", "-q", "-v", "ON_ERROR_STOP=1", "-c", PROBE_SQL],
+      {
+        env: {
+          PGHOST: credentials.host,
+          PGPORT: String(credentials.port),
+          PGDATABASE: credentials.database,
+          PGUSER: credentials.user,
+          PGPASSWORD: credentials.password,
+          PGSSLMODE: externalPostgresSslMode(config),
+          PGCONNECT_TIMEOUT: "10",
+        },
+        timeout: 30000,
+      },
+    );
+    return { status: "ok" };
+  } catch (error) {
+    const err = error as { code?: string; stderr?: string; message?: string };
+    if (err.code === "ENOENT") {
+      return { status: "skipped", reason: "psql is not installed" };
+    }
+    return classifyPostgresProbeError(
+      err.stderr || err.message || "",
+      credentials,
+    );
+  }
+}
@@ -0,0 +1,80 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { promises as fs } from "node:fs";
+import os from "node:os";
+import path from "node:path";
+import {
+  isStaleLock,
+  waitForFileUnlocked,
+  withFileLock,
+} from "./fileLock.js";
+
+async function scratch(): Promise<string> {
+  return fs.mkdtemp(path.join(os.tmpdir(), "rb-lock-test-"));
+}
+
+test("concurrent read-modify-writes under the lock are not lost", async () => {
+  const dir = await scratch();
+  const file = path.join(dir, "counter");
+  await fs.writeFile(file, "0");
+
+  await Promise.all(
+    Array.from({ length: 20 }, () =>
+      withFileLock(`${file}.lock`, async () => {
+        const value = Number(await fs.readFile(file, "utf-8"));
+        await new Promise((resolve) => setTimeout(resolve, 1));
+        await fs.writeFile(file, String(value + 1));
+      }),
+    ),
+  );
+
+  assert.equal(await fs.readFile(file, "utf-8"), "20");
+  await assert.rejects(fs.access(`${file}.lock`));
+});
+
+test("a lock left by a dead process is taken over", async () => {
+  const dir = await scratch();
+  const lock = path.join(dir, "state.yaml.lock");
+  // Pids are far below this on every platform the CLI supports.
+  await fs.writeFile(lock, "999999999\n");
+  assert.equal(await isStaleLock(lock), true);
+
+  assert.equal(await withFileLock(lock, async () => "ran"), "ran");
+});
+
+test("waiters taking over a stale lock never hold it together", async () => {
+  const dir = await scratch();
+  const lock = path.join(dir, "state.yaml.lock");
+  await fs.writeFile(lock, "999999999\n");
+
+  let holders = 0;
+  let overlapped = false;
+  await Promise.all(
+    Array.from({ length: 50 }, () =>
+      withFileLock(lock, async () => {
+        holders++;
+        overlapped ||= holders > 1;
+        await new Promise((resolve) => setTimeout(resolve, 5));
+        holders--;
+      }),
+    ),
+  );
+
+  assert.equal(overlapped, false);
+  await assert.rejects(fs.access(`${lock}.takeover`));
+});
+
+test("readers wait for a live lock, then time out clearly", async () => {
+  const dir = await scratch();
+  const lock = path.join(dir, "state.yaml.lock");
+  await fs.writeFile(lock, `${process.pid}\n`);
+  assert.equal(await isStaleLock(lock), false);
+
+  await assert.rejects(
+    waitForFileUnlocked(lock, { timeoutMs: 100, retryMs: 10 }),
+    /another rulebricks command is using this deployment/,
+  );
+
+  setTimeout(() => fs.rm(lock), 30);
+  await waitForFileUnlocked(lock, { timeoutMs: 2000, retryMs: 10 });
+});
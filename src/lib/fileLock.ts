@@ -0,0 +1,164 @@
+import { promises as fs } from "fs";
+
+/**
+ * Advisory locks for the files under ~/.rulebricks, so two commands running
+ * at once (a `status --watch` during a `deploy`) can't interleave their
+ * read-modify-write of the same state.yaml.
+ *
+ * A lock is a `<file>.lock` created with O_EXCL and holding the owner's pid.
+ * Writers hold it for the few milliseconds of a read-modify-write; readers
+ * only wait for it to be released (a shared lock: readers never block one
+ * another). A lock whose owner has exited, or that is older than `staleMs`,
+ * was left by a crash and is taken over, one process at a time: see
+ * removeStaleLock.
+ */
+
+export interface FileLockOptions {
+  /** Give up waiting after this long. */
+  timeoutMs?: number;
+  /** Treat a lock older than this as abandoned. */
+  staleMs?: number;
+  retryMs?: number;
+}
+
+const DEFAULT_TIMEOUT_MS = 30000;
+const DEFAULT_STALE_MS = 60000;
+const DEFAULT_RETRY_MS = 50;
+
+function errorCode(error: unknown): string | undefined {
+  return (error as NodeJS.ErrnoException)?.code;
+}
+
+function isProcessAlive(pid: number): boolean {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    // EPERM: the process exists but belongs to someone else.
+    return errorCode(error) === "EPERM";
+  }
+}
+
+/** Whether an existing lock was left behind by a process that is gone. */
+export async function isStaleLock(
+  lockPath: string,
+  staleMs: number = DEFAULT_STALE_MS,
+): Promise<boolean> {
+  try {
+    const stat = await fs.stat(lockPath);
+    if (Date.now() - stat.mtimeMs > staleMs) return true;
+    const pid = Number.parseInt(await fs.readFile(lockPath, "utf-8"), 10);
+    return Number.isInteger(pid) && pid > 0 && !isProcessAlive(pid);
+  } catch (error) {
+    // Released between our attempt and this check: not stale, just gone.
+    if (errorCode(error) === "ENOENT") return false;
+    throw error;
+  }
+}
+
+/**
+ * Removes a stale lock so the caller can retry. Two waiters can both find
+ * the same lock stale; if both removed it, the slower one would delete the
+ * fresh lock the faster one had just created, and both would hold it. So a
+ * takeover runs under `<lock>.takeover` (itself created with O_EXCL) and
+ * re-checks the lock there. False when another process is taking over.
+ */
+async function removeStaleLock(
+  lockPath: string,
+  staleMs: number,
+): Promise<boolean> {
+  const guardPath = `${lockPath}.takeover`;
+  try {
+    await (await fs.open(guardPath, "wx", 0o600)).close();
+  } catch (error) {
+    if (errorCode(error) !== "EEXIST") throw error;
+    // A guard is held for milliseconds; an old one was left by a crash.
+    const guard = await fs.stat(guardPath).catch(() => null);
+    if (guard && Date.now() - guard.mtimeMs > staleMs) {
+      await fs.rm(guardPath, { force: true });
+    }
+    return false;
+  }
+  try {
+    if (await isStaleLock(lockPath, staleMs)) {
+      await fs.rm(lockPath, { force: true });
+    }
+    return true;
+  } finally {
+    await fs.rm(guardPath, { force: true });
+  }
+}
+
+function timeoutError(lockPath: string): Error {
+  return new Error(
+    `Timed out waiting for ${lockPath}; another rulebricks command is using this deployment. If none is running, delete the lock file.`,
+  );
+}
+
+/** Runs `fn` holding the exclusive lock at `lockPath`. */
+export async function withFileLock<T>(
+  lockPath: string,
+  fn: () => Promise<T>,
+  options: FileLockOptions = {},
+): Promise<T> {
+  const {
+    timeoutMs = DEFAULT_TIMEOUT_MS,
+    staleMs = DEFAULT_STALE_MS,
+    retryMs = DEFAULT_RETRY_MS,
+  } = options;
+  const deadline = Date.now() + timeoutMs;
+
+  for (;;) {
+    try {
+      const handle = await fs.open(lockPath, "wx", 0o600);
+      try {
+        await handle.writeFile(`${process.pid}\n`);
+      } finally {
+        await handle.close();
+      }
+      break;
+    } catch (error) {
+      if (errorCode(error) !== "EEXIST") throw error;
+    }
+    if (
+      (await isStaleLock(lockPath, staleMs)) &&
+      (await removeStaleLock(lockPath, staleMs))
+    ) {
+      continue;
+    }
+    if (Date.now() >= deadline) throw timeoutError(lockPath);
+    await new Promise((resolve) => setTimeout(resolve, retryMs));
+  }
+
+  try {
+    return await fn();
+  } finally {
+    await fs.rm(lockPath, { force: true });
+  }
+}
+
+/**
+ * Waits until no writer holds `lockPath`, for reads that must not see a
+ * write in progress. A stale lock doesn't hold readers up.
+ */
+export async function waitForFileUnlocked(
+  lockPath: string,
+  options: FileLockOptions = {},
+): Promise<void> {
+  const {
+    timeoutMs = DEFAULT_TIMEOUT_MS,
+    staleMs = DEFAULT_STALE_MS,
+    retryMs = DEFAULT_RETRY_MS,
+  } = options;
+  const deadline = Date.now() + timeoutMs;
+  for (;;) {
+    try {
+      await fs.access(lockPath);
+    } catch {
+      return;
+    }
+    if (await isStaleLock(lockPath, staleMs)) return;
+    if (Date.now() >= deadline) throw timeoutError(lockPath);
+    await new Promise((resolve) => setTimeout(resolve, retryMs));
+  }
+}
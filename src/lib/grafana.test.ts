@@ -0,0 +1,40 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  grafanaPortForwardCommand,
+  maskSecret,
+  parseGrafanaAdminSecret,
+} from "./grafana.js";
+
+const b64 = (value: string) => Buffer.from(value).toString("base64");
+
+test("the admin login comes from the secret that holds a password", () => {
+  const json = JSON.stringify({
+    items: [
+      { metadata: { name: "rulebricks-prod-grafana-config" }, data: {} },
+      {
+        metadata: { name: "rulebricks-prod-grafana" },
+        data: {
+          "admin-user": b64("admin"),
+          "admin-password": b64("s3cret!"),
+          "ldap-toml": "",
+        },
+      },
+    ],
+  });
+  assert.deepEqual(parseGrafanaAdminSecret(json), {
+    secretName: "rulebricks-prod-grafana",
+    username: "admin",
+    password: "s3cret!",
+  });
+  assert.equal(parseGrafanaAdminSecret(JSON.stringify({ items: [] })), null);
+});
+
+test("the password is masked and Grafana is reached by port-forward", () => {
+  assert.equal(maskSecret("s3cret!"), "********");
+  assert.equal(maskSecret(""), "");
+  assert.equal(
+    grafanaPortForwardCommand("rulebricks-prod", "rulebricks-prod"),
+    "kubectl port-forward -n rulebricks-prod svc/rulebricks-prod-grafana 3000:80",
+  );
+});
@@ -0,0 +1,61 @@
+/**
+ * `rulebricks monitoring credentials`: the admin login of the in-cluster
+ * Grafana (features.monitoring.destination "local-grafana"). The chart keeps
+ * it in the Grafana subchart's Secret, so the cluster is the source of truth
+ * and nothing has to survive in local state. Grafana has no ingress; it is
+ * reached with a port-forward to its Service.
+ */
+
+export interface GrafanaCredentials {
+  secretName: string;
+  username: string;
+  password: string;
+}
+
+interface SecretList {
+  items?: Array<{
+    metadata?: { name?: string };
+    data?: Record<string, string>;
+  }>;
+}
+
+/**
+ * Picks the admin login out of `kubectl get secret -l
+ * app.kubernetes.io/name=grafana -o json`. Null when no listed Secret holds
+ * an admin password.
+ */
+export function parseGrafanaAdminSecret(
+  json: string,
+): GrafanaCredentials | null {
+  const list = JSON.parse(json) as SecretList;
+  for (const item of list.items ?? []) {
+    const password = item.data?.["admin-password"];
+    if (!password) continue;
+    const decode = (value: string) =>
+      Buffer.from(value, "base64").toString("utf-8");
+    return {
+      secretName: item.metadata?.name ?? "grafana",
+      username: decode(item.data?.["admin-user"] ?? "") || "admin",
+      password: decode(password),
+    };
+  }
+  return null;
+}
+
+/** Grafana's Service: the subchart is named after the release. */
+export function grafanaServiceName(releaseName: string): string {
+  return `${releaseName}-grafana`;
+}
+
+/** The command that makes Grafana reachable on http://localhost:3000. */
+export function grafanaPortForwardCommand(
+  namespace: string,
+  releaseName: string,
+): string {
+  return `kubectl port-forward -n ${namespace} svc/${grafanaServiceName(releaseName)} 3000:80`;
+}
+
+/** Hides a secret's value, length included. */
+export function maskSecret(value: string): string {
+  return value ? "********" : "";
+}
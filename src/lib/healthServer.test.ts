@@ -0,0 +1,94 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  createOperationTracker,
+  formatMetrics,
+  parseHealthPort,
+  startHealthServer,
+} from "./healthServer.js";
+
+const at = (second: number) =>
+  new Date(Date.UTC(2026, 0, 1, 0, 0, second)).toISOString();
+
+test("tracker follows the current step and the operation result", () => {
+  const tracker = createOperationTracker("deploy");
+  tracker.record({ step: "preflight", status: "started", timestamp: at(1) });
+  assert.equal(tracker.snapshot().currentStep, "preflight");
+
+  tracker.record({ step: "preflight", status: "completed", timestamp: at(2) });
+  tracker.record({ step: "helmInstall", status: "started", timestamp: at(3) });
+  tracker.record({
+    step: "deploy",
+    status: "failed",
+    detail: "timed out",
+    timestamp: at(4),
+  });
+
+  const snapshot = tracker.snapshot();
+  assert.equal(snapshot.result, "failed");
+  assert.equal(snapshot.detail, "timed out");
+  assert.equal(snapshot.currentStep, null);
+  assert.equal(snapshot.lastEventAt, at(4));
+  assert.deepEqual(snapshot.steps, {
+    preflight: "completed",
+    helmInstall: "started",
+  });
+});
+
+test("metrics expose step status and the last-event timestamp", () => {
+  const tracker = createOperationTracker("upgrade");
+  tracker.record({ step: "upgrading", status: "started", timestamp: at(5) });
+  const text = formatMetrics(tracker.snapshot());
+
+  assert.match(text, /rulebricks_operation_running\{operation="upgrade"\} 1/);
+  assert.match(
+    text,
+    /rulebricks_operation_step_status\{operation="upgrade",step="upgrading",status="started"\} 1/,
+  );
+  assert.match(
+    text,
+    new RegExp(
+      `rulebricks_operation_last_event_timestamp_seconds\\{operation="upgrade"\\} ${Date.parse(at(5)) / 1000}`,
+    ),
+  );
+});
+
+test("parseHealthPort rejects out-of-range ports", () => {
+  assert.equal(parseHealthPort("9090"), 9090);
+  assert.throws(() => parseHealthPort("0"));
+  assert.throws(() => parseHealthPort("http"));
+});
+
+test("healthz turns unhealthy once the operation fails", async () => {
+  const server = await startHealthServer(0, "deploy");
+  try {
+    const base = `http://127.0.0.1:${server.port}`;
+    assert.equal((await fetch(`${base}/healthz`)).status, 200);
+
+    server.record({
+      step: "deploy",
+      status: "failed",
+      detail: "boom",
+      timestamp: new Date().toISOString(),
+    });
+    const res = await fetch(`${base}/healthz`);
+    assert.equal(res.status, 503);
+    assert.match(await res.text(), /boom/);
+
+    const status = await (await fetch(`${base}/status`)).json();
+    assert.equal(status.result, "failed");
+  } finally {
+    await server.close();
+  }
+});
+
+test("the health server listens on loopback unless told otherwise", async () => {
+  const server = await startHealthServer(0, "deploy");
+  try {
+    assert.equal(server.host, "127.0.0.1");
+    const res = await fetch(`http://127.0.0.1:${server.port}/healthz`);
+    assert.equal(res.status, 200);
+  } finally {
+    await server.close();
+  }
+});
@@ -0,0 +1,177 @@
+import http from "http";
+import type { ProgressEvent, ProgressEventStatus } from "./progress.js";
+
+/**
+ * `--health-port` for long-running commands (deploy, upgrade). A small HTTP
+ * server fed by the same progress events as `--progress plain|json`, so an
+ * orchestrator can follow along and spot a hung operation:
+ *   GET /         current snapshot as JSON (also /status)
+ *   GET /healthz  200 while running or completed, 503 once the operation fails
+ *   GET /metrics  Prometheus text; alert on the last-event timestamp going stale
+ * It listens on 127.0.0.1 unless `--health-host` names another address (say
+ * 0.0.0.0 inside a container whose port is published).
+ */
+
+export type OperationResult = "running" | "completed" | "failed";
+
+export interface OperationSnapshot {
+  operation: string;
+  result: OperationResult;
+  currentStep: string | null;
+  steps: Record<string, ProgressEventStatus>;
+  startedAt: string;
+  lastEventAt: string;
+  detail?: string;
+}
+
+export interface OperationTracker {
+  record(event: ProgressEvent): void;
+  snapshot(): OperationSnapshot;
+}
+
+export function createOperationTracker(operation: string): OperationTracker {
+  const startedAt = new Date().toISOString();
+  const state: OperationSnapshot = {
+    operation,
+    result: "running",
+    currentStep: null,
+    steps: {},
+    startedAt,
+    lastEventAt: startedAt,
+  };
+
+  return {
+    record(event) {
+      state.lastEventAt = event.timestamp;
+      if (event.step === operation) {
+        if (event.status === "completed") state.result = "completed";
+        if (event.status === "failed") state.result = "failed";
+        if (event.status !== "started") state.currentStep = null;
+        if (event.detail) state.detail = event.detail;
+        return;
+      }
+      state.steps[event.step] = event.status;
+      if (event.status === "started") {
+        state.currentStep = event.step;
+      } else if (state.currentStep === event.step) {
+        state.currentStep = null;
+      }
+    },
+    snapshot() {
+      return { ...state, steps: { ...state.steps } };
+    },
+  };
+}
+
+function seconds(iso: string): number {
+  return Math.floor(Date.parse(iso) / 1000);
+}
+
+/** Renders a snapshot in the Prometheus text exposition format. */
+export function formatMetrics(snapshot: OperationSnapshot): string {
+  const op = `operation="${snapshot.operation}"`;
+  const completed = Object.values(snapshot.steps).filter(
+    (status) => status === "completed" || status === "skipped",
+  ).length;
+  const lines = [
+    "# HELP rulebricks_operation_running Whether the operation is still running.",
+    "# TYPE rulebricks_operation_running gauge",
+    `rulebricks_operation_running{${op}} ${snapshot.result === "running" ? 1 : 0}`,
+    "# HELP rulebricks_operation_failed Whether the operation failed.",
+    "# TYPE rulebricks_operation_failed gauge",
+    `rulebricks_operation_failed{${op}} ${snapshot.result === "failed" ? 1 : 0}`,
+    "# HELP rulebricks_operation_steps_finished Steps completed or skipped so far.",
+    "# TYPE rulebricks_operation_steps_finished gauge",
+    `rulebricks_operation_steps_finished{${op}} ${completed}`,
+    "# HELP rulebricks_operation_step_status Current status of each step seen so far.",
+    "# TYPE rulebricks_operation_step_status gauge",
+    ...Object.entries(snapshot.steps).map(
+      ([step, status]) =>
+        `rulebricks_operation_step_status{${op},step="${step}",status="${status}"} 1`,
+    ),
+    "# HELP rulebricks_operation_start_timestamp_seconds When the operation started.",
+    "# TYPE rulebricks_operation_start_timestamp_seconds gauge",
+    `rulebricks_operation_start_timestamp_seconds{${op}} ${seconds(snapshot.startedAt)}`,
+    "# HELP rulebricks_operation_last_event_timestamp_seconds When the last progress event was seen.",
+    "# TYPE rulebricks_operation_last_event_timestamp_seconds gauge",
+    `rulebricks_operation_last_event_timestamp_seconds{${op}} ${seconds(snapshot.lastEventAt)}`,
+  ];
+  return `${lines.join("\n")}\n`;
+}
+
+/** Validates a --health-port value. */
+export function parseHealthPort(value: string): number {
+  const port = Number(value);
+  if (!Number.isInteger(port) || port < 1 || port > 65535) {
+    throw new Error(
+      `Invalid --health-port "${value}". Use a port from 1 to 65535.`,
+    );
+  }
+  return port;
+}
+
+export const DEFAULT_HEALTH_HOST = "127.0.0.1";
+
+export interface HealthServer {
+  /** Bound address. */
+  host: string;
+  /** Bound port (differs from the requested one only when that was 0). */
+  port: number;
+  /** Feed progress events here (pass as the command's onProgressEvent). */
+  record(event: ProgressEvent): void;
+  close(): Promise<void>;
+}
+
+export async function startHealthServer(
+  port: number,
+  operation: string,
+  host: string = DEFAULT_HEALTH_HOST,
+): Promise<HealthServer> {
+  const tracker = createOperationTracker(operation);
+
+  const server = http.createServer((req, res) => {
+    const path = (req.url ?? "/").split("?")[0];
+    const snapshot = tracker.snapshot();
+
+    if (req.method !== "GET") {
+      res.writeHead(405).end();
+    } else if (path === "/healthz") {
+      const healthy = snapshot.result !== "failed";
+      res
+        .writeHead(healthy ? 200 : 503, { "Content-Type": "text/plain" })
+        .end(healthy ? "ok\n" : `failed: ${snapshot.detail ?? "unknown"}\n`);
+    } else if (path === "/metrics") {
+      res
+        .writeHead(200, { "Content-Type": "text/plain; version=0.0.4" })
+        .end(formatMetrics(snapshot));
+    } else if (path === "/" || path === "/status") {
+      res
+        .writeHead(200, { "Content-Type": "application/json" })
+        .end(`${JSON.stringify(snapshot)}\n`);
+    } else {
+      res.writeHead(404).end();
+    }
+  });
+
+  await new Promise<void>((resolve, reject) => {
+    server.once("error", reject);
+    server.listen(port, host, () => {
+      server.off("error", reject);
+      resolve();
+    });
+  });
+
+  const address = server.address();
+  return {
+    host: typeof address === "object" && address ? address.address : host,
+    port: typeof address === "object" && address ? address.port : port,
+    record: (event) => tracker.record(event),
+    close: () =>
+      new Promise<void>((resolve) => {
+        // Drop idle keep-alive scrapers so close() doesn't wait on them
+        // (Node 18.2+).
+        server.closeAllConnections?.();
+        server.close(() => resolve());
+      }),
+  };
+}
@@ -1,6 +1,18 @@
 import test from "node:test";
 import assert from "node:assert/strict";
-import { parseGitHubReleases } from "./helm.js";
+import {
+  classifyReleaseOwnership,
+  helmOverrideArgs,
+  helmSupportsReleaseLabels,
+  parseDeployedRevision,
+  parseValueOverride,
+  parseGitHubReleases,
+  parseReleaseHistory,
+  previousServedRevision,
+  releasesBetween,
+  requiredUpgradeStops,
+  RELEASE_OWNER_LABEL,
+} from "./helm.js";
 import { deriveTlsEnabled } from "./helmValues.js";
 
 test("parses GitHub releases into chart versions, newest first", () => {
@@ -67,3 +79,143 @@ test("derives TLS state from values with sensible fallbacks", () => {
   assert.equal(deriveTlsEnabled({}), true);
   assert.equal(deriveTlsEnabled(null), true);
 });
+
+test("classifies release ownership from the release labels", () => {
+  const helmLabels = { name: "rulebricks-prod", owner: "helm", version: "3" };
+  assert.equal(classifyReleaseOwnership(null, false), "absent");
+  assert.equal(
+    classifyReleaseOwnership(
+      { ...helmLabels, [RELEASE_OWNER_LABEL]: "rulebricks-cli" },
+      false,
+    ),
+    "rulebricks",
+  );
+  assert.equal(classifyReleaseOwnership(helmLabels, false), "foreign");
+  assert.equal(
+    classifyReleaseOwnership(
+      { ...helmLabels, [RELEASE_OWNER_LABEL]: "argocd" },
+      true,
+    ),
+    "foreign",
+  );
+});
+
+test("release labels are only used with helm 3.13 or newer", () => {
+  assert.equal(helmSupportsReleaseLabels("v3.13.0+g825e86f"), true);
+  assert.equal(helmSupportsReleaseLabels("v3.16.2+g13654a5"), true);
+  assert.equal(helmSupportsReleaseLabels("v3.12.3+g3a31588"), false);
+  assert.equal(helmSupportsReleaseLabels("not a version"), false);
+});
+
+test("unlabeled releases are ours when state shows a previous deploy", () => {
+  const helmLabels = { name: "rulebricks-prod", owner: "helm", version: "7" };
+  assert.equal(classifyReleaseOwnership(helmLabels, true), "rulebricks");
+});
+
+test("chart upgrades stop at the newest release of each skipped major", () => {
+  const tags = ["1.4.0", "1.5.0", "2.0.0", "2.3.1", "2.3.0", "3.0.0", "3.1.0"];
+  const available = parseGitHubReleases(
+    [...tags, "4.0.0"].map((v) => ({
+      tag_name: `v${v}`,
+      published_at: "2026-01-01T00:00:00Z",
+    })),
+  );
+  const stops = (from: string, to: string) =>
+    requiredUpgradeStops(from, to, available).map((v) => v.version);
+
+  assert.deepEqual(stops("1.4.0", "1.5.0"), []);
+  assert.deepEqual(stops("1.4.0", "2.3.1"), []);
+  assert.deepEqual(stops("1.4.0", "3.1.0"), ["2.3.1"]);
+  assert.deepEqual(stops("1.4.0", "4.0.0"), ["2.3.1", "3.1.0"]);
+  // Downgrades and reinstalls have nothing to stop at.
+  assert.deepEqual(stops("4.0.0", "1.4.0"), []);
+});
+
+test("0.x chart upgrades treat each minor as a breaking series", () => {
+  const available = parseGitHubReleases(
+    ["0.1.0", "0.2.0", "0.2.5", "0.3.0"].map((v) => ({
+      tag_name: `v${v}`,
+      published_at: "2026-01-01T00:00:00Z",
+    })),
+  );
+  assert.deepEqual(
+    requiredUpgradeStops("0.1.0", "0.3.0", available).map((v) => v.version),
+    ["0.2.5"],
+  );
+});
+
+test("release notes cover the releases after installed up to the target", () => {
+  const available = parseGitHubReleases([
+    { tag_name: "v2.0.0", published_at: "2026-01-01T00:00:00Z", body: "old" },
+    { tag_name: "v2.1.0", published_at: "2026-02-01T00:00:00Z", body: " New CRDs \n" },
+    { tag_name: "v2.2.0", published_at: "2026-03-01T00:00:00Z", body: "" },
+    { tag_name: "v2.3.0", published_at: "2026-04-01T00:00:00Z", body: "later" },
+  ]);
+  const between = releasesBetween("2.0.0", "2.2.0", available);
+  assert.deepEqual(
+    between.map((v) => v.version),
+    ["2.1.0", "2.2.0"],
+  );
+  assert.equal(between[0].notes, "New CRDs");
+  assert.equal(between[1].notes, undefined);
+});
+
+test("the deployed revision is the newest one helm marks deployed", () => {
+  const history = JSON.stringify([
+    { revision: 3, status: "superseded" },
+    { revision: 4, status: "deployed" },
+    { revision: 5, status: "failed" },
+  ]);
+  assert.equal(parseDeployedRevision(history), 4);
+  assert.equal(
+    parseDeployedRevision(JSON.stringify([{ revision: 1, status: "failed" }])),
+    null,
+  );
+  assert.equal(parseDeployedRevision("not json"), null);
+});
+
+test("rollback skips failed revisions to the last one that served", () => {
+  const history = parseReleaseHistory(
+    JSON.stringify([
+      { revision: 4, status: "deployed", chart: "stack-1.3.0" },
+      { revision: 3, status: "failed", chart: "stack-1.3.0-rc.1" },
+      { revision: 2, status: "superseded", chart: "stack-1.2.0" },
+      { revision: 1, status: "superseded", chart: "stack-1.1.0" },
+    ]),
+  );
+  assert.deepEqual(history.map((entry) => entry.revision), [1, 2, 3, 4]);
+  assert.equal(history[2].chartVersion, "1.3.0-rc.1");
+
+  const pair = previousServedRevision(history);
+  assert.equal(pair?.current.revision, 4);
+  assert.equal(pair?.target.revision, 2);
+  assert.equal(pair?.target.chartVersion, "1.2.0");
+
+  assert.equal(previousServedRevision(history.slice(2)), null);
+  assert.deepEqual(parseReleaseHistory("not json"), []);
+});
+
+test("--set overrides need a component.path key", () => {
+  assert.deepEqual(
+    parseValueOverride("rulebricks.hps.workers.keda.lagThreshold=100", "--set"),
+    { path: "rulebricks.hps.workers.keda.lagThreshold", value: "100" },
+  );
+  // Values may contain "=" and be empty; list indexes and escaped dots pass.
+  assert.equal(parseValueOverride("a.b=x=y", "--set").value, "x=y");
+  assert.equal(parseValueOverride("a.b=", "--set").value, "");
+  parseValueOverride("vector.env[0].value=1", "--set");
+  parseValueOverride("ingress.annotations.nginx\\.io/ssl=true", "--set");
+
+  assert.throws(() => parseValueOverride("replicas", "--set"), /--set/);
+  assert.throws(() => parseValueOverride("=3", "--set"), /component\.path/);
+  assert.throws(() => parseValueOverride("a..b=1", "--set"));
+  assert.throws(() => parseValueOverride("a.b=", "--set-file"), /file path/);
+});
+
+test("overrides follow values.yaml as helm arguments", () => {
+  assert.deepEqual(helmOverrideArgs(undefined), []);
+  assert.deepEqual(
+    helmOverrideArgs({ set: ["a.b=1", "c=2"], setFile: ["d.e=/tmp/f"] }),
+    ["--set", "a.b=1", "--set", "c=2", "--set-file", "d.e=/tmp/f"],
+  );
+});
@@ -1,6 +1,10 @@
+import { promises as fs } from "fs";
+import path from "path";
 import { execa, ExecaError } from "execa";
 import { HELM_CHART_OCI, ChartVersion } from "../types/index.js";
 import { getHelmValuesPath } from "./config.js";
+import { withNetworkRetry } from "./networkRetry.js";
+import { createTempDir, removeTempPath } from "./tempFiles.js";
 
 /**
  * Extracts meaningful error message from execa error
@@ -37,13 +41,44 @@ export async function getHelmVersion(): Promise<string> {
   return stdout.trim();
 }
 
+/** Helm added release labels (`--labels`, `helm get metadata`) in 3.13. */
+const RELEASE_LABELS_MIN_HELM = "3.13.0";
+
+/** Whether a `helm version --short` output supports release labels. */
+export function helmSupportsReleaseLabels(helmVersion: string): boolean {
+  const version = helmVersion.match(/(\d+\.\d+(?:\.\d+)?)/)?.[1];
+  return (
+    version !== undefined &&
+    compareChartVersions(version, RELEASE_LABELS_MIN_HELM) >= 0
+  );
+}
+
+let releaseLabelsSupported: Promise<boolean> | undefined;
+
+/** Detected once per process; an unreadable version counts as too old. */
+function releaseLabelsAvailable(): Promise<boolean> {
+  releaseLabelsSupported ??= getHelmVersion()
+    .then(helmSupportsReleaseLabels)
+    .catch(() => false);
+  return releaseLabelsSupported;
+}
+
+/** `--labels` marking the release as ours, when the local helm has it. */
+async function ownerLabelArgs(): Promise<string[]> {
+  return (await releaseLabelsAvailable())
+    ? ["--labels", `${RELEASE_OWNER_LABEL}=${RELEASE_OWNER_VALUE}`]
+    : [];
+}
+
 /**
  * Fetches available chart versions from the OCI registry
  */
 export async function fetchChartVersions(): Promise<ChartVersion[]> {
   try {
     // Use helm show chart to get info about the latest version
-    const { stdout } = await execa("helm", ["show", "chart", HELM_CHART_OCI]);
+    const { stdout } = await withNetworkRetry(() =>
+      execa("helm", ["show", "chart", HELM_CHART_OCI]),
+    );
 
     // Parse the chart info
     const lines = stdout.split("\n");
@@ -68,6 +103,15 @@ export async function fetchChartVersions(): Promise<ChartVersion[]> {
   }
 }
 
+/**
+ * The newest chart version the registry serves, or undefined when it can't
+ * be told (offline, or no parsable version).
+ */
+export async function fetchLatestChartVersion(): Promise<string | undefined> {
+  const [latest] = await fetchChartVersions();
+  return latest && latest.version !== "unknown" ? latest.version : undefined;
+}
+
 /**
  * Compares two semver-ish strings (local copy: versions.ts imports this
  * module, so helm.ts cannot import compareVersions from there).
@@ -92,7 +136,14 @@ export function parseGitHubReleases(payload: unknown): ChartVersion[] {
   if (!Array.isArray(payload)) return [];
   return payload
     .filter(
-      (r): r is { tag_name: string; published_at: string; prerelease?: boolean } =>
+      (
+        r,
+      ): r is {
+        tag_name: string;
+        published_at: string;
+        prerelease?: boolean;
+        body?: unknown;
+      } =>
         !!r &&
         typeof r === "object" &&
         typeof (r as { tag_name?: unknown }).tag_name === "string" &&
@@ -104,10 +155,73 @@ export function parseGitHubReleases(payload: unknown): ChartVersion[] {
       appVersion: r.tag_name.replace(/^v/, ""),
       created: r.published_at,
       digest: "",
+      ...(typeof r.body === "string" && r.body.trim()
+        ? { notes: r.body.trim() }
+        : {}),
     }))
     .sort((a, b) => compareChartVersions(b.version, a.version));
 }
 
+/**
+ * The breaking-change series a chart version belongs to: its major, or
+ * major.minor while the major is 0 (semver's "anything may change").
+ */
+function chartSeries(version: string): string {
+  const [major = "0", minor = "0"] = version.replace(/^v/, "").split(".");
+  return major === "0" ? `0.${minor}` : major;
+}
+
+/**
+ * Releases an upgrade from `installed` to `target` must stop at: the newest
+ * release of every series strictly between the two. Each series may ship
+ * migrations that assume the previous one ran, so a jump straight past one
+ * is refused (unless forced). Empty for upgrades into the next series.
+ */
+export function requiredUpgradeStops(
+  installed: string,
+  target: string,
+  available: ChartVersion[],
+): ChartVersion[] {
+  const from = chartSeries(installed);
+  const to = chartSeries(target);
+  const newestPerSeries = new Map<string, ChartVersion>();
+  for (const release of available) {
+    if (
+      compareChartVersions(release.version, installed) <= 0 ||
+      compareChartVersions(release.version, target) >= 0
+    ) {
+      continue;
+    }
+    const series = chartSeries(release.version);
+    if (series === from || series === to) continue;
+    const newest = newestPerSeries.get(series);
+    if (!newest || compareChartVersions(release.version, newest.version) > 0) {
+      newestPerSeries.set(series, release);
+    }
+  }
+  return [...newestPerSeries.values()].sort((a, b) =>
+    compareChartVersions(a.version, b.version),
+  );
+}
+
+/**
+ * Releases after `installed` up to and including `target`, oldest first:
+ * whose notes an upgrade should show before it proceeds.
+ */
+export function releasesBetween(
+  installed: string,
+  target: string,
+  available: ChartVersion[],
+): ChartVersion[] {
+  return available
+    .filter(
+      (release) =>
+        compareChartVersions(release.version, installed) > 0 &&
+        compareChartVersions(release.version, target) <= 0,
+    )
+    .sort((a, b) => compareChartVersions(a.version, b.version));
+}
+
 /**
  * Fetches versions from GitHub releases API
  */
@@ -160,6 +274,60 @@ export async function getReleaseComputedValues(
   }
 }
 
+/**
+ * Gets a release's USER-SUPPLIED values (what the last install/upgrade passed
+ * with -f, or the given revision's) as JSON. Returns null when the release
+ * does not exist or helm fails.
+ */
+export async function getReleaseUserValues(
+  releaseName: string,
+  namespace: string,
+  revision?: number,
+): Promise<Record<string, unknown> | null> {
+  try {
+    const { stdout } = await execa(
+      "helm",
+      [
+        "get",
+        "values",
+        releaseName,
+        "-n",
+        namespace,
+        "-o",
+        "json",
+        ...(revision ? ["--revision", String(revision)] : []),
+      ],
+      { timeout: 30000 },
+    );
+    // helm prints "null" for a release installed without values.
+    return (JSON.parse(stdout) as Record<string, unknown> | null) ?? {};
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Lists the Rulebricks stack releases (rulebricks-<name>) across all
+ * namespaces, from `helm list -A`. Throws when helm fails.
+ */
+export async function listRulebricksReleases(): Promise<
+  Array<{ name: string; namespace: string; chart: string }>
+> {
+  const { stdout } = await execa("helm", ["list", "-A", "-o", "json"], {
+    timeout: 30000,
+  });
+  const releases = JSON.parse(stdout || "[]") as Array<{
+    name: string;
+    namespace: string;
+    chart: string;
+  }>;
+  return releases
+    .filter(
+      (r) => r.name.startsWith("rulebricks-") && /^stack-\d/.test(r.chart),
+    )
+    .map(({ name, namespace, chart }) => ({ name, namespace, chart }));
+}
+
 /**
  * Gets the currently installed chart version for a deployment
  */
@@ -274,9 +442,64 @@ export async function installChart(
   }
 }
 
+/**
+ * Helm release label (Helm >= 3.13 `--labels`) marking releases this CLI
+ * installed, so a deploy never takes over a same-named release it doesn't own.
+ */
+export const RELEASE_OWNER_LABEL = "app.kubernetes.io/managed-by";
+export const RELEASE_OWNER_VALUE = "rulebricks-cli";
+
+export type ReleaseOwnership = "absent" | "rulebricks" | "foreign";
+
+/**
+ * Decides who owns an existing release from its storage labels (null when no
+ * release exists). Releases installed before the owner label was added carry
+ * none, so an unlabeled release counts as ours when state shows this CLI
+ * already deployed the deployment.
+ */
+export function classifyReleaseOwnership(
+  labels: Record<string, string> | null,
+  deployedByCli: boolean,
+): ReleaseOwnership {
+  if (!labels) return "absent";
+  if (labels[RELEASE_OWNER_LABEL] === RELEASE_OWNER_VALUE) return "rulebricks";
+  if (!labels[RELEASE_OWNER_LABEL] && deployedByCli) return "rulebricks";
+  return "foreign";
+}
+
+/**
+ * Reads the labels of a release's latest revision with `helm get metadata`,
+ * which works with every storage driver. Returns null when the release does
+ * not exist. A helm older than 3.13 can neither set nor read labels, so an
+ * existing release reads as unlabeled there.
+ */
+export async function getReleaseLabels(
+  releaseName: string,
+  namespace: string,
+): Promise<Record<string, string> | null> {
+  const args = (await releaseLabelsAvailable())
+    ? ["get", "metadata", releaseName, "--namespace", namespace, "-o", "json"]
+    : ["status", releaseName, "--namespace", namespace, "-o", "json"];
+  let stdout: string;
+  try {
+    ({ stdout } = await execa("helm", args, { timeout: 30000 }));
+  } catch (error) {
+    if (/release: not found/.test(getErrorMessage(error))) return null;
+    throw new Error(
+      `Could not read Helm release ${releaseName}:\n${getErrorMessage(error)}`,
+    );
+  }
+  if (args[0] === "status") return {};
+  const metadata = JSON.parse(stdout) as { labels?: Record<string, string> };
+  return metadata.labels ?? {};
+}
+
 interface HelmHistoryEntry {
   revision?: number;
   status?: string;
+  chart?: string;
+  updated?: string;
+  description?: string;
 }
 
 /**
@@ -320,6 +543,209 @@ async function isReleaseStrandedBeforeFirstDeploy(
   }
 }
 
+/**
+ * The revision currently serving a release, from `helm history -o json`:
+ * the newest "deployed" entry. Null when nothing is deployed.
+ */
+export function parseDeployedRevision(historyJson: string): number | null {
+  try {
+    const entries = JSON.parse(historyJson) as HelmHistoryEntry[];
+    if (!Array.isArray(entries)) return null;
+    const deployed = entries
+      .filter((entry) => entry.status === "deployed" && entry.revision)
+      .map((entry) => entry.revision as number);
+    return deployed.length > 0 ? Math.max(...deployed) : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Reads the deployed revision before a change, so a failed upgrade can be
+ * rolled back to exactly it. Null when the release or its history is missing.
+ */
+export async function getDeployedRevision(
+  releaseName: string,
+  namespace: string,
+): Promise<number | null> {
+  try {
+    const { stdout } = await execa(
+      "helm",
+      ["history", releaseName, "--namespace", namespace, "--output", "json"],
+      { timeout: 30000 },
+    );
+    return parseDeployedRevision(stdout);
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Rolls a release back to a previous revision and waits for it to be ready.
+ */
+export async function rollbackRelease(
+  releaseName: string,
+  namespace: string,
+  revision: number,
+  timeout = "15m",
+): Promise<void> {
+  try {
+    await execa("helm", [
+      "rollback",
+      releaseName,
+      String(revision),
+      "--namespace",
+      namespace,
+      "--wait",
+      "--timeout",
+      timeout,
+    ]);
+  } catch (error) {
+    throw new Error(`Helm rollback failed:\n${getErrorMessage(error)}`);
+  }
+}
+
+/** One revision from `helm history`. */
+export interface ReleaseRevision {
+  revision: number;
+  status: string;
+  /** From the chart name, e.g. "stack-1.4.0" -> "1.4.0". */
+  chartVersion: string | null;
+  updated: string;
+  description: string;
+}
+
+export function parseReleaseHistory(historyJson: string): ReleaseRevision[] {
+  try {
+    const entries = JSON.parse(historyJson) as HelmHistoryEntry[];
+    if (!Array.isArray(entries)) return [];
+    return entries
+      .filter((entry) => entry.revision)
+      .map((entry) => ({
+        revision: entry.revision as number,
+        status: entry.status ?? "unknown",
+        chartVersion:
+          entry.chart?.match(/-(\d+\.\d+\.\d+\S*)$/)?.[1] ?? null,
+        updated: entry.updated ?? "",
+        description: entry.description ?? "",
+      }))
+      .sort((a, b) => a.revision - b.revision);
+  } catch {
+    return [];
+  }
+}
+
+/**
+ * What `upgrade rollback` moves between: the deployed revision and the last
+ * one that served before it. Failed revisions never served, so they are
+ * skipped. Null when there is nothing to go back to.
+ */
+export function previousServedRevision(
+  history: ReleaseRevision[],
+): { current: ReleaseRevision; target: ReleaseRevision } | null {
+  const deployed = history.filter((entry) => entry.status === "deployed");
+  const current = deployed[deployed.length - 1];
+  if (!current) return null;
+  const target = history
+    .filter(
+      (entry) =>
+        entry.revision < current.revision && entry.status === "superseded",
+    )
+    .pop();
+  return target ? { current, target } : null;
+}
+
+export async function getReleaseHistory(
+  releaseName: string,
+  namespace: string,
+): Promise<ReleaseRevision[]> {
+  try {
+    const { stdout } = await execa(
+      "helm",
+      ["history", releaseName, "--namespace", namespace, "--output", "json"],
+      { timeout: 30000 },
+    );
+    return parseReleaseHistory(stdout);
+  } catch (error) {
+    throw new Error(
+      `Failed to read the history of ${releaseName}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
+/**
+ * Ad-hoc values for a single deploy (`deploy --set` / `--set-file`), passed
+ * to helm after values.yaml so they win over it. Keys are paths into the
+ * umbrella chart's values, the first segment naming the component
+ * (rulebricks.hps.workers.keda.lagThreshold=100).
+ * They are not written to values.yaml: the next deploy without them reverts.
+ */
+export interface HelmValueOverrides {
+  set?: string[];
+  setFile?: string[];
+}
+
+// component.path, with helm's [n] list indexes and \. escaped dots.
+const VALUE_PATH = /^[\w-]+(\[\d+\])?(\.([\w/-]|\\\.)+(\[\d+\])?)*$/;
+
+/**
+ * Checks one `key=value` override before helm sees it. Throws naming the
+ * flag, so the user knows which argument to fix.
+ */
+export function parseValueOverride(
+  spec: string,
+  flag: "--set" | "--set-file",
+): { path: string; value: string } {
+  const eq = spec.indexOf("=");
+  const path = eq > 0 ? spec.slice(0, eq) : "";
+  if (!VALUE_PATH.test(path)) {
+    throw new Error(
+      `Invalid ${flag} "${spec}": expected component.path=value, e.g. rulebricks.hps.workers.keda.lagThreshold=100`,
+    );
+  }
+  const value = spec.slice(eq + 1);
+  if (flag === "--set-file" && !value) {
+    throw new Error(`Invalid ${flag} "${spec}": no file path after "="`);
+  }
+  return { path, value };
+}
+
+/** The helm arguments for a set of overrides, in the order given. */
+export function helmOverrideArgs(overrides?: HelmValueOverrides): string[] {
+  return [
+    ...(overrides?.set ?? []).flatMap((spec) => ["--set", spec]),
+    ...(overrides?.setFile ?? []).flatMap((spec) => ["--set-file", spec]),
+  ];
+}
+
+/**
+ * Downloads the chart archive into a private temp directory, retrying
+ * registry network errors, and returns its path. Install and upgrade run
+ * against this local copy so only the fetch is retried: rerunning a
+ * `helm upgrade --wait` that failed partway would apply it twice. Release
+ * it with removeTempPath(path.dirname(chart)).
+ */
+async function pullChart(version?: string): Promise<string> {
+  const dir = await createTempDir("rb-chart-");
+  const args = ["pull", HELM_CHART_OCI, "--destination", dir];
+  if (version) {
+    args.push("--version", version);
+  }
+  try {
+    await withNetworkRetry(() => execa("helm", args, { timeout: 120000 }));
+    const archive = (await fs.readdir(dir)).find((file) =>
+      file.endsWith(".tgz"),
+    );
+    if (!archive) {
+      throw new Error(`helm pull ${HELM_CHART_OCI} wrote no chart archive`);
+    }
+    return path.join(dir, archive);
+  } catch (error) {
+    await removeTempPath(dir);
+    throw error;
+  }
+}
+
 /**
  * Installs or upgrades the Rulebricks Helm chart (idempotent operation).
  * Uses `helm upgrade --install` which will install if release doesn't exist,
@@ -340,6 +766,9 @@ export async function installOrUpgradeChart(
     wait?: boolean;
     timeout?: string;
     createNamespace?: boolean;
+    overrides?: HelmValueOverrides;
+    /** Values file to install instead of the deployment's values.yaml. */
+    valuesPath?: string;
   },
 ): Promise<void> {
   const {
@@ -349,6 +778,8 @@ export async function installOrUpgradeChart(
     wait = true,
     timeout = "15m",
     createNamespace = true,
+    overrides,
+    valuesPath = getHelmValuesPath(deploymentName),
   } = options;
 
   if (await isReleaseStrandedBeforeFirstDeploy(releaseName, namespace)) {
@@ -361,23 +792,25 @@ export async function installOrUpgradeChart(
     });
   }
 
-  const valuesPath = getHelmValuesPath(deploymentName);
+  let chart: string;
+  try {
+    chart = await pullChart(version);
+  } catch (error) {
+    throw new Error(`Helm install/upgrade failed:\n${getErrorMessage(error)}`);
+  }
 
   const args = [
     "upgrade",
     "--install", // This makes it idempotent - install if not exists, upgrade if exists
     releaseName,
-    HELM_CHART_OCI,
+    chart,
     "--namespace",
     namespace,
     "--values",
     valuesPath,
+    ...helmOverrideArgs(overrides),
   ];
 
-  if (version) {
-    args.push("--version", version);
-  }
-
   if (createNamespace) {
     args.push("--create-namespace");
   }
@@ -387,10 +820,16 @@ export async function installOrUpgradeChart(
     args.push("--timeout", timeout);
   }
 
+  // Marks the release as ours (see classifyReleaseOwnership); re-applied on
+  // every upgrade so adopted and pre-label releases pick it up.
+  args.push(...(await ownerLabelArgs()));
+
   try {
     await execa("helm", args);
   } catch (error) {
     throw new Error(`Helm install/upgrade failed:\n${getErrorMessage(error)}`);
+  } finally {
+    await removeTempPath(path.dirname(chart));
   }
 }
 
@@ -407,6 +846,9 @@ export async function upgradeChart(
     timeout?: string;
     /** Roll the release back automatically when the upgrade fails. */
     atomic?: boolean;
+    overrides?: HelmValueOverrides;
+    /** Values file to apply instead of the deployment's values.yaml. */
+    valuesPath?: string;
   },
 ): Promise<void> {
   const {
@@ -416,24 +858,28 @@ export async function upgradeChart(
     wait = true,
     timeout = "15m",
     atomic = false,
+    overrides,
+    valuesPath = getHelmValuesPath(deploymentName),
   } = options;
 
-  const valuesPath = getHelmValuesPath(deploymentName);
+  let chart: string;
+  try {
+    chart = await pullChart(version);
+  } catch (error) {
+    throw new Error(`Helm upgrade failed:\n${getErrorMessage(error)}`);
+  }
 
   const args = [
     "upgrade",
     releaseName,
-    HELM_CHART_OCI,
+    chart,
     "--namespace",
     namespace,
     "--values",
     valuesPath,
+    ...helmOverrideArgs(overrides),
   ];
 
-  if (version) {
-    args.push("--version", version);
-  }
-
   if (atomic) {
     // --atomic implies --wait; a failed upgrade rolls back to the previous
     // release instead of leaving it stranded mid-upgrade.
@@ -444,10 +890,14 @@ export async function upgradeChart(
     args.push("--timeout", timeout);
   }
 
+  args.push(...(await ownerLabelArgs()));
+
   try {
     await execa("helm", args);
   } catch (error) {
     throw new Error(`Helm upgrade failed:\n${getErrorMessage(error)}`);
+  } finally {
+    await removeTempPath(path.dirname(chart));
   }
 }
 
@@ -489,6 +939,45 @@ export async function uninstallChart(
   }
 }
 
+/**
+ * Renders what `installOrUpgradeChart` would apply, without applying it
+ * (`deploy --dry-run`). Returns helm's dry-run output, manifests included.
+ */
+export async function dryRunInstallOrUpgrade(
+  valuesPath: string,
+  options: {
+    releaseName: string;
+    namespace: string;
+    version?: string;
+    overrides?: HelmValueOverrides;
+  },
+): Promise<string> {
+  const { releaseName, namespace, version, overrides } = options;
+  const args = [
+    "upgrade",
+    "--install",
+    releaseName,
+    HELM_CHART_OCI,
+    "--namespace",
+    namespace,
+    "--values",
+    valuesPath,
+    ...helmOverrideArgs(overrides),
+    "--dry-run",
+  ];
+
+  if (version) {
+    args.push("--version", version);
+  }
+
+  try {
+    const { stdout } = await withNetworkRetry(() => execa("helm", args));
+    return stdout;
+  } catch (error) {
+    throw new Error(`Helm dry run failed:\n${getErrorMessage(error)}`);
+  }
+}
+
 /**
  * Performs a dry-run upgrade to preview changes
  */
@@ -518,6 +1007,6 @@ export async function dryRunUpgrade(
     args.push("--version", version);
   }
 
-  const { stdout } = await execa("helm", args);
+  const { stdout } = await withNetworkRetry(() => execa("helm", args));
   return stdout;
 }
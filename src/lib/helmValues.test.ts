@@ -2,7 +2,13 @@ import test from "node:test";
 import assert from "node:assert/strict";
 import fs from "node:fs";
 import path from "node:path";
-import { buildHelmValues, signSupabaseJwt } from "./helmValues.js";
+import {
+  buildHelmValues,
+  otlpLogsUri,
+  parseOtlpHeaders,
+  resolveLoggingPlatformSinks,
+  signSupabaseJwt,
+} from "./helmValues.js";
 import { bundledImageCatalog } from "./imageCatalog.js";
 import { getActiveWizardSteps } from "./wizardSteps.js";
 import {
@@ -17,7 +23,7 @@ import {
   validateHelmValues,
   validateValuesInvariants,
 } from "./validateValues.js";
-import { buildConfigMatrix } from "./configFixtures.js";
+import { buildConfigMatrix, configFixture } from "./configFixtures.js";
 import {
   DeploymentConfig,
   DeploymentConfigSchema,
@@ -37,11 +43,12 @@ const BURST_POOL_TOLERATION: Toleration = {
   effect: "NoSchedule",
 };
 
-function cloneFixture(name: string): DeploymentConfig {
-  const entry = matrix.find((c) => c.name === name);
-  assert.ok(entry, `missing matrix fixture ${name}`);
-  return JSON.parse(JSON.stringify(entry.config)) as DeploymentConfig;
-}
+const AKS_SPOT_TOLERATION: Toleration = {
+  key: "kubernetes.azure.com/scalesetpriority",
+  operator: "Equal",
+  value: "spot",
+  effect: "NoSchedule",
+};
 
 function assertNoBareExistsToleration(
   label: string,
@@ -105,7 +112,7 @@ test("generated values are valid both with and without TLS", () => {
 });
 
 test("ClickStack is the default in-cluster observability backend", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const values = buildHelmValues(config) as Record<string, any>;
 
   assert.equal(values.global.clickstack.enabled, true);
@@ -173,7 +180,7 @@ test("ClickStack is the default in-cluster observability backend", () => {
 });
 
 test("built-in observability settings flow into generated Helm values", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   config.features.observability = {
     clickstack: {
       enabled: true,
@@ -192,7 +199,7 @@ test("built-in observability settings flow into generated Helm values", () => {
 });
 
 test("buildHelmValues rejects self-hosted Supabase without a JWT secret early", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   delete config.database.supabaseJwtSecret;
 
   assert.throws(
@@ -202,7 +209,7 @@ test("buildHelmValues rejects self-hosted Supabase without a JWT secret early",
 });
 
 test("buildHelmValues rejects enabled AI without an OpenAI key early", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   config.features.ai = { enabled: true };
 
   assert.throws(
@@ -212,7 +219,7 @@ test("buildHelmValues rejects enabled AI without an OpenAI key early", () => {
 });
 
 test("configure wizard backfills missing self-hosted Supabase JWT secret", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   delete config.database.supabaseJwtSecret;
 
   const state = configToWizardState(config);
@@ -228,7 +235,7 @@ test("configure wizard backfills missing self-hosted Supabase JWT secret", () =>
 });
 
 test("self-hosted Supabase keys derive from the configured JWT secret", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   config.database.supabaseJwtSecret = "test-jwt-secret-used-for-derived-keys";
 
   const values = buildHelmValues(config) as Record<string, any>;
@@ -336,7 +343,7 @@ test("wizard includes feature config for Valkey Admin options", () => {
 });
 
 test("Valkey Admin ingress emits public hostname and BasicAuth users", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   config.features.cache = {
     valkeyAdmin: {
       enabled: true,
@@ -388,7 +395,7 @@ test("ClickHouse decision-log bootstrap reads only the object-storage archive",
 });
 
 test("BYO observability opt-out disables ClickStack and keeps export paths", () => {
-  const config = cloneFixture("aws-tracing-elastic");
+  const config = configFixture("aws-tracing-elastic");
   const values = buildHelmValues(config) as Record<string, any>;
 
   assert.equal(values.global.clickstack.enabled, false);
@@ -463,14 +470,14 @@ test("external MSK IAM topic-provisioning toggle maps to kafka.provisioning.enab
 test("kafka-exporter defaults on wherever the chart can authenticate it", () => {
   // In-cluster: plaintext broker, exporter always works.
   const inCluster = buildHelmValues(
-    cloneFixture("aws-self-hosted-minimal"),
+    configFixture("aws-self-hosted-minimal"),
   ) as Record<string, any>;
   assert.equal(inCluster.rulebricks.kafkaExporter.enabled, true);
 
   // External MSK IAM: opt-in - the exporter only supports IRSA, not the Pod
   // Identity associations the CLI creates (kafka_exporter#494).
   const msk = buildHelmValues(
-    cloneFixture("aws-external-kafka-msk"),
+    configFixture("aws-external-kafka-msk"),
   ) as Record<string, any>;
   assert.equal(msk.rulebricks.kafkaExporter.enabled, false);
   assert.equal(
@@ -479,7 +486,7 @@ test("kafka-exporter defaults on wherever the chart can authenticate it", () =>
   );
 
   // Explicit opt-in (IRSA users) is honored.
-  const optIn = cloneFixture("aws-external-kafka-msk");
+  const optIn = configFixture("aws-external-kafka-msk");
   optIn.features.cache = { kafkaExporter: { enabled: true } };
   const optInValues = buildHelmValues(optIn) as Record<string, any>;
   assert.equal(optInValues.rulebricks.kafkaExporter.enabled, true);
@@ -487,12 +494,12 @@ test("kafka-exporter defaults on wherever the chart can authenticate it", () =>
   // Static PLAIN/SCRAM with credentials: the chart inherits kafkaSasl into the
   // exporter, so it works out of the box.
   const staticSasl = buildHelmValues(
-    cloneFixture("gcp-external-kafka"),
+    configFixture("gcp-external-kafka"),
   ) as Record<string, any>;
   assert.equal(staticSasl.rulebricks.kafkaExporter.enabled, true);
 
   // Static mechanism without any credential to carry: stay opt-in.
-  const noCreds = cloneFixture("gcp-external-kafka");
+  const noCreds = configFixture("gcp-external-kafka");
   (noCreds.externalServices!.kafka!.external!.sasl as any) = {
     mechanism: "plain",
   };
@@ -501,7 +508,7 @@ test("kafka-exporter defaults on wherever the chart can authenticate it", () =>
 });
 
 test("MSK IAM config without a SASL region fails schema validation", () => {
-  const cfg = cloneFixture("aws-external-kafka-msk");
+  const cfg = configFixture("aws-external-kafka-msk");
   delete (cfg.externalServices!.kafka!.external!.sasl as any).region;
   const result = DeploymentConfigSchema.safeParse(cfg);
   assert.equal(result.success, false);
@@ -651,8 +658,28 @@ test("self-hosted deployments emit supabase.db.enabled so backup validation hold
   assert.equal(values.supabase.db.enabled, true);
 });
 
+test("database.resources flow into the bundled Postgres only when set", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const defaults = buildHelmValues(config) as {
+    supabase: { db: { resources?: unknown } };
+  };
+  assert.equal(defaults.supabase.db.resources, undefined);
+
+  config.database.resources = {
+    requests: { cpu: "1", memory: "4Gi" },
+    limits: { memory: "8Gi" },
+  };
+  const values = buildHelmValues(config) as {
+    supabase: { db: { resources?: unknown } };
+  };
+  assert.deepEqual(values.supabase.db.resources, {
+    requests: { cpu: "1", memory: "4Gi" },
+    limits: { memory: "8Gi" },
+  });
+});
+
 test("external Postgres disables backups even with stale backup config", () => {
-  const config = cloneFixture("aws-external-postgres");
+  const config = configFixture("aws-external-postgres");
   config.backup = {
     enabled: true,
     schedule: "0 2 * * *",
@@ -799,6 +826,37 @@ test("Azure Monitor workload identity maps to azureAd.sdk (not workloadIdentity)
   );
 });
 
+test("Prometheus retention and storage come from monitoring.metrics", () => {
+  type PrometheusValues = {
+    "kube-prometheus-stack": {
+      prometheus: {
+        prometheusSpec: {
+          retention: string;
+          storageSpec: {
+            volumeClaimTemplate: {
+              spec: { resources: { requests: { storage: string } } };
+            };
+          };
+        };
+      };
+    };
+  };
+  const sizing = (config: DeploymentConfig) => {
+    const spec = (buildHelmValues(config) as PrometheusValues)[
+      "kube-prometheus-stack"
+    ].prometheus.prometheusSpec;
+    return [
+      spec.retention,
+      spec.storageSpec.volumeClaimTemplate.spec.resources.requests.storage,
+    ];
+  };
+
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.deepEqual(sizing(config), ["30d", "50Gi"]);
+  config.features.monitoring.metrics = { retention: "7d", storageSize: "10Gi" };
+  assert.deepEqual(sizing(config), ["7d", "10Gi"]);
+});
+
 test("remote write URL is stripped of stray control characters", () => {
   const base = matrix.find((c) => c.name === "azure-remote-write-workload")!;
   const dirty = JSON.parse(JSON.stringify(base.config));
@@ -921,6 +979,98 @@ test("decision_logs sink writes gzipped NDJSON (never parquet) for every cloud",
   }
 });
 
+test("fans out to every configured logging platform with unique sink ids", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.features.logging = {
+    sink: "datadog",
+    bucket: "dd-key",
+    sinks: [
+      {
+        sink: "splunk",
+        bucket: "hec-token",
+        region: "https://hec.example.com",
+      },
+      { sink: "datadog", bucket: "dd-key-eu", region: "datadoghq.eu" },
+      {
+        sink: "loki",
+        name: "loki_archive",
+        bucket: "https://loki.example.com",
+      },
+    ],
+  };
+
+  assert.deepEqual(
+    resolveLoggingPlatformSinks(config).map((s) => s.name),
+    ["datadog", "splunk", "datadog_2", "loki_archive"],
+  );
+  const sinks = vectorSinks(config);
+  assert.equal(sinks.datadog.default_api_key, "dd-key");
+  assert.equal(sinks.datadog_2.site, "datadoghq.eu");
+  assert.equal(sinks.splunk.endpoint, "https://hec.example.com");
+  assert.equal(sinks.loki_archive.type, "loki");
+  for (const name of ["datadog", "datadog_2", "splunk", "loki_archive"]) {
+    assert.deepEqual(sinks[name].inputs, ["normalize_logs"], name);
+  }
+  assert.ok(sinks.console);
+  assert.ok(sinks.decision_logs);
+});
+
+test("generated sink ids skip names that later sinks set explicitly", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.features.logging = {
+    sink: "console",
+    sinks: [
+      { sink: "datadog", bucket: "dd-key" },
+      { sink: "datadog", bucket: "dd-key-2" },
+      { sink: "splunk", name: "datadog_2", bucket: "hec-token" },
+    ],
+  };
+
+  assert.deepEqual(
+    resolveLoggingPlatformSinks(config).map((s) => s.name),
+    ["datadog", "datadog_3", "datadog_2"],
+  );
+});
+
+test("an OTLP sink posts OTLP/JSON logs through the envelope transform", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.equal(
+    (buildHelmValues(config) as any).vector.customConfig.transforms.otlp_logs,
+    undefined,
+  );
+
+  config.features.logging = {
+    sink: "otlp",
+    bucket: "https://otel-collector.observability:4318",
+    region: "Authorization=Bearer abc, X-Scope-OrgID=rulebricks",
+  };
+  const values = buildHelmValues(config) as any;
+  const transform = values.vector.customConfig.transforms.otlp_logs;
+  assert.deepEqual(transform.inputs, ["normalize_logs"]);
+  assert.match(transform.source, /resourceLogs/);
+
+  const sink = values.vector.customConfig.sinks.otlp;
+  assert.equal(sink.type, "opentelemetry");
+  assert.deepEqual(sink.inputs, ["otlp_logs"]);
+  assert.equal(
+    sink.protocol.uri,
+    "https://otel-collector.observability:4318/v1/logs",
+  );
+  assert.deepEqual(sink.protocol.request.headers, {
+    Authorization: "Bearer abc",
+    "X-Scope-OrgID": "rulebricks",
+  });
+  assert.equal(sink.protocol.encoding.codec, "json");
+  assert.equal(sink.protocol.compression, "gzip");
+
+  // A URL with a path is used as given.
+  assert.equal(
+    otlpLogsUri("https://otlp.example.com/otlp/v1/logs"),
+    "https://otlp.example.com/otlp/v1/logs",
+  );
+  assert.deepEqual(parseOtlpHeaders("novalue, =x"), {});
+});
+
 test("no vector sink uses the unsupported parquet codec or extension", () => {
   for (const { name, config } of matrix) {
     for (const [key, sink] of Object.entries(vectorSinks(config))) {
@@ -1079,7 +1229,10 @@ test("appLogs enabled produces a vector-agent with an elasticsearch sink", () =>
   assert.equal(agent.enabled, true);
   assert.equal(agent.role, "Agent");
   assertNoBareExistsToleration("vector-agent", agent.tolerations);
-  assert.deepEqual(agent.tolerations, [BURST_POOL_TOLERATION]);
+  assert.deepEqual(agent.tolerations, [
+    BURST_POOL_TOLERATION,
+    AKS_SPOT_TOLERATION,
+  ]);
   assert.equal(
     agent.customConfig.sources.kubernetes_logs.type,
     "kubernetes_logs",
@@ -1107,12 +1260,15 @@ test("operational DaemonSets use explicit safe tolerations", () => {
   const prepullTolerations = values.rulebricks.hps.imagePrepull
     .tolerations as Toleration[];
   assertNoBareExistsToleration("imagePrepull", prepullTolerations);
-  assert.deepEqual(prepullTolerations, [BURST_POOL_TOLERATION]);
+  assert.deepEqual(prepullTolerations, [
+    BURST_POOL_TOLERATION,
+    AKS_SPOT_TOLERATION,
+  ]);
 });
 
 test("operational DaemonSet tolerations include ARM and burst pools explicitly", () => {
-  const config = cloneFixture("azure-workload-identity");
-  const appLogsConfig = cloneFixture("aws-app-logs-elasticsearch");
+  const config = configFixture("azure-workload-identity");
+  const appLogsConfig = configFixture("aws-app-logs-elasticsearch");
   config.infrastructure.arm64TolerationRequired = true;
   config.features.logging.appLogs = appLogsConfig.features.logging.appLogs;
 
@@ -1159,7 +1315,7 @@ test("invariant rejects enabled tracing without an Elastic endpoint", () => {
 });
 
 test("external Postgres maps to supabase.externalDatabase with bootstrap creds", () => {
-  const config = cloneFixture("aws-external-postgres");
+  const config = configFixture("aws-external-postgres");
   const values = buildHelmValues(config) as Record<string, any>;
   const sb = values.supabase;
   assert.equal(sb.enabled, true);
@@ -1191,7 +1347,7 @@ test("external Postgres maps to supabase.externalDatabase with bootstrap creds",
 });
 
 test("embedded Postgres does not override DB_SSL (in-cluster DB has no TLS)", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const values = buildHelmValues(config) as Record<string, any>;
   const sb = values.supabase;
   assert.equal(sb.auth.environment, undefined);
@@ -1201,7 +1357,7 @@ test("embedded Postgres does not override DB_SSL (in-cluster DB has no TLS)", ()
 });
 
 test("external Postgres k8s secret mode keeps compatibility and uses secret refs", () => {
-  const config = cloneFixture("aws-external-postgres");
+  const config = configFixture("aws-external-postgres");
   const values = buildHelmValues(config, { secretMode: "k8s" }) as Record<
     string,
     any
@@ -1240,7 +1396,7 @@ test("external Postgres k8s secret mode keeps compatibility and uses secret refs
 });
 
 test("embedded Postgres still deploys the bundled database", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const values = buildHelmValues(config) as Record<string, any>;
   assert.equal(values.supabase.db.enabled, true);
   assert.equal(values.supabase.externalDatabase, undefined);
@@ -1250,7 +1406,7 @@ import { buildDeploymentSecrets } from "./secrets.js";
 import { deriveRealtimeSecrets } from "./helmValues.js";
 
 test("k8s secret mode: secretRefs set, app secrets kept out of values (license stays inline for the pull secret)", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   config.features.ai = {
     enabled: true,
     openaiApiKey: "sk-test-openai-key-for-secret-mode",
@@ -1323,7 +1479,7 @@ test("k8s secret mode: SSO + AI configs validate against the chart schema", () =
   // SSO clientId/clientSecret and the OpenAI key are redacted into the app
   // Secret in k8s mode; the chart schema must accept global.secrets.secretRef
   // in place of the inline values (delivered via envFrom).
-  const config = cloneFixture("aws-all-features");
+  const config = configFixture("aws-all-features");
   const values = buildHelmValues(config, { secretMode: "k8s" }) as Record<
     string,
     any
@@ -1342,7 +1498,7 @@ test("k8s secret mode: SSO + AI configs validate against the chart schema", () =
 test("k8s secret mode: managed Supabase config validates against the chart schema", () => {
   // Managed (Supabase Cloud) redacts the access token into the app Secret; the
   // schema must accept secretRef instead of an inline global.supabase.accessToken.
-  const config = cloneFixture("aws-supabase-cloud");
+  const config = configFixture("aws-supabase-cloud");
   const values = buildHelmValues(config, { secretMode: "k8s" }) as Record<
     string,
     any
@@ -1366,7 +1522,7 @@ test("k8s secret mode: managed Supabase config validates against the chart schem
 });
 
 test("inline secret mode keeps secrets in values (dev path)", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const values = buildHelmValues(config, { secretMode: "inline" }) as Record<
     string,
     any
@@ -1383,7 +1539,7 @@ test("inline secret mode keeps secrets in values (dev path)", () => {
 });
 
 test("buildDeploymentSecrets: app + supabase secrets with JWT-derived keys", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const jwt = config.database.supabaseJwtSecret!;
   const byName = Object.fromEntries(
     buildDeploymentSecrets(config).map((s) => [s.name, s.stringData]),
@@ -1412,7 +1568,7 @@ test("buildDeploymentSecrets: app + supabase secrets with JWT-derived keys", ()
 });
 
 test("buildDeploymentSecrets includes external Postgres host/port and bootstrap creds", () => {
-  const config = cloneFixture("aws-external-postgres");
+  const config = configFixture("aws-external-postgres");
   const byName = Object.fromEntries(
     buildDeploymentSecrets(config).map((s) => [s.name, s.stringData]),
   );
@@ -1436,7 +1592,7 @@ test("external Postgres wires migrations.externalDb host/port for the migration
   // templates/migration-job.yaml reads DB_HOST from .Values.migrations.externalDb
   // (not supabase.externalDatabase). If unset, pg_isready gets an empty host and
   // the migrate hook hangs until Helm times out. Guards that regression.
-  const config = cloneFixture("aws-external-postgres");
+  const config = configFixture("aws-external-postgres");
   const values = buildHelmValues(config, { secretMode: "k8s" }) as Record<
     string,
     any
@@ -1463,7 +1619,7 @@ test("external Postgres wires migrations.externalDb host/port for the migration
   );
   assert.equal(values.migrations.externalDb.existingSecretKey, "master-password");
   // Bundled-Postgres deploys must NOT set it (chart uses the internal service).
-  const internal = buildHelmValues(cloneFixture("aws-self-hosted-minimal"), {
+  const internal = buildHelmValues(configFixture("aws-self-hosted-minimal"), {
     secretMode: "k8s",
   }) as Record<string, any>;
   assert.equal(internal.migrations?.externalDb, undefined);
@@ -1473,7 +1629,7 @@ test("supabase kong ingress carries Traefik websecure router annotations under T
   // The supabase subchart's kong ingress doesn't emit router.entrypoints/tls
   // itself, so Traefik only builds a web router and https://supabase.<domain>
   // 404s. The CLI must inject them (via the subchart's annotations passthrough).
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const tls = buildHelmValues(config, {
     tlsEnabled: true,
     secretMode: "k8s",
@@ -1493,12 +1649,41 @@ test("supabase kong ingress carries Traefik websecure router annotations under T
   assert.equal(b["traefik.ingress.kubernetes.io/router.tls"], "false");
 });
 
+test("a custom ingress class applies to both the app and supabase ingresses", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.ingress = { className: "traefik-external" };
+  const values = buildHelmValues(config, {
+    tlsEnabled: true,
+    secretMode: "k8s",
+  }) as Record<string, any>;
+
+  const app = values.rulebricks.ingress;
+  const kong = values.supabase.kong.ingress;
+  assert.equal(app.className, "traefik-external");
+  assert.equal(kong.className, "traefik-external");
+  // The chart's app ingress takes no annotations value.
+  assert.equal(app.annotations, undefined);
+  assert.equal(
+    kong.annotations["traefik.ingress.kubernetes.io/router.entrypoints"],
+    "websecure",
+  );
+});
+
+test("default ingress settings leave the app ingress annotations to the chart", () => {
+  const values = buildHelmValues(configFixture("aws-self-hosted-minimal"), {
+    tlsEnabled: true,
+    secretMode: "k8s",
+  }) as Record<string, any>;
+  assert.equal(values.rulebricks.ingress.className, "traefik");
+  assert.equal(values.rulebricks.ingress.annotations, undefined);
+});
+
 // ===========================================================================
 // Image registry / digest pinning (docker.io/rulebricks/* + global.imageRegistry)
 // ===========================================================================
 
 test("default image refs use the rulebricks/* split shape with no legacy hosts", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const values = buildHelmValues(config) as Record<string, any>;
 
   // app/hps use the split { registry, repository } shape (host never in repo).
@@ -1545,7 +1730,7 @@ test("default image refs use the rulebricks/* split shape with no legacy hosts",
 });
 
 test("global.imageDigests is always present and threaded into global", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const values = buildHelmValues(config) as Record<string, any>;
   assert.ok(
     values.global.imageDigests !== undefined,
@@ -1557,7 +1742,7 @@ test("global.imageDigests is always present and threaded into global", () => {
 });
 
 test("imageRegistry override rewrites every image host to the custom registry", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   config.imageRegistry = "myacr.azurecr.io";
   // Enable external-dns so its image block is emitted and can be asserted.
   config.dns = { provider: "route53", autoManage: true };
@@ -1686,7 +1871,7 @@ test("imageRegistry override rewrites every image host to the custom registry",
 });
 
 test("cluster-autoscaler enabled on AWS with cluster name + region, disabled elsewhere", () => {
-  const aws = cloneFixture("aws-self-hosted-minimal");
+  const aws = configFixture("aws-self-hosted-minimal");
   const awsValues = buildHelmValues(aws) as Record<string, any>;
   assert.deepEqual(awsValues["cluster-autoscaler"], {
     enabled: true,
@@ -1701,19 +1886,19 @@ test("cluster-autoscaler enabled on AWS with cluster name + region, disabled els
   });
 
   // GKE/AKS node pools autoscale natively - the subchart must stay off.
-  const gcp = cloneFixture("gcp-external-postgres");
+  const gcp = configFixture("gcp-external-postgres");
   const gcpValues = buildHelmValues(gcp) as Record<string, any>;
   assert.deepEqual(gcpValues["cluster-autoscaler"], { enabled: false });
 
   // Without a cluster name the autoscaler cannot auto-discover ASGs.
-  const noCluster = cloneFixture("aws-self-hosted-minimal");
+  const noCluster = configFixture("aws-self-hosted-minimal");
   noCluster.infrastructure.clusterName = "";
   const noClusterValues = buildHelmValues(noCluster) as Record<string, any>;
   assert.deepEqual(noClusterValues["cluster-autoscaler"], { enabled: false });
 });
 
 test("per-chart imagePullSecrets are still emitted for private rulebricks/*", () => {
-  const config = cloneFixture("aws-self-hosted-minimal");
+  const config = configFixture("aws-self-hosted-minimal");
   const values = buildHelmValues(config) as Record<string, any>;
   const expected = [{ name: `${getReleaseName(config.name)}-regcred` }];
 
@@ -1732,3 +1917,214 @@ test("per-chart imagePullSecrets are still emitted for private rulebricks/*", ()
   // global has no legacy dhi.io reference.
   assert.ok(!JSON.stringify(values.global).includes("dhi.io"));
 });
+
+test("only workers tolerate spot burst nodes; stateful services don't", () => {
+  const values = buildHelmValues(
+    configFixture("azure-workload-identity"),
+  ) as Record<string, any>;
+  assertIncludesToleration(
+    "workers",
+    values.rulebricks.hps.workers.tolerations,
+    AKS_SPOT_TOLERATION,
+  );
+  for (const [label, tolerations] of [
+    ["app", values.rulebricks.app.tolerations],
+    ["hps", values.rulebricks.hps.tolerations],
+    ["kafka", values.kafka.tolerations],
+    ["supabase.db", values.supabase.db.tolerations],
+  ] as Array<[string, Toleration[] | undefined]>) {
+    assert.ok(
+      !(tolerations ?? []).some((tol) => tol.key === AKS_SPOT_TOLERATION.key),
+      `${label} must not land on spot nodes`,
+    );
+  }
+});
+
+test("statefulPool pins Kafka and Postgres to the stateful pool", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const baseline = buildHelmValues(config) as Record<string, any>;
+  assert.equal(baseline.kafka.nodeSelector, undefined);
+  assert.equal(baseline.supabase.db.nodeSelector, undefined);
+
+  config.infrastructure.statefulPool = true;
+  config.infrastructure.arm64TolerationRequired = true;
+  const values = buildHelmValues(config) as Record<string, any>;
+  const nodeSelector = { "rulebricks.com/pool": "stateful" };
+  const tolerations = [
+    {
+      key: "kubernetes.io/arch",
+      operator: "Equal",
+      value: "arm64",
+      effect: "NoSchedule",
+    },
+    {
+      key: "rulebricks.com/pool",
+      operator: "Equal",
+      value: "stateful",
+      effect: "NoSchedule",
+    },
+  ];
+  assert.deepEqual(values.kafka.nodeSelector, nodeSelector);
+  assert.deepEqual(values.kafka.tolerations, tolerations);
+  assert.deepEqual(values.supabase.db.nodeSelector, nodeSelector);
+  assert.deepEqual(values.supabase.db.tolerations, tolerations);
+
+  // Everything else stays on the shared pools.
+  assert.equal(values.rulebricks.redis.nodeSelector, undefined);
+});
+
+test("scheduling overrides place Kafka and Postgres on user node groups", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const dedicated = {
+    key: "dedicated",
+    operator: "Equal" as const,
+    value: "kafka",
+    effect: "NoSchedule" as const,
+  };
+  const affinity = {
+    nodeAffinity: {
+      requiredDuringSchedulingIgnoredDuringExecution: {
+        nodeSelectorTerms: [
+          {
+            matchExpressions: [
+              { key: "disktype", operator: "In", values: ["ssd"] },
+            ],
+          },
+        ],
+      },
+    },
+  };
+  config.scheduling = {
+    kafka: {
+      nodeSelector: { "node-group": "kafka" },
+      tolerations: [dedicated],
+      affinity,
+    },
+    database: { nodeSelector: { "node-group": "db" } },
+  };
+  const values = buildHelmValues(config) as Record<string, any>;
+  assert.deepEqual(values.kafka.nodeSelector, { "node-group": "kafka" });
+  assert.deepEqual(values.kafka.tolerations, [dedicated]);
+  assert.deepEqual(values.kafka.affinity, affinity);
+  assert.deepEqual(values.supabase.db.nodeSelector, { "node-group": "db" });
+
+  // Merged over the stateful pool rather than replacing it.
+  config.infrastructure.statefulPool = true;
+  const pooled = buildHelmValues(config) as Record<string, any>;
+  assert.deepEqual(pooled.kafka.nodeSelector, {
+    "rulebricks.com/pool": "stateful",
+    "node-group": "kafka",
+  });
+  assert.deepEqual(
+    pooled.kafka.tolerations.map((t: { key: string }) => t.key),
+    ["rulebricks.com/pool", "dedicated"],
+  );
+});
+
+test("database.storageSize sizes the bundled Postgres volume", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const unset = buildHelmValues(config) as Record<string, any>;
+  assert.equal(unset.supabase.db.persistence.size, undefined);
+
+  config.database.storageSize = "200Gi";
+  const values = buildHelmValues(config) as Record<string, any>;
+  assert.equal(values.supabase.db.persistence.size, "200Gi");
+});
+
+test("storageClasses override the cluster-wide class per component", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.infrastructure.storageClass = "standard-rwo";
+  config.storageClasses = { kafka: "io2", database: "premium-db" };
+  const values = buildHelmValues(config) as Record<string, any>;
+
+  assert.equal(values.kafka.storage.class, "io2");
+  assert.equal(values.supabase.db.persistence.storageClassName, "premium-db");
+  // Components without an override keep infrastructure.storageClass.
+  assert.equal(
+    values.rulebricks.redis.persistence.storageClass,
+    "standard-rwo",
+  );
+  assert.equal(values.clickhouse.persistence.storageClass, "standard-rwo");
+  assert.equal(
+    values["kube-prometheus-stack"].prometheus.prometheusSpec.storageSpec
+      .volumeClaimTemplate.spec.storageClassName,
+    "standard-rwo",
+  );
+});
+
+test("workloadArchitecture pins core, worker and stateful pods to one arch", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.infrastructure.nodeArchitecture = "mixed";
+  config.infrastructure.arm64TolerationRequired = true;
+  const unpinned = buildHelmValues(config) as Record<string, any>;
+  assert.equal(unpinned.rulebricks.app.nodeSelector, undefined);
+  assert.equal(unpinned.rulebricks.hps.workers.nodeSelector, undefined);
+
+  config.infrastructure.workloadArchitecture = "amd64";
+  config.infrastructure.statefulPool = true;
+  const x86 = buildHelmValues(config) as Record<string, any>;
+  const amd64 = { "kubernetes.io/arch": "amd64" };
+  assert.deepEqual(x86.rulebricks.app.nodeSelector, amd64);
+  assert.deepEqual(x86.rulebricks.hps.workers.nodeSelector, amd64);
+  assert.deepEqual(x86.kafka.nodeSelector, {
+    "kubernetes.io/arch": "amd64",
+    "rulebricks.com/pool": "stateful",
+  });
+  // x86-only pods don't need to tolerate the arm64 taint.
+  assert.ok(
+    !JSON.stringify(x86.rulebricks.app.tolerations ?? []).includes("arm64"),
+  );
+
+  config.infrastructure.workloadArchitecture = "arm64";
+  config.infrastructure.arm64TolerationRequired = false;
+  const graviton = buildHelmValues(config) as Record<string, any>;
+  assert.deepEqual(graviton.rulebricks.app.nodeSelector, {
+    "kubernetes.io/arch": "arm64",
+  });
+  assertIncludesToleration("app", graviton.rulebricks.app.tolerations, {
+    key: "kubernetes.io/arch",
+    operator: "Equal",
+    value: "arm64",
+    effect: "NoSchedule",
+  });
+});
+
+test("Cloudflare Origin TLS replaces cert-manager with a default certificate", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.tls = {
+    provider: "cloudflare-origin",
+    cloudflareOrigin: { apiToken: "cf-token" },
+  };
+  const values = buildHelmValues(config, {
+    tlsEnabled: true,
+  }) as Record<string, any>;
+  assert.equal(values.global.tlsEnabled, true);
+  assert.equal(values["cert-manager"].enabled, false);
+  assert.equal(values.clusterIssuer.enabled, false);
+  assert.deepEqual(values.traefik.tlsStore, {
+    default: {
+      defaultCertificate: {
+        secretName: `rulebricks-${config.name}-cloudflare-origin-tls`,
+      },
+    },
+  });
+
+  const baseline = buildHelmValues(configFixture("aws-self-hosted-minimal"), {
+    tlsEnabled: true,
+  }) as Record<string, any>;
+  assert.equal(baseline["cert-manager"].enabled, true);
+  assert.equal(baseline.traefik.tlsStore, undefined);
+});
+
+test("wildcard tls.domains leave the chart's ClusterIssuer alone", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.tls = {
+    domains: ["*.rules.example.com"],
+    dns01: { secretRef: "route53-credentials", hostedZoneId: "Z123" },
+  };
+  const values = buildHelmValues(config, {
+    tlsEnabled: true,
+  }) as Record<string, any>;
+  // The chart has no solvers value; the DNS-01 issuer is applied separately.
+  assert.equal(values.clusterIssuer.solvers, undefined);
+});
@@ -2,14 +2,19 @@ import {
   DeploymentConfig,
   getReleaseName,
   isSupportedDnsProvider,
+  LoggingPlatformSink,
+  originCertificateSecretName,
   RemoteWriteConfig,
+  SchedulingOverride,
   SecretKeyRef,
+  usesCloudflareOriginTls,
   validateRemoteWriteConfig,
 } from "../types/index.js";
 import {
   loadHelmValues,
   saveHelmValues,
   getHelmValuesPath,
+  writePrivateFile,
 } from "./config.js";
 import { assertValidHelmValues } from "./validateValues.js";
 import {
@@ -136,6 +141,25 @@ const VECTOR_NORMALIZE_LOGS_VRL = [
   '.params = to_string(.params) ?? "{}"',
 ].join("\n");
 
+// VRL wrapping each normalized decision log in an OTLP/JSON logs request (one
+// resource, one scope, one record) for the OpenTelemetry sink. The whole event
+// rides in the record body; level and trace context map to their OTLP fields.
+const VECTOR_OTLP_LOGS_VRL = [
+  "record = {",
+  '  "timeUnixNano": to_string(to_unix_timestamp(timestamp(.timestamp) ?? now(), unit: "nanoseconds")),',
+  '  "severityText": to_string(.level) ?? "info",',
+  '  "body": {"stringValue": encode_json(.)}',
+  "}",
+  "if is_string(.trace_id) { record.traceId = .trace_id }",
+  "if is_string(.span_id) { record.spanId = .span_id }",
+  ". = {",
+  '  "resourceLogs": [{',
+  '    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "rulebricks"}}]},',
+  '    "scopeLogs": [{"scope": {"name": "rulebricks.decision-logs"}, "logRecords": [record]}]',
+  "  }]",
+  "}",
+].join("\n");
+
 function decisionLogPathPrefix(config: DeploymentConfig): string {
   const path = config.storage?.paths?.decisionLogs || "decision-logs";
   return `${path.replace(/^\/+|\/+$/g, "")}/year=%Y/month=%m/day=%d/hour=%H/`;
@@ -224,123 +248,244 @@ function generateVectorSinks(
     }
   }
 
-  // Add external logging-platform sink if configured. Decision logs always go
-  // to object storage via the decision_logs sink above; this is an additional
-  // platform destination (Datadog, Splunk, etc.).
-  if (
-    config.features.logging.sink !== "console" &&
-    config.features.logging.sink !== "pending"
-  ) {
-    const { sink, bucket, region } = config.features.logging;
-
-    switch (sink) {
-      // Logging platform sinks
-      // For platforms, bucket is repurposed for API key/token, region for site/URL
-      case "datadog":
-        sinks.datadog = {
-          type: "datadog_logs",
-          inputs: ["normalize_logs"],
-          default_api_key: bucket, // API key stored in bucket field
-          site: region || "datadoghq.com", // Site stored in region field
-          compression: "gzip",
-          encoding: {
-            codec: "json",
-          },
-        };
-        break;
+  // Add external logging-platform sinks if configured. Decision logs always go
+  // to object storage via the decision_logs sink above; these are additional
+  // platform destinations (Datadog, Splunk, etc.).
+  for (const platform of resolveLoggingPlatformSinks(config)) {
+    sinks[platform.name] = generatePlatformSink(platform);
+  }
 
-      case "splunk":
-        sinks.splunk = {
-          type: "splunk_hec_logs",
-          inputs: ["normalize_logs"],
-          endpoint: region, // URL stored in region field
-          default_token: bucket, // HEC token stored in bucket field
-          compression: "gzip",
-          encoding: {
-            codec: "json",
-          },
-        };
-        break;
+  return sinks;
+}
 
-      case "elasticsearch":
-        // Elasticsearch config is JSON-encoded in bucket field
-        try {
-          const esConfig = JSON.parse(bucket || "{}");
-          sinks.elasticsearch = {
-            type: "elasticsearch",
-            inputs: ["normalize_logs"],
-            endpoints: [esConfig.url],
-            bulk: {
-              index: esConfig.index || "rulebricks-logs",
-            },
-            ...(esConfig.user && esConfig.password
-              ? {
-                  auth: {
-                    strategy: "basic",
-                    user: esConfig.user,
-                    password: esConfig.password,
-                  },
-                }
-              : {}),
-          };
-        } catch {
-          // Fallback if JSON parsing fails
-          sinks.elasticsearch = {
-            type: "elasticsearch",
-            inputs: ["normalize_logs"],
-            endpoints: [bucket],
-            bulk: {
-              index: region || "rulebricks-logs",
-            },
-          };
-        }
-        break;
+/**
+ * Vector transforms: the decision-log normalization every sink reads, plus the
+ * OTLP envelope when an OpenTelemetry sink is configured.
+ */
+function generateVectorTransforms(
+  config: DeploymentConfig,
+): Record<string, unknown> {
+  const transforms: Record<string, unknown> = {
+    normalize_logs: {
+      type: "remap",
+      inputs: ["kafka"],
+      source: VECTOR_NORMALIZE_LOGS_VRL,
+    },
+  };
+  if (resolveLoggingPlatformSinks(config).some((p) => p.sink === "otlp")) {
+    transforms.otlp_logs = {
+      type: "remap",
+      inputs: ["normalize_logs"],
+      source: VECTOR_OTLP_LOGS_VRL,
+    };
+  }
+  return transforms;
+}
 
-      case "loki":
-        sinks.loki = {
-          type: "loki",
+/**
+ * Parses OTLP headers in the OTEL_EXPORTER_OTLP_HEADERS form
+ * ("key1=value1,key2=value2"). Entries without "=" are ignored.
+ */
+export function parseOtlpHeaders(
+  spec: string | undefined,
+): Record<string, string> {
+  const headers: Record<string, string> = {};
+  for (const entry of (spec ?? "").split(",")) {
+    const index = entry.indexOf("=");
+    if (index <= 0) continue;
+    const key = entry.slice(0, index).trim();
+    if (key) headers[key] = entry.slice(index + 1).trim();
+  }
+  return headers;
+}
+
+/**
+ * The OTLP/HTTP logs URL: a collector base URL (no path) gets the standard
+ * /v1/logs path, as OTel SDKs do for OTEL_EXPORTER_OTLP_ENDPOINT.
+ */
+export function otlpLogsUri(endpoint: string): string {
+  try {
+    const url = new URL(endpoint);
+    if (url.pathname === "" || url.pathname === "/") {
+      url.pathname = "/v1/logs";
+      return url.toString();
+    }
+  } catch {
+    // Not a URL; pass it through and let Vector report it.
+  }
+  return endpoint;
+}
+
+/**
+ * Every external logging-platform sink with its Vector sink id: the primary
+ * features.logging.sink first, then features.logging.sinks in order. Ids
+ * default to the platform name; a repeated platform gets a _2, _3... suffix
+ * unless the entry names itself.
+ */
+export function resolveLoggingPlatformSinks(
+  config: DeploymentConfig,
+): Array<LoggingPlatformSink & { name: string }> {
+  const logging = config.features.logging;
+  const entries: LoggingPlatformSink[] = [];
+  if (logging.sink !== "console" && logging.sink !== "pending") {
+    entries.push({
+      sink: logging.sink,
+      bucket: logging.bucket,
+      region: logging.region,
+    });
+  }
+  entries.push(...(logging.sinks ?? []));
+
+  // Explicit names are taken first, so a generated one never collides.
+  const used = new Set([
+    "console",
+    "decision_logs",
+    ...entries.flatMap((entry) => (entry.name ? [entry.name] : [])),
+  ]);
+  return entries.map((entry) => {
+    let name = entry.name ?? entry.sink;
+    for (let n = 2; !entry.name && used.has(name); n++) {
+      name = `${entry.sink}_${n}`;
+    }
+    used.add(name);
+    return { ...entry, name };
+  });
+}
+
+/**
+ * Vector sink for one logging platform. For platforms, bucket is repurposed
+ * for the API key/token and region for the site/URL.
+ */
+function generatePlatformSink({
+  sink,
+  bucket,
+  region,
+}: LoggingPlatformSink): Record<string, unknown> {
+  switch (sink) {
+    case "datadog":
+      return {
+        type: "datadog_logs",
+        inputs: ["normalize_logs"],
+        default_api_key: bucket, // API key stored in bucket field
+        site: region || "datadoghq.com", // Site stored in region field
+        compression: "gzip",
+        encoding: {
+          codec: "json",
+        },
+      };
+
+    case "splunk":
+      return {
+        type: "splunk_hec_logs",
+        inputs: ["normalize_logs"],
+        endpoint: region, // URL stored in region field
+        default_token: bucket, // HEC token stored in bucket field
+        compression: "gzip",
+        encoding: {
+          codec: "json",
+        },
+      };
+
+    case "elasticsearch":
+      // Elasticsearch config is JSON-encoded in bucket field
+      try {
+        const esConfig = JSON.parse(bucket || "{}");
+        return {
+          type: "elasticsearch",
           inputs: ["normalize_logs"],
-          endpoint: bucket, // Loki URL stored in bucket field
-          labels: {
-            app: "rulebricks",
-            source: "decision-logs",
-          },
-          encoding: {
-            codec: "json",
+          endpoints: [esConfig.url],
+          bulk: {
+            index: esConfig.index || "rulebricks-logs",
           },
+          ...(esConfig.user && esConfig.password
+            ? {
+                auth: {
+                  strategy: "basic",
+                  user: esConfig.user,
+                  password: esConfig.password,
+                },
+              }
+            : {}),
         };
-        break;
-
-      case "newrelic":
-        sinks.newrelic = {
-          type: "new_relic",
+      } catch {
+        // Fallback if JSON parsing fails
+        return {
+          type: "elasticsearch",
           inputs: ["normalize_logs"],
-          license_key: bucket, // License key stored in bucket field
-          account_id: region, // Account ID stored in region field
-          api: "logs",
-          compression: "gzip",
-          encoding: {
-            codec: "json",
+          endpoints: [bucket],
+          bulk: {
+            index: region || "rulebricks-logs",
           },
         };
-        break;
+      }
 
-      case "axiom":
-        sinks.axiom = {
-          type: "axiom",
-          inputs: ["normalize_logs"],
-          token: bucket, // API token stored in bucket field
-          dataset: region || "rulebricks", // Dataset stored in region field
+    case "loki":
+      return {
+        type: "loki",
+        inputs: ["normalize_logs"],
+        endpoint: bucket, // Loki URL stored in bucket field
+        labels: {
+          app: "rulebricks",
+          source: "decision-logs",
+        },
+        encoding: {
+          codec: "json",
+        },
+      };
+
+    case "newrelic":
+      return {
+        type: "new_relic",
+        inputs: ["normalize_logs"],
+        license_key: bucket, // License key stored in bucket field
+        account_id: region, // Account ID stored in region field
+        api: "logs",
+        compression: "gzip",
+        encoding: {
+          codec: "json",
+        },
+      };
+
+    case "axiom":
+      return {
+        type: "axiom",
+        inputs: ["normalize_logs"],
+        token: bucket, // API token stored in bucket field
+        dataset: region || "rulebricks", // Dataset stored in region field
+        compression: "gzip",
+        encoding: {
+          codec: "json",
+        },
+      };
+
+    case "otlp": {
+      // Vector's opentelemetry sink speaks OTLP over HTTP only (no gRPC).
+      // Each request must be a single OTLP JSON document, so events go out
+      // one per request rather than as a JSON array. The collector URL is
+      // stored in the bucket field, extra headers in the region field.
+      const headers = parseOtlpHeaders(region);
+      return {
+        type: "opentelemetry",
+        inputs: ["otlp_logs"],
+        protocol: {
+          type: "http",
+          uri: otlpLogsUri(bucket ?? ""),
+          method: "post",
           compression: "gzip",
           encoding: {
             codec: "json",
           },
-        };
-        break;
+          framing: {
+            method: "bytes",
+          },
+          batch: {
+            max_events: 1,
+          },
+          ...(Object.keys(headers).length > 0 ? { request: { headers } } : {}),
+        },
+      };
     }
   }
-
-  return sinks;
 }
 
 /**
@@ -698,7 +843,7 @@ function generateClickStackValues(
       },
       ingress: {
         enabled,
-        className: "traefik",
+        className: ingressClassName(config.ingress),
         hostname: "",
         allowedIPs: [],
       },
@@ -1055,8 +1200,10 @@ function generateWorkerPodAntiAffinity(): Record<string, unknown> {
 function generateScheduling(
   tolerations?: Array<Record<string, string>>,
   affinity?: Record<string, unknown>,
+  nodeSelector?: Record<string, string>,
 ): Record<string, unknown> {
   return {
+    ...(nodeSelector ? { nodeSelector } : {}),
     ...(affinity ? { affinity } : {}),
     ...(tolerations ? { tolerations } : {}),
   };
@@ -1086,6 +1233,66 @@ const BURST_POOL_NODE_PREFERENCE: Record<string, unknown> = {
   },
 };
 
+/**
+ * AKS taints every spot node pool with this, so a burst pool running on spot
+ * capacity (cluster-setup's burstSpot) needs it on top of the burst-pool
+ * toleration. EKS and GKE spot nodes carry no taint of their own. Only the
+ * workers and the per-node DaemonSets tolerate it: Kafka, Postgres and the
+ * core services stay on on-demand nodes, where an eviction can't take them
+ * down mid-write.
+ */
+const AKS_SPOT_TOLERATION: Record<string, string> = {
+  key: "kubernetes.azure.com/scalesetpriority",
+  operator: "Equal",
+  value: "spot",
+  effect: "NoSchedule",
+};
+
+/**
+ * Stateful-pool placement, opt-in via infrastructure.statefulPool, applied
+ * like a scheduling.kafka / scheduling.database override. Unlike the burst
+ * pool this is a hard nodeSelector: Kafka and Postgres are meant to stay off
+ * shared nodes, so a cluster without a rulebricks.com/pool=stateful pool
+ * leaves them Pending rather than silently co-locating them.
+ */
+const STATEFUL_POOL_PLACEMENT: SchedulingOverride = {
+  nodeSelector: { "rulebricks.com/pool": "stateful" },
+  tolerations: [
+    {
+      key: "rulebricks.com/pool",
+      operator: "Equal",
+      value: "stateful",
+      effect: "NoSchedule",
+    },
+  ],
+};
+
+/**
+ * Merges a user placement override (scheduling.kafka / scheduling.database)
+ * over generated scheduling: nodeSelector keys are added, tolerations
+ * appended, and a user affinity replaces the generated one.
+ */
+function applySchedulingOverride(
+  scheduling: Record<string, unknown>,
+  override: SchedulingOverride | undefined,
+): Record<string, unknown> {
+  if (!override) return scheduling;
+  const nodeSelector = {
+    ...(scheduling.nodeSelector as Record<string, string> | undefined),
+    ...override.nodeSelector,
+  };
+  const tolerations = [
+    ...((scheduling.tolerations as Array<Record<string, unknown>>) ?? []),
+    ...(override.tolerations ?? []),
+  ];
+  return {
+    ...scheduling,
+    ...(Object.keys(nodeSelector).length > 0 ? { nodeSelector } : {}),
+    ...(tolerations.length > 0 ? { tolerations } : {}),
+    ...(override.affinity ? { affinity: override.affinity } : {}),
+  };
+}
+
 function generateBackupValues(config: DeploymentConfig): Record<string, unknown> {
   const usesInClusterPostgres =
     config.database.type === "self-hosted" &&
@@ -1493,6 +1700,32 @@ function generateTraefikTracing(
   };
 }
 
+const DEFAULT_INGRESS_CLASS = "traefik";
+
+/** Ingress class shared by the app, Supabase kong and ClickStack ingresses. */
+export function ingressClassName(
+  ingress: DeploymentConfig["ingress"],
+): string {
+  return ingress?.className ?? DEFAULT_INGRESS_CLASS;
+}
+
+/**
+ * Traefik router annotations for the chart-rendered ingresses the chart
+ * doesn't annotate itself. They match what the chart's app ingress always
+ * writes, so every route lands on the same entrypoint; other controllers
+ * ignore them.
+ */
+export function generateIngressAnnotations(
+  tlsEnabled: boolean,
+): Record<string, string> {
+  return {
+    "traefik.ingress.kubernetes.io/router.entrypoints": tlsEnabled
+      ? "websecure"
+      : "web",
+    "traefik.ingress.kubernetes.io/router.tls": tlsEnabled ? "true" : "false",
+  };
+}
+
 /**
  * vector-agent block: a second Vector deployment (role Agent / DaemonSet) that
  * tails all pod logs and ships them to a customer-managed Elasticsearch. Decision
@@ -1631,8 +1864,9 @@ export function buildHelmValues(
   const externalDnsEnabled =
     config.dns.autoManage && isSupportedDnsProvider(config.dns.provider);
 
+  const workloadArchitecture = config.infrastructure.workloadArchitecture;
   const gcpDiskType =
-    config.infrastructure.nodeArchitecture === "amd64"
+    (workloadArchitecture ?? config.infrastructure.nodeArchitecture) === "amd64"
       ? "pd-balanced"
       : "hyperdisk-balanced";
 
@@ -1647,9 +1881,20 @@ export function buildHelmValues(
         : config.infrastructure.provider === "azure"
           ? "managed-premium"
           : "gp3");
-
-  const shouldApplyArm64Toleration =
-    config.infrastructure.arm64TolerationRequired ?? false;
+  // storageClasses.<component> overrides the class for that component's
+  // volumes.
+  const storageClassFor = (
+    component: keyof NonNullable<DeploymentConfig["storageClasses"]>,
+  ): string => config.storageClasses?.[component] || storageClass;
+
+  // A pinned architecture decides; otherwise tolerate tainted arm64 nodes
+  // whenever the capability scan found them.
+  const shouldApplyArm64Toleration = workloadArchitecture
+    ? workloadArchitecture === "arm64"
+    : (config.infrastructure.arm64TolerationRequired ?? false);
+  const architectureNodeSelector = workloadArchitecture
+    ? { "kubernetes.io/arch": workloadArchitecture }
+    : undefined;
   const architectureTolerations = shouldApplyArm64Toleration
     ? [
         {
@@ -1660,23 +1905,36 @@ export function buildHelmValues(
         },
       ]
     : undefined;
-  const coreScheduling = generateScheduling(architectureTolerations);
+  const coreScheduling = generateScheduling(
+    architectureTolerations,
+    undefined,
+    architectureNodeSelector,
+  );
+  // Kafka and Postgres: core scheduling unless they get their own pool.
+  const statefulScheduling = config.infrastructure.statefulPool
+    ? applySchedulingOverride(coreScheduling, STATEFUL_POOL_PLACEMENT)
+    : coreScheduling;
   // Workers always tolerate + softly prefer the optional burst pool
   // (rulebricks.com/pool=burst). The preference is soft, so clusters without a
   // burst pool schedule workers on ordinary capacity exactly as before.
   const workerTolerations = [
     ...(architectureTolerations ?? []),
     BURST_POOL_TOLERATION,
+    AKS_SPOT_TOLERATION,
   ];
   const operationalDaemonSetTolerations = workerTolerations;
-  const workerScheduling = generateScheduling(workerTolerations, {
-    ...generateWorkerPodAntiAffinity(),
-    nodeAffinity: {
-      preferredDuringSchedulingIgnoredDuringExecution: [
-        BURST_POOL_NODE_PREFERENCE,
-      ],
+  const workerScheduling = generateScheduling(
+    workerTolerations,
+    {
+      ...generateWorkerPodAntiAffinity(),
+      nodeAffinity: {
+        preferredDuringSchedulingIgnoredDuringExecution: [
+          BURST_POOL_NODE_PREFERENCE,
+        ],
+      },
     },
-  });
+    architectureNodeSelector,
+  );
   const infrastructurePodLabels = {
     "rulebricks.com/workload-group": "infrastructure",
   };
@@ -1770,6 +2028,11 @@ export function buildHelmValues(
     };
   }
 
+  // With Cloudflare Origin certificates the CLI writes the TLS Secret itself,
+  // so cert-manager and the ACME issuer stay off even when TLS is on.
+  const originTls = usesCloudflareOriginTls(config);
+  const certManagerEnabled = tlsEnabled && !originTls;
+
   const values: Record<string, unknown> = {
     // =============================================================================
     // GLOBAL CONFIGURATION
@@ -1881,7 +2144,7 @@ export function buildHelmValues(
     clickstack: generateClickStackValues(
       clickStackEnabled,
       config,
-      storageClass,
+      storageClassFor("clickstack"),
       infrastructurePodLabels,
       operationalDaemonSetTolerations,
       images,
@@ -2006,17 +2269,19 @@ export function buildHelmValues(
         },
       },
 
-      // Ingress configuration
+      // Ingress configuration. The chart's ingress template emits the
+      // default Traefik annotations itself and takes no others, which is why
+      // configValidation only accepts ingress.className.
       ingress: {
         enabled: true,
-        className: "traefik",
+        className: ingressClassName(config.ingress),
         paths: [{ path: "/", pathType: "Prefix" }],
       },
 
       // Redis configuration (in-cluster sizing or external connection settings)
       redis: generateRedisBlock(
         config,
-        storageClass,
+        storageClassFor("redis"),
         infrastructurePodLabels,
         coreScheduling,
       ),
@@ -2036,10 +2301,14 @@ export function buildHelmValues(
       replicas: TOPIC_REPLICATION_FACTOR,
       storage: {
         size: "20Gi",
-        class: storageClass,
+        class: storageClassFor("kafka"),
       },
       // Critical tier: the broker must always be able to preempt burst workers.
       priorityClassName: criticalPriorityClass,
+      ...applySchedulingOverride(
+        config.infrastructure.statefulPool ? statefulScheduling : {},
+        config.scheduling?.kafka,
+      ),
       config: generateKafkaConfig(),
       jvm: {
         xms: "1g",
@@ -2092,7 +2361,7 @@ export function buildHelmValues(
       persistence: clickStackEnabled
         ? {
             enabled: true,
-            storageClass: storageClass,
+            storageClass: storageClassFor("clickstack"),
             size: clickHouseStorageSize,
           }
         : { enabled: false },
@@ -2199,6 +2468,18 @@ export function buildHelmValues(
       persistence: {
         enabled: false,
       },
+      // Serve the Origin certificate for every router without its own.
+      ...(originTls
+        ? {
+            tlsStore: {
+              default: {
+                defaultCertificate: {
+                  secretName: originCertificateSecretName(config.name),
+                },
+              },
+            },
+          }
+        : {}),
     },
 
     // =============================================================================
@@ -2238,7 +2519,7 @@ export function buildHelmValues(
     // CERT-MANAGER (TLS Certificates)
     // =============================================================================
     "cert-manager": {
-      enabled: tlsEnabled,
+      enabled: certManagerEnabled,
       // CRDs managed in parent chart (cert-manager v1.15+ uses crds.enabled,
       // not the deprecated installCRDs flag).
       crds: { enabled: false },
@@ -2302,7 +2583,7 @@ export function buildHelmValues(
 
     // Cluster Issuer for Let's Encrypt
     clusterIssuer: {
-      enabled: tlsEnabled,
+      enabled: certManagerEnabled,
       email: config.tlsEmail,
       server: "https://acme-v02.api.letsencrypt.org/directory",
     },
@@ -2368,13 +2649,7 @@ export function buildHelmValues(
             },
           },
         },
-        transforms: {
-          normalize_logs: {
-            type: "remap",
-            inputs: ["kafka"],
-            source: VECTOR_NORMALIZE_LOGS_VRL,
-          },
-        },
+        transforms: generateVectorTransforms(config),
         sinks: generateVectorSinks(config),
       },
     },
@@ -2473,14 +2748,24 @@ export function buildHelmValues(
                       podLabels: infrastructurePodLabels,
                       // Critical tier: the primary datastore must preempt burst
                       // workers to reschedule; never autoscaler-evicted.
-                      // Resources and persistence size fall back to chart
-                      // defaults.
+                      // Resources and the volume size come from
+                      // database.resources and database.storageSize when set;
+                      // otherwise they fall back to chart defaults.
                       priorityClassName: criticalPriorityClass,
+                      ...(config.database.resources
+                        ? { resources: config.database.resources }
+                        : {}),
                       podAnnotations: safeToEvictAnnotations,
-                      ...coreScheduling,
+                      ...applySchedulingOverride(
+                        statefulScheduling,
+                        config.scheduling?.database,
+                      ),
                       persistence: {
                         enabled: true,
-                        storageClassName: storageClass,
+                        storageClassName: storageClassFor("database"),
+                        ...(config.database.storageSize
+                          ? { size: config.database.storageSize }
+                          : {}),
                       },
                     },
                   }),
@@ -2515,7 +2800,7 @@ export function buildHelmValues(
                 ...coreScheduling,
                 ingress: {
                   enabled: true,
-                  className: "traefik",
+                  className: ingressClassName(config.ingress),
                   // The supabase subchart's kong ingress does NOT emit Traefik's
                   // router.entrypoints/router.tls annotations the way the app
                   // ingress does; without them Traefik only builds a web (HTTP)
@@ -2523,13 +2808,7 @@ export function buildHelmValues(
                   // reach Supabase. Inject them via the subchart's annotations
                   // passthrough (kong/ingress.yaml ranges over these), matching
                   // charts/rulebricks/templates/ingress.yaml.
-                  annotations: {
-                    "traefik.ingress.kubernetes.io/router.entrypoints":
-                      tlsEnabled ? "websecure" : "web",
-                    "traefik.ingress.kubernetes.io/router.tls": tlsEnabled
-                      ? "true"
-                      : "false",
-                  },
+                  annotations: generateIngressAnnotations(tlsEnabled),
                 },
               },
               studio: {
@@ -2625,7 +2904,9 @@ export function buildHelmValues(
         enabled: true,
         serviceAccount: generatePrometheusServiceAccount(config),
         prometheusSpec: {
-          retention: PROMETHEUS_RETENTION,
+          retention:
+            config.features.monitoring.metrics?.retention ??
+            PROMETHEUS_RETENTION,
           image: {
             registry: reg,
             repository: IMAGE_REPOSITORIES.prometheus,
@@ -2638,11 +2919,13 @@ export function buildHelmValues(
           storageSpec: {
             volumeClaimTemplate: {
               spec: {
-                storageClassName: storageClass,
+                storageClassName: storageClassFor("prometheus"),
                 accessModes: ["ReadWriteOnce"],
                 resources: {
                   requests: {
-                    storage: PROMETHEUS_STORAGE_SIZE,
+                    storage:
+                      config.features.monitoring.metrics?.storageSize ??
+                      PROMETHEUS_STORAGE_SIZE,
                   },
                 },
               },
@@ -2968,12 +3251,23 @@ export async function generateHelmValuesPreservingEdits(
   config: DeploymentConfig,
   options: GenerateOptions = {},
 ): Promise<void> {
+  await saveHelmValues(config.name, await previewHelmValues(config, options));
+}
+
+/**
+ * The values generateHelmValuesPreservingEdits would write, without writing
+ * them (`deploy --dry-run`).
+ */
+export async function previewHelmValues(
+  config: DeploymentConfig,
+  options: GenerateOptions = {},
+): Promise<Record<string, unknown>> {
   const images = await resolveGenerateImages(config, options);
   const existing = await loadHelmValues(config.name);
   const values = buildDeployValues(existing, config, { ...options, images });
   // Last-line guardrail: never write/deploy values the chart would reject.
   assertValidHelmValues(values);
-  await saveHelmValues(config.name, values);
+  return values;
 }
 
 /**
@@ -3013,14 +3307,18 @@ export async function updateHelmValuesForTLS(
       (values.global as Record<string, unknown>).tlsEnabled = tlsEnabled;
     }
 
-    // Update cert-manager
+    // Update cert-manager and the cluster issuer, unless Traefik serves a
+    // Cloudflare Origin certificate (tls.provider) and they stay off.
+    const traefikValues = values.traefik as Record<string, any> | undefined;
+    const certManagerEnabled =
+      tlsEnabled && !traefikValues?.tlsStore?.default?.defaultCertificate;
     if (values["cert-manager"] && typeof values["cert-manager"] === "object") {
-      (values["cert-manager"] as Record<string, unknown>).enabled = tlsEnabled;
+      (values["cert-manager"] as Record<string, unknown>).enabled =
+        certManagerEnabled;
     }
-
-    // Update cluster issuer
     if (values.clusterIssuer && typeof values.clusterIssuer === "object") {
-      (values.clusterIssuer as Record<string, unknown>).enabled = tlsEnabled;
+      (values.clusterIssuer as Record<string, unknown>).enabled =
+        certManagerEnabled;
     }
 
     // Update traefik TLS
@@ -3047,15 +3345,12 @@ export async function updateHelmValuesForTLS(
     if (kongIngress && typeof kongIngress === "object") {
       kongIngress.annotations = {
         ...(kongIngress.annotations as Record<string, unknown> | undefined),
-        "traefik.ingress.kubernetes.io/router.entrypoints": tlsEnabled
-          ? "websecure"
-          : "web",
-        "traefik.ingress.kubernetes.io/router.tls": tlsEnabled ? "true" : "false",
+        ...generateIngressAnnotations(tlsEnabled),
       };
     }
 
     // Save updated values
-    await fs.writeFile(valuesPath, YAML.stringify(values), "utf8");
+    await writePrivateFile(valuesPath, YAML.stringify(values));
   } catch (error) {
     throw new Error(`Failed to update Helm values: ${error}`);
   }
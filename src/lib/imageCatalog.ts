@@ -6,6 +6,8 @@ import YAML from "yaml";
 import { HELM_CHART_OCI } from "../types/index.js";
 import { BUNDLED_IMAGE_MANIFEST } from "../generated/imageManifest.js";
 import { DEFAULT_IMAGE_REGISTRY } from "./versions.js";
+import { withNetworkRetry } from "./networkRetry.js";
+import { createTempDir, removeTempPath } from "./tempFiles.js";
 
 // ============================================================================
 // Image catalog — runtime single source of truth for infrastructure image tags
@@ -238,12 +240,12 @@ async function fetchManifestFromChart(
 ): Promise<{ raw: string; chartVersion?: string } | null> {
   let tmpDir: string | null = null;
   try {
-    tmpDir = await fs.mkdtemp(path.join(os.tmpdir(), "rb-chart-manifest-"));
+    tmpDir = await createTempDir("rb-chart-manifest-");
     const args = ["pull", HELM_CHART_OCI, "--untar", "--untardir", tmpDir];
     if (version) {
       args.push("--version", version);
     }
-    await execa("helm", args, { timeout: 120000 });
+    await withNetworkRetry(() => execa("helm", args, { timeout: 120000 }));
 
     // The tarball's top-level directory is the chart name; discover it instead
     // of hardcoding "stack".
@@ -278,7 +280,7 @@ async function fetchManifestFromChart(
     return null;
   } finally {
     if (tmpDir) {
-      await fs.rm(tmpDir, { recursive: true, force: true }).catch(() => {});
+      await removeTempPath(tmpDir);
     }
   }
 }
@@ -0,0 +1,124 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  appliedConfigFor,
+  parseDeployComponents,
+  planComponentDeploy,
+  planIncrementalDeploy,
+} from "./incrementalDeploy.js";
+import { configFixture } from "./configFixtures.js";
+import { DeploymentConfig, DeploymentState } from "../types/index.js";
+
+const OPTIONS = { chartVersion: "1.4.0", secretMode: "eso" };
+
+function runningState(config: DeploymentConfig): DeploymentState {
+  return {
+    name: config.name,
+    version: config.version,
+    createdAt: "2026-01-01T00:00:00.000Z",
+    updatedAt: "2026-01-01T00:00:00.000Z",
+    status: "running",
+    appliedConfig: appliedConfigFor(config, OPTIONS),
+  };
+}
+
+test("an unchanged config plans no phases", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const plan = planIncrementalDeploy(config, runningState(config), OPTIONS);
+  assert.equal(plan.full, false);
+  assert.deepEqual([...plan.phases], []);
+});
+
+test("a backup schedule change only needs the chart upgrade", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const state = runningState(config);
+  config.backup = { enabled: true, schedule: "0 4 * * *", retentionDays: 14 };
+
+  const plan = planIncrementalDeploy(config, state, OPTIONS);
+  assert.equal(plan.full, false);
+  assert.deepEqual(plan.changed, ["backup"]);
+  assert.deepEqual([...plan.phases], ["chart"]);
+});
+
+test("a new chart version only needs the chart upgrade", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const state = runningState(config);
+  const upgrade = planIncrementalDeploy(config, state, {
+    ...OPTIONS,
+    chartVersion: "1.5.0",
+  });
+  assert.deepEqual([...upgrade.phases], ["chart"]);
+
+  // "latest" that couldn't be resolved may be anything: upgrade to be safe.
+  const unresolved = { ...OPTIONS, chartVersion: "latest" };
+  const latest = {
+    ...state,
+    appliedConfig: appliedConfigFor(config, unresolved),
+  };
+  assert.deepEqual(
+    [...planIncrementalDeploy(config, latest, unresolved).phases],
+    ["chart"],
+  );
+});
+
+test("a domain change re-runs DNS and TLS", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const state = runningState(config);
+  config.domain = "other.example.com";
+
+  const plan = planIncrementalDeploy(config, state, OPTIONS);
+  assert.ok(plan.phases.has("dns"));
+  assert.ok(!plan.phases.has("secrets"));
+});
+
+test("unmapped changes and missing baselines fall back to a full deploy", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const state = runningState(config);
+  config.infrastructure = { ...config.infrastructure, region: "eu-west-1" };
+  assert.equal(planIncrementalDeploy(config, state, OPTIONS).full, true);
+
+  const failed = { ...runningState(config), status: "failed" as const };
+  assert.equal(planIncrementalDeploy(config, failed, OPTIONS).full, true);
+
+  assert.equal(
+    planIncrementalDeploy(config, runningState(config), {
+      ...OPTIONS,
+      secretMode: "k8s",
+    }).full,
+    true,
+  );
+});
+
+test("--components names the phases to run", () => {
+  assert.deepEqual(parseDeployComponents("chart, dns,chart"), ["chart", "dns"]);
+  assert.throws(
+    () => parseDeployComponents("chart,application"),
+    /Unknown deploy component "application"/,
+  );
+  assert.throws(() => parseDeployComponents(" , "), /at least one/);
+
+  const config = configFixture("aws-self-hosted-minimal");
+  const plan = planComponentDeploy(["secrets"], runningState(config));
+  assert.equal(plan.full, false);
+  assert.deepEqual([...plan.phases], ["secrets"]);
+});
+
+test("--components refuses to run without what it builds on", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.throws(
+    () => planComponentDeploy(["chart"], null),
+    /successful deploy on record/,
+  );
+  assert.throws(
+    () =>
+      planComponentDeploy(["chart"], {
+        ...runningState(config),
+        status: "failed",
+      }),
+    /successful deploy on record/,
+  );
+  assert.throws(
+    () => planComponentDeploy(["dns"], runningState(config)),
+    /needs chart too/,
+  );
+});
@@ -0,0 +1,206 @@
+import { createHash } from "crypto";
+import type {
+  AppliedConfig,
+  DeploymentConfig,
+  DeploymentState,
+} from "../types/index.js";
+import { isConcreteChartVersion } from "./chartPin.js";
+
+/**
+ * Planning for `deploy --since-state` and `deploy --components`.
+ *
+ * A successful deploy records a fingerprint of each top-level config section
+ * in state.yaml. The next `--since-state` deploy diffs the current config
+ * against it and skips the phases no changed section feeds into. The Helm
+ * upgrade is the reconciler for everything the chart renders, so it runs
+ * whenever anything changed; what can be skipped is the slow work around it
+ * (identity federation, secrets seeding/sync, the DNS + TLS handshake) and,
+ * when nothing changed at all, the upgrade itself. Anything the mapping
+ * doesn't know about falls back to a full deploy.
+ */
+
+export type DeployPhase = "federation" | "secrets" | "chart" | "dns";
+
+/** Phases each top-level config section feeds into. */
+const SECTION_PHASES: Record<string, DeployPhase[]> = {
+  domain: ["chart", "dns"],
+  dns: ["chart", "dns"],
+  ingress: ["chart", "dns"],
+  adminEmail: ["chart"],
+  tlsEmail: ["chart", "dns"],
+  smtp: ["secrets", "chart"],
+  database: ["secrets", "chart"],
+  externalServices: ["federation", "secrets", "chart"],
+  storage: ["federation", "chart"],
+  backup: ["chart"],
+  secrets: ["federation", "secrets", "chart"],
+  features: ["secrets", "chart"],
+  licenseKey: ["secrets", "chart"],
+  version: ["chart"],
+  imageRegistry: ["chart"],
+  scheduling: ["chart"],
+  chartVersion: ["chart"],
+  // Read by the CLI when a deploy ends; nothing in the cluster uses it.
+  notifications: [],
+};
+
+export interface DeployPlan {
+  /** True when every phase runs (no usable baseline or an unmapped change). */
+  full: boolean;
+  reason?: string;
+  /** Top-level config sections that differ from the applied baseline. */
+  changed: string[];
+  phases: Set<DeployPhase>;
+}
+
+/** Stable phase names, in run order; `deploy --components` accepts these. */
+export const DEPLOY_PHASES: DeployPhase[] = [
+  "federation",
+  "secrets",
+  "chart",
+  "dns",
+];
+
+// The DNS + TLS handshake ends in a Helm upgrade with TLS turned on.
+const PHASE_REQUIRES: Partial<Record<DeployPhase, DeployPhase>> = {
+  dns: "chart",
+};
+
+function hashSection(value: unknown): string {
+  return createHash("sha256")
+    .update(JSON.stringify(value ?? null))
+    .digest("hex");
+}
+
+/** Fingerprints every top-level section of a config. */
+export function fingerprintConfig(
+  config: DeploymentConfig,
+): Record<string, string> {
+  const sections: Record<string, string> = {};
+  for (const [key, value] of Object.entries(config)) {
+    sections[key] = hashSection(value);
+  }
+  return sections;
+}
+
+function fullPlan(reason: string, changed: string[] = []): DeployPlan {
+  return { full: true, reason, changed, phases: new Set(DEPLOY_PHASES) };
+}
+
+/**
+ * Decides which phases a `--since-state` deploy must run. Falls back to a
+ * full deploy when there is no running baseline, the secret mode moved, or a
+ * changed section has no known phase mapping (including `name` and
+ * `infrastructure`, which retarget the whole deployment). A different chart
+ * version only needs the Helm upgrade, as does one that isn't concrete:
+ * "latest" could be anything, so it never matches the baseline.
+ */
+export function planIncrementalDeploy(
+  config: DeploymentConfig,
+  state: DeploymentState | null,
+  options: { chartVersion: string; secretMode: string },
+): DeployPlan {
+  const applied = state?.appliedConfig;
+  if (!state || state.status !== "running" || !applied) {
+    return fullPlan("no successful deploy recorded in state");
+  }
+  if (applied.secretMode !== options.secretMode) {
+    return fullPlan(
+      `secret mode changed (${applied.secretMode} → ${options.secretMode})`,
+    );
+  }
+
+  const current = fingerprintConfig(config);
+  const keys = new Set([
+    ...Object.keys(current),
+    ...Object.keys(applied.sections),
+  ]);
+  const changed = [...keys]
+    .filter((key) => current[key] !== applied.sections[key])
+    .sort();
+
+  const phases = new Set<DeployPhase>();
+  if (
+    !isConcreteChartVersion(options.chartVersion) ||
+    applied.chartVersion !== options.chartVersion
+  ) {
+    phases.add("chart");
+  }
+  for (const key of changed) {
+    const mapped = SECTION_PHASES[key];
+    if (!mapped) {
+      return fullPlan(`${key} changed`, changed);
+    }
+    mapped.forEach((phase) => phases.add(phase));
+  }
+
+  return { full: false, changed, phases };
+}
+
+function isDeployPhase(value: string): value is DeployPhase {
+  return (DEPLOY_PHASES as string[]).includes(value);
+}
+
+/** Parses `--components federation,chart`; throws on unknown names. */
+export function parseDeployComponents(spec: string): DeployPhase[] {
+  const phases: DeployPhase[] = [];
+  for (const entry of spec.split(",")) {
+    const name = entry.trim();
+    if (!name) continue;
+    if (!isDeployPhase(name)) {
+      throw new Error(
+        `Unknown deploy component "${name}". Use one or more of: ${DEPLOY_PHASES.join(", ")}.`,
+      );
+    }
+    if (!phases.includes(name)) phases.push(name);
+  }
+  if (phases.length === 0) {
+    throw new Error(
+      `--components needs at least one of: ${DEPLOY_PHASES.join(", ")}.`,
+    );
+  }
+  return phases;
+}
+
+/**
+ * Plan for `deploy --components`: exactly the named phases. The phases left
+ * out must already be in place, so this needs a successful deploy on record,
+ * and a phase that builds on another (the TLS upgrade on the chart) must be
+ * named together with it.
+ */
+export function planComponentDeploy(
+  components: DeployPhase[],
+  state: DeploymentState | null,
+): DeployPlan {
+  if (!state || state.status !== "running" || !state.appliedConfig) {
+    throw new Error(
+      "--components needs a successful deploy on record to build on; run a full deploy first.",
+    );
+  }
+  for (const phase of components) {
+    const required = PHASE_REQUIRES[phase];
+    if (required && !components.includes(required)) {
+      throw new Error(
+        `The ${phase} component needs ${required} too (--components ${required},${phase}).`,
+      );
+    }
+  }
+  return {
+    full: false,
+    reason: `components ${components.join(", ")}`,
+    changed: [],
+    phases: new Set(components),
+  };
+}
+
+/** Baseline to store in state after a successful deploy. */
+export function appliedConfigFor(
+  config: DeploymentConfig,
+  options: { chartVersion: string; secretMode: string },
+): AppliedConfig {
+  return {
+    sections: fingerprintConfig(config),
+    chartVersion: options.chartVersion,
+    secretMode: options.secretMode,
+  };
+}
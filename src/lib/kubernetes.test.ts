@@ -0,0 +1,287 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  databaseClaimResize,
+  describeUnreadyCertificates,
+  kubeNameMatchesCluster,
+  logComponentForPod,
+  parseCertificateList,
+  parseKafkaTopicList,
+  parseStuckNamespaces,
+  parseWarningEvents,
+} from "./kubernetes.js";
+
+test("matches the kubeconfig names each cloud CLI writes", () => {
+  for (const kubeName of [
+    "arn:aws:eks:us-east-1:123456789012:cluster/prod",
+    "admin@prod.us-east-1.eksctl.io",
+    "gke_acme-project_us-central1_prod",
+    "prod",
+    "prod-admin",
+  ]) {
+    assert.ok(kubeNameMatchesCluster(kubeName, "prod"), kubeName);
+  }
+});
+
+test("does not match a different cluster with a shared prefix", () => {
+  for (const kubeName of [
+    "arn:aws:eks:us-east-1:123456789012:cluster/prod-2",
+    "gke_acme-project_us-central1_staging",
+    "kind-prod",
+    "preprod",
+  ]) {
+    assert.ok(!kubeNameMatchesCluster(kubeName, "prod"), kubeName);
+  }
+});
+
+const CERTIFICATES = JSON.stringify({
+  items: [
+    {
+      metadata: { name: "rulebricks-tls" },
+      spec: { dnsNames: ["rules.example.com"] },
+      status: { conditions: [{ type: "Ready", status: "True" }] },
+    },
+    {
+      metadata: { name: "supabase-tls" },
+      spec: { dnsNames: ["supabase.rules.example.com"] },
+      status: {
+        conditions: [
+          {
+            type: "Ready",
+            status: "False",
+            reason: "DoesNotExist",
+            message: "Issuing certificate as Secret does not exist",
+          },
+          {
+            type: "Issuing",
+            status: "False",
+            reason: "Failed",
+            message: "ACME order failed: 403 urn:ietf:params:acme:error:unauthorized",
+          },
+        ],
+      },
+    },
+    { metadata: { name: "pending-tls" } },
+  ],
+});
+
+test("reads Ready and the failing condition from Certificates", () => {
+  const certs = parseCertificateList(CERTIFICATES);
+  assert.deepEqual(
+    certs.map((c) => [c.name, c.ready, c.failed, c.reason]),
+    [
+      ["rulebricks-tls", true, false, undefined],
+      ["supabase-tls", false, true, "Failed"],
+      ["pending-tls", false, false, undefined],
+    ],
+  );
+});
+
+test("describes each unready certificate with its reason and message", () => {
+  assert.equal(
+    describeUnreadyCertificates(parseCertificateList(CERTIFICATES)),
+    [
+      "  supabase-tls (supabase.rules.example.com): Failed: ACME order failed: 403 urn:ietf:params:acme:error:unauthorized",
+      "  pending-tls: not ready",
+    ].join("\n"),
+  );
+});
+
+test("names KafkaTopics by their broker topic and reads Ready", () => {
+  const topics = parseKafkaTopicList(
+    JSON.stringify({
+      items: [
+        {
+          metadata: { name: "logs-4f2a" },
+          spec: { topicName: "logs" },
+          status: { conditions: [{ type: "Ready", status: "True" }] },
+        },
+        {
+          metadata: { name: "solution" },
+          status: {
+            conditions: [
+              { type: "Ready", status: "False", message: "Not authorized" },
+            ],
+          },
+        },
+      ],
+    }),
+  );
+  assert.deepEqual(topics, [
+    { name: "logs", ready: true, message: undefined },
+    { name: "solution", ready: false, message: "Not authorized" },
+  ]);
+});
+
+test("keeps Warning events, most recent first", () => {
+  const events = parseWarningEvents(
+    JSON.stringify({
+      items: [
+        {
+          type: "Warning",
+          involvedObject: { kind: "Pod", name: "app-1" },
+          reason: "BackOff",
+          message: "Back-off restarting failed container\n",
+          count: 4,
+          lastTimestamp: "2026-10-15T10:00:00Z",
+        },
+        {
+          type: "Normal",
+          involvedObject: { kind: "Pod", name: "app-1" },
+          reason: "Pulled",
+          lastTimestamp: "2026-10-15T10:05:00Z",
+        },
+        {
+          type: "Warning",
+          involvedObject: { kind: "PersistentVolumeClaim", name: "data-0" },
+          reason: "ProvisioningFailed",
+          message: "quota exceeded",
+          eventTime: "2026-10-15T10:02:00Z",
+        },
+      ],
+    }),
+  );
+  assert.deepEqual(
+    events.map((e) => [e.object, e.reason, e.message, e.count]),
+    [
+      [
+        "PersistentVolumeClaim/data-0",
+        "ProvisioningFailed",
+        "quota exceeded",
+        1,
+      ],
+      ["Pod/app-1", "BackOff", "Back-off restarting failed container", 4],
+    ],
+  );
+});
+
+test("finds terminating Rulebricks namespaces and what holds them up", () => {
+  const stuck = parseStuckNamespaces(
+    JSON.stringify({
+      items: [
+        {
+          metadata: { name: "rulebricks-prod" },
+          status: { phase: "Active" },
+        },
+        {
+          metadata: {
+            name: "rulebricks-staging",
+            deletionTimestamp: "2026-10-15T10:00:00Z",
+          },
+          spec: { finalizers: ["kubernetes"] },
+          status: {
+            phase: "Terminating",
+            conditions: [
+              {
+                type: "NamespaceDeletionDiscoveryFailure",
+                status: "False",
+                message: "All resources successfully discovered",
+              },
+              {
+                type: "NamespaceContentRemaining",
+                status: "True",
+                message:
+                  "Some resources are remaining: scaledobjects.keda.sh has 1 resource instances",
+              },
+            ],
+          },
+        },
+        {
+          metadata: {
+            name: "other-app",
+            deletionTimestamp: "2026-10-15T10:00:00Z",
+          },
+          status: { phase: "Terminating" },
+        },
+      ],
+    }),
+  );
+  assert.deepEqual(stuck, [
+    {
+      name: "rulebricks-staging",
+      deletionTimestamp: "2026-10-15T10:00:00Z",
+      finalizers: ["kubernetes"],
+      blockers: [
+        "NamespaceContentRemaining: Some resources are remaining: scaledobjects.keda.sh has 1 resource instances",
+      ],
+    },
+  ]);
+});
+
+test("compares a database size with the existing Postgres claim", () => {
+  const claims = [
+    {
+      name: "data-rulebricks-prod-supabase-db-0",
+      sizeGi: 50,
+      requestedGi: 50,
+    },
+    { name: "data-rulebricks-prod-kafka-0", sizeGi: 100 },
+  ];
+  assert.deepEqual(databaseClaimResize(claims, "rulebricks-prod", "20Gi"), {
+    claim: claims[0],
+    direction: "shrink",
+  });
+  assert.equal(
+    databaseClaimResize(claims, "rulebricks-prod", "50Gi"),
+    undefined,
+  );
+  assert.equal(
+    databaseClaimResize(claims, "rulebricks-prod", "1Ti")?.direction,
+    "grow",
+  );
+  assert.equal(
+    databaseClaimResize(claims, "rulebricks-prod", "1Ei")?.direction,
+    "grow",
+  );
+  assert.equal(
+    databaseClaimResize(claims, "rulebricks-staging", "20Gi"),
+    undefined,
+  );
+  // A resize in progress: the request leads capacity and decides.
+  const resizing = [{ ...claims[0], sizeGi: 20 }];
+  assert.equal(
+    databaseClaimResize(resizing, "rulebricks-prod", "50Gi"),
+    undefined,
+  );
+});
+
+test("a grown claim under a stale StatefulSet template still needs the grow", () => {
+  // An earlier deploy patched the claim to 50Gi but failed to release the
+  // StatefulSet, whose template still asks for 20Gi.
+  const claims = [
+    {
+      name: "data-rulebricks-prod-supabase-db-0",
+      sizeGi: 50,
+      requestedGi: 50,
+    },
+  ];
+  assert.deepEqual(databaseClaimResize(claims, "rulebricks-prod", "50Gi", 20), {
+    claim: claims[0],
+    direction: "grow",
+  });
+  assert.equal(
+    databaseClaimResize(claims, "rulebricks-prod", "50Gi", 50),
+    undefined,
+  );
+  // Released already: the upgrade recreates it from the new template.
+  assert.equal(
+    databaseClaimResize(claims, "rulebricks-prod", "50Gi", null),
+    undefined,
+  );
+});
+
+test("logs all labels each pod with the most specific component", () => {
+  assert.equal(
+    logComponentForPod("rulebricks-hps-worker-6b8d-fghij"),
+    "workers",
+  );
+  assert.equal(logComponentForPod("rulebricks-hps-5d9c7-abcde"), "hps");
+  assert.equal(logComponentForPod("rulebricks-supabase-db-0"), "supabase");
+  assert.equal(logComponentForPod("rulebricks-grafana-5f-pqrst"), "grafana");
+  assert.equal(
+    logComponentForPod("prometheus-rulebricks-kube-prometheus-0"),
+    "prometheus",
+  );
+  assert.equal(logComponentForPod("rulebricks-app-7f8b9c6d5-x2k4m"), "app");
+  assert.equal(logComponentForPod("cert-manager-abc"), undefined);
+});
@@ -1,5 +1,17 @@
+import { promises as fs } from "node:fs";
+import { finished } from "node:stream/promises";
 import { execa, ExecaError } from "execa";
 import { DEFAULT_NAMESPACE, NodeArchitecture } from "../types/index.js";
+import { groupKind } from "./manifestApply.js";
+import {
+  buildNodeResourceUsage,
+  findUnschedulablePods,
+  parseNodeAllocatable,
+  parseNodeTop,
+  sumPodRequestsByNode,
+  type NodeResourceUsage,
+  type UnschedulablePod,
+} from "./resourceUsage.js";
 
 /**
  * Extracts meaningful error message from execa error
@@ -190,6 +202,62 @@ export async function getCurrentContext(): Promise<string | null> {
   }
 }
 
+/**
+ * Gets the cluster entry name of the current kubectl context. It usually
+ * carries the cloud cluster name even when the context itself was renamed.
+ */
+export async function getCurrentContextCluster(): Promise<string | null> {
+  try {
+    const { stdout } = await execa("kubectl", [
+      "config",
+      "view",
+      "--minify",
+      "-o",
+      "jsonpath={.clusters[0].name}",
+    ]);
+    return stdout.trim() || null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Whether a kubeconfig context or cluster entry name refers to the given
+ * cloud cluster, across the names each provider's CLI writes:
+ *   EKS  arn:aws:eks:<region>:<account>:cluster/<name>, <user>@<name>.<region>.eksctl.io
+ *   GKE  gke_<project>_<location>_<name>
+ *   AKS  <name>, <name>-admin
+ */
+export function kubeNameMatchesCluster(
+  kubeName: string,
+  clusterName: string,
+): boolean {
+  return (
+    kubeName === clusterName ||
+    kubeName === `${clusterName}-admin` ||
+    kubeName.endsWith(`/${clusterName}`) ||
+    kubeName.endsWith(`_${clusterName}`) ||
+    kubeName.includes(`@${clusterName}.`)
+  );
+}
+
+/**
+ * Whether kubectl's current context points at the given cloud cluster, by its
+ * context name or its cluster entry name.
+ */
+export async function currentContextMatchesCluster(
+  clusterName: string,
+): Promise<boolean> {
+  const kubeNames = await Promise.all([
+    getCurrentContext(),
+    getCurrentContextCluster(),
+  ]);
+  return kubeNames.some(
+    (kubeName) =>
+      kubeName !== null && kubeNameMatchesCluster(kubeName, clusterName),
+  );
+}
+
 function parseCpuToCores(cpu: string): number {
   if (cpu.endsWith("n")) return Number(cpu.slice(0, -1)) / 1_000_000_000;
   if (cpu.endsWith("u")) return Number(cpu.slice(0, -1)) / 1_000_000;
@@ -198,7 +266,7 @@ function parseCpuToCores(cpu: string): number {
 }
 
 function parseMemoryToGi(memory: string): number {
-  const match = memory.match(/^(\d+(?:\.\d+)?)([KMGTP]i?|[kMGTPE])?$/);
+  const match = memory.match(/^(\d+(?:\.\d+)?)([KMGTPE]i?|k)?$/);
   if (!match) return 0;
 
   const value = Number(match[1]);
@@ -209,11 +277,14 @@ function parseMemoryToGi(memory: string): number {
     Gi: 1,
     Ti: 1024,
     Pi: 1024 * 1024,
+    Ei: 1024 ** 3,
+    k: 1000 / 1024 / 1024 / 1024,
     K: 1000 / 1024 / 1024 / 1024,
     M: 1000 ** 2 / 1024 ** 3,
     G: 1000 ** 3 / 1024 ** 3,
     T: 1000 ** 4 / 1024 ** 3,
     P: 1000 ** 5 / 1024 ** 3,
+    E: 1000 ** 6 / 1024 ** 3,
   };
 
   return value * (multipliers[unit] ?? 1 / 1024 ** 3);
@@ -484,6 +555,26 @@ export interface PodStatus {
   restarts: number;
 }
 
+/**
+ * Deletes a pod so its controller recreates it (`status --repair`).
+ */
+export async function deletePod(
+  namespace: string,
+  podName: string,
+): Promise<void> {
+  try {
+    await execa(
+      "kubectl",
+      ["delete", "pod", podName, "-n", namespace, "--ignore-not-found"],
+      { timeout: 60000 },
+    );
+  } catch (error) {
+    throw new Error(
+      `Failed to delete pod ${podName}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
 /**
  * Gets service status for the Rulebricks namespace
  */
@@ -586,57 +677,81 @@ export interface IngressStatus {
 }
 
 /**
- * Gets certificate status
+ * Parses `kubectl get certificates -o json` into per-certificate status.
+ * A certificate is ready only when its Ready condition is True; the reason
+ * and message come from the failed Issuing condition when there is one
+ * (that is where ACME errors land), otherwise from Ready.
  */
-export async function getCertificateStatus(
+export function parseCertificateList(raw: string): CertificateStatus[] {
+  const data = JSON.parse(raw) as {
+    items?: Array<{
+      metadata: { name: string };
+      spec?: { dnsNames?: string[] };
+      status?: {
+        conditions?: Array<{
+          type: string;
+          status: string;
+          reason?: string;
+          message?: string;
+        }>;
+      };
+    }>;
+  };
+
+  return (data.items ?? []).map((cert) => {
+    const conditions = cert.status?.conditions ?? [];
+    const readyCond = conditions.find((c) => c.type === "Ready");
+    const issuingCond = conditions.find((c) => c.type === "Issuing");
+    const ready = readyCond?.status === "True";
+    const failed =
+      !ready &&
+      issuingCond?.status === "False" &&
+      issuingCond?.reason === "Failed";
+    const detail = failed ? issuingCond : readyCond;
+
+    return {
+      name: cert.metadata.name,
+      dnsNames: cert.spec?.dnsNames ?? [],
+      ready,
+      failed,
+      reason: detail?.reason,
+      message: detail?.message,
+    };
+  });
+}
+
+/**
+ * Lists cert-manager Certificates in a namespace. Throws when kubectl fails
+ * (including when the cert-manager CRDs are not installed).
+ */
+export async function listCertificates(
   namespace: string = DEFAULT_NAMESPACE,
 ): Promise<CertificateStatus[]> {
   try {
     const { stdout } = await execa("kubectl", [
       "get",
-      "certificates",
+      "certificates.cert-manager.io",
       "-n",
       namespace,
       "-o",
       "json",
     ]);
+    return parseCertificateList(stdout);
+  } catch (error) {
+    throw new Error(
+      `Failed to list certificates in ${namespace}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
 
-    const data = JSON.parse(stdout) as {
-      items: Array<{
-        metadata: { name: string };
-        spec: { dnsNames?: string[] };
-        status: {
-          conditions?: Array<{
-            type: string;
-            status: string;
-            reason?: string;
-            message?: string;
-          }>;
-        };
-      }>;
-    };
-
-    return data.items.map((cert) => {
-      const readyCond = cert.status.conditions?.find(
-        (c) => c.type === "Ready",
-      );
-      const issuingCond = cert.status.conditions?.find(
-        (c) => c.type === "Issuing",
-      );
-      const ready = readyCond?.status === "True";
-      const failed =
-        !ready &&
-        issuingCond?.status === "False" &&
-        issuingCond?.reason === "Failed";
-
-      return {
-        name: cert.metadata.name,
-        dnsNames: cert.spec.dnsNames ?? [],
-        ready,
-        failed: failed ?? false,
-        message: failed ? issuingCond?.message : readyCond?.message,
-      };
-    });
+/**
+ * Gets certificate status (empty when they cannot be listed)
+ */
+export async function getCertificateStatus(
+  namespace: string = DEFAULT_NAMESPACE,
+): Promise<CertificateStatus[]> {
+  try {
+    return await listCertificates(namespace);
   } catch {
     return [];
   }
@@ -647,9 +762,143 @@ export interface CertificateStatus {
   dnsNames: string[];
   ready: boolean;
   failed: boolean;
+  reason?: string;
+  message?: string;
+}
+
+/** One line per certificate that is not Ready, for timeout errors. */
+export function describeUnreadyCertificates(
+  certs: CertificateStatus[],
+): string {
+  return certs
+    .filter((c) => !c.ready)
+    .map((c) => {
+      const names = c.dnsNames.length > 0 ? ` (${c.dnsNames.join(", ")})` : "";
+      const detail = [c.reason, c.message].filter(Boolean).join(": ");
+      return `  ${c.name}${names}: ${detail || "not ready"}`;
+    })
+    .join("\n");
+}
+
+export interface KafkaTopicStatus {
+  /** The topic's name on the broker (spec.topicName, else the CR's name). */
+  name: string;
+  ready: boolean;
   message?: string;
 }
 
+/** Strimzi KafkaTopic resources, ready when the Topic Operator says so. */
+export function parseKafkaTopicList(raw: string): KafkaTopicStatus[] {
+  const data = JSON.parse(raw) as {
+    items?: Array<{
+      metadata: { name: string };
+      spec?: { topicName?: string };
+      status?: {
+        conditions?: Array<{ type: string; status: string; message?: string }>;
+      };
+    }>;
+  };
+
+  return (data.items ?? []).map((topic) => {
+    const readyCond = topic.status?.conditions?.find((c) => c.type === "Ready");
+    return {
+      name: topic.spec?.topicName || topic.metadata.name,
+      ready: readyCond?.status === "True",
+      message: readyCond?.message,
+    };
+  });
+}
+
+/**
+ * Lists the Strimzi KafkaTopics in a namespace. Throws when kubectl fails
+ * (including when the Strimzi CRDs are not installed).
+ */
+export async function listKafkaTopics(
+  namespace: string,
+): Promise<KafkaTopicStatus[]> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      ["get", "kafkatopics.kafka.strimzi.io", "-n", namespace, "-o", "json"],
+      { timeout: 15000 },
+    );
+    return parseKafkaTopicList(stdout);
+  } catch (error) {
+    throw new Error(
+      `Failed to list Kafka topics in ${namespace}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
+export interface WarningEvent {
+  /** Kind/name of the object the event is about, e.g. "Pod/app-7d9f-x2k4p". */
+  object: string;
+  reason: string;
+  message: string;
+  count: number;
+  lastSeen?: string;
+}
+
+/** Warning events, most recent first. */
+export function parseWarningEvents(raw: string): WarningEvent[] {
+  const data = JSON.parse(raw) as {
+    items?: Array<{
+      type?: string;
+      involvedObject?: { kind?: string; name?: string };
+      reason?: string;
+      message?: string;
+      count?: number;
+      lastTimestamp?: string;
+      eventTime?: string;
+      metadata?: { creationTimestamp?: string };
+    }>;
+  };
+
+  return (data.items ?? [])
+    .filter((event) => event.type === "Warning")
+    .map((event) => ({
+      object: `${event.involvedObject?.kind ?? "Object"}/${event.involvedObject?.name ?? ""}`,
+      reason: event.reason ?? "",
+      message: (event.message ?? "").trim(),
+      count: event.count ?? 1,
+      lastSeen:
+        event.lastTimestamp ||
+        event.eventTime ||
+        event.metadata?.creationTimestamp,
+    }))
+    .sort((a, b) => (b.lastSeen ?? "").localeCompare(a.lastSeen ?? ""));
+}
+
+/**
+ * The namespace's Warning events the API server still holds (an hour by
+ * default). Throws when kubectl fails.
+ */
+export async function listWarningEvents(
+  namespace: string,
+): Promise<WarningEvent[]> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      [
+        "get",
+        "events",
+        "-n",
+        namespace,
+        "--field-selector",
+        "type=Warning",
+        "-o",
+        "json",
+      ],
+      { timeout: 15000 },
+    );
+    return parseWarningEvents(stdout);
+  } catch (error) {
+    throw new Error(
+      `Failed to list events in ${namespace}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
 /**
  * Deletes a failed cert-manager Certificate and recreates it from its spec,
  * bypassing cert-manager's exponential backoff on failed issuance attempts.
@@ -713,8 +962,9 @@ export async function recreateFailedCertificate(
  * On failure detection: deletes and recreates the Certificate resource to
  * bypass cert-manager's 1-hour exponential backoff, then continues polling.
  *
- * Throws on timeout with details about which certs are not ready.
- * Returns silently if no Certificate resources exist in the namespace.
+ * Throws on timeout with each unready cert's condition reason and message
+ * (the ACME error, usually), or with the kubectl error if Certificates could
+ * never be listed. Returns silently if none exist in the namespace.
  */
 export async function waitForCertificatesReady(
   namespace: string,
@@ -731,39 +981,54 @@ export async function waitForCertificatesReady(
   } = options ?? {};
 
   let retriesUsed = 0;
+  let listError: unknown = null;
   const deadline = Date.now() + timeoutMs;
 
+  // A failed list is not "no certificates": keep polling until it works.
+  const poll = async (): Promise<CertificateStatus[] | null> => {
+    try {
+      const certs = await listCertificates(namespace);
+      listError = null;
+      return certs;
+    } catch (error) {
+      listError = error;
+      return null;
+    }
+  };
+
   while (Date.now() < deadline) {
-    const certs = await getCertificateStatus(namespace);
+    const certs = await poll();
 
-    if (certs.length === 0) return;
-    if (certs.every((c) => c.ready)) return;
+    if (certs) {
+      if (certs.length === 0) return;
+      if (certs.every((c) => c.ready)) return;
 
-    const failed = certs.filter((c) => c.failed);
-    if (failed.length > 0 && retriesUsed < maxRetries) {
-      for (const cert of failed) {
-        await recreateFailedCertificate(namespace, cert.name);
+      const failed = certs.filter((c) => c.failed);
+      if (failed.length > 0 && retriesUsed < maxRetries) {
+        for (const cert of failed) {
+          await recreateFailedCertificate(namespace, cert.name);
+        }
+        retriesUsed++;
       }
-      retriesUsed++;
     }
 
     await sleep(pollIntervalMs);
   }
 
   // Final check after timeout
-  const certs = await getCertificateStatus(namespace);
-  if (certs.length > 0 && certs.every((c) => c.ready)) return;
-
-  const notReady = certs.filter((c) => !c.ready);
-  if (notReady.length > 0) {
-    const details = notReady
-      .map((c) => `  ${c.name}: ${c.message || "not ready"}`)
-      .join("\n");
-    throw new Error(
-      `TLS certificates not ready after ${timeoutMs / 1000}s:\n${details}\n\n` +
-        `Run 'rulebricks status' to check certificate status.`,
-    );
+  const certs = await poll();
+  if (!certs) {
+    throw listError instanceof Error
+      ? listError
+      : new Error(`Failed to list certificates in ${namespace}`);
   }
+  if (certs.every((c) => c.ready)) return;
+
+  throw new Error(
+    `TLS certificates not ready after ${timeoutMs / 1000}s:\n` +
+      `${describeUnreadyCertificates(certs)}\n\n` +
+      `Run 'rulebricks status' to check certificate status.`,
+  );
 }
 
 /**
@@ -802,21 +1067,100 @@ export async function execInPod(
   podName: string,
   container: string | undefined,
   args: string[],
+  options: { input?: string } = {},
 ): Promise<string> {
   const kubectlArgs = ["exec", "-n", namespace, podName];
+  if (options.input !== undefined) {
+    // Feeds `input` to the command's stdin, keeping it out of argv.
+    kubectlArgs.push("-i");
+  }
   if (container) {
     kubectlArgs.push("-c", container);
   }
   kubectlArgs.push("--", ...args);
 
   try {
-    const { stdout } = await execa("kubectl", kubectlArgs);
+    const { stdout } = await execa("kubectl", kubectlArgs, {
+      input: options.input,
+    });
     return stdout;
   } catch (error) {
     throw new Error(`Failed to exec into pod ${podName}:\n${getErrorMessage(error)}`);
   }
 }
 
+/**
+ * psql as supabase_admin in the bundled Supabase database container,
+ * authenticating with the container's own POSTGRES_PASSWORD so no
+ * credential leaves the cluster. Run with `sh -c`.
+ */
+export const SUPABASE_DB_PSQL =
+  'PGPASSWORD="$POSTGRES_PASSWORD" exec psql -h localhost -U supabase_admin -d postgres';
+
+/** The Service in front of a release's bundled Supabase database. */
+export function supabaseDbService(releaseName: string): string {
+  return `svc/${releaseName}-supabase-db`;
+}
+
+/**
+ * Runs SQL in the bundled Supabase database, stopping at the first error,
+ * and returns psql's output. The SQL goes in on stdin, so the values it
+ * carries never appear in a process list.
+ */
+export async function runSupabaseDbSql(
+  namespace: string,
+  releaseName: string,
+  sql: string,
+): Promise<string> {
+  return execInPod(
+    namespace,
+    supabaseDbService(releaseName),
+    undefined,
+    ["sh", "-c", `${SUPABASE_DB_PSQL} -v ON_ERROR_STOP=1 -f -`],
+    { input: sql },
+  );
+}
+
+/**
+ * Runs a command in a pod and streams its stdout into a new local file
+ * (created 0600), for binary output such as database dumps. Refuses to
+ * overwrite an existing file, resolves once the file is fully written, and
+ * removes a partial file when the command fails.
+ */
+export async function execInPodToFile(
+  namespace: string,
+  podName: string,
+  container: string | undefined,
+  args: string[],
+  filePath: string,
+): Promise<void> {
+  const kubectlArgs = ["exec", "-n", namespace, podName];
+  if (container) {
+    kubectlArgs.push("-c", container);
+  }
+  kubectlArgs.push("--", ...args);
+
+  // "wx": an existing file would keep its own mode, so never reuse one.
+  let handle: fs.FileHandle;
+  try {
+    handle = await fs.open(filePath, "wx", 0o600);
+  } catch (error) {
+    if ((error as NodeJS.ErrnoException).code === "EEXIST") {
+      throw new Error(`${filePath} already exists; choose another path`);
+    }
+    throw error;
+  }
+  const out = handle.createWriteStream();
+  try {
+    await execa("kubectl", kubectlArgs).pipeStdout!(out);
+    await finished(out);
+  } catch (error) {
+    out.destroy();
+    await fs.unlink(filePath).catch(() => {});
+    throw new Error(`Failed to exec into pod ${podName}:\n${getErrorMessage(error)}`);
+  }
+}
+
 export interface EphemeralJobOptions {
   name: string;
   namespace: string;
@@ -830,6 +1174,11 @@ export interface EphemeralJobOptions {
   // e.g. an rclone download that hands off to a postgres pg_restore via a shared
   // emptyDir. Each entry is a raw container spec.
   initContainers?: Array<Record<string, unknown>>;
+  // Files mounted read-only into the job container from a ConfigMap named
+  // after the job, which is removed again when the job ends. For images
+  // without a shell, where a command can't write its own config.
+  configFiles?: { mountPath: string; files: Record<string, string> };
+  imagePullSecrets?: Array<{ name: string }>;
   labels?: Record<string, string>;
   backoffLimit?: number;
   timeoutSeconds?: number;
@@ -853,6 +1202,8 @@ export async function runEphemeralJob(
     volumeMounts = [],
     volumes = [],
     initContainers = [],
+    configFiles,
+    imagePullSecrets = [],
     labels = {},
     backoffLimit = 0,
     timeoutSeconds = 3600,
@@ -868,14 +1219,28 @@ export async function runEphemeralJob(
         imagePullPolicy: "IfNotPresent",
         command,
         env,
-        volumeMounts,
+        volumeMounts: configFiles
+          ? [
+              ...volumeMounts,
+              {
+                name: "job-files",
+                mountPath: configFiles.mountPath,
+                readOnly: true,
+              },
+            ]
+          : volumeMounts,
       },
     ],
-    volumes,
+    volumes: configFiles
+      ? [...volumes, { name: "job-files", configMap: { name } }]
+      : volumes,
   };
   if (initContainers.length > 0) {
     podSpec.initContainers = initContainers;
   }
+  if (imagePullSecrets.length > 0) {
+    podSpec.imagePullSecrets = imagePullSecrets;
+  }
 
   const manifest = {
     apiVersion: "batch/v1",
@@ -905,6 +1270,16 @@ export async function runEphemeralJob(
       namespace,
       "--ignore-not-found=true",
     ]);
+    if (configFiles) {
+      await execa("kubectl", ["apply", "-f", "-"], {
+        input: JSON.stringify({
+          apiVersion: "v1",
+          kind: "ConfigMap",
+          metadata: { name, namespace, labels },
+          data: configFiles.files,
+        }),
+      });
+    }
     await execa("kubectl", ["apply", "-f", "-"], {
       input: JSON.stringify(manifest),
     });
@@ -926,6 +1301,17 @@ export async function runEphemeralJob(
       throw new Error(`Job ${name} failed:\n${logs || getErrorMessage(error)}`);
     }
     throw new Error(`Job ${name} did not complete:\n${logs || getErrorMessage(error)}`);
+  } finally {
+    if (configFiles) {
+      await execa("kubectl", [
+        "delete",
+        "configmap",
+        name,
+        "-n",
+        namespace,
+        "--ignore-not-found=true",
+      ]).catch(() => undefined);
+    }
   }
 }
 
@@ -1027,6 +1413,78 @@ export async function scaleDeployment(
   }
 }
 
+export interface ScaledObjectBounds {
+  name: string;
+  minReplicaCount?: number;
+  maxReplicaCount?: number;
+}
+
+/** The KEDA ScaledObject that scales a Deployment, or null when none does. */
+export async function getScaledObjectFor(
+  namespace: string,
+  deploymentName: string,
+): Promise<ScaledObjectBounds | null> {
+  try {
+    const { stdout } = await execa("kubectl", [
+      "get",
+      "scaledobjects.keda.sh",
+      "-n",
+      namespace,
+      "-o",
+      "json",
+    ]);
+    const data = JSON.parse(stdout) as {
+      items?: Array<{
+        metadata: { name: string };
+        spec?: {
+          scaleTargetRef?: { name?: string };
+          minReplicaCount?: number;
+          maxReplicaCount?: number;
+        };
+      }>;
+    };
+    const match = (data.items ?? []).find(
+      (item) => item.spec?.scaleTargetRef?.name === deploymentName,
+    );
+    return match
+      ? {
+          name: match.metadata.name,
+          minReplicaCount: match.spec?.minReplicaCount,
+          maxReplicaCount: match.spec?.maxReplicaCount,
+        }
+      : null;
+  } catch (error) {
+    throw new Error(
+      `Failed to list ScaledObjects in ${namespace}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
+export async function patchScaledObjectBounds(
+  namespace: string,
+  name: string,
+  minReplicaCount: number,
+  maxReplicaCount: number,
+): Promise<void> {
+  try {
+    await execa("kubectl", [
+      "patch",
+      "scaledobjects.keda.sh",
+      name,
+      "-n",
+      namespace,
+      "--type",
+      "merge",
+      "-p",
+      JSON.stringify({ spec: { minReplicaCount, maxReplicaCount } }),
+    ]);
+  } catch (error) {
+    throw new Error(
+      `Failed to patch ScaledObject ${name}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
 export async function waitForDeploymentReady(
   namespace: string,
   name: string,
@@ -1046,6 +1504,29 @@ export async function waitForDeploymentReady(
   }
 }
 
+/** Waits for a restarted workload of any kind to finish rolling out. */
+export async function waitForRolloutReady(
+  namespace: string,
+  workloadType: WorkloadType,
+  name: string,
+  timeoutSeconds = 600,
+): Promise<void> {
+  try {
+    await execa("kubectl", [
+      "rollout",
+      "status",
+      `${workloadType}/${name}`,
+      "-n",
+      namespace,
+      `--timeout=${timeoutSeconds}s`,
+    ]);
+  } catch (error) {
+    throw new Error(
+      `${workloadType} ${name} is not ready:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
 export async function getDeploymentReplicas(
   namespace: string,
   name: string,
@@ -1066,13 +1547,176 @@ export async function getDeploymentReplicas(
   }
 }
 
-/**
- * Colors for multi-pod log prefixes
- */
-const POD_COLORS = [
-  "\x1b[36m", // cyan
-  "\x1b[33m", // yellow
-  "\x1b[35m", // magenta
+/** Secrets matching a label selector, as the raw `kubectl get -o json` List. */
+export async function getSecretsJson(
+  namespace: string,
+  selector: string,
+): Promise<string> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      ["get", "secrets", "-n", namespace, "-l", selector, "-o", "json"],
+      { timeout: 30000 },
+    );
+    return stdout;
+  } catch (error) {
+    throw new Error(
+      `Failed to read secrets in ${namespace}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
+/**
+ * Creates or replaces a kubernetes.io/tls Secret from PEM-encoded
+ * certificate and key, as `kubectl create secret tls` would.
+ */
+export async function createTLSSecret(
+  namespace: string,
+  name: string,
+  certificate: string,
+  privateKey: string,
+  annotations: Record<string, string> = {},
+): Promise<void> {
+  const manifest = JSON.stringify({
+    apiVersion: "v1",
+    kind: "Secret",
+    type: "kubernetes.io/tls",
+    metadata: {
+      name,
+      namespace,
+      labels: { "app.kubernetes.io/managed-by": "rulebricks-cli" },
+      annotations,
+    },
+    data: {
+      "tls.crt": Buffer.from(certificate).toString("base64"),
+      "tls.key": Buffer.from(privateKey).toString("base64"),
+    },
+  });
+  // create/replace rather than apply: apply would copy the whole Secret,
+  // private key included, into its last-applied-configuration annotation.
+  try {
+    try {
+      await execa("kubectl", ["create", "-f", "-"], { input: manifest });
+    } catch (error) {
+      if (!/AlreadyExists/i.test(getErrorMessage(error))) throw error;
+      await execa("kubectl", ["replace", "-f", "-"], { input: manifest });
+    }
+  } catch (error) {
+    throw new Error(
+      `Failed to write TLS secret ${namespace}/${name}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
+export interface TLSSecretCertificate {
+  /** PEM. */
+  certificate: string;
+  annotations: Record<string, string>;
+}
+
+/**
+ * The certificate of a TLS Secret and the Secret's annotations, or null when
+ * it doesn't exist.
+ */
+export async function getTLSSecretCertificate(
+  namespace: string,
+  name: string,
+): Promise<TLSSecretCertificate | null> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      ["get", "secret", name, "-n", namespace, "-o", "json"],
+      { timeout: 30000 },
+    );
+    const secret = JSON.parse(stdout) as {
+      metadata?: { annotations?: Record<string, string> };
+      data?: Record<string, string>;
+    };
+    const encoded = secret.data?.["tls.crt"];
+    if (!encoded) return null;
+    return {
+      certificate: Buffer.from(encoded, "base64").toString("utf-8"),
+      annotations: secret.metadata?.annotations ?? {},
+    };
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Deployments, StatefulSets and DaemonSets in a namespace, as the raw
+ * `kubectl get -o json` List.
+ */
+export async function getWorkloadsJson(namespace: string): Promise<string> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      [
+        "get",
+        "deployments,statefulsets,daemonsets",
+        "-n",
+        namespace,
+        "-o",
+        "json",
+      ],
+      { timeout: 30000 },
+    );
+    return stdout;
+  } catch (error) {
+    throw new Error(
+      `Failed to list workloads in ${namespace}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
+/**
+ * Per-node allocatable vs. requested (and, via metrics-server, used) CPU and
+ * memory, plus the namespace's pods that cannot be scheduled for lack of
+ * room. Live usage is null when `kubectl top` fails (no metrics-server).
+ */
+export async function getClusterResourceUsage(namespace: string): Promise<{
+  nodes: NodeResourceUsage[];
+  unschedulable: UnschedulablePod[];
+}> {
+  const kubectlJson = async (args: string[]) => {
+    try {
+      const { stdout } = await execa("kubectl", [...args, "-o", "json"], {
+        timeout: 30000,
+      });
+      return stdout;
+    } catch (error) {
+      throw new Error(
+        `Failed to read cluster resources:\n${getErrorMessage(error)}`,
+      );
+    }
+  };
+
+  const [nodesRaw, podsRaw, namespacePodsRaw, top] = await Promise.all([
+    kubectlJson(["get", "nodes"]),
+    kubectlJson(["get", "pods", "-A"]),
+    kubectlJson(["get", "pods", "-n", namespace]),
+    execa("kubectl", ["top", "nodes", "--no-headers"], { timeout: 30000 })
+      .then(({ stdout }) => parseNodeTop(stdout))
+      .catch(() => null),
+  ]);
+
+  return {
+    nodes: buildNodeResourceUsage(
+      parseNodeAllocatable(nodesRaw),
+      sumPodRequestsByNode(podsRaw),
+      top,
+    ),
+    unschedulable: findUnschedulablePods(namespacePodsRaw),
+  };
+}
+
+/**
+ * Colors for multi-pod log prefixes
+ */
+const POD_COLORS = [
+  "\x1b[36m", // cyan
+  "\x1b[33m", // yellow
+  "\x1b[35m", // magenta
   "\x1b[32m", // green
   "\x1b[34m", // blue
   "\x1b[91m", // bright red
@@ -1102,11 +1746,22 @@ export function streamMultiPodLogs(
     follow?: boolean;
     tail?: number;
     timestamps?: boolean;
+    /** Stream every container, each line prefixed "[pod/<pod>/<container>]". */
+    allContainers?: boolean;
     onLine?: LogLineCallback;
+    /** Called once every kubectl process has exited and its output flushed. */
+    onDone?: () => void;
   } = {},
 ): () => void {
-  const { follow = true, tail = 100, timestamps = false, onLine } = options;
-  const processes: Array<{ kill: (signal?: string) => void }> = [];
+  const {
+    follow = true,
+    tail = 100,
+    timestamps = false,
+    allContainers = false,
+    onLine,
+    onDone,
+  } = options;
+  const processes: Array<ReturnType<typeof execa>> = [];
 
   // Spawn a kubectl logs process for each pod
   podNames.forEach((podName, index) => {
@@ -1124,6 +1779,10 @@ export function streamMultiPodLogs(
       args.push("--timestamps");
     }
 
+    if (allContainers) {
+      args.push("--all-containers=true", "--prefix");
+    }
+
     const colorIndex = index % POD_COLORS.length;
     const color = POD_COLORS[colorIndex];
 
@@ -1184,6 +1843,11 @@ export function streamMultiPodLogs(
     proc.catch(() => {});
   });
 
+  if (onDone) {
+    // execa settles after the stdio streams close, so every line is out.
+    Promise.allSettled(processes).then(() => onDone());
+  }
+
   // Return cleanup function
   return () => {
     for (const proc of processes) {
@@ -1256,8 +1920,14 @@ export const VALID_LOG_COMPONENTS = [
   "supabase",
   "traefik",
   "redis",
+  "grafana",
+  "prometheus",
+  "vector",
 ];
 
+/** `rulebricks logs all`: every log component's pods in one stream. */
+export const ALL_LOG_COMPONENTS = "all";
+
 /**
  * Pod name patterns for each component.
  * Used to filter pods by name when label selectors may vary.
@@ -1269,21 +1939,64 @@ const COMPONENT_POD_PATTERNS: Record<string, string[]> = {
   kafka: ["kafka"],
   supabase: ["supabase", "db", "postgres"],
   traefik: ["traefik"],
-  redis: ["redis", "dragonfly", "keydb"],
+  redis: ["redis", "dragonfly", "keydb", "valkey"],
+  grafana: ["grafana"],
+  prometheus: ["prometheus"],
+  vector: ["vector"],
 };
 
+/**
+ * Order in which a pod is attributed to a single component, most specific
+ * first ("hps-worker" pods also match hps, the Supabase "db" pattern matches
+ * little else once the others have had their turn).
+ */
+const LOG_COMPONENT_MATCH_ORDER = [
+  "workers",
+  "hps",
+  "kafka",
+  "redis",
+  "traefik",
+  "grafana",
+  "prometheus",
+  "vector",
+  "supabase",
+  "app",
+];
+
+function podMatchesComponent(podName: string, component: string): boolean {
+  const patterns = COMPONENT_POD_PATTERNS[component] || [component];
+  const lowerPodName = podName.toLowerCase();
+  return patterns.some((pattern) =>
+    lowerPodName.includes(pattern.toLowerCase()),
+  );
+}
+
+/**
+ * The log component a pod belongs to, for labelling `logs all` output, or
+ * undefined for pods no component claims.
+ */
+export function logComponentForPod(podName: string): string | undefined {
+  return LOG_COMPONENT_MATCH_ORDER.find((component) =>
+    podMatchesComponent(podName, component),
+  );
+}
+
 /**
  * Gets pods for a specific component in a deployment.
  * Queries all pods in the namespace and filters by component name patterns.
  * This approach works for all components including subcharts like Traefik
  * that may have different instance labels than the parent release.
+ * ALL_LOG_COMPONENTS returns the pods of every log component.
  */
 export async function getComponentPods(
   component: string,
   _releaseName: string,
   namespace: string,
 ): Promise<string[]> {
-  if (!VALID_LOG_COMPONENTS.includes(component)) {
+  if (
+    component !== ALL_LOG_COMPONENTS &&
+    !VALID_LOG_COMPONENTS.includes(component)
+  ) {
     return [];
   }
 
@@ -1302,15 +2015,10 @@ export async function getComponentPods(
     const pods = stdout.split(" ").filter(Boolean);
 
     // Filter pods by component name patterns
-    const patterns = COMPONENT_POD_PATTERNS[component] || [component];
-    const matchingPods = pods.filter((podName) => {
-      const lowerPodName = podName.toLowerCase();
-      return patterns.some((pattern) =>
-        lowerPodName.includes(pattern.toLowerCase()),
-      );
-    });
-
-    return matchingPods;
+    if (component === ALL_LOG_COMPONENTS) {
+      return pods.filter((podName) => logComponentForPod(podName));
+    }
+    return pods.filter((podName) => podMatchesComponent(podName, component));
   } catch {
     return [];
   }
@@ -1341,6 +2049,223 @@ export async function deleteNamespace(
   }
 }
 
+export interface PersistentVolumeClaimInfo {
+  name: string;
+  storageClass?: string;
+  sizeGi: number;
+  /** What the claim asks for (spec), which leads capacity during a resize. */
+  requestedGi?: number;
+  /** Pending, Bound or Lost. */
+  phase?: string;
+}
+
+/**
+ * Lists the PVCs in a namespace with their requested size
+ */
+export async function getPersistentVolumeClaims(
+  namespace: string,
+): Promise<PersistentVolumeClaimInfo[]> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      ["get", "pvc", "-n", namespace, "-o", "json"],
+      { timeout: 15000 },
+    );
+    const data = JSON.parse(stdout) as {
+      items?: Array<{
+        metadata?: { name?: string };
+        spec?: {
+          storageClassName?: string;
+          resources?: { requests?: { storage?: string } };
+        };
+        status?: { phase?: string; capacity?: { storage?: string } };
+      }>;
+    };
+    return (data.items ?? []).map((item) => ({
+      name: item.metadata?.name ?? "",
+      storageClass: item.spec?.storageClassName,
+      sizeGi: parseMemoryToGi(
+        item.status?.capacity?.storage ||
+          item.spec?.resources?.requests?.storage ||
+          "0",
+      ),
+      requestedGi: item.spec?.resources?.requests?.storage
+        ? parseMemoryToGi(item.spec.resources.requests.storage)
+        : undefined,
+      phase: item.status?.phase,
+    }));
+  } catch (error) {
+    throw new Error(`Failed to list PVCs:\n${getErrorMessage(error)}`);
+  }
+}
+
+/** The bundled Postgres StatefulSet, whose volumeClaimTemplate sizes it. */
+export function supabaseDbStatefulSet(releaseName: string): string {
+  return `${releaseName}-supabase-db`;
+}
+
+export interface DatabaseClaimResize {
+  claim: PersistentVolumeClaimInfo;
+  /** Kubernetes can grow a volume in place but never shrink one. */
+  direction: "grow" | "shrink";
+}
+
+/**
+ * How `size` differs from the release's Postgres claim, or undefined when it
+ * matches or there is no claim yet. The claim comes from the StatefulSet's
+ * volumeClaimTemplate, which helm can't change in place, so any difference
+ * needs handling before the upgrade. `templateGi` is that template's current
+ * size, when known: a claim already at `size` under a smaller template is
+ * still a "grow", since a deploy that grew it didn't get to release the
+ * StatefulSet.
+ */
+export function databaseClaimResize(
+  claims: PersistentVolumeClaimInfo[],
+  releaseName: string,
+  size: string,
+  templateGi?: number | null,
+): DatabaseClaimResize | undefined {
+  const wantedGi = parseMemoryToGi(size);
+  const claim = claims.find((c) =>
+    c.name.includes(supabaseDbStatefulSet(releaseName)),
+  );
+  if (!claim) return undefined;
+  const currentGi = claim.requestedGi ?? claim.sizeGi;
+  if (Math.abs(currentGi - wantedGi) < 1e-6) {
+    return templateGi != null && wantedGi - templateGi > 1e-6
+      ? { claim, direction: "grow" }
+      : undefined;
+  }
+  return { claim, direction: wantedGi > currentGi ? "grow" : "shrink" };
+}
+
+/**
+ * Storage requested by a StatefulSet's first volumeClaimTemplate, or null
+ * when the StatefulSet doesn't exist.
+ */
+export async function getStatefulSetClaimTemplateGi(
+  namespace: string,
+  name: string,
+): Promise<number | null> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      [
+        "get",
+        "statefulset",
+        name,
+        "-n",
+        namespace,
+        "--ignore-not-found",
+        "-o",
+        "jsonpath={.spec.volumeClaimTemplates[0].spec.resources.requests.storage}",
+      ],
+      { timeout: 15000 },
+    );
+    return stdout.trim() ? parseMemoryToGi(stdout.trim()) : null;
+  } catch (error) {
+    throw new Error(
+      `Failed to read StatefulSet ${name}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
+/**
+ * Grows the bundled Postgres volume to `size` ahead of a helm upgrade that
+ * carries the larger volumeClaimTemplate. The claim is patched first, which
+ * needs a storage class with allowVolumeExpansion. Then the StatefulSet is
+ * deleted with --cascade=orphan: its pod keeps running, and the upgrade
+ * recreates it from the new template. The StatefulSet's template is checked
+ * too, so a rerun after a failed delete finishes the job (patching a claim to
+ * its current size is a no-op). Returns the claim that was grown.
+ */
+export async function growDatabaseClaim(
+  namespace: string,
+  releaseName: string,
+  size: string,
+): Promise<string | null> {
+  const resize = databaseClaimResize(
+    await getPersistentVolumeClaims(namespace),
+    releaseName,
+    size,
+    await getStatefulSetClaimTemplateGi(
+      namespace,
+      supabaseDbStatefulSet(releaseName),
+    ),
+  );
+  if (resize?.direction !== "grow") return null;
+  try {
+    await execa(
+      "kubectl",
+      [
+        "patch",
+        "pvc",
+        resize.claim.name,
+        "-n",
+        namespace,
+        "--type",
+        "merge",
+        "-p",
+        JSON.stringify({
+          spec: { resources: { requests: { storage: size } } },
+        }),
+      ],
+      { timeout: 30000 },
+    );
+  } catch (error) {
+    throw new Error(
+      `Failed to grow ${resize.claim.name} to ${size} (does its storage class allow volume expansion?):\n${getErrorMessage(error)}`,
+    );
+  }
+  try {
+    await execa(
+      "kubectl",
+      [
+        "delete",
+        "statefulset",
+        supabaseDbStatefulSet(releaseName),
+        "-n",
+        namespace,
+        "--cascade=orphan",
+        "--ignore-not-found",
+      ],
+      { timeout: 30000 },
+    );
+  } catch (error) {
+    throw new Error(
+      `Grew ${resize.claim.name}, but failed to release its StatefulSet for the upgrade:\n${getErrorMessage(error)}`,
+    );
+  }
+  return resize.claim.name;
+}
+
+/**
+ * Marks every PVC in a namespace with Helm's keep policy, so `helm
+ * uninstall` leaves the release's own claims (not only StatefulSet ones) in
+ * place for the next install to adopt (`destroy --keep-data`).
+ */
+export async function protectPVCsFromUninstall(
+  namespace: string,
+): Promise<void> {
+  try {
+    await execa(
+      "kubectl",
+      [
+        "annotate",
+        "pvc",
+        "--all",
+        "-n",
+        namespace,
+        "helm.sh/resource-policy=keep",
+        "--overwrite",
+      ],
+      { timeout: 30000 },
+    );
+  } catch (error) {
+    throw new Error(`Failed to protect PVCs:\n${getErrorMessage(error)}`);
+  }
+}
+
 /**
  * Deletes all PVCs in a namespace
  */
@@ -1656,6 +2581,118 @@ export async function forceReleaseStuckNamespaceFinalizers(
   return processed;
 }
 
+// Namespace conditions that explain why deletion hasn't finished.
+const NAMESPACE_BLOCKING_CONDITIONS = new Set([
+  "NamespaceContentRemaining",
+  "NamespaceFinalizersRemaining",
+  "NamespaceDeletionDiscoveryFailure",
+  "NamespaceDeletionContentFailure",
+  "NamespaceDeletionGroupVersionParsingFailure",
+]);
+
+export interface StuckNamespace {
+  name: string;
+  /** When deletion was requested. */
+  deletionTimestamp?: string;
+  /** The namespace's own finalizers (spec.finalizers), e.g. "kubernetes". */
+  finalizers: string[];
+  /** Messages of the conditions holding deletion up. */
+  blockers: string[];
+}
+
+/**
+ * Terminating Rulebricks namespaces from `kubectl get namespaces -o json`.
+ * Namespaces are named `rulebricks-<name>` (see getNamespace).
+ */
+export function parseStuckNamespaces(raw: string): StuckNamespace[] {
+  const data = JSON.parse(raw) as {
+    items?: Array<{
+      metadata?: { name?: string; deletionTimestamp?: string };
+      spec?: { finalizers?: string[] };
+      status?: {
+        phase?: string;
+        conditions?: Array<{ type?: string; status?: string; message?: string }>;
+      };
+    }>;
+  };
+
+  return (data.items ?? [])
+    .filter(
+      (ns) =>
+        ns.metadata?.name?.startsWith("rulebricks-") &&
+        (ns.metadata?.deletionTimestamp || ns.status?.phase === "Terminating"),
+    )
+    .map((ns) => ({
+      name: ns.metadata!.name!,
+      deletionTimestamp: ns.metadata?.deletionTimestamp,
+      finalizers: ns.spec?.finalizers ?? [],
+      blockers: (ns.status?.conditions ?? [])
+        .filter(
+          (c) =>
+            c.status === "True" &&
+            NAMESPACE_BLOCKING_CONDITIONS.has(c.type ?? ""),
+        )
+        .map((c) => `${c.type}: ${c.message ?? ""}`.trim()),
+    }));
+}
+
+export async function listStuckNamespaces(): Promise<StuckNamespace[]> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      ["get", "namespaces", "-o", "json"],
+      { timeout: 30000 },
+    );
+    return parseStuckNamespaces(stdout);
+  } catch (error) {
+    throw new Error(`Failed to list namespaces:\n${getErrorMessage(error)}`);
+  }
+}
+
+/**
+ * Last resort for a namespace that still won't finish: clears its own
+ * spec.finalizers through the finalize subresource, so the API server
+ * deletes it without waiting for its contents. Anything still inside is
+ * orphaned in etcd, which is why namespaces cleanup only does this on
+ * request.
+ */
+export async function clearNamespaceFinalizers(
+  namespace: string,
+): Promise<void> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      ["get", "namespace", namespace, "-o", "json"],
+      { timeout: 15000 },
+    );
+    const parsed = JSON.parse(stdout) as { spec?: { finalizers?: string[] } };
+    await execa(
+      "kubectl",
+      [
+        "replace",
+        "--raw",
+        `/api/v1/namespaces/${namespace}/finalize`,
+        "-f",
+        "-",
+      ],
+      {
+        input: JSON.stringify({
+          ...parsed,
+          spec: { ...parsed.spec, finalizers: [] },
+        }),
+        timeout: 30000,
+      },
+    );
+  } catch (error) {
+    const errorMsg =
+      (error as ExecaError).stderr || (error as ExecaError).message || "";
+    if (errorMsg.includes("not found")) return;
+    throw new Error(
+      `Failed to clear the finalizers of namespace ${namespace}:\n${getErrorMessage(error)}`,
+    );
+  }
+}
+
 /**
  * Removes this release's leftovers in the kube-system namespace. The
  * kube-prometheus-stack prometheus-operator creates a "<release>-...-kubelet"
@@ -1822,6 +2859,55 @@ export async function deleteRulebricksCRDs(): Promise<string[]> {
   return deleted;
 }
 
+/**
+ * Kinds the API server reports as cluster-scoped (Namespace, ClusterRole,
+ * StorageClass, cluster-scoped CRDs, ...) as groupKind() keys, read from
+ * the APIVERSION and KIND columns of `kubectl api-resources
+ * --namespaced=false` (SHORTNAMES may be empty, so from the end).
+ */
+export async function getClusterScopedKinds(): Promise<Set<string>> {
+  try {
+    const { stdout } = await execa(
+      "kubectl",
+      ["api-resources", "--namespaced=false", "--no-headers"],
+      { timeout: 30000 },
+    );
+    const kinds = new Set<string>();
+    for (const line of stdout.split("\n")) {
+      const columns = line.trim().split(/\s+/);
+      if (columns.length < 4) continue;
+      const kind = columns[columns.length - 1];
+      const apiVersion = columns[columns.length - 3];
+      kinds.add(groupKind(apiVersion, kind));
+    }
+    return kinds;
+  } catch (error) {
+    throw new Error(
+      `Failed to list cluster-scoped resource kinds: ${getErrorMessage(error)}`,
+    );
+  }
+}
+
+/**
+ * Deletes cluster-scoped objects recorded by `rulebricks apply`
+ * ("Kind.group/name" refs). Best-effort; returns the refs that were deleted
+ * or already gone.
+ */
+export async function deleteClusterResources(refs: string[]): Promise<string[]> {
+  const deleted: string[] = [];
+  for (const ref of refs) {
+    try {
+      await execa("kubectl", ["delete", ref, "--ignore-not-found"], {
+        timeout: 30000,
+      });
+      deleted.push(ref);
+    } catch {
+      // best-effort: one failure should not block teardown
+    }
+  }
+  return deleted;
+}
+
 /**
  * Deployed image versions from Kubernetes
  */
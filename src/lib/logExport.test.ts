@@ -0,0 +1,77 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  bundleName,
+  exportComponentForPod,
+  logSection,
+  parseExportPods,
+} from "./logExport.js";
+
+test("pods are filed under the most specific component", () => {
+  assert.equal(exportComponentForPod("rulebricks-app-7f8b9c6d5-x2k4m"), "app");
+  assert.equal(exportComponentForPod("rulebricks-hps-5d9c7-abcde"), "hps");
+  assert.equal(
+    exportComponentForPod("rulebricks-hps-worker-6b8d-fghij"),
+    "workers",
+  );
+  // Filed like `rulebricks logs` labels them, database included.
+  assert.equal(exportComponentForPod("rulebricks-supabase-db-0"), "supabase");
+  assert.equal(exportComponentForPod("rulebricks-valkey-0"), "redis");
+  assert.equal(
+    exportComponentForPod("rulebricks-supabase-auth-77c-klmno"),
+    "supabase",
+  );
+  assert.equal(
+    exportComponentForPod("prometheus-rulebricks-kube-prometheus-0"),
+    "prometheus",
+  );
+  assert.equal(exportComponentForPod("rulebricks-grafana-5f-pqrst"), "grafana");
+  assert.equal(exportComponentForPod("cert-manager-abc"), "other");
+});
+
+test("parseExportPods lists containers and the ones that restarted", () => {
+  const pods = parseExportPods(
+    JSON.stringify({
+      items: [
+        {
+          metadata: { name: "rulebricks-app-1" },
+          spec: { containers: [{ name: "app" }, { name: "proxy" }] },
+          status: {
+            containerStatuses: [
+              { name: "app", restartCount: 3 },
+              { name: "proxy", restartCount: 0 },
+            ],
+          },
+        },
+        { metadata: {}, spec: { containers: [{ name: "orphan" }] } },
+      ],
+    }),
+  );
+
+  assert.deepEqual(pods, [
+    {
+      name: "rulebricks-app-1",
+      component: "app",
+      containers: ["app", "proxy"],
+      restarted: ["app"],
+    },
+  ]);
+});
+
+test("bundle names are timestamped in UTC", () => {
+  assert.equal(
+    bundleName("prod", new Date("2026-03-04T05:06:07.890Z")),
+    "rulebricks-prod-logs-20260304-050607",
+  );
+});
+
+test("log sections are headed by their container", () => {
+  assert.equal(
+    logSection("app", "line 1\nline 2\n"),
+    "==> app <==\nline 1\nline 2\n",
+  );
+  assert.equal(
+    logSection("app", "", true),
+    "==> app (previous run) <==\n(no output)\n",
+  );
+});
@@ -0,0 +1,241 @@
+import { promises as fs } from "fs";
+import path from "path";
+import { execa } from "execa";
+import { logComponentForPod } from "./kubernetes.js";
+import { createTempDir, removeTempPath } from "./tempFiles.js";
+
+/**
+ * `rulebricks logs export`: a support bundle. Every pod in the deployment's
+ * namespace is filed under the component it belongs to, with one file of
+ * logs per pod (every container, plus the previous run of any container
+ * that restarted) and its `kubectl describe` output, alongside the pod list
+ * and recent events. The directory is archived as a .tar.gz with `tar`.
+ */
+
+export interface ExportPod {
+  name: string;
+  component: string;
+  containers: string[];
+  /** Containers that have restarted, whose previous run is also exported. */
+  restarted: string[];
+}
+
+export interface LogExportOptions {
+  /** Only logs newer than this many seconds. */
+  sinceSeconds: number;
+  /** At most this many lines per container (all within `since` if unset). */
+  tail?: number;
+}
+
+export interface LogExportResult {
+  path: string;
+  pods: number;
+  /** Pods or files that couldn't be collected; the bundle notes them too. */
+  failures: string[];
+}
+
+/**
+ * The directory a pod is filed under: its `rulebricks logs` component, so
+ * the bundle groups pods the way `logs <component>` does, else "other".
+ */
+export function exportComponentForPod(podName: string): string {
+  return logComponentForPod(podName) ?? "other";
+}
+
+/** The pods to export from `kubectl get pods -o json`. */
+export function parseExportPods(raw: string): ExportPod[] {
+  const list = JSON.parse(raw) as {
+    items?: Array<{
+      metadata?: { name?: string };
+      spec?: { containers?: Array<{ name?: string }> };
+      status?: {
+        containerStatuses?: Array<{ name?: string; restartCount?: number }>;
+      };
+    }>;
+  };
+  const pods: ExportPod[] = [];
+  for (const item of list.items ?? []) {
+    const name = item.metadata?.name;
+    if (!name) continue;
+    pods.push({
+      name,
+      component: exportComponentForPod(name),
+      containers: (item.spec?.containers ?? [])
+        .map((container) => container.name)
+        .filter((container): container is string => Boolean(container)),
+      restarted: (item.status?.containerStatuses ?? [])
+        .filter((status) => (status.restartCount ?? 0) > 0 && status.name)
+        .map((status) => status.name as string),
+    });
+  }
+  return pods;
+}
+
+/** `rulebricks-<name>-logs-20260101-120000`, in UTC. */
+export function bundleName(deploymentName: string, date: Date): string {
+  const stamp = date
+    .toISOString()
+    .replace(/\.\d+Z$/, "")
+    .replace(/[-:]/g, "")
+    .replace("T", "-");
+  return `rulebricks-${deploymentName}-logs-${stamp}`;
+}
+
+/** One section of a pod's log file, headed by the container it came from. */
+export function logSection(
+  container: string,
+  text: string,
+  previous = false,
+): string {
+  const title = previous ? `${container} (previous run)` : container;
+  return `==> ${title} <==\n${text.trimEnd() || "(no output)"}\n`;
+}
+
+async function kubectl(args: string[]): Promise<string> {
+  const { stdout } = await execa("kubectl", args, { timeout: 120000 });
+  return stdout;
+}
+
+/** kubectl output, or the reason it failed, which is worth keeping too. */
+async function kubectlOrError(
+  args: string[],
+  failures: string[],
+  what: string,
+): Promise<string> {
+  try {
+    return await kubectl(args);
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    failures.push(`${what}: ${message.split("\n")[0]}`);
+    return `(unavailable: ${message})`;
+  }
+}
+
+async function podLogs(
+  namespace: string,
+  pod: ExportPod,
+  options: LogExportOptions,
+  failures: string[],
+): Promise<string> {
+  const sections: string[] = [];
+  for (const container of pod.containers) {
+    const args = [
+      "logs",
+      pod.name,
+      "-n",
+      namespace,
+      "-c",
+      container,
+      "--timestamps",
+      `--since=${options.sinceSeconds}s`,
+      ...(options.tail !== undefined ? [`--tail=${options.tail}`] : []),
+    ];
+    const what = `${pod.name}/${container}`;
+    sections.push(
+      logSection(container, await kubectlOrError(args, failures, what)),
+    );
+    if (pod.restarted.includes(container)) {
+      // The previous run is usually the one that crashed; --since would
+      // often cut it off entirely.
+      const previous = await kubectlOrError(
+        [
+          "logs",
+          pod.name,
+          "-n",
+          namespace,
+          "-c",
+          container,
+          "--previous",
+          "--timestamps",
+          ...(options.tail !== undefined ? [`--tail=${options.tail}`] : []),
+        ],
+        failures,
+        `${what} (previous)`,
+      );
+      sections.push(logSection(container, previous, true));
+    }
+  }
+  return sections.join("\n");
+}
+
+/**
+ * Collects the bundle for `namespace` and writes it to `outputPath`.
+ * `onPod` reports progress as each pod is collected. Throws only when the
+ * pods can't be listed or the archive can't be written.
+ */
+export async function exportLogs(
+  deploymentName: string,
+  namespace: string,
+  outputPath: string,
+  options: LogExportOptions,
+  onPod?: (done: number, total: number) => void,
+): Promise<LogExportResult> {
+  const pods = parseExportPods(
+    await kubectl(["get", "pods", "-n", namespace, "-o", "json"]),
+  );
+  const failures: string[] = [];
+  const workDir = await createTempDir("rulebricks-logs-");
+  const name = bundleName(deploymentName, new Date());
+  const root = path.join(workDir, name);
+
+  try {
+    await fs.mkdir(root);
+    await fs.writeFile(
+      path.join(root, "pods.txt"),
+      await kubectlOrError(
+        ["get", "pods", "-n", namespace, "-o", "wide"],
+        failures,
+        "pod list",
+      ),
+    );
+    await fs.writeFile(
+      path.join(root, "events.txt"),
+      await kubectlOrError(
+        ["get", "events", "-n", namespace, "--sort-by=.lastTimestamp"],
+        failures,
+        "events",
+      ),
+    );
+
+    for (const [index, pod] of pods.entries()) {
+      const dir = path.join(root, pod.component);
+      await fs.mkdir(dir, { recursive: true });
+      await fs.writeFile(
+        path.join(dir, `${pod.name}.log`),
+        await podLogs(namespace, pod, options, failures),
+      );
+      await fs.writeFile(
+        path.join(dir, `${pod.name}.describe.txt`),
+        await kubectlOrError(
+          ["describe", "pod", pod.name, "-n", namespace],
+          failures,
+          `${pod.name} (describe)`,
+        ),
+      );
+      onPod?.(index + 1, pods.length);
+    }
+
+    if (failures.length > 0) {
+      await fs.writeFile(
+        path.join(root, "errors.txt"),
+        `${failures.join("\n")}\n`,
+      );
+    }
+
+    // The logs can hold secrets: the archive is private to the user, even
+    // when it replaces an existing file.
+    const archive = path.resolve(outputPath);
+    const handle = await fs.open(archive, "w", 0o600);
+    try {
+      await handle.chmod(0o600);
+      await execa("tar", ["-czf", "-", "-C", workDir, name], {
+        stdout: handle.fd,
+      });
+    } finally {
+      await handle.close();
+    }
+    return { path: archive, pods: pods.length, failures };
+  } finally {
+    await removeTempPath(workDir);
+  }
+}
@@ -0,0 +1,35 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { formatLogEnvelope, toLogEnvelope } from "./logFormat.js";
+
+test("prefix and timestamp decorations become envelope fields", () => {
+  const envelope = toLogEnvelope(
+    "hps",
+    "rulebricks-hps-7f8b9c6d5-x2k4m",
+    "[pod/rulebricks-hps-7f8b9c6d5-x2k4m/hps] 2026-01-01T00:00:00.123456789Z started",
+  );
+  assert.deepEqual(envelope, {
+    component: "hps",
+    pod: "rulebricks-hps-7f8b9c6d5-x2k4m",
+    container: "hps",
+    timestamp: "2026-01-01T00:00:00.123456789Z",
+    message: "started",
+  });
+});
+
+test("JSON messages are nested rather than escaped", () => {
+  const envelope = toLogEnvelope(
+    "app",
+    "app-1",
+    '2026-01-01T00:00:00Z {"level":"info","msg":"ready"}',
+  );
+  assert.deepEqual(envelope.message, { level: "info", msg: "ready" });
+  assert.equal(envelope.container, null);
+  assert.equal(JSON.parse(formatLogEnvelope(envelope)).message.level, "info");
+});
+
+test("malformed JSON-looking messages stay as text", () => {
+  const envelope = toLogEnvelope("app", "app-1", "{not json");
+  assert.equal(envelope.message, "{not json");
+  assert.equal(envelope.timestamp, null);
+});
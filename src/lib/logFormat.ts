@@ -0,0 +1,70 @@
+// Structured output for `rulebricks logs --output json`. Each kubectl
+// log line becomes one JSON object per line so captured logs can be piped
+// straight into jq or a log pipeline. Messages that are themselves JSON (the
+// app, HPS and Vector all log structured JSON) are nested as objects rather
+// than left as escaped strings.
+
+export type LogOutputFormat = "text" | "json";
+export const LOG_OUTPUT_FORMATS: LogOutputFormat[] = ["text", "json"];
+
+export interface LogEnvelope {
+  component: string;
+  pod: string;
+  container: string | null;
+  timestamp: string | null;
+  message: unknown;
+}
+
+export function isLogOutputFormat(value: string): value is LogOutputFormat {
+  return (LOG_OUTPUT_FORMATS as string[]).includes(value);
+}
+
+// `kubectl logs --prefix` emits "[pod/<pod>/<container>] ".
+const PREFIX_PATTERN = /^\[pod\/([^/\]]+)\/([^\]]+)\] /;
+// `kubectl logs --timestamps` emits an RFC3339Nano timestamp and a space.
+const TIMESTAMP_PATTERN = /^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})) /;
+
+function parseMessage(text: string): unknown {
+  const trimmed = text.trim();
+  if (trimmed.startsWith("{") || trimmed.startsWith("[")) {
+    try {
+      return JSON.parse(trimmed);
+    } catch {
+      // Not JSON after all; keep the raw text.
+    }
+  }
+  return text;
+}
+
+/**
+ * Wraps one raw kubectl log line (optionally carrying --prefix and
+ * --timestamps decorations) in a LogEnvelope.
+ */
+export function toLogEnvelope(
+  component: string,
+  pod: string,
+  line: string,
+): LogEnvelope {
+  let rest = line;
+  let container: string | null = null;
+  let timestamp: string | null = null;
+
+  const prefix = rest.match(PREFIX_PATTERN);
+  if (prefix) {
+    pod = prefix[1];
+    container = prefix[2];
+    rest = rest.slice(prefix[0].length);
+  }
+
+  const stamp = rest.match(TIMESTAMP_PATTERN);
+  if (stamp) {
+    timestamp = stamp[1];
+    rest = rest.slice(stamp[0].length);
+  }
+
+  return { component, pod, container, timestamp, message: parseMessage(rest) };
+}
+
+export function formatLogEnvelope(envelope: LogEnvelope): string {
+  return JSON.stringify(envelope);
+}
@@ -0,0 +1,103 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  groupKind,
+  parseManifests,
+  prepareManifests,
+} from "./manifestApply.js";
+
+const OPTIONS = {
+  namespace: "rulebricks-prod",
+  releaseName: "rulebricks-prod",
+  clusterScopedKinds: new Set([
+    "Namespace",
+    "ClusterRole.rbac.authorization.k8s.io",
+  ]),
+};
+
+test("namespaced objects get the deployment namespace and labels", () => {
+  const objects = parseManifests(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: extra
+  labels:
+    team: platform
+data:
+  key: value
+---
+`);
+  const { manifests, errors } = prepareManifests(objects, OPTIONS);
+  assert.deepEqual(errors, []);
+  assert.equal(manifests.length, 1);
+  assert.equal(manifests[0].metadata.namespace, "rulebricks-prod");
+  assert.deepEqual(manifests[0].metadata.labels, {
+    team: "platform",
+    "app.kubernetes.io/managed-by": "rulebricks-cli",
+    "app.kubernetes.io/instance": "rulebricks-prod",
+  });
+});
+
+test("other namespaces, cluster-scoped kinds and malformed docs are refused", () => {
+  const objects = parseManifests(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: elsewhere
+  namespace: default
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+kind: Secret
+metadata:
+  name: nameless-api
+`);
+  const { manifests, errors } = prepareManifests(objects, OPTIONS);
+  assert.equal(manifests.length, 0);
+  assert.equal(errors.length, 3);
+  assert.match(errors[0], /namespace "default"/);
+  assert.match(errors[1], /--allow-cluster-scoped/);
+  assert.match(errors[2], /Document 3/);
+});
+
+test("allowed cluster-scoped objects are recorded without a namespace", () => {
+  const objects = parseManifests(`
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: rbac.authorization.k8s.io/v1
+    kind: ClusterRole
+    metadata:
+      name: reader
+      namespace: ignored
+`);
+  const prepared = prepareManifests(objects, {
+    ...OPTIONS,
+    allowClusterScoped: true,
+  });
+  assert.deepEqual(prepared.errors, []);
+  assert.deepEqual(prepared.clusterScoped, [
+    "ClusterRole.rbac.authorization.k8s.io/reader",
+  ]);
+  assert.equal(prepared.manifests[0].metadata.namespace, undefined);
+});
+
+test("scope is decided by API group, not the bare kind", () => {
+  assert.equal(groupKind("v1", "Namespace"), "Namespace");
+  assert.equal(
+    groupKind("example.com/v1", "ClusterRole"),
+    "ClusterRole.example.com",
+  );
+  const objects = parseManifests(`
+apiVersion: example.com/v1
+kind: ClusterRole
+metadata:
+  name: namespaced-lookalike
+`);
+  const { manifests, errors } = prepareManifests(objects, OPTIONS);
+  assert.deepEqual(errors, []);
+  assert.equal(manifests[0].metadata.namespace, "rulebricks-prod");
+});
@@ -0,0 +1,173 @@
+import { execa, ExecaError } from "execa";
+import yaml from "yaml";
+
+/**
+ * `rulebricks apply -f <manifest>` for small additions next to the chart (a
+ * ConfigMap, an extra route, a custom resource). Every object gets the same
+ * labels the CLI puts on its own ESO resources and lands in the deployment
+ * namespace, so namespace teardown in `destroy` removes it with everything
+ * else. Cluster-scoped objects are refused unless explicitly allowed; the
+ * allowed ones are recorded in state so `destroy` can delete them too.
+ */
+
+export const APPLY_LABELS = {
+  managedBy: "app.kubernetes.io/managed-by",
+  instance: "app.kubernetes.io/instance",
+} as const;
+
+export interface ManifestObject {
+  apiVersion: string;
+  kind: string;
+  metadata: {
+    name: string;
+    namespace?: string;
+    labels?: Record<string, string>;
+    [key: string]: unknown;
+  };
+  [key: string]: unknown;
+}
+
+export interface PreparedManifests {
+  manifests: ManifestObject[];
+  /** "Kind.group/name" of each cluster-scoped object (only when allowed). */
+  clusterScoped: string[];
+  errors: string[];
+}
+
+/**
+ * A kind qualified by its API group the way kubectl writes it, "Kind.group"
+ * ("ClusterRole.rbac.authorization.k8s.io"), or the bare kind for the core
+ * group. Two CRDs can share a kind, one namespaced and one not, so scope is
+ * decided on this rather than the kind alone.
+ */
+export function groupKind(apiVersion: string, kind: string): string {
+  const slash = apiVersion.indexOf("/");
+  return slash === -1 ? kind : `${kind}.${apiVersion.slice(0, slash)}`;
+}
+
+/**
+ * Parses a (multi-document) manifest file into objects. Empty documents are
+ * skipped and `kind: List` documents are flattened.
+ */
+export function parseManifests(content: string): unknown[] {
+  const objects: unknown[] = [];
+  for (const doc of yaml.parseAllDocuments(content)) {
+    if (doc.errors.length > 0) {
+      throw new Error(`Invalid YAML: ${doc.errors[0].message}`);
+    }
+    const value = doc.toJS();
+    if (value == null) continue;
+    if (
+      typeof value === "object" &&
+      (value as { kind?: unknown }).kind === "List" &&
+      Array.isArray((value as { items?: unknown }).items)
+    ) {
+      objects.push(...(value as { items: unknown[] }).items);
+    } else {
+      objects.push(value);
+    }
+  }
+  return objects;
+}
+
+function isManifestObject(value: unknown): value is ManifestObject {
+  const obj = value as Partial<ManifestObject> | null;
+  return (
+    !!obj &&
+    typeof obj === "object" &&
+    typeof obj.apiVersion === "string" &&
+    typeof obj.kind === "string" &&
+    !!obj.metadata &&
+    typeof obj.metadata === "object" &&
+    typeof obj.metadata.name === "string" &&
+    obj.metadata.name.length > 0
+  );
+}
+
+/**
+ * Validates parsed objects, scopes them to the deployment namespace, and
+ * adds the deployment labels. Problems are collected rather than thrown so
+ * the whole file is reported in one pass; nothing should be applied when
+ * `errors` is non-empty.
+ */
+export function prepareManifests(
+  objects: unknown[],
+  options: {
+    namespace: string;
+    releaseName: string;
+    /** groupKind() of every cluster-scoped resource type. */
+    clusterScopedKinds: Set<string>;
+    allowClusterScoped?: boolean;
+  },
+): PreparedManifests {
+  const manifests: ManifestObject[] = [];
+  const clusterScoped: string[] = [];
+  const errors: string[] = [];
+
+  if (objects.length === 0) {
+    errors.push("The manifest contains no objects");
+  }
+
+  objects.forEach((value, index) => {
+    if (!isManifestObject(value)) {
+      errors.push(
+        `Document ${index + 1}: apiVersion, kind, and metadata.name are required`,
+      );
+      return;
+    }
+    const qualifiedKind = groupKind(value.apiVersion, value.kind);
+    const ref = `${qualifiedKind}/${value.metadata.name}`;
+    const metadata = {
+      ...value.metadata,
+      labels: {
+        ...value.metadata.labels,
+        [APPLY_LABELS.managedBy]: "rulebricks-cli",
+        [APPLY_LABELS.instance]: options.releaseName,
+      },
+    };
+
+    if (options.clusterScopedKinds.has(qualifiedKind)) {
+      if (!options.allowClusterScoped) {
+        errors.push(
+          `${ref} is cluster-scoped; pass --allow-cluster-scoped to apply it`,
+        );
+        return;
+      }
+      delete metadata.namespace;
+      clusterScoped.push(ref);
+    } else {
+      if (metadata.namespace && metadata.namespace !== options.namespace) {
+        errors.push(
+          `${ref} targets namespace "${metadata.namespace}"; apply only manages ${options.namespace}`,
+        );
+        return;
+      }
+      metadata.namespace = options.namespace;
+    }
+
+    manifests.push({ ...value, metadata });
+  });
+
+  return { manifests, clusterScoped, errors };
+}
+
+/** Applies prepared objects in one `kubectl apply`, returning its output. */
+export async function applyManifests(
+  manifests: ManifestObject[],
+): Promise<string> {
+  try {
+    const { stdout } = await execa("kubectl", ["apply", "-f", "-"], {
+      input: JSON.stringify({
+        apiVersion: "v1",
+        kind: "List",
+        items: manifests,
+      }),
+    });
+    return stdout;
+  } catch (error) {
+    const execaError = error as ExecaError;
+    throw new Error(
+      `kubectl apply failed: ${execaError.stderr || execaError.message}`,
+    );
+  }
+}
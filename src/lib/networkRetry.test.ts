@@ -0,0 +1,87 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  isTransientNetworkError,
+  networkRetryDelayMs,
+  withNetworkRetry,
+} from "./networkRetry.js";
+
+function helmError(stderr: string): Error {
+  return Object.assign(new Error("Command failed with exit code 1"), {
+    stderr,
+  });
+}
+
+test("recognizes registry network failures in helm output", () => {
+  for (const stderr of [
+    'Error: failed to do request: Head "https://ghcr.io/v2/rulebricks/helm/stack/manifests/1.4.0": dial tcp: i/o timeout',
+    "Error: net/http: TLS handshake timeout",
+    "Error: read tcp 10.0.0.4:51234->140.82.112.33:443: read: connection reset by peer",
+    "Error: dial tcp: lookup ghcr.io: no such host",
+    "Error: unexpected status from HEAD request: 503 Service Unavailable",
+  ]) {
+    assert.ok(isTransientNetworkError(helmError(stderr)), stderr);
+  }
+});
+
+test("does not retry chart or values errors", () => {
+  for (const stderr of [
+    "Error: ghcr.io/rulebricks/helm/stack:9.9.9: not found",
+    "Error: values don't meet the specifications of the schema(s)",
+    "Error: UPGRADE FAILED: context deadline exceeded",
+  ]) {
+    assert.ok(!isTransientNetworkError(helmError(stderr)), stderr);
+  }
+});
+
+test("backs off exponentially", () => {
+  assert.deepEqual(
+    [1, 2, 3].map((n) => networkRetryDelayMs(n)),
+    [2_000, 4_000, 8_000],
+  );
+});
+
+test("retries transient failures up to the attempt limit", async () => {
+  const waits: number[] = [];
+  let calls = 0;
+  const result = await withNetworkRetry(
+    async () => {
+      calls++;
+      if (calls < 3) throw helmError("dial tcp: i/o timeout");
+      return "pulled";
+    },
+    { sleep: async (ms) => void waits.push(ms) },
+  );
+  assert.equal(result, "pulled");
+  assert.equal(calls, 3);
+  assert.deepEqual(waits, [2_000, 4_000]);
+
+  calls = 0;
+  await assert.rejects(
+    withNetworkRetry(
+      async () => {
+        calls++;
+        throw helmError("dial tcp: i/o timeout");
+      },
+      { sleep: async () => {} },
+    ),
+    /exit code 1/,
+  );
+  assert.equal(calls, 3);
+});
+
+test("fails on the first attempt for a non-network error", async () => {
+  let calls = 0;
+  await assert.rejects(
+    withNetworkRetry(
+      async () => {
+        calls++;
+        throw helmError("Error: chart not found");
+      },
+      { sleep: async () => {} },
+    ),
+    (err: unknown) =>
+      (err as { stderr?: string }).stderr === "Error: chart not found",
+  );
+  assert.equal(calls, 1);
+});
@@ -0,0 +1,60 @@
+import type { ExecaError } from "execa";
+import { retryStep } from "./stepRetry.js";
+
+// Retries for the helm calls that fetch from a registry (the OCI chart, the
+// External Secrets chart repo). Slow CI networks drop these often enough to
+// fail whole deploys; the calls themselves are idempotent. Only errors that
+// look like the network retry - a missing chart version or a values error
+// fails on the first attempt with helm's own output.
+
+export const NETWORK_RETRY_ATTEMPTS = 3;
+
+const TRANSIENT_PATTERNS = [
+  /i\/o timeout/i,
+  /TLS handshake timeout/i,
+  /connection reset by peer/i,
+  /connection refused/i,
+  /no such host/i,
+  /temporary failure in name resolution/i,
+  /unexpected EOF/i,
+  /net\/http: request canceled/i,
+  /Too Many Requests|Bad Gateway|Service Unavailable|Gateway Time-?out/i,
+];
+
+/** The text helm printed for a failed command, falling back to the message. */
+function errorOutput(error: unknown): string {
+  const execaError = error as Partial<ExecaError> | undefined;
+  return [execaError?.stderr, execaError?.stdout, execaError?.message]
+    .filter((part): part is string => typeof part === "string")
+    .join("\n");
+}
+
+export function isTransientNetworkError(error: unknown): boolean {
+  const output = errorOutput(error);
+  return TRANSIENT_PATTERNS.some((pattern) => pattern.test(output));
+}
+
+/** Backoff before retry `attempt` (1-based): 2s, 4s, 8s, ... */
+export function networkRetryDelayMs(attempt: number, baseMs = 2_000): number {
+  return baseMs * 2 ** (attempt - 1);
+}
+
+/**
+ * Runs `fn` up to `attempts` times, backing off between tries, while it fails
+ * with a transient network error. Any other error, or the last one, is
+ * rethrown unchanged.
+ */
+export function withNetworkRetry<T>(
+  fn: () => Promise<T>,
+  options: {
+    attempts?: number;
+    sleep?: (ms: number) => Promise<void>;
+  } = {},
+): Promise<T> {
+  return retryStep(fn, {
+    retries: Math.max(1, options.attempts ?? NETWORK_RETRY_ATTEMPTS) - 1,
+    shouldRetry: isTransientNetworkError,
+    delayMs: (attempt) => networkRetryDelayMs(attempt),
+    sleep: options.sleep,
+  });
+}
@@ -0,0 +1,116 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import http from "node:http";
+import {
+  DeployNotification,
+  notificationTargets,
+  parseNotifyUrl,
+  sendNotifications,
+  slackMessage,
+} from "./notifications.js";
+
+const FAILED: DeployNotification = {
+  deployment: "prod",
+  outcome: "failed",
+  durationMs: 302000,
+  version: "1.4.0",
+  failedStep: "Helm chart installation",
+  error: "timed out waiting for the condition",
+};
+
+/** Records each request's headers and JSON body; replies with `status`. */
+async function fakeReceiver(status = 200): Promise<{
+  url: string;
+  received: Array<{ headers: http.IncomingHttpHeaders; body: unknown }>;
+  close: () => void;
+}> {
+  const received: Array<{ headers: http.IncomingHttpHeaders; body: unknown }> =
+    [];
+  const server = http.createServer((req, res) => {
+    let body = "";
+    req.on("data", (chunk) => (body += chunk));
+    req.on("end", () => {
+      received.push({ headers: req.headers, body: JSON.parse(body) });
+      res.writeHead(status).end();
+    });
+  });
+  await new Promise<void>((resolve) => server.listen(0, "127.0.0.1", resolve));
+  const { port } = server.address() as { port: number };
+  return {
+    url: `http://127.0.0.1:${port}/hook`,
+    received,
+    close: () => server.close(),
+  };
+}
+
+test("--notify picks Slack by host and replaces the config's targets", () => {
+  assert.deepEqual(parseNotifyUrl("https://hooks.slack.com/services/T/B/x"), {
+    kind: "slack",
+    url: "https://hooks.slack.com/services/T/B/x",
+  });
+  const notify = parseNotifyUrl("https://ci.example.com/hooks/deploy");
+  assert.equal(notify.kind, "webhook");
+  assert.throws(() => parseNotifyUrl("ftp://example.com"), /scheme/);
+  assert.throws(
+    () => parseNotifyUrl("http://ci.example.com/hooks/deploy"),
+    /plain http:\/\//,
+  );
+
+  const config = {
+    webhook: { url: "https://ci.example.com/a", token: "t0k" },
+    slack: { webhookUrl: "https://hooks.slack.com/services/T/B/y" },
+  };
+  assert.deepEqual(notificationTargets(config, notify), [notify]);
+  assert.deepEqual(notificationTargets(config), [
+    { kind: "webhook", url: "https://ci.example.com/a", token: "t0k" },
+    { kind: "slack", url: "https://hooks.slack.com/services/T/B/y" },
+  ]);
+  assert.deepEqual(notificationTargets(undefined), []);
+});
+
+test("the Slack message names the failed step and error", () => {
+  assert.equal(
+    slackMessage(FAILED).text,
+    ":x: Rulebricks deploy of *prod* (1.4.0) failed after 5m02s at Helm chart installation: timed out waiting for the condition",
+  );
+  assert.equal(
+    slackMessage({
+      deployment: "prod",
+      outcome: "succeeded",
+      durationMs: 45000,
+      url: "https://rules.example.com",
+    }).text,
+    ":white_check_mark: Rulebricks deploy of *prod* succeeded in 45s: https://rules.example.com",
+  );
+});
+
+test("webhooks get the summary as JSON with a bearer token", async () => {
+  const receiver = await fakeReceiver();
+  try {
+    const warnings = await sendNotifications(
+      [{ kind: "webhook", url: receiver.url, token: "t0k" }],
+      FAILED,
+    );
+    assert.deepEqual(warnings, []);
+    assert.equal(receiver.received.length, 1);
+    const [request] = receiver.received;
+    assert.equal(request.headers.authorization, "Bearer t0k");
+    assert.deepEqual(request.body, { event: "deploy", ...FAILED });
+  } finally {
+    receiver.close();
+  }
+});
+
+test("a rejected post is a warning, not an error", async () => {
+  const receiver = await fakeReceiver(500);
+  try {
+    const warnings = await sendNotifications(
+      [{ kind: "webhook", url: receiver.url }],
+      FAILED,
+    );
+    assert.equal(warnings.length, 1);
+    assert.match(warnings[0], /^Could not notify 127\.0\.0\.1:\d+: HTTP 500$/);
+  } finally {
+    receiver.close();
+  }
+});
@@ -0,0 +1,148 @@
+import type { DeploymentConfig } from "../types/index.js";
+import { formatDurationMs } from "./deploymentHistory.js";
+
+/**
+ * Deploy notifications: when a deploy ends, a summary is posted to the
+ * config's `notifications` targets, or to the `--notify` URL instead. A
+ * generic webhook gets the summary as JSON (with its token as a bearer
+ * token); a Slack incoming webhook gets a one-line message. A failed post
+ * never fails the deploy; it comes back as a warning.
+ */
+
+export type NotificationTarget =
+  | { kind: "webhook"; url: string; token?: string }
+  | { kind: "slack"; url: string };
+
+export interface DeployNotification {
+  deployment: string;
+  outcome: "succeeded" | "failed";
+  durationMs: number;
+  version?: string;
+  chartVersion?: string;
+  url?: string;
+  /** Label of the step that was running when the deploy failed. */
+  failedStep?: string;
+  /** First line of the error, for failed deploys. */
+  error?: string;
+}
+
+const NOTIFY_TIMEOUT_MS = 10000;
+const SLACK_WEBHOOK_HOST = "hooks.slack.com";
+
+/** A `--notify` URL: Slack incoming webhooks by host, anything else JSON. */
+export function parseNotifyUrl(url: string): NotificationTarget {
+  let parsed: URL;
+  try {
+    parsed = new URL(url);
+  } catch {
+    throw new Error(`Invalid --notify "${url}".`);
+  }
+  // The URL can carry a secret (Slack webhook URLs are one), so it only
+  // goes out encrypted, like --config-url.
+  if (parsed.protocol === "http:") {
+    throw new Error(
+      `Refusing to post --notify "${url}" over plain http://. Use https:// instead.`,
+    );
+  }
+  if (parsed.protocol !== "https:") {
+    throw new Error(
+      `Unsupported --notify scheme "${parsed.protocol}". Use https://.`,
+    );
+  }
+  return parsed.hostname === SLACK_WEBHOOK_HOST
+    ? { kind: "slack", url }
+    : { kind: "webhook", url };
+}
+
+/** Where a deploy's summary goes; `notify` replaces the config's targets. */
+export function notificationTargets(
+  notifications: DeploymentConfig["notifications"],
+  notify?: NotificationTarget,
+): NotificationTarget[] {
+  if (notify) return [notify];
+  const targets: NotificationTarget[] = [];
+  if (notifications?.webhook) {
+    targets.push({ kind: "webhook", ...notifications.webhook });
+  }
+  if (notifications?.slack) {
+    targets.push({ kind: "slack", url: notifications.slack.webhookUrl });
+  }
+  return targets;
+}
+
+export function slackMessage(notification: DeployNotification): {
+  text: string;
+} {
+  const took = formatDurationMs(notification.durationMs);
+  const version = notification.version ? ` (${notification.version})` : "";
+  if (notification.outcome === "succeeded") {
+    const url = notification.url ? `: ${notification.url}` : "";
+    return {
+      text: `:white_check_mark: Rulebricks deploy of *${notification.deployment}*${version} succeeded in ${took}${url}`,
+    };
+  }
+  const at = notification.failedStep ? ` at ${notification.failedStep}` : "";
+  const error = notification.error ? `: ${notification.error}` : "";
+  return {
+    text: `:x: Rulebricks deploy of *${notification.deployment}*${version} failed after ${took}${at}${error}`,
+  };
+}
+
+async function post(
+  target: NotificationTarget,
+  notification: DeployNotification,
+): Promise<void> {
+  const headers: Record<string, string> = {
+    "Content-Type": "application/json",
+  };
+  if (target.kind === "webhook" && target.token) {
+    headers.Authorization = `Bearer ${target.token}`;
+  }
+  const body =
+    target.kind === "slack"
+      ? slackMessage(notification)
+      : { event: "deploy", ...notification };
+  const response = await fetch(target.url, {
+    method: "POST",
+    headers,
+    body: JSON.stringify(body),
+    signal: AbortSignal.timeout(NOTIFY_TIMEOUT_MS),
+  });
+  if (!response.ok) {
+    throw new Error(`HTTP ${response.status}`);
+  }
+}
+
+function targetName(target: NotificationTarget): string {
+  if (target.kind === "slack") return "Slack";
+  try {
+    return new URL(target.url).host;
+  } catch {
+    return "webhook";
+  }
+}
+
+/**
+ * Posts the summary to every target. Resolves to one warning per target that
+ * couldn't be reached, naming its host rather than the (secret) URL.
+ */
+export async function sendNotifications(
+  targets: NotificationTarget[],
+  notification: DeployNotification,
+): Promise<string[]> {
+  const warnings: string[] = [];
+  await Promise.all(
+    targets.map(async (target) => {
+      try {
+        await post(target, notification);
+      } catch (error) {
+        warnings.push(
+          `Could not notify ${targetName(target)}: ${
+            error instanceof Error ? error.message : String(error)
+          }`,
+        );
+      }
+    }),
+  );
+  return warnings;
+}
@@ -0,0 +1,89 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  createProgressReporter,
+  diffStepStates,
+  formatProgressEvent,
+  ProgressEvent,
+  resolveProgressMode,
+  stepTransitionEvents,
+} from "./progress.js";
+
+test("auto mode keeps the TTY UI and falls back to plain when piped", () => {
+  assert.equal(resolveProgressMode("auto", true), "tty");
+  assert.equal(resolveProgressMode("auto", false), "plain");
+  assert.equal(resolveProgressMode("json", true), "json");
+});
+
+test("diffStepStates reports only changed, non-pending steps", () => {
+  const changes = diffStepStates(
+    { preflight: "running", helmInstall: "pending", certCheck: "pending" },
+    { preflight: "success", helmInstall: "running", certCheck: "pending" },
+  );
+  assert.deepEqual(changes, [
+    ["preflight", "completed"],
+    ["helmInstall", "started"],
+  ]);
+});
+
+test("json reporter writes one parseable event per line", () => {
+  const lines: string[] = [];
+  const reporter = createProgressReporter("json", {
+    write: (line) => lines.push(line),
+  });
+  reporter.emit("helmInstall", "failed", "timed out");
+
+  assert.equal(lines.length, 1);
+  const event = JSON.parse(lines[0]);
+  assert.equal(event.step, "helmInstall");
+  assert.equal(event.status, "failed");
+  assert.equal(event.detail, "timed out");
+  assert.equal(typeof event.timestamp, "string");
+});
+
+test("plain output uses the step label", () => {
+  const line = formatProgressEvent(
+    { step: "preflight", status: "completed", timestamp: "t" },
+    "plain",
+    "Preflight checks",
+  );
+  assert.equal(line, "✓ Preflight checks: completed");
+});
+
+test("tty reporter is a no-op", () => {
+  const lines: string[] = [];
+  const reporter = createProgressReporter("tty", {
+    write: (line) => lines.push(line),
+  });
+  reporter.emit("preflight", "started");
+  assert.equal(reporter.enabled, false);
+  assert.deepEqual(lines, []);
+});
+
+test("onEvent listeners receive events even in tty mode", () => {
+  const lines: string[] = [];
+  const events: ProgressEvent[] = [];
+  const reporter = createProgressReporter("tty", {
+    write: (line) => lines.push(line),
+    onEvent: (event) => events.push(event),
+  });
+  reporter.emit("preflight", "started");
+  assert.equal(reporter.enabled, true);
+  assert.deepEqual(lines, []);
+  assert.equal(events[0].step, "preflight");
+});
+
+test("step machine transitions close the previous step", () => {
+  assert.deepEqual(stepTransitionEvents("upgrade", null, "loading"), [
+    ["upgrade", "started"],
+    ["loading", "started"],
+  ]);
+  assert.deepEqual(stepTransitionEvents("upgrade", "loading", "upgrading"), [
+    ["loading", "completed"],
+    ["upgrading", "started"],
+  ]);
+  assert.deepEqual(stepTransitionEvents("upgrade", "upgrading", "error"), [
+    ["upgrading", "failed"],
+    ["upgrade", "failed"],
+  ]);
+});
@@ -0,0 +1,146 @@
+// Machine-readable progress for long-running commands. The Ink UI stays the
+// default on a TTY; "plain" and "json" modes additionally write one line per
+// step transition to stdout so wrapping tools (CI dashboards, web UIs) can
+// follow along. In those modes the Ink UI is rendered to stderr instead so the
+// event stream stays clean.
+
+export type ProgressMode = "auto" | "plain" | "json";
+export const PROGRESS_MODES: ProgressMode[] = ["auto", "plain", "json"];
+
+/** Step status vocabulary shared with StatusLine. */
+export type StepState = "pending" | "running" | "success" | "error" | "skipped";
+
+export type ProgressEventStatus = "started" | "completed" | "failed" | "skipped";
+
+export interface ProgressEvent {
+  step: string;
+  status: ProgressEventStatus;
+  detail?: string;
+  timestamp: string;
+}
+
+export interface ProgressReporter {
+  /** False in TTY mode without a listener, where the Ink UI is the only output. */
+  readonly enabled: boolean;
+  emit(step: string, status: ProgressEventStatus, detail?: string): void;
+}
+
+const EVENT_FOR_STATE: Record<StepState, ProgressEventStatus | null> = {
+  pending: null,
+  running: "started",
+  success: "completed",
+  error: "failed",
+  skipped: "skipped",
+};
+
+const PLAIN_ICONS: Record<ProgressEventStatus, string> = {
+  started: "…",
+  completed: "✓",
+  failed: "✗",
+  skipped: "⊘",
+};
+
+export function isProgressMode(value: string): value is ProgressMode {
+  return (PROGRESS_MODES as string[]).includes(value);
+}
+
+/**
+ * Resolves "auto" against the output stream: a TTY keeps the Ink UI, anything
+ * else (pipes, CI logs) gets plain lines.
+ */
+export function resolveProgressMode(
+  mode: ProgressMode,
+  isTty: boolean,
+): "tty" | "plain" | "json" {
+  if (mode === "auto") return isTty ? "tty" : "plain";
+  return mode;
+}
+
+/** Formats an event the way the given mode writes it (without newline). */
+export function formatProgressEvent(
+  event: ProgressEvent,
+  mode: "plain" | "json",
+  label: string = event.step,
+): string {
+  if (mode === "json") {
+    return JSON.stringify(event);
+  }
+  const suffix = event.detail ? ` - ${event.detail}` : "";
+  return `${PLAIN_ICONS[event.status]} ${label}: ${event.status}${suffix}`;
+}
+
+export function createProgressReporter(
+  mode: "tty" | "plain" | "json",
+  options: {
+    labels?: Record<string, string>;
+    write?: (line: string) => void;
+    /** Also receives every event (e.g. the --health-port server), in any mode. */
+    onEvent?: (event: ProgressEvent) => void;
+  } = {},
+): ProgressReporter {
+  const write =
+    options.write ?? ((line: string) => process.stdout.write(`${line}\n`));
+  const { onEvent } = options;
+
+  if (mode === "tty" && !onEvent) {
+    return { enabled: false, emit: () => {} };
+  }
+
+  return {
+    enabled: true,
+    emit(step, status, detail) {
+      const event: ProgressEvent = {
+        step,
+        status,
+        ...(detail ? { detail } : {}),
+        timestamp: new Date().toISOString(),
+      };
+      onEvent?.(event);
+      if (mode !== "tty") {
+        write(formatProgressEvent(event, mode, options.labels?.[step]));
+      }
+    },
+  };
+}
+
+/**
+ * Returns the (step, event) pairs for every step whose state changed between
+ * two StatusLine snapshots. Transitions back to "pending" are not events.
+ */
+export function diffStepStates<K extends string>(
+  previous: Record<K, StepState>,
+  next: Record<K, StepState>,
+): Array<[K, ProgressEventStatus]> {
+  const changes: Array<[K, ProgressEventStatus]> = [];
+  for (const key of Object.keys(next) as K[]) {
+    if (previous[key] === next[key]) continue;
+    const event = EVENT_FOR_STATE[next[key]];
+    if (event) changes.push([key, event]);
+  }
+  return changes;
+}
+
+/**
+ * Events for commands driven by a single step machine (upgrade) rather than
+ * per-step StatusLines: entering a step starts it and closes the previous one,
+ * and the "complete"/"error" steps finish the operation itself.
+ */
+export function stepTransitionEvents(
+  operation: string,
+  previous: string | null,
+  next: string,
+): Array<[string, ProgressEventStatus]> {
+  const failed = next === "error";
+  const events: Array<[string, ProgressEventStatus]> = [];
+  if (previous === null) {
+    events.push([operation, "started"]);
+  } else if (previous !== "complete" && previous !== "error") {
+    events.push([previous, failed ? "failed" : "completed"]);
+  }
+  if (next === "complete" || failed) {
+    events.push([operation, failed ? "failed" : "completed"]);
+  } else {
+    events.push([next, "started"]);
+  }
+  return events;
+}
@@ -0,0 +1,31 @@
+// Kubernetes resource quantities ("500m", "2", "512Mi", "4Gi"), shared by
+// config validation and the `status --resources` capacity view.
+
+/** Parses a Kubernetes CPU quantity ("500m", "2", 1.5) to millicores. */
+export function parseCpuMillicores(value: string | number): number | undefined {
+  if (typeof value === "number") return value * 1000;
+  const match = /^(\d+(?:\.\d+)?)(m?)$/.exec(value.trim());
+  if (!match) return undefined;
+  const amount = Number(match[1]);
+  return match[2] === "m" ? amount : amount * 1000;
+}
+
+const MEMORY_UNITS: Record<string, number> = {
+  "": 1,
+  k: 1e3,
+  M: 1e6,
+  G: 1e9,
+  T: 1e12,
+  Ki: 2 ** 10,
+  Mi: 2 ** 20,
+  Gi: 2 ** 30,
+  Ti: 2 ** 40,
+};
+
+/** Parses a Kubernetes memory quantity ("512Mi", "4Gi", "1G") to bytes. */
+export function parseMemoryBytes(value: string): number | undefined {
+  const match = /^(\d+(?:\.\d+)?)([kMGT]i?|Ki)?$/.exec(value.trim());
+  if (!match) return undefined;
+  const unit = MEMORY_UNITS[match[2] ?? ""];
+  return unit === undefined ? undefined : Number(match[1]) * unit;
+}
@@ -0,0 +1,41 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { createDecipheriv } from "crypto";
+import {
+  encryptRealtimeValue,
+  findRealtimeSecretErrors,
+  realtimeTenantUpdateSql,
+} from "./realtimeRepair.js";
+import { deriveRealtimeSecrets } from "./helmValues.js";
+
+test("tenant secrets are encrypted with AES-128-ECB under DB_ENC_KEY", () => {
+  const key = "0123456789abcdef";
+  const encrypted = encryptRealtimeValue("super-secret-jwt", key);
+
+  const decipher = createDecipheriv("aes-128-ecb", Buffer.from(key), null);
+  const decrypted = Buffer.concat([
+    decipher.update(Buffer.from(encrypted, "base64")),
+    decipher.final(),
+  ]).toString("utf-8");
+  assert.equal(decrypted, "super-secret-jwt");
+});
+
+test("the tenant update uses the key derived from the same JWT secret", () => {
+  const jwt = "a".repeat(40);
+  const { dbEncKey } = deriveRealtimeSecrets(jwt);
+  const sql = realtimeTenantUpdateSql(jwt);
+  assert.ok(sql.startsWith("UPDATE _realtime.tenants SET jwt_secret = '"));
+  assert.ok(sql.includes(encryptRealtimeValue(jwt, dbEncKey)));
+});
+
+test("only secret-related Realtime log lines are reported", () => {
+  const logs = [
+    "12:00:00.000 [info] Running RealtimeWeb.Endpoint",
+    "12:00:01.000 [error] Failed to decrypt tenant secret",
+    "12:00:02.000 [error] JwtSignatureError for tenant realtime-dev",
+  ].join("\n");
+  assert.deepEqual(findRealtimeSecretErrors(logs), [
+    "12:00:01.000 [error] Failed to decrypt tenant secret",
+    "12:00:02.000 [error] JwtSignatureError for tenant realtime-dev",
+  ]);
+});
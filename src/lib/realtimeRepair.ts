@@ -0,0 +1,124 @@
+import { createCipheriv } from "crypto";
+import { execa } from "execa";
+import { deriveRealtimeSecrets } from "./helmValues.js";
+import {
+  rolloutRestart,
+  runSupabaseDbSql,
+  waitForDeploymentReady,
+} from "./kubernetes.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+} from "../types/index.js";
+
+/**
+ * `rulebricks supabase fix-realtime`: recovers Realtime after the JWT secret
+ * changes.
+ *
+ * Realtime keeps each tenant's jwt_secret in `_realtime.tenants`, encrypted
+ * with DB_ENC_KEY. DB_ENC_KEY is itself derived from
+ * database.supabaseJwtSecret (deriveRealtimeSecrets), so after a rotation the
+ * stored value is both stale and encrypted under the old key, and Realtime
+ * rejects every client token without saying why. This encrypts the current
+ * JWT secret with the current key, writes it to every tenant row, and
+ * restarts Realtime so it drops its cached tenant.
+ */
+
+/**
+ * Encrypts a value the way Realtime.Crypto does: AES-128-ECB keyed by the
+ * 16-byte DB_ENC_KEY, PKCS#7 padded, Base64 encoded.
+ */
+export function encryptRealtimeValue(
+  plaintext: string,
+  dbEncKey: string,
+): string {
+  const key = Buffer.from(dbEncKey, "utf-8");
+  const cipher = createCipheriv("aes-128-ecb", key, null);
+  return Buffer.concat([
+    cipher.update(plaintext, "utf-8"),
+    cipher.final(),
+  ]).toString("base64");
+}
+
+/** SQL that points every Realtime tenant at the given JWT secret. */
+export function realtimeTenantUpdateSql(jwtSecret: string): string {
+  const { dbEncKey } = deriveRealtimeSecrets(jwtSecret);
+  // Base64 output never contains a quote, so it is safe to inline.
+  const encrypted = encryptRealtimeValue(jwtSecret, dbEncKey);
+  return `UPDATE _realtime.tenants SET jwt_secret = '${encrypted}', updated_at = now();`;
+}
+
+// Log lines Realtime emits when it cannot use a tenant's secrets.
+const REALTIME_FAILURE_PATTERN =
+  /(decrypt|jwt_secret|JwtSignatureError|InvalidJWTToken|signature_error)/i;
+
+/** Lines in Realtime's logs that point at a still-broken tenant secret. */
+export function findRealtimeSecretErrors(logs: string): string[] {
+  return logs
+    .split("\n")
+    .map((line) => line.trim())
+    .filter((line) => line && REALTIME_FAILURE_PATTERN.test(line));
+}
+
+export function realtimeDeploymentName(config: DeploymentConfig): string {
+  return `${getReleaseName(config.name)}-supabase-realtime`;
+}
+
+/**
+ * Rewrites the tenant secrets through the bundled database. Returns the
+ * number of tenant rows updated.
+ */
+export async function reencryptRealtimeTenants(
+  config: DeploymentConfig,
+): Promise<number> {
+  const jwtSecret = config.database.supabaseJwtSecret;
+  if (!jwtSecret) {
+    throw new Error(
+      "database.supabaseJwtSecret is not set; run `rulebricks configure` first.",
+    );
+  }
+  if (config.externalServices?.postgres?.mode === "external") {
+    throw new Error(
+      "fix-realtime needs the bundled Postgres; with an external database, run the update against it directly.",
+    );
+  }
+  // supabase_admin owns the _realtime schema; the db container carries its
+  // password (the same one `restore` connects with) as POSTGRES_PASSWORD.
+  const output = await runSupabaseDbSql(
+    getNamespace(config.name),
+    getReleaseName(config.name),
+    realtimeTenantUpdateSql(jwtSecret),
+  );
+  const match = /UPDATE (\d+)/.exec(output);
+  return match ? Number(match[1]) : 0;
+}
+
+/** Restarts Realtime and waits for the new pods to become ready. */
+export async function restartRealtime(
+  config: DeploymentConfig,
+): Promise<void> {
+  const namespace = getNamespace(config.name);
+  const name = realtimeDeploymentName(config);
+  if (!(await rolloutRestart("deployment", name, namespace))) {
+    throw new Error(`Could not restart deployment ${name} in ${namespace}`);
+  }
+  await waitForDeploymentReady(namespace, name, 300);
+}
+
+/**
+ * Checks the restarted Realtime's logs for secret errors. Returns the
+ * offending lines; empty means Realtime came up cleanly.
+ */
+export async function verifyRealtime(
+  config: DeploymentConfig,
+): Promise<string[]> {
+  const { stdout } = await execa("kubectl", [
+    "logs",
+    `deployment/${realtimeDeploymentName(config)}`,
+    "-n",
+    getNamespace(config.name),
+    "--since=2m",
+  ]);
+  return findRealtimeSecretErrors(stdout);
+}
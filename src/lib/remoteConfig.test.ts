@@ -0,0 +1,43 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { CONFIG_TOKEN_ENV, configSource } from "./remoteConfig.js";
+
+test("https config URLs send the bearer token when one is set", () => {
+  const url = "https://artifacts.example.com/rulebricks/prod.yaml";
+  assert.deepEqual(configSource(url, {}), { kind: "http", url, headers: {} });
+  assert.deepEqual(configSource(url, { [CONFIG_TOKEN_ENV]: "t0ken" }), {
+    kind: "http",
+    url,
+    headers: { Authorization: "Bearer t0ken" },
+  });
+});
+
+test("bucket URIs are read with the cloud's CLI", () => {
+  assert.deepEqual(configSource("s3://ops-configs/rulebricks/prod.yaml", {}), {
+    kind: "cli",
+    url: "s3://ops-configs/rulebricks/prod.yaml",
+    command: "aws",
+    args: ["s3", "cp", "s3://ops-configs/rulebricks/prod.yaml", "-"],
+  });
+  assert.deepEqual(configSource("gs://ops-configs/prod.yaml", {}), {
+    kind: "cli",
+    url: "gs://ops-configs/prod.yaml",
+    command: "gcloud",
+    args: ["storage", "cat", "gs://ops-configs/prod.yaml"],
+  });
+});
+
+test("plain http URLs are refused so the token never goes out in the clear", () => {
+  assert.throws(
+    () =>
+      configSource("http://artifacts.example.com/prod.yaml", {
+        [CONFIG_TOKEN_ENV]: "t0ken",
+      }),
+    /https/,
+  );
+});
+
+test("other schemes and malformed URLs are rejected", () => {
+  assert.throws(() => configSource("ftp://example.com/c.yaml", {}), /scheme/);
+  assert.throws(() => configSource("./config.yaml", {}), /Invalid/);
+});
@@ -0,0 +1,104 @@
+import { execa } from "execa";
+
+/**
+ * `deploy --config-url`: fetches a deployment's config.yaml from where CI
+ * keeps it. HTTPS URLs are fetched directly, with a bearer token from
+ * RULEBRICKS_CONFIG_TOKEN when set; s3:// and gs:// URIs are read with the
+ * aws and gcloud CLIs, so they use the same credentials as everything else
+ * the CLI does in that cloud. Plain http:// is refused: the token and the
+ * config (secrets included) would cross the network in the clear, and
+ * anyone on the path could swap in a config of their own.
+ */
+
+export const CONFIG_TOKEN_ENV = "RULEBRICKS_CONFIG_TOKEN";
+
+const FETCH_TIMEOUT_MS = 30000;
+
+export type ConfigSource =
+  | { kind: "http"; url: string; headers: Record<string, string> }
+  | { kind: "cli"; url: string; command: "aws" | "gcloud"; args: string[] };
+
+export function configSource(
+  url: string,
+  env: NodeJS.ProcessEnv = process.env,
+): ConfigSource {
+  let parsed: URL;
+  try {
+    parsed = new URL(url);
+  } catch {
+    throw new Error(`Invalid --config-url "${url}".`);
+  }
+  switch (parsed.protocol) {
+    case "https:": {
+      const token = env[CONFIG_TOKEN_ENV];
+      return {
+        kind: "http",
+        url,
+        headers: token ? { Authorization: `Bearer ${token}` } : {},
+      };
+    }
+    case "http:":
+      throw new Error(
+        `Refusing to fetch --config-url "${url}" over plain http://. Serve it over https:// instead.`,
+      );
+    case "s3:":
+      return { kind: "cli", url, command: "aws", args: ["s3", "cp", url, "-"] };
+    case "gs:":
+      return {
+        kind: "cli",
+        url,
+        command: "gcloud",
+        args: ["storage", "cat", url],
+      };
+    default:
+      throw new Error(
+        `Unsupported --config-url scheme "${parsed.protocol}". Use https://, s3:// or gs://.`,
+      );
+  }
+}
+
+/** The config file's content. Throws with the reason it couldn't be read. */
+export async function fetchRemoteConfig(url: string): Promise<string> {
+  const source = configSource(url);
+
+  if (source.kind === "http") {
+    let response: Response;
+    try {
+      response = await fetch(source.url, {
+        headers: source.headers,
+        signal: AbortSignal.timeout(FETCH_TIMEOUT_MS),
+      });
+    } catch (error) {
+      throw new Error(
+        `Failed to fetch ${source.url}: ${error instanceof Error ? error.message : String(error)}`,
+      );
+    }
+    if (!response.ok) {
+      const hint =
+        response.status === 401 || response.status === 403
+          ? ` (set ${CONFIG_TOKEN_ENV} to send a bearer token)`
+          : "";
+      throw new Error(
+        `Failed to fetch ${source.url}: HTTP ${response.status}${hint}`,
+      );
+    }
+    return response.text();
+  }
+
+  try {
+    const { stdout } = await execa(source.command, source.args, {
+      timeout: FETCH_TIMEOUT_MS,
+    });
+    return stdout;
+  } catch (error) {
+    const err = error as { code?: string; stderr?: string; message?: string };
+    if (err.code === "ENOENT") {
+      throw new Error(
+        `Reading ${source.url} needs the ${source.command} CLI, which is not installed or not on PATH.`,
+      );
+    }
+    throw new Error(
+      `Failed to read ${source.url}:\n${err.stderr || err.message}`,
+    );
+  }
+}
@@ -0,0 +1,79 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { planRepairs } from "./repairs.js";
+import { configFixture } from "./configFixtures.js";
+import type { CertificateStatus, PodStatus } from "./kubernetes.js";
+
+function pod(name: string, ready: boolean, restarts: number): PodStatus {
+  return { name, status: "Running", ready, restarts };
+}
+
+const realtimePod = pod("rulebricks-x-supabase-realtime-7d9f-abcde", false, 6);
+
+test("a healthy deployment needs no repairs", () => {
+  const repairs = planRepairs({
+    config: configFixture("aws-self-hosted-minimal"),
+    pods: [
+      pod("rulebricks-x-app-1", true, 0),
+      pod("rulebricks-x-hps-1", true, 4),
+    ],
+    certificates: [{ name: "tls", dnsNames: [], ready: true, failed: false }],
+  });
+  assert.deepEqual(repairs, []);
+});
+
+test("only crash-looping pods are restarted", () => {
+  const repairs = planRepairs({
+    config: configFixture("aws-self-hosted-minimal"),
+    pods: [
+      pod("rulebricks-x-app-1", false, 5),
+      pod("rulebricks-x-hps-1", false, 1),
+    ],
+    certificates: [],
+  });
+  assert.deepEqual(
+    repairs.map((r) => [r.kind, r.target]),
+    [["restart-pod", "rulebricks-x-app-1"]],
+  );
+});
+
+test("failed certificates are reissued", () => {
+  const certificates: CertificateStatus[] = [
+    {
+      name: "app-tls",
+      dnsNames: [],
+      ready: false,
+      failed: true,
+      message: "429",
+    },
+    { name: "hps-tls", dnsNames: [], ready: false, failed: false },
+  ];
+  const repairs = planRepairs({
+    config: configFixture("aws-self-hosted-minimal"),
+    pods: [],
+    certificates,
+  });
+  assert.deepEqual(repairs, [
+    { kind: "reissue-certificate", target: "app-tls", reason: "429" },
+  ]);
+});
+
+test("a crash-looping Realtime is reseeded instead of restarted", () => {
+  const repairs = planRepairs({
+    config: configFixture("aws-self-hosted-minimal"),
+    pods: [realtimePod],
+    certificates: [],
+  });
+  assert.deepEqual(repairs.map((r) => r.kind), ["reseed-realtime"]);
+});
+
+test("Realtime is only restarted when the database isn't bundled", () => {
+  for (const name of ["aws-external-postgres", "aws-supabase-cloud"]) {
+    const repairs = planRepairs({
+      config: configFixture(name),
+      pods: [realtimePod],
+      certificates: [],
+    });
+    assert.deepEqual(repairs.map((r) => r.kind), ["restart-pod"], name);
+  }
+});
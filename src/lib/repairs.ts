@@ -0,0 +1,127 @@
+import {
+  deletePod,
+  recreateFailedCertificate,
+  type CertificateStatus,
+  type PodStatus,
+} from "./kubernetes.js";
+import {
+  reencryptRealtimeTenants,
+  restartRealtime,
+} from "./realtimeRepair.js";
+import { DeploymentConfig, getNamespace } from "../types/index.js";
+
+/**
+ * `rulebricks status --repair`: safe fixes for problems `status` can see.
+ *
+ * Only repairs that are idempotent and never touch data are planned:
+ *   restart-pod          delete a crash-looping pod so its controller
+ *                        recreates it
+ *   reissue-certificate  recreate a Certificate whose issuance failed,
+ *                        skipping cert-manager's backoff
+ *   reseed-realtime      re-encrypt the Realtime tenant secret and restart
+ *                        Realtime (the `supabase fix-realtime` flow)
+ * PVCs, the database contents, and the Helm release are never changed; a
+ * missing ingress or other chart-rendered object comes back with `deploy`.
+ */
+
+export type RepairKind =
+  | "restart-pod"
+  | "reissue-certificate"
+  | "reseed-realtime";
+
+export interface PlannedRepair {
+  kind: RepairKind;
+  /** Pod, Certificate, or deployment the repair acts on. */
+  target: string;
+  /** What `status` saw that calls for it. */
+  reason: string;
+}
+
+/** Restarts before a not-ready pod counts as crash-looping. */
+const CRASH_LOOP_RESTARTS = 3;
+
+function isCrashLooping(pod: PodStatus): boolean {
+  return !pod.ready && pod.restarts >= CRASH_LOOP_RESTARTS;
+}
+
+function isRealtimePod(pod: PodStatus): boolean {
+  return pod.name.includes("-supabase-realtime-");
+}
+
+/** Repairs for the problems visible in a deployment's pods and certificates. */
+export function planRepairs(input: {
+  config: DeploymentConfig;
+  pods: PodStatus[];
+  certificates: CertificateStatus[];
+}): PlannedRepair[] {
+  const repairs: PlannedRepair[] = [];
+  const bundledSupabase =
+    input.config.database.type === "self-hosted" &&
+    input.config.externalServices?.postgres?.mode !== "external";
+
+  // A crash-looping Realtime is almost always a stale tenant secret, which a
+  // plain restart won't fix.
+  const realtime = input.pods.filter(
+    (pod) => isRealtimePod(pod) && isCrashLooping(pod),
+  );
+  if (bundledSupabase && realtime.length > 0) {
+    repairs.push({
+      kind: "reseed-realtime",
+      target: "supabase-realtime",
+      reason: `${realtime[0].name} restarted ${realtime[0].restarts} times`,
+    });
+  }
+
+  for (const pod of input.pods) {
+    if (!isCrashLooping(pod)) continue;
+    if (bundledSupabase && isRealtimePod(pod)) continue;
+    repairs.push({
+      kind: "restart-pod",
+      target: pod.name,
+      reason: `not ready after ${pod.restarts} restarts`,
+    });
+  }
+
+  for (const cert of input.certificates) {
+    if (!cert.failed) continue;
+    repairs.push({
+      kind: "reissue-certificate",
+      target: cert.name,
+      reason: cert.message || "issuance failed",
+    });
+  }
+
+  return repairs;
+}
+
+export function describeRepair(repair: PlannedRepair): string {
+  switch (repair.kind) {
+    case "restart-pod":
+      return `Restart pod ${repair.target}`;
+    case "reissue-certificate":
+      return `Reissue certificate ${repair.target}`;
+    case "reseed-realtime":
+      return "Re-encrypt the Realtime tenant secret and restart Realtime";
+  }
+}
+
+export async function applyRepair(
+  config: DeploymentConfig,
+  repair: PlannedRepair,
+): Promise<void> {
+  const namespace = getNamespace(config.name);
+  switch (repair.kind) {
+    case "restart-pod":
+      await deletePod(namespace, repair.target);
+      return;
+    case "reissue-certificate":
+      if (!(await recreateFailedCertificate(namespace, repair.target))) {
+        throw new Error(`Could not recreate certificate ${repair.target}`);
+      }
+      return;
+    case "reseed-realtime":
+      await reencryptRealtimeTenants(config);
+      await restartRealtime(config);
+      return;
+  }
+}
@@ -0,0 +1,137 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  buildNodeResourceUsage,
+  findUnschedulablePods,
+  formatCpu,
+  formatMemory,
+  parseNodeAllocatable,
+  parseNodeTop,
+  sumPodRequestsByNode,
+} from "./resourceUsage.js";
+
+const NODES = JSON.stringify({
+  items: [
+    {
+      metadata: { name: "node-b" },
+      status: { allocatable: { cpu: "1930m", memory: "7934604Ki" } },
+    },
+    {
+      metadata: { name: "node-a" },
+      status: { allocatable: { cpu: "4", memory: "16Gi" } },
+    },
+  ],
+});
+
+const PODS = JSON.stringify({
+  items: [
+    {
+      metadata: { name: "app-1", namespace: "rulebricks-prod" },
+      spec: {
+        nodeName: "node-a",
+        containers: [
+          { resources: { requests: { cpu: "500m", memory: "1Gi" } } },
+          { resources: { requests: { cpu: "100m", memory: "128Mi" } } },
+        ],
+        // Larger than the containers' CPU sum, so it sets the CPU request.
+        initContainers: [{ resources: { requests: { cpu: "1" } } }],
+      },
+      status: { phase: "Running" },
+    },
+    {
+      metadata: { name: "migrate-1", namespace: "rulebricks-prod" },
+      spec: {
+        nodeName: "node-a",
+        containers: [{ resources: { requests: { cpu: "2" } } }],
+      },
+      status: { phase: "Succeeded" },
+    },
+    {
+      metadata: { name: "hps-worker-9", namespace: "rulebricks-prod" },
+      spec: {
+        containers: [{ resources: { requests: { cpu: "8", memory: "4Gi" } } }],
+      },
+      status: {
+        phase: "Pending",
+        conditions: [
+          {
+            type: "PodScheduled",
+            status: "False",
+            reason: "Unschedulable",
+            message: "0/2 nodes are available: 2 Insufficient cpu.",
+          },
+        ],
+      },
+    },
+    {
+      metadata: { name: "db-0", namespace: "rulebricks-prod" },
+      spec: { containers: [] },
+      status: {
+        phase: "Pending",
+        conditions: [
+          {
+            type: "PodScheduled",
+            status: "False",
+            reason: "Unschedulable",
+            message: "pod has unbound immediate PersistentVolumeClaims",
+          },
+        ],
+      },
+    },
+  ],
+});
+
+test("sums effective requests per node, skipping finished pods", () => {
+  const byNode = sumPodRequestsByNode(PODS);
+  assert.deepEqual([...byNode.keys()], ["node-a"]);
+  assert.deepEqual(byNode.get("node-a"), {
+    cpu: 1000,
+    memory: 2 ** 30 + 128 * 2 ** 20,
+  });
+});
+
+test("reports only pods rejected for insufficient CPU or memory", () => {
+  assert.deepEqual(findUnschedulablePods(PODS), [
+    {
+      name: "hps-worker-9",
+      namespace: "rulebricks-prod",
+      message: "0/2 nodes are available: 2 Insufficient cpu.",
+    },
+  ]);
+});
+
+test("combines allocatable, requests and live usage per node", () => {
+  const usage = buildNodeResourceUsage(
+    parseNodeAllocatable(NODES),
+    sumPodRequestsByNode(PODS),
+    parseNodeTop("node-a   250m   6%   3Gi   19%\nnode-b 90m 4% 812Mi 10%\n"),
+  );
+  assert.deepEqual(usage.map((n) => n.name), ["node-a", "node-b"]);
+  assert.deepEqual(usage[0].cpu, {
+    allocatable: 4000,
+    requested: 1000,
+    used: 250,
+  });
+  assert.deepEqual(usage[1].memory, {
+    allocatable: 7934604 * 1024,
+    requested: 0,
+    used: 812 * 2 ** 20,
+  });
+});
+
+test("leaves usage null without metrics-server", () => {
+  const [node] = buildNodeResourceUsage(
+    parseNodeAllocatable(NODES),
+    new Map(),
+    null,
+  );
+  assert.equal(node.cpu.used, null);
+  assert.equal(node.memory.used, null);
+});
+
+test("formats CPU and memory compactly", () => {
+  assert.equal(formatCpu(250), "250m");
+  assert.equal(formatCpu(1930), "1.93");
+  assert.equal(formatMemory(512 * 2 ** 20), "512Mi");
+  assert.equal(formatMemory(16 * 2 ** 30), "16Gi");
+});
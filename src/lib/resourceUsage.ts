@@ -0,0 +1,210 @@
+import { parseCpuMillicores, parseMemoryBytes } from "./quantities.js";
+
+/**
+ * `status --resources`: per-node allocatable CPU/memory against what the
+ * scheduled pods request (and, when metrics-server answers, what they use),
+ * plus the deployment's pods the scheduler cannot place for lack of room.
+ * Parsers take raw `kubectl ... -o json` / `kubectl top` output.
+ */
+
+export interface ResourceAmount {
+  allocatable: number;
+  requested: number;
+  /** From `kubectl top nodes`; null when metrics-server is unavailable. */
+  used: number | null;
+}
+
+export interface NodeResourceUsage {
+  name: string;
+  /** Millicores. */
+  cpu: ResourceAmount;
+  /** Bytes. */
+  memory: ResourceAmount;
+}
+
+export interface UnschedulablePod {
+  name: string;
+  namespace: string;
+  message: string;
+}
+
+/** Millicores and bytes. */
+export interface ResourceTotals {
+  cpu: number;
+  memory: number;
+}
+
+type ContainerList = Array<{
+  resources?: { requests?: Record<string, string> };
+}>;
+
+interface PodItem {
+  metadata?: { name?: string; namespace?: string };
+  spec?: {
+    nodeName?: string;
+    containers?: ContainerList;
+    initContainers?: ContainerList;
+  };
+  status?: {
+    phase?: string;
+    conditions?: Array<{
+      type?: string;
+      status?: string;
+      reason?: string;
+      message?: string;
+    }>;
+  };
+}
+
+function cpu(value: string | undefined): number {
+  return value ? (parseCpuMillicores(value) ?? 0) : 0;
+}
+
+function memory(value: string | undefined): number {
+  return value ? (parseMemoryBytes(value) ?? 0) : 0;
+}
+
+function sumRequests(containers: ContainerList | undefined): ResourceTotals {
+  return (containers ?? []).reduce(
+    (total, c) => ({
+      cpu: total.cpu + cpu(c.resources?.requests?.cpu),
+      memory: total.memory + memory(c.resources?.requests?.memory),
+    }),
+    { cpu: 0, memory: 0 },
+  );
+}
+
+/**
+ * A pod's effective request, as the scheduler counts it: the sum over its
+ * containers, or the largest init container if that is bigger.
+ */
+function podRequests(pod: PodItem): ResourceTotals {
+  const running = sumRequests(pod.spec?.containers);
+  const init = (pod.spec?.initContainers ?? []).map((c) => sumRequests([c]));
+  return {
+    cpu: Math.max(running.cpu, ...init.map((t) => t.cpu)),
+    memory: Math.max(running.memory, ...init.map((t) => t.memory)),
+  };
+}
+
+/** Node name → allocatable millicores/bytes (`kubectl get nodes -o json`). */
+export function parseNodeAllocatable(raw: string): Map<string, ResourceTotals> {
+  const data = JSON.parse(raw) as {
+    items?: Array<{
+      metadata?: { name?: string };
+      status?: { allocatable?: Record<string, string> };
+    }>;
+  };
+  const nodes = new Map<string, ResourceTotals>();
+  for (const node of data.items ?? []) {
+    if (!node.metadata?.name) continue;
+    nodes.set(node.metadata.name, {
+      cpu: cpu(node.status?.allocatable?.cpu),
+      memory: memory(node.status?.allocatable?.memory),
+    });
+  }
+  return nodes;
+}
+
+/**
+ * Node name → summed requests of the pods scheduled there, from
+ * `kubectl get pods -A -o json`. Finished pods hold no resources.
+ */
+export function sumPodRequestsByNode(raw: string): Map<string, ResourceTotals> {
+  const data = JSON.parse(raw) as { items?: PodItem[] };
+  const byNode = new Map<string, ResourceTotals>();
+  for (const pod of data.items ?? []) {
+    const node = pod.spec?.nodeName;
+    const phase = pod.status?.phase;
+    if (!node || phase === "Succeeded" || phase === "Failed") continue;
+    const requests = podRequests(pod);
+    const total = byNode.get(node) ?? { cpu: 0, memory: 0 };
+    byNode.set(node, {
+      cpu: total.cpu + requests.cpu,
+      memory: total.memory + requests.memory,
+    });
+  }
+  return byNode;
+}
+
+/** Node name → live usage, from `kubectl top nodes --no-headers`. */
+export function parseNodeTop(stdout: string): Map<string, ResourceTotals> {
+  const usage = new Map<string, ResourceTotals>();
+  for (const line of stdout.split("\n")) {
+    // NAME CPU(cores) CPU% MEMORY(bytes) MEMORY%
+    const [name, cpuUsed, , memoryUsed] = line.trim().split(/\s+/);
+    if (!name || !cpuUsed || !memoryUsed) continue;
+    usage.set(name, { cpu: cpu(cpuUsed), memory: memory(memoryUsed) });
+  }
+  return usage;
+}
+
+/**
+ * Pending pods the scheduler rejected for lack of CPU/memory (the
+ * PodScheduled condition's "Insufficient cpu/memory" message).
+ */
+export function findUnschedulablePods(raw: string): UnschedulablePod[] {
+  const data = JSON.parse(raw) as { items?: PodItem[] };
+  const pods: UnschedulablePod[] = [];
+  for (const pod of data.items ?? []) {
+    if (pod.status?.phase !== "Pending") continue;
+    const scheduled = pod.status.conditions?.find(
+      (c) => c.type === "PodScheduled",
+    );
+    if (
+      scheduled?.status === "False" &&
+      scheduled.reason === "Unschedulable" &&
+      /Insufficient (cpu|memory)/i.test(scheduled.message ?? "")
+    ) {
+      pods.push({
+        name: pod.metadata?.name ?? "",
+        namespace: pod.metadata?.namespace ?? "",
+        message: scheduled.message ?? "",
+      });
+    }
+  }
+  return pods;
+}
+
+export function buildNodeResourceUsage(
+  allocatable: Map<string, ResourceTotals>,
+  requested: Map<string, ResourceTotals>,
+  usage: Map<string, ResourceTotals> | null,
+): NodeResourceUsage[] {
+  return [...allocatable.entries()]
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([name, alloc]) => {
+      const req = requested.get(name) ?? { cpu: 0, memory: 0 };
+      const used = usage?.get(name) ?? null;
+      return {
+        name,
+        cpu: {
+          allocatable: alloc.cpu,
+          requested: req.cpu,
+          used: used?.cpu ?? null,
+        },
+        memory: {
+          allocatable: alloc.memory,
+          requested: req.memory,
+          used: used?.memory ?? null,
+        },
+      };
+    });
+}
+
+/** Millicores as "250m" or "1.5". */
+export function formatCpu(millicores: number): string {
+  if (millicores < 1000) return `${Math.round(millicores)}m`;
+  return `${Number((millicores / 1000).toFixed(2))}`;
+}
+
+/** Bytes as Mi below 1Gi, else Gi. */
+export function formatMemory(bytes: number): string {
+  if (bytes < 2 ** 30) return `${Math.round(bytes / 2 ** 20)}Mi`;
+  return `${Number((bytes / 2 ** 30).toFixed(1))}Gi`;
+}
+
+/** Share of allocatable, 0-100 (rounded); 0 when nothing is allocatable. */
+export function percentOf(amount: number, allocatable: number): number {
+  return allocatable > 0 ? Math.round((amount / allocatable) * 100) : 0;
+}
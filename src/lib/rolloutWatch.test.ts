@@ -0,0 +1,79 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  applyPodUpdate,
+  applyWarning,
+  EMPTY_ROLLOUT_VIEW,
+  parseEventWatchLine,
+  parsePodWatchLine,
+  rolloutPodHealth,
+} from "./rolloutWatch.js";
+
+test("pod watch lines parse kubectl's status column", () => {
+  assert.deepEqual(
+    parsePodWatchLine(
+      "rulebricks-hps-7f8b9c6d5-x2k4m   0/1   ContainerCreating   0     3s",
+    ),
+    {
+      name: "rulebricks-hps-7f8b9c6d5-x2k4m",
+      ready: "0/1",
+      status: "ContainerCreating",
+      restarts: 0,
+    },
+  );
+  const restarted = parsePodWatchLine(
+    "rulebricks-app-1   1/1   Running   2 (40s ago)   5m",
+  );
+  assert.equal(restarted?.restarts, 2);
+  assert.equal(parsePodWatchLine("No resources found in ns namespace."), null);
+});
+
+test("pod health distinguishes failing, progressing and ready", () => {
+  const pod = { name: "p", ready: "0/1", status: "Pending", restarts: 0 };
+  assert.equal(rolloutPodHealth(pod), "progressing");
+  assert.equal(
+    rolloutPodHealth({ ...pod, status: "ImagePullBackOff" }),
+    "failing",
+  );
+  assert.equal(rolloutPodHealth({ ...pod, status: "Running" }), "progressing");
+  assert.equal(
+    rolloutPodHealth({ ...pod, status: "Running", ready: "1/1" }),
+    "ready",
+  );
+});
+
+test("updates replace pods by name and warnings drop repeats", () => {
+  let view = applyPodUpdate(EMPTY_ROLLOUT_VIEW, {
+    name: "b",
+    ready: "0/1",
+    status: "Pending",
+    restarts: 0,
+  });
+  view = applyPodUpdate(view, {
+    name: "a",
+    ready: "0/1",
+    status: "Pending",
+    restarts: 0,
+  });
+  view = applyPodUpdate(view, {
+    name: "b",
+    ready: "1/1",
+    status: "Running",
+    restarts: 0,
+  });
+  assert.deepEqual(
+    view.pods.map((pod) => `${pod.name}:${pod.status}`),
+    ["a:Pending", "b:Running"],
+  );
+
+  const warning = parseEventWatchLine(
+    "rulebricks-hps-x   FailedScheduling   0/3 nodes are available: 3 Insufficient cpu.",
+  );
+  assert.deepEqual(warning, {
+    object: "rulebricks-hps-x",
+    reason: "FailedScheduling",
+    message: "0/3 nodes are available: 3 Insufficient cpu.",
+  });
+  view = applyWarning(applyWarning(view, warning!), warning!);
+  assert.equal(view.warnings.length, 1);
+});
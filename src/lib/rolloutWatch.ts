@@ -0,0 +1,189 @@
+import { execa } from "execa";
+
+/**
+ * Live pod view for `deploy --watch-rollout`.
+ *
+ * While a step installs workloads, two `kubectl --watch` streams run against
+ * the deployment namespace: pod status lines (the same STATUS column
+ * `kubectl get pods` shows, so ContainerCreating, ErrImagePull and
+ * CrashLoopBackOff come through as-is) and new Warning events
+ * (FailedScheduling, FailedMount, BackOff, ...). The deploy screen renders the
+ * folded result under the running step so stuck pulls and unschedulable pods
+ * show up immediately instead of after the Helm timeout.
+ */
+
+export interface RolloutPod {
+  name: string;
+  /** Container readiness as printed by kubectl, e.g. "1/2". */
+  ready: string;
+  /** kubectl's STATUS column: Pending, ContainerCreating, Running, ... */
+  status: string;
+  restarts: number;
+}
+
+export interface RolloutWarning {
+  object: string;
+  reason: string;
+  message: string;
+}
+
+export interface RolloutView {
+  pods: RolloutPod[];
+  warnings: RolloutWarning[];
+}
+
+export const EMPTY_ROLLOUT_VIEW: RolloutView = { pods: [], warnings: [] };
+
+/** Warnings kept on screen; older ones scroll off. */
+const MAX_WARNINGS = 5;
+
+const FAILING_STATUSES = new Set([
+  "ErrImagePull",
+  "ImagePullBackOff",
+  "CrashLoopBackOff",
+  "CreateContainerConfigError",
+  "InvalidImageName",
+  "OOMKilled",
+  "Error",
+  "Failed",
+]);
+
+export type RolloutPodHealth = "ready" | "progressing" | "failing";
+
+export function rolloutPodHealth(pod: RolloutPod): RolloutPodHealth {
+  if (FAILING_STATUSES.has(pod.status) || pod.status.startsWith("Init:Err")) {
+    return "failing";
+  }
+  if (pod.status === "Completed") return "ready";
+  const [ready, total] = pod.ready.split("/");
+  return pod.status === "Running" && ready === total ? "ready" : "progressing";
+}
+
+/**
+ * Parses a `kubectl get pods --watch --no-headers` line
+ * ("NAME READY STATUS RESTARTS AGE"; RESTARTS may carry "(5m ago)").
+ */
+export function parsePodWatchLine(line: string): RolloutPod | null {
+  const fields = line.trim().split(/\s+/);
+  if (fields.length < 4 || !/^\d+\/\d+$/.test(fields[1])) return null;
+  return {
+    name: fields[0],
+    ready: fields[1],
+    status: fields[2],
+    restarts: Number.parseInt(fields[3], 10) || 0,
+  };
+}
+
+/**
+ * Parses a line of the Warning event watch, printed with custom columns
+ * OBJECT, REASON, MESSAGE (the message keeps its spaces).
+ */
+export function parseEventWatchLine(line: string): RolloutWarning | null {
+  const match = /^(\S+)\s+(\S+)\s+(.*)$/.exec(line.trim());
+  if (!match) return null;
+  return { object: match[1], reason: match[2], message: match[3] };
+}
+
+/** Folds a pod update into the view (pods stay sorted by name). */
+export function applyPodUpdate(
+  view: RolloutView,
+  pod: RolloutPod,
+): RolloutView {
+  const pods = view.pods.filter((existing) => existing.name !== pod.name);
+  pods.push(pod);
+  pods.sort((a, b) => a.name.localeCompare(b.name));
+  return { ...view, pods };
+}
+
+/** Appends a warning, dropping an identical repeat and the oldest overflow. */
+export function applyWarning(
+  view: RolloutView,
+  warning: RolloutWarning,
+): RolloutView {
+  const last = view.warnings[view.warnings.length - 1];
+  if (
+    last &&
+    last.object === warning.object &&
+    last.reason === warning.reason &&
+    last.message === warning.message
+  ) {
+    return view;
+  }
+  return {
+    ...view,
+    warnings: [...view.warnings, warning].slice(-MAX_WARNINGS),
+  };
+}
+
+function followLines(
+  args: string[],
+  onLine: (line: string) => void,
+): { kill: (signal?: string) => void } {
+  const proc = execa("kubectl", args);
+  let buffer = "";
+  proc.stdout?.on("data", (chunk: Buffer) => {
+    buffer += chunk.toString();
+    const lines = buffer.split("\n");
+    buffer = lines.pop() || "";
+    for (const line of lines) {
+      if (line.trim()) onLine(line);
+    }
+  });
+  // Watches end when killed, or when the namespace is deleted underneath.
+  proc.catch(() => {});
+  return proc;
+}
+
+/**
+ * Starts the pod and Warning event watches for a namespace. `onChange`
+ * receives the updated view after every line. Returns a function that stops
+ * both watches.
+ */
+export function watchRollout(
+  namespace: string,
+  onChange: (view: RolloutView) => void,
+): () => void {
+  let view = EMPTY_ROLLOUT_VIEW;
+
+  const pods = followLines(
+    ["get", "pods", "-n", namespace, "--watch", "--no-headers"],
+    (line) => {
+      const pod = parsePodWatchLine(line);
+      if (!pod) return;
+      view = applyPodUpdate(view, pod);
+      onChange(view);
+    },
+  );
+
+  // --watch-only: warnings left over from earlier deploys aren't news.
+  const events = followLines(
+    [
+      "get",
+      "events",
+      "-n",
+      namespace,
+      "--watch-only",
+      "--no-headers",
+      "--field-selector",
+      "type=Warning",
+      "-o",
+      "custom-columns=OBJECT:.involvedObject.name,REASON:.reason,MESSAGE:.message",
+    ],
+    (line) => {
+      const warning = parseEventWatchLine(line);
+      if (!warning) return;
+      view = applyWarning(view, warning);
+      onChange(view);
+    },
+  );
+
+  return () => {
+    for (const proc of [pods, events]) {
+      try {
+        proc.kill("SIGTERM");
+      } catch {
+        // Process may have already exited
+      }
+    }
+  };
+}
@@ -0,0 +1,98 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import {
+  dbPasswordRotationSql,
+  rotateConfigSecrets,
+  rotationProblem,
+  workloadsToRestart,
+} from "./secretRotation.js";
+import { configFixture } from "./configFixtures.js";
+import { DeploymentState } from "../types/index.js";
+
+test("only the chosen credentials are regenerated", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  const rotated = rotateConfigSecrets(config, ["dbPassword"], (length) =>
+    "N".repeat(length),
+  );
+  assert.equal(rotated.database.supabaseDbPassword, "N".repeat(32));
+  assert.equal(
+    rotated.database.supabaseJwtSecret,
+    config.database.supabaseJwtSecret,
+  );
+  assert.equal(
+    rotated.database.supabaseDashboardPass,
+    config.database.supabaseDashboardPass,
+  );
+  // The input config is left alone.
+  assert.notEqual(config.database.supabaseDbPassword, "N".repeat(32));
+});
+
+test("rotation is refused where the CLI can't deliver the secrets", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.equal(rotationProblem(config, null, ["jwt"]), null);
+
+  assert.match(
+    String(rotationProblem(configFixture("aws-supabase-cloud"), null, ["jwt"])),
+    /only applies to self-hosted Supabase/,
+  );
+  const external = configFixture("aws-external-postgres");
+  assert.equal(rotationProblem(external, null, ["jwt"]), null);
+  assert.match(
+    String(rotationProblem(external, null, ["dbPassword"])),
+    /external Postgres/,
+  );
+  const inline = {
+    appliedConfig: {
+      sections: {},
+      chartVersion: "1.0.0",
+      secretMode: "inline",
+    },
+  } as unknown as DeploymentState;
+  assert.match(
+    String(rotationProblem(config, inline, ["dashboard"])),
+    /--inline-secrets/,
+  );
+});
+
+test("the password SQL only touches roles that exist", () => {
+  const sql = dbPasswordRotationSql("Abc123");
+  assert.match(sql, /'supabase_auth_admin'/);
+  assert.match(sql, /IF EXISTS \(SELECT 1 FROM pg_roles WHERE rolname = r\)/);
+  assert.match(sql, /ALTER ROLE %I WITH PASSWORD %L', r, 'Abc123'/);
+  assert.throws(() => dbPasswordRotationSql("x'; DROP TABLE t; --"));
+});
+
+test("each credential restarts only the workloads that read it", () => {
+  const release = "rulebricks-prod";
+  const workloads = [
+    { kind: "Deployment", name: `${release}-app`, images: [] },
+    { kind: "Deployment", name: `${release}-hps`, images: [] },
+    { kind: "Deployment", name: `${release}-supabase-auth`, images: [] },
+    { kind: "Deployment", name: `${release}-supabase-kong`, images: [] },
+    { kind: "Deployment", name: `${release}-supabase-studio`, images: [] },
+    { kind: "StatefulSet", name: `${release}-supabase-db`, images: [] },
+    { kind: "StatefulSet", name: `${release}-kafka`, images: [] },
+    { kind: "DaemonSet", name: `${release}-vector-agent`, images: [] },
+    { kind: "Deployment", name: "cert-manager", images: [] },
+  ];
+  const names = (targets: Parameters<typeof workloadsToRestart>[2]) =>
+    workloadsToRestart(workloads, release, targets).map((w) => w.name);
+
+  assert.deepEqual(names(["dashboard"]), [
+    `${release}-supabase-kong`,
+    `${release}-supabase-studio`,
+  ]);
+  assert.deepEqual(names(["dbPassword"]), [
+    `${release}-supabase-auth`,
+    `${release}-supabase-kong`,
+    `${release}-supabase-studio`,
+    `${release}-supabase-db`,
+  ]);
+  assert.deepEqual(names(["jwt"]), [
+    `${release}-app`,
+    `${release}-hps`,
+    `${release}-supabase-auth`,
+    `${release}-supabase-kong`,
+    `${release}-supabase-studio`,
+  ]);
+});
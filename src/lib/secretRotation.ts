@@ -0,0 +1,197 @@
+import { WorkloadSpec } from "./driftReport.js";
+import {
+  rolloutRestart,
+  runSupabaseDbSql,
+  waitForRolloutReady,
+  WorkloadType,
+} from "./kubernetes.js";
+import { generateSecureSecret } from "./validation.js";
+import {
+  DeploymentConfig,
+  DeploymentState,
+  getNamespace,
+  getReleaseName,
+} from "../types/index.js";
+
+/**
+ * `rulebricks secrets rotate`: replaces self-hosted Supabase credentials.
+ *
+ * New values are written to config.yaml first, which stays the source of
+ * truth; the anon and service keys are not stored but signed from the JWT
+ * secret (buildDeploymentSecrets), so both always change together with it.
+ * The database password is then changed on the Postgres roles, since the
+ * secrets are useless until the database accepts them, then the secrets are
+ * pushed through the deployment's secrets backend as a deploy would, and
+ * the workloads reading them are restarted.
+ */
+
+export type RotationTarget = "jwt" | "dbPassword" | "dashboard";
+
+export const ROTATION_TARGET_LABELS: Record<RotationTarget, string> = {
+  jwt: "JWT secret (anon and service keys)",
+  dbPassword: "Database password",
+  dashboard: "Studio dashboard password",
+};
+
+/**
+ * Roles self-hosted Supabase creates with the database password. Roles the
+ * image doesn't have are skipped by the rotation SQL.
+ */
+export const SUPABASE_PASSWORD_ROLES = [
+  "postgres",
+  "supabase_admin",
+  "authenticator",
+  "pgbouncer",
+  "supabase_auth_admin",
+  "supabase_storage_admin",
+  "supabase_functions_admin",
+  "supabase_replication_admin",
+  "supabase_read_only_user",
+];
+
+/**
+ * Why the targets can't be rotated for this deployment, or null. `state`
+ * says how the last deploy delivered secrets: inline ones live in the Helm
+ * release and only a deploy can replace them.
+ */
+export function rotationProblem(
+  config: DeploymentConfig,
+  state: DeploymentState | null,
+  targets: RotationTarget[],
+): string | null {
+  if (config.database.type !== "self-hosted") {
+    return "Secret rotation only applies to self-hosted Supabase; rotate Supabase Cloud keys in the Supabase dashboard.";
+  }
+  if (
+    targets.includes("dbPassword") &&
+    config.externalServices?.postgres?.mode === "external"
+  ) {
+    return "With an external Postgres, change the database password there and update database.supabaseDbPassword, then run `rulebricks deploy`.";
+  }
+  if (config.secrets?.backend === "byo-secret-store") {
+    return "With byo-secret-store the CLI does not write your secret store; update the entries there and restart the workloads.";
+  }
+  if (state?.appliedConfig?.secretMode === "inline") {
+    return "This deployment was installed with --inline-secrets, so its secrets are in the Helm release; change them in config.yaml and run `rulebricks deploy --inline-secrets`.";
+  }
+  return null;
+}
+
+/** A copy of `config` with fresh values for the targets. */
+export function rotateConfigSecrets(
+  config: DeploymentConfig,
+  targets: RotationTarget[],
+  generate: (length: number) => string = generateSecureSecret,
+): DeploymentConfig {
+  const database = { ...config.database };
+  if (targets.includes("jwt")) {
+    database.supabaseJwtSecret = generate(64);
+  }
+  if (targets.includes("dbPassword")) {
+    database.supabaseDbPassword = generate(32);
+  }
+  if (targets.includes("dashboard")) {
+    database.supabaseDashboardPass = generate(32);
+  }
+  return { ...config, database };
+}
+
+/**
+ * SQL that sets the password of every Supabase role that exists. Generated
+ * passwords are alphanumeric, so inlining one is safe; anything else is
+ * refused rather than quoted.
+ */
+export function dbPasswordRotationSql(password: string): string {
+  if (!/^[A-Za-z0-9]+$/.test(password)) {
+    throw new Error("Refusing to inline a non-alphanumeric password in SQL");
+  }
+  const roles = SUPABASE_PASSWORD_ROLES.map((role) => `'${role}'`).join(", ");
+  return [
+    "DO $$ DECLARE r text; BEGIN",
+    `FOREACH r IN ARRAY ARRAY[${roles}] LOOP`,
+    "IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = r) THEN",
+    `EXECUTE format('ALTER ROLE %I WITH PASSWORD %L', r, '${password}');`,
+    "END IF; END LOOP; END $$;",
+  ].join(" ");
+}
+
+/**
+ * Workloads that read the rotated secrets, from the namespace's live ones.
+ * Every Rulebricks and Supabase service verifies tokens against the JWT
+ * secret; only Supabase connects with the database password (the database
+ * itself is restarted so its environment carries the new one); the
+ * dashboard password is checked by Kong in front of Studio.
+ */
+export function workloadsToRestart(
+  workloads: WorkloadSpec[],
+  releaseName: string,
+  targets: RotationTarget[],
+): Array<{ type: WorkloadType; name: string }> {
+  const supabase = `${releaseName}-supabase-`;
+  const selected = workloads.filter((workload) => {
+    if (!workload.name.startsWith(`${releaseName}-`)) return false;
+    const isSupabase = workload.name.startsWith(supabase);
+    if (workload.kind === "StatefulSet") {
+      return (
+        targets.includes("dbPassword") && workload.name === `${supabase}db`
+      );
+    }
+    if (workload.kind !== "Deployment") return false;
+    if (targets.includes("jwt")) return true;
+    if (targets.includes("dbPassword") && isSupabase) return true;
+    return (
+      targets.includes("dashboard") &&
+      (workload.name === `${supabase}kong` ||
+        workload.name === `${supabase}studio`)
+    );
+  });
+  return selected.map((workload) => ({
+    type: workload.kind === "StatefulSet" ? "statefulset" : "deployment",
+    name: workload.name,
+  }));
+}
+
+/**
+ * Changes the role passwords through the bundled database, connecting with
+ * the password it runs with now (its POSTGRES_PASSWORD).
+ */
+export async function rotateDatabasePassword(
+  config: DeploymentConfig,
+  password: string,
+): Promise<void> {
+  await runSupabaseDbSql(
+    getNamespace(config.name),
+    getReleaseName(config.name),
+    dbPasswordRotationSql(password),
+  );
+}
+
+/**
+ * Restarts the workloads, the database first since the others connect to
+ * it, then waits for all of them to roll out. Returns the names restarted.
+ */
+export async function restartWorkloads(
+  namespace: string,
+  workloads: Array<{ type: WorkloadType; name: string }>,
+): Promise<string[]> {
+  const ordered = [
+    ...workloads.filter((w) => w.type === "statefulset"),
+    ...workloads.filter((w) => w.type !== "statefulset"),
+  ];
+  for (const workload of ordered) {
+    if (!(await rolloutRestart(workload.type, workload.name, namespace))) {
+      throw new Error(
+        `Could not restart ${workload.type} ${workload.name} in ${namespace}`,
+      );
+    }
+    if (workload.type === "statefulset") {
+      await waitForRolloutReady(namespace, workload.type, workload.name);
+    }
+  }
+  for (const workload of ordered) {
+    if (workload.type !== "statefulset") {
+      await waitForRolloutReady(namespace, workload.type, workload.name);
+    }
+  }
+  return ordered.map((workload) => workload.name);
+}
@@ -0,0 +1,48 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { holdComponentValues } from "./skipComponents.js";
+
+const VALUES = {
+  rulebricks: { app: { replicas: 2 } },
+  monitoring: { enabled: true },
+  "kube-prometheus-stack": { enabled: true, prometheus: { retention: "30d" } },
+  vector: { enabled: true, sinks: ["s3"] },
+  "vector-agent": { enabled: true },
+};
+
+test("skipped components keep the live release's blocks", () => {
+  const live = {
+    rulebricks: { app: { replicas: 1 } },
+    monitoring: { enabled: true },
+    "kube-prometheus-stack": { enabled: true, prometheus: { retention: "7d" } },
+  };
+
+  const held = holdComponentValues(VALUES, live, ["monitoring"]);
+  assert.deepEqual(
+    held["kube-prometheus-stack"],
+    live["kube-prometheus-stack"],
+  );
+  assert.deepEqual(held.rulebricks, VALUES.rulebricks);
+  assert.deepEqual(held.vector, VALUES.vector);
+});
+
+test("a block the release was installed without is left to the chart", () => {
+  const held = holdComponentValues(VALUES, { vector: { enabled: true } }, [
+    "logging",
+  ]);
+  assert.deepEqual(held.vector, { enabled: true });
+  assert.equal("vector-agent" in held, false);
+});
+
+test("without a release, skipped components stay uninstalled", () => {
+  const held = holdComponentValues(VALUES, null, ["monitoring", "logging"]);
+  for (const block of [
+    "monitoring",
+    "kube-prometheus-stack",
+    "vector",
+    "vector-agent",
+  ]) {
+    assert.deepEqual(held[block], { enabled: false });
+  }
+  assert.deepEqual(VALUES.monitoring, { enabled: true });
+});
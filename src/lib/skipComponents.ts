@@ -0,0 +1,76 @@
+import path from "path";
+import yaml from "yaml";
+import { loadHelmValues } from "./config.js";
+import { getInstalledChartVersion, getReleaseUserValues } from "./helm.js";
+import { removeTempPath, writeTempFile } from "./tempFiles.js";
+
+/**
+ * `deploy --skip-monitoring` / `--skip-logging`. Monitoring and logging are
+ * part of the one umbrella release, so they can't be left out of the Helm
+ * upgrade; instead their value blocks are held at what the live release was
+ * last installed with, which re-renders them unchanged. Nothing installed is
+ * removed, and a config change to them waits for a deploy without the flag.
+ */
+
+export type SkippableComponent = "monitoring" | "logging";
+
+/** The top-level chart value blocks each skippable component owns. */
+export const SKIPPABLE_COMPONENT_BLOCKS: Record<SkippableComponent, string[]> =
+  {
+    monitoring: ["monitoring", "kube-prometheus-stack"],
+    logging: ["vector", "vector-agent"],
+  };
+
+/**
+ * values.yaml with the skipped components' blocks replaced by the live
+ * release's. `live` is null when there is no release yet, in which case the
+ * components stay uninstalled. A block the release was installed without
+ * is dropped, so the chart default applies again as it did then.
+ */
+export function holdComponentValues(
+  values: Record<string, unknown>,
+  live: Record<string, unknown> | null,
+  skip: SkippableComponent[],
+): Record<string, unknown> {
+  const held = { ...values };
+  for (const block of skip.flatMap((c) => SKIPPABLE_COMPONENT_BLOCKS[c])) {
+    if (!live) {
+      held[block] = { enabled: false };
+    } else if (block in live) {
+      held[block] = live[block];
+    } else {
+      delete held[block];
+    }
+  }
+  return held;
+}
+
+/**
+ * Writes the held values for one Helm install or upgrade to a private temp
+ * file and returns its path, with a release function that removes it.
+ * Throws when the release exists but its values can't be read, rather than
+ * treating it as absent and disabling what it runs.
+ */
+export async function writeHeldValues(
+  deploymentName: string,
+  releaseName: string,
+  namespace: string,
+  skip: SkippableComponent[],
+): Promise<{ path: string; release: () => Promise<void> }> {
+  const values = await loadHelmValues(deploymentName);
+  if (!values) {
+    throw new Error(`No values.yaml found for ${deploymentName}`);
+  }
+  const live = await getReleaseUserValues(releaseName, namespace);
+  if (!live && (await getInstalledChartVersion(releaseName, namespace))) {
+    throw new Error(
+      `Could not read the values of release ${releaseName}, so ${skip.join(" and ")} can't be held as installed`,
+    );
+  }
+  const file = await writeTempFile(
+    "rulebricks-values-",
+    "values.yaml",
+    yaml.stringify(holdComponentValues(values, live, skip)),
+  );
+  return { path: file, release: () => removeTempPath(path.dirname(file)) };
+}
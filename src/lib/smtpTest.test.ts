@@ -0,0 +1,148 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import net from "node:net";
+import {
+  advertises,
+  authMechanisms,
+  buildTestMessage,
+  smtpTransport,
+  testSmtp,
+  type SmtpSettings,
+} from "./smtpTest.js";
+
+const SMTP: SmtpSettings = {
+  host: "127.0.0.1",
+  port: 587,
+  user: "mailer",
+  pass: "hunter2",
+  from: "noreply@example.com",
+  fromName: "Rulebricks",
+};
+
+/**
+ * A minimal SMTP server without STARTTLS. `authCode` is the reply to AUTH;
+ * every command line and the DATA payload are recorded.
+ */
+async function fakeServer(
+  authCode = 235,
+): Promise<{ port: number; received: string[]; close: () => void }> {
+  const received: string[] = [];
+  const server = net.createServer((socket) => {
+    let buffer = "";
+    let inData = false;
+    socket.setEncoding("utf-8");
+    socket.write("220 fake ESMTP\r\n");
+    socket.on("data", (chunk: string) => {
+      buffer += chunk;
+      if (inData) {
+        const end = buffer.indexOf("\r\n.\r\n");
+        if (end === -1) return;
+        received.push(buffer.slice(0, end));
+        buffer = buffer.slice(end + 5);
+        inData = false;
+        socket.write("250 queued\r\n");
+      }
+      let end: number;
+      while (!inData && (end = buffer.indexOf("\r\n")) !== -1) {
+        const line = buffer.slice(0, end);
+        buffer = buffer.slice(end + 2);
+        received.push(line);
+        const verb = line.split(" ")[0].toUpperCase();
+        if (verb === "EHLO") {
+          socket.write("250-fake\r\n250-AUTH LOGIN PLAIN\r\n250 8BITMIME\r\n");
+        } else if (verb === "AUTH") {
+          socket.write(`${authCode} auth\r\n`);
+        } else if (verb === "DATA") {
+          inData = true;
+          socket.write("354 go ahead\r\n");
+        } else if (verb === "QUIT") {
+          socket.end("221 bye\r\n");
+        } else {
+          socket.write("250 ok\r\n");
+        }
+      }
+    });
+  });
+  await new Promise<void>((resolve) => server.listen(0, "127.0.0.1", resolve));
+  return {
+    port: (server.address() as net.AddressInfo).port,
+    received,
+    close: () => server.close(),
+  };
+}
+
+test("port 465 is implicit TLS and everything else STARTTLS", () => {
+  assert.equal(smtpTransport(465), "tls");
+  assert.equal(smtpTransport(587), "starttls");
+  assert.equal(smtpTransport(2525), "starttls");
+});
+
+test("EHLO extensions are read case-insensitively", () => {
+  const ehlo = ["smtp.example.com", "auth=login plain", "starttls"];
+  assert.deepEqual(authMechanisms(ehlo), ["LOGIN", "PLAIN"]);
+  assert.ok(advertises(ehlo, "STARTTLS"));
+  assert.equal(advertises(ehlo, "SMTPUTF8"), false);
+});
+
+test("the test message is CRLF-terminated with the configured sender", () => {
+  const message = buildTestMessage(
+    SMTP,
+    "ops@example.com",
+    new Date("2026-01-01T00:00:00Z"),
+  );
+  assert.match(message, /^From: Rulebricks <noreply@example\.com>\r\n/);
+  assert.match(message, /\r\nTo: <ops@example\.com>\r\n/);
+  assert.ok(message.endsWith("\r\n"));
+  assert.equal(message.includes("\n.\r"), false);
+});
+
+test("authenticates and sends through a local server", async () => {
+  const server = await fakeServer();
+  try {
+    const result = await testSmtp(
+      { ...SMTP, port: server.port },
+      { to: "ops@example.com" },
+    );
+
+    assert.ok(result.ok);
+    assert.deepEqual(
+      result.steps.map((step) => step.name),
+      ["connect", "starttls", "auth", "send"],
+    );
+    const token = Buffer.from("\0mailer\0hunter2").toString("base64");
+    assert.ok(server.received.includes(`AUTH PLAIN ${token}`));
+    assert.ok(server.received.includes("MAIL FROM:<noreply@example.com>"));
+    assert.ok(server.received.includes("RCPT TO:<ops@example.com>"));
+    assert.ok(
+      server.received.some((line) =>
+        line.includes("Subject: Rulebricks SMTP test"),
+      ),
+    );
+  } finally {
+    server.close();
+  }
+});
+
+test("a rejected login fails the auth step", async () => {
+  const server = await fakeServer(535);
+  try {
+    const result = await testSmtp({ ...SMTP, port: server.port });
+
+    assert.equal(result.ok, false);
+    const last = result.steps[result.steps.length - 1];
+    assert.equal(last.name, "auth");
+    assert.equal(last.ok, false);
+    assert.match(last.detail ?? "", /^AUTH PLAIN: 535/);
+  } finally {
+    server.close();
+  }
+});
+
+test("an unreachable server fails the connect step", async () => {
+  const server = await fakeServer();
+  server.close();
+  const result = await testSmtp({ ...SMTP, port: server.port });
+
+  assert.equal(result.ok, false);
+  assert.deepEqual(result.steps.map((step) => step.name), ["connect"]);
+});
@@ -0,0 +1,334 @@
+import net from "net";
+import tls from "tls";
+import type { DeploymentConfig } from "../types/index.js";
+
+/**
+ * `rulebricks email test`: proves the SMTP settings work before GoTrue
+ * needs them. Connects the way GoTrue does (implicit TLS on port 465,
+ * otherwise STARTTLS), authenticates with the configured user and password,
+ * and with a recipient sends a real message. Runs from this machine, so a
+ * cluster whose egress blocks the port can still fail where this passes.
+ */
+
+export type SmtpTransport = "tls" | "starttls";
+
+export type SmtpSettings = DeploymentConfig["smtp"];
+
+export interface SmtpTestStep {
+  name: "connect" | "starttls" | "auth" | "send";
+  ok: boolean;
+  detail?: string;
+}
+
+export interface SmtpTestResult {
+  host: string;
+  port: number;
+  transport: SmtpTransport;
+  steps: SmtpTestStep[];
+  ok: boolean;
+}
+
+interface SmtpReply {
+  code: number;
+  lines: string[];
+}
+
+const CLIENT_NAME = "rulebricks-cli";
+const DEFAULT_TIMEOUT_MS = 15000;
+const LOCAL_HOSTS = new Set(["localhost", "127.0.0.1", "::1"]);
+
+/** Port 465 is implicit TLS (SMTPS); every other port upgrades in-band. */
+export function smtpTransport(port: number): SmtpTransport {
+  return port === 465 ? "tls" : "starttls";
+}
+
+/** The AUTH mechanisms an EHLO reply advertises, upper-cased. */
+export function authMechanisms(ehlo: string[]): string[] {
+  for (const line of ehlo) {
+    const match = /^AUTH[ =](.*)$/i.exec(line.trim());
+    if (match) return match[1].trim().toUpperCase().split(/\s+/);
+  }
+  return [];
+}
+
+export function advertises(ehlo: string[], extension: string): boolean {
+  return ehlo.some(
+    (line) => line.trim().toUpperCase().split(/\s+/)[0] === extension,
+  );
+}
+
+/** Escapes lines starting with "." so the body can't end DATA early. */
+function dotStuff(body: string): string {
+  return body
+    .split(/\r?\n/)
+    .map((line) => (line.startsWith(".") ? `.${line}` : line))
+    .join("\r\n");
+}
+
+/** The test message, CRLF-terminated and dot-stuffed for DATA. */
+export function buildTestMessage(
+  smtp: SmtpSettings,
+  to: string,
+  date: Date = new Date(),
+): string {
+  const domain = smtp.from.split("@")[1] || "localhost";
+  const headers = [
+    `From: ${smtp.fromName} <${smtp.from}>`,
+    `To: <${to}>`,
+    "Subject: Rulebricks SMTP test",
+    `Date: ${date.toUTCString()}`,
+    `Message-ID: <${date.getTime()}.${CLIENT_NAME}@${domain}>`,
+    "MIME-Version: 1.0",
+    "Content-Type: text/plain; charset=utf-8",
+  ];
+  const body = [
+    `This message was sent by \`rulebricks email test\` through ${smtp.host}:${smtp.port}.`,
+    "If you received it, the deployment's SMTP settings can deliver mail.",
+  ];
+  return `${headers.join("\r\n")}\r\n\r\n${dotStuff(body.join("\n"))}\r\n`;
+}
+
+/** Reads SMTP replies (including multi-line ones) off a socket. */
+class SmtpConnection {
+  private buffer = "";
+  private lines: string[] = [];
+  private replies: SmtpReply[] = [];
+  private waiting: {
+    resolve: (reply: SmtpReply) => void;
+    reject: (error: Error) => void;
+  } | null = null;
+  private error: Error | null = null;
+
+  constructor(
+    public socket: net.Socket,
+    private timeoutMs: number,
+  ) {
+    this.attach(socket);
+  }
+
+  attach(socket: net.Socket): void {
+    this.socket = socket;
+    socket.setEncoding("utf-8");
+    socket.setTimeout(this.timeoutMs, () =>
+      socket.destroy(new Error(`No reply within ${this.timeoutMs / 1000}s`)),
+    );
+    socket.on("data", (chunk: string) => {
+      this.buffer += chunk;
+      this.drain();
+    });
+    socket.on("error", (error) => this.fail(error));
+    socket.on("close", () => this.fail(new Error("Connection closed")));
+  }
+
+  /** Stops reading from the current socket, e.g. before a TLS upgrade. */
+  detach(): net.Socket {
+    this.socket.removeAllListeners("data");
+    this.socket.removeAllListeners("error");
+    this.socket.removeAllListeners("close");
+    this.socket.setTimeout(0);
+    return this.socket;
+  }
+
+  private drain(): void {
+    let end: number;
+    while ((end = this.buffer.indexOf("\n")) !== -1) {
+      const line = this.buffer.slice(0, end).replace(/\r$/, "");
+      this.buffer = this.buffer.slice(end + 1);
+      this.lines.push(line.slice(4));
+      if (line[3] !== "-") {
+        const reply = { code: Number(line.slice(0, 3)), lines: this.lines };
+        this.lines = [];
+        if (this.waiting) {
+          this.waiting.resolve(reply);
+          this.waiting = null;
+        } else {
+          this.replies.push(reply);
+        }
+      }
+    }
+  }
+
+  private fail(error: Error): void {
+    if (!this.error) this.error = error;
+    this.waiting?.reject(error);
+    this.waiting = null;
+  }
+
+  read(): Promise<SmtpReply> {
+    const queued = this.replies.shift();
+    if (queued) return Promise.resolve(queued);
+    if (this.error) return Promise.reject(this.error);
+    return new Promise((resolve, reject) => {
+      this.waiting = { resolve, reject };
+    });
+  }
+
+  /** Sends a command and fails unless the reply code is one of `expect`. */
+  async command(
+    line: string,
+    expect: number[],
+    shown: string = line,
+  ): Promise<SmtpReply> {
+    this.socket.write(`${line}\r\n`);
+    return this.expect(expect, shown);
+  }
+
+  async expect(expect: number[], shown: string): Promise<SmtpReply> {
+    const reply = await this.read();
+    if (!expect.includes(reply.code)) {
+      throw new Error(
+        `${shown}: ${reply.code} ${reply.lines.join(" ").trim()}`,
+      );
+    }
+    return reply;
+  }
+
+  close(): void {
+    this.detach().destroy();
+  }
+}
+
+function connect(
+  host: string,
+  port: number,
+  transport: SmtpTransport,
+): Promise<net.Socket> {
+  return new Promise((resolve, reject) => {
+    const socket =
+      transport === "tls"
+        ? tls.connect({ host, port, servername: host }, () => resolve(socket))
+        : net.connect({ host, port }, () => resolve(socket));
+    socket.once("error", reject);
+  });
+}
+
+function upgrade(socket: net.Socket, host: string): Promise<tls.TLSSocket> {
+  return new Promise((resolve, reject) => {
+    const secure = tls.connect({ socket, servername: host }, () =>
+      resolve(secure),
+    );
+    secure.once("error", reject);
+  });
+}
+
+async function authenticate(
+  conn: SmtpConnection,
+  ehlo: string[],
+  smtp: SmtpSettings,
+): Promise<string> {
+  const mechanisms = authMechanisms(ehlo);
+  if (mechanisms.includes("PLAIN")) {
+    const token = Buffer.from(`\0${smtp.user}\0${smtp.pass}`).toString(
+      "base64",
+    );
+    await conn.command(`AUTH PLAIN ${token}`, [235], "AUTH PLAIN");
+    return "PLAIN";
+  }
+  if (mechanisms.includes("LOGIN")) {
+    await conn.command("AUTH LOGIN", [334]);
+    await conn.command(
+      Buffer.from(smtp.user).toString("base64"),
+      [334],
+      "AUTH LOGIN username",
+    );
+    await conn.command(
+      Buffer.from(smtp.pass).toString("base64"),
+      [235],
+      "AUTH LOGIN password",
+    );
+    return "LOGIN";
+  }
+  throw new Error(
+    mechanisms.length > 0
+      ? `Server offers AUTH ${mechanisms.join(" ")}, but not PLAIN or LOGIN`
+      : "Server does not offer AUTH",
+  );
+}
+
+/**
+ * Runs the test, recording each step as it passes; the first failure ends
+ * the run and is the last step. With `to`, a test message is sent.
+ */
+export async function testSmtp(
+  smtp: SmtpSettings,
+  options: { to?: string; timeoutMs?: number } = {},
+): Promise<SmtpTestResult> {
+  const transport = smtpTransport(smtp.port);
+  const result: SmtpTestResult = {
+    host: smtp.host,
+    port: smtp.port,
+    transport,
+    steps: [],
+    ok: false,
+  };
+  const timeoutMs = options.timeoutMs ?? DEFAULT_TIMEOUT_MS;
+  let step: SmtpTestStep["name"] = "connect";
+  let conn: SmtpConnection | undefined;
+
+  try {
+    conn = new SmtpConnection(
+      await connect(smtp.host, smtp.port, transport),
+      timeoutMs,
+    );
+    await conn.expect([220], "greeting");
+    let ehlo = (await conn.command(`EHLO ${CLIENT_NAME}`, [250])).lines;
+    result.steps.push({
+      name: "connect",
+      ok: true,
+      detail: transport === "tls" ? "implicit TLS" : ehlo[0],
+    });
+
+    if (transport === "starttls") {
+      step = "starttls";
+      if (advertises(ehlo, "STARTTLS")) {
+        await conn.command("STARTTLS", [220]);
+        conn.attach(await upgrade(conn.detach(), smtp.host));
+        ehlo = (await conn.command(`EHLO ${CLIENT_NAME}`, [250])).lines;
+        result.steps.push({ name: "starttls", ok: true });
+      } else if (LOCAL_HOSTS.has(smtp.host)) {
+        result.steps.push({
+          name: "starttls",
+          ok: true,
+          detail: "not offered; continuing unencrypted to a local server",
+        });
+      } else {
+        throw new Error(
+          "Server does not offer STARTTLS, so the password would be sent unencrypted",
+        );
+      }
+    }
+
+    step = "auth";
+    const mechanism = await authenticate(conn, ehlo, smtp);
+    result.steps.push({
+      name: "auth",
+      ok: true,
+      detail: `${smtp.user} (${mechanism})`,
+    });
+
+    if (options.to) {
+      step = "send";
+      await conn.command(`MAIL FROM:<${smtp.from}>`, [250]);
+      await conn.command(`RCPT TO:<${options.to}>`, [250, 251]);
+      await conn.command("DATA", [354]);
+      await conn.command(
+        `${buildTestMessage(smtp, options.to)}.`,
+        [250],
+        "message",
+      );
+      result.steps.push({ name: "send", ok: true, detail: options.to });
+    }
+
+    result.ok = true;
+    await conn.command("QUIT", [221]).catch(() => undefined);
+  } catch (error) {
+    result.steps.push({
+      name: step,
+      ok: false,
+      detail: error instanceof Error ? error.message : String(error),
+    });
+  } finally {
+    conn?.close();
+  }
+  return result;
+}
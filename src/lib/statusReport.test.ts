@@ -0,0 +1,117 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  buildStatusReport,
+  groupWorkloads,
+  podWorkload,
+} from "./statusReport.js";
+import type { DeploymentHealth } from "./deploymentHealth.js";
+import type { DeploymentState } from "../types/index.js";
+
+function health(overrides: Partial<DeploymentHealth>): DeploymentHealth {
+  return {
+    name: "acme",
+    kind: "online",
+    config: null,
+    state: null,
+    namespace: "rulebricks-acme",
+    releaseName: "rulebricks-acme",
+    helmVersion: "1.4.0",
+    pods: [],
+    url: "https://rules.acme.com",
+    httpReachable: true,
+    clusterError: null,
+    configError: null,
+    ...overrides,
+  };
+}
+
+test("pods map to the workload that owns them", () => {
+  assert.equal(
+    podWorkload("rulebricks-acme-app-7d9f8b6c5d-x2k4p"),
+    "rulebricks-acme-app",
+  );
+  assert.equal(podWorkload("rulebricks-acme-kafka-0"), "rulebricks-acme-kafka");
+  assert.equal(
+    podWorkload("rulebricks-acme-vector-q8w2z"),
+    "rulebricks-acme-vector",
+  );
+  // A five-letter word at the end is a name, not a generated suffix.
+  assert.equal(podWorkload("rulebricks-acme-redis"), "rulebricks-acme-redis");
+});
+
+test("workloads count ready replicas", () => {
+  const workloads = groupWorkloads([
+    {
+      name: "app-7d9f8b6c5d-ccccc",
+      status: "Running",
+      ready: true,
+      restarts: 0,
+    },
+    {
+      name: "app-7d9f8b6c5d-bbbbb",
+      status: "CrashLoopBackOff",
+      ready: false,
+      restarts: 4,
+    },
+    { name: "kafka-0", status: "Running", ready: true, restarts: 0 },
+  ]);
+  assert.deepEqual(workloads, [
+    { name: "app", ready: 1, total: 2, healthy: false },
+    { name: "kafka", ready: 1, total: 1, healthy: true },
+  ]);
+});
+
+test("the report carries the overall status and state record", () => {
+  const state: DeploymentState = {
+    name: "acme",
+    version: "1.0.0",
+    createdAt: "2026-01-01T00:00:00.000Z",
+    updatedAt: "2026-01-02T00:00:00.000Z",
+    status: "running",
+    application: {
+      version: "1.5.0",
+      namespace: "rulebricks-acme",
+      url: "https://rules.acme.com",
+    },
+  };
+  const report = buildStatusReport(
+    health({
+      state,
+      kind: "installed-degraded",
+      pods: [{ name: "kafka-0", status: "Pending", ready: false, restarts: 0 }],
+    }),
+    { services: [], ingresses: [], certificates: [] },
+    new Date("2026-01-03T00:00:00.000Z"),
+  );
+  assert.equal(report.status, "degraded");
+  assert.equal(report.healthy, false);
+  assert.equal(report.checkedAt, "2026-01-03T00:00:00.000Z");
+  assert.equal(report.state?.application?.version, "1.5.0");
+  assert.deepEqual(report.pods, [
+    {
+      name: "kafka-0",
+      status: "Pending",
+      ready: false,
+      restarts: 0,
+      healthy: false,
+    },
+  ]);
+  assert.equal("resources" in JSON.parse(JSON.stringify(report)), false);
+
+  // Before the first install the state decides between pending and failed.
+  const failed = buildStatusReport(
+    health({ kind: "not-installed", state: { ...state, status: "failed" } }),
+    { services: [], ingresses: [], certificates: [] },
+    new Date(),
+  );
+  assert.equal(failed.status, "failed");
+  assert.equal(
+    buildStatusReport(
+      health({ kind: "not-installed" }),
+      { services: [], ingresses: [], certificates: [] },
+      new Date(),
+    ).status,
+    "not-deployed",
+  );
+});
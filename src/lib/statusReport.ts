@@ -0,0 +1,171 @@
+import {
+  getCertificateStatus,
+  getClusterResourceUsage,
+  getIngressStatus,
+  getServiceStatus,
+  type CertificateStatus,
+  type IngressStatus,
+  type PodStatus,
+  type ServiceStatus,
+} from "./kubernetes.js";
+import {
+  arePodsHealthy,
+  loadDeploymentHealth,
+  overallStatus,
+  type DeploymentHealth,
+  type OverallStatus,
+} from "./deploymentHealth.js";
+import type { NodeResourceUsage, UnschedulablePod } from "./resourceUsage.js";
+import type { DeploymentState } from "../types/index.js";
+
+/**
+ * The document `status --output json` prints: everything the status screen
+ * shows, from the same checks, for dashboards and scripts.
+ */
+
+export interface WorkloadReplicas {
+  /** Deployment / StatefulSet / DaemonSet name, from its pods' names. */
+  name: string;
+  ready: number;
+  total: number;
+  healthy: boolean;
+}
+
+export interface StatusReport {
+  deployment: string;
+  status: OverallStatus;
+  healthy: boolean;
+  checkedAt: string;
+  url: string | null;
+  urlReachable: boolean;
+  /** Chart version of the installed Helm release. */
+  chartVersion: string | null;
+  namespace: string;
+  clusterError: string | null;
+  configError: string | null;
+  /** The local state file's record of the last deploy. */
+  state: Pick<
+    DeploymentState,
+    "status" | "updatedAt" | "infrastructure" | "application" | "dnsRecords"
+  > | null;
+  workloads: WorkloadReplicas[];
+  pods: Array<PodStatus & { healthy: boolean }>;
+  services: ServiceStatus[];
+  ingresses: IngressStatus[];
+  certificates: CertificateStatus[];
+  resources?: {
+    nodes: NodeResourceUsage[];
+    unschedulable: UnschedulablePod[];
+    error?: string;
+  };
+}
+
+// Kubernetes' generated name suffixes use this vowel-free alphabet, which
+// keeps a word like "redis" from being mistaken for one.
+const NAME_SUFFIX = "[bcdfghjklmnpqrstvwxz2-9]";
+const WORKLOAD_SUFFIXES = [
+  new RegExp(`-${NAME_SUFFIX}{6,10}-${NAME_SUFFIX}{5}$`),
+  /-\d+$/,
+  new RegExp(`-${NAME_SUFFIX}{5}$`),
+];
+
+/**
+ * The workload a pod belongs to: "<deployment>-<replicaset hash>-<id>",
+ * "<statefulset>-<ordinal>" and "<daemonset>-<id>" all map to their owner.
+ */
+export function podWorkload(podName: string): string {
+  for (const suffix of WORKLOAD_SUFFIXES) {
+    if (suffix.test(podName)) return podName.replace(suffix, "");
+  }
+  return podName;
+}
+
+export function groupWorkloads(pods: PodStatus[]): WorkloadReplicas[] {
+  const byName = new Map<string, PodStatus[]>();
+  for (const pod of pods) {
+    const name = podWorkload(pod.name);
+    byName.set(name, [...(byName.get(name) ?? []), pod]);
+  }
+  return [...byName.entries()]
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([name, members]) => ({
+      name,
+      ready: members.filter((pod) => pod.ready).length,
+      total: members.length,
+      healthy: arePodsHealthy(members),
+    }));
+}
+
+export function buildStatusReport(
+  health: DeploymentHealth,
+  cluster: {
+    services: ServiceStatus[];
+    ingresses: IngressStatus[];
+    certificates: CertificateStatus[];
+    resources?: StatusReport["resources"];
+  },
+  checkedAt: Date,
+): StatusReport {
+  const status = overallStatus(health);
+  const state = health.state;
+  return {
+    deployment: health.name,
+    status,
+    healthy: status === "healthy",
+    checkedAt: checkedAt.toISOString(),
+    url: health.url,
+    urlReachable: health.httpReachable,
+    chartVersion: health.helmVersion,
+    namespace: health.namespace,
+    clusterError: health.clusterError,
+    configError: health.configError,
+    state: state
+      ? {
+          status: state.status,
+          updatedAt: state.updatedAt,
+          infrastructure: state.infrastructure,
+          application: state.application,
+          dnsRecords: state.dnsRecords,
+        }
+      : null,
+    workloads: groupWorkloads(health.pods),
+    pods: health.pods.map((pod) => ({
+      ...pod,
+      healthy: arePodsHealthy([pod]),
+    })),
+    ...cluster,
+  };
+}
+
+/** Runs the status checks and assembles the report. */
+export async function collectStatusReport(
+  name: string,
+  options: { resources?: boolean } = {},
+): Promise<StatusReport> {
+  const health = await loadDeploymentHealth(name, { refreshKubeconfig: true });
+  const reachable = health.config !== null && !health.clusterError;
+  const [services, ingresses, certificates] = reachable
+    ? await Promise.all([
+        getServiceStatus(health.namespace),
+        getIngressStatus(health.namespace),
+        getCertificateStatus(health.namespace),
+      ])
+    : [[], [], []];
+
+  let resources: StatusReport["resources"];
+  if (options.resources && reachable) {
+    resources = await getClusterResourceUsage(health.namespace).catch(
+      (err) => ({
+        nodes: [],
+        unschedulable: [],
+        error: err instanceof Error ? err.message : String(err),
+      }),
+    );
+  }
+
+  return buildStatusReport(
+    health,
+    { services, ingresses, certificates, resources },
+    new Date(),
+  );
+}
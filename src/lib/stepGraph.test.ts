@@ -0,0 +1,117 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { assertValidStepGraph, runStepGraph } from "./stepGraph.js";
+
+function deferred() {
+  let resolve!: () => void;
+  const promise = new Promise<void>((r) => (resolve = r));
+  return { promise, resolve };
+}
+
+test("independent steps run concurrently; dependents wait", async () => {
+  const log: string[] = [];
+  const images = deferred();
+  const run = runStepGraph([
+    {
+      id: "federation",
+      run: async () => {
+        log.push("federation:start");
+        await Promise.resolve();
+        log.push("federation:end");
+      },
+    },
+    {
+      id: "images",
+      run: async () => {
+        log.push("images:start");
+        await images.promise;
+        log.push("images:end");
+      },
+    },
+    {
+      id: "autoscaler",
+      dependsOn: ["federation"],
+      run: async () => {
+        log.push("autoscaler");
+      },
+    },
+  ]);
+  await new Promise((r) => setImmediate(r));
+  // images is still in flight while federation and its dependent finished.
+  assert.deepEqual(log, [
+    "federation:start",
+    "images:start",
+    "federation:end",
+    "autoscaler",
+  ]);
+  images.resolve();
+  await run;
+  assert.equal(log.at(-1), "images:end");
+});
+
+test("concurrency bounds how many steps run at once", async () => {
+  let active = 0;
+  let peak = 0;
+  const step = (id: string) => ({
+    id,
+    run: async () => {
+      active++;
+      peak = Math.max(peak, active);
+      await new Promise((r) => setImmediate(r));
+      active--;
+    },
+  });
+  await runStepGraph([step("a"), step("b"), step("c"), step("d")], {
+    concurrency: 2,
+  });
+  assert.equal(peak, 2);
+});
+
+test("a failure stops new steps and is rethrown after running ones settle", async () => {
+  const log: string[] = [];
+  const slow = deferred();
+  const run = runStepGraph(
+    [
+      {
+        id: "federation",
+        run: async () => {
+          throw new Error("trust policy denied");
+        },
+      },
+      {
+        id: "images",
+        run: async () => {
+          await slow.promise;
+          log.push("images");
+        },
+      },
+      {
+        id: "autoscaler",
+        dependsOn: ["federation"],
+        run: async () => {
+          log.push("autoscaler");
+        },
+      },
+    ],
+    { concurrency: 2 },
+  );
+  slow.resolve();
+  await assert.rejects(run, /trust policy denied/);
+  assert.deepEqual(log, ["images"]);
+});
+
+test("assertValidStepGraph rejects unknown dependencies and cycles", () => {
+  const noop = async () => {};
+  assert.throws(
+    () => assertValidStepGraph([{ id: "a", dependsOn: ["b"], run: noop }]),
+    /unknown step "b"/,
+  );
+  assert.throws(
+    () =>
+      assertValidStepGraph([
+        { id: "a", dependsOn: ["b"], run: noop },
+        { id: "b", dependsOn: ["a"], run: noop },
+      ]),
+    /cycle: a → b → a/,
+  );
+});
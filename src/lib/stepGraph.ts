@@ -0,0 +1,101 @@
+// Runs deploy steps as a dependency graph: each step names the steps it needs,
+// and every step whose dependencies have finished starts right away, up to a
+// concurrency bound. Used for the pre-install work that talks to different
+// systems (cloud identity APIs, the chart registry) and otherwise waits in
+// line; the Helm install itself stays a single serialized step after it.
+
+export interface GraphStep {
+  id: string;
+  dependsOn?: string[];
+  run: () => Promise<void>;
+}
+
+export const DEFAULT_STEP_CONCURRENCY = 3;
+
+/** Throws on duplicate ids, unknown dependencies, or cycles. */
+export function assertValidStepGraph(steps: GraphStep[]): void {
+  const ids = new Set<string>();
+  for (const step of steps) {
+    if (ids.has(step.id)) {
+      throw new Error(`Duplicate deploy step "${step.id}"`);
+    }
+    ids.add(step.id);
+  }
+  for (const step of steps) {
+    for (const dep of step.dependsOn ?? []) {
+      if (!ids.has(dep)) {
+        throw new Error(
+          `Deploy step "${step.id}" depends on unknown step "${dep}"`,
+        );
+      }
+    }
+  }
+
+  const visiting = new Set<string>();
+  const done = new Set<string>();
+  const byId = new Map(steps.map((s) => [s.id, s]));
+  const visit = (id: string, path: string[]) => {
+    if (done.has(id)) return;
+    if (visiting.has(id)) {
+      throw new Error(
+        `Deploy steps form a cycle: ${[...path, id].join(" → ")}`,
+      );
+    }
+    visiting.add(id);
+    for (const dep of byId.get(id)!.dependsOn ?? []) visit(dep, [...path, id]);
+    visiting.delete(id);
+    done.add(id);
+  };
+  for (const step of steps) visit(step.id, []);
+}
+
+/**
+ * Runs every step once its dependencies have succeeded, at most `concurrency`
+ * at a time. After the first failure no new steps start; the ones already
+ * running are allowed to finish and the first error is rethrown.
+ */
+export async function runStepGraph(
+  steps: GraphStep[],
+  options: { concurrency?: number } = {},
+): Promise<void> {
+  assertValidStepGraph(steps);
+  const concurrency = Math.max(
+    1,
+    options.concurrency ?? DEFAULT_STEP_CONCURRENCY,
+  );
+  const pending = [...steps];
+  const finished = new Set<string>();
+  const running = new Map<string, Promise<void>>();
+  const errors: unknown[] = [];
+
+  while (pending.length > 0 || running.size > 0) {
+    if (errors.length === 0) {
+      for (let i = 0; i < pending.length && running.size < concurrency; ) {
+        const step = pending[i];
+        if ((step.dependsOn ?? []).every((dep) => finished.has(dep))) {
+          pending.splice(i, 1);
+          running.set(
+            step.id,
+            step.run().then(
+              () => {
+                finished.add(step.id);
+              },
+              (error) => {
+                errors.push(error);
+              },
+            ),
+          );
+        } else {
+          i++;
+        }
+      }
+    }
+    if (running.size === 0) break;
+    const settled = await Promise.race(
+      [...running].map(([id, promise]) => promise.then(() => id)),
+    );
+    running.delete(settled);
+  }
+
+  if (errors.length > 0) throw errors[0];
+}
@@ -0,0 +1,64 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { parseRetryCount, retryDelayMs, retryStep } from "./stepRetry.js";
+
+const noSleep = async () => {};
+
+test("retries a failing step until it succeeds", async () => {
+  let calls = 0;
+  const retries: number[] = [];
+  const result = await retryStep(
+    async () => {
+      calls++;
+      if (calls < 3) throw new Error("webhook not ready");
+      return "installed";
+    },
+    {
+      retries: 3,
+      sleep: noSleep,
+      onRetry: (attempt) => retries.push(attempt),
+    },
+  );
+  assert.equal(result, "installed");
+  assert.equal(calls, 3);
+  assert.deepEqual(retries, [1, 2]);
+});
+
+test("gives up after the retry budget with the last error", async () => {
+  let calls = 0;
+  await assert.rejects(
+    retryStep(
+      async () => {
+        calls++;
+        throw new Error(`attempt ${calls}`);
+      },
+      { retries: 2, sleep: noSleep },
+    ),
+    /attempt 3/,
+  );
+  assert.equal(calls, 3);
+});
+
+test("never retries without a budget or for non-retryable errors", async () => {
+  let calls = 0;
+  const fail = async () => {
+    calls++;
+    throw new Error("denied");
+  };
+  await assert.rejects(retryStep(fail, { retries: 0, sleep: noSleep }));
+  await assert.rejects(
+    retryStep(fail, { retries: 5, sleep: noSleep, shouldRetry: () => false }),
+  );
+  assert.equal(calls, 2);
+});
+
+test("backoff doubles and caps, and counts are bounded", () => {
+  assert.equal(retryDelayMs(1), 10_000);
+  assert.equal(retryDelayMs(2), 20_000);
+  assert.equal(retryDelayMs(10), 120_000);
+  assert.equal(parseRetryCount("3"), 3);
+  assert.equal(parseRetryCount("0"), 0);
+  assert.throws(() => parseRetryCount("11"), /0 to 10/);
+  assert.throws(() => parseRetryCount("-1"));
+  assert.throws(() => parseRetryCount("two"));
+});
@@ -0,0 +1,67 @@
+// Whole-step retries for `deploy --retry-failed-step N`. Distinct from the
+// retries inside individual operations (ExternalSecret sync polling, helm's
+// own --wait): when a retry-safe step fails outright, it is run again from the
+// top after a backoff, up to N more times. Only idempotent steps go through
+// this - workload identity trust, `helm upgrade --install`, the TLS upgrade -
+// never preflight or anything destructive.
+
+export const MAX_STEP_RETRIES = 10;
+
+/** Backoff before retry `attempt` (1-based): 10s, 20s, 40s, ... capped at 2m. */
+export function retryDelayMs(attempt: number, baseMs = 10_000): number {
+  return Math.min(baseMs * 2 ** (attempt - 1), 120_000);
+}
+
+/** Parses the --retry-failed-step count (0-10). */
+export function parseRetryCount(value: string): number {
+  const trimmed = value.trim();
+  const count = Number(trimmed);
+  if (!/^\d+$/.test(trimmed) || count > MAX_STEP_RETRIES) {
+    throw new Error(
+      `Invalid retry count "${value}". Use a whole number from 0 to ${MAX_STEP_RETRIES}.`,
+    );
+  }
+  return count;
+}
+
+export interface StepRetryOptions {
+  retries: number;
+  /** Errors that retrying cannot fix (denied commands, bad config) return false. */
+  shouldRetry?: (error: unknown) => boolean;
+  /** Called, and awaited, before each retry's backoff. */
+  onRetry?: (
+    attempt: number,
+    error: unknown,
+    delayMs: number,
+  ) => void | Promise<void>;
+  delayMs?: (attempt: number) => number;
+  sleep?: (ms: number) => Promise<void>;
+}
+
+/**
+ * Runs `fn`, re-running it after a backoff when it throws a retryable error,
+ * at most `retries` more times. The last error is rethrown.
+ */
+export async function retryStep<T>(
+  fn: () => Promise<T>,
+  options: StepRetryOptions,
+): Promise<T> {
+  const {
+    retries,
+    shouldRetry = () => true,
+    onRetry,
+    delayMs = retryDelayMs,
+    sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms)),
+  } = options;
+
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await fn();
+    } catch (error) {
+      if (attempt > retries || !shouldRetry(error)) throw error;
+      const wait = delayMs(attempt);
+      await onRetry?.(attempt, error, wait);
+      await sleep(wait);
+    }
+  }
+}
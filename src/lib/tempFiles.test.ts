@@ -0,0 +1,27 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { promises as fs } from "node:fs";
+import path from "node:path";
+import {
+  createTempDir,
+  pendingTempPaths,
+  removeTempPath,
+  writeTempFile,
+} from "./tempFiles.js";
+
+test("temp directories are private and tracked until removed", async () => {
+  const dir = await createTempDir("rb-test-");
+  assert.ok(pendingTempPaths().includes(dir));
+  assert.equal((await fs.stat(dir)).mode & 0o777, 0o700);
+
+  await removeTempPath(dir);
+  assert.ok(!pendingTempPaths().includes(dir));
+  await assert.rejects(fs.stat(dir));
+});
+
+test("temp files are written 0600", async () => {
+  const file = await writeTempFile("rb-test-", "values.yaml", "secret: x\n");
+  assert.equal((await fs.stat(file)).mode & 0o777, 0o600);
+  assert.equal(await fs.readFile(file, "utf-8"), "secret: x\n");
+  await removeTempPath(path.dirname(file));
+});
@@ -0,0 +1,89 @@
+import { promises as fs, rmSync, constants } from "fs";
+import os from "os";
+import path from "path";
+
+/**
+ * Central registry for temporary files and directories.
+ *
+ * `finally` blocks don't run when the process is torn down by a signal or a
+ * process.exit() elsewhere in the CLI, so anything created under os.tmpdir()
+ * is registered here and removed synchronously on exit/SIGINT/SIGTERM/SIGHUP
+ * as a backstop. Directories are created 0700 and files 0600 since callers
+ * may put generated credentials in them.
+ */
+
+const pending = new Set<string>();
+let hooksInstalled = false;
+
+function removeAllSync(): void {
+  for (const target of pending) {
+    try {
+      rmSync(target, { recursive: true, force: true });
+    } catch {
+      // Best-effort during teardown.
+    }
+  }
+  pending.clear();
+}
+
+function installHooks(): void {
+  if (hooksInstalled) return;
+  hooksInstalled = true;
+  process.once("exit", removeAllSync);
+  for (const signal of ["SIGINT", "SIGTERM", "SIGHUP"] as const) {
+    process.once(signal, () => {
+      removeAllSync();
+      process.exit(128 + (os.constants.signals[signal] ?? 0));
+    });
+  }
+}
+
+/** Registers a path for removal if the process exits before it is released. */
+export function registerTempPath(target: string): void {
+  pending.add(target);
+  installHooks();
+}
+
+/** Removes a registered path now and drops it from the registry. */
+export async function removeTempPath(target: string): Promise<void> {
+  pending.delete(target);
+  await fs.rm(target, { recursive: true, force: true }).catch(() => {});
+}
+
+/** Paths still awaiting cleanup (for tests and diagnostics). */
+export function pendingTempPaths(): string[] {
+  return [...pending];
+}
+
+/** Creates a private (0700) temp directory that is cleaned up on exit. */
+export async function createTempDir(prefix: string): Promise<string> {
+  const dir = await fs.mkdtemp(path.join(os.tmpdir(), prefix));
+  registerTempPath(dir);
+  await fs.chmod(dir, 0o700);
+  return dir;
+}
+
+/**
+ * Writes `content` to a new 0600 file inside a private temp directory and
+ * returns the file path. Release it with removeTempPath(path.dirname(file)).
+ */
+export async function writeTempFile(
+  prefix: string,
+  fileName: string,
+  content: string,
+): Promise<string> {
+  const dir = await createTempDir(prefix);
+  const file = path.join(dir, fileName);
+  // O_EXCL: never follow or reuse a pre-existing path.
+  const handle = await fs.open(
+    file,
+    constants.O_WRONLY | constants.O_CREAT | constants.O_EXCL,
+    0o600,
+  );
+  try {
+    await handle.writeFile(content, "utf-8");
+  } finally {
+    await handle.close();
+  }
+  return file;
+}
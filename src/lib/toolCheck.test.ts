@@ -0,0 +1,43 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  describeToolProblems,
+  parseToolVersion,
+  requiredTools,
+} from "./toolCheck.js";
+import { configFixture } from "./configFixtures.js";
+
+test("reads versions out of each tool's version output", () => {
+  assert.equal(parseToolVersion("v3.14.0+g3fc9f4b"), "3.14.0");
+  assert.equal(
+    parseToolVersion("Client Version: v1.29.1\nKustomize Version: v5.0.4"),
+    "1.29.1",
+  );
+  assert.equal(
+    parseToolVersion("aws-cli/2.15.30 Python/3.11.8 Darwin/23.3.0"),
+    "2.15.30",
+  );
+  assert.equal(parseToolVersion("Google Cloud SDK 460.0.0"), "460.0.0");
+  assert.equal(parseToolVersion("no version here"), undefined);
+});
+
+test("requires the provider CLI only when the config names a cloud", () => {
+  assert.deepEqual(requiredTools(null), ["kubectl", "helm"]);
+
+  const config = configFixture("aws-self-hosted-minimal");
+  assert.deepEqual(requiredTools(config), ["kubectl", "helm", "aws"]);
+  config.infrastructure.provider = undefined;
+  assert.deepEqual(requiredTools(config), ["kubectl", "helm"]);
+});
+
+test("reports every missing or outdated tool with install instructions", () => {
+  const problems = describeToolProblems([
+    { tool: "kubectl", installed: false },
+    { tool: "helm", installed: true, version: "3.7.2" },
+    { tool: "aws", installed: true, version: "2.15.30" },
+    { tool: "gcloud", installed: true },
+  ]);
+  assert.equal(problems.length, 2);
+  assert.match(problems[0], /kubectl.*not installed.*kubernetes\.io/);
+  assert.match(problems[1], /Helm 3\.7\.2 is older than the required 3\.13\.0/);
+});
@@ -0,0 +1,153 @@
+import { execa } from "execa";
+import { compareVersions } from "./versions.js";
+import {
+  CloudProvider,
+  DeploymentConfig,
+  usesCloudflareOriginTls,
+} from "../types/index.js";
+
+/**
+ * Up-front check for the command-line tools deploy and destroy shell out to.
+ * Every missing or outdated tool is reported at once, each with where to get
+ * it, instead of failing on the first `spawn kubectl ENOENT` mid-run.
+ */
+
+export type RequiredTool =
+  | "kubectl"
+  | "helm"
+  | "aws"
+  | "gcloud"
+  | "az"
+  | "openssl";
+
+interface ToolSpec {
+  label: string;
+  versionArgs: string[];
+  /** Oldest supported version; unset when any version works. */
+  minVersion?: string;
+  install: string;
+}
+
+export const TOOL_SPECS: Record<RequiredTool, ToolSpec> = {
+  kubectl: {
+    label: "kubectl",
+    versionArgs: ["version", "--client"],
+    // client.authentication.k8s.io/v1, which current EKS/GKE/AKS
+    // kubeconfigs use for their exec credential plugins.
+    minVersion: "1.24.0",
+    install: "https://kubernetes.io/docs/tasks/tools/",
+  },
+  helm: {
+    label: "Helm",
+    versionArgs: ["version", "--short"],
+    minVersion: "3.13.0",
+    install: "https://helm.sh/docs/intro/install/",
+  },
+  aws: {
+    label: "AWS CLI",
+    versionArgs: ["--version"],
+    minVersion: "2.0.0",
+    install:
+      "https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+  },
+  gcloud: {
+    label: "Google Cloud CLI",
+    versionArgs: ["version"],
+    install: "https://cloud.google.com/sdk/docs/install",
+  },
+  az: {
+    label: "Azure CLI",
+    versionArgs: ["version"],
+    install: "https://learn.microsoft.com/cli/azure/install-azure-cli",
+  },
+  openssl: {
+    label: "OpenSSL",
+    versionArgs: ["version"],
+    install: "https://openssl-library.org/source/",
+  },
+};
+
+const PROVIDER_TOOLS: Record<CloudProvider, RequiredTool> = {
+  aws: "aws",
+  gcp: "gcloud",
+  azure: "az",
+};
+
+export interface ToolStatus {
+  tool: RequiredTool;
+  installed: boolean;
+  version?: string;
+}
+
+/**
+ * kubectl and Helm always; the provider CLI when the config names a cloud
+ * (kubeconfig refresh and workload identity go through it); OpenSSL for the
+ * certificate request of a Cloudflare Origin certificate.
+ */
+export function requiredTools(cfg: DeploymentConfig | null): RequiredTool[] {
+  const provider = cfg?.infrastructure.provider;
+  return [
+    "kubectl",
+    "helm",
+    ...(provider ? [PROVIDER_TOOLS[provider]] : []),
+    ...(cfg && usesCloudflareOriginTls(cfg) ? (["openssl"] as const) : []),
+  ];
+}
+
+/**
+ * First dotted version in a tool's version output, e.g. "v3.14.0+g3fc9f4b"
+ * -> "3.14.0", "aws-cli/2.15.30 Python/3.11.8" -> "2.15.30".
+ */
+export function parseToolVersion(output: string): string | undefined {
+  return output.match(/(\d+\.\d+(?:\.\d+)?)/)?.[1];
+}
+
+export async function checkTool(tool: RequiredTool): Promise<ToolStatus> {
+  try {
+    const { stdout, stderr } = await execa(tool, TOOL_SPECS[tool].versionArgs);
+    // AWS CLI v1 prints its version on stderr.
+    return {
+      tool,
+      installed: true,
+      version: parseToolVersion(stdout || stderr),
+    };
+  } catch {
+    return { tool, installed: false };
+  }
+}
+
+/** One line per missing or too-old tool; empty when all are usable. */
+export function describeToolProblems(statuses: ToolStatus[]): string[] {
+  const problems: string[] = [];
+  for (const status of statuses) {
+    const spec = TOOL_SPECS[status.tool];
+    if (!status.installed) {
+      problems.push(
+        `${spec.label} (${status.tool}) is not installed or not on PATH. Install it: ${spec.install}`,
+      );
+    } else if (
+      spec.minVersion &&
+      status.version &&
+      compareVersions(status.version, spec.minVersion) < 0
+    ) {
+      problems.push(
+        `${spec.label} ${status.version} is older than the required ${spec.minVersion}. Upgrade it: ${spec.install}`,
+      );
+    }
+  }
+  return problems;
+}
+
+/** Throws listing every unusable tool. */
+export async function assertRequiredTools(
+  tools: RequiredTool[],
+): Promise<void> {
+  const problems = describeToolProblems(
+    await Promise.all(tools.map((tool) => checkTool(tool))),
+  );
+  if (problems.length > 0) {
+    throw new Error(
+      `Missing required tools:\n${problems.map((p) => `  - ${p}`).join("\n")}`,
+    );
+  }
+}
@@ -0,0 +1,58 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  buildVectorTestConfig,
+  parseVectorTestLogs,
+  vectorValuesFrom,
+} from "./vectorTest.js";
+import { configFixture } from "./configFixtures.js";
+import { buildHelmValues } from "./helmValues.js";
+
+test("the test config replays the deployed sinks from a one-event source", () => {
+  const config = configFixture("aws-self-hosted-minimal");
+  config.features.logging = { sink: "datadog", bucket: "dd-key" };
+  const vector = vectorValuesFrom(
+    buildHelmValues(config) as Record<string, unknown>,
+  );
+  const { config: testConfig, sinkIds } = buildVectorTestConfig(vector, "42");
+
+  assert.deepEqual(sinkIds, ["decision_logs", "datadog"]);
+  const sources = testConfig.sources as Record<string, any>;
+  assert.equal(sources.kafka.type, "demo_logs");
+  assert.equal(sources.kafka.count, 1);
+  assert.equal(JSON.parse(sources.kafka.lines[0]).test_id, "42");
+  assert.deepEqual(testConfig.transforms, vector.customConfig?.transforms);
+
+  // Object storage writes outside the decision-logs prefix ClickHouse reads.
+  const sinks = testConfig.sinks as Record<string, any>;
+  assert.equal(sinks.decision_logs.key_prefix, "vector-test/42/");
+  assert.equal(sinks.datadog.default_api_key, "dd-key");
+
+  assert.throws(() => vectorValuesFrom(null), /deploy the deployment first/);
+});
+
+test("sink errors in Vector's log are attributed by component id", () => {
+  const logs = [
+    "2026-10-15T12:00:00.000000Z  INFO vector::app: Log level is enabled. level=\"info\"",
+    '2026-10-15T12:00:01.000000Z ERROR vector::topology::builder: msg="Healthcheck failed." error=Invalid API key component_kind="sink" component_type="datadog_logs" component_id=datadog',
+    '2026-10-15T12:00:01.500000Z ERROR sink{component_kind="sink" component_id=datadog component_type=datadog_logs}: vector::sinks::util::retries: Not retriable; dropping the request.',
+    '2026-10-15T12:00:02.000000Z ERROR transform{component_id=normalize_logs}: vector::internal_events: Mapping failed.',
+  ].join("\n");
+
+  assert.deepEqual(
+    parseVectorTestLogs(logs, {
+      decision_logs: { type: "aws_s3" },
+      datadog: { type: "datadog_logs" },
+    }),
+    [
+      { id: "decision_logs", type: "aws_s3", ok: true },
+      {
+        id: "datadog",
+        type: "datadog_logs",
+        ok: false,
+        error:
+          'vector::topology::builder: msg="Healthcheck failed." error=Invalid API key component_kind="sink" component_type="datadog_logs" component_id=datadog',
+      },
+    ],
+  );
+});
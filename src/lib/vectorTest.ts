@@ -0,0 +1,200 @@
+import { getInstalledChartVersion } from "./helm.js";
+import { resolveImageCatalog } from "./imageCatalog.js";
+import { k8sName } from "./backupStorage.js";
+import { runEphemeralJob } from "./kubernetes.js";
+import {
+  DeploymentConfig,
+  getNamespace,
+  getReleaseName,
+} from "../types/index.js";
+
+/**
+ * `rulebricks vector test`: proves the decision-log sinks accept data. Runs a
+ * one-shot Vector job with the deployment's own transforms and sinks (from
+ * its generated values.yaml), the Vector service account (so object storage
+ * uses the same workload identity), and a source that emits one synthetic
+ * event instead of reading Kafka. `--require-healthy` fails the run when a
+ * sink's healthcheck fails (bucket access, API key, endpoint); delivery errors
+ * are read back from Vector's log per sink. Object-storage sinks write under
+ * a separate prefix so the event never shows up as a decision log.
+ */
+
+export const VECTOR_TEST_PREFIX = "vector-test/";
+
+const CONFIG_DIR = "/etc/vector-test";
+
+export interface SinkTestResult {
+  id: string;
+  type: string;
+  ok: boolean;
+  error?: string;
+}
+
+export interface VectorTestResult {
+  testId: string;
+  sinks: SinkTestResult[];
+  /** A failure Vector didn't attribute to a sink (e.g. a config error). */
+  error?: string;
+}
+
+type VectorValues = {
+  image?: { pullSecrets?: Array<{ name: string }> };
+  podLabels?: Record<string, string>;
+  env?: Array<Record<string, unknown>>;
+  initContainers?: Array<Record<string, unknown>>;
+  extraVolumes?: Array<Record<string, unknown>>;
+  extraVolumeMounts?: Array<Record<string, unknown>>;
+  customConfig?: {
+    transforms?: Record<string, unknown>;
+    sinks?: Record<string, Record<string, unknown>>;
+  };
+};
+
+const PREFIX_FIELDS: Record<string, string> = {
+  aws_s3: "key_prefix",
+  gcp_cloud_storage: "key_prefix",
+  azure_blob: "blob_prefix",
+};
+
+/** The deployment's Vector block from its generated values. */
+export function vectorValuesFrom(
+  values: Record<string, unknown> | null,
+): VectorValues {
+  const vector = values?.vector as VectorValues | undefined;
+  if (!vector?.customConfig?.sinks) {
+    throw new Error(
+      "No Vector sinks in the generated values.yaml; deploy the deployment first.",
+    );
+  }
+  return vector;
+}
+
+/**
+ * The one-shot config: the deployed transforms and sinks (minus console),
+ * fed by a demo_logs source that stands in for the Kafka source (same id, so
+ * the transforms' inputs still resolve) and emits one event.
+ */
+export function buildVectorTestConfig(
+  vector: VectorValues,
+  testId: string,
+): { config: Record<string, unknown>; sinkIds: string[] } {
+  const event = {
+    timestamp: new Date().toISOString(),
+    level: "info",
+    operation: "vector-test",
+    rule_name: "rulebricks vector test",
+    test_id: testId,
+  };
+  const sinks: Record<string, Record<string, unknown>> = {};
+  for (const [id, sink] of Object.entries(vector.customConfig?.sinks ?? {})) {
+    if (sink.type === "console") continue;
+    const prefixField = PREFIX_FIELDS[String(sink.type)];
+    sinks[id] = prefixField
+      ? {
+          ...sink,
+          [prefixField]: `${VECTOR_TEST_PREFIX}${testId}/`,
+          batch: { max_events: 1, timeout_secs: 1 },
+        }
+      : sink;
+  }
+  return {
+    config: {
+      sources: {
+        kafka: {
+          type: "demo_logs",
+          format: "shuffle",
+          lines: [JSON.stringify(event)],
+          count: 1,
+          interval: 0,
+        },
+      },
+      transforms: vector.customConfig?.transforms ?? {},
+      sinks,
+    },
+    sinkIds: Object.keys(sinks),
+  };
+}
+
+/**
+ * Per-sink outcome from Vector's log: the first ERROR line naming a sink's
+ * component_id (healthcheck or delivery failure) marks it failed.
+ */
+export function parseVectorTestLogs(
+  logs: string,
+  sinks: Record<string, { type?: unknown }>,
+): SinkTestResult[] {
+  const errors = new Map<string, string>();
+  for (const line of logs.split("\n")) {
+    if (!/\bERROR\b/.test(line)) continue;
+    const match = line.match(/component_id="?([A-Za-z0-9_-]+)"?/);
+    if (!match || !(match[1] in sinks) || errors.has(match[1])) continue;
+    errors.set(match[1], line.replace(/^\S+\s+ERROR\s+/, "").trim());
+  }
+  return Object.entries(sinks).map(([id, sink]) => ({
+    id,
+    type: String(sink.type ?? "unknown"),
+    ok: !errors.has(id),
+    ...(errors.has(id) ? { error: errors.get(id) } : {}),
+  }));
+}
+
+export async function runVectorTest(
+  cfg: DeploymentConfig,
+  values: Record<string, unknown> | null,
+): Promise<VectorTestResult> {
+  const vector = vectorValuesFrom(values);
+  const namespace = getNamespace(cfg.name);
+  const releaseName = getReleaseName(cfg.name);
+  const testId = `${Date.now()}`;
+  const { config, sinkIds } = buildVectorTestConfig(vector, testId);
+  if (sinkIds.length === 0) {
+    throw new Error("Vector has no sinks besides the console to test.");
+  }
+  const sinks = (config.sinks ?? {}) as Record<string, { type?: unknown }>;
+
+  const chartVersion = await getInstalledChartVersion(releaseName, namespace);
+  const catalog = await resolveImageCatalog(chartVersion ?? undefined);
+
+  let logs: string;
+  let failure: string | undefined;
+  try {
+    ({ logs } = await runEphemeralJob({
+      name: k8sName(`${releaseName}-vector-test-${testId}`),
+      namespace,
+      serviceAccountName: "vector",
+      image: catalog.image("vector", cfg.imageRegistry).ref,
+      command: [
+        "vector",
+        "--require-healthy",
+        "true",
+        "--config",
+        `${CONFIG_DIR}/vector.json`,
+      ],
+      env: vector.env,
+      initContainers: vector.initContainers,
+      volumes: vector.extraVolumes,
+      volumeMounts: vector.extraVolumeMounts,
+      configFiles: {
+        mountPath: CONFIG_DIR,
+        files: { "vector.json": JSON.stringify(config, null, 2) },
+      },
+      imagePullSecrets: vector.image?.pullSecrets,
+      labels: {
+        ...vector.podLabels,
+        "app.kubernetes.io/component": "vector-test",
+      },
+      timeoutSeconds: 300,
+    }));
+  } catch (err) {
+    // The job's message carries its logs; sink errors are parsed from it.
+    logs = err instanceof Error ? err.message : String(err);
+    failure = logs;
+  }
+
+  const results = parseVectorTestLogs(logs, sinks);
+  return {
+    testId,
+    sinks: results,
+    ...(failure && results.every((r) => r.ok) ? { error: failure } : {}),
+  };
+}
@@ -495,6 +495,7 @@ test("every logging platform has a field chain", () => {
     ["loki", ["logging-loki-url"]],
     ["newrelic", ["logging-newrelic-key", "logging-newrelic-account"]],
     ["axiom", ["logging-axiom-token", "logging-axiom-dataset"]],
+    ["otlp", ["logging-otlp-endpoint", "logging-otlp-headers"]],
   ] as const;
   for (const [sink, expected] of sinks) {
     const order = featureConfigFieldOrder(
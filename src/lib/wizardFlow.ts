@@ -179,6 +179,9 @@ export function featureConfigFieldOrder(s: FeatureConfigFlowState): string[] {
       case "axiom":
         fields.push("logging-axiom-token", "logging-axiom-dataset");
         break;
+      case "otlp":
+        fields.push("logging-otlp-endpoint", "logging-otlp-headers");
+        break;
       default:
         break;
     }
@@ -0,0 +1,96 @@
+import test from "node:test";
+import assert from "node:assert/strict";
+import {
+  applyWorkerScaleToValues,
+  resolveWorkerScale,
+  solutionPartitions,
+} from "./workerScaling.js";
+
+const current = { minReplicas: 2, maxReplicas: 40 };
+
+test("--replicas pins both bounds", () => {
+  assert.deepEqual(resolveWorkerScale({ replicas: "8" }, current, 128), {
+    minReplicas: 8,
+    maxReplicas: 8,
+  });
+  assert.throws(
+    () => resolveWorkerScale({ replicas: "8", max: "10" }, current, 128),
+    /cannot be combined/,
+  );
+});
+
+test("--min or --max alone keeps the other live bound", () => {
+  assert.deepEqual(resolveWorkerScale({ min: "4" }, current, 128), {
+    minReplicas: 4,
+    maxReplicas: 40,
+  });
+  assert.deepEqual(resolveWorkerScale({ max: "60" }, current, 128), {
+    minReplicas: 2,
+    maxReplicas: 60,
+  });
+  assert.throws(
+    () => resolveWorkerScale({ max: "60" }, {}, 128),
+    /pass --min and --max together/,
+  );
+  assert.throws(() => resolveWorkerScale({}, current, 128), /Pass --min/);
+});
+
+test("bounds must be ordered whole numbers within the partition count", () => {
+  assert.throws(
+    () => resolveWorkerScale({ min: "10", max: "5" }, current, 128),
+    /--min \(10\) must not exceed --max \(5\)/,
+  );
+  assert.throws(
+    () => resolveWorkerScale({ max: "129" }, current, 128),
+    /exceeds the solution topic's 128 partitions/,
+  );
+  assert.throws(
+    () => resolveWorkerScale({ min: "1.5" }, current, 128),
+    /--min must be a whole number/,
+  );
+  assert.throws(
+    () => resolveWorkerScale({ replicas: "0" }, current, 128),
+    /--max must be at least 1/,
+  );
+});
+
+test("the partition ceiling comes from values.yaml when it is there", () => {
+  assert.equal(
+    solutionPartitions({
+      rulebricks: { hps: { workers: { solutionPartitions: 64 } } },
+    }),
+    64,
+  );
+  assert.equal(solutionPartitions(null), 128);
+});
+
+test("bounds land under hps.workers.keda without touching the rest", () => {
+  const values = {
+    global: { domain: "rules.acme.com" },
+    rulebricks: {
+      hps: {
+        replicas: 3,
+        workers: { keda: { enabled: true, lagThreshold: 50 } },
+      },
+    },
+  };
+  assert.deepEqual(
+    applyWorkerScaleToValues(values, { minReplicas: 4, maxReplicas: 60 }),
+    {
+      global: { domain: "rules.acme.com" },
+      rulebricks: {
+        hps: {
+          replicas: 3,
+          workers: {
+            keda: {
+              enabled: true,
+              lagThreshold: 50,
+              minReplicaCount: 4,
+              maxReplicaCount: 60,
+            },
+          },
+        },
+      },
+    },
+  );
+});
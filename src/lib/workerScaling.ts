@@ -0,0 +1,127 @@
+import { SOLUTION_TOPIC_PARTITIONS } from "./chartDefaults.js";
+
+/**
+ * `rulebricks scale workers`: pins the HPS worker fleet's KEDA bounds. The
+ * live ScaledObject is patched so the change takes effect now, and the same
+ * bounds are written to values.yaml, whose edits deploy preserves (the CLI
+ * never generates worker min/max itself), so the next deploy keeps them.
+ */
+
+export interface WorkerScale {
+  minReplicas: number;
+  maxReplicas: number;
+}
+
+export interface WorkerScaleOptions {
+  min?: string;
+  max?: string;
+  replicas?: string;
+}
+
+function parseReplicaCount(value: string, flag: string): number {
+  if (!/^\d+$/.test(value.trim())) {
+    throw new Error(`${flag} must be a whole number, got "${value}".`);
+  }
+  return Number(value);
+}
+
+/** The worker topic's partition count: the most workers that get work. */
+export function solutionPartitions(
+  values: Record<string, unknown> | null,
+): number {
+  const workers = (
+    values?.rulebricks as
+      | { hps?: { workers?: { solutionPartitions?: unknown } } }
+      | undefined
+  )?.hps?.workers;
+  return typeof workers?.solutionPartitions === "number"
+    ? workers.solutionPartitions
+    : SOLUTION_TOPIC_PARTITIONS;
+}
+
+/**
+ * The bounds to apply: `--replicas` pins min and max to one count; `--min`
+ * and `--max` each keep the other bound at its current value.
+ */
+export function resolveWorkerScale(
+  options: WorkerScaleOptions,
+  current: Partial<WorkerScale>,
+  partitions: number,
+): WorkerScale {
+  if (options.replicas !== undefined) {
+    if (options.min !== undefined || options.max !== undefined) {
+      throw new Error("--replicas cannot be combined with --min or --max.");
+    }
+    const replicas = parseReplicaCount(options.replicas, "--replicas");
+    return validateWorkerScale(
+      { minReplicas: replicas, maxReplicas: replicas },
+      partitions,
+    );
+  }
+  if (options.min === undefined && options.max === undefined) {
+    throw new Error("Pass --min and/or --max, or --replicas.");
+  }
+
+  const minReplicas =
+    options.min !== undefined
+      ? parseReplicaCount(options.min, "--min")
+      : current.minReplicas;
+  const maxReplicas =
+    options.max !== undefined
+      ? parseReplicaCount(options.max, "--max")
+      : current.maxReplicas;
+  if (minReplicas === undefined || maxReplicas === undefined) {
+    throw new Error(
+      "The worker ScaledObject does not set both bounds; pass --min and --max together.",
+    );
+  }
+  return validateWorkerScale({ minReplicas, maxReplicas }, partitions);
+}
+
+function validateWorkerScale(
+  scale: WorkerScale,
+  partitions: number,
+): WorkerScale {
+  if (scale.maxReplicas < 1) {
+    throw new Error("--max must be at least 1.");
+  }
+  if (scale.minReplicas > scale.maxReplicas) {
+    throw new Error(
+      `--min (${scale.minReplicas}) must not exceed --max (${scale.maxReplicas}).`,
+    );
+  }
+  if (scale.maxReplicas > partitions) {
+    throw new Error(
+      `--max (${scale.maxReplicas}) exceeds the solution topic's ${partitions} partitions; workers beyond that would sit idle.`,
+    );
+  }
+  return scale;
+}
+
+/** values.yaml with the worker bounds set under hps.workers.keda. */
+export function applyWorkerScaleToValues(
+  values: Record<string, unknown>,
+  scale: WorkerScale,
+): Record<string, unknown> {
+  const rulebricks = (values.rulebricks ?? {}) as Record<string, unknown>;
+  const hps = (rulebricks.hps ?? {}) as Record<string, unknown>;
+  const workers = (hps.workers ?? {}) as Record<string, unknown>;
+  const keda = (workers.keda ?? {}) as Record<string, unknown>;
+  return {
+    ...values,
+    rulebricks: {
+      ...rulebricks,
+      hps: {
+        ...hps,
+        workers: {
+          ...workers,
+          keda: {
+            ...keda,
+            minReplicaCount: scale.minReplicas,
+            maxReplicaCount: scale.maxReplicas,
+          },
+        },
+      },
+    },
+  };
+}
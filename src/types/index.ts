@@ -59,7 +59,8 @@ export type LoggingSink =
   | "elasticsearch" // Elasticsearch
   | "loki" // Grafana Loki
   | "newrelic" // New Relic Logs
-  | "axiom"; // Axiom
+  | "axiom" // Axiom
+  | "otlp"; // OpenTelemetry collector (OTLP/HTTP)
 
 // Prometheus remote_write destination and auth configuration.
 export type MonitoringDestination =
@@ -332,13 +333,41 @@ export const LOGGING_SINK_INFO: Record<
     name: "Axiom",
     description: "Send logs to Axiom dataset",
   },
+  otlp: {
+    name: "OpenTelemetry (OTLP)",
+    description: "Send logs to an OpenTelemetry collector over OTLP/HTTP",
+  },
 };
 
+const TolerationSchema = z.object({
+  key: z.string().optional(),
+  operator: z.enum(["Exists", "Equal"]).optional(),
+  value: z.string().optional(),
+  effect: z.enum(["NoSchedule", "PreferNoSchedule", "NoExecute"]).optional(),
+  tolerationSeconds: z.number().int().optional(),
+});
+
+// Pod placement for one component (scheduling.kafka, scheduling.database),
+// in the shape of the pod spec fields of the same names.
+const SchedulingOverrideSchema = z.object({
+  nodeSelector: z.record(z.string()).optional(),
+  tolerations: z.array(TolerationSchema).optional(),
+  affinity: z.record(z.unknown()).optional(),
+});
+
+export type SchedulingOverride = z.infer<typeof SchedulingOverrideSchema>;
+
 const SecretKeyRefSchema = z.object({
   name: z.string().min(1),
   key: z.string().min(1),
 });
 
+// Kubernetes quantities ("500m", "2", "4Gi"); checked in configValidation.
+const ResourceQuantitiesSchema = z.object({
+  cpu: z.union([z.string(), z.number()]).optional(),
+  memory: z.string().optional(),
+});
+
 /**
  * Validates a Prometheus remote_write config the same way buildHelmValues and
  * the Helm chart do, returning human-readable errors. Centralized so the wizard
@@ -585,6 +614,28 @@ const TracingConfigSchema = z
 
 export type TracingConfig = z.infer<typeof TracingConfigSchema>;
 
+// An additional decision-log platform sink (features.logging.sinks). bucket
+// and region carry the credential and endpoint/site, as for logging.sink.
+const LoggingPlatformSinkSchema = z.object({
+  sink: z.enum([
+    "datadog",
+    "splunk",
+    "elasticsearch",
+    "loki",
+    "newrelic",
+    "axiom",
+    "otlp",
+  ]),
+  name: z
+    .string()
+    .regex(/^[a-z][a-z0-9_]*$/, "lowercase letters, digits and _ only")
+    .optional(),
+  bucket: z.string().optional(),
+  region: z.string().optional(),
+});
+
+export type LoggingPlatformSink = z.infer<typeof LoggingPlatformSinkSchema>;
+
 // Application/container log shipping to a customer-managed Elasticsearch (BYO)
 // via the Vector agent DaemonSet. Distinct from decision-log sinks.
 const AppLogsConfigSchema = z
@@ -715,6 +766,14 @@ export const DeploymentConfigSchema = z.object({
       .enum(["amd64", "arm64", "mixed", "unknown"])
       .optional(),
     arm64TolerationRequired: z.boolean().optional(),
+    // Pin Rulebricks pods to one CPU architecture (a kubernetes.io/arch
+    // nodeSelector). Unset schedules on any node; mainly for mixed clusters,
+    // e.g. Graviton plus x86 node groups on EKS.
+    workloadArchitecture: z.enum(["amd64", "arm64"]).optional(),
+    // Pin in-cluster Kafka and Postgres to a dedicated node pool labeled and
+    // tainted rulebricks.com/pool=stateful (cluster-setup's optional stateful
+    // pool, or any pool carrying the same label and taint).
+    statefulPool: z.boolean().optional(),
     storageClass: z.string().optional(),
     storageProvisioner: z.string().optional(),
     schedulableNodeCount: z.number().optional(),
@@ -730,6 +789,62 @@ export const DeploymentConfigSchema = z.object({
   adminEmail: z.string().email(),
   tlsEmail: z.string().email(),
 
+  // Ingress class shared by every ingress the chart renders (app, Supabase
+  // kong, ClickStack) so they all land on the same controller. Defaults to
+  // the bundled Traefik's "traefik". Entrypoints and cert resolver aren't
+  // settable: the chart's app ingress writes its own Traefik annotations.
+  ingress: z
+    .object({
+      className: z.string().min(1).optional(),
+    })
+    .optional(),
+
+  // Certificate issuance. The chart's ClusterIssuer answers HTTP-01
+  // challenges, which cannot prove wildcard names; listing a wildcard here
+  // makes the CLI apply a second, DNS-01 ClusterIssuer for it on the
+  // dns.provider zone, with credentials from tls.dns01.secretRef, and a
+  // Certificate for the wildcard names from that issuer.
+  tls: z
+    .object({
+      // Who issues the certificates Traefik serves. "letsencrypt" (default)
+      // is cert-manager's ACME ClusterIssuer; "cloudflare-origin" is for
+      // traffic proxied through Cloudflare: the CLI issues a Cloudflare
+      // Origin CA certificate for every host and Traefik serves it as its
+      // default certificate, with no cert-manager or public DNS involved.
+      provider: z.enum(["letsencrypt", "cloudflare-origin"]).optional(),
+      cloudflareOrigin: z
+        .object({
+          // API token with Zone / SSL and Certificates / Edit on the zone.
+          apiToken: z.string().min(1),
+          // Requested lifetime; Cloudflare accepts 7, 30, 90, 365, 730, 1095
+          // or 5475 (the default, 15 years).
+          validityDays: z.number().int().optional(),
+        })
+        .optional(),
+      // Names certificates are issued for beyond the app/supabase hosts, e.g.
+      // "*.rules.acme.com" for Certificates applied with `rulebricks apply`.
+      domains: z.array(z.string().min(1)).optional(),
+      dns01: z
+        .object({
+          // Secret (in the cert-manager namespace) holding the solver
+          // credentials: route53 access-key-id/secret-access-key, google
+          // key.json, azure client-secret, cloudflare api-token.
+          secretRef: z.string().min(1),
+          // route53: the hosted zone (optional; looked up from the name).
+          hostedZoneId: z.string().min(1).optional(),
+          // google: defaults to infrastructure.gcpProjectId.
+          project: z.string().min(1).optional(),
+          // azure: service principal with DNS Zone Contributor on the zone.
+          subscriptionId: z.string().min(1).optional(),
+          resourceGroup: z.string().min(1).optional(),
+          hostedZoneName: z.string().min(1).optional(),
+          clientId: z.string().min(1).optional(),
+          tenantId: z.string().min(1).optional(),
+        })
+        .optional(),
+    })
+    .optional(),
+
   // DNS Configuration
   dns: z.object({
     // Where is the user's DNS hosted?
@@ -762,6 +877,23 @@ export const DeploymentConfigSchema = z.object({
     supabaseDbPassword: z.string().optional(),
     supabaseDashboardUser: z.string().optional(),
     supabaseDashboardPass: z.string().optional(),
+    // Requests/limits for the bundled Postgres (supabase.db). Unset means the
+    // chart defaults, which are sized for evaluation rather than production.
+    resources: z
+      .object({
+        requests: ResourceQuantitiesSchema.optional(),
+        limits: ResourceQuantitiesSchema.optional(),
+      })
+      .optional(),
+    // Size of the bundled Postgres volume; unset means the chart default.
+    // Volumes can grow but never shrink.
+    storageSize: z
+      .string()
+      .regex(
+        /^\d+(\.\d+)?([KMGTPE]i|[kMGTPE])?$/,
+        'a Kubernetes quantity such as "50Gi"',
+      )
+      .optional(),
   }),
 
   // Shared object storage: one provider, one identity, one bucket/container.
@@ -901,6 +1033,19 @@ export const DeploymentConfigSchema = z.object({
               host: z.string().optional(),
               port: z.number().int().min(1).max(65535).optional(),
               database: z.string().optional(),
+              // libpq sslmode for the CLI's own connections (deploy preflight,
+              // `db psql`). Defaults to require, as the bootstrap hook uses;
+              // verify-full also checks the server certificate.
+              sslMode: z
+                .enum([
+                  "disable",
+                  "allow",
+                  "prefer",
+                  "require",
+                  "verify-ca",
+                  "verify-full",
+                ])
+                .optional(),
               // One-time master/owner credentials the chart's pre-install hook
               // uses to initialize the database (roles, schemas, auth helpers,
               // publication). Inline creds are materialized into a hook-scoped
@@ -921,6 +1066,67 @@ export const DeploymentConfigSchema = z.object({
     })
     .optional(),
 
+  // Extra placement for the in-cluster stateful services, e.g. to pin Kafka
+  // to a dedicated, labeled and tainted node group. Merged over the CLI's own
+  // scheduling (architecture pin, infrastructure.statefulPool): nodeSelector
+  // keys are added, tolerations appended, and affinity replaces the default.
+  scheduling: z
+    .object({
+      kafka: SchedulingOverrideSchema.optional(),
+      database: SchedulingOverrideSchema.optional(),
+    })
+    .optional(),
+
+  // StorageClass per stateful component, for clusters where one class
+  // doesn't suit every volume (e.g. faster disks for Kafka). Unset
+  // components use infrastructure.storageClass. Only new volumes pick the
+  // class up: a StatefulSet's volume claims can't change class in place.
+  storageClasses: z
+    .object({
+      database: z.string().min(1).optional(),
+      kafka: z.string().min(1).optional(),
+      redis: z.string().min(1).optional(),
+      clickstack: z.string().min(1).optional(),
+      prometheus: z.string().min(1).optional(),
+    })
+    .optional(),
+
+  // Deploy wait deadlines as Go-style durations ("90s", "20m", "1h30m").
+  // `default` covers any component without its own entry; deploy
+  // --component-timeout overrides both for a single run.
+  timeouts: z
+    .object({
+      default: z.string().optional(),
+      secrets: z.string().optional(),
+      chart: z.string().optional(),
+      certificates: z.string().optional(),
+    })
+    .optional(),
+
+  // Where deploy posts a summary when it succeeds or fails: `webhook` gets
+  // JSON (its token sent as a bearer token), `slack` an incoming webhook's
+  // message. deploy --notify replaces both for one run. The token and the
+  // Slack URL are credentials, so `config encrypt` covers them.
+  notifications: z
+    .object({
+      // https:// only: the token and the Slack URL itself are credentials.
+      webhook: z
+        .object({
+          url: z.string().url().startsWith("https://", "must use https://"),
+          token: z.string().min(1).optional(),
+        })
+        .optional(),
+      slack: z
+        .object({
+          webhookUrl: z
+            .string()
+            .url()
+            .startsWith("https://", "must use https://"),
+        })
+        .optional(),
+    })
+    .optional(),
+
   backup: z
     .object({
       enabled: z.boolean(),
@@ -1004,6 +1210,25 @@ export const DeploymentConfigSchema = z.object({
       // Legacy optional URL retained for existing config files.
       remoteWriteUrl: z.string().url().optional(),
       remoteWrite: RemoteWriteConfigSchema.optional(),
+      // In-cluster Prometheus sizing; defaults to 30d / 50Gi.
+      metrics: z
+        .object({
+          retention: z
+            .string()
+            .regex(
+              /^(\d+(ms|s|m|h|d|w|y))+$/,
+              'a Prometheus duration such as "15d" or "12h"',
+            )
+            .optional(),
+          storageSize: z
+            .string()
+            .regex(
+              /^\d+(\.\d+)?([KMGTPE]i|[kMGTPE])?$/,
+              'a Kubernetes quantity such as "50Gi"',
+            )
+            .optional(),
+        })
+        .optional(),
     }),
     observability: z
       .object({
@@ -1031,11 +1256,16 @@ export const DeploymentConfigSchema = z.object({
         "loki",
         "newrelic",
         "axiom",
+        "otlp",
       ]),
       // For platforms, bucket/region are repurposed to carry the credential
       // (API key/token) and endpoint/site.
       bucket: z.string().optional(),
       region: z.string().optional(),
+      // Further platforms to fan out to alongside `sink` (e.g. Datadog for
+      // alerting plus Splunk for retention). Same bucket/region convention;
+      // `name` sets the Vector sink id when the same platform appears twice.
+      sinks: z.array(LoggingPlatformSinkSchema).optional(),
       // Application/container log shipping to Elasticsearch via the Vector
       // agent DaemonSet (distinct from the decision-log `sink` above).
       appLogs: AppLogsConfigSchema.optional(),
@@ -1076,7 +1306,8 @@ export const DeploymentConfigSchema = z.object({
   // the rulebricks/<name> path. See the helm chart's global.imageRegistry knob.
   imageRegistry: z.string().optional(),
 
-  // Legacy chart version (deprecated, kept for backwards compatibility)
+  // Chart version deploys install (`deploy --pin-version` records it). Unset,
+  // deploys stay on the version last recorded in state.
   chartVersion: z.string().optional(),
 });
 
@@ -1107,7 +1338,7 @@ export interface DeploymentState {
   application?: {
     /** Unified Rulebricks product version */
     version: string;
-    /** Legacy chart version (deprecated) */
+    /** Chart version last installed; redeploys stay on it */
     chartVersion?: string;
     namespace: string;
     url: string;
@@ -1119,6 +1350,41 @@ export interface DeploymentState {
     target: string;
     verified: boolean;
   }[];
+  /** Baseline from the last successful deploy, for `deploy --since-state`. */
+  appliedConfig?: AppliedConfig;
+  /** Step durations (ms) of the last successful deploy, for its estimates. */
+  stepDurations?: Record<string, number>;
+  /** Cluster-scoped "Kind/name" objects created by `rulebricks apply`. */
+  appliedClusterResources?: string[];
+  /** Deploys, upgrades, rollbacks, destroys and rotations, oldest first. */
+  history?: DeploymentEvent[];
+}
+
+/** One run recorded in DeploymentState.history, for `rulebricks history`. */
+export interface DeploymentEvent {
+  /** When the run finished. */
+  at: string;
+  action:
+    | "deploy"
+    | "upgrade"
+    | "upgrade-chart"
+    | "rollback"
+    | "destroy"
+    | "rotate-secrets";
+  /** Product version before and after; chart versions for upgrade-chart. */
+  fromVersion?: string;
+  toVersion?: string;
+  outcome: "succeeded" | "failed";
+  durationMs: number;
+  /** First line of the error, for failed runs. */
+  error?: string;
+}
+
+export interface AppliedConfig {
+  /** sha256 of each top-level config section, keyed by section name. */
+  sections: Record<string, string>;
+  chartVersion: string;
+  secretMode: string;
 }
 
 // Helm chart version info (legacy)
@@ -1127,6 +1393,8 @@ export interface ChartVersion {
   appVersion: string;
   created: string;
   digest: string;
+  /** Release notes (GitHub releases only). */
+  notes?: string;
 }
 
 // Rulebricks product version with registry metadata
@@ -1157,6 +1425,29 @@ export function isSupportedDnsProvider(provider: DnsProvider): boolean {
   return SUPPORTED_DNS_PROVIDERS.includes(provider);
 }
 
+// DNS providers cert-manager can answer DNS-01 challenges on.
+export function isDns01Provider(provider: DnsProvider): boolean {
+  return ["route53", "google", "azure", "cloudflare"].includes(provider);
+}
+
+// Wildcard names in tls.domains; each needs the DNS-01 solver.
+export function wildcardTlsDomains(config: DeploymentConfig): string[] {
+  return (config.tls?.domains ?? []).filter((d) => d.startsWith("*."));
+}
+
+// Certificates come from Cloudflare's Origin CA instead of cert-manager.
+export function usesCloudflareOriginTls(config: DeploymentConfig): boolean {
+  return config.tls?.provider === "cloudflare-origin";
+}
+
+// Lifetimes (days) the Cloudflare Origin CA accepts.
+export const ORIGIN_CERT_VALIDITY_DAYS = [7, 30, 90, 365, 730, 1095, 5475];
+
+// The TLS Secret Traefik serves the Cloudflare Origin certificate from.
+export function originCertificateSecretName(deploymentName: string): string {
+  return `${getReleaseName(deploymentName)}-cloudflare-origin-tls`;
+}
+
 // Profile configuration schema for persistent user preferences
 export const ProfileConfigSchema = z.object({
   // Infrastructure preferences